@@ -7,14 +7,18 @@ package api
 import (
 	"fmt"
 	"os"
+	"os/user"
+	"path"
 	"runtime/pprof"
 
 	"github.com/zenoss/glog"
 	dockerclient "github.com/zenoss/go-dockerclient"
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/node"
 	"github.com/control-center/serviced/rpc/agent"
 	"github.com/control-center/serviced/rpc/master"
+	"github.com/control-center/serviced/rpc/rpcutils"
 )
 
 var options Options
@@ -49,8 +53,11 @@ type Options struct {
 	DockerRegistry       string
 	CPUProfile           string // write cpu profile to file
 	MaxContainerAge      int    // max container age in seconds
+	DrainTimeout         int    // seconds stopping instances are drained
 	VirtualAddressSubnet string
 	MasterPoolID         string
+	APIToken             string // the API token to authenticate RPC requests with
+	JoinToken            string // the token the agent is issued its first certificate with
 }
 
 // LoadOptions overwrites the existing server options
@@ -65,6 +72,14 @@ func LoadOptions(ops Options) {
 		glog.V(0).Infof("overriding elastic search startup timeout with minimum %d", minTimeout)
 		options.ESStartupTimeout = minTimeout
 	}
+
+	// processes that can read the key of the master may act on behalf of
+	// their user; everyone else needs an API token
+	creds := rpcutils.Credentials{Bearer: options.APIToken}
+	if key, err := rpcutils.ReadKey(path.Join(GetVarPath(), rpcutils.KeyFile)); err == nil {
+		creds.Key = key
+	}
+	rpcutils.SetDefaultCredentials(creds)
 }
 
 type api struct {
@@ -99,11 +114,20 @@ func (a *api) StartServer() error {
 	return d.run()
 }
 
+// caller identifies the user running the CLI to the master
+func caller() datastore.Caller {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return datastore.Caller{User: name, Source: datastore.SourceCLI}
+}
+
 // Opens a connection to the master if not already connected
 func (a *api) connectMaster() (*master.Client, error) {
 	if a.master == nil {
 		var err error
-		a.master, err = master.NewClient(options.Endpoint)
+		a.master, err = master.NewClientForCaller(options.Endpoint, caller())
 		if err != nil {
 			return nil, fmt.Errorf("could not create a client to the master: %s", err)
 		}
@@ -139,7 +163,7 @@ func (a *api) connectDocker() (*dockerclient.Client, error) {
 func (a *api) connectDAO() (dao.ControlPlane, error) {
 	if a.dao == nil {
 		var err error
-		a.dao, err = node.NewControlClient(options.Endpoint)
+		a.dao, err = node.NewControlClientForCaller(options.Endpoint, caller())
 		if err != nil {
 			return nil, fmt.Errorf("could not create a client to the agent: %s", err)
 		}
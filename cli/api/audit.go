@@ -0,0 +1,20 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/control-center/serviced/domain/audit"
+)
+
+// GetAuditEntries returns the entries of the audit log that match the filter,
+// newest first
+func (a *api) GetAuditEntries(filter audit.Filter) ([]*audit.Entry, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetAuditEntries(filter)
+}
@@ -6,9 +6,33 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"path/filepath"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/rpc/rpcutils"
 )
 
+// checkKeyTransport refuses to send the passphrase or private key of a backup
+// to an endpoint on another host over a connection that is not encrypted
+func checkKeyTransport(endpoint string, key dao.BackupKey) error {
+	if key.Passphrase == "" && key.PrivateKey == "" {
+		return nil
+	} else if rpcutils.DefaultCredentials().TLS != nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	} else if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("the connection to %s is not encrypted; run the command on the master to keep the key of the backup secret", endpoint)
+}
+
 // Dump all templates and services to a tgz file.
 // This includes a snapshot of all shared file systems
 // and exports all docker images the services depend on.
@@ -26,6 +50,56 @@ func (a *api) Backup(dirpath string) (string, error) {
 	return path, nil
 }
 
+// IncrementalBackup dumps a backup that is incremental to the newest backup in
+// the directory, leaving out what the backups it is incremental to have.
+func (a *api) IncrementalBackup(dirpath string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+	if err := client.IncrementalBackup(dirpath, &path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// EncryptedBackup dumps a backup, or an incremental backup, encrypted with a
+// passphrase or a public key
+func (a *api) EncryptedBackup(request dao.BackupRequest) (string, error) {
+	if err := checkKeyTransport(options.Endpoint, request.Key); err != nil {
+		return "", err
+	}
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+	if err := client.EncryptedBackup(request, &path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// ListBackups lists the backups in a directory, oldest first
+func (a *api) ListBackups(dirpath string) ([]dao.BackupInfo, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []dao.BackupInfo
+	if err := client.ListBackups(dirpath, &backups); err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
 // Restores templates, services, snapshots, and docker images from a tgz file.
 // This is the inverse of CmdBackup.
 func (a *api) Restore(path string) error {
@@ -41,3 +115,100 @@ func (a *api) Restore(path string) error {
 
 	return client.Restore(filepath.Clean(fp), &unusedInt)
 }
+
+// EncryptedRestore restores an encrypted backup with its passphrase or
+// private key
+func (a *api) EncryptedRestore(request dao.RestoreRequest) error {
+	if err := checkKeyTransport(options.Endpoint, request.Key); err != nil {
+		return err
+	}
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	fp, err := filepath.Abs(request.FilePath)
+	if err != nil {
+		return fmt.Errorf("could not convert '%s' to an absolute file path: %v", request.FilePath, err)
+	}
+	request.FilePath = filepath.Clean(fp)
+
+	return client.EncryptedRestore(request, &unusedInt)
+}
+
+// VerifyBackup checks the checksums of the files of a backup, without
+// restoring it
+func (a *api) VerifyBackup(request dao.RestoreRequest) (*dao.BackupVerification, error) {
+	if err := checkKeyTransport(options.Endpoint, request.Key); err != nil {
+		return nil, err
+	}
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := filepath.Abs(request.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert '%s' to an absolute file path: %v", request.FilePath, err)
+	}
+	request.FilePath = filepath.Clean(fp)
+
+	var verification dao.BackupVerification
+	if err := client.VerifyBackup(request, &verification); err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// BackupProgress gets the progress of the backup in flight, or of the last
+// one, once it changed since the sequence number or the server times out
+func (a *api) BackupProgress(since int) (*dao.BackupProgress, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var progress dao.BackupProgress
+	if err := client.BackupProgress(since, &progress); err != nil {
+		return nil, err
+	}
+
+	return &progress, nil
+}
+
+// RestoreProgress gets the progress of the restore in flight, or of the last
+// one, once it changed since the sequence number or the server times out
+func (a *api) RestoreProgress(since int) (*dao.BackupProgress, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var progress dao.BackupProgress
+	if err := client.RestoreProgress(since, &progress); err != nil {
+		return nil, err
+	}
+
+	return &progress, nil
+}
+
+// CancelBackup cancels the backup in flight
+func (a *api) CancelBackup() error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	return client.CancelBackup("", &unusedInt)
+}
+
+// CancelRestore cancels the restore in flight
+func (a *api) CancelRestore() error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	return client.CancelRestore("", &unusedInt)
+}
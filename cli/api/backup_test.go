@@ -5,7 +5,11 @@
 package api
 
 import (
+	"crypto/tls"
 	"testing"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/rpc/rpcutils"
 )
 
 func TestBackup(t *testing.T) {
@@ -17,5 +21,26 @@ func BenchmarkBackup(b *testing.B) {
 func TestRestore(t *testing.T) {
 }
 
+func TestCheckKeyTransport(t *testing.T) {
+	defer rpcutils.SetDefaultCredentials(rpcutils.Credentials{})
+
+	secret := dao.BackupKey{Passphrase: "secret"}
+	for _, endpoint := range []string{"localhost:4979", "127.0.0.1:4979", "[::1]:4979"} {
+		if err := checkKeyTransport(endpoint, secret); err != nil {
+			t.Errorf("Expected the key to be sent to %s, got %s", endpoint, err)
+		}
+	}
+	if err := checkKeyTransport("master:4979", secret); err == nil {
+		t.Errorf("Expected the key not to be sent to another host in the clear")
+	}
+	if err := checkKeyTransport("master:4979", dao.BackupKey{PublicKey: "public"}); err != nil {
+		t.Errorf("Expected a public key to be sent anywhere, got %s", err)
+	}
+	rpcutils.SetDefaultCredentials(rpcutils.Credentials{TLS: &tls.Config{}})
+	if err := checkKeyTransport("master:4979", secret); err != nil {
+		t.Errorf("Expected the key to be sent over TLS, got %s", err)
+	}
+}
+
 func BenchmarkRestore(b *testing.B) {
 }
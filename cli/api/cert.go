@@ -0,0 +1,28 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+// RotateCertificates makes every host reissue its mux certificate, replacing
+// the certificate authority if newCA is set, and returns the new generation
+// of certificates
+func (a *api) RotateCertificates(newCA bool) (int, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return 0, err
+	}
+
+	return client.RotateCertificates(newCA)
+}
+
+// CreateJoinToken creates the token that a host is issued its first
+// certificate with
+func (a *api) CreateJoinToken(hostID string) (string, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return "", err
+	}
+
+	return client.CreateJoinToken(hostID)
+}
@@ -15,11 +15,16 @@ import (
 	"github.com/control-center/serviced/datastore/elastic"
 	"github.com/control-center/serviced/dfs/nfs"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/audit"
+	"github.com/control-center/serviced/domain/event"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/notification"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicetemplate"
+	"github.com/control-center/serviced/domain/token"
 	"github.com/control-center/serviced/domain/user"
 	"github.com/control-center/serviced/facade"
 	"github.com/control-center/serviced/isvcs"
@@ -27,6 +32,7 @@ import (
 	"github.com/control-center/serviced/proxy"
 	"github.com/control-center/serviced/rpc/agent"
 	"github.com/control-center/serviced/rpc/master"
+	"github.com/control-center/serviced/rpc/rpcutils"
 	"github.com/control-center/serviced/scheduler"
 	"github.com/control-center/serviced/shell"
 	"github.com/control-center/serviced/stats"
@@ -34,6 +40,8 @@ import (
 	"github.com/control-center/serviced/volume"
 	// Need to do btrfs driver initializations
 	_ "github.com/control-center/serviced/volume/btrfs"
+	// Need to do lvm driver initializations
+	_ "github.com/control-center/serviced/volume/lvm"
 	// Need to do rsync driver initializations
 	_ "github.com/control-center/serviced/volume/rsync"
 	"github.com/control-center/serviced/web"
@@ -67,6 +75,8 @@ type daemon struct {
 	dsDriver         datastore.Driver
 	dsContext        datastore.Context
 	facade           *facade.Facade
+	ca               *proxy.CertificateAuthority
+	rpcAuth          *rpcutils.Authenticator
 	hostID           string
 	zClient          *coordclient.Client
 	storageHandler   *storage.Server
@@ -130,11 +140,20 @@ func (d *daemon) run() error {
 	}
 
 	rpc.HandleHTTP()
+	var handler http.Handler = http.DefaultServeMux
+	if options.Master {
+		// attribute the changes made by authenticated clients to them; hosts
+		// authenticate with the certificates issued by the master
+		handler = rpcutils.NewHandler(handler, d.rpcAuth, d.newCallerRPC)
+		listener := rpcutils.NewListener(l)
+		go d.refreshRPCListener(listener)
+		l = listener
+	}
 
 	glog.V(0).Infof("Listening on %s", l.Addr().String())
 	go func() {
 		// start the server
-		http.Serve(l, nil)
+		http.Serve(l, handler)
 		glog.Infof("http server done")
 	}()
 
@@ -205,6 +224,29 @@ func (d *daemon) startMaster() error {
 		return err
 	}
 
+	if err = d.facade.CreateDefaultRoles(d.dsContext); err != nil {
+		return err
+	}
+
+	if d.ca, err = proxy.LoadCertificateAuthority(path.Join(options.VarPath, "certs")); err != nil {
+		glog.Errorf("could not load certificate authority: %s", err)
+		return err
+	}
+
+	key, err := rpcutils.CreateKey(path.Join(options.VarPath, rpcutils.KeyFile))
+	if err != nil {
+		glog.Errorf("could not load rpc key: %s", err)
+		return err
+	}
+	creds := rpcutils.DefaultCredentials()
+	creds.Key = key
+	rpcutils.SetDefaultCredentials(creds)
+	d.rpcAuth = &rpcutils.Authenticator{
+		Key:    key,
+		Bearer: d.authenticateBearer,
+		Host:   d.authenticateHost,
+	}
+
 	if err = d.registerMasterRPC(); err != nil {
 		return err
 	}
@@ -269,31 +311,44 @@ func getKeyPairs(certPEMFile, keyPEMFile string) (certPEM, keyPEM []byte, err er
 	return
 }
 
-func createMuxListener() (net.Listener, error) {
-	if options.TLS {
-		glog.V(1).Info("using TLS on mux")
-
-		proxyCertPEM, proxyKeyPEM, err := getKeyPairs(options.CertPEMFile, options.KeyPEMFile)
-		if err != nil {
-			return nil, err
-		}
-
-		cert, err := tls.X509KeyPair([]byte(proxyCertPEM), []byte(proxyKeyPEM))
-		if err != nil {
-			glog.Error("ListenAndMux Error (tls.X509KeyPair): ", err)
-			return nil, err
-		}
-
-		tlsConfig := tls.Config{Certificates: []tls.Certificate{cert}}
-		glog.V(1).Infof("TLS enabled tcp mux listening on %d", options.MuxPort)
-		return tls.Listen("tcp", fmt.Sprintf(":%d", options.MuxPort), &tlsConfig)
+// createMuxListener listens on the mux port. With TLS, the mux only accepts
+// peers with certificates of the same deployment, and refuses connections
+// until the master has issued the certificates of the host.
+func createMuxListener() (net.Listener, *proxy.TLSListener, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", options.MuxPort))
+	if err != nil || !options.TLS {
+		return listener, nil, err
+	}
+	glog.V(1).Infof("mutual TLS enabled tcp mux listening on %d", options.MuxPort)
+	tlsListener := proxy.NewTLSListener(listener, nil)
+	return tlsListener, tlsListener, nil
+}
 
+// loadMuxCertificate loads the certificate and key configured for the mux,
+// if any. The mux presents it to clients that do not ask for the certificate
+// issued by the master; peers are verified either way.
+func loadMuxCertificate() (*tls.Certificate, error) {
+	if !options.TLS || (len(options.CertPEMFile) == 0 && len(options.KeyPEMFile) == 0) {
+		return nil, nil
+	}
+	proxyCertPEM, proxyKeyPEM, err := getKeyPairs(options.CertPEMFile, options.KeyPEMFile)
+	if err != nil {
+		return nil, err
 	}
-	return net.Listen("tcp", fmt.Sprintf(":%d", options.MuxPort))
+	cert, err := tls.X509KeyPair([]byte(proxyCertPEM), []byte(proxyKeyPEM))
+	if err != nil {
+		glog.Error("ListenAndMux Error (tls.X509KeyPair): ", err)
+		return nil, err
+	}
+	return &cert, nil
 }
 
 func (d *daemon) startAgent() error {
-	muxListener, err := createMuxListener()
+	muxListener, muxTLS, err := createMuxListener()
+	if err != nil {
+		return err
+	}
+	muxCert, err := loadMuxCertificate()
 	if err != nil {
 		return err
 	}
@@ -316,13 +371,29 @@ func (d *daemon) startAgent() error {
 		return fmt.Errorf("HostID failed: %v", err)
 	}
 
+	certs, err := node.NewHostCertificates(node.CertificateOptions{
+		Master:    d.servicedEndpoint,
+		HostID:    myHostID,
+		VarPath:   options.VarPath,
+		JoinToken: options.JoinToken,
+		MuxTLS:    muxTLS,
+		MuxCert:   muxCert,
+	})
+	if err != nil {
+		return fmt.Errorf("could not load the certificates of this host: %v", err)
+	}
+
 	go func() {
 		var poolID string
 		for {
 			glog.Infof("Trying to discover my pool...")
 			var myHost *host.Host
-			masterClient, err := master.NewClient(d.servicedEndpoint)
+			var masterClient *master.Client
+			// the host identifies itself to the master with its certificate
+			err := certs.Refresh()
 			if err != nil {
+				glog.Warningf("Could not get the certificates of host %v: %v (has this host been added?)", myHostID, err)
+			} else if masterClient, err = master.NewClient(d.servicedEndpoint); err != nil {
 				glog.Errorf("master.NewClient failed (endpoint %+v) : %v", d.servicedEndpoint, err)
 			} else {
 				myHost, err = masterClient.GetHost(myHostID)
@@ -413,6 +484,7 @@ func (d *daemon) startAgent() error {
 			VFS:                  options.VFS,
 			Zookeepers:           options.Zookeepers,
 			Mux:                  mux,
+			Certificates:         certs,
 			DockerRegistry:       options.DockerRegistry,
 			MaxContainerAge:      time.Duration(int(time.Second) * options.MaxContainerAge),
 			VirtualAddressSubnet: options.VirtualAddressSubnet,
@@ -421,6 +493,12 @@ func (d *daemon) startAgent() error {
 		hostAgent, err := node.NewHostAgent(agentOptions)
 		d.hostAgent = hostAgent
 
+		d.waitGroup.Add(1)
+		go func() {
+			certs.RefreshLoop(time.Minute, d.shutdown)
+			d.waitGroup.Done()
+		}()
+
 		d.waitGroup.Add(1)
 		go func() {
 			hostAgent.Start(d.shutdown)
@@ -471,20 +549,84 @@ func (d *daemon) startAgent() error {
 func (d *daemon) registerMasterRPC() error {
 	glog.V(0).Infoln("registering Master RPC services")
 
-	if err := rpc.RegisterName("Master", master.NewServer(d.facade)); err != nil {
-		return fmt.Errorf("could not register rpc server LoadBalancer: %v", err)
+	// connections that did not authenticate are anonymous, and may only make
+	// the requests that need no roles
+	return d.registerCallerRPC(rpc.DefaultServer, datastore.Caller{Source: datastore.SourceRPC})
+}
+
+// newCallerRPC creates an rpc server for the master services that attributes
+// the changes it makes to the caller
+func (d *daemon) newCallerRPC(caller datastore.Caller) (*rpc.Server, error) {
+	server := rpc.NewServer()
+	if err := d.registerCallerRPC(server, caller); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// registerCallerRPC registers the master services of the caller
+func (d *daemon) registerCallerRPC(server *rpc.Server, caller datastore.Caller) error {
+	cpDao, ok := d.cpDao.(*elasticsearch.ControlPlaneDao)
+	if !ok {
+		return fmt.Errorf("unexpected control plane dao %T", d.cpDao)
+	}
+	cpDao = elasticsearch.WithCaller(cpDao, caller)
+
+	if err := server.RegisterName("Master", master.NewServerForCaller(d.facade, d.ca, caller)); err != nil {
+		return fmt.Errorf("could not register rpc server Master: %v", err)
 	}
 
 	// register the deprecated rpc servers
-	if err := rpc.RegisterName("LoadBalancer", d.cpDao); err != nil {
+	if err := server.RegisterName("LoadBalancer", cpDao); err != nil {
 		return fmt.Errorf("could not register rpc server LoadBalancer: %v", err)
 	}
+	if err := server.RegisterName("ControlPlane", cpDao); err != nil {
+		return fmt.Errorf("could not register rpc server ControlPlane: %v", err)
+	}
+	return nil
+}
+
+// authenticateBearer returns the user and ID of a valid API token
+func (d *daemon) authenticateBearer(bearer string) (string, string, error) {
+	t, err := d.facade.AuthenticateToken(d.dsContext, bearer)
+	if err != nil {
+		return "", "", err
+	}
+	return t.User, t.ID, nil
+}
 
-	if err := rpc.RegisterName("ControlPlane", d.cpDao); err != nil {
-		return fmt.Errorf("could not register rpc server LoadBalancer: %v", err)
+// authenticateHost checks that the host of a certificate has not been removed
+func (d *daemon) authenticateHost(hostID string) error {
+	h, err := d.facade.GetHost(d.dsContext, hostID)
+	if err != nil {
+		return err
+	} else if h == nil {
+		return fmt.Errorf("host %s not found", hostID)
 	}
 	return nil
 }
+
+// refreshRPCListener keeps the certificates that hosts connect to the rpc
+// port with up to date with the certificate authority. Clients without a
+// certificate may still connect.
+func (d *daemon) refreshRPCListener(listener *rpcutils.Listener) {
+	for {
+		if bundle, err := d.ca.Own(); err != nil {
+			glog.Errorf("could not get rpc certificates: %s", err)
+		} else if config, err := bundle.ServerConfig(); err != nil {
+			glog.Errorf("could not use rpc certificates: %s", err)
+		} else {
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+			listener.SetConfig(config)
+		}
+		select {
+		case <-d.shutdown:
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
 func (d *daemon) initDriver() (datastore.Driver, error) {
 
 	eDriver := elastic.New("localhost", 9200, "controlplane")
@@ -495,6 +637,11 @@ func (d *daemon) initDriver() (datastore.Driver, error) {
 	eDriver.AddMapping(addressassignment.MAPPING)
 	eDriver.AddMapping(serviceconfigfile.MAPPING)
 	eDriver.AddMapping(user.MAPPING)
+	eDriver.AddMapping(audit.MAPPING)
+	eDriver.AddMapping(event.MAPPING)
+	eDriver.AddMapping(notification.MAPPING)
+	eDriver.AddMapping(role.MAPPING)
+	eDriver.AddMapping(token.MAPPING)
 	err := eDriver.Initialize(10 * time.Second)
 	if err != nil {
 		return nil, err
@@ -533,9 +680,30 @@ func (d *daemon) initWeb() {
 	// TODO: Make bind port for web server optional?
 	glog.V(4).Infof("Starting web server: uiport: %v; port: %v; zookeepers: %v", options.UIPort, options.Endpoint, options.Zookeepers)
 	cpserver := web.NewServiceConfig(options.UIPort, options.Endpoint, options.ReportStats, options.HostAliases, options.TLS, options.MuxPort)
+	if options.TLS {
+		go d.refreshMuxDialer(cpserver.MuxDialer())
+	}
 	go cpserver.ServeUI()
 	go cpserver.Serve(d.shutdown)
 }
+
+// refreshMuxDialer keeps the certificates that the master dials muxes with up
+// to date with the certificate authority
+func (d *daemon) refreshMuxDialer(dialer *proxy.MuxDialer) {
+	for {
+		if bundle, err := d.ca.Own(); err != nil {
+			glog.Errorf("could not get mux certificates: %s", err)
+		} else if err := dialer.SetCertificates(bundle); err != nil {
+			glog.Errorf("could not use mux certificates: %s", err)
+		}
+		select {
+		case <-d.shutdown:
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
 func (d *daemon) startScheduler() {
 	go d.runScheduler()
 }
@@ -578,7 +746,7 @@ func (d *daemon) addTemplates() {
 
 func (d *daemon) runScheduler() {
 	for {
-		sched, schedShutdown := scheduler.NewScheduler("", d.hostID, d.cpDao, d.facade)
+		sched, schedShutdown := scheduler.NewScheduler("", d.hostID, d.cpDao, d.facade, time.Duration(options.DrainTimeout)*time.Second)
 		sched.Start()
 		select {
 		case <-d.shutdown:
@@ -0,0 +1,20 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/control-center/serviced/domain/event"
+)
+
+// GetEvents returns the threshold events that match the filter, most
+// recently seen first
+func (a *api) GetEvents(filter event.Filter) ([]*event.Event, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetEvents(filter)
+}
@@ -5,6 +5,8 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/rpc/agent"
 )
@@ -75,3 +77,45 @@ func (a *api) RemoveHost(id string) error {
 
 	return client.RemoveHost(id)
 }
+
+// Sets labels on an existing host, replacing the values of existing labels
+func (a *api) SetHostLabels(id string, labels map[string]string) (*host.Host, error) {
+	return a.updateHostLabels(id, func(h *host.Host) {
+		if h.Labels == nil {
+			h.Labels = make(map[string]string)
+		}
+		for key, value := range labels {
+			h.Labels[key] = value
+		}
+	})
+}
+
+// Removes labels from an existing host
+func (a *api) RemoveHostLabels(id string, keys []string) (*host.Host, error) {
+	return a.updateHostLabels(id, func(h *host.Host) {
+		for _, key := range keys {
+			delete(h.Labels, key)
+		}
+	})
+}
+
+func (a *api) updateHostLabels(id string, update func(*host.Host)) (*host.Host, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := client.GetHost(id)
+	if err != nil {
+		return nil, err
+	} else if h == nil {
+		return nil, fmt.Errorf("host not found: %s", id)
+	}
+
+	update(h)
+	if err := client.UpdateHost(*h); err != nil {
+		return nil, err
+	}
+
+	return a.GetHost(id)
+}
@@ -8,12 +8,21 @@ import (
 	"io"
 
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/audit"
+	"github.com/control-center/serviced/domain/event"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/notification"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicestate"
 	template "github.com/control-center/serviced/domain/servicetemplate"
+	"github.com/control-center/serviced/domain/token"
 	"github.com/control-center/serviced/facade"
+	"github.com/control-center/serviced/zzk/autoscale"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 )
 
 // API is the intermediary between the command-line interface and the dao layer
@@ -28,11 +37,14 @@ type API interface {
 	GetHost(string) (*host.Host, error)
 	AddHost(HostConfig) (*host.Host, error)
 	RemoveHost(string) error
+	SetHostLabels(string, map[string]string) (*host.Host, error)
+	RemoveHostLabels(string, []string) (*host.Host, error)
 
 	// Pools
 	GetResourcePools() ([]*pool.ResourcePool, error)
 	GetResourcePool(string) (*pool.ResourcePool, error)
 	AddResourcePool(PoolConfig) (*pool.ResourcePool, error)
+	UpdateResourcePool(pool.ResourcePool) (*pool.ResourcePool, error)
 	RemoveResourcePool(string) error
 	GetPoolIPs(string) (*facade.PoolIPs, error)
 	AddVirtualIP(pool.VirtualIP) error
@@ -46,8 +58,14 @@ type API interface {
 	AddService(ServiceConfig) (*service.Service, error)
 	RemoveService(RemoveServiceConfig) error
 	UpdateService(io.Reader) (*service.Service, error)
+	UpdateServiceRolling(io.Reader, RolloutConfig) (*service.Service, error)
 	StartService(string) error
 	StopService(string) error
+	RestartService(RestartServiceConfig) error
+	GetServiceRollout(string) (*rollout.Rollout, error)
+	CancelServiceRollout(string) error
+	GetScalingEvents(string) ([]autoscale.Event, error)
+	GetServiceHealth(string) ([]*zkhealth.Status, error)
 	AssignIP(IPConfig) error
 
 	// RunningServices (ServiceStates)
@@ -66,6 +84,10 @@ type API interface {
 	RemoveSnapshot(string) error
 	Commit(string) (string, error)
 	Rollback(string) error
+	ExportSnapshot(string, string) (string, error)
+	ImportSnapshot(string) (string, error)
+	SetSnapshotPolicy(string, servicedefinition.SnapshotPolicy) error
+	GetSnapshotSchedule(string) (*dao.SnapshotSchedule, error)
 
 	// Templates
 	GetServiceTemplates() ([]*template.ServiceTemplate, error)
@@ -74,10 +96,46 @@ type API interface {
 	RemoveServiceTemplate(string) error
 	CompileServiceTemplate(CompileTemplateConfig) (*template.ServiceTemplate, error)
 	DeployServiceTemplate(DeployTemplateConfig) (*service.Service, error)
+	DiffServiceTemplate(string, string, bool) (*service.TemplateDiff, error)
+	ApplyServiceTemplate(string, string, bool) (*service.TemplateDiff, error)
 
 	// Backup & Restore
 	Backup(string) (string, error)
+	IncrementalBackup(string) (string, error)
+	ListBackups(string) ([]dao.BackupInfo, error)
 	Restore(string) error
+	EncryptedBackup(dao.BackupRequest) (string, error)
+	EncryptedRestore(dao.RestoreRequest) error
+	VerifyBackup(dao.RestoreRequest) (*dao.BackupVerification, error)
+	BackupProgress(int) (*dao.BackupProgress, error)
+	RestoreProgress(int) (*dao.BackupProgress, error)
+	CancelBackup() error
+	CancelRestore() error
+
+	// Audit log
+	GetAuditEntries(audit.Filter) ([]*audit.Entry, error)
+
+	// Threshold events
+	GetEvents(event.Filter) ([]*event.Event, error)
+
+	// Notification sinks
+	AddNotificationSink(notification.Sink) (string, error)
+	GetNotificationSinks() ([]*notification.Sink, error)
+	RemoveNotificationSink(string) error
+	TestNotificationSink(string) error
+
+	// Users
+	GetUserRoles() ([]*role.UserRoles, error)
+	GrantRole(string, role.Grant) error
+	RevokeRole(string, role.Grant) error
+	RemoveUserRoles(string) error
+	CreateToken(TokenConfig) (string, error)
+	GetTokens(string) ([]*token.Token, error)
+	RevokeToken(string) error
+
+	// Certificates
+	RotateCertificates(bool) (int, error)
+	CreateJoinToken(string) (string, error)
 
 	// Docker
 	Squash(imageName, downToLayer, newName, tempDir string) (string, error)
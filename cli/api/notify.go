@@ -0,0 +1,49 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// AddNotificationSink configures a notification sink and returns its id
+func (a *api) AddNotificationSink(sink notification.Sink) (string, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return "", err
+	}
+
+	return client.AddNotificationSink(sink)
+}
+
+// GetNotificationSinks returns the notification sinks, without their secrets
+func (a *api) GetNotificationSinks() ([]*notification.Sink, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetNotificationSinks()
+}
+
+// RemoveNotificationSink removes a notification sink
+func (a *api) RemoveNotificationSink(id string) error {
+	client, err := a.connectMaster()
+	if err != nil {
+		return err
+	}
+
+	return client.RemoveNotificationSink(id)
+}
+
+// TestNotificationSink sends a test notification to a sink
+func (a *api) TestNotificationSink(id string) error {
+	client, err := a.connectMaster()
+	if err != nil {
+		return err
+	}
+
+	return client.TestNotificationSink(id)
+}
@@ -15,10 +15,14 @@ var ()
 
 // PoolConfig is the deserialized data from the command-line
 type PoolConfig struct {
-	PoolID      string
-	CoreLimit   int
-	MemoryLimit uint64
-	Priority    int
+	PoolID           string
+	CoreLimit        int
+	MemoryLimit      uint64
+	Priority         int
+	MemoryWeight     float64
+	CoreWeight       float64
+	MemoryOvercommit float64
+	CoreOvercommit   float64
 }
 
 // Returns a list of all pools
@@ -49,10 +53,14 @@ func (a *api) AddResourcePool(config PoolConfig) (*pool.ResourcePool, error) {
 	}
 
 	p := pool.ResourcePool{
-		ID:          config.PoolID,
-		CoreLimit:   config.CoreLimit,
-		MemoryLimit: config.MemoryLimit,
-		Priority:    config.Priority,
+		ID:               config.PoolID,
+		CoreLimit:        config.CoreLimit,
+		MemoryLimit:      config.MemoryLimit,
+		Priority:         config.Priority,
+		MemoryWeight:     config.MemoryWeight,
+		CoreWeight:       config.CoreWeight,
+		MemoryOvercommit: config.MemoryOvercommit,
+		CoreOvercommit:   config.CoreOvercommit,
 	}
 
 	if err := client.AddResourcePool(p); err != nil {
@@ -62,6 +70,20 @@ func (a *api) AddResourcePool(config PoolConfig) (*pool.ResourcePool, error) {
 	return a.GetResourcePool(p.ID)
 }
 
+// Updates an existing pool
+func (a *api) UpdateResourcePool(p pool.ResourcePool) (*pool.ResourcePool, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.UpdateResourcePool(p); err != nil {
+		return nil, err
+	}
+
+	return a.GetResourcePool(p.ID)
+}
+
 // Removes an existing pool
 func (a *api) RemoveResourcePool(id string) error {
 	client, err := a.connectMaster()
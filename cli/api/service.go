@@ -8,11 +8,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/zzk/autoscale"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 )
 
 const ()
@@ -35,6 +39,20 @@ type RemoveServiceConfig struct {
 	RemoveSnapshots bool
 }
 
+// RestartServiceConfig is the deserialized object from the command-line
+type RestartServiceConfig struct {
+	ServiceID string
+	Rolling   bool
+	BatchSize int
+	Timeout   time.Duration
+}
+
+// RolloutConfig is how a rolling update restarts the instances of a service
+type RolloutConfig struct {
+	BatchSize int
+	Timeout   time.Duration
+}
+
 // IPConfig is the deserialized object from the command-line
 type IPConfig struct {
 	ServiceID string
@@ -191,6 +209,34 @@ func (a *api) UpdateService(reader io.Reader) (*service.Service, error) {
 	return a.GetService(s.ID)
 }
 
+// UpdateServiceRolling updates an existing service and restarts its instances
+// a batch at a time
+func (a *api) UpdateServiceRolling(reader io.Reader, config RolloutConfig) (*service.Service, error) {
+	// Unmarshal JSON from the reader
+	var s service.Service
+	if err := json.NewDecoder(reader).Decode(&s); err != nil {
+		return nil, fmt.Errorf("could not unmarshal json: %s", err)
+	}
+
+	// Connect to the client
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the service
+	request := dao.ServiceUpdateRequest{
+		Service:   s,
+		BatchSize: config.BatchSize,
+		Timeout:   config.Timeout,
+	}
+	if err := client.UpdateServiceRolling(request, &unusedInt); err != nil {
+		return nil, err
+	}
+
+	return a.GetService(s.ID)
+}
+
 // StartService starts a service
 func (a *api) StartService(id string) error {
 	client, err := a.connectDAO()
@@ -220,6 +266,86 @@ func (a *api) StopService(id string) error {
 	return nil
 }
 
+// RestartService restarts the instances of a service
+func (a *api) RestartService(config RestartServiceConfig) error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	request := dao.ServiceRestartRequest{
+		ServiceID: config.ServiceID,
+		Rolling:   config.Rolling,
+		BatchSize: config.BatchSize,
+		Timeout:   config.Timeout,
+	}
+	if err := client.RestartService(request, &unusedInt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetServiceRollout gets the progress of the rolling restart of a service
+func (a *api) GetServiceRollout(id string) (*rollout.Rollout, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var r rollout.Rollout
+	if err := client.GetServiceRollout(id, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// CancelServiceRollout cancels the rolling restart of a service
+func (a *api) CancelServiceRollout(id string) error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	if err := client.CancelServiceRollout(id, &unusedInt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetScalingEvents gets the decisions of the autoscaler for a service
+func (a *api) GetScalingEvents(id string) ([]autoscale.Event, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []autoscale.Event
+	if err := client.GetScalingEvents(id, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetServiceHealth gets the status of the health checks of each instance of a
+// service
+func (a *api) GetServiceHealth(id string) ([]*zkhealth.Status, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*zkhealth.Status
+	if err := client.GetServiceHealth(id, &statuses); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
 // AssignIP assigns an IP address to a service
 func (a *api) AssignIP(config IPConfig) error {
 	client, err := a.connectDAO()
@@ -6,6 +6,10 @@ package api
 
 import (
 	"fmt"
+	"path/filepath"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/servicedefinition"
 )
 
 const ()
@@ -103,3 +107,77 @@ func (a *api) Rollback(snapshotID string) error {
 
 	return nil
 }
+
+// ExportSnapshot writes a snapshot, with the services and images of its
+// tenant, to a tgz file
+func (a *api) ExportSnapshot(snapshotID, path string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	fp, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("could not convert '%s' to an absolute file path: %v", path, err)
+	}
+
+	request := dao.SnapshotExportRequest{SnapshotID: snapshotID, FilePath: filepath.Clean(fp)}
+	if err := client.ExportSnapshot(request, &unusedInt); err != nil {
+		return "", err
+	}
+
+	return request.FilePath, nil
+}
+
+// ImportSnapshot adds a snapshot from a tgz file written by ExportSnapshot
+// and rolls its tenant back to it
+func (a *api) ImportSnapshot(path string) (string, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return "", err
+	}
+
+	fp, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("could not convert '%s' to an absolute file path: %v", path, err)
+	}
+
+	var snapshotID string
+	if err := client.ImportSnapshot(filepath.Clean(fp), &snapshotID); err != nil {
+		return "", err
+	}
+
+	return snapshotID, nil
+}
+
+// SetSnapshotPolicy sets the snapshot schedule and retention rules of a
+// tenant
+func (a *api) SetSnapshotPolicy(tenantID string, policy servicedefinition.SnapshotPolicy) error {
+	client, err := a.connectDAO()
+	if err != nil {
+		return err
+	}
+
+	request := dao.SnapshotPolicyRequest{TenantID: tenantID, Policy: policy}
+	if err := client.SetSnapshotPolicy(request, &unusedInt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSnapshotSchedule gets the snapshot policy of the tenant of a service and
+// the runs of its schedule
+func (a *api) GetSnapshotSchedule(serviceID string) (*dao.SnapshotSchedule, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule dao.SnapshotSchedule
+	if err := client.GetSnapshotSchedule(serviceID, &schedule); err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
@@ -159,3 +159,35 @@ func (a *api) DeployServiceTemplate(config DeployTemplateConfig) (*service.Servi
 
 	return s, nil
 }
+
+// DiffServiceTemplate compares a deployed tenant with a template given its
+// template ID
+func (a *api) DiffServiceTemplate(tenantID, templateID string, overwriteContext bool) (*service.TemplateDiff, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	req := dao.ServiceTemplateApplyRequest{TenantID: tenantID, TemplateID: templateID, OverwriteContext: overwriteContext}
+	var diff service.TemplateDiff
+	if err := client.DiffTemplate(req, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// ApplyServiceTemplate updates a deployed tenant to a template given its
+// template ID
+func (a *api) ApplyServiceTemplate(tenantID, templateID string, overwriteContext bool) (*service.TemplateDiff, error) {
+	client, err := a.connectDAO()
+	if err != nil {
+		return nil, err
+	}
+
+	req := dao.ServiceTemplateApplyRequest{TenantID: tenantID, TemplateID: templateID, OverwriteContext: overwriteContext}
+	var diff service.TemplateDiff
+	if err := client.ApplyTemplate(req, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
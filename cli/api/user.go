@@ -0,0 +1,100 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"time"
+
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/token"
+	"github.com/control-center/serviced/rpc/master"
+)
+
+// GetUserRoles returns the roles of every user that has been granted any
+func (a *api) GetUserRoles() ([]*role.UserRoles, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.GetUserRoles()
+}
+
+// GrantRole grants a role to a user
+func (a *api) GrantRole(user string, grant role.Grant) error {
+	client, err := a.connectMaster()
+	if err != nil {
+		return err
+	}
+
+	return client.GrantRole(user, grant)
+}
+
+// RevokeRole revokes a role from a user
+func (a *api) RevokeRole(user string, grant role.Grant) error {
+	client, err := a.connectMaster()
+	if err != nil {
+		return err
+	}
+
+	return client.RevokeRole(user, grant)
+}
+
+// RemoveUserRoles removes all of the roles of a user
+func (a *api) RemoveUserRoles(user string) error {
+	client, err := a.connectMaster()
+	if err != nil {
+		return err
+	}
+
+	return client.RemoveUserRoles(user)
+}
+
+// TokenConfig is the deserialized data from the command-line
+type TokenConfig struct {
+	User        string
+	Description string
+	Grants      []role.Grant
+	TTL         time.Duration
+}
+
+// CreateToken creates an API token and returns its bearer value
+func (a *api) CreateToken(config TokenConfig) (string, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return "", err
+	}
+
+	return client.CreateToken(master.TokenRequest{
+		User:        config.User,
+		Description: config.Description,
+		Grants:      config.Grants,
+		TTL:         config.TTL,
+	})
+}
+
+// GetTokens returns the API tokens of a user, or of the current user if the
+// user is empty
+func (a *api) GetTokens(user string) ([]*token.Token, error) {
+	client, err := a.connectMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	if user == "" {
+		user = caller().User
+	}
+	return client.GetTokens(user)
+}
+
+// RevokeToken revokes an API token
+func (a *api) RevokeToken(id string) error {
+	client, err := a.connectMaster()
+	if err != nil {
+		return err
+	}
+
+	return client.RevokeToken(id)
+}
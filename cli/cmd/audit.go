@@ -0,0 +1,81 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/domain/audit"
+)
+
+// initAudit is the initializer for serviced audit
+func (c *ServicedCli) initAudit() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "audit",
+		Usage:       "Reviews the changes made to the control plane",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:        "list",
+				Usage:       "Lists the audit log, newest first",
+				Description: "serviced audit list",
+				Action:      c.cmdAuditList,
+				Flags: []cli.Flag{
+					cli.StringFlag{"type", "", "Only show changes to this type of entity (service, host, pool, template, snapshot, backup)"},
+					cli.StringFlag{"id", "", "Only show changes to the entity with this ID"},
+					cli.StringFlag{"user", "", "Only show changes made by this user"},
+					cli.StringFlag{"since", "", "Only show changes made within this long, such as 24h"},
+					cli.IntFlag{"limit", audit.DefaultLimit, "Maximum number of entries to show"},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			},
+		},
+	})
+}
+
+// serviced audit list [--type TYPE] [--id ID] [--user USER] [--since DURATION] [--limit N]
+func (c *ServicedCli) cmdAuditList(ctx *cli.Context) {
+	filter := audit.Filter{
+		EntityType: ctx.String("type"),
+		EntityID:   ctx.String("id"),
+		User:       ctx.String("user"),
+		Limit:      ctx.Int("limit"),
+	}
+	if since := ctx.String("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			fmt.Fprintln(os.Stderr, "since must be a positive duration, such as 90m or 24h")
+			return
+		}
+		filter.Since = time.Now().Add(-d)
+	}
+	if filter.Limit < 1 {
+		fmt.Fprintln(os.Stderr, "limit must be at least 1")
+		return
+	}
+
+	if entries, err := c.driver.GetAuditEntries(filter); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if entries == nil || len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "no audit entries found")
+	} else if ctx.Bool("verbose") {
+		if jsonEntries, err := json.MarshalIndent(entries, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal audit entries: %s\n", err)
+		} else {
+			fmt.Println(string(jsonEntries))
+		}
+	} else {
+		tableEntries := newtable(0, 8, 2)
+		tableEntries.printrow("TIME", "USER", "SOURCE", "ACTION", "TYPE", "ID")
+		for _, e := range entries {
+			tableEntries.printrow(e.Timestamp.Local().Format(time.RFC3339), e.User, e.Source, e.Action, e.EntityType, e.EntityID)
+		}
+		tableEntries.flush()
+	}
+}
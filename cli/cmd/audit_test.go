@@ -0,0 +1,103 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/audit"
+)
+
+var DefaultAuditAPITest = AuditAPITest{entries: DefaultTestAuditEntries}
+
+var DefaultTestAuditEntries = []*audit.Entry{
+	{
+		ID:         "entry-2",
+		Timestamp:  time.Date(2014, 8, 1, 12, 30, 0, 0, time.UTC),
+		User:       "bob",
+		Source:     "REST",
+		Action:     "update",
+		EntityType: "service",
+		EntityID:   "test-service-1",
+		Diff:       "Instances: 1 -> 2",
+	}, {
+		ID:         "entry-1",
+		Timestamp:  time.Date(2014, 8, 1, 12, 0, 0, 0, time.UTC),
+		User:       "alice",
+		Source:     "CLI",
+		Action:     "add",
+		EntityType: "pool",
+		EntityID:   "test-pool",
+	},
+}
+
+var ErrInvalidAuditFilter = errors.New("invalid audit filter")
+
+type AuditAPITest struct {
+	api.API
+	entries []*audit.Entry
+}
+
+func InitAuditAPITest(args ...string) {
+	New(DefaultAuditAPITest).Run(args)
+}
+
+func (t AuditAPITest) GetAuditEntries(filter audit.Filter) ([]*audit.Entry, error) {
+	if filter.EntityType == "invalid" {
+		return nil, ErrInvalidAuditFilter
+	}
+
+	var entries []*audit.Entry
+	for _, e := range t.entries {
+		if (filter.EntityType == "" || filter.EntityType == e.EntityType) &&
+			(filter.EntityID == "" || filter.EntityID == e.EntityID) &&
+			(filter.User == "" || filter.User == e.User) &&
+			len(entries) < filter.Limit {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func ExampleServicedCLI_CmdAuditList() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitAuditAPITest("serviced", "audit", "list")
+}
+
+func TestServicedCLI_CmdAuditList_verbose(t *testing.T) {
+	var actual []*audit.Entry
+	output := pipe(InitAuditAPITest, "serviced", "audit", "list", "--user", "alice", "--verbose")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshalling resource: %s", err)
+	}
+
+	expected := DefaultTestAuditEntries[1:]
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\ngot:\n%+v\nwant:\n%+v", actual, expected)
+	}
+}
+
+func ExampleServicedCLI_CmdAuditList_fail() {
+	pipeStderr(InitAuditAPITest, "serviced", "audit", "list", "--since", "yesterday")
+	pipeStderr(InitAuditAPITest, "serviced", "audit", "list", "--limit", "0")
+	pipeStderr(InitAuditAPITest, "serviced", "audit", "list", "--type", "invalid")
+
+	// Output:
+	// since must be a positive duration, such as 90m or 24h
+	// limit must be at least 1
+	// invalid audit filter
+}
+
+func ExampleServicedCLI_CmdAuditList_err() {
+	pipeStderr(InitAuditAPITest, "serviced", "audit", "list", "--type", "host")
+
+	// Output:
+	// no audit entries found
+}
@@ -5,10 +5,16 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/dao"
 )
 
 // Initializer for serviced backup and serviced restore
@@ -18,28 +24,102 @@ func (c *ServicedCli) initBackup() {
 		cli.Command{
 			Name:        "backup",
 			Usage:       "Dump all templates and services to a tgz file",
-			Description: "serviced backup DIRPATH",
+			Description: "serviced backup [--incremental] [--passphrase-file FILE | --public-key FILE] DIRPATH | serviced backup list [DIRPATH] | serviced backup verify [--passphrase-file FILE | --private-key FILE] FILEPATH | serviced backup progress | serviced backup cancel",
 			Action:      c.cmdBackup,
+			Flags: []cli.Flag{
+				cli.BoolFlag{"incremental", "Only dump what changed since the newest backup in DIRPATH"},
+				cli.StringFlag{"passphrase-file", "", "File with the passphrase to encrypt the backup with, or to decrypt it with to verify it"},
+				cli.StringFlag{"public-key", "", "PEM file with the RSA public key to encrypt the backup with"},
+				cli.StringFlag{"private-key", "", "PEM file with the RSA private key to decrypt the backup with to verify it"},
+			},
 		},
 		cli.Command{
 			Name:        "restore",
 			Usage:       "Restore templates and services from a tgz file",
-			Description: "serviced restore FILEPATH",
+			Description: "serviced restore [--passphrase-file FILE | --private-key FILE] FILEPATH | serviced restore progress | serviced restore cancel",
 			Action:      c.cmdRestore,
+			Flags: []cli.Flag{
+				cli.StringFlag{"passphrase-file", "", "File with the passphrase to decrypt an encrypted backup with"},
+				cli.StringFlag{"private-key", "", "PEM file with the RSA private key to decrypt an encrypted backup with"},
+			},
 		},
 	)
 }
 
-// serviced backup DIRPATH
+// backupKey reads the passphrase and the keys in the files named by the
+// flags of a backup or restore
+func backupKey(passphraseFile, publicKeyFile, privateKeyFile string) (dao.BackupKey, error) {
+	var key dao.BackupKey
+	if filename := passphraseFile; filename != "" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return key, err
+		}
+		if key.Passphrase = strings.TrimRight(string(data), "\r\n"); key.Passphrase == "" {
+			return key, fmt.Errorf("passphrase file %s is empty", filename)
+		}
+	}
+	if filename := publicKeyFile; filename != "" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return key, err
+		}
+		key.PublicKey = string(data)
+	}
+	if filename := privateKeyFile; filename != "" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return key, err
+		}
+		key.PrivateKey = string(data)
+	}
+	return key, nil
+}
+
+// serviced backup [--incremental] [--passphrase-file FILE | --public-key FILE] DIRPATH
 func (c *ServicedCli) cmdBackup(ctx *cli.Context) {
 	args := ctx.Args()
-	if len(args) < 1 {
+	if len(args) > 0 && args[0] == "list" {
+		c.cmdBackupList(ctx)
+		return
+	} else if len(args) > 0 && args[0] == "verify" {
+		c.cmdBackupVerify(ctx)
+		return
+	} else if len(args) == 1 && args[0] == "progress" {
+		followProgress(c.driver.BackupProgress)
+		return
+	} else if len(args) == 1 && args[0] == "cancel" {
+		if err := c.driver.CancelBackup(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
+	} else if len(args) < 1 {
 		fmt.Printf("Incorrect Usage.\n\n")
 		cli.ShowCommandHelp(ctx, "backup")
 		return
 	}
 
-	if path, err := c.driver.Backup(args[0]); err != nil {
+	key, err := backupKey(ctx.String("passphrase-file"), ctx.String("public-key"), ctx.String("private-key"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if key.PrivateKey != "" {
+		fmt.Fprintln(os.Stderr, "a backup is encrypted with a passphrase or a public key, not a private key")
+		return
+	}
+
+	var path string
+	err = runWithProgress(func() error {
+		if key.Passphrase != "" || key.PublicKey != "" {
+			path, err = c.driver.EncryptedBackup(dao.BackupRequest{Dirpath: args[0], Incremental: ctx.Bool("incremental"), Key: key})
+		} else if ctx.Bool("incremental") {
+			path, err = c.driver.IncrementalBackup(args[0])
+		} else {
+			path, err = c.driver.Backup(args[0])
+		}
+		return err
+	}, c.driver.BackupProgress, c.driver.CancelBackup)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	} else if path == "" {
 		fmt.Fprintln(os.Stderr, "received nil path to backup file")
@@ -48,17 +128,209 @@ func (c *ServicedCli) cmdBackup(ctx *cli.Context) {
 	}
 }
 
-// serviced restore FILEPATH
+// serviced backup list [DIRPATH]
+func (c *ServicedCli) cmdBackupList(ctx *cli.Context) {
+	var dirpath string
+	if args := ctx.Args(); len(args) > 1 {
+		dirpath = args[1]
+	}
+
+	if backups, err := c.driver.ListBackups(dirpath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if backups == nil || len(backups) == 0 {
+		fmt.Fprintln(os.Stderr, "no backups found")
+	} else {
+		// incremental backups are shown below the backups they need
+		rows := make(map[string]dao.BackupInfo)
+		for _, b := range backups {
+			rows[b.Name] = b
+		}
+		childMap := make(map[string][]string)
+		for _, b := range backups {
+			parent := b.Parent
+			if _, ok := rows[parent]; !ok {
+				parent = ""
+			}
+			childMap[parent] = append(childMap[parent], b.Name)
+		}
+		tableBackups := newtable(0, 8, 2)
+		tableBackups.printrow("NAME", "CREATED", "SIZE")
+		tableBackups.formattree(childMap, "", func(name string) (row []interface{}) {
+			b := rows[name]
+			return append(row, b.Name, b.Created.Local().Format(time.RFC3339), b.Size)
+		}, func(row []interface{}) string {
+			return row[0].(string)
+		})
+		tableBackups.flush()
+	}
+}
+
+// serviced backup verify [--passphrase-file FILE | --private-key FILE] FILEPATH
+func (c *ServicedCli) cmdBackupVerify(ctx *cli.Context) {
+	// the flags may come after verify too
+	set := flag.NewFlagSet("verify", flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	passphraseFile := set.String("passphrase-file", ctx.String("passphrase-file"), "")
+	privateKeyFile := set.String("private-key", ctx.String("private-key"), "")
+	if err := set.Parse(ctx.Args()[1:]); err != nil || set.NArg() < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "backup")
+		return
+	}
+	filename := set.Arg(0)
+
+	key, err := backupKey(*passphraseFile, "", *privateKeyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	v, err := c.driver.VerifyBackup(dao.RestoreRequest{FilePath: filename, Key: key})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if len(v.Errors) > 0 {
+		for _, e := range v.Errors {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, e)
+		}
+		fmt.Fprintf(os.Stderr, "%s: FAILED\n", filename)
+		return
+	}
+	var notes []string
+	if v.Encrypted {
+		notes = append(notes, "encrypted")
+	}
+	if v.Checksums {
+		notes = append(notes, fmt.Sprintf("%d files checked", v.Files))
+	} else {
+		notes = append(notes, "no checksums, only read to its end")
+	}
+	fmt.Printf("%s: OK (%s)\n", filename, strings.Join(notes, ", "))
+}
+
+// serviced restore [--passphrase-file FILE | --private-key FILE] FILEPATH
 func (c *ServicedCli) cmdRestore(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 1 {
 		fmt.Printf("Incorrect Usage.\n\n")
 		cli.ShowCommandHelp(ctx, "restore")
 		return
+	} else if len(args) == 1 && args[0] == "progress" {
+		followProgress(c.driver.RestoreProgress)
+		return
+	} else if len(args) == 1 && args[0] == "cancel" {
+		if err := c.driver.CancelRestore(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
 	}
 
-	err := c.driver.Restore(args[0])
+	key, err := backupKey(ctx.String("passphrase-file"), "", ctx.String("private-key"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	err = runWithProgress(func() error {
+		if key.Passphrase != "" || key.PrivateKey != "" {
+			return c.driver.EncryptedRestore(dao.RestoreRequest{FilePath: args[0], Key: key})
+		}
+		return c.driver.Restore(args[0])
+	}, c.driver.RestoreProgress, c.driver.CancelRestore)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	}
 }
+
+// progressGetter gets the progress of a backup or restore once it changed
+// since a sequence number
+type progressGetter func(since int) (*dao.BackupProgress, error)
+
+// formatProgress describes the progress of a backup or restore on one line
+func formatProgress(p *dao.BackupProgress) string {
+	if p.Err != "" {
+		return fmt.Sprintf("%s failed: %s", p.Operation, p.Err)
+	}
+	line := p.Message
+	if p.Steps > 0 {
+		line = fmt.Sprintf("[%d/%d] %s", p.StepsDone, p.Steps, line)
+	}
+	if p.BytesTotal > 0 {
+		line += fmt.Sprintf(" (%d of %d bytes)", p.BytesDone, p.BytesTotal)
+	} else if p.BytesDone > 0 {
+		line += fmt.Sprintf(" (%d bytes)", p.BytesDone)
+	}
+	if p.Running && !p.ETA.IsZero() {
+		if left := p.ETA.Sub(time.Now()); left > 0 {
+			line += fmt.Sprintf(", about %s left", left-left%time.Second)
+		}
+	}
+	return line
+}
+
+// followProgress prints the progress of the backup or restore in flight, or of
+// the last one, until it is done
+func followProgress(get progressGetter) {
+	since := -1
+	for {
+		p, err := get(since)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		if p.Sequence != since {
+			fmt.Println(formatProgress(p))
+		}
+		if !p.Running {
+			return
+		}
+		since = p.Sequence
+	}
+}
+
+// runWithProgress runs a backup or restore, printing its progress to stderr
+// while it runs. An interrupt cancels it; a second one stops the command.
+func runWithProgress(run func() error, get progressGetter, cancel func() error) error {
+	// the changes that came before this run are not printed
+	p, err := get(-1)
+	if err != nil {
+		// the server cannot report the progress
+		return run()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func(since int) {
+		for {
+			p, err := get(since)
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err != nil {
+				return
+			} else if p.Running && p.Sequence != since {
+				fmt.Fprintln(os.Stderr, formatProgress(p))
+			}
+			since = p.Sequence
+		}
+	}(p.Sequence)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			signal.Stop(interrupt)
+			fmt.Fprintln(os.Stderr, "Canceling; interrupt again to stop waiting")
+			if err := cancel(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		case <-done:
+		}
+	}()
+
+	return run()
+}
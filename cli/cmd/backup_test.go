@@ -7,9 +7,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
+	"time"
 
 	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/dao"
 )
 
 const (
@@ -22,6 +26,8 @@ var DefaultBackupAPITest = BackupAPITest{}
 var (
 	ErrBackupFailed  = errors.New("backup failed")
 	ErrRestoreFailed = errors.New("restore failed")
+	ErrNoRestore     = errors.New("no restore in progress")
+	ErrWrongKey      = errors.New("the passphrase of the backup is wrong")
 )
 
 type BackupAPITest struct {
@@ -32,6 +38,17 @@ func InitBackupAPITest(args ...string) {
 	New(DefaultBackupAPITest).Run(args)
 }
 
+// writePassphraseFile writes a passphrase to a temporary file
+func writePassphraseFile(passphrase string) string {
+	file, err := ioutil.TempFile("", "passphrase")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	file.WriteString(passphrase)
+	return file.Name()
+}
+
 func (t BackupAPITest) Backup(dirpath string) (string, error) {
 	switch dirpath {
 	case PathNotFound:
@@ -43,6 +60,29 @@ func (t BackupAPITest) Backup(dirpath string) (string, error) {
 	}
 }
 
+func (t BackupAPITest) IncrementalBackup(dirpath string) (string, error) {
+	if p, err := t.Backup(dirpath); err != nil || p == "" {
+		return p, err
+	}
+	return fmt.Sprintf("%s-incremental.tgz", path.Base(dirpath)), nil
+}
+
+func (t BackupAPITest) ListBackups(dirpath string) ([]dao.BackupInfo, error) {
+	switch dirpath {
+	case PathNotFound:
+		return nil, ErrBackupFailed
+	case NilPath:
+		return nil, nil
+	default:
+		created := time.Date(2014, 10, 1, 0, 0, 0, 0, time.UTC)
+		return []dao.BackupInfo{
+			{Name: "backup-2014-10-01-000000.tgz", Created: created, Size: 1000},
+			{Name: "backup-2014-10-02-000000.tgz", Parent: "backup-2014-10-01-000000.tgz", Created: created.AddDate(0, 0, 1), Size: 100},
+			{Name: "backup-2014-10-03-000000.tgz", Created: created.AddDate(0, 0, 2), Size: 1000},
+		}, nil
+	}
+}
+
 func (t BackupAPITest) Restore(path string) error {
 	switch path {
 	case PathNotFound:
@@ -52,6 +92,64 @@ func (t BackupAPITest) Restore(path string) error {
 	}
 }
 
+func (t BackupAPITest) EncryptedBackup(request dao.BackupRequest) (string, error) {
+	p, err := t.Backup(request.Dirpath)
+	if err != nil || p == "" {
+		return p, err
+	}
+	return p + ".enc", nil
+}
+
+func (t BackupAPITest) EncryptedRestore(request dao.RestoreRequest) error {
+	if request.Key.Passphrase != "secret" {
+		return ErrWrongKey
+	}
+	return t.Restore(request.FilePath)
+}
+
+func (t BackupAPITest) VerifyBackup(request dao.RestoreRequest) (*dao.BackupVerification, error) {
+	switch request.FilePath {
+	case PathNotFound:
+		return nil, ErrBackupFailed
+	case "old.tgz":
+		return &dao.BackupVerification{FilePath: request.FilePath}, nil
+	case "corrupt.tgz":
+		return &dao.BackupVerification{FilePath: request.FilePath, Checksums: true, Files: 2, Errors: []string{"checksum mismatch on templates.json", "missing file services.json"}}, nil
+	case "backup.tgz.enc":
+		if request.Key.Passphrase != "secret" {
+			return nil, ErrWrongKey
+		}
+		return &dao.BackupVerification{FilePath: request.FilePath, Encrypted: true, Checksums: true, Files: 3}, nil
+	default:
+		return &dao.BackupVerification{FilePath: request.FilePath, Checksums: true, Files: 3}, nil
+	}
+}
+
+func (t BackupAPITest) BackupProgress(since int) (*dao.BackupProgress, error) {
+	if since < 0 {
+		return &dao.BackupProgress{Operation: "backup", Sequence: 1, Phase: "images", Message: "Exporting docker image: abc", Image: "abc", StepsDone: 1, Steps: 3, BytesDone: 100, Running: true}, nil
+	} else if since > 1 {
+		// nothing changes until the server times out
+		time.Sleep(10 * time.Millisecond)
+	}
+	return &dao.BackupProgress{Operation: "backup", Sequence: 2, Phase: "archive", Message: "Finished backup", StepsDone: 3, Steps: 3, BytesDone: 300}, nil
+}
+
+func (t BackupAPITest) RestoreProgress(since int) (*dao.BackupProgress, error) {
+	if since >= 4 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return &dao.BackupProgress{Operation: "restore", Sequence: 4, Phase: "images", Message: "Restoring Docker image: abc", Err: "image abc not found"}, nil
+}
+
+func (t BackupAPITest) CancelBackup() error {
+	return nil
+}
+
+func (t BackupAPITest) CancelRestore() error {
+	return ErrNoRestore
+}
+
 func ExampleServicedCli_cmdBackup() {
 	// Invalid path
 	InitBackupAPITest("serviced", "backup", PathNotFound)
@@ -77,9 +175,96 @@ func ExampleServicedCLI_CmdBackup_usage() {
 	//    command backup [command options] [arguments...]
 	//
 	// DESCRIPTION:
-	//    serviced backup DIRPATH
+	//    serviced backup [--incremental] [--passphrase-file FILE | --public-key FILE] DIRPATH | serviced backup list [DIRPATH] | serviced backup verify [--passphrase-file FILE | --private-key FILE] FILEPATH | serviced backup progress | serviced backup cancel
 	//
 	// OPTIONS:
+	//    --incremental	Only dump what changed since the newest backup in DIRPATH
+	//    --passphrase-file 	File with the passphrase to encrypt the backup with, or to decrypt it with to verify it
+	//    --public-key 	PEM file with the RSA public key to encrypt the backup with
+	//    --private-key 	PEM file with the RSA private key to decrypt the backup with to verify it
+}
+
+func ExampleServicedCli_cmdBackup_incremental() {
+	// Invalid path
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "--incremental", PathNotFound)
+	// Success
+	InitBackupAPITest("serviced", "backup", "--incremental", "path/to/dir")
+
+	// Output:
+	// backup failed
+	// dir-incremental.tgz
+}
+
+func ExampleServicedCli_cmdBackupList() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitBackupAPITest("serviced", "backup", "list", "path/to/dir")
+}
+
+func ExampleServicedCli_cmdBackupList_err() {
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "list", PathNotFound)
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "list", NilPath)
+
+	// Output:
+	// backup failed
+	// no backups found
+}
+
+func ExampleServicedCli_cmdBackup_encrypted() {
+	passphraseFile := writePassphraseFile("secret\n")
+	defer os.Remove(passphraseFile)
+
+	// Passphrase file not found
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "--passphrase-file", "/no/such/file", "path/to/dir")
+	// Private keys decrypt backups
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "--private-key", passphraseFile, "path/to/dir")
+	// Success
+	InitBackupAPITest("serviced", "backup", "--passphrase-file", passphraseFile, "path/to/dir")
+
+	// Output:
+	// open /no/such/file: no such file or directory
+	// a backup is encrypted with a passphrase or a public key, not a private key
+	// dir.tgz.enc
+}
+
+func ExampleServicedCli_cmdBackupVerify() {
+	passphraseFile := writePassphraseFile("secret\n")
+	defer os.Remove(passphraseFile)
+
+	InitBackupAPITest("serviced", "backup", "verify", "backup.tgz")
+	InitBackupAPITest("serviced", "backup", "verify", "old.tgz")
+	InitBackupAPITest("serviced", "backup", "verify", "--passphrase-file", passphraseFile, "backup.tgz.enc")
+
+	// Output:
+	// backup.tgz: OK (3 files checked)
+	// old.tgz: OK (no checksums, only read to its end)
+	// backup.tgz.enc: OK (encrypted, 3 files checked)
+}
+
+func ExampleServicedCli_cmdBackupVerify_err() {
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "verify", PathNotFound)
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "verify", "corrupt.tgz")
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "verify", "backup.tgz.enc")
+
+	// Output:
+	// backup failed
+	// corrupt.tgz: checksum mismatch on templates.json
+	// corrupt.tgz: missing file services.json
+	// corrupt.tgz: FAILED
+	// the passphrase of the backup is wrong
+}
+
+func ExampleServicedCli_cmdBackupProgress() {
+	InitBackupAPITest("serviced", "backup", "progress")
+
+	// Output:
+	// [1/3] Exporting docker image: abc (100 bytes)
+	// [3/3] Finished backup (300 bytes)
+}
+
+func ExampleServicedCli_cmdBackupCancel() {
+	pipeStderr(InitBackupAPITest, "serviced", "backup", "cancel")
+
+	// Output:
 }
 
 func ExampleServicedCli_cmdRestore() {
@@ -102,7 +287,33 @@ func ExampleServicedCLI_CmdRestore_usage() {
 	//    command restore [command options] [arguments...]
 	//
 	// DESCRIPTION:
-	//    serviced restore FILEPATH
+	//    serviced restore [--passphrase-file FILE | --private-key FILE] FILEPATH | serviced restore progress | serviced restore cancel
 	//
 	// OPTIONS:
+	//    --passphrase-file 	File with the passphrase to decrypt an encrypted backup with
+	//    --private-key 	PEM file with the RSA private key to decrypt an encrypted backup with
+}
+
+func ExampleServicedCli_cmdRestore_encrypted() {
+	passphraseFile := writePassphraseFile("wrong\n")
+	defer os.Remove(passphraseFile)
+
+	pipeStderr(InitBackupAPITest, "serviced", "restore", "--passphrase-file", passphraseFile, "backup.tgz.enc")
+
+	// Output:
+	// the passphrase of the backup is wrong
+}
+
+func ExampleServicedCli_cmdRestoreProgress() {
+	InitBackupAPITest("serviced", "restore", "progress")
+
+	// Output:
+	// restore failed: image abc not found
+}
+
+func ExampleServicedCli_cmdRestoreCancel() {
+	pipeStderr(InitBackupAPITest, "serviced", "restore", "cancel")
+
+	// Output:
+	// no restore in progress
 }
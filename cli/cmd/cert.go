@@ -0,0 +1,70 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/codegangsta/cli"
+)
+
+// initCert is the initializer for serviced cert
+func (c *ServicedCli) initCert() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "cert",
+		Usage:       "Administers the certificates of the hosts",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:        "rotate",
+				Usage:       "Reissues the mux certificates of every host",
+				Description: "serviced cert rotate",
+				Action:      c.cmdCertRotate,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"ca", "Also replace the certificate authority"},
+				},
+			}, {
+				Name:        "join-token",
+				Usage:       "Creates the token a host is issued its first certificate with",
+				Description: "serviced cert join-token HOSTID",
+				Action:      c.cmdCertJoinToken,
+			},
+		},
+	})
+}
+
+// serviced cert rotate [--ca]
+func (c *ServicedCli) cmdCertRotate(ctx *cli.Context) {
+	if len(ctx.Args()) > 0 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "rotate")
+		return
+	}
+
+	generation, err := c.driver.RotateCertificates(ctx.Bool("ca"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(generation)
+}
+
+// serviced cert join-token HOSTID
+func (c *ServicedCli) cmdCertJoinToken(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "join-token")
+		return
+	}
+
+	token, err := c.driver.CreateJoinToken(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Println(token)
+}
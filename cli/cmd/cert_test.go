@@ -0,0 +1,110 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/control-center/serviced/cli/api"
+)
+
+var (
+	ErrRolloutPending = errors.New("a new certificate authority is already being rolled out")
+	ErrNoSuchHost     = errors.New("host not found")
+)
+
+type CertAPITest struct {
+	api.API
+	generation int
+	pending    bool
+}
+
+func InitCertAPITest(args ...string) {
+	New(&CertAPITest{generation: 3}).Run(args)
+}
+
+func (t *CertAPITest) RotateCertificates(newCA bool) (int, error) {
+	if newCA && t.pending {
+		return 0, ErrRolloutPending
+	}
+	t.pending = t.pending || newCA
+	t.generation++
+	return t.generation, nil
+}
+
+func (t *CertAPITest) CreateJoinToken(hostID string) (string, error) {
+	if hostID != "test-host-id-1" {
+		return "", ErrNoSuchHost
+	}
+	return "0123456789abcdef", nil
+}
+
+func ExampleServicedCLI_CmdCertRotate() {
+	InitCertAPITest("serviced", "cert", "rotate")
+	InitCertAPITest("serviced", "cert", "rotate", "--ca")
+
+	// Output:
+	// 4
+	// 4
+}
+
+func ExampleServicedCLI_CmdCertRotate_usage() {
+	InitCertAPITest("serviced", "cert", "rotate", "now")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    rotate - Reissues the mux certificates of every host
+	//
+	// USAGE:
+	//    command rotate [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced cert rotate
+	//
+	// OPTIONS:
+	//    --ca	Also replace the certificate authority
+}
+
+func ExampleServicedCLI_CmdCertRotate_err() {
+	pending := &CertAPITest{generation: 3, pending: true}
+	pipeStderr(func(args ...string) { New(pending).Run(args) }, "serviced", "cert", "rotate", "--ca")
+
+	// Output:
+	// a new certificate authority is already being rolled out
+}
+
+func ExampleServicedCLI_CmdCertJoinToken() {
+	InitCertAPITest("serviced", "cert", "join-token", "test-host-id-1")
+
+	// Output:
+	// 0123456789abcdef
+}
+
+func ExampleServicedCLI_CmdCertJoinToken_usage() {
+	InitCertAPITest("serviced", "cert", "join-token")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    join-token - Creates the token a host is issued its first certificate with
+	//
+	// USAGE:
+	//    command join-token [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced cert join-token HOSTID
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdCertJoinToken_err() {
+	pipeStderr(InitCertAPITest, "serviced", "cert", "join-token", "test-host-id-0")
+
+	// Output:
+	// host not found
+}
@@ -97,16 +97,19 @@ func New(driver api.API) *ServicedCli {
 		cli.IntFlag{"mux", configInt("MUX_PORT", 22250), "multiplexing port"},
 		cli.BoolTFlag{"tls", "enable TLS"},
 		cli.StringFlag{"var", varPath, "path to store serviced data"},
-		cli.StringFlag{"keyfile", configEnv("KEY_FILE", ""), "path to private key file (defaults to compiled in private key)"},
-		cli.StringFlag{"certfile", configEnv("CERT_FILE", ""), "path to public certificate file (defaults to compiled in public cert)"},
+		cli.StringFlag{"keyfile", configEnv("KEY_FILE", ""), "path to private key file the mux presents to clients that are not peers (peers use one issued by the master)"},
+		cli.StringFlag{"certfile", configEnv("CERT_FILE", ""), "path to public certificate file the mux presents to clients that are not peers (peers use one issued by the master)"},
 		cli.StringSliceFlag{"zk", &zks, "Specify a zookeeper instance to connect to (e.g. -zk localhost:2181)"},
 		cli.StringSliceFlag{"mount", &cli.StringSlice{}, "bind mount: DOCKER_IMAGE,HOST_PATH[,CONTAINER_PATH]"},
 		cli.StringFlag{"vfs", "rsync", "filesystem for container volumes"},
 		cli.StringSliceFlag{"alias", &aliases, "list of aliases for this host, e.g., localhost"},
 		cli.IntFlag{"es-startup-timeout", esStartupTimeout, "time to wait on elasticsearch startup before bailing"},
 		cli.IntFlag{"max-container-age", configInt("MAX_CONTAINER_AGE", 60), "maximum age of a stopped container before removing"},
+		cli.IntFlag{"drain-timeout", configInt("DRAIN_TIMEOUT", 30), "seconds a stopping instance keeps its connections before it is stopped"},
 		cli.StringFlag{"virtual-address-subnet", configEnv("VIRTUAL_ADDRESS_SUBNET", "10.3"), "/16 subnet for virtual addresses"},
 		cli.StringFlag{"master-pool-id", configEnv("MASTER_POOLID", "default"), "master's pool ID"},
+		cli.StringFlag{"api-token", configEnv("API_TOKEN", ""), "API token to authenticate with (ID.SECRET); prefer setting SERVICED_API_TOKEN"},
+		cli.StringFlag{"join-token", configEnv("JOIN_TOKEN", ""), "token the agent is issued its first certificate with (see serviced cert join-token)"},
 
 		cli.BoolTFlag{"report-stats", "report container statistics"},
 		cli.StringFlag{"host-stats", "127.0.0.1:8443", "container statistics for host:port"},
@@ -133,6 +136,11 @@ func New(driver api.API) *ServicedCli {
 	c.initSnapshot()
 	c.initLog()
 	c.initBackup()
+	c.initAudit()
+	c.initEvent()
+	c.initNotify()
+	c.initUser()
+	c.initCert()
 	c.initDocker()
 
 	return c
@@ -171,8 +179,11 @@ func (c *ServicedCli) cmdInit(ctx *cli.Context) error {
 		MCPasswd:             ctx.GlobalString("mc-password"),
 		Verbosity:            ctx.GlobalInt("v"),
 		CPUProfile:           ctx.GlobalString("cpuprofile"),
+		DrainTimeout:         ctx.GlobalInt("drain-timeout"),
 		VirtualAddressSubnet: ctx.GlobalString("virtual-address-subnet"),
 		MasterPoolID:         ctx.GlobalString("master-pool-id"),
+		APIToken:             ctx.GlobalString("api-token"),
+		JoinToken:            ctx.GlobalString("join-token"),
 	}
 	if os.Getenv("SERVICED_MASTER") == "1" {
 		options.Master = true
@@ -0,0 +1,87 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/domain/event"
+)
+
+// initEvent is the initializer for serviced event
+func (c *ServicedCli) initEvent() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "event",
+		Usage:       "Reviews the events raised by monitoring thresholds",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:        "list",
+				Usage:       "Lists the threshold events, most recently seen first",
+				Description: "serviced event list",
+				Action:      c.cmdEventList,
+				Flags: []cli.Flag{
+					cli.StringFlag{"type", "", "Only show events of this type of entity (host, pool, service)"},
+					cli.StringFlag{"id", "", "Only show events of the entity with this ID"},
+					cli.StringFlag{"pool", "", "Only show events of the entities in this pool"},
+					cli.BoolFlag{"active", "Only show the events that are not cleared"},
+					cli.StringFlag{"since", "", "Only show events seen within this long, such as 24h"},
+					cli.IntFlag{"limit", event.DefaultLimit, "Maximum number of events to show"},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			},
+		},
+	})
+}
+
+// serviced event list [--type TYPE] [--id ID] [--pool POOLID] [--active] [--since DURATION] [--limit N]
+func (c *ServicedCli) cmdEventList(ctx *cli.Context) {
+	filter := event.Filter{
+		EntityType: ctx.String("type"),
+		EntityID:   ctx.String("id"),
+		PoolID:     ctx.String("pool"),
+		Active:     ctx.Bool("active"),
+		Limit:      ctx.Int("limit"),
+	}
+	if since := ctx.String("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			fmt.Fprintln(os.Stderr, "since must be a positive duration, such as 90m or 24h")
+			return
+		}
+		filter.Since = time.Now().Add(-d)
+	}
+	if filter.Limit < 1 {
+		fmt.Fprintln(os.Stderr, "limit must be at least 1")
+		return
+	}
+
+	if events, err := c.driver.GetEvents(filter); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if events == nil || len(events) == 0 {
+		fmt.Fprintln(os.Stderr, "no events found")
+	} else if ctx.Bool("verbose") {
+		if jsonEvents, err := json.MarshalIndent(events, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal events: %s\n", err)
+		} else {
+			fmt.Println(string(jsonEvents))
+		}
+	} else {
+		tableEvents := newtable(0, 8, 2)
+		tableEvents.printrow("LAST SEEN", "STATE", "SEVERITY", "TYPE", "ID", "THRESHOLD", "COUNT", "SUMMARY")
+		for _, e := range events {
+			state := "active"
+			if !e.Active {
+				state = "cleared"
+			}
+			tableEvents.printrow(e.LastSeen.Local().Format(time.RFC3339), state, e.Severity(), e.EntityType, e.EntityID, e.ThresholdID, e.Count, e.Summary)
+		}
+		tableEvents.flush()
+	}
+}
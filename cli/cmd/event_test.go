@@ -0,0 +1,116 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/event"
+)
+
+var DefaultEventAPITest = EventAPITest{events: DefaultTestEvents}
+
+var DefaultTestEvents = []*event.Event{
+	{
+		ID:          "event-2",
+		EntityType:  event.EntityService,
+		EntityID:    "test-service-1",
+		PoolID:      "default",
+		ThresholdID: "cpu.high",
+		Threshold:   "CPU high",
+		Metric:      "cpu.user",
+		Value:       95,
+		Summary:     "cpu.user was above 90 in 8 of 10 values (80%) over the last 5m0s",
+		Tags:        map[string]interface{}{"Severity": float64(4)},
+		Count:       3,
+		FirstSeen:   time.Date(2014, 8, 1, 12, 28, 0, 0, time.UTC),
+		LastSeen:    time.Date(2014, 8, 1, 12, 30, 0, 0, time.UTC),
+		Active:      true,
+	}, {
+		ID:          "event-1",
+		EntityType:  event.EntityHost,
+		EntityID:    "test-host-1",
+		PoolID:      "default",
+		ThresholdID: "swap.empty",
+		Threshold:   "Swap empty",
+		Metric:      "swap.free",
+		Summary:     "swap.free is -1, below 0",
+		Tags:        map[string]interface{}{"Severity": float64(1)},
+		Count:       1,
+		FirstSeen:   time.Date(2014, 8, 1, 12, 0, 0, 0, time.UTC),
+		LastSeen:    time.Date(2014, 8, 1, 12, 0, 0, 0, time.UTC),
+		Cleared:     time.Date(2014, 8, 1, 12, 1, 0, 0, time.UTC),
+	},
+}
+
+var ErrInvalidEventFilter = errors.New("invalid event filter")
+
+type EventAPITest struct {
+	api.API
+	events []*event.Event
+}
+
+func InitEventAPITest(args ...string) {
+	New(DefaultEventAPITest).Run(args)
+}
+
+func (t EventAPITest) GetEvents(filter event.Filter) ([]*event.Event, error) {
+	if filter.EntityType == "invalid" {
+		return nil, ErrInvalidEventFilter
+	}
+
+	var events []*event.Event
+	for _, e := range t.events {
+		if (filter.EntityType == "" || filter.EntityType == e.EntityType) &&
+			(filter.EntityID == "" || filter.EntityID == e.EntityID) &&
+			(filter.PoolID == "" || filter.PoolID == e.PoolID) &&
+			(!filter.Active || e.Active) &&
+			len(events) < filter.Limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func ExampleServicedCLI_CmdEventList() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitEventAPITest("serviced", "event", "list")
+}
+
+func TestServicedCLI_CmdEventList_verbose(t *testing.T) {
+	var actual []*event.Event
+	output := pipe(InitEventAPITest, "serviced", "event", "list", "--active", "--verbose")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshalling resource: %s", err)
+	}
+
+	expected := DefaultTestEvents[:1]
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\ngot:\n%+v\nwant:\n%+v", actual, expected)
+	}
+}
+
+func ExampleServicedCLI_CmdEventList_fail() {
+	pipeStderr(InitEventAPITest, "serviced", "event", "list", "--since", "yesterday")
+	pipeStderr(InitEventAPITest, "serviced", "event", "list", "--limit", "0")
+	pipeStderr(InitEventAPITest, "serviced", "event", "list", "--type", "invalid")
+
+	// Output:
+	// since must be a positive duration, such as 90m or 24h
+	// limit must be at least 1
+	// invalid event filter
+}
+
+func ExampleServicedCLI_CmdEventList_err() {
+	pipeStderr(InitEventAPITest, "serviced", "event", "list", "--type", "pool")
+
+	// Output:
+	// no events found
+}
@@ -47,6 +47,18 @@ func (c *ServicedCli) initHost() {
 				Description:  "serviced host remove HOSTID ...",
 				BashComplete: c.printHostsAll,
 				Action:       c.cmdHostRemove,
+			}, {
+				Name:         "set-label",
+				Usage:        "Sets labels on an existing host",
+				Description:  "serviced host set-label HOSTID KEY=VALUE ...",
+				BashComplete: c.printHostsFirst,
+				Action:       c.cmdHostSetLabel,
+			}, {
+				Name:         "remove-label",
+				Usage:        "Removes labels from an existing host",
+				Description:  "serviced host remove-label HOSTID KEY ...",
+				BashComplete: c.printHostsFirst,
+				Action:       c.cmdHostRemoveLabel,
 			},
 		},
 	})
@@ -206,3 +218,49 @@ func (c *ServicedCli) cmdHostRemove(ctx *cli.Context) {
 		}
 	}
 }
+
+// serviced host set-label HOSTID KEY=VALUE ...
+func (c *ServicedCli) cmdHostSetLabel(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "set-label")
+		return
+	}
+
+	labels := make(map[string]string)
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			fmt.Fprintf(os.Stderr, "bad format: %s; must be formatted as KEY=VALUE\n", arg)
+			return
+		}
+		labels[strings.TrimSpace(parts[0])] = parts[1]
+	}
+
+	if host, err := c.driver.SetHostLabels(args[0], labels); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if host == nil {
+		fmt.Fprintln(os.Stderr, "received nil host")
+	} else {
+		fmt.Println(host.ID)
+	}
+}
+
+// serviced host remove-label HOSTID KEY ...
+func (c *ServicedCli) cmdHostRemoveLabel(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "remove-label")
+		return
+	}
+
+	if host, err := c.driver.RemoveHostLabels(args[0], args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if host == nil {
+		fmt.Fprintln(os.Stderr, "received nil host")
+	} else {
+		fmt.Println(host.ID)
+	}
+}
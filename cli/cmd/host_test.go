@@ -113,6 +113,32 @@ func (t HostAPITest) RemoveHost(id string) error {
 	return nil
 }
 
+func (t HostAPITest) SetHostLabels(id string, labels map[string]string) (*host.Host, error) {
+	if h, err := t.GetHost(id); err != nil {
+		return nil, err
+	} else if h == nil {
+		return nil, ErrNoHostFound
+	} else {
+		for key, value := range labels {
+			fmt.Printf("%s=%s\n", key, value)
+		}
+		return h, nil
+	}
+}
+
+func (t HostAPITest) RemoveHostLabels(id string, keys []string) (*host.Host, error) {
+	if h, err := t.GetHost(id); err != nil {
+		return nil, err
+	} else if h == nil {
+		return nil, ErrNoHostFound
+	} else {
+		for _, key := range keys {
+			fmt.Println(key)
+		}
+		return h, nil
+	}
+}
+
 func TestServicedCLI_CmdHostList_one(t *testing.T) {
 	hostID := "test-host-id-1"
 
@@ -308,3 +334,73 @@ func ExampleServicedCLI_CmdHostRemove_complete() {
 	// test-host-id-1
 	// test-host-id-3
 }
+
+func ExampleServicedCLI_CmdHostSetLabel() {
+	InitHostAPITest("serviced", "host", "set-label", "test-host-id-1", "disk=ssd")
+
+	// Output:
+	// disk=ssd
+	// test-host-id-1
+}
+
+func ExampleServicedCLI_CmdHostSetLabel_usage() {
+	InitHostAPITest("serviced", "host", "set-label", "test-host-id-1")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    set-label - Sets labels on an existing host
+	//
+	// USAGE:
+	//    command set-label [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced host set-label HOSTID KEY=VALUE ...
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdHostSetLabel_err() {
+	// Bad label
+	pipeStderr(InitHostAPITest, "serviced", "host", "set-label", "test-host-id-1", "disk")
+	// Host not found
+	pipeStderr(InitHostAPITest, "serviced", "host", "set-label", "test-host-id-0", "disk=ssd")
+
+	// Output:
+	// bad format: disk; must be formatted as KEY=VALUE
+	// no host found
+}
+
+func ExampleServicedCLI_CmdHostRemoveLabel() {
+	InitHostAPITest("serviced", "host", "remove-label", "test-host-id-1", "disk")
+
+	// Output:
+	// disk
+	// test-host-id-1
+}
+
+func ExampleServicedCLI_CmdHostRemoveLabel_usage() {
+	InitHostAPITest("serviced", "host", "remove-label", "test-host-id-1")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    remove-label - Removes labels from an existing host
+	//
+	// USAGE:
+	//    command remove-label [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced host remove-label HOSTID KEY ...
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdHostRemoveLabel_err() {
+	pipeStderr(InitHostAPITest, "serviced", "host", "remove-label", "test-host-id-0", "disk")
+
+	// Output:
+	// no host found
+}
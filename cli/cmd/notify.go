@@ -0,0 +1,216 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// initNotify is the initializer for serviced notify
+func (c *ServicedCli) initNotify() {
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "notify",
+		Usage:       "Administers the sinks notified of failing health checks and breached thresholds",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:        "list",
+				Usage:       "Lists the notification sinks",
+				Description: "serviced notify list",
+				Action:      c.cmdNotifyList,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:  "add",
+				Usage: "Adds a notification sink for a pool or a tenant",
+				Description: "serviced notify add (--pool POOLID | --tenant TENANTID) NAME webhook URL | " +
+					"NAME smtp RECIPIENT[,RECIPIENT...] | NAME syslog (local | HOST:PORT)",
+				Action: c.cmdNotifyAdd,
+				Flags: []cli.Flag{
+					cli.StringFlag{"pool", "", "Notify about the hosts and services of this pool"},
+					cli.StringFlag{"tenant", "", "Notify about the services of this tenant"},
+					cli.StringFlag{"method", "", "HTTP method of the webhook requests; POST if not set"},
+					cli.StringSliceFlag{"header", &cli.StringSlice{}, "Header added to the webhook requests: 'NAME: VALUE'"},
+					cli.StringFlag{"template", "", "File with the text/template of the webhook payload; the notification as JSON if not set"},
+					cli.StringFlag{"smtp-server", "", "HOST:PORT of the mail server"},
+					cli.StringFlag{"from", "", "Sender of the emails"},
+					cli.StringFlag{"username", "", "Authenticate to the mail server as this user"},
+					cli.StringFlag{"password-file", "", "File with the password to authenticate to the mail server with"},
+					cli.StringFlag{"network", "udp", "Network of a remote syslog (udp, tcp)"},
+					cli.StringFlag{"tag", "", "Syslog tag; serviced if not set"},
+				},
+			}, {
+				Name:        "remove",
+				ShortName:   "rm",
+				Usage:       "Removes notification sinks",
+				Description: "serviced notify remove SINKID ...",
+				Action:      c.cmdNotifyRemove,
+			}, {
+				Name:        "test",
+				Usage:       "Sends a test notification to a sink",
+				Description: "serviced notify test SINKID",
+				Action:      c.cmdNotifyTest,
+			},
+		},
+	})
+}
+
+// serviced notify list
+func (c *ServicedCli) cmdNotifyList(ctx *cli.Context) {
+	if sinks, err := c.driver.GetNotificationSinks(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if sinks == nil || len(sinks) == 0 {
+		fmt.Fprintln(os.Stderr, "no notification sinks found")
+	} else if ctx.Bool("verbose") {
+		if jsonSinks, err := json.MarshalIndent(sinks, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal notification sinks: %s\n", err)
+		} else {
+			fmt.Println(string(jsonSinks))
+		}
+	} else {
+		tableSinks := newtable(0, 8, 2)
+		tableSinks.printrow("ID", "NAME", "TYPE", "POOL", "TENANT", "TARGET")
+		for _, s := range sinks {
+			tableSinks.printrow(s.ID, s.Name, s.Type, s.PoolID, s.TenantID, sinkTarget(s))
+		}
+		tableSinks.flush()
+	}
+}
+
+// sinkTarget describes where a sink sends the notifications
+func sinkTarget(s *notification.Sink) string {
+	switch {
+	case s.Webhook != nil:
+		return s.Webhook.URL
+	case s.SMTP != nil:
+		return strings.Join(s.SMTP.To, ",")
+	case s.Syslog != nil && s.Syslog.Network == "":
+		return "local"
+	case s.Syslog != nil:
+		return s.Syslog.Network + "://" + s.Syslog.Address
+	}
+	return ""
+}
+
+// parseHeaders parses the 'NAME: VALUE' headers of a webhook
+func parseHeaders(headers []string) (map[string]string, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]string)
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("header %q is not NAME: VALUE", h)
+		}
+		parsed[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return parsed, nil
+}
+
+// serviced notify add (--pool POOLID | --tenant TENANTID) [flags] NAME TYPE TARGET
+func (c *ServicedCli) cmdNotifyAdd(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 3 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "add")
+		return
+	}
+
+	sink := notification.Sink{
+		Name:     args[0],
+		Type:     args[1],
+		PoolID:   ctx.String("pool"),
+		TenantID: ctx.String("tenant"),
+	}
+	switch sink.Type {
+	case notification.TypeWebhook:
+		headers, err := parseHeaders(ctx.StringSlice("header"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		sink.Webhook = &notification.Webhook{URL: args[2], Method: ctx.String("method"), Headers: headers}
+		if filename := ctx.String("template"); filename != "" {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			sink.Webhook.Template = string(data)
+		}
+	case notification.TypeSMTP:
+		sink.SMTP = &notification.SMTP{
+			Server:   ctx.String("smtp-server"),
+			From:     ctx.String("from"),
+			To:       strings.Split(args[2], ","),
+			Username: ctx.String("username"),
+		}
+		if filename := ctx.String("password-file"); filename != "" {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			sink.SMTP.Password = strings.TrimRight(string(data), "\r\n")
+		}
+	case notification.TypeSyslog:
+		sink.Syslog = &notification.Syslog{Tag: ctx.String("tag")}
+		if args[2] != "local" {
+			sink.Syslog.Network, sink.Syslog.Address = ctx.String("network"), args[2]
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "type must be %s, %s or %s\n", notification.TypeWebhook, notification.TypeSMTP, notification.TypeSyslog)
+		return
+	}
+
+	if id, err := c.driver.AddNotificationSink(sink); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(id)
+	}
+}
+
+// serviced notify remove SINKID ...
+func (c *ServicedCli) cmdNotifyRemove(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "remove")
+		return
+	}
+
+	for _, id := range args {
+		if err := c.driver.RemoveNotificationSink(id); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", id, err)
+		} else {
+			fmt.Println(id)
+		}
+	}
+}
+
+// serviced notify test SINKID
+func (c *ServicedCli) cmdNotifyTest(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) != 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "test")
+		return
+	}
+
+	if err := c.driver.TestNotificationSink(args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+	} else {
+		fmt.Printf("%s: test notification sent\n", args[0])
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/notification"
+)
+
+var DefaultNotifyAPITest = NotifyAPITest{sinks: DefaultTestSinks}
+
+var DefaultTestSinks = []*notification.Sink{
+	{
+		ID:      "sink-1",
+		Name:    "ops",
+		Type:    notification.TypeWebhook,
+		PoolID:  "default",
+		Webhook: &notification.Webhook{URL: "https://hooks.example.com/serviced"},
+	}, {
+		ID:       "sink-2",
+		Name:     "oncall",
+		Type:     notification.TypeSMTP,
+		TenantID: "test-tenant",
+		SMTP:     &notification.SMTP{Server: "mail.example.com:25", From: "serviced@example.com", To: []string{"a@example.com", "b@example.com"}},
+	},
+}
+
+var ErrNoSuchSink = errors.New("notification sink not found")
+
+type NotifyAPITest struct {
+	api.API
+	sinks []*notification.Sink
+}
+
+func InitNotifyAPITest(args ...string) {
+	New(DefaultNotifyAPITest).Run(args)
+}
+
+func (t NotifyAPITest) GetNotificationSinks() ([]*notification.Sink, error) {
+	return t.sinks, nil
+}
+
+func (t NotifyAPITest) AddNotificationSink(sink notification.Sink) (string, error) {
+	data, err := json.Marshal(sink)
+	if err != nil {
+		return "", err
+	}
+	fmt.Println(string(data))
+	return "sink-3", nil
+}
+
+func (t NotifyAPITest) RemoveNotificationSink(id string) error {
+	for _, s := range t.sinks {
+		if s.ID == id {
+			return nil
+		}
+	}
+	return ErrNoSuchSink
+}
+
+func (t NotifyAPITest) TestNotificationSink(id string) error {
+	if id == "sink-2" {
+		return errors.New("dial tcp: connection refused")
+	}
+	return t.RemoveNotificationSink(id)
+}
+
+func ExampleServicedCLI_CmdNotifyList() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitNotifyAPITest("serviced", "notify", "list")
+}
+
+func TestServicedCLI_CmdNotifyList_verbose(t *testing.T) {
+	var actual []*notification.Sink
+	output := pipe(InitNotifyAPITest, "serviced", "notify", "list", "--verbose")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshalling resource: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, DefaultTestSinks) {
+		t.Fatalf("\ngot:\n%+v\nwant:\n%+v", actual, DefaultTestSinks)
+	}
+}
+
+func ExampleServicedCLI_CmdNotifyList_err() {
+	pipeStderr(func(args ...string) { New(NotifyAPITest{}).Run(args) }, "serviced", "notify", "list")
+
+	// Output:
+	// no notification sinks found
+}
+
+func ExampleServicedCLI_CmdNotifyAdd() {
+	InitNotifyAPITest("serviced", "notify", "add", "--tenant", "test-tenant", "logs", "syslog", "logs.example.com:514")
+
+	// Output:
+	// {"ID":"","Name":"logs","Type":"syslog","PoolID":"","TenantID":"test-tenant","Syslog":{"Network":"udp","Address":"logs.example.com:514","Tag":""},"Created":"0001-01-01T00:00:00Z"}
+	// sink-3
+}
+
+func ExampleServicedCLI_CmdNotifyAdd_fail() {
+	pipeStderr(InitNotifyAPITest, "serviced", "notify", "add", "--pool", "default", "pager", "sms", "5551234")
+
+	// Output:
+	// type must be webhook, smtp or syslog
+}
+
+func ExampleServicedCLI_CmdNotifyRemove() {
+	InitNotifyAPITest("serviced", "notify", "remove", "sink-1")
+
+	// Output:
+	// sink-1
+}
+
+func ExampleServicedCLI_CmdNotifyRemove_err() {
+	pipeStderr(InitNotifyAPITest, "serviced", "notify", "remove", "sink-0")
+
+	// Output:
+	// sink-0: notification sink not found
+}
+
+func ExampleServicedCLI_CmdNotifyTest() {
+	InitNotifyAPITest("serviced", "notify", "test", "sink-1")
+	pipeStderr(InitNotifyAPITest, "serviced", "notify", "test", "sink-2")
+
+	// Output:
+	// sink-1: test notification sent
+	// sink-2: dial tcp: connection refused
+}
+
+func TestNotify_parseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"Authorization: Bearer abc:def", " X-Team :ops"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]string{"Authorization": "Bearer abc:def", "X-Team": "ops"}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Expected %v, got %v", expected, headers)
+	}
+	if _, err := parseHeaders([]string{"no-colon"}); err == nil {
+		t.Errorf("Expected an error parsing a header without a value")
+	}
+}
@@ -33,12 +33,31 @@ func (c *ServicedCli) initPool() {
 					cli.BoolFlag{"verbose, v", "Show JSON format"},
 				},
 			}, {
-				Name:  "add",
-				Usage: "Adds a new resource pool",
-				//Description:  "serviced pool add POOLID CORE_LIMIT MEMORY_LIMIT PRIORITY",
+				Name:         "add",
+				Usage:        "Adds a new resource pool",
 				Description:  "serviced pool add POOLID PRIORITY",
 				BashComplete: nil,
 				Action:       c.cmdPoolAdd,
+				Flags: []cli.Flag{
+					cli.IntFlag{"core-limit", 0, "Quota on the cores committed to running services, 0 = unlimited"},
+					cli.StringFlag{"memory-limit", "", "Quota on the bytes of RAM committed to running services, 0 = unlimited"},
+					cli.StringFlag{"memory-weight", "", "Weight of uncommitted RAM when ranking hosts"},
+					cli.StringFlag{"core-weight", "", "Weight of uncommitted cores when ranking hosts"},
+					cli.StringFlag{"memory-overcommit", "", "Max ratio of RAM committed on a host to its RAM, 0 = unlimited"},
+					cli.StringFlag{"core-overcommit", "", "Max ratio of cores committed on a host to its cores, 0 = unlimited"},
+				},
+			}, {
+				Name:         "update",
+				Usage:        "Updates how an existing resource pool schedules services on its hosts",
+				Description:  "serviced pool update POOLID",
+				BashComplete: c.printPoolsFirst,
+				Action:       c.cmdPoolUpdate,
+				Flags: []cli.Flag{
+					cli.StringFlag{"memory-weight", "", "Weight of uncommitted RAM when ranking hosts"},
+					cli.StringFlag{"core-weight", "", "Weight of uncommitted cores when ranking hosts"},
+					cli.StringFlag{"memory-overcommit", "", "Max ratio of RAM committed on a host to its RAM, 0 = unlimited"},
+					cli.StringFlag{"core-overcommit", "", "Max ratio of cores committed on a host to its cores, 0 = unlimited"},
+				},
 			}, {
 				Name:         "remove",
 				ShortName:    "rm",
@@ -146,9 +165,9 @@ func (c *ServicedCli) cmdPoolList(ctx *cli.Context) {
 		}
 	} else {
 		tablePool := newtable(0, 8, 2)
-		tablePool.printrow("ID", "PARENT" /*"CORE", "MEM",*/, "PRI")
+		tablePool.printrow("ID", "PARENT", "CORE", "MEM", "PRI")
 		for _, p := range pools {
-			tablePool.printrow(p.ID, p.ParentID /*p.CoreLimit, p.MemoryLimit,*/, p.Priority)
+			tablePool.printrow(p.ID, p.ParentID, p.CoreLimit, p.MemoryLimit, p.Priority)
 		}
 		tablePool.flush()
 	}
@@ -168,19 +187,23 @@ func (c *ServicedCli) cmdPoolAdd(ctx *cli.Context) {
 	cfg := api.PoolConfig{}
 	cfg.PoolID = args[0]
 
-	/* Disabled until enforced. See ZEN-11450
-	cfg.CoreLimit, err = strconv.Atoi(args[1])
-	if err != nil {
-		fmt.Println("CORE_LIMIT must be a number")
+	if cfg.CoreLimit = ctx.Int("core-limit"); cfg.CoreLimit < 0 {
+		fmt.Println("core-limit cannot be negative")
 		return
 	}
 
-	cfg.MemoryLimit, err = strconv.ParseUint(args[2], 10, 64)
-	if err != nil {
-		fmt.Println("MEMORY_LIMIT must be a number")
+	if limit := ctx.String("memory-limit"); limit != "" {
+		cfg.MemoryLimit, err = strconv.ParseUint(limit, 10, 64)
+		if err != nil {
+			fmt.Println("memory-limit must be a number")
+			return
+		}
+	}
+
+	if err := parseSchedulingFlags(ctx, &cfg.MemoryWeight, &cfg.CoreWeight, &cfg.MemoryOvercommit, &cfg.CoreOvercommit); err != nil {
+		fmt.Println(err)
 		return
 	}
-	*/
 
 	cfg.Priority, err = strconv.Atoi(args[1])
 	if err != nil {
@@ -197,6 +220,64 @@ func (c *ServicedCli) cmdPoolAdd(ctx *cli.Context) {
 	}
 }
 
+// serviced pool update POOLID
+func (c *ServicedCli) cmdPoolUpdate(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "update")
+		return
+	}
+
+	p, err := c.driver.GetResourcePool(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if p == nil {
+		fmt.Fprintln(os.Stderr, "pool not found")
+		return
+	}
+
+	if err := parseSchedulingFlags(ctx, &p.MemoryWeight, &p.CoreWeight, &p.MemoryOvercommit, &p.CoreOvercommit); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if pool, err := c.driver.UpdateResourcePool(*p); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if pool == nil {
+		fmt.Fprintln(os.Stderr, "received nil resource pool")
+	} else {
+		fmt.Println(pool.ID)
+	}
+}
+
+// parseSchedulingFlags sets the weights and overcommit ratios of a pool from
+// the flags that were given, leaving the others as they are
+func parseSchedulingFlags(ctx *cli.Context, memoryWeight, coreWeight, memoryOvercommit, coreOvercommit *float64) error {
+	flags := []struct {
+		name  string
+		value *float64
+	}{
+		{"memory-weight", memoryWeight},
+		{"core-weight", coreWeight},
+		{"memory-overcommit", memoryOvercommit},
+		{"core-overcommit", coreOvercommit},
+	}
+	for _, flag := range flags {
+		str := ctx.String(flag.name)
+		if str == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil || value < 0 {
+			return fmt.Errorf("%s must be a non-negative number", flag.name)
+		}
+		*flag.value = value
+	}
+	return nil
+}
+
 // serviced pool remove POOLID ...
 func (c *ServicedCli) cmdPoolRemove(ctx *cli.Context) {
 	args := ctx.Args()
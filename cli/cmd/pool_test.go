@@ -71,6 +71,8 @@ type PoolAPITest struct {
 	fail    bool
 	pools   []*pool.ResourcePool
 	hostIPs []host.HostIPResource
+	added   *api.PoolConfig
+	updated *pool.ResourcePool
 }
 
 func InitPoolAPITest(args ...string) {
@@ -105,18 +107,38 @@ func (t PoolAPITest) AddResourcePool(config api.PoolConfig) (*pool.ResourcePool,
 	} else if config.PoolID == NilPool {
 		return nil, nil
 	}
+	if t.added != nil {
+		*t.added = config
+	}
 
 	p := &pool.ResourcePool{
-		ID:          config.PoolID,
-		ParentID:    "",
-		Priority:    0,
-		CoreLimit:   config.CoreLimit,
-		MemoryLimit: config.MemoryLimit,
+		ID:               config.PoolID,
+		ParentID:         "",
+		Priority:         0,
+		CoreLimit:        config.CoreLimit,
+		MemoryLimit:      config.MemoryLimit,
+		MemoryWeight:     config.MemoryWeight,
+		CoreWeight:       config.CoreWeight,
+		MemoryOvercommit: config.MemoryOvercommit,
+		CoreOvercommit:   config.CoreOvercommit,
 	}
 
 	return p, nil
 }
 
+func (t PoolAPITest) UpdateResourcePool(p pool.ResourcePool) (*pool.ResourcePool, error) {
+	if current, err := t.GetResourcePool(p.ID); err != nil {
+		return nil, err
+	} else if current == nil {
+		return nil, ErrNoPoolFound
+	}
+	if t.updated != nil {
+		*t.updated = p
+	}
+
+	return &p, nil
+}
+
 func (t PoolAPITest) RemoveResourcePool(id string) error {
 	if p, err := t.GetResourcePool(id); err != nil {
 		return err
@@ -222,20 +244,36 @@ func ExampleServicedCLI_CmdPoolList_complete() {
 }
 
 func ExampleServicedCLI_CmdPoolAdd() {
-	// // Bad CoreLimit
-	// InitPoolAPITest("serviced", "pool", "add", "test-pool", "abc", "1024", "3")
-	// // Bad MemoryLimit
-	// InitPoolAPITest("serviced", "pool", "add", "test-pool", "4", "abc", "3")
+	// Bad CoreLimit
+	InitPoolAPITest("serviced", "pool", "add", "--core-limit", "-1", "test-pool", "3")
+	// Bad MemoryLimit
+	InitPoolAPITest("serviced", "pool", "add", "--memory-limit", "abc", "test-pool", "3")
 	// Bad Priority
 	InitPoolAPITest("serviced", "pool", "add", "test-pool", "abc")
 	// Bad Result
 	InitPoolAPITest("serviced", "pool", "add", "test-pool-id-1", "3")
 	// Success
 	InitPoolAPITest("serviced", "pool", "add", "test-pool", "3")
+	// Success with limits
+	InitPoolAPITest("serviced", "pool", "add", "--core-limit", "4", "--memory-limit", "1024", "test-pool", "3")
 
 	// Output:
+	// core-limit cannot be negative
+	// memory-limit must be a number
 	// PRIORITY must be a number
 	// test-pool
+	// test-pool
+}
+
+func TestServicedCLI_CmdPoolAdd_scheduling(t *testing.T) {
+	var added api.PoolConfig
+	test := DefaultPoolAPITest
+	test.added = &added
+	pipe(func(args ...string) { New(test).Run(args) }, "serviced", "pool", "add", "--memory-weight", "2", "--core-weight", "0.5", "--memory-overcommit", "1.25", "--core-overcommit", "4", "test-pool", "3")
+
+	if added.MemoryWeight != 2 || added.CoreWeight != 0.5 || added.MemoryOvercommit != 1.25 || added.CoreOvercommit != 4 {
+		t.Errorf("unexpected scheduling settings: %+v", added)
+	}
 }
 
 func ExampleServicedCLI_CmdPoolAdd_usage() {
@@ -254,6 +292,12 @@ func ExampleServicedCLI_CmdPoolAdd_usage() {
 	//    serviced pool add POOLID PRIORITY
 	//
 	// OPTIONS:
+	//    --core-limit '0'	Quota on the cores committed to running services, 0 = unlimited
+	//    --memory-limit 	Quota on the bytes of RAM committed to running services, 0 = unlimited
+	//    --memory-weight 	Weight of uncommitted RAM when ranking hosts
+	//    --core-weight 	Weight of uncommitted cores when ranking hosts
+	//    --memory-overcommit 	Max ratio of RAM committed on a host to its RAM, 0 = unlimited
+	//    --core-overcommit 	Max ratio of cores committed on a host to its cores, 0 = unlimited
 }
 
 func ExampleServicedCLI_CmdPoolAdd_err() {
@@ -263,6 +307,76 @@ func ExampleServicedCLI_CmdPoolAdd_err() {
 	// received nil resource pool
 }
 
+func ExampleServicedCLI_CmdPoolAdd_scheduling() {
+	// Bad weight
+	InitPoolAPITest("serviced", "pool", "add", "--memory-weight", "heavy", "test-pool", "3")
+	// Bad overcommit
+	InitPoolAPITest("serviced", "pool", "add", "--core-overcommit", "-1", "test-pool", "3")
+
+	// Output:
+	// memory-weight must be a non-negative number
+	// core-overcommit must be a non-negative number
+}
+
+func TestServicedCLI_CmdPoolUpdate(t *testing.T) {
+	var updated pool.ResourcePool
+	current := *DefaultTestPools[1]
+	current.MemoryWeight = 1
+	test := PoolAPITest{pools: []*pool.ResourcePool{&current}, updated: &updated}
+	output := pipe(func(args ...string) { New(test).Run(args) }, "serviced", "pool", "update", "--core-weight", "3", "--memory-overcommit", "1.5", current.ID)
+	if string(output) != current.ID+"\n" {
+		t.Fatalf("unexpected output: %q", output)
+	}
+
+	// flags that are not given keep the settings of the pool
+	if updated.ID != current.ID || updated.CoreLimit != current.CoreLimit || updated.MemoryWeight != 1 {
+		t.Errorf("expected the rest of the pool to be kept, got %+v", updated)
+	}
+	if updated.CoreWeight != 3 || updated.MemoryOvercommit != 1.5 || updated.CoreOvercommit != 0 {
+		t.Errorf("unexpected scheduling settings: %+v", updated)
+	}
+}
+
+func ExampleServicedCLI_CmdPoolUpdate() {
+	// Bad weight
+	InitPoolAPITest("serviced", "pool", "update", "--core-weight", "x", "test-pool-id-1")
+	// Success
+	InitPoolAPITest("serviced", "pool", "update", "--memory-overcommit", "2", "test-pool-id-1")
+
+	// Output:
+	// core-weight must be a non-negative number
+	// test-pool-id-1
+}
+
+func ExampleServicedCLI_CmdPoolUpdate_usage() {
+	InitPoolAPITest("serviced", "pool", "update")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    update - Updates how an existing resource pool schedules services on its hosts
+	//
+	// USAGE:
+	//    command update [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced pool update POOLID
+	//
+	// OPTIONS:
+	//    --memory-weight 	Weight of uncommitted RAM when ranking hosts
+	//    --core-weight 	Weight of uncommitted cores when ranking hosts
+	//    --memory-overcommit 	Max ratio of RAM committed on a host to its RAM, 0 = unlimited
+	//    --core-overcommit 	Max ratio of cores committed on a host to its cores, 0 = unlimited
+}
+
+func ExampleServicedCLI_CmdPoolUpdate_err() {
+	pipeStderr(InitPoolAPITest, "serviced", "pool", "update", "test-pool-id-0")
+
+	// Output:
+	// pool not found
+}
+
 func ExampleServicedCLI_CmdPoolRemove() {
 	InitPoolAPITest("serviced", "pool", "remove", "test-pool-id-1")
 
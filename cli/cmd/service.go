@@ -80,6 +80,9 @@ func (c *ServicedCli) initService() {
 				Action:       c.cmdServiceEdit,
 				Flags: []cli.Flag{
 					cli.StringFlag{"editor, e", os.Getenv("EDITOR"), "Editor used to update the service definition"},
+					cli.BoolFlag{"rolling", "Restart the instances a batch at a time after the update, waiting for each batch to pass its health checks"},
+					cli.IntFlag{"batch", 1, "Number of instances to restart at a time with --rolling"},
+					cli.StringFlag{"timeout", "5m", "How long a batch has to pass its health checks before the rolling restart is paused"},
 				},
 			}, {
 				Name:         "assign-ip",
@@ -99,6 +102,46 @@ func (c *ServicedCli) initService() {
 				Description:  "serviced service stop SERVICEID",
 				BashComplete: c.printServicesFirst,
 				Action:       c.cmdServiceStop,
+			}, {
+				Name:         "restart",
+				Usage:        "Restarts a service",
+				Description:  "serviced service restart SERVICEID",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdServiceRestart,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"rolling", "Restart the instances a batch at a time, waiting for each batch to pass its health checks"},
+					cli.IntFlag{"batch", 1, "Number of instances to restart at a time with --rolling"},
+					cli.StringFlag{"timeout", "5m", "How long a batch has to pass its health checks before the rolling restart is paused"},
+				},
+			}, {
+				Name:         "rollout",
+				Usage:        "Shows the progress of a rolling restart",
+				Description:  "serviced service rollout SERVICEID",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdServiceRollout,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+					cli.BoolFlag{"cancel", "Cancel the paused or in progress rolling restart"},
+				},
+			}, {
+				Name:         "scaling",
+				Usage:        "Lists the decisions of the autoscaler for a service",
+				Description:  "serviced service scaling SERVICEID",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdServiceScaling,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:         "health",
+				Usage:        "Shows the status of the health checks of each instance of a service",
+				Description:  "serviced service health SERVICEID",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdServiceHealth,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"history", "Show when each health check changed status"},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
 			}, {
 				Name:         "proxy",
 				Usage:        "Starts a server proxy for a container",
@@ -510,7 +553,7 @@ func (c *ServicedCli) cmdServiceRemove(ctx *cli.Context) {
 	}
 }
 
-// serviced service edit SERVICEID
+// serviced service edit SERVICEID [--rolling [--batch N] [--timeout DURATION]]
 func (c *ServicedCli) cmdServiceEdit(ctx *cli.Context) {
 	args := ctx.Args()
 	if len(args) < 1 {
@@ -528,6 +571,15 @@ func (c *ServicedCli) cmdServiceEdit(ctx *cli.Context) {
 		return
 	}
 
+	var rollout api.RolloutConfig
+	rolling := ctx.Bool("rolling")
+	if rolling {
+		if rollout, err = rolloutConfig(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+	}
+
 	jsonService, err := json.MarshalIndent(service, " ", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error marshalling service: %s\n", err)
@@ -541,7 +593,12 @@ func (c *ServicedCli) cmdServiceEdit(ctx *cli.Context) {
 		return
 	}
 
-	if service, err := c.driver.UpdateService(reader); err != nil {
+	if rolling {
+		service, err = c.driver.UpdateServiceRolling(reader, rollout)
+	} else {
+		service, err = c.driver.UpdateService(reader)
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	} else if service == nil {
 		fmt.Fprintln(os.Stderr, "received nil service")
@@ -607,6 +664,169 @@ func (c *ServicedCli) cmdServiceStop(ctx *cli.Context) {
 	}
 }
 
+// serviced service restart SERVICEID [--rolling [--batch N] [--timeout DURATION]]
+func (c *ServicedCli) cmdServiceRestart(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "restart")
+		return
+	}
+
+	cfg := api.RestartServiceConfig{
+		ServiceID: args[0],
+		Rolling:   ctx.Bool("rolling"),
+	}
+	if cfg.Rolling {
+		rollout, err := rolloutConfig(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		cfg.BatchSize, cfg.Timeout = rollout.BatchSize, rollout.Timeout
+	}
+
+	if err := c.driver.RestartService(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if cfg.Rolling {
+		fmt.Printf("Rolling restart scheduled; check its progress with 'serviced service rollout %s'.\n", cfg.ServiceID)
+	} else {
+		fmt.Printf("Service scheduled to restart.\n")
+	}
+}
+
+// rolloutConfig reads the --batch and --timeout options of a rolling restart
+func rolloutConfig(ctx *cli.Context) (api.RolloutConfig, error) {
+	cfg := api.RolloutConfig{BatchSize: ctx.Int("batch")}
+	if cfg.BatchSize < 1 {
+		return cfg, fmt.Errorf("batch must be at least 1")
+	}
+	timeout, err := time.ParseDuration(ctx.String("timeout"))
+	if err != nil || timeout <= 0 {
+		return cfg, fmt.Errorf("timeout must be a positive duration, such as 90s or 5m")
+	}
+	cfg.Timeout = timeout
+	return cfg, nil
+}
+
+// serviced service rollout SERVICEID [--cancel]
+func (c *ServicedCli) cmdServiceRollout(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "rollout")
+		return
+	}
+
+	if ctx.Bool("cancel") {
+		if err := c.driver.CancelServiceRollout(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Printf("Rolling restart canceled.\n")
+		}
+		return
+	}
+
+	r, err := c.driver.GetServiceRollout(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonRollout, err := json.MarshalIndent(r, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal rollout: %s\n", err)
+		} else {
+			fmt.Println(string(jsonRollout))
+		}
+		return
+	}
+
+	total := len(r.Restarted) + len(r.Batch) + len(r.Pending)
+	switch {
+	case r.Done():
+		fmt.Printf("Finished: %d of %d instances restarted\n", len(r.Restarted), total)
+	case r.Paused():
+		fmt.Printf("Paused: %d of %d instances restarted; instances %v failed: %s\n", len(r.Restarted), total, r.Batch, r.Err)
+		fmt.Printf("Resume with 'serviced service restart --rolling %s' or cancel with 'serviced service rollout --cancel %s'.\n", r.ServiceID, r.ServiceID)
+	default:
+		fmt.Printf("In progress: %d of %d instances restarted; restarting instances %v\n", len(r.Restarted), total, r.Batch)
+	}
+}
+
+// serviced service scaling SERVICEID
+func (c *ServicedCli) cmdServiceScaling(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "scaling")
+		return
+	}
+
+	if events, err := c.driver.GetScalingEvents(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if events == nil || len(events) == 0 {
+		fmt.Fprintln(os.Stderr, "no scaling events found")
+	} else if ctx.Bool("verbose") {
+		if jsonEvents, err := json.MarshalIndent(events, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal scaling events: %s\n", err)
+		} else {
+			fmt.Println(string(jsonEvents))
+		}
+	} else {
+		tableEvents := newtable(0, 8, 2)
+		tableEvents.printrow("TIME", "METRIC", "VALUE", "TARGET", "FROM", "TO", "ERROR")
+		for _, e := range events {
+			tableEvents.printrow(e.Timestamp.Format(time.RFC3339), e.Metric, fmt.Sprintf("%.2f", e.Value), fmt.Sprintf("%.2f", e.Target), e.From, e.To, e.Err)
+		}
+		tableEvents.flush()
+	}
+}
+
+// serviced service health [--history] SERVICEID
+func (c *ServicedCli) cmdServiceHealth(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "health")
+		return
+	}
+
+	if statuses, err := c.driver.GetServiceHealth(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if statuses == nil || len(statuses) == 0 {
+		fmt.Fprintln(os.Stderr, "no health check results found")
+	} else if ctx.Bool("verbose") {
+		if jsonStatuses, err := json.MarshalIndent(statuses, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal health statuses: %s\n", err)
+		} else {
+			fmt.Println(string(jsonStatuses))
+		}
+	} else if ctx.Bool("history") {
+		tableHistory := newtable(0, 8, 2)
+		tableHistory.printrow("INSTANCE", "CHECK", "TIME", "STATUS")
+		for _, s := range statuses {
+			for _, change := range s.History {
+				tableHistory.printrow(s.InstanceID, s.Name, change.Timestamp.Local().Format(time.RFC3339), change.Status)
+			}
+		}
+		tableHistory.flush()
+	} else {
+		now := time.Now()
+		tableHealth := newtable(0, 8, 2)
+		tableHealth.printrow("INSTANCE", "CHECK", "STATUS", "SINCE", "LAST RESULT")
+		for _, s := range statuses {
+			status, since := s.Current(now), s.Since()
+			if status != s.Status {
+				// missed, but not marked as missed by the leader yet
+				since = s.Timestamp.Add(2 * s.Interval)
+			}
+			tableHealth.printrow(s.InstanceID, s.Name, status, since.Local().Format(time.RFC3339), s.Timestamp.Local().Format(time.RFC3339))
+		}
+		tableHealth.flush()
+	}
+}
+
 // sendLogMessage sends a log message to the host agent
 func sendLogMessage(lbClientPort string, serviceLogInfo node.ServiceLogInfo) error {
 	client, err := node.NewLBClient(lbClientPort)
@@ -9,13 +9,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/control-center/serviced/cli/api"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/pool"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/zzk/autoscale"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 )
 
 const (
@@ -173,6 +178,10 @@ func (t ServiceAPITest) UpdateService(reader io.Reader) (*service.Service, error
 	return &s, nil
 }
 
+func (t ServiceAPITest) UpdateServiceRolling(reader io.Reader, config api.RolloutConfig) (*service.Service, error) {
+	return t.UpdateService(reader)
+}
+
 func (t ServiceAPITest) StartService(id string) error {
 	if s, err := t.GetService(id); err != nil {
 		return err
@@ -193,6 +202,68 @@ func (t ServiceAPITest) StopService(id string) error {
 	return nil
 }
 
+func (t ServiceAPITest) RestartService(config api.RestartServiceConfig) error {
+	if s, err := t.GetService(config.ServiceID); err != nil {
+		return err
+	} else if s == nil {
+		return ErrNoServiceFound
+	}
+
+	return nil
+}
+
+func (t ServiceAPITest) CancelServiceRollout(id string) error {
+	if s, err := t.GetService(id); err != nil {
+		return err
+	} else if s == nil {
+		return ErrNoServiceFound
+	}
+
+	return nil
+}
+
+func (t ServiceAPITest) GetServiceRollout(id string) (*rollout.Rollout, error) {
+	if s, err := t.GetService(id); err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, ErrNoServiceFound
+	}
+
+	return &rollout.Rollout{
+		ServiceID: id,
+		BatchSize: 1,
+		Pending:   []int{2},
+		Batch:     []int{1},
+		Restarted: []int{0},
+	}, nil
+}
+
+func (t ServiceAPITest) GetScalingEvents(id string) ([]autoscale.Event, error) {
+	if s, err := t.GetService(id); err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, ErrNoServiceFound
+	} else if id != "test-service-2" {
+		return nil, nil
+	}
+
+	return []autoscale.Event{
+		{ServiceID: id, Timestamp: time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC), Metric: "cpu.user", Value: 100, Target: 50, From: 1, To: 2, Err: "no hosts available"},
+	}, nil
+}
+
+func (t ServiceAPITest) GetServiceHealth(id string) ([]*zkhealth.Status, error) {
+	if s, err := t.GetService(id); err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, ErrNoServiceFound
+	} else if id != "test-service-2" {
+		return nil, nil
+	}
+
+	return DefaultTestHealthStatuses, nil
+}
+
 func (t ServiceAPITest) AssignIP(config api.IPConfig) error {
 	if _, err := t.GetService(config.ServiceID); err != nil {
 		return err
@@ -484,6 +555,9 @@ func ExampleServicedCLI_CmdServiceEdit_usage() {
 	//
 	// OPTIONS:
 	//    --editor, -e 	Editor used to update the service definition
+	//    --rolling	Restart the instances a batch at a time after the update, waiting for each batch to pass its health checks
+	//    --batch '1'	Number of instances to restart at a time with --rolling
+	//    --timeout '5m'	How long a batch has to pass its health checks before the rolling restart is paused
 }
 
 func ExampleServicedCLI_CmdServiceEdit_fail() {
@@ -502,8 +576,12 @@ func ExampleServicedCLI_CmdServiceEdit_err() {
 	pipeStderr(InitServiceAPITest, "serviced", "service", "edit", "test-service-0")
 	// TODO: Nil Service after update
 
+	// Bad rollout options
+	pipeStderr(InitServiceAPITest, "serviced", "service", "edit", "--rolling", "--batch", "0", "test-service-1")
+
 	// Output:
 	// service not found
+	// batch must be at least 1
 }
 
 func ExampleServicedCLI_CmdServiceAssignIPs() {
@@ -623,6 +701,168 @@ func ExampleServicedCLI_CmdServiceStop_err() {
 	// no service found
 }
 
+func ExampleServicedCLI_CmdServiceRestart() {
+	InitServiceAPITest("serviced", "service", "restart", "test-service-2")
+	InitServiceAPITest("serviced", "service", "restart", "--rolling", "--batch", "2", "test-service-2")
+
+	// Output:
+	// Service scheduled to restart.
+	// Rolling restart scheduled; check its progress with 'serviced service rollout test-service-2'.
+}
+
+func ExampleServicedCLI_CmdServiceRestart_usage() {
+	InitServiceAPITest("serviced", "service", "restart")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    restart - Restarts a service
+	//
+	// USAGE:
+	//    command restart [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced service restart SERVICEID
+	//
+	// OPTIONS:
+	//    --rolling	Restart the instances a batch at a time, waiting for each batch to pass its health checks
+	//    --batch '1'	Number of instances to restart at a time with --rolling
+	//    --timeout '5m'	How long a batch has to pass its health checks before the rolling restart is paused
+}
+
+func ExampleServicedCLI_CmdServiceRestart_err() {
+	pipeStderr(InitServiceAPITest, "serviced", "service", "restart", "test-service-0")
+	pipeStderr(InitServiceAPITest, "serviced", "service", "restart", "--rolling", "--batch", "0", "test-service-2")
+	pipeStderr(InitServiceAPITest, "serviced", "service", "restart", "--rolling", "--timeout", "soon", "test-service-2")
+
+	// Output:
+	// no service found
+	// batch must be at least 1
+	// timeout must be a positive duration, such as 90s or 5m
+}
+
+func ExampleServicedCLI_CmdServiceRollout() {
+	InitServiceAPITest("serviced", "service", "rollout", "test-service-2")
+
+	// Output:
+	// In progress: 1 of 3 instances restarted; restarting instances [1]
+}
+
+func ExampleServicedCLI_CmdServiceRollout_usage() {
+	InitServiceAPITest("serviced", "service", "rollout")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    rollout - Shows the progress of a rolling restart
+	//
+	// USAGE:
+	//    command rollout [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced service rollout SERVICEID
+	//
+	// OPTIONS:
+	//    --verbose, -v	Show JSON format
+	//    --cancel	Cancel the paused or in progress rolling restart
+}
+
+func ExampleServicedCLI_CmdServiceRollout_cancel() {
+	InitServiceAPITest("serviced", "service", "rollout", "--cancel", "test-service-2")
+
+	// Output:
+	// Rolling restart canceled.
+}
+
+func ExampleServicedCLI_CmdServiceRollout_err() {
+	pipeStderr(InitServiceAPITest, "serviced", "service", "rollout", "test-service-0")
+	pipeStderr(InitServiceAPITest, "serviced", "service", "rollout", "--cancel", "test-service-0")
+
+	// Output:
+	// no service found
+	// no service found
+}
+
+func ExampleServicedCLI_CmdServiceScaling() {
+	InitServiceAPITest("serviced", "service", "scaling", "test-service-2")
+
+	// Output:
+	// TIME			METRIC		VALUE	TARGET	FROM	TO	ERROR
+	// 2014-10-01T12:00:00Z	cpu.user	100.00	50.00	1	2	no hosts available
+}
+
+func ExampleServicedCLI_CmdServiceScaling_usage() {
+	InitServiceAPITest("serviced", "service", "scaling")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    scaling - Lists the decisions of the autoscaler for a service
+	//
+	// USAGE:
+	//    command scaling [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced service scaling SERVICEID
+	//
+	// OPTIONS:
+	//    --verbose, -v	Show JSON format
+}
+
+func ExampleServicedCLI_CmdServiceScaling_err() {
+	pipeStderr(InitServiceAPITest, "serviced", "service", "scaling", "test-service-0")
+	pipeStderr(InitServiceAPITest, "serviced", "service", "scaling", "test-service-1")
+
+	// Output:
+	// no service found
+	// no scaling events found
+}
+
+var DefaultTestHealthStatuses = []*zkhealth.Status{
+	{
+		ServiceID:  "test-service-2",
+		InstanceID: 0,
+		Name:       "answering",
+		Status:     zkhealth.StatusFailed,
+		Timestamp:  time.Date(2014, 8, 1, 12, 30, 0, 0, time.UTC),
+		Interval:   10 * time.Second,
+		History: []zkhealth.Change{
+			{zkhealth.StatusPassed, time.Date(2014, 8, 1, 12, 0, 0, 0, time.UTC)},
+			{zkhealth.StatusFailed, time.Date(2014, 8, 1, 12, 29, 50, 0, time.UTC)},
+		},
+	},
+}
+
+func ExampleServicedCLI_CmdServiceHealth() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitServiceAPITest("serviced", "service", "health", "test-service-2")
+	InitServiceAPITest("serviced", "service", "health", "--history", "test-service-2")
+}
+
+func TestServicedCLI_CmdServiceHealth_verbose(t *testing.T) {
+	var actual []*zkhealth.Status
+	output := pipe(InitServiceAPITest, "serviced", "service", "health", "-v", "test-service-2")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshalling resource: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, DefaultTestHealthStatuses) {
+		t.Fatalf("\ngot:\n%+v\nwant:\n%+v", actual, DefaultTestHealthStatuses)
+	}
+}
+
+func ExampleServicedCLI_CmdServiceHealth_err() {
+	pipeStderr(InitServiceAPITest, "serviced", "service", "health", "test-service-0")
+	pipeStderr(InitServiceAPITest, "serviced", "service", "health", "test-service-1")
+
+	// Output:
+	// no service found
+	// no health check results found
+}
+
 func ExampleServicedCLI_CmdServiceProxy_usage() {
 	// FIXME: Non-reproducible error on buildbox
 	InitServiceAPITest("serviced", "service", "proxy")
@@ -5,10 +5,16 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/node"
 )
 
 // initSnapshot is the initializer for serviced snapshot
@@ -21,9 +27,12 @@ func (c *ServicedCli) initSnapshot() {
 			{
 				Name:         "list",
 				Usage:        "Lists all snapshots",
-				Description:  "serviced snapshot list [SERVICEID]",
+				Description:  "serviced snapshot list [--schedule] [SERVICEID]",
 				BashComplete: c.printServicesFirst,
 				Action:       c.cmdSnapshotList,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"schedule", "Show when each snapshot was taken and whether its tenant's schedule took it"},
+				},
 			}, {
 				Name:         "add",
 				Usage:        "Take a snapshot of an existing service",
@@ -48,6 +57,35 @@ func (c *ServicedCli) initSnapshot() {
 				Description:  "serviced snapshot rollback SNAPSHOTID",
 				BashComplete: c.printSnapshotsFirst,
 				Action:       c.cmdSnapshotRollback,
+			}, {
+				Name:         "export",
+				Usage:        "Writes a snapshot with the services and images of its tenant to a file",
+				Description:  "serviced snapshot export SNAPSHOTID FILEPATH",
+				BashComplete: c.printSnapshotsFirst,
+				Action:       c.cmdSnapshotExport,
+			}, {
+				Name:        "import",
+				Usage:       "Adds a snapshot from an exported file and restores its tenant to it",
+				Description: "serviced snapshot import FILEPATH",
+				Action:      c.cmdSnapshotImport,
+			}, {
+				Name:         "schedule",
+				Usage:        "Shows or sets the snapshot schedule and retention rules of a tenant",
+				Description:  "serviced snapshot schedule SERVICEID [CRONSPEC]",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdSnapshotSchedule,
+				Flags: []cli.Flag{
+					cli.IntFlag{"keep-last", 0, "Number of most recent scheduled snapshots to keep"},
+					cli.IntFlag{"keep-daily", 0, "Number of days for which to keep the newest scheduled snapshot of the day"},
+					cli.IntFlag{"keep-weekly", 0, "Number of weeks for which to keep the newest scheduled snapshot of the week"},
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:         "unschedule",
+				Usage:        "Stops taking scheduled snapshots of a tenant",
+				Description:  "serviced snapshot unschedule SERVICEID",
+				BashComplete: c.printServicesFirst,
+				Action:       c.cmdSnapshotUnschedule,
 			},
 		},
 	})
@@ -101,26 +139,49 @@ func (c *ServicedCli) printSnapshotsAll(ctx *cli.Context) {
 	}
 }
 
-// serviced snapshot list [SERVICEID]
+// serviced snapshot list [--schedule] [SERVICEID]
 func (c *ServicedCli) cmdSnapshotList(ctx *cli.Context) {
+	var (
+		snapshots []string
+		err       error
+	)
 	if len(ctx.Args()) > 0 {
-		serviceID := ctx.Args().First()
-		if snapshots, err := c.driver.GetSnapshotsByServiceID(serviceID); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-		} else if snapshots == nil || len(snapshots) == 0 {
-			fmt.Fprintln(os.Stderr, "no snapshots found")
-		} else {
-			for _, s := range snapshots {
-				fmt.Println(s)
-			}
-		}
-		return
+		snapshots, err = c.driver.GetSnapshotsByServiceID(ctx.Args().First())
+	} else {
+		snapshots, err = c.driver.GetSnapshots()
 	}
 
-	if snapshots, err := c.driver.GetSnapshots(); err != nil {
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	} else if snapshots == nil || len(snapshots) == 0 {
 		fmt.Fprintln(os.Stderr, "no snapshots found")
+	} else if ctx.Bool("schedule") {
+		schedules := make(map[string]*dao.SnapshotSchedule)
+		tableSnapshots := newtable(0, 8, 2)
+		tableSnapshots.printrow("SNAPSHOTID", "CREATED", "SCHEDULED")
+		for _, s := range snapshots {
+			parts := strings.SplitN(s, "_", 2)
+			if len(parts) < 2 {
+				tableSnapshots.printrow(s, "", "")
+				continue
+			}
+
+			created := parts[1]
+			if t, err := time.Parse(node.TIMEFMT, parts[1]); err == nil {
+				created = t.Format(time.RFC3339)
+			}
+
+			schedule, ok := schedules[parts[0]]
+			if !ok {
+				if schedule, err = c.driver.GetSnapshotSchedule(parts[0]); err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", parts[0], err)
+				}
+				schedules[parts[0]] = schedule
+			}
+			scheduled := schedule != nil && schedule.Status != nil && schedule.Status.Scheduled(s)
+			tableSnapshots.printrow(s, created, scheduled)
+		}
+		tableSnapshots.flush()
 	} else {
 		for _, s := range snapshots {
 			fmt.Println(s)
@@ -197,3 +258,118 @@ func (c *ServicedCli) cmdSnapshotRollback(ctx *cli.Context) {
 		fmt.Println(args[0])
 	}
 }
+
+// serviced snapshot export SNAPSHOTID FILEPATH
+func (c *ServicedCli) cmdSnapshotExport(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "export")
+		return
+	}
+
+	if path, err := c.driver.ExportSnapshot(args[0], args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if path == "" {
+		fmt.Fprintln(os.Stderr, "received nil path to export file")
+	} else {
+		fmt.Println(path)
+	}
+}
+
+// serviced snapshot import FILEPATH
+func (c *ServicedCli) cmdSnapshotImport(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "import")
+		return
+	}
+
+	if snapshot, err := c.driver.ImportSnapshot(args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if snapshot == "" {
+		fmt.Fprintln(os.Stderr, "received nil snapshot")
+	} else {
+		fmt.Println(snapshot)
+	}
+}
+
+// serviced snapshot schedule SERVICEID [CRONSPEC]
+func (c *ServicedCli) cmdSnapshotSchedule(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "schedule")
+		return
+	}
+
+	if len(args) > 1 {
+		policy := servicedefinition.SnapshotPolicy{
+			Schedule:   args[1],
+			KeepLast:   ctx.Int("keep-last"),
+			KeepDaily:  ctx.Int("keep-daily"),
+			KeepWeekly: ctx.Int("keep-weekly"),
+		}
+		if err := c.driver.SetSnapshotPolicy(args[0], policy); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Println(args[0])
+		}
+		return
+	}
+
+	schedule, err := c.driver.GetSnapshotSchedule(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	} else if schedule == nil || !schedule.Policy.Enabled() {
+		fmt.Fprintln(os.Stderr, "no snapshot schedule found")
+		return
+	}
+
+	if ctx.Bool("verbose") {
+		if jsonSchedule, err := json.MarshalIndent(schedule, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal snapshot schedule: %s\n", err)
+		} else {
+			fmt.Println(string(jsonSchedule))
+		}
+		return
+	}
+
+	p := schedule.Policy
+	fmt.Printf("Schedule: %s (keep last %d, daily for %d days, weekly for %d weeks)\n", p.Schedule, p.KeepLast, p.KeepDaily, p.KeepWeekly)
+	if !schedule.Next.IsZero() {
+		fmt.Printf("Next run: %s\n", schedule.Next.Format(time.RFC3339))
+	}
+	if status := schedule.Status; status != nil && !status.LastRun.IsZero() {
+		lastRun := status.LastRun.Format(time.RFC3339)
+		if status.LastSnapshot != "" {
+			fmt.Printf("Last run: %s took %s\n", lastRun, status.LastSnapshot)
+		} else {
+			fmt.Printf("Last run: %s\n", lastRun)
+		}
+		if status.Err != "" {
+			fmt.Printf("Last error: %s\n", status.Err)
+		}
+		if len(status.Pruned) > 0 {
+			fmt.Printf("Pruned: %s\n", strings.Join(status.Pruned, ", "))
+		}
+	}
+}
+
+// serviced snapshot unschedule SERVICEID
+func (c *ServicedCli) cmdSnapshotUnschedule(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "unschedule")
+		return
+	}
+
+	if err := c.driver.SetSnapshotPolicy(args[0], servicedefinition.SnapshotPolicy{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(args[0])
+	}
+}
@@ -8,8 +8,12 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/zzk/snapshotschedule"
 )
 
 const (
@@ -97,6 +101,60 @@ func (t SnapshotAPITest) Rollback(id string) error {
 	return t.RemoveSnapshot(id)
 }
 
+func (t SnapshotAPITest) ExportSnapshot(id, path string) (string, error) {
+	if ok, err := t.hasSnapshot(id); err != nil {
+		return "", err
+	} else if !ok {
+		return "", ErrNoSnapshotFound
+	} else if path == NilPath {
+		return "", nil
+	}
+	return path, nil
+}
+
+func (t SnapshotAPITest) ImportSnapshot(path string) (string, error) {
+	if t.fail {
+		return "", ErrInvalidSnapshot
+	} else if path == NilPath {
+		return "", nil
+	}
+	return DefaultTestSnapshots[0], nil
+}
+
+func (t SnapshotAPITest) SetSnapshotPolicy(id string, policy servicedefinition.SnapshotPolicy) error {
+	if t.fail {
+		return ErrInvalidSnapshot
+	} else if id == "test-service-0" {
+		return ErrNoServiceFound
+	}
+	return nil
+}
+
+func (t SnapshotAPITest) GetSnapshotSchedule(id string) (*dao.SnapshotSchedule, error) {
+	if t.fail {
+		return nil, ErrInvalidSnapshot
+	}
+	switch id {
+	case "test-service-0":
+		return nil, ErrNoServiceFound
+	case "test-service-1":
+		lastRun := time.Date(2014, 10, 1, 2, 0, 0, 0, time.UTC)
+		return &dao.SnapshotSchedule{
+			TenantID: id,
+			Policy:   servicedefinition.SnapshotPolicy{Schedule: "0 2 * * *", KeepLast: 3, KeepDaily: 7, KeepWeekly: 4},
+			Next:     lastRun.AddDate(0, 0, 1),
+			Status: &snapshotschedule.Status{
+				TenantID:     id,
+				LastRun:      lastRun,
+				LastSnapshot: "test-service-1-snapshot-2",
+				Snapshots:    []snapshotschedule.Snapshot{{ID: "test-service-1-snapshot-2", Created: lastRun}},
+				Pruned:       []string{"test-service-1-snapshot-0"},
+			},
+		}, nil
+	}
+	return &dao.SnapshotSchedule{TenantID: id}, nil
+}
+
 func ExampleServicedCLI_CmdSnapshotList() {
 	InitSnapshotAPITest("serviced", "snapshot", "list")
 
@@ -140,6 +198,11 @@ func ExampleServicedCLI_CmdSnapshotList_err() {
 	// no snapshots found
 }
 
+func ExampleServicedCLI_CmdSnapshotList_schedule() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitSnapshotAPITest("serviced", "snapshot", "list", "--schedule", "test-service-1")
+}
+
 func ExampleServicedCLI_CmdSnapshotAdd() {
 	InitSnapshotAPITest("serviced", "snapshot", "add", "test-service-99")
 
@@ -285,3 +348,155 @@ func ExampleServicedCLI_CmdSnapshotRollback_err() {
 	// Output:
 	// no snapshot found
 }
+
+func ExampleServicedCLI_CmdSnapshotExport() {
+	InitSnapshotAPITest("serviced", "snapshot", "export", "test-service-1-snapshot-1", "/path/to/file.tgz")
+
+	// Output:
+	// /path/to/file.tgz
+}
+
+func ExampleServicedCLI_CmdSnapshotExport_usage() {
+	InitSnapshotAPITest("serviced", "snapshot", "export", "test-service-1-snapshot-1")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    export - Writes a snapshot with the services and images of its tenant to a file
+	//
+	// USAGE:
+	//    command export [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced snapshot export SNAPSHOTID FILEPATH
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdSnapshotExport_err() {
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "export", "test-service-0-snapshot", "/path/to/file.tgz")
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "export", "test-service-1-snapshot-1", NilPath)
+
+	// Output:
+	// no snapshot found
+	// received nil path to export file
+}
+
+func ExampleServicedCLI_CmdSnapshotImport() {
+	InitSnapshotAPITest("serviced", "snapshot", "import", "/path/to/file.tgz")
+
+	// Output:
+	// test-service-1-snapshot-1
+}
+
+func ExampleServicedCLI_CmdSnapshotImport_usage() {
+	InitSnapshotAPITest("serviced", "snapshot", "import")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    import - Adds a snapshot from an exported file and restores its tenant to it
+	//
+	// USAGE:
+	//    command import [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced snapshot import FILEPATH
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdSnapshotImport_fail() {
+	DefaultSnapshotAPITest.fail = true
+	defer func() { DefaultSnapshotAPITest.fail = false }()
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "import", "/path/to/file.tgz")
+
+	// Output:
+	// invalid snapshot
+}
+
+func ExampleServicedCLI_CmdSnapshotImport_err() {
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "import", NilPath)
+
+	// Output:
+	// received nil snapshot
+}
+
+func ExampleServicedCLI_CmdSnapshotSchedule() {
+	InitSnapshotAPITest("serviced", "snapshot", "schedule", "test-service-1")
+	InitSnapshotAPITest("serviced", "snapshot", "schedule", "--keep-last", "3", "test-service-1", "@daily")
+
+	// Output:
+	// Schedule: 0 2 * * * (keep last 3, daily for 7 days, weekly for 4 weeks)
+	// Next run: 2014-10-02T02:00:00Z
+	// Last run: 2014-10-01T02:00:00Z took test-service-1-snapshot-2
+	// Pruned: test-service-1-snapshot-0
+	// test-service-1
+}
+
+func ExampleServicedCLI_CmdSnapshotSchedule_usage() {
+	InitSnapshotAPITest("serviced", "snapshot", "schedule")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    schedule - Shows or sets the snapshot schedule and retention rules of a tenant
+	//
+	// USAGE:
+	//    command schedule [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced snapshot schedule SERVICEID [CRONSPEC]
+	//
+	// OPTIONS:
+	//    --keep-last '0'	Number of most recent scheduled snapshots to keep
+	//    --keep-daily '0'	Number of days for which to keep the newest scheduled snapshot of the day
+	//    --keep-weekly '0'	Number of weeks for which to keep the newest scheduled snapshot of the week
+	//    --verbose, -v	Show JSON format
+}
+
+func ExampleServicedCLI_CmdSnapshotSchedule_err() {
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "schedule", "test-service-0")
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "schedule", "test-service-2")
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "schedule", "test-service-0", "@daily")
+
+	// Output:
+	// no service found
+	// no snapshot schedule found
+	// no service found
+}
+
+func ExampleServicedCLI_CmdSnapshotUnschedule() {
+	InitSnapshotAPITest("serviced", "snapshot", "unschedule", "test-service-1")
+
+	// Output:
+	// test-service-1
+}
+
+func ExampleServicedCLI_CmdSnapshotUnschedule_usage() {
+	InitSnapshotAPITest("serviced", "snapshot", "unschedule")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    unschedule - Stops taking scheduled snapshots of a tenant
+	//
+	// USAGE:
+	//    command unschedule [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced snapshot unschedule SERVICEID
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdSnapshotUnschedule_err() {
+	pipeStderr(InitSnapshotAPITest, "serviced", "snapshot", "unschedule", "test-service-0")
+
+	// Output:
+	// no service found
+}
@@ -8,9 +8,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/codegangsta/cli"
 	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/service"
 )
 
 // initTemplate is the initializer for serviced template
@@ -50,6 +52,25 @@ func (c *ServicedCli) initTemplate() {
 				Flags: []cli.Flag{
 					cli.BoolFlag{"manual-assign-ips", "Manually assign IP addresses"},
 				},
+			}, {
+				Name:         "diff",
+				Usage:        "Shows how a deployed tenant differs from a template",
+				Description:  "serviced template diff [--overwrite-context] TEMPLATEID TENANTID",
+				BashComplete: c.printTemplatesFirst,
+				Action:       c.cmdTemplateDiff,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+					cli.BoolFlag{"overwrite-context", "Replace the contexts of services with the template's"},
+				},
+			}, {
+				Name:         "apply",
+				Usage:        "Updates a deployed tenant to a template, keeping edited config files",
+				Description:  "serviced template apply [--overwrite-context] TEMPLATEID TENANTID",
+				BashComplete: c.printTemplatesFirst,
+				Action:       c.cmdTemplateApply,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"overwrite-context", "Replace the contexts of services with the template's"},
+				},
 			}, {
 				Name:        "compile",
 				Usage:       "Convert a directory of service definitions into a template",
@@ -232,6 +253,90 @@ func (c *ServicedCli) cmdTemplateDeploy(ctx *cli.Context) {
 	}
 }
 
+// serviced template diff [--verbose, -v] [--overwrite-context] TEMPLATEID TENANTID
+func (c *ServicedCli) cmdTemplateDiff(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "diff")
+		return
+	}
+
+	if diff, err := c.driver.DiffServiceTemplate(args[1], args[0], ctx.Bool("overwrite-context")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if diff == nil {
+		fmt.Fprintln(os.Stderr, "received nil template diff")
+	} else if ctx.Bool("verbose") {
+		if jsonDiff, err := json.MarshalIndent(diff, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal template diff: %s\n", err)
+		} else {
+			fmt.Println(string(jsonDiff))
+		}
+	} else {
+		printTemplateDiff(diff)
+	}
+}
+
+// serviced template apply [--overwrite-context] TEMPLATEID TENANTID
+func (c *ServicedCli) cmdTemplateApply(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "apply")
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "Applying template - please wait...")
+	if diff, err := c.driver.ApplyServiceTemplate(args[1], args[0], ctx.Bool("overwrite-context")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if diff == nil {
+		fmt.Fprintln(os.Stderr, "received nil template diff")
+	} else {
+		printTemplateDiff(diff)
+	}
+}
+
+// printTemplateDiff shows added (+), removed (-) and changed (~) services
+func printTemplateDiff(diff *service.TemplateDiff) {
+	if diff.Empty() {
+		fmt.Println("no changes")
+		return
+	}
+
+	for _, s := range diff.Services {
+		switch s.Change {
+		case service.DiffAdded:
+			fmt.Printf("+ %s\n", s.Path)
+		case service.DiffRemoved:
+			fmt.Printf("- %s (%s)\n", s.Path, s.ServiceID)
+		default:
+			fmt.Printf("~ %s (%s)\n", s.Path, s.ServiceID)
+			if s.Image != "" {
+				fmt.Printf("    image: %s\n", s.Image)
+			}
+			switch s.Context {
+			case service.ContextKept:
+				fmt.Println("    context: differs (keeping user edits)")
+			case service.ContextOverwritten:
+				fmt.Println("    context: overwritten")
+			}
+			if len(s.Fields) > 0 {
+				fmt.Printf("    changed: %s\n", strings.Join(s.Fields, ", "))
+			}
+			for _, ep := range s.Endpoints {
+				fmt.Printf("    endpoint %s: %s\n", ep.Name, ep.Change)
+			}
+			for _, conf := range s.ConfigFiles {
+				if conf.Preserved {
+					fmt.Printf("    config file %s: %s (keeping user edits)\n", conf.Name, conf.Change)
+				} else {
+					fmt.Printf("    config file %s: %s\n", conf.Name, conf.Change)
+				}
+			}
+		}
+	}
+}
+
 // serviced template compile DIR [[--map IMAGE,IMAGE] ...]
 func (c *ServicedCli) cmdTemplateCompile(ctx *cli.Context) {
 	args := ctx.Args()
@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"testing"
 )
 
@@ -120,6 +121,47 @@ func (t TemplateAPITest) DeployServiceTemplate(cfg api.DeployTemplateConfig) (*s
 	return &s, nil
 }
 
+func (t TemplateAPITest) DiffServiceTemplate(tenantID, templateID string, overwriteContext bool) (*service.TemplateDiff, error) {
+	if tpl, err := t.GetServiceTemplate(templateID); err != nil {
+		return nil, err
+	} else if tpl == nil {
+		return nil, ErrNoTemplateFound
+	} else if tenantID == NilTemplate {
+		return nil, nil
+	} else if templateID != "test-template-2" {
+		return &service.TemplateDiff{TenantID: tenantID, TemplateID: templateID}, nil
+	} else if overwriteContext {
+		diff := DefaultTestTemplateDiff
+		diff.Services = append([]service.ServiceDiff{}, diff.Services...)
+		diff.Services[0].Context = service.ContextOverwritten
+		return &diff, nil
+	}
+	return &DefaultTestTemplateDiff, nil
+}
+
+func (t TemplateAPITest) ApplyServiceTemplate(tenantID, templateID string, overwriteContext bool) (*service.TemplateDiff, error) {
+	return t.DiffServiceTemplate(tenantID, templateID, overwriteContext)
+}
+
+var DefaultTestTemplateDiff = service.TemplateDiff{
+	TenantID:   "test-tenant",
+	TemplateID: "test-template-2",
+	Services: []service.ServiceDiff{
+		{
+			Path:        "/Beta/zope",
+			ServiceID:   "test-service-1",
+			Change:      service.DiffChanged,
+			Image:       "localhost:5000/test-tenant/core -> zenoss/core:5.0.1",
+			Context:     service.ContextKept,
+			Fields:      []string{"Startup", "Instances"},
+			Endpoints:   []service.ItemDiff{{Name: "zope", Change: service.DiffChanged}},
+			ConfigFiles: []service.ItemDiff{{Name: "/etc/zope.conf", Change: service.DiffChanged, Preserved: true}},
+		},
+		{Path: "/Beta/redis", Change: service.DiffAdded},
+		{Path: "/Beta/memcached", ServiceID: "test-service-2", Change: service.DiffRemoved},
+	},
+}
+
 func TestServicedCLI_CmdTemplateList_one(t *testing.T) {
 	templateID := "test-template-1"
 
@@ -283,6 +325,120 @@ func ExampleServicedCLI_CmdTemplateDeploy_err() {
 	// received nil service definition
 }
 
+func ExampleServicedCLI_CmdTemplateDiff() {
+	InitTemplateAPITest("serviced", "template", "diff", "test-template-2", "test-tenant")
+	InitTemplateAPITest("serviced", "template", "diff", "test-template-1", "test-tenant")
+
+	// Output:
+	// ~ /Beta/zope (test-service-1)
+	//     image: localhost:5000/test-tenant/core -> zenoss/core:5.0.1
+	//     context: differs (keeping user edits)
+	//     changed: Startup, Instances
+	//     endpoint zope: changed
+	//     config file /etc/zope.conf: changed (keeping user edits)
+	// + /Beta/redis
+	// - /Beta/memcached (test-service-2)
+	// no changes
+}
+
+func TestServicedCLI_CmdTemplateDiff_verbose(t *testing.T) {
+	var actual service.TemplateDiff
+	output := pipe(InitTemplateAPITest, "serviced", "template", "diff", "--verbose", "test-template-2", "test-tenant")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshaling resource: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, DefaultTestTemplateDiff) {
+		t.Fatalf("got:\n%+v\nwant:\n%+v", actual, DefaultTestTemplateDiff)
+	}
+}
+
+func ExampleServicedCLI_CmdTemplateDiff_usage() {
+	InitTemplateAPITest("serviced", "template", "diff", "test-template-2")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    diff - Shows how a deployed tenant differs from a template
+	//
+	// USAGE:
+	//    command diff [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced template diff [--overwrite-context] TEMPLATEID TENANTID
+	//
+	// OPTIONS:
+	//    --verbose, -v	Show JSON format
+	//    --overwrite-context	Replace the contexts of services with the template's
+}
+
+func ExampleServicedCLI_CmdTemplateDiff_err() {
+	pipeStderr(InitTemplateAPITest, "serviced", "template", "diff", "test-template-0", "test-tenant")
+	pipeStderr(InitTemplateAPITest, "serviced", "template", "diff", "test-template-1", NilTemplate)
+
+	// Output:
+	// no templates found
+	// received nil template diff
+}
+
+func ExampleServicedCLI_CmdTemplateApply() {
+	InitTemplateAPITest("serviced", "template", "apply", "test-template-2", "test-tenant")
+
+	// Output:
+	// ~ /Beta/zope (test-service-1)
+	//     image: localhost:5000/test-tenant/core -> zenoss/core:5.0.1
+	//     context: differs (keeping user edits)
+	//     changed: Startup, Instances
+	//     endpoint zope: changed
+	//     config file /etc/zope.conf: changed (keeping user edits)
+	// + /Beta/redis
+	// - /Beta/memcached (test-service-2)
+}
+
+func ExampleServicedCLI_CmdTemplateApply_overwriteContext() {
+	InitTemplateAPITest("serviced", "template", "apply", "--overwrite-context", "test-template-2", "test-tenant")
+
+	// Output:
+	// ~ /Beta/zope (test-service-1)
+	//     image: localhost:5000/test-tenant/core -> zenoss/core:5.0.1
+	//     context: overwritten
+	//     changed: Startup, Instances
+	//     endpoint zope: changed
+	//     config file /etc/zope.conf: changed (keeping user edits)
+	// + /Beta/redis
+	// - /Beta/memcached (test-service-2)
+}
+
+func ExampleServicedCLI_CmdTemplateApply_usage() {
+	InitTemplateAPITest("serviced", "template", "apply")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    apply - Updates a deployed tenant to a template, keeping edited config files
+	//
+	// USAGE:
+	//    command apply [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced template apply [--overwrite-context] TEMPLATEID TENANTID
+	//
+	// OPTIONS:
+	//    --overwrite-context	Replace the contexts of services with the template's
+}
+
+func ExampleServicedCLI_CmdTemplateApply_err() {
+	DefaultTemplateAPITest.fail = true
+	defer func() { DefaultTemplateAPITest.fail = false }()
+	pipeStderr(InitTemplateAPITest, "serviced", "template", "apply", "test-template-2", "test-tenant")
+
+	// Output:
+	// Applying template - please wait...
+	// invalid template
+}
+
 func TestServicedCLI_CmdTemplateCompile(t *testing.T) {
 	dir := "/path/to/template"
 
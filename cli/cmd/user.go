@@ -0,0 +1,275 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/role"
+)
+
+// initUser is the initializer for serviced user
+func (c *ServicedCli) initUser() {
+	grantFlags := []cli.Flag{
+		cli.StringFlag{"resource", "", "Limit the role to this type of resource (service, host, pool, template, snapshot, backup, user, audit, event)"},
+		cli.StringFlag{"pool", "", "Limit the role to the resources of this pool"},
+		cli.StringFlag{"tenant", "", "Limit the role to the services of this tenant"},
+	}
+
+	c.app.Commands = append(c.app.Commands, cli.Command{
+		Name:        "user",
+		Usage:       "Administers the roles of users",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:        "list",
+				Usage:       "Lists the roles granted to users",
+				Description: "serviced user list",
+				Action:      c.cmdUserList,
+				Flags: []cli.Flag{
+					cli.BoolFlag{"verbose, v", "Show JSON format"},
+				},
+			}, {
+				Name:        "grant",
+				Usage:       "Grants a role (viewer, operator or admin) to a user",
+				Description: "serviced user grant USER ROLE",
+				Action:      c.cmdUserGrant,
+				Flags:       grantFlags,
+			}, {
+				Name:        "revoke",
+				Usage:       "Revokes a role from a user",
+				Description: "serviced user revoke USER ROLE",
+				Action:      c.cmdUserRevoke,
+				Flags:       grantFlags,
+			}, {
+				Name:        "remove",
+				ShortName:   "rm",
+				Usage:       "Removes all of the roles of a user",
+				Description: "serviced user remove USER ...",
+				Action:      c.cmdUserRemove,
+			}, {
+				Name:        "token",
+				Usage:       "Administers API tokens for REST clients",
+				Description: "",
+				Subcommands: []cli.Command{
+					{
+						Name:        "create",
+						Usage:       "Creates an API token and prints it",
+						Description: "serviced user token create [USER]",
+						Action:      c.cmdUserTokenCreate,
+						Flags: []cli.Flag{
+							cli.StringFlag{"description", "", "What the token is used for"},
+							cli.StringFlag{"ttl", "", "How long until the token expires, such as 720h; never if not set"},
+							cli.StringFlag{"role", "", "Limit the token to this role (viewer, operator or admin)"},
+							cli.StringFlag{"resource", "", "Limit the token to this type of resource"},
+							cli.StringFlag{"pool", "", "Limit the token to the resources of this pool"},
+							cli.StringFlag{"tenant", "", "Limit the token to the services of this tenant"},
+						},
+					}, {
+						Name:        "list",
+						Usage:       "Lists the API tokens of a user",
+						Description: "serviced user token list [USER]",
+						Action:      c.cmdUserTokenList,
+						Flags: []cli.Flag{
+							cli.BoolFlag{"verbose, v", "Show JSON format"},
+						},
+					}, {
+						Name:        "revoke",
+						Usage:       "Revokes API tokens",
+						Description: "serviced user token revoke TOKENID ...",
+						Action:      c.cmdUserTokenRevoke,
+					},
+				},
+			},
+		},
+	})
+}
+
+// serviced user list
+func (c *ServicedCli) cmdUserList(ctx *cli.Context) {
+	if roles, err := c.driver.GetUserRoles(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if roles == nil || len(roles) == 0 {
+		fmt.Fprintln(os.Stderr, "no roles found; every user is an administrator")
+	} else if ctx.Bool("verbose") {
+		if jsonRoles, err := json.MarshalIndent(roles, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal user roles: %s\n", err)
+		} else {
+			fmt.Println(string(jsonRoles))
+		}
+	} else {
+		tableRoles := newtable(0, 8, 2)
+		tableRoles.printrow("USER", "ROLE", "RESOURCE", "POOL", "TENANT")
+		for _, r := range roles {
+			for _, g := range r.Grants {
+				tableRoles.printrow(r.User, g.Role, orAll(g.Resource), orAll(g.PoolID), orAll(g.TenantID))
+			}
+		}
+		tableRoles.flush()
+	}
+}
+
+// orAll shows empty grant fields as matching everything
+func orAll(value string) string {
+	if value == "" {
+		return "*"
+	}
+	return value
+}
+
+// grantFromContext reads the user and grant of the grant and revoke commands
+func grantFromContext(ctx *cli.Context, command string) (string, role.Grant, bool) {
+	args := ctx.Args()
+	if len(args) < 2 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, command)
+		return "", role.Grant{}, false
+	}
+
+	return args[0], role.Grant{
+		Role:     args[1],
+		Resource: ctx.String("resource"),
+		PoolID:   ctx.String("pool"),
+		TenantID: ctx.String("tenant"),
+	}, true
+}
+
+// serviced user grant [--resource RESOURCE] [--pool POOLID] [--tenant TENANTID] USER ROLE
+func (c *ServicedCli) cmdUserGrant(ctx *cli.Context) {
+	user, grant, ok := grantFromContext(ctx, "grant")
+	if !ok {
+		return
+	}
+
+	if err := c.driver.GrantRole(user, grant); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(user)
+	}
+}
+
+// serviced user revoke [--resource RESOURCE] [--pool POOLID] [--tenant TENANTID] USER ROLE
+func (c *ServicedCli) cmdUserRevoke(ctx *cli.Context) {
+	user, grant, ok := grantFromContext(ctx, "revoke")
+	if !ok {
+		return
+	}
+
+	if err := c.driver.RevokeRole(user, grant); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(user)
+	}
+}
+
+// serviced user remove USER ...
+func (c *ServicedCli) cmdUserRemove(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "remove")
+		return
+	}
+
+	for _, user := range args {
+		if err := c.driver.RemoveUserRoles(user); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", user, err)
+		} else {
+			fmt.Println(user)
+		}
+	}
+}
+
+// serviced user token create [--description DESCRIPTION] [--ttl DURATION] [--role ROLE [--resource RESOURCE] [--pool POOLID] [--tenant TENANTID]] [USER]
+func (c *ServicedCli) cmdUserTokenCreate(ctx *cli.Context) {
+	cfg := api.TokenConfig{Description: ctx.String("description")}
+	if args := ctx.Args(); len(args) > 0 {
+		cfg.User = args[0]
+	}
+	if ttl := ctx.String("ttl"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			fmt.Fprintln(os.Stderr, "ttl must be a positive duration, such as 720h")
+			return
+		}
+		cfg.TTL = d
+	}
+	grant := role.Grant{
+		Role:     ctx.String("role"),
+		Resource: ctx.String("resource"),
+		PoolID:   ctx.String("pool"),
+		TenantID: ctx.String("tenant"),
+	}
+	if grant.Role != "" {
+		cfg.Grants = []role.Grant{grant}
+	} else if grant != (role.Grant{}) {
+		fmt.Fprintln(os.Stderr, "role is required to limit the scope of a token")
+		return
+	}
+
+	if bearer, err := c.driver.CreateToken(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		fmt.Println(bearer)
+	}
+}
+
+// serviced user token list [USER]
+func (c *ServicedCli) cmdUserTokenList(ctx *cli.Context) {
+	var user string
+	if args := ctx.Args(); len(args) > 0 {
+		user = args[0]
+	}
+
+	if tokens, err := c.driver.GetTokens(user); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if tokens == nil || len(tokens) == 0 {
+		fmt.Fprintln(os.Stderr, "no API tokens found")
+	} else if ctx.Bool("verbose") {
+		if jsonTokens, err := json.MarshalIndent(tokens, " ", "  "); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to marshal API tokens: %s\n", err)
+		} else {
+			fmt.Println(string(jsonTokens))
+		}
+	} else {
+		tableTokens := newtable(0, 8, 2)
+		tableTokens.printrow("ID", "USER", "DESCRIPTION", "CREATED", "EXPIRES", "LAST USED")
+		for _, t := range tokens {
+			tableTokens.printrow(t.ID, t.User, t.Description, formatTime(t.Created), formatTime(t.Expires), formatTime(t.LastUsed))
+		}
+		tableTokens.flush()
+	}
+}
+
+// formatTime shows zero times as never
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+// serviced user token revoke TOKENID ...
+func (c *ServicedCli) cmdUserTokenRevoke(ctx *cli.Context) {
+	args := ctx.Args()
+	if len(args) < 1 {
+		fmt.Printf("Incorrect Usage.\n\n")
+		cli.ShowCommandHelp(ctx, "revoke")
+		return
+	}
+
+	for _, id := range args {
+		if err := c.driver.RevokeToken(id); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", id, err)
+		} else {
+			fmt.Println(id)
+		}
+	}
+}
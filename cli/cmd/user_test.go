@@ -0,0 +1,309 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/cli/api"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/token"
+)
+
+var DefaultUserAPITest = UserAPITest{roles: DefaultTestUserRoles, tokens: DefaultTestTokens}
+
+var DefaultTestUserRoles = []*role.UserRoles{
+	{
+		User:   "alice",
+		Grants: []role.Grant{{Role: role.Admin}},
+	}, {
+		User: "bob",
+		Grants: []role.Grant{
+			{Role: role.Viewer},
+			{Role: role.Operator, Resource: role.ResourceService, PoolID: "test-pool"},
+		},
+	},
+}
+
+var DefaultTestTokens = []*token.Token{
+	{
+		ID:          "test-token-1",
+		User:        "bob",
+		Description: "nightly backups",
+		Grants:      []role.Grant{{Role: role.Operator, Resource: role.ResourceBackup}},
+		Created:     time.Date(2014, 8, 1, 12, 0, 0, 0, time.UTC),
+		LastUsed:    time.Date(2014, 8, 2, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+var ErrNoRoles = errors.New("user has not been granted any roles")
+
+type UserAPITest struct {
+	api.API
+	roles  []*role.UserRoles
+	tokens []*token.Token
+}
+
+func InitUserAPITest(args ...string) {
+	New(DefaultUserAPITest).Run(args)
+}
+
+func (t UserAPITest) GetUserRoles() ([]*role.UserRoles, error) {
+	return t.roles, nil
+}
+
+func (t UserAPITest) find(user string) *role.UserRoles {
+	for _, r := range t.roles {
+		if r.User == user {
+			return r
+		}
+	}
+	return nil
+}
+
+func (t UserAPITest) GrantRole(user string, grant role.Grant) error {
+	if grant.Role != role.Viewer && grant.Role != role.Operator && grant.Role != role.Admin {
+		return fmt.Errorf("unknown role %s", grant.Role)
+	}
+	return nil
+}
+
+func (t UserAPITest) RevokeRole(user string, grant role.Grant) error {
+	r := t.find(user)
+	if r == nil {
+		return ErrNoRoles
+	}
+	for _, g := range r.Grants {
+		if g == grant {
+			return nil
+		}
+	}
+	return fmt.Errorf("user %s has not been granted role %s", user, grant.Role)
+}
+
+func (t UserAPITest) RemoveUserRoles(user string) error {
+	if t.find(user) == nil {
+		return ErrNoRoles
+	}
+	return nil
+}
+
+func (t UserAPITest) CreateToken(config api.TokenConfig) (string, error) {
+	if config.User == "" {
+		config.User = "bob"
+	}
+	if t.find(config.User) == nil {
+		return "", ErrNoRoles
+	}
+	return "test-token-2.secret", nil
+}
+
+func (t UserAPITest) GetTokens(user string) ([]*token.Token, error) {
+	var tokens []*token.Token
+	for _, tok := range t.tokens {
+		if user == "" || tok.User == user {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens, nil
+}
+
+func (t UserAPITest) RevokeToken(id string) error {
+	for _, tok := range t.tokens {
+		if tok.ID == id {
+			return nil
+		}
+	}
+	return errors.New("API token not found")
+}
+
+func ExampleServicedCLI_CmdUserList() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitUserAPITest("serviced", "user", "list")
+}
+
+func TestServicedCLI_CmdUserList_verbose(t *testing.T) {
+	var actual []*role.UserRoles
+	output := pipe(InitUserAPITest, "serviced", "user", "list", "--verbose")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshalling resource: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, DefaultTestUserRoles) {
+		t.Fatalf("\ngot:\n%+v\nwant:\n%+v", actual, DefaultTestUserRoles)
+	}
+}
+
+func ExampleServicedCLI_CmdUserList_err() {
+	DefaultUserAPITest.roles = nil
+	defer func() { DefaultUserAPITest.roles = DefaultTestUserRoles }()
+	pipeStderr(InitUserAPITest, "serviced", "user", "list")
+
+	// Output:
+	// no roles found; every user is an administrator
+}
+
+func ExampleServicedCLI_CmdUserGrant() {
+	InitUserAPITest("serviced", "user", "grant", "--pool", "test-pool", "carol", "operator")
+
+	// Output:
+	// carol
+}
+
+func ExampleServicedCLI_CmdUserGrant_usage() {
+	InitUserAPITest("serviced", "user", "grant", "carol")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    grant - Grants a role (viewer, operator or admin) to a user
+	//
+	// USAGE:
+	//    command grant [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced user grant USER ROLE
+	//
+	// OPTIONS:
+	//    --resource 	Limit the role to this type of resource (service, host, pool, template, snapshot, backup, user, audit, event)
+	//    --pool 	Limit the role to the resources of this pool
+	//    --tenant 	Limit the role to the services of this tenant
+}
+
+func ExampleServicedCLI_CmdUserGrant_err() {
+	pipeStderr(InitUserAPITest, "serviced", "user", "grant", "carol", "superuser")
+
+	// Output:
+	// unknown role superuser
+}
+
+func ExampleServicedCLI_CmdUserRevoke() {
+	InitUserAPITest("serviced", "user", "revoke", "--resource", "service", "--pool", "test-pool", "bob", "operator")
+
+	// Output:
+	// bob
+}
+
+func ExampleServicedCLI_CmdUserRevoke_err() {
+	pipeStderr(InitUserAPITest, "serviced", "user", "revoke", "bob", "admin")
+	pipeStderr(InitUserAPITest, "serviced", "user", "revoke", "carol", "viewer")
+
+	// Output:
+	// user bob has not been granted role admin
+	// user has not been granted any roles
+}
+
+func ExampleServicedCLI_CmdUserRemove() {
+	InitUserAPITest("serviced", "user", "remove", "alice", "bob")
+
+	// Output:
+	// alice
+	// bob
+}
+
+func ExampleServicedCLI_CmdUserRemove_usage() {
+	InitUserAPITest("serviced", "user", "rm")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    remove - Removes all of the roles of a user
+	//
+	// USAGE:
+	//    command remove [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced user remove USER ...
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdUserRemove_err() {
+	pipeStderr(InitUserAPITest, "serviced", "user", "remove", "carol")
+
+	// Output:
+	// carol: user has not been granted any roles
+}
+
+func ExampleServicedCLI_CmdUserTokenCreate() {
+	InitUserAPITest("serviced", "user", "token", "create", "--ttl", "720h", "--role", "viewer")
+
+	// Output:
+	// test-token-2.secret
+}
+
+func ExampleServicedCLI_CmdUserTokenCreate_fail() {
+	pipeStderr(InitUserAPITest, "serviced", "user", "token", "create", "--ttl", "forever")
+	pipeStderr(InitUserAPITest, "serviced", "user", "token", "create", "--pool", "test-pool")
+	pipeStderr(InitUserAPITest, "serviced", "user", "token", "create", "carol")
+
+	// Output:
+	// ttl must be a positive duration, such as 720h
+	// role is required to limit the scope of a token
+	// user has not been granted any roles
+}
+
+func ExampleServicedCLI_CmdUserTokenList() {
+	// Gofmt cleans up the spaces at the end of each row
+	InitUserAPITest("serviced", "user", "token", "list")
+}
+
+func TestServicedCLI_CmdUserTokenList_verbose(t *testing.T) {
+	var actual []*token.Token
+	output := pipe(InitUserAPITest, "serviced", "user", "token", "list", "--verbose", "bob")
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("error unmarshalling resource: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual, DefaultTestTokens) {
+		t.Fatalf("\ngot:\n%+v\nwant:\n%+v", actual, DefaultTestTokens)
+	}
+}
+
+func ExampleServicedCLI_CmdUserTokenList_err() {
+	pipeStderr(InitUserAPITest, "serviced", "user", "token", "list", "carol")
+
+	// Output:
+	// no API tokens found
+}
+
+func ExampleServicedCLI_CmdUserTokenRevoke() {
+	InitUserAPITest("serviced", "user", "token", "revoke", "test-token-1")
+
+	// Output:
+	// test-token-1
+}
+
+func ExampleServicedCLI_CmdUserTokenRevoke_usage() {
+	InitUserAPITest("serviced", "user", "token", "revoke")
+
+	// Output:
+	// Incorrect Usage.
+	//
+	// NAME:
+	//    revoke - Revokes API tokens
+	//
+	// USAGE:
+	//    command revoke [command options] [arguments...]
+	//
+	// DESCRIPTION:
+	//    serviced user token revoke TOKENID ...
+	//
+	// OPTIONS:
+}
+
+func ExampleServicedCLI_CmdUserTokenRevoke_err() {
+	pipeStderr(InitUserAPITest, "serviced", "user", "token", "revoke", "test-token-0")
+
+	// Output:
+	// test-token-0: API token not found
+}
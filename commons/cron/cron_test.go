@@ -0,0 +1,65 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// a wednesday
+	start := time.Date(2014, 10, 1, 10, 30, 15, 0, time.UTC)
+	for _, tc := range []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2014, 10, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2014, 10, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2014, 10, 2, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2014, 10, 2, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2014, 10, 1, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2014, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2014, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{"30 4 1,15 * *", time.Date(2014, 10, 15, 4, 30, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2014, 10, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2014, 10, 1, 13, 0, 0, 0, time.UTC)},
+		// either day matches when both are restricted
+		{"0 0 13 * 5", time.Date(2014, 10, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", tc.expr, err)
+			continue
+		}
+		if actual := s.Next(start); !actual.Equal(tc.expected) {
+			t.Errorf("Expected %q after %s to be %s, got %s", tc.expr, start, tc.expected, actual)
+		}
+	}
+}
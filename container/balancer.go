@@ -0,0 +1,103 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package container
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+// ejectionTime is how long an address that could not be dialed is skipped
+const ejectionTime = 30 * time.Second
+
+// backend is an address of an imported endpoint and its connections
+type backend struct {
+	address  string
+	active   int       // connections that are open
+	total    int       // connections made
+	failures int       // dials that failed
+	ejected  time.Time // the address is skipped until then
+}
+
+// BackendStats are the connection counters of an address of an endpoint
+type BackendStats struct {
+	Address  string
+	Active   int
+	Total    int
+	Failures int
+	Ejected  bool
+}
+
+// ProxyStats are the connection counters of an imported endpoint
+type ProxyStats struct {
+	Name         string
+	LoadBalancer string
+	Backends     []BackendStats
+}
+
+// balancer picks the backend of a new connection
+type balancer interface {
+	// pick chooses one of the candidates, of which there is at least one
+	pick(candidates []*backend) *backend
+}
+
+// newBalancer returns the balancer of a load balancing strategy; round robin
+// is used if it is not known
+func newBalancer(strategy string) balancer {
+	switch strategy {
+	case servicedefinition.LoadBalancerLeastConnections:
+		return leastConnections{}
+	case servicedefinition.LoadBalancerTwoChoices:
+		return twoChoices{rand.New(rand.NewSource(time.Now().UnixNano()))}
+	}
+	return &roundRobin{}
+}
+
+// roundRobin sends each connection to the next backend
+type roundRobin struct {
+	next int
+}
+
+func (b *roundRobin) pick(candidates []*backend) *backend {
+	b.next++
+	return candidates[b.next%len(candidates)]
+}
+
+// leastConnections sends each connection to the backend with the fewest
+// active connections, or the fewest connections made if that is a tie
+type leastConnections struct{}
+
+func (leastConnections) pick(candidates []*backend) *backend {
+	best := candidates[0]
+	for _, b := range candidates[1:] {
+		if b.active < best.active || (b.active == best.active && b.total < best.total) {
+			best = b
+		}
+	}
+	return best
+}
+
+// twoChoices sends each connection to the backend with fewer active
+// connections of two picked at random
+type twoChoices struct {
+	random *rand.Rand
+}
+
+func (b twoChoices) pick(candidates []*backend) *backend {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	i := b.random.Intn(len(candidates))
+	j := b.random.Intn(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+	if candidates[j].active < candidates[i].active {
+		return candidates[j]
+	}
+	return candidates[i]
+}
@@ -0,0 +1,46 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package container
+
+import (
+	"testing"
+
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func TestBalancer_RoundRobin(t *testing.T) {
+	backends := []*backend{{address: "a"}, {address: "b"}, {address: "c"}}
+	b := newBalancer("")
+	seen := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		seen[b.pick(backends).address]++
+	}
+	for _, be := range backends {
+		if seen[be.address] != 2 {
+			t.Errorf("expected 2 connections to %s, got %d", be.address, seen[be.address])
+		}
+	}
+}
+
+func TestBalancer_LeastConnections(t *testing.T) {
+	backends := []*backend{{address: "a", active: 3}, {address: "b", active: 1, total: 5}, {address: "c", active: 1, total: 2}}
+	b := newBalancer(servicedefinition.LoadBalancerLeastConnections)
+	if picked := b.pick(backends); picked.address != "c" {
+		t.Errorf("expected c, got %s", picked.address)
+	}
+}
+
+func TestBalancer_TwoChoices(t *testing.T) {
+	backends := []*backend{{address: "a", active: 10}, {address: "b", active: 0}}
+	b := newBalancer(servicedefinition.LoadBalancerTwoChoices)
+	for i := 0; i < 10; i++ {
+		if picked := b.pick(backends); picked.address != "b" {
+			t.Fatalf("expected the less busy of two backends, got %s", picked.address)
+		}
+	}
+	if picked := b.pick(backends[:1]); picked.address != "a" {
+		t.Errorf("expected the only backend, got %s", picked.address)
+	}
+}
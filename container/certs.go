@@ -0,0 +1,65 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package container
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/node"
+	servicedproxy "github.com/control-center/serviced/proxy"
+)
+
+// certificateCheckInterval is how often the container picks up certificates
+// that have been reissued to the agent
+const certificateCheckInterval = time.Minute
+
+// cMuxDialer dials the muxes of remote hosts with the certificates of the agent
+var cMuxDialer servicedproxy.MuxDialer
+
+// getAgentCertificates retrieves the certificates the agent dials muxes with
+func getAgentCertificates(lbClientPort string) (*servicedproxy.CertificateBundle, error) {
+	client, err := node.NewLBClient(lbClientPort)
+	if err != nil {
+		glog.Errorf("Could not create a client to endpoint: %s, %s", lbClientPort, err)
+		return nil, err
+	}
+	defer client.Close()
+
+	var bundle servicedproxy.CertificateBundle
+	if err := client.GetProxyCertificates(&bundle); err != nil {
+		return nil, err
+	}
+	glog.V(1).Infof("getAgentCertificates: generation %d", bundle.Generation)
+	return &bundle, nil
+}
+
+// watchCertificates keeps the certificates muxes are dialed with up to date
+// with those of the agent
+func (c *Controller) watchCertificates() {
+	refresh := func() {
+		bundle, err := getAgentCertificates(c.options.ServicedEndpoint)
+		if err != nil {
+			// muxes cannot be dialed until the master has issued the
+			// certificates of the agent
+			if err.Error() == servicedproxy.ErrNoCertificates.Error() {
+				glog.Warningf("Agent has no mux certificates yet")
+			} else {
+				glog.Warningf("Could not get mux certificates: %s", err)
+			}
+			return
+		}
+		if err := cMuxDialer.SetCertificates(bundle); err != nil {
+			glog.Errorf("Could not use mux certificates: %s", err)
+		}
+	}
+
+	refresh()
+	go func() {
+		for _ = range time.Tick(certificateCheckInterval) {
+			refresh()
+		}
+	}()
+}
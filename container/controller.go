@@ -413,6 +413,9 @@ func (c *Controller) Run() (err error) {
 	var startAfter <-chan time.Time
 	service := &subprocess.Instance{}
 	serviceExited := make(chan error, 1)
+	if c.options.Mux.Enabled && c.options.Mux.TLS {
+		c.watchCertificates()
+	}
 	c.handleControlCenterImports()
 	c.watchRemotePorts()
 	go c.checkPrereqs(prereqsPassed)
@@ -518,49 +521,57 @@ func (c *Controller) kickOffHealthChecks() map[string]chan bool {
 	for key, mapping := range healthChecks {
 		glog.Infof("Kicking off health check %s.", key)
 		exitChannels[key] = make(chan bool)
-		glog.Infof("Setting up health check: %s", mapping.Script)
-		go c.handleHealthCheck(key, mapping.Script, mapping.Interval, exitChannels[key])
+		glog.Infof("Setting up %s health check: %+v", mapping.Kind(), mapping)
+		go c.handleHealthCheck(key, instanceID, mapping, exitChannels[key])
 	}
 	return exitChannels
 }
 
-func (c *Controller) handleHealthCheck(name string, script string, interval time.Duration, exitChannel chan bool) {
+func (c *Controller) handleHealthCheck(name string, instanceID int, hc domain.HealthCheck, exitChannel chan bool) {
 	client, err := node.NewLBClient(c.options.ServicedEndpoint)
 	if err != nil {
 		glog.Errorf("Could not create a client to endpoint: %s, %s", c.options.ServicedEndpoint, err)
 		return
 	}
 	defer client.Close()
-	scriptFile, err := ioutil.TempFile("", name)
-	if err != nil {
-		glog.Errorf("Error creating temporary file for health check %s: %s", name, err)
-		return
-	}
-	defer scriptFile.Close()
-	defer os.Remove(scriptFile.Name())
-	err = ioutil.WriteFile(scriptFile.Name(), []byte(script), os.FileMode(0777))
-	if err != nil {
-		glog.Errorf("Error writing script for health check %s: %s", name, err)
-		return
+	var scriptPath string
+	if hc.Kind() == domain.HealthCheckExec {
+		scriptFile, err := ioutil.TempFile("", name)
+		if err != nil {
+			glog.Errorf("Error creating temporary file for health check %s: %s", name, err)
+			return
+		}
+		defer scriptFile.Close()
+		defer os.Remove(scriptFile.Name())
+		err = ioutil.WriteFile(scriptFile.Name(), []byte(hc.Script), os.FileMode(0777))
+		if err != nil {
+			glog.Errorf("Error writing script for health check %s: %s", name, err)
+			return
+		}
+		scriptFile.Close()
+		err = os.Chmod(scriptFile.Name(), os.FileMode(0777))
+		if err != nil {
+			glog.Errorf("Error setting script executable for health check %s: %s", name, err)
+			return
+		}
+		scriptPath = scriptFile.Name()
 	}
-	scriptFile.Close()
-	err = os.Chmod(scriptFile.Name(), os.FileMode(0777))
+	expectedBody, err := compileExpectedBody(hc)
 	if err != nil {
-		glog.Errorf("Error setting script executable for health check %s: %s", name, err)
+		glog.Errorf("Error compiling expected body of health check %s: %s", name, err)
 		return
 	}
 	var unused int
 	for {
 		select {
-		case <-time.After(interval):
-			cmd := exec.Command("sh", "-c", scriptFile.Name())
-			err = cmd.Run()
+		case <-time.After(hc.Interval):
+			err = runHealthCheck(hc, scriptPath, expectedBody)
 			if err == nil {
 				glog.V(4).Infof("Health check %s succeeded.", name)
-				_ = client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, name, time.Now().String(), "passed"}, &unused)
+				_ = client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, instanceID, name, time.Now().String(), "passed"}, &unused)
 			} else {
-				glog.Warningf("Health check %s failed.", name)
-				_ = client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, name, time.Now().String(), "failed"}, &unused)
+				glog.Warningf("Health check %s failed: %s", name, err)
+				_ = client.LogHealthCheck(domain.HealthCheckResult{c.options.Service.ID, instanceID, name, time.Now().String(), "failed"}, &unused)
 			}
 		case <-exitChannel:
 			return
@@ -613,13 +624,13 @@ func (c *Controller) handleControlCenterImports() {
 		}
 
 		// set proxy addresses
-		c.setProxyAddresses(key, endpointList, endpointList[0].VirtualAddress, cc_endpoint_purpose)
+		c.setProxyAddresses(key, endpointList, endpointList[0].VirtualAddress, cc_endpoint_purpose, "")
 
 		// add/replace entries in importedEndpoints
 		instanceIDStr := fmt.Sprintf("%d", endpointList[0].InstanceID)
 		setImportedEndpoint(&c.importedEndpoints, c.tenantID,
 			endpointList[0].Application, instanceIDStr,
-			endpointList[0].VirtualAddress, cc_endpoint_purpose,
+			endpointList[0].VirtualAddress, cc_endpoint_purpose, "",
 			endpointList[0].ContainerPort)
 
 		// TODO: agent needs to register controlplane and controlplane_consumer
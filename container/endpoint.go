@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/commons"
 	coordclient "github.com/control-center/serviced/coordinator/client"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain/service"
@@ -54,6 +55,7 @@ type importedEndpoint struct {
 	instanceID     string
 	virtualAddress string
 	purpose        string
+	loadBalancer   string
 	port           uint16
 }
 
@@ -234,7 +236,7 @@ func buildImportedEndpoints(conn coordclient.Connection, tenantID string, state
 			}
 			instanceIDStr := fmt.Sprintf("%d", endpoint.InstanceID)
 			setImportedEndpoint(&result, tenantID, endpoint.Application,
-				instanceIDStr, endpoint.VirtualAddress, defep.Purpose, endpoint.ContainerPort)
+				instanceIDStr, endpoint.VirtualAddress, defep.Purpose, defep.LoadBalancer, endpoint.ContainerPort)
 		}
 	}
 
@@ -268,7 +270,7 @@ func buildApplicationEndpoint(state *servicestate.ServiceState, endpoint *servic
 	}
 	ae.HostIP = state.HostIP
 	if len(state.PortMapping) > 0 {
-		pmKey := fmt.Sprintf("%d/%s", ae.ContainerPort, ae.Protocol)
+		pmKey := fmt.Sprintf("%d/%s", ae.ContainerPort, strings.ToLower(ae.Protocol))
 		pm := state.PortMapping[pmKey]
 		if len(pm) > 0 {
 			port, err := strconv.Atoi(pm[0].HostPort)
@@ -288,11 +290,12 @@ func buildApplicationEndpoint(state *servicestate.ServiceState, endpoint *servic
 }
 
 // setImportedEndpoint sets an imported endpoint
-func setImportedEndpoint(importedEndpoints *map[string]importedEndpoint, tenantID, endpointID, instanceID, virtualAddress, purpose string, port uint16) {
+func setImportedEndpoint(importedEndpoints *map[string]importedEndpoint, tenantID, endpointID, instanceID, virtualAddress, purpose, loadBalancer string, port uint16) {
 	ie := importedEndpoint{}
 	ie.endpointID = endpointID
 	ie.virtualAddress = virtualAddress
 	ie.purpose = purpose
+	ie.loadBalancer = loadBalancer
 	ie.instanceID = instanceID
 	ie.port = port
 	key := registry.TenantEndpointKey(tenantID, endpointID)
@@ -442,6 +445,8 @@ func (c *Controller) processTenantEndpoint(conn coordclient.Connection, parentPa
 	tenantEndpointID := parts[len(parts)-1]
 
 	if ep := c.getMatchingEndpoint(tenantEndpointID); ep != nil {
+		// draining endpoints take no new connections
+		hostContainerIDs = registry.ActiveItems(conn, parentPath, hostContainerIDs)
 		endpoints := make([]*dao.ApplicationEndpoint, len(hostContainerIDs))
 		for ii, hostContainerID := range hostContainerIDs {
 			path := fmt.Sprintf("%s/%s", parentPath, hostContainerID)
@@ -458,12 +463,12 @@ func (c *Controller) processTenantEndpoint(conn coordclient.Connection, parentPa
 			}
 		}
 
-		c.setProxyAddresses(tenantEndpointID, endpoints, ep.virtualAddress, ep.purpose)
+		c.setProxyAddresses(tenantEndpointID, endpoints, ep.virtualAddress, ep.purpose, ep.loadBalancer)
 	}
 }
 
 // setProxyAddresses tells the proxies to update with addresses
-func (c *Controller) setProxyAddresses(tenantEndpointID string, endpoints []*dao.ApplicationEndpoint, importVirtualAddress, purpose string) {
+func (c *Controller) setProxyAddresses(tenantEndpointID string, endpoints []*dao.ApplicationEndpoint, importVirtualAddress, purpose, loadBalancer string) {
 	glog.Infof("starting setProxyAddresses(tenantEndpointID: %s, purpose: %s)", tenantEndpointID, purpose)
 	proxiesLock.Lock()
 	defer proxiesLock.Unlock()
@@ -530,7 +535,7 @@ func (c *Controller) setProxyAddresses(tenantEndpointID string, endpoints []*dao
 				}
 			}
 			var err error
-			prxy, err = createNewProxy(proxyKey, endpoint)
+			prxy, err = createNewProxy(proxyKey, endpoint, loadBalancer)
 			if err != nil {
 				glog.Errorf("error with createNewProxy(%s, %+v) %v", proxyKey, endpoint, err)
 				return
@@ -561,20 +566,42 @@ func (c *Controller) setProxyAddresses(tenantEndpointID string, endpoints []*dao
 }
 
 // createNewProxy creates a new proxy
-func createNewProxy(tenantEndpointID string, endpoint *dao.ApplicationEndpoint) (*proxy, error) {
+func createNewProxy(tenantEndpointID string, endpoint *dao.ApplicationEndpoint, loadBalancer string) (*proxy, error) {
 	glog.Infof("Attempting port map for: %s -> %+v", tenantEndpointID, endpoint)
 
 	// setup a new proxy
-	listener, err := net.Listen("tcp4", fmt.Sprintf(":%d", endpoint.ContainerPort))
-	if err != nil {
-		glog.Errorf("Could not bind to port %d: %s", endpoint.ContainerPort, err)
-		return nil, err
+	var (
+		prxy *proxy
+		err  error
+	)
+	if strings.ToLower(endpoint.Protocol) == commons.UDP {
+		var conn *net.UDPConn
+		if conn, err = net.ListenUDP("udp4", &net.UDPAddr{Port: int(endpoint.ContainerPort)}); err != nil {
+			glog.Errorf("Could not bind to udp port %d: %s", endpoint.ContainerPort, err)
+			return nil, err
+		}
+		prxy, err = newUDPProxy(
+			fmt.Sprintf("%v", endpoint),
+			loadBalancer,
+			cMuxPort,
+			cMuxTLS,
+			conn)
+		if err != nil {
+			conn.Close()
+		}
+	} else {
+		var listener net.Listener
+		if listener, err = net.Listen("tcp4", fmt.Sprintf(":%d", endpoint.ContainerPort)); err != nil {
+			glog.Errorf("Could not bind to port %d: %s", endpoint.ContainerPort, err)
+			return nil, err
+		}
+		prxy, err = newProxy(
+			fmt.Sprintf("%v", endpoint),
+			loadBalancer,
+			cMuxPort,
+			cMuxTLS,
+			listener)
 	}
-	prxy, err := newProxy(
-		fmt.Sprintf("%v", endpoint),
-		cMuxPort,
-		cMuxTLS,
-		listener)
 	if err != nil {
 		glog.Errorf("Could not build proxy: %s", err)
 		return nil, err
@@ -584,6 +611,18 @@ func createNewProxy(tenantEndpointID string, endpoint *dao.ApplicationEndpoint)
 	return prxy, nil
 }
 
+// proxyStats returns the connection counters of each proxy by its key
+func proxyStats() map[string]ProxyStats {
+	proxiesLock.RLock()
+	defer proxiesLock.RUnlock()
+
+	stats := make(map[string]ProxyStats, len(proxies))
+	for key, prxy := range proxies {
+		stats[key] = prxy.Stats()
+	}
+	return stats
+}
+
 // registerExportedEndpoints registers exported ApplicationEndpoints with zookeeper
 func (c *Controller) registerExportedEndpoints() {
 	conn, err := zzk.GetBasePathConnection("/")
@@ -0,0 +1,116 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package container
+
+import (
+	"github.com/control-center/serviced/domain"
+
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// how much of the body of a response an http health check reads
+const maxHealthCheckBody = 64 * 1024
+
+// compileExpectedBody compiles the expected body of an http health check, which
+// is nil if any body will do
+func compileExpectedBody(hc domain.HealthCheck) (*regexp.Regexp, error) {
+	if hc.ExpectedBody == "" {
+		return nil, nil
+	}
+	return regexp.Compile(hc.ExpectedBody)
+}
+
+// runHealthCheck runs a health check once, returning why it failed if it did.
+// An exec check runs the script in the file at scriptPath; an http check
+// matches the body of the response with expectedBody, if it is not nil.
+func runHealthCheck(hc domain.HealthCheck, scriptPath string, expectedBody *regexp.Regexp) error {
+	timeout := hc.CheckTimeout()
+	switch hc.Kind() {
+	case domain.HealthCheckExec:
+		return runScriptCheck(scriptPath, timeout)
+	case domain.HealthCheckHTTP:
+		return runHTTPCheck(hc, expectedBody, timeout)
+	case domain.HealthCheckTCP:
+		return runTCPCheck(hc.Address, timeout)
+	}
+	return fmt.Errorf("unknown health check type %q", hc.Type)
+}
+
+// runScriptCheck runs a script, killing it and everything it started if it
+// does not exit in time
+func runScriptCheck(scriptPath string, timeout time.Duration) error {
+	cmd := exec.Command("sh", "-c", scriptPath)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("script timed out after %s", timeout)
+	}
+}
+
+// runHTTPCheck requests the URL of a health check, which passes if the
+// response has the expected status and its body matches the expected body
+func runHTTPCheck(hc domain.HealthCheck, expectedBody *regexp.Regexp, timeout time.Duration) error {
+	// the deadline covers connecting, redirects and reading the response
+	deadline := time.Now().Add(timeout)
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			Dial: func(network, addr string) (net.Conn, error) {
+				conn, err := net.DialTimeout(network, addr, deadline.Sub(time.Now()))
+				if err != nil {
+					return nil, err
+				}
+				return conn, conn.SetDeadline(deadline)
+			},
+		},
+	}
+	resp, err := client.Get(hc.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if expected := hc.ExpectedStatusCode(); resp.StatusCode != expected {
+		return fmt.Errorf("%s returned status %d, expected %d", hc.URL, resp.StatusCode, expected)
+	}
+	if expectedBody == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHealthCheckBody))
+	if err != nil {
+		return err
+	} else if !expectedBody.Match(body) {
+		return fmt.Errorf("body of %s does not match %q", hc.URL, hc.ExpectedBody)
+	}
+	return nil
+}
+
+// runTCPCheck connects to an address
+func runTCPCheck(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,102 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package container
+
+import (
+	"github.com/control-center/serviced/domain"
+
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHealthCheck_http(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ping":
+			fmt.Fprint(w, "pong")
+		case "/slow":
+			time.Sleep(time.Second)
+			fmt.Fprint(w, "pong")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	checks := []struct {
+		hc   domain.HealthCheck
+		pass bool
+	}{
+		{domain.HealthCheck{Type: domain.HealthCheckHTTP, URL: server.URL + "/ping"}, true},
+		{domain.HealthCheck{Type: domain.HealthCheckHTTP, URL: server.URL + "/ping", ExpectedBody: "^po"}, true},
+		{domain.HealthCheck{Type: domain.HealthCheckHTTP, URL: server.URL + "/ping", ExpectedBody: "ping"}, false},
+		{domain.HealthCheck{Type: domain.HealthCheckHTTP, URL: server.URL + "/missing"}, false},
+		{domain.HealthCheck{Type: domain.HealthCheckHTTP, URL: server.URL + "/missing", ExpectedStatus: http.StatusNotFound}, true},
+		{domain.HealthCheck{Type: domain.HealthCheckHTTP, URL: server.URL + "/slow", Timeout: 100 * time.Millisecond}, false},
+	}
+	for _, check := range checks {
+		expectedBody, err := compileExpectedBody(check.hc)
+		if err != nil {
+			t.Fatalf("Could not compile the expected body of %+v: %s", check.hc, err)
+		}
+		if err := runHealthCheck(check.hc, "", expectedBody); (err == nil) != check.pass {
+			t.Errorf("Expected %+v to pass: %t, got %v", check.hc, check.pass, err)
+		}
+	}
+}
+
+func TestRunHealthCheck_tcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	address := listener.Addr().String()
+
+	hc := domain.HealthCheck{Type: domain.HealthCheckTCP, Address: address, Timeout: time.Second}
+	if err := runHealthCheck(hc, "", nil); err != nil {
+		t.Errorf("Unexpected error connecting to %s: %s", address, err)
+	}
+	listener.Close()
+	if err := runHealthCheck(hc, "", nil); err == nil {
+		t.Errorf("Expected an error connecting to %s once it is closed", address)
+	}
+}
+
+func TestRunHealthCheck_exec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-health-check")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	scripts := []struct {
+		script string
+		pass   bool
+	}{
+		{"exit 0", true},
+		{"exit 1", false},
+		{"sleep 5", false},
+	}
+	for i, s := range scripts {
+		path := fmt.Sprintf("%s/check%d", dir, i)
+		if err := ioutil.WriteFile(path, []byte(s.script), 0777); err != nil {
+			t.Fatalf("Failed to write script: %s", err)
+		}
+		start := time.Now()
+		err := runHealthCheck(domain.HealthCheck{Script: s.script, Timeout: 200 * time.Millisecond}, path, nil)
+		if (err == nil) != s.pass {
+			t.Errorf("Expected %q to pass: %t, got %v", s.script, s.pass, err)
+		} else if err != nil && strings.HasPrefix(s.script, "sleep") && time.Since(start) > 2*time.Second {
+			t.Errorf("Expected %q to be killed after its timeout", s.script)
+		}
+	}
+}
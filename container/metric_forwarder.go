@@ -49,6 +49,11 @@ func (forwarder *MetricForwarder) loop() {
 			PathExp:    "/api/metrics/store",
 			Func:       postAPIMetricsStore(forwarder.metricsRedirectURL),
 		},
+		rest.Route{
+			HttpMethod: "GET",
+			PathExp:    "/api/metrics/proxies",
+			Func:       getAPIMetricsProxies,
+		},
 	}
 
 	handler := rest.ResourceHandler{}
@@ -87,3 +92,10 @@ func postAPIMetricsStore(redirectURL string) func(*rest.ResponseWriter, *rest.Re
 		}
 	}
 }
+
+// getAPIMetricsProxies returns the connection counters of the endpoints the
+// container imports
+func getAPIMetricsProxies(w *rest.ResponseWriter, request *rest.Request) {
+	stats := proxyStats()
+	w.WriteJson(&stats)
+}
@@ -5,13 +5,15 @@
 package container
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/domain/servicedefinition"
 )
 
 /*
@@ -55,28 +57,47 @@ The netcat (nc) command is particularly useful for this:
 
 type proxy struct {
 	name         string          // Name of the remote service
+	loadBalancer string          // How connections are spread over the addresses
 	addresses    []string        // Public IP:Port of the remote service
 	tcpMuxPort   uint16          // the port to use for TCP Muxing, 0 is disabled
 	useTLS       bool            // use encryption over mux port
 	closing      chan chan error // internal shutdown signal
 	newAddresses chan []string   // a stream of updates to the addresses
 	listener     net.Listener    // handle on the listening socket
+	udpConn      *net.UDPConn    // handle on the listening socket of a udp proxy
+	lock         sync.Mutex      // guards the backends and the balancer
+	backends     []*backend      // the connections of each address
+	balancer     balancer        // picks the address of each connection
 }
 
 // Newproxy create a new proxy object. It starts listening on the prxy port asynchronously.
-func newProxy(name string, tcpMuxPort uint16, useTLS bool, listener net.Listener) (p *proxy, err error) {
+func newProxy(name, loadBalancer string, tcpMuxPort uint16, useTLS bool, listener net.Listener) (p *proxy, err error) {
+	if p, err = makeProxy(name, loadBalancer, tcpMuxPort, useTLS); err != nil {
+		return nil, err
+	}
+	p.listener = listener
+	go p.listenAndproxy()
+	return p, nil
+}
+
+// makeProxy creates a proxy object that is not yet listening
+func makeProxy(name, loadBalancer string, tcpMuxPort uint16, useTLS bool) (p *proxy, err error) {
 	if len(name) == 0 {
 		return nil, fmt.Errorf("prxy: name can not be empty")
 	}
+	if len(loadBalancer) == 0 {
+		loadBalancer = servicedefinition.LoadBalancerRoundRobin
+	}
 	p = &proxy{
-		name:       name,
-		addresses:  make([]string, 0),
-		tcpMuxPort: tcpMuxPort,
-		useTLS:     useTLS,
-		listener:   listener,
+		name:         name,
+		loadBalancer: loadBalancer,
+		addresses:    make([]string, 0),
+		tcpMuxPort:   tcpMuxPort,
+		useTLS:       useTLS,
+		closing:      make(chan chan error),
+		balancer:     newBalancer(loadBalancer),
 	}
 	p.newAddresses = make(chan []string, 2)
-	go p.listenAndproxy()
 	return p, nil
 }
 
@@ -87,6 +108,9 @@ func (p *proxy) Name() string {
 
 // String() pretty prints the proxy struct.
 func (p *proxy) String() string {
+	if p.udpConn != nil {
+		return fmt.Sprintf("proxy[%s; udp %s]=>%v", p.name, p.udpConn.LocalAddr(), p.addresses)
+	}
 	return fmt.Sprintf("proxy[%s; %s]=>%v", p.name, p.listener, p.addresses)
 }
 
@@ -107,7 +131,11 @@ func (p *proxy) SetNewAddresses(addresses []string) {
 
 // Close() terminates the prxy; it can not be restarted.
 func (p *proxy) Close() error {
-	p.listener.Close()
+	if p.udpConn != nil {
+		p.udpConn.Close()
+	} else {
+		p.listener.Close()
+	}
 	errc := make(chan error)
 	p.closing <- errc
 	return <-errc
@@ -128,7 +156,6 @@ func (p *proxy) listenAndproxy() {
 		}
 	}(p.listener, connections)
 
-	i := 0
 	for {
 		select {
 		case conn := <-connections:
@@ -137,11 +164,10 @@ func (p *proxy) listenAndproxy() {
 				conn.Close()
 				continue
 			}
-			i++
-			// round robin connections to list of addresses
 			glog.V(1).Infof("choosing address from %v", p.addresses)
-			go p.prxy(conn, p.addresses[i%len(p.addresses)])
+			go p.prxy(conn)
 		case p.addresses = <-p.newAddresses:
+			p.setBackends(p.addresses)
 		case errc := <-p.closing:
 			p.listener.Close()
 			errc <- nil
@@ -150,35 +176,30 @@ func (p *proxy) listenAndproxy() {
 	}
 }
 
-// prxy takes an established local connection, Dials the remote address specified
-// by the proxy structure and then copies data to and from the resulting pair
-// of endpoints.
-func (p *proxy) prxy(local net.Conn, address string) {
-	remoteAddr := address
-	// NOTE: here we are relying on the initial remoteAddr to have the
-	//       publicly exposed port for the target service. If TCPMux is
-	//       in play that port will be replaced with the TCPMux port, so
-	//       we grab it here in order to be able to create a proper Zen-Service
-	//       header later.
-	if p.tcpMuxPort > 0 {
-		remoteAddr = fmt.Sprintf("%s:%d", strings.Split(remoteAddr, ":")[0], p.tcpMuxPort)
-	}
-
+// prxy takes an established local connection, Dials a remote address picked
+// by the balancer of the proxy and then copies data to and from the resulting
+// pair of endpoints. Addresses that cannot be dialed are ejected for a while
+// and another address is tried.
+func (p *proxy) prxy(local net.Conn) {
 	var remote net.Conn
-	var err error
+	var b *backend
+	tried := make(map[*backend]bool)
+	for {
+		if b = p.pick(tried); b == nil {
+			glog.Errorf("No remote services could be reached for prxying %s", p)
+			local.Close()
+			return
+		}
+		tried[b] = true
 
-	glog.V(2).Infof("Dialing hostAgent:%v to prxy %v<->%v<->%v",
-		remoteAddr, local.LocalAddr(), local.RemoteAddr(), address)
-	if p.useTLS && (p.tcpMuxPort > 0) { // Only do TLS if connecting to a TCPMux
-		config := tls.Config{InsecureSkipVerify: true}
-		remote, err = tls.Dial("tcp4", remoteAddr, &config)
-	} else {
-		remote, err = net.Dial("tcp4", remoteAddr)
-	}
-	if err != nil {
-		glog.Error("Error (net.Dial): ", err)
-		return
+		var err error
+		if remote, err = p.dial(local, b.address); err == nil {
+			break
+		}
+		glog.Warningf("Ejecting %s from %s for %s: %s", b.address, p.name, ejectionTime, err)
+		p.eject(b)
 	}
+	address := b.address
 
 	if p.tcpMuxPort > 0 {
 		io.WriteString(remote, fmt.Sprintf("%s\n", address))
@@ -186,7 +207,10 @@ func (p *proxy) prxy(local net.Conn, address string) {
 
 	glog.V(2).Infof("Using   hostAgent:%v to prxy %v<->%v<->%v<->%v",
 		remote.RemoteAddr(), local.LocalAddr(), local.RemoteAddr(), remote.LocalAddr(), address)
+	var wg sync.WaitGroup
+	wg.Add(2)
 	go func(address string) {
+		defer wg.Done()
 		defer local.Close()
 		defer remote.Close()
 		io.Copy(local, remote)
@@ -194,10 +218,121 @@ func (p *proxy) prxy(local net.Conn, address string) {
 			remote.RemoteAddr(), local.LocalAddr(), local.RemoteAddr(), remote.LocalAddr(), address)
 	}(address)
 	go func(address string) {
+		defer wg.Done()
 		defer local.Close()
 		defer remote.Close()
 		io.Copy(remote, local)
 		glog.V(2).Infof("closing hostAgent:%v to prxy %v<->%v<->%v<->%v",
 			remote.RemoteAddr(), local.LocalAddr(), local.RemoteAddr(), remote.LocalAddr(), address)
 	}(address)
+	go func() {
+		wg.Wait()
+		p.release(b)
+	}()
+}
+
+// dial connects to the remote address of a local connection
+func (p *proxy) dial(local net.Conn, address string) (net.Conn, error) {
+	remoteAddr := address
+	// NOTE: here we are relying on the initial remoteAddr to have the
+	//       publicly exposed port for the target service. If TCPMux is
+	//       in play that port will be replaced with the TCPMux port, so
+	//       we grab it here in order to be able to create a proper Zen-Service
+	//       header later.
+	if p.tcpMuxPort > 0 {
+		remoteAddr = fmt.Sprintf("%s:%d", strings.Split(remoteAddr, ":")[0], p.tcpMuxPort)
+	}
+
+	glog.V(2).Infof("Dialing hostAgent:%v to prxy %v<->%v<->%v",
+		remoteAddr, local.LocalAddr(), local.RemoteAddr(), address)
+	if p.useTLS && (p.tcpMuxPort > 0) { // Only do TLS if connecting to a TCPMux
+		return cMuxDialer.Dial(remoteAddr)
+	}
+	return net.Dial("tcp4", remoteAddr)
+}
+
+// setBackends replaces the addresses connections are made to, keeping the
+// counters of the addresses that remain
+func (p *proxy) setBackends(addresses []string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	existing := make(map[string]*backend)
+	for _, b := range p.backends {
+		existing[b.address] = b
+	}
+	p.backends = make([]*backend, len(addresses))
+	for i, address := range addresses {
+		if b, ok := existing[address]; ok {
+			p.backends[i] = b
+		} else {
+			p.backends[i] = &backend{address: address}
+		}
+	}
+}
+
+// pick chooses the backend of a new connection among those not yet tried,
+// preferring those that are not ejected, and counts the connection
+func (p *proxy) pick(tried map[*backend]bool) *backend {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	var candidates, ejected []*backend
+	for _, b := range p.backends {
+		if tried[b] {
+			continue
+		} else if now.Before(b.ejected) {
+			ejected = append(ejected, b)
+		} else {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		// an ejected address is better than none
+		candidates = ejected
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	b := p.balancer.pick(candidates)
+	b.active++
+	b.total++
+	return b
+}
+
+// eject skips a backend that could not be dialed for the ejectionTime
+func (p *proxy) eject(b *backend) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b.active--
+	b.failures++
+	b.ejected = time.Now().Add(ejectionTime)
+}
+
+// release counts the end of a connection
+func (p *proxy) release(b *backend) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b.active--
+}
+
+// Stats returns the connection counters of each address of the proxy
+func (p *proxy) Stats() ProxyStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	stats := ProxyStats{Name: p.name, LoadBalancer: p.loadBalancer, Backends: make([]BackendStats, len(p.backends))}
+	now := time.Now()
+	for i, b := range p.backends {
+		stats.Backends[i] = BackendStats{
+			Address:  b.address,
+			Active:   b.active,
+			Total:    b.total,
+			Failures: b.failures,
+			Ejected:  now.Before(b.ejected),
+		}
+	}
+	return stats
 }
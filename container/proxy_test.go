@@ -7,6 +7,7 @@ package container
 import (
 	"github.com/zenoss/glog"
 
+	"fmt"
 	"net"
 	"testing"
 	"time"
@@ -43,7 +44,7 @@ func TestNoMux(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Could not bind to a port for test")
 	}
-	prxy, err := newProxy("foo", 0, false, local)
+	prxy, err := newProxy("foo", "", 0, false, local)
 	if err != nil {
 		t.Fatalf("Could not create a prxy: %s", err)
 	}
@@ -77,3 +78,112 @@ func TestNoMux(t *testing.T) {
 		t.Fatalf("Timed out reading response from test port")
 	}
 }
+
+func TestProxyEjectsUnreachableAddress(t *testing.T) {
+	remote, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not bind to a port for test")
+	}
+	// a port that is no longer listened on
+	dead, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not bind to a port for test")
+	}
+	dead.Close()
+	local, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not bind to a port for test")
+	}
+	prxy, err := newProxy("foo", "", 0, false, local)
+	if err != nil {
+		t.Fatalf("Could not create a prxy: %s", err)
+	}
+	prxy.SetNewAddresses([]string{dead.Addr().String(), remote.Addr().String()})
+	stringChan := stringAcceptor(remote)
+
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp4", local.Addr().String())
+		if err != nil {
+			t.Fatalf("Could not create a connection to the prxyport: %s", err)
+		}
+		msg := fmt.Sprintf("foo%d", i)
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatalf("Failed to write msg to prxy: %s", err)
+		}
+		select {
+		case stringResponse := <-stringChan:
+			if stringResponse != msg {
+				t.Fatalf("response did not equal msg: '%v' != '%v'", stringResponse, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out reading response from test port")
+		}
+		conn.Close()
+	}
+
+	stats := prxy.Stats()
+	if len(stats.Backends) != 2 {
+		t.Fatalf("expected 2 backends, got %+v", stats)
+	}
+	if b := stats.Backends[0]; !b.Ejected || b.Failures != 1 {
+		t.Errorf("expected the unreachable address to be ejected after one failure, got %+v", b)
+	}
+	if b := stats.Backends[1]; b.Ejected || b.Total != 3 {
+		t.Errorf("expected every connection to go to the reachable address, got %+v", b)
+	}
+}
+
+func TestUDPProxy(t *testing.T) {
+	// an echo service
+	remote, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Could not bind to a port for test")
+	}
+	defer remote.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := remote.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			remote.WriteToUDP(buf[:n], addr)
+		}
+	}()
+	local, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Could not bind to a port for test")
+	}
+	prxy, err := newUDPProxy("foo", "", 0, false, local)
+	if err != nil {
+		t.Fatalf("Could not create a prxy: %s", err)
+	}
+	defer prxy.Close()
+	prxy.SetNewAddresses([]string{remote.LocalAddr().String()})
+
+	conn, err := net.Dial("udp4", local.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Could not create a connection to the prxyport: %s", err)
+	}
+	defer conn.Close()
+	buf := make([]byte, 1024)
+	for i := 0; i < 3; i++ {
+		msg := fmt.Sprintf("foo%d", i)
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatalf("Failed to write msg to prxy: %s", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read response from prxy: %s", err)
+		}
+		if string(buf[:n]) != msg {
+			t.Fatalf("response did not equal msg: '%s' != '%s'", buf[:n], msg)
+		}
+	}
+
+	// the datagrams of a client share a session
+	if b := prxy.Stats().Backends[0]; b.Total != 1 {
+		t.Errorf("expected one session, got %+v", b)
+	}
+}
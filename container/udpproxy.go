@@ -0,0 +1,218 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package container
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/zenoss/glog"
+	servicedproxy "github.com/control-center/serviced/proxy"
+)
+
+/*
+A udp proxy relays the datagrams of each local client to one of the remote
+addresses; the replies of the remote are sent back to the client. The remote
+is picked by the balancer when the client sends its first datagram, and the
+session is closed when there has been no traffic for the UDPSessionTimeout.
+When TCPMux is in play, the datagrams of a session are framed on a mux
+connection and relayed by the mux of the remote host.
+*/
+
+// datagram is a datagram received from a local client
+type datagram struct {
+	client *net.UDPAddr
+	data   []byte
+}
+
+// udpSession relays the datagrams of a local client
+type udpSession struct {
+	client   *net.UDPAddr
+	backend  *backend
+	remote   net.Conn      // a udp socket, or a mux connection if framed
+	framed   bool          // datagrams are framed on a mux connection
+	done     chan struct{} // closed when the replies of the remote stop
+	activity servicedproxy.SessionActivity
+}
+
+// newUDPProxy creates a proxy of the datagrams received on conn. It starts
+// proxying asynchronously.
+func newUDPProxy(name, loadBalancer string, tcpMuxPort uint16, useTLS bool, conn *net.UDPConn) (p *proxy, err error) {
+	if p, err = makeProxy(name, loadBalancer, tcpMuxPort, useTLS); err != nil {
+		return nil, err
+	}
+	p.udpConn = conn
+	go p.listenAndproxyUDP()
+	return p, nil
+}
+
+// listenAndproxyUDP reads datagrams on the prxy's specified Port and relays
+// them over the session of their client
+func (p *proxy) listenAndproxyUDP() {
+	datagrams := make(chan datagram)
+	go func(conn *net.UDPConn, datagrams chan datagram) {
+		for {
+			buffer := make([]byte, servicedproxy.MaxDatagramSize)
+			n, client, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				glog.Errorf("Error (ReadFromUDP): %s", err)
+				close(datagrams)
+				return
+			}
+			datagrams <- datagram{client, buffer[:n]}
+		}
+	}(p.udpConn, datagrams)
+
+	sessions := make(map[string]*udpSession)
+	expire := time.NewTicker(servicedproxy.UDPSessionTimeout / 4)
+	defer expire.Stop()
+	for {
+		select {
+		case d, ok := <-datagrams:
+			if !ok {
+				datagrams = nil
+				continue
+			}
+			key := d.client.String()
+			s, ok := sessions[key]
+			if ok && s.closed() {
+				delete(sessions, key)
+				ok = false
+			}
+			if !ok {
+				if len(p.addresses) == 0 {
+					glog.Warningf("No remote services available for prxying %s", p)
+					continue
+				}
+				if s = p.openSession(d.client); s == nil {
+					continue
+				}
+				sessions[key] = s
+			}
+			s.activity.Touch()
+			if err := s.send(d.data); err != nil {
+				glog.Warningf("Could not relay datagram of %s to %s: %s", key, s.backend.address, err)
+				s.remote.Close()
+				delete(sessions, key)
+			}
+		case <-expire.C:
+			for key, s := range sessions {
+				if s.closed() || s.activity.Idle() {
+					glog.V(2).Infof("Closing datagram session %s<->%s", key, s.backend.address)
+					s.remote.Close()
+					delete(sessions, key)
+				}
+			}
+		case p.addresses = <-p.newAddresses:
+			p.setBackends(p.addresses)
+		case errc := <-p.closing:
+			for _, s := range sessions {
+				s.remote.Close()
+			}
+			p.udpConn.Close()
+			errc <- nil
+			return
+		}
+	}
+}
+
+// openSession starts relaying the datagrams of a client to a remote address
+// picked by the balancer of the proxy. Addresses that cannot be dialed are
+// ejected for a while and another address is tried.
+func (p *proxy) openSession(client *net.UDPAddr) *udpSession {
+	s := &udpSession{client: client, done: make(chan struct{})}
+	tried := make(map[*backend]bool)
+	for {
+		if s.backend = p.pick(tried); s.backend == nil {
+			glog.Errorf("No remote services could be reached for prxying %s", p)
+			return nil
+		}
+		tried[s.backend] = true
+
+		var err error
+		if s.remote, s.framed, err = p.dialUDP(s.backend.address); err == nil {
+			break
+		}
+		glog.Warningf("Ejecting %s from %s for %s: %s", s.backend.address, p.name, ejectionTime, err)
+		p.eject(s.backend)
+	}
+
+	glog.V(2).Infof("Using   %v to prxy datagrams %v<->%v<->%v", s.remote.RemoteAddr(), client, p.udpConn.LocalAddr(), s.backend.address)
+	go func() {
+		defer close(s.done)
+		defer p.release(s.backend)
+		buffer := make([]byte, servicedproxy.MaxDatagramSize)
+		for {
+			n, err := s.receive(buffer)
+			if err != nil {
+				// the remote service may not be listening yet
+				if !s.framed && strings.Contains(err.Error(), "connection refused") {
+					continue
+				}
+				return
+			}
+			s.activity.Touch()
+			if _, err := p.udpConn.WriteToUDP(buffer[:n], client); err != nil {
+				glog.Warningf("Could not relay datagram to %s: %s", client, err)
+			}
+		}
+	}()
+	return s
+}
+
+// dialUDP connects to a remote address, through the mux of its host if
+// TCPMux is in play
+func (p *proxy) dialUDP(address string) (remote net.Conn, framed bool, err error) {
+	if p.tcpMuxPort == 0 {
+		remote, err = net.Dial("udp4", address)
+		return remote, false, err
+	}
+
+	remoteAddr := fmt.Sprintf("%s:%d", strings.Split(address, ":")[0], p.tcpMuxPort)
+	glog.V(2).Infof("Dialing hostAgent:%v to prxy datagrams to %v", remoteAddr, address)
+	if p.useTLS {
+		remote, err = cMuxDialer.Dial(remoteAddr)
+	} else {
+		remote, err = net.Dial("tcp4", remoteAddr)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := io.WriteString(remote, fmt.Sprintf("%s%s\n", address, servicedproxy.UDPSuffix)); err != nil {
+		remote.Close()
+		return nil, false, err
+	}
+	return remote, true, nil
+}
+
+// send relays a datagram of the client to the remote
+func (s *udpSession) send(data []byte) error {
+	if s.framed {
+		return servicedproxy.WriteDatagram(s.remote, data)
+	}
+	_, err := s.remote.Write(data)
+	return err
+}
+
+// receive reads a reply of the remote
+func (s *udpSession) receive(buffer []byte) (int, error) {
+	if s.framed {
+		return servicedproxy.ReadDatagram(s.remote, buffer)
+	}
+	return s.remote.Read(buffer)
+}
+
+// closed is true once the replies of the remote have stopped
+func (s *udpSession) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -99,8 +99,11 @@ func (reg *VIFRegistry) RegisterVirtualAddress(address, toport, protocol string)
 		}
 		reg.vifs[host] = viface
 	}
-	switch strings.ToLower(protocol) {
-	case "tcp":
+	// endpoints without a protocol are tcp
+	protocol = strings.ToLower(protocol)
+	switch protocol {
+	case "tcp", "":
+		protocol = "tcp"
 		portmap = &viface.tcpPorts
 	case "udp":
 		portmap = &viface.udpPorts
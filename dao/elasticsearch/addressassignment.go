@@ -5,22 +5,31 @@
 package elasticsearch
 
 import (
-	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/role"
 )
 
 // GetServiceAddressAssignments fills in all AddressAssignments for the specified serviced id.
 func (this *ControlPlaneDao) GetServiceAddressAssignments(serviceID string, assignments *[]*addressassignment.AddressAssignment) error {
-	return this.facade.GetServiceAddressAssignments(datastore.Get(), serviceID, assignments)
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	return this.facade.GetServiceAddressAssignments(this.context(), serviceID, assignments)
 }
 
 // RemoveAddressAssignemnt Removes an AddressAssignment by id
 func (this *ControlPlaneDao) RemoveAddressAssignment(id string, _ *struct{}) error {
-	return this.facade.RemoveAddressAssignment(datastore.Get(), id)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService}); err != nil {
+		return err
+	}
+	return this.facade.RemoveAddressAssignment(this.context(), id)
 }
 
 // AssignAddress Creates an AddressAssignment, verifies that an assignment for the service/endpoint does not already exist
 // id param contains id of newly created assignment if successful
 func (this *ControlPlaneDao) AssignAddress(assignment addressassignment.AddressAssignment, id *string) error {
-	return this.facade.AssignAddress(datastore.Get(), assignment, id)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: assignment.ServiceID}); err != nil {
+		return err
+	}
+	return this.facade.AssignAddress(this.context(), assignment, id)
 }
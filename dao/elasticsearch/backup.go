@@ -9,11 +9,12 @@ import (
 	dockerclient "github.com/zenoss/go-dockerclient"
 	"github.com/control-center/serviced/commons/docker"
 	"github.com/control-center/serviced/dao"
-	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/facade"
+	"github.com/control-center/serviced/volume"
 
 	"encoding/json"
 	"errors"
@@ -28,11 +29,6 @@ import (
 	"time"
 )
 
-var backupOutput chan string = nil
-var backupError chan string = nil
-var restoreOutput chan string = nil
-var restoreError chan string = nil
-
 func commandAsRoot(name string, arg ...string) (*exec.Cmd, error) {
 	user, e := user.Current()
 	if e != nil {
@@ -220,6 +216,41 @@ func importDockerImageFromFile(imageID, filename string) error {
 	return nil
 }
 
+// loadDockerImage imports an image from the file it was exported to, unless
+// the image is already there, and tags it with its names
+func loadDockerImage(imageID string, imageTags []string, filename string) error {
+	imageName := "imported:" + imageID
+	image, e := docker.FindImage(imageID, false)
+	if e != nil {
+		if e != dockerclient.ErrNoSuchImage {
+			glog.Errorf("Unexpected error when inspecting docker image %s: %v", imageID, e)
+			return e
+		}
+		if e := importDockerImageFromFile(imageName, filename); e != nil {
+			glog.Errorf("Could not import docker image %s (%+v) from file %s: %v", imageID, imageTags, filename, e)
+			return e
+		}
+		image, e = docker.FindImage(imageName, false)
+		if e != nil {
+			glog.Errorf("Could not find imported docker image %s (%+v): %v", imageName, imageTags, e)
+			return e
+		}
+	}
+
+	if _, e := image.Tag(imageID); e != nil {
+		glog.Errorf("Found image %s already exists, but could not tag it: %s", imageID, e)
+		return e
+	}
+
+	for _, imageTag := range imageTags {
+		if _, e := image.Tag(imageTag); e != nil {
+			glog.Errorf("Could not tag docker image %s as %s: %s", imageID, imageTag, e)
+			return e
+		}
+	}
+	return nil
+}
+
 func utcNow() time.Time {
 	return time.Now().UTC()
 }
@@ -248,6 +279,10 @@ func dockerImageSet(templates map[string]*servicetemplate.ServiceTemplate, servi
 }
 
 func (this *ControlPlaneDao) AsyncBackup(backupsDirectory string, backupFilePath *string) (err error) {
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	this.backupProgress.resetStatus()
 	go func() {
 		this.Backup(backupsDirectory, backupFilePath)
 	}()
@@ -255,65 +290,87 @@ func (this *ControlPlaneDao) AsyncBackup(backupsDirectory string, backupFilePath
 	return nil
 }
 
+// BackupStatus gets the next change of the progress of the backup in flight:
+// "timeout" if there is none within 10 seconds, an empty status once the
+// backup is done, or the error it failed with.
 func (this *ControlPlaneDao) BackupStatus(notUsed string, backupStatus *string) (err error) {
-	select {
-	case *backupStatus = <-backupOutput:
-	case <-time.After(10 * time.Second):
-		*backupStatus = "timeout"
-	case *backupStatus = <-backupError:
-		err = errors.New(*backupStatus)
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceBackup}); err != nil {
 		return err
 	}
-
-	return nil
+	*backupStatus, err = this.backupProgress.status(progressTimeout)
+	return err
 }
 
 // Backup saves the service templates, services, and related docker images and shared filesystems to a tgz file.
-func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *string) (err error) {
-	// Lock the error and output channels to ensure that only one backup runs at any given time.
-	// Done in an anonymous function so we can ensure unlocking of the channel when we are done.
-	err = func() error {
-		cp.backupLock.Lock()
-
-		//ensure that the backupLock is unlocked after this function exits
-		defer cp.backupLock.Unlock()
-
-		backupError = make(chan string, 100)
-
-		//open a channel for asynchronous Backup calls
-		if backupOutput != nil {
-			e := errors.New("Another backup is currently in progress")
-			glog.Errorf("An error occured when starting backup: %v", e)
-			backupError <- e.Error()
-			return e
-		}
-		backupOutput = make(chan string, 100)
-		return nil
-	}()
-
-	defer func() {
-		//close the channel for asynchronous calls to Backup
-		close(backupOutput)
-		backupOutput = nil
-	}()
+func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *string) error {
+	if err := cp.authorize(role.Request{Action: role.Operate, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	return cp.trusted().backup(backupsDirectory, false, dao.BackupKey{}, backupFilePath)
+}
 
-	// check the error status of the channel creation if there was an error, return it now.
-	if err != nil {
+// IncrementalBackup saves a backup that is incremental to the newest backup in
+// the directory: it leaves out the docker layers that the backups of its chain
+// have, and, on volumes that can send snapshots, has the changes to the shared
+// filesystems since their snapshots.
+func (cp *ControlPlaneDao) IncrementalBackup(backupsDirectory string, backupFilePath *string) error {
+	if err := cp.authorize(role.Request{Action: role.Operate, Resource: role.ResourceBackup}); err != nil {
 		return err
 	}
+	return cp.trusted().backup(backupsDirectory, true, dao.BackupKey{}, backupFilePath)
+}
 
-	backupOutput <- "Starting backup"
+// EncryptedBackup saves a backup, or an incremental backup, encrypted with a
+// passphrase or a public key, to a tgz.enc file
+func (cp *ControlPlaneDao) EncryptedBackup(request dao.BackupRequest, backupFilePath *string) error {
+	if err := cp.authorize(role.Request{Action: role.Operate, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	if backupKeyIsEmpty(request.Key) {
+		return errors.New("a passphrase or a public key is needed to encrypt a backup")
+	}
+	return cp.trusted().backup(request.Dirpath, request.Incremental, request.Key, backupFilePath)
+}
 
+func (cp *ControlPlaneDao) backup(backupsDirectory string, incremental bool, key dao.BackupKey, backupFilePath *string) (err error) {
 	var (
-		templates      map[string]*servicetemplate.ServiceTemplate
-		services       []*service.Service
-		imagesNameTags [][]string
+		templates map[string]*servicetemplate.ServiceTemplate
+		services  []*service.Service
 	)
-	backupName := utcNow().Format("backup-2006-01-02-150405")
+	created := utcNow()
+	backupName := created.Format("backup-2006-01-02-150405")
 	if backupsDirectory == "" {
 		backupsDirectory = filepath.Join(varPath(), "backups")
 	}
-	*backupFilePath = path.Join(backupsDirectory, backupName+".tgz")
+	filename := path.Join(backupsDirectory, backupName+".tgz")
+
+	// An encrypted backup is written to a tgz file first
+	var (
+		envelope *backupEnvelope
+		keys     *backupKeys
+	)
+	if !backupKeyIsEmpty(key) {
+		if envelope, keys, err = newBackupEnvelope(key); err != nil {
+			glog.Errorf("Could not encrypt backup: %v", err)
+			return err
+		}
+		filename += ".enc"
+	}
+
+	// Only one backup runs at any given time
+	progress := cp.backupProgress
+	if e := progress.start(filename); e != nil {
+		glog.Errorf("An error occured when starting backup: %v", e)
+		return e
+	}
+	defer func() {
+		if err == errCanceled {
+			glog.Infof("Canceled backup to %s", filename)
+		}
+		progress.finish(err)
+	}()
+
+	*backupFilePath = filename
 	defer func() {
 		// Zero-value the backupFilePath if we're returning an error
 		if err != nil && backupFilePath != nil && *backupFilePath != "" {
@@ -323,9 +380,14 @@ func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *strin
 	backupPath := func(relPath ...string) string {
 		return filepath.Join(append([]string{backupsDirectory, backupName}, relPath...)...)
 	}
+	stepDone := func() {
+		progress.update(func(p *dao.BackupProgress) {
+			p.StepsDone++
+			p.BytesDone = pathSize(backupPath())
+		})
+	}
 	if e := os.MkdirAll(backupPath("images"), os.ModeDir|0755); e != nil {
 		glog.Errorf("Could not find nor create %s: %v", backupPath(), e)
-		backupError <- e.Error()
 		return e
 	}
 	defer func() {
@@ -338,27 +400,50 @@ func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *strin
 	}()
 	if e := os.MkdirAll(backupPath("snapshots"), os.ModeDir|0755); e != nil {
 		glog.Errorf("Could not find nor create %s: %v", backupPath(), e)
-		backupError <- e.Error()
 		return e
 	}
 
+	// The newest backup is the parent of an incremental backup. The snapshots
+	// it kept are not needed once this backup is done.
+	previous, e := newestBackup(backupsDirectory)
+	if e != nil {
+		glog.Errorf("Could not find the newest backup in %s: %v", backupsDirectory, e)
+		return e
+	}
+	var chain []*backupManifest
+	if incremental && previous != nil {
+		if chain, e = backupChain(filepath.Join(backupsDirectory, previous.Name)); e != nil {
+			glog.Errorf("Could not find the backups %s is incremental to: %v", previous.Name, e)
+			return e
+		}
+	} else if incremental {
+		glog.Infof("There are no backups in %s to be incremental to, taking a full backup", backupsDirectory)
+	}
+	manifest := backupManifest{Name: filepath.Base(filename), Created: created}
+	if len(chain) > 0 {
+		manifest.Parent = previous.Name
+	}
+
 	// Retrieve all service definitions
+	progress.step("services", "Saving services")
 	var request dao.EntityRequest
 	if e := cp.GetServices(request, &services); e != nil {
 		glog.Errorf("Could not get services: %v", e)
-		backupError <- e.Error()
+		return e
+	}
+	if e := writeJSONToFile(services, backupPath("services.json")); e != nil {
+		glog.Errorf("Could not write services.json: %v", e)
 		return e
 	}
 
 	// Dump all template definitions
+	progress.step("templates", "Saving service templates")
 	if e := cp.GetServiceTemplates(0, &templates); e != nil {
 		glog.Errorf("Could not get templates: %v", e)
-		backupError <- e.Error()
 		return e
 	}
 	if e := writeJSONToFile(templates, backupPath("templates.json")); e != nil {
 		glog.Errorf("Could not write templates.json: %v", e)
-		backupError <- e.Error()
 		return e
 	}
 
@@ -366,7 +451,6 @@ func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *strin
 	imageNameIds, e := getDockerImageNameIds()
 	if e != nil {
 		glog.Errorf("Could not get image tags from docker: %v", e)
-		backupError <- e.Error()
 		return e
 	}
 
@@ -375,7 +459,11 @@ func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *strin
 	imageNameSet := dockerImageSet(templates, services)
 
 	for imageName := range imageNameSet {
-		imageID := imageNameIds[imageName]
+		imageID, ok := imageNameIds[imageName]
+		if !ok {
+			glog.Infof("Docker image %s was referenced, but does not exist. Ignoring.", imageName)
+			continue
+		}
 		imageIDTags[imageID] = []string{}
 	}
 
@@ -390,89 +478,178 @@ func (cp *ControlPlaneDao) Backup(backupsDirectory string, backupFilePath *strin
 		imageIDTags[imageID] = append(tags, imageName)
 	}
 
-	i := 0
-	for imageID, imageTags := range imageIDTags {
-		filename := backupPath("images", fmt.Sprintf("%d.tar", i))
-		backupOutput <- fmt.Sprintf("Exporting docker image: %v", imageID)
-		if e := exportDockerImageToFile(imageID, filename); e != nil {
-			if e == dockerclient.ErrNoSuchImage {
-				glog.Infof("Docker image %s was referenced, but does not exist. Ignoring.", imageID)
-			} else {
-				glog.Errorf("Error while exporting docker image %s: %v", imageID, e)
-				backupError <- e.Error()
-				return e
-			}
-		} else {
-			imageNameWithTags := append([]string{imageID}, imageTags...)
-			imagesNameTags = append(imagesNameTags, imageNameWithTags)
-			i++
+	// Each image, each snapshot of a tenant and the backup file is a step
+	tenants := 0
+	for _, service := range services {
+		if service.ParentServiceID == "" {
+			tenants++
 		}
 	}
+	progress.update(func(p *dao.BackupProgress) {
+		p.Steps = len(imageIDTags) + tenants + 1
+	})
 
-	if e := writeJSONToFile(imagesNameTags, backupPath("images.json")); e != nil {
-		glog.Errorf("Could not write images.json: %v", e)
-		backupError <- e.Error()
-		return e
+	// Layers that the backups of the chain have are left out
+	knownLayers := make(map[string]bool)
+	for _, m := range chain {
+		for _, layer := range m.Layers {
+			knownLayers[layer] = true
+		}
+	}
+	for imageID, imageTags := range imageIDTags {
+		if e := progress.checkpoint(); e != nil {
+			return e
+		}
+		progress.update(func(p *dao.BackupProgress) {
+			p.Phase = "images"
+			p.Message = fmt.Sprintf("Exporting docker image: %v", imageID)
+			p.Image = imageID
+		})
+		layers, saved, e := saveDockerImageLayers(imageID, backupPath("images"), knownLayers)
+		if e != nil {
+			glog.Errorf("Error while exporting docker image %s: %v", imageID, e)
+			return e
+		}
+		manifest.Images = append(manifest.Images, backupImage{ID: imageID, Tags: imageTags, Layers: layers})
+		manifest.Layers = append(manifest.Layers, saved...)
+		stepDone()
 	}
 
-	// Dump all snapshots
-	snapshotToTgzFile := func(service *service.Service) (filename string, err error) {
-		glog.V(0).Infof("snapshotToTgzFile(%v)", service.ID)
-		backupOutput <- fmt.Sprintf("Taking snapshot of service: %v", service.Name)
+	// Dump all snapshots to tgz files, which any volume can restore. Volumes
+	// that can send snapshots keep the snapshots of the newest backup, and an
+	// incremental backup has send streams of the changes from them.
+	var kept []string
+	defer func() {
+		if err != nil {
+			for _, snapshotID := range kept {
+				var unused int
+				if e := cp.DeleteSnapshot(snapshotID, &unused); e != nil {
+					glog.Errorf("Error while deleting snapshot %s: %v", snapshotID, e)
+				}
+			}
+		}
+	}()
+	saveSnapshot := func(service *service.Service) (err error) {
+		glog.V(0).Infof("saveSnapshot(%v)", service.ID)
+		progress.step("snapshots", fmt.Sprintf("Taking snapshot of service: %v", service.Name))
 		var snapshotID string
 		if e := cp.Snapshot(service.ID, &snapshotID); e != nil {
 			glog.Errorf("Could not snapshot service %s: %v", service.ID, e)
-			backupError <- e.Error()
-			return "", e
+			return e
 		}
-
-		// Delete snapshot on the way out
-		defer func() {
-			var unused int
-			if e := cp.DeleteSnapshot(snapshotID, &unused); e != nil {
-				glog.Errorf("Error while deleting snapshot %s: %v", snapshotID, e)
-				if err == nil {
-					err = e
-				}
-			}
-		}()
-		snapDir, e := getSnapshotPath(cp.vfs, service.PoolID, service.ID, snapshotID)
+		vol, e := getSubvolume(cp.vfs, service.PoolID, service.ID)
 		if e != nil {
 			glog.Errorf("Could not get subvolume %s:%s: %v", service.PoolID, service.ID, e)
-			backupError <- e.Error()
-			return "", e
+			return e
 		}
-		snapFile := backupPath("snapshots", fmt.Sprintf("%s.tgz", snapshotID))
-		if e := writeDirectoryToTgz(snapDir, snapFile); e != nil {
-			glog.Errorf("Could not write %s to %s: %v", snapDir, snapFile, e)
-			backupError <- e.Error()
-			return "", e
+		snapshot := backupSnapshot{ServiceID: service.ID, SnapshotID: snapshotID}
+
+		sender, ok := vol.Conn.(volume.Sender)
+		if ok {
+			kept = append(kept, snapshotID)
+			snapshot.Kept = true
+			if len(chain) > 0 {
+				snapshot.Parent = keptSnapshot(previous, service.ID, vol)
+			}
+		} else {
+			// Delete snapshot on the way out
+			defer func() {
+				var unused int
+				if e := cp.DeleteSnapshot(snapshotID, &unused); e != nil {
+					glog.Errorf("Error while deleting snapshot %s: %v", snapshotID, e)
+					if err == nil {
+						err = e
+					}
+				}
+			}()
 		}
 
-		glog.V(2).Infof("Saved snapshot of service:%v from dir:%v to snapFile:%v", service.ID, snapDir, snapFile)
-		return snapFile, nil
+		if snapshot.Parent != "" {
+			snapshot.File = filepath.Join("snapshots", snapshotID+".stream")
+			if e := sender.Send(snapshotID, snapshot.Parent, backupPath(snapshot.File)); e != nil {
+				glog.Errorf("Could not send snapshot %s: %v", snapshotID, e)
+				return e
+			}
+		} else {
+			snapDir := vol.SnapshotPath(snapshotID)
+			snapshot.File = filepath.Join("snapshots", snapshotID+".tgz")
+			if e := writeDirectoryToTgz(snapDir, backupPath(snapshot.File)); e != nil {
+				glog.Errorf("Could not write %s to %s: %v", snapDir, snapshot.File, e)
+				return e
+			}
+		}
+
+		glog.V(2).Infof("Saved snapshot of service:%v to %v", service.ID, snapshot.File)
+		manifest.Snapshots = append(manifest.Snapshots, snapshot)
+		return nil
 	}
 
 	glog.Infof("Snapshot all top level services (count:%d)", len(services))
 
 	for _, service := range services {
 		if service.ParentServiceID == "" {
-			if _, e := snapshotToTgzFile(service); e != nil {
+			if e := progress.checkpoint(); e != nil {
+				return e
+			}
+			if e := saveSnapshot(service); e != nil {
 				glog.Errorf("Could not save snapshot of service %s: %v", service.ID, e)
-				backupError <- e.Error()
 				return e
 			}
 			// Note: the deferred RemoveAll (above) will cleanup the file.
+			stepDone()
 		}
 	}
 
-	if e := writeDirectoryToTgz(backupPath(), *backupFilePath); e != nil {
-		glog.Errorf("Could not write %s to %s: %v", backupPath(), *backupFilePath, e)
-		backupError <- e.Error()
+	if e := progress.checkpoint(); e != nil {
+		return e
+	}
+	progress.step("archive", fmt.Sprintf("Writing backup file: %v", filename))
+	manifestDir := backupPath() + "-manifest"
+	if e := os.MkdirAll(manifestDir, os.ModeDir|0755); e != nil {
+		glog.Errorf("Could not find nor create %s: %v", manifestDir, e)
+		return e
+	}
+	defer os.RemoveAll(manifestDir)
+	if manifest.Files, e = checksumFiles(backupPath()); e != nil {
+		glog.Errorf("Could not checksum the files of %s: %v", backupPath(), e)
+		return e
+	}
+	if e := writeJSONToFile(manifest, filepath.Join(manifestDir, backupManifestName)); e != nil {
+		glog.Errorf("Could not write %s: %v", backupManifestName, e)
+		return e
+	}
+
+	tgzPath := filename
+	if envelope != nil {
+		tgzPath = backupPath() + ".tgz"
+		defer os.Remove(tgzPath)
+	}
+	if e := writeBackupToTgz(manifestDir, backupPath(), tgzPath); e != nil {
+		glog.Errorf("Could not write %s to %s: %v", backupPath(), tgzPath, e)
+		// Remove what was written of the backup file
+		if e := os.Remove(tgzPath); e != nil && !os.IsNotExist(e) {
+			glog.Errorf("Could not remove %s: %v", tgzPath, e)
+		}
 		return e
 	}
+	if envelope != nil {
+		envelope.Manifest = &manifest
+		if e := encryptBackup(tgzPath, filename, envelope, keys); e != nil {
+			glog.Errorf("Could not encrypt %s to %s: %v", tgzPath, filename, e)
+			return e
+		}
+	}
+	progress.update(func(p *dao.BackupProgress) {
+		p.StepsDone++
+		p.BytesDone = pathSize(filename)
+	})
+
+	if previous != nil {
+		cp.removeKeptSnapshots(previous)
+	}
 
-	glog.Infof("Created backup from dir:%s to file:%s", backupPath(), *backupFilePath)
+	glog.Infof("Created backup from dir:%s to file:%s", backupPath(), filename)
+	cp.facade.Audit(cp.context(), "backup", "backup", filename, nil, nil)
 	return nil
 }
 
@@ -485,6 +662,10 @@ func getSnapshotPath(vfs, poolId, serviceID, snapshotID string) (string, error)
 }
 
 func (this *ControlPlaneDao) AsyncRestore(backupFilePath string, unused *int) (err error) {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	this.restoreProgress.resetStatus()
 	go func() {
 		this.Restore(backupFilePath, unused)
 	}()
@@ -492,55 +673,51 @@ func (this *ControlPlaneDao) AsyncRestore(backupFilePath string, unused *int) (e
 	return nil
 }
 
+// RestoreStatus gets the next change of the progress of the restore in
+// flight: "timeout" if there is none within 10 seconds, an empty status once
+// the restore is done, or the error it failed with.
 func (this *ControlPlaneDao) RestoreStatus(notUsed string, restoreStatus *string) (err error) {
-	select {
-	case *restoreStatus = <-restoreOutput:
-	case <-time.After(10 * time.Second):
-		*restoreStatus = "timeout"
-	case *restoreStatus = <-restoreError:
-		err = errors.New(*restoreStatus)
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceBackup}); err != nil {
 		return err
 	}
-
-	return nil
+	*restoreStatus, err = this.restoreProgress.status(progressTimeout)
+	return err
 }
 
 // Restore replaces or restores the service templates, services, and related
 // docker images and shared file systmes, as extracted from a tgz backup file.
-func (cp *ControlPlaneDao) Restore(backupFilePath string, unused *int) (err error) {
-	// Lock the error and output channels to ensure that only one restore runs at any given time.
-	// Done in an anonymous function so we can ensure unlocking of the channel when we are done.
-	err = func() error {
-		cp.restoreLock.Lock()
-
-		//ensure that restoreLock is unlocked when this function exits
-		defer cp.restoreLock.Unlock()
-		restoreError = make(chan string, 100)
-
-		if restoreOutput != nil {
-			e := errors.New("Another restore is currently in progress")
-			glog.Errorf("An error occured when starting restore: %v", e)
-			restoreError <- e.Error()
-			return e
-		}
-		restoreOutput = make(chan string, 100)
-		return nil
-	}()
-
-	defer func() {
-		//close the channel for asynchronous calls to Backup
-		close(restoreOutput)
-		restoreOutput = nil
-	}()
+// Until it restores the templates, the restore can be canceled; the docker
+// images it loaded are kept.
+func (cp *ControlPlaneDao) Restore(backupFilePath string, unused *int) error {
+	if err := cp.authorize(role.Request{Action: role.Modify, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	return cp.trusted().restore(backupFilePath, dao.BackupKey{}, unused)
+}
 
-	//check the error status from channel creation here and return the error if it exists
-	if err != nil {
+// EncryptedRestore restores an encrypted backup, decrypted with its passphrase
+// or private key. The backups it is incremental to have the same key.
+func (cp *ControlPlaneDao) EncryptedRestore(request dao.RestoreRequest, unused *int) error {
+	if err := cp.authorize(role.Request{Action: role.Modify, Resource: role.ResourceBackup}); err != nil {
 		return err
 	}
+	return cp.trusted().restore(request.FilePath, request.Key, unused)
+}
 
-	restoreOutput <- "Starting restore"
+func (cp *ControlPlaneDao) restore(backupFilePath string, key dao.BackupKey, unused *int) (err error) {
+	// Only one restore runs at any given time
+	progress := cp.restoreProgress
+	if e := progress.start(backupFilePath); e != nil {
+		glog.Errorf("An error occured when starting restore: %v", e)
+		return e
+	}
+	defer func() {
+		if err == errCanceled {
+			glog.Infof("Canceled restore of %s", backupFilePath)
+		}
+		progress.finish(err)
+	}()
 
-	//TODO: acquire restore mutex, defer release
 	var (
 		doReloadLogstashContainer bool
 		templates                 map[string]*servicetemplate.ServiceTemplate
@@ -548,22 +725,26 @@ func (cp *ControlPlaneDao) Restore(backupFilePath string, unused *int) (err erro
 	)
 	defer func() {
 		if doReloadLogstashContainer {
-			go facade.LogstashContainerReloader(datastore.Get(), cp.facade) // don't block the main thread
+			go facade.LogstashContainerReloader(cp.context(), cp.facade) // don't block the main thread
 		}
 	}()
 	restorePath := func(relPath ...string) string {
 		return filepath.Join(append([]string{varPath(), "restore"}, relPath...)...)
 	}
+	stepDone := func(bytes int64) {
+		progress.update(func(p *dao.BackupProgress) {
+			p.StepsDone++
+			p.BytesDone += bytes
+		})
+	}
 
 	if e := os.RemoveAll(restorePath()); e != nil {
 		glog.Errorf("Could not remove %s: %v", restorePath(), e)
-		restoreError <- e.Error()
 		return e
 	}
 
 	if e := os.MkdirAll(restorePath(), os.ModeDir|0755); e != nil {
 		glog.Errorf("Could not find nor create %s: %v", restorePath(), e)
-		restoreError <- e.Error()
 		return e
 	}
 
@@ -576,93 +757,108 @@ func (cp *ControlPlaneDao) Restore(backupFilePath string, unused *int) (err erro
 		}
 	}()
 
-	if e := writeDirectoryFromTgz(restorePath(), backupFilePath); e != nil {
+	// A backup with a manifest may need the backups it is incremental to
+	chain, e := backupChain(backupFilePath)
+	if e != nil {
+		glog.Errorf("Could not find the backups %s is incremental to: %v", backupFilePath, e)
+		return e
+	}
+	filenames := []string{backupFilePath}
+	for i := 0; i < len(chain)-1; i++ {
+		filenames = append(filenames, filepath.Join(filepath.Dir(backupFilePath), chain[i].Name))
+	}
+	progress.update(func(p *dao.BackupProgress) {
+		p.Steps = len(filenames)
+		for _, filename := range filenames {
+			p.BytesTotal += pathSize(filename)
+		}
+	})
+
+	progress.step("expand", fmt.Sprintf("Expanding backup: %v", filepath.Base(backupFilePath)))
+	if e := expandBackup(restorePath(), backupFilePath, key); e != nil {
 		glog.Errorf("Could not expand %s to %s: %v", backupFilePath, restorePath(), e)
-		restoreError <- e.Error()
 		return e
 	}
+	stepDone(pathSize(backupFilePath))
+	for i := 0; i < len(chain)-1; i++ {
+		if e := progress.checkpoint(); e != nil {
+			return e
+		}
+		filename := filepath.Join(filepath.Dir(backupFilePath), chain[i].Name)
+		progress.step("expand", fmt.Sprintf("Expanding backup: %v", chain[i].Name))
+		if e := expandBackup(restorePath("chain", chain[i].Name), filename, key); e != nil {
+			glog.Errorf("Could not expand %s to %s: %v", filename, restorePath("chain", chain[i].Name), e)
+			return e
+		}
+		stepDone(pathSize(filename))
+	}
 
 	if e := readJSONFromFile(&templates, restorePath("templates.json")); e != nil {
 		glog.Errorf("Could not read templates from %s: %v", restorePath("templates.json"), e)
-		restoreError <- e.Error()
 		return e
 	}
 
+	if len(chain) > 0 {
+		newest := chain[len(chain)-1]
+		progress.update(func(p *dao.BackupProgress) {
+			p.Steps += len(newest.Images) + len(templates) + len(newest.Snapshots)
+		})
+		if e := cp.restoreChainImages(chain, restorePath); e != nil {
+			return e
+		}
+		if e := cp.restoreTemplates(templates, unused); e != nil {
+			return e
+		}
+		doReloadLogstashContainer = len(templates) > 0
+		if e := cp.restoreChain(chain, restorePath); e != nil {
+			return e
+		}
+		cp.facade.Audit(cp.context(), "restore", "backup", backupFilePath, nil, nil)
+		return nil
+	}
+
 	if e := readJSONFromFile(&imagesNameTags, restorePath("images.json")); e != nil {
 		glog.Errorf("Could not read images from %s: %v", restorePath("images.json"), e)
-		restoreError <- e.Error()
 		return e
 	}
-
-	// Restore the service templates ...
-	for templateID, template := range templates {
-		template.ID = templateID
-		restoreOutput <- fmt.Sprintf("Restoring service template: %v", template.ID)
-		if e := cp.UpdateServiceTemplate(*template, unused); e != nil {
-			glog.Errorf("Could not update template %s: %v", templateID, e)
-			restoreError <- e.Error()
-			return e
-		}
-		doReloadLogstashContainer = true
+	snapFiles, e := readDirFileNames(restorePath("snapshots"))
+	if e != nil {
+		glog.Errorf("Could not list contents of %s: %v", restorePath("snapshots"), e)
+		return e
 	}
+	progress.update(func(p *dao.BackupProgress) {
+		p.Steps += len(imagesNameTags) + len(templates) + len(snapFiles)
+	})
 
 	// Restore the docker images ...
 	for i, imageNameWithTags := range imagesNameTags {
+		if e := progress.checkpoint(); e != nil {
+			return e
+		}
 		imageID := imageNameWithTags[0]
 		imageTags := imageNameWithTags[1:]
-		imageName := "imported:" + imageID
-		restoreOutput <- fmt.Sprintf("Restoring Docker image: %v", imageName)
-		image, e := docker.FindImage(imageID, false)
-		if e != nil {
-			if e != dockerclient.ErrNoSuchImage {
-				glog.Errorf("Unexpected error when inspecting docker image %s: %v", imageID, e)
-				restoreError <- e.Error()
-				return e
-			}
-			filename := restorePath("images", fmt.Sprintf("%d.tar", i))
-			if e := importDockerImageFromFile(imageName, filename); e != nil {
-				glog.Errorf("Could not import docker image %s (%+v) from file %s: %v", imageID, imageTags, filename, e)
-				restoreError <- e.Error()
-				return e
-			}
-			image, e = docker.FindImage(imageName, false)
-			if e != nil {
-				glog.Errorf("Could not find imported docker image %s (%+v): %v", imageName, imageTags, e)
-				restoreError <- e.Error()
-				return e
-			}
-		}
-
-		//		if e := client.TagImage(imageID, dockerclient.TagImageOptions{Repo: "imported", Tag: imageID, Force: true}); e != nil {
-		if _, e := image.Tag(imageID); e != nil {
-			glog.Errorf("Found image %s already exists, but could not tag it: %s", imageID, e)
-			restoreError <- e.Error()
+		progress.update(func(p *dao.BackupProgress) {
+			p.Phase = "images"
+			p.Message = fmt.Sprintf("Restoring Docker image: %v", "imported:"+imageID)
+			p.Image = imageID
+		})
+		filename := restorePath("images", fmt.Sprintf("%d.tar", i))
+		if e := loadDockerImage(imageID, imageTags, filename); e != nil {
 			return e
 		}
-
-		for _, imageTag := range imageTags {
-			img, e := docker.FindImage(imageName, false)
-			if e != nil {
-				return e
-			}
-
-			_, e = img.Tag(imageTag)
-			if e != nil {
-				return e
-			}
-		}
+		stepDone(0)
 	}
 
-	// Restore the snapshots ...
-	snapFiles, e := readDirFileNames(restorePath("snapshots"))
-	if e != nil {
-		glog.Errorf("Could not list contents of %s: %v", restorePath("snapshots"), e)
-		restoreError <- e.Error()
+	// Restore the service templates ...
+	if e := cp.restoreTemplates(templates, unused); e != nil {
 		return e
 	}
+	doReloadLogstashContainer = len(templates) > 0
+
+	// Restore the snapshots ...
 	for _, snapFile := range snapFiles {
 		snapshotID := strings.TrimSuffix(snapFile, ".tgz")
-		restoreOutput <- fmt.Sprintf("Restoring snapshot: %v", snapshotID)
+		progress.step("snapshots", fmt.Sprintf("Restoring snapshot: %v", snapshotID))
 		if snapshotID == snapFile {
 			continue //the filename does not end with .tgz
 		}
@@ -677,32 +873,27 @@ func (cp *ControlPlaneDao) Restore(backupFilePath string, unused *int) (err erro
 		snapDirTemp := restorePath("snapshots", snapshotID)
 		if e := writeDirectoryFromTgz(snapDirTemp, snapFilePath); e != nil {
 			glog.Errorf("Could not write %s from %s: %v", snapDirTemp, snapFilePath, e)
-			restoreError <- e.Error()
 			return e
 		}
 		if e := cp.dfs.RollbackServices(snapDirTemp); e != nil {
 			glog.Errorf("Could not rollback services: %s", e)
-			restoreError <- e.Error()
 			return e
 		}
 
 		var service service.Service
 		if e := cp.GetService(serviceID, &service); e != nil {
 			glog.Errorf("Could not find service %s for snapshot %s: %s", serviceID, snapshotID, e)
-			restoreError <- e.Error()
 			return e
 		}
 
 		snapDir, e := getSnapshotPath(cp.vfs, service.PoolID, service.ID, snapshotID)
 		if e != nil {
 			glog.Errorf("Could not get subvolume %s:%s: %v", service.PoolID, service.ID, e)
-			restoreError <- e.Error()
 			return e
 		}
 
 		if e = os.Rename(snapDirTemp, snapDir); e != nil {
 			glog.Errorf("Could not move %s to %s: %s", snapDirTemp, snapDir, e)
-			restoreError <- e.Error()
 			return e
 		}
 
@@ -718,12 +909,32 @@ func (cp *ControlPlaneDao) Restore(backupFilePath string, unused *int) (err erro
 
 		if e := cp.Rollback(snapshotID, unused); e != nil {
 			glog.Errorf("Could not rollback to snapshot %s: %v", snapshotID, e)
-			restoreError <- e.Error()
 			return e
 		}
+		stepDone(0)
 	}
 
 	//TODO: garbage collect (http://jimhoskins.com/2013/07/27/remove-untagged-docker-images.html)
+	cp.facade.Audit(cp.context(), "restore", "backup", backupFilePath, nil, nil)
+	return nil
+}
+
+// restoreTemplates restores the service templates of a backup. From here on,
+// the restore can no longer be canceled.
+func (cp *ControlPlaneDao) restoreTemplates(templates map[string]*servicetemplate.ServiceTemplate, unused *int) error {
+	progress := cp.restoreProgress
+	if e := progress.commit(); e != nil {
+		return e
+	}
+	for templateID, template := range templates {
+		template.ID = templateID
+		progress.step("templates", fmt.Sprintf("Restoring service template: %v", template.ID))
+		if e := cp.UpdateServiceTemplate(*template, unused); e != nil {
+			glog.Errorf("Could not update template %s: %v", templateID, e)
+			return e
+		}
+		progress.update(func(p *dao.BackupProgress) { p.StepsDone++ })
+	}
 	return nil
 }
 
@@ -0,0 +1,506 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// A backup may be incremental to the backup before it, which may itself be
+// incremental, and so on down to a full backup. Together they are a chain:
+//    backup-2014-10-01-000000.tgz  full
+//    backup-2014-10-02-000000.tgz  parent: backup-2014-10-01-000000.tgz
+//    backup-2014-10-03-000000.tgz  parent: backup-2014-10-02-000000.tgz
+// The backups of a chain are kept in the same directory. Each backup starts
+// with a manifest naming its parent, so that a chain can be found without
+// expanding its backups.
+
+package elasticsearch
+
+import (
+	dockerclient "github.com/zenoss/go-dockerclient"
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/commons/docker"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/volume"
+
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backupManifestName is the name of the manifest, the first file of a backup
+const backupManifestName = "backup.json"
+
+// backupManifest describes a backup. Backups taken before there were
+// manifests have none, and are never part of a chain.
+type backupManifest struct {
+	Name      string            // file name of the backup
+	Parent    string            // file name of the backup this one is incremental to, if any
+	Created   time.Time         // when the backup was taken
+	Layers    []string          // ids of the docker layers saved in this backup
+	Images    []backupImage     // the images the services and templates need
+	Snapshots []backupSnapshot  // a snapshot of each tenant
+	Files     map[string]string // SHA-256 of each file of the backup, by path
+}
+
+// backupImage is a docker image in a backup. Its layers are saved in this
+// backup, or in the backups of the chain before it.
+type backupImage struct {
+	ID     string   // the image id
+	Tags   []string // the names the image is tagged with
+	Layers []string // the ids of the layers of the image
+}
+
+// backupSnapshot is a tenant snapshot in a backup
+type backupSnapshot struct {
+	ServiceID  string // the tenant
+	SnapshotID string
+	File       string // path of the snapshot data in the backup: a tgz, or a send stream if it has a parent
+	Kept       bool   // the snapshot is kept for the next incremental backup
+	Parent     string // the snapshot the send stream has the changes from, if any
+}
+
+// readBackupManifest reads the manifest at the start of a backup file. It is
+// nil if the backup has no manifest.
+func readBackupManifest(filename string) (*backupManifest, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	// the manifest of an encrypted backup is in its header
+	if envelope, _, err := readBackupEnvelope(file); err != nil {
+		return nil, fmt.Errorf("could not read backup %s: %s", filename, err)
+	} else if envelope != nil {
+		return envelope.Manifest, nil
+	} else if _, err := file.Seek(0, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read backup %s: %s", filename, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	if header, err := tr.Next(); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read backup %s: %s", filename, err)
+	} else if header.Name != backupManifestName {
+		return nil, nil
+	}
+	var manifest backupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("could not read the manifest of backup %s: %s", filename, err)
+	}
+	return &manifest, nil
+}
+
+// writeBackupToTgz writes the manifest in manifestDir, followed by the
+// contents of src, to a tgz file
+func writeBackupToTgz(manifestDir, src, filename string) error {
+	cmd, e := commandAsRoot("tar", "-czf", filename, "-C", manifestDir, backupManifestName, "-C", src, ".")
+	if e != nil {
+		return e
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		glog.Errorf("Unable to writeBackupToTgz cmd:%+v  error:%v  output:%s", cmd, err, string(output))
+		return err
+	}
+	return nil
+}
+
+// backupChain returns the manifests of a backup and of the backups it is
+// incremental to, oldest first. It is empty if the backup has no manifest.
+func backupChain(filename string) ([]*backupManifest, error) {
+	var chain []*backupManifest
+	seen := make(map[string]bool)
+	for {
+		manifest, err := readBackupManifest(filename)
+		if err != nil {
+			return nil, err
+		} else if manifest == nil {
+			if len(chain) > 0 {
+				return nil, fmt.Errorf("backup %s of the chain has no manifest", filename)
+			}
+			return nil, nil
+		}
+		chain = append([]*backupManifest{manifest}, chain...)
+		if manifest.Parent == "" {
+			return chain, nil
+		} else if seen[manifest.Parent] {
+			return nil, fmt.Errorf("backup %s is incremental to itself", manifest.Parent)
+		}
+		seen[manifest.Parent] = true
+
+		parent := filepath.Join(filepath.Dir(filename), manifest.Parent)
+		if _, err := os.Stat(parent); err != nil {
+			return nil, fmt.Errorf("backup %s is incremental to %s, which is not in %s", manifest.Name, manifest.Parent, filepath.Dir(filename))
+		}
+		filename = parent
+	}
+}
+
+// listBackups returns the backups in a directory, oldest first
+func listBackups(backupsDirectory string) ([]dao.BackupInfo, error) {
+	files, err := ioutil.ReadDir(backupsDirectory)
+	if os.IsNotExist(err) {
+		return []dao.BackupInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	backups := make([]dao.BackupInfo, 0)
+	for _, file := range files {
+		if file.IsDir() || !(strings.HasSuffix(file.Name(), ".tgz") || strings.HasSuffix(file.Name(), ".tgz.enc")) {
+			continue
+		}
+		info := dao.BackupInfo{Name: file.Name(), Created: file.ModTime().UTC(), Size: file.Size()}
+		if manifest, err := readBackupManifest(filepath.Join(backupsDirectory, file.Name())); err != nil {
+			glog.Warningf("Could not read backup %s: %s", file.Name(), err)
+		} else if manifest != nil {
+			info.Parent = manifest.Parent
+			info.Created = manifest.Created
+		}
+		backups = append(backups, info)
+	}
+	sort.Sort(backupsByCreated(backups))
+	return backups, nil
+}
+
+type backupsByCreated []dao.BackupInfo
+
+func (b backupsByCreated) Len() int           { return len(b) }
+func (b backupsByCreated) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b backupsByCreated) Less(i, j int) bool { return b[i].Created.Before(b[j].Created) }
+
+// newestBackup returns the manifest of the newest backup in a directory that
+// has one, or nil if there is none
+func newestBackup(backupsDirectory string) (*backupManifest, error) {
+	backups, err := listBackups(backupsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		manifest, err := readBackupManifest(filepath.Join(backupsDirectory, backups[i].Name))
+		if err != nil {
+			glog.Warningf("Could not read backup %s: %s", backups[i].Name, err)
+		} else if manifest != nil {
+			return manifest, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListBackups returns the backups in a directory, oldest first
+func (cp *ControlPlaneDao) ListBackups(backupsDirectory string, backups *[]dao.BackupInfo) (err error) {
+	if err := cp.authorize(role.Request{Action: role.Read, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	if backupsDirectory == "" {
+		backupsDirectory = filepath.Join(varPath(), "backups")
+	}
+	*backups, err = listBackups(backupsDirectory)
+	return err
+}
+
+// saveDockerImageLayers saves the layers of an image to dir/layers, leaving
+// out the layers that are known, that is, already saved. It returns the
+// layers of the image and the layers it saved.
+func saveDockerImageLayers(imageID, dir string, known map[string]bool) (layers, saved []string, err error) {
+	filename := filepath.Join(dir, imageID+".tar")
+	if output, err := exec.Command("docker", "save", "-o", filename, imageID).CombinedOutput(); err != nil {
+		glog.Errorf("Could not save docker image %s: %s", imageID, string(output))
+		return nil, nil, err
+	}
+	defer os.Remove(filename)
+
+	saveDir := filepath.Join(dir, imageID)
+	if err := writeDirectoryFromTgz(saveDir, filename); err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(saveDir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "layers"), os.ModeDir|0755); err != nil {
+		return nil, nil, err
+	}
+	files, err := ioutil.ReadDir(saveDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue // the repositories file; tags are kept in the manifest
+		}
+		layer := file.Name()
+		layers = append(layers, layer)
+		if known[layer] {
+			continue
+		}
+		if err := os.Rename(filepath.Join(saveDir, layer), filepath.Join(dir, "layers", layer)); err != nil {
+			return nil, nil, err
+		}
+		known[layer] = true
+		saved = append(saved, layer)
+	}
+	return layers, saved, nil
+}
+
+// loadDockerImageLayers loads an image from the layers saved by
+// saveDockerImageLayers, unless the image is already there, and tags it with
+// its names. layerPaths has where each layer was expanded; dir is where the
+// image is put together.
+func loadDockerImageLayers(image backupImage, layerPaths map[string]string, dir string) error {
+	img, e := docker.FindImage(image.ID, false)
+	if e == dockerclient.ErrNoSuchImage {
+		if e := os.MkdirAll(dir, os.ModeDir|0755); e != nil {
+			return e
+		}
+		for _, layer := range image.Layers {
+			layerPath, ok := layerPaths[layer]
+			if !ok {
+				return fmt.Errorf("layer %s of docker image %s is not in the backups", layer, image.ID)
+			}
+			if e := os.Symlink(layerPath, filepath.Join(dir, layer)); e != nil {
+				return e
+			}
+		}
+
+		filename := dir + ".tar"
+		cmd, e := commandAsRoot("tar", "-chf", filename, "-C", dir, ".")
+		if e != nil {
+			return e
+		}
+		if output, e := cmd.CombinedOutput(); e != nil {
+			glog.Errorf("Could not put docker image %s together: %s", image.ID, string(output))
+			return e
+		}
+		if output, e := exec.Command("docker", "load", "-i", filename).CombinedOutput(); e != nil {
+			glog.Errorf("Could not load docker image %s: %s", image.ID, string(output))
+			return e
+		}
+		if img, e = docker.FindImage(image.ID, false); e != nil {
+			glog.Errorf("Could not find loaded docker image %s (%+v): %v", image.ID, image.Tags, e)
+			return e
+		}
+	} else if e != nil {
+		glog.Errorf("Unexpected error when inspecting docker image %s: %v", image.ID, e)
+		return e
+	}
+
+	for _, tag := range image.Tags {
+		if _, e := img.Tag(tag); e != nil {
+			glog.Errorf("Could not tag docker image %s as %s: %s", image.ID, tag, e)
+			return e
+		}
+	}
+	return nil
+}
+
+// snapshotStreams returns the snapshots that add a kept snapshot of the
+// newest backup of a chain, oldest first, with the index of the backup each
+// is in: the tgz of a full backup, then the send streams of the changes since
+func snapshotStreams(chain []*backupManifest, snapshot backupSnapshot) ([]backupSnapshot, []int, error) {
+	streams, backups := []backupSnapshot{snapshot}, []int{len(chain) - 1}
+	for i := len(chain) - 2; snapshot.Parent != ""; i-- {
+		if i < 0 {
+			return nil, nil, fmt.Errorf("snapshot %s is not in the backups", snapshot.Parent)
+		}
+		for _, s := range chain[i].Snapshots {
+			if s.Kept && s.SnapshotID == snapshot.Parent {
+				snapshot = s
+				streams = append([]backupSnapshot{s}, streams...)
+				backups = append([]int{i}, backups...)
+				break
+			}
+		}
+	}
+	return streams, backups, nil
+}
+
+// restoreChainImages loads the docker images of the newest backup of a chain
+// from the layers of the chain. The newest backup is expanded at
+// restorePath(), the others at restorePath("chain", name).
+func (cp *ControlPlaneDao) restoreChainImages(chain []*backupManifest, restorePath func(...string) string) error {
+	progress := cp.restoreProgress
+	backupPath := chainBackupPath(chain, restorePath)
+	layerPaths := make(map[string]string)
+	for i, manifest := range chain {
+		for _, layer := range manifest.Layers {
+			layerPaths[layer] = backupPath(i, "images", "layers", layer)
+		}
+	}
+	for i, image := range chain[len(chain)-1].Images {
+		if e := progress.checkpoint(); e != nil {
+			return e
+		}
+		id := image.ID
+		progress.update(func(p *dao.BackupProgress) {
+			p.Phase = "images"
+			p.Message = fmt.Sprintf("Restoring Docker image: %v", id)
+			p.Image = id
+		})
+		if e := loadDockerImageLayers(image, layerPaths, restorePath("load", strconv.Itoa(i))); e != nil {
+			return e
+		}
+		progress.update(func(p *dao.BackupProgress) { p.StepsDone++ })
+	}
+	return nil
+}
+
+// restoreChain restores the services and snapshots of the newest backup of a
+// chain, once its images are loaded
+func (cp *ControlPlaneDao) restoreChain(chain []*backupManifest, restorePath func(...string) string) error {
+	progress := cp.restoreProgress
+	backupPath := chainBackupPath(chain, restorePath)
+	newest := chain[len(chain)-1]
+
+	// Restore the services, so that the volumes of the tenants can be found ...
+	if e := cp.dfs.RollbackServices(restorePath()); e != nil {
+		glog.Errorf("Could not rollback services: %s", e)
+		return e
+	}
+
+	// Restore the snapshots ...
+	for _, snapshot := range newest.Snapshots {
+		progress.step("snapshots", fmt.Sprintf("Restoring snapshot: %v", snapshot.SnapshotID))
+		if e := cp.restoreSnapshot(chain, snapshot, backupPath); e != nil {
+			return e
+		}
+		progress.update(func(p *dao.BackupProgress) { p.StepsDone++ })
+	}
+	return nil
+}
+
+// chainBackupPath returns where the i-th backup of a chain is expanded
+func chainBackupPath(chain []*backupManifest, restorePath func(...string) string) func(int, ...string) string {
+	return func(i int, relPath ...string) string {
+		if i == len(chain)-1 {
+			return restorePath(relPath...)
+		}
+		return restorePath(append([]string{"chain", chain[i].Name}, relPath...)...)
+	}
+}
+
+// restoreSnapshot adds a snapshot of the newest backup of a chain to the
+// volume of its tenant, unless it is there already, and rolls back to it
+func (cp *ControlPlaneDao) restoreSnapshot(chain []*backupManifest, snapshot backupSnapshot, backupPath func(int, ...string) string) (err error) {
+	var tenant service.Service
+	if e := cp.GetService(snapshot.ServiceID, &tenant); e != nil {
+		glog.Errorf("Could not find service %s for snapshot %s: %s", snapshot.ServiceID, snapshot.SnapshotID, e)
+		return e
+	}
+	vol, e := getSubvolume(cp.vfs, tenant.PoolID, tenant.ID)
+	if e != nil {
+		glog.Errorf("Could not get subvolume %s:%s: %v", tenant.PoolID, tenant.ID, e)
+		return e
+	}
+	existing := make(map[string]bool)
+	if snapshots, e := vol.Snapshots(); e != nil {
+		return e
+	} else {
+		for _, label := range snapshots {
+			existing[label] = true
+		}
+	}
+
+	// Delete the snapshots that were added on the way out
+	var added []string
+	defer func() {
+		for _, snapshotID := range added {
+			var unused int
+			if e := cp.DeleteSnapshot(snapshotID, &unused); e != nil {
+				glog.Errorf("Couldn't delete snapshot %s: %v", snapshotID, e)
+				if err == nil {
+					err = e
+				}
+			}
+		}
+	}()
+
+	// A send stream is received on top of its parent as the volume took or
+	// received it; a snapshot added from a tgz cannot be its parent
+	parents := make(map[string]bool)
+	for label := range existing {
+		parents[label] = true
+	}
+	streams, backups, e := snapshotStreams(chain, snapshot)
+	if e != nil {
+		return e
+	}
+	for i, s := range streams {
+		if existing[s.SnapshotID] {
+			continue
+		}
+		if s.Parent == "" {
+			if e := addSnapshot(vol, s.SnapshotID, backupPath(backups[i], s.File)); e != nil {
+				glog.Errorf("Could not add snapshot %s from %s: %v", s.SnapshotID, s.File, e)
+				return e
+			}
+			added = append(added, s.SnapshotID)
+			continue
+		}
+		sender, ok := vol.Conn.(volume.Sender)
+		if !ok {
+			return fmt.Errorf("the volume of %s cannot receive snapshot %s; restore a full backup instead", tenant.ID, s.SnapshotID)
+		} else if !parents[s.Parent] {
+			return fmt.Errorf("snapshot %s has the changes since snapshot %s, which the volume of %s does not have; restore a full backup instead", s.SnapshotID, s.Parent, tenant.ID)
+		}
+		if e := sender.Receive(backupPath(backups[i], s.File)); e != nil {
+			return e
+		}
+		added = append(added, s.SnapshotID)
+		parents[s.SnapshotID] = true
+	}
+
+	var unused int
+	if e := cp.Rollback(snapshot.SnapshotID, &unused); e != nil {
+		glog.Errorf("Could not rollback to snapshot %s: %v", snapshot.SnapshotID, e)
+		return e
+	}
+	return nil
+}
+
+// keptSnapshot returns the snapshot of a tenant that a backup kept, if the
+// volume still has it
+func keptSnapshot(manifest *backupManifest, tenantID string, vol *volume.Volume) string {
+	snapshots, e := vol.Snapshots()
+	if e != nil {
+		glog.Warningf("Could not list the snapshots of %s: %v", tenantID, e)
+		return ""
+	}
+	for _, snapshot := range manifest.Snapshots {
+		if snapshot.ServiceID != tenantID || !snapshot.Kept {
+			continue
+		}
+		for _, label := range snapshots {
+			if label == snapshot.SnapshotID {
+				return label
+			}
+		}
+	}
+	return ""
+}
+
+// removeKeptSnapshots deletes the snapshots a backup kept for the next
+// incremental backup
+func (cp *ControlPlaneDao) removeKeptSnapshots(manifest *backupManifest) {
+	for _, snapshot := range manifest.Snapshots {
+		if !snapshot.Kept {
+			continue
+		}
+		var unused int
+		if e := cp.DeleteSnapshot(snapshot.SnapshotID, &unused); e != nil {
+			glog.Warningf("Could not delete snapshot %s kept for backup %s: %v", snapshot.SnapshotID, manifest.Name, e)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTestBackup writes a backup with a manifest and a data file to dir
+func writeTestBackup(t *testing.T, dir string, manifest backupManifest) {
+	src, err := ioutil.TempDir("", "test-backup-src")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(src)
+	manifestDir, err := ioutil.TempDir("", "test-backup-manifest")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(manifestDir)
+
+	if err := ioutil.WriteFile(filepath.Join(src, "templates.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %s", err)
+	}
+	if err := writeJSONToFile(manifest, filepath.Join(manifestDir, backupManifestName)); err != nil {
+		t.Fatalf("Failed to write manifest: %s", err)
+	}
+	if err := writeBackupToTgz(manifestDir, src, filepath.Join(dir, manifest.Name)); err != nil {
+		t.Fatalf("Failed to write backup %s: %s", manifest.Name, err)
+	}
+}
+
+func TestBackup_readBackupManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-backups")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	manifest := backupManifest{
+		Name:    "backup-2014-10-01-000000.tgz",
+		Created: time.Date(2014, 10, 1, 0, 0, 0, 0, time.UTC),
+		Layers:  []string{"layer1"},
+		Images:  []backupImage{{ID: "image1", Tags: []string{"repo:tag"}, Layers: []string{"layer1"}}},
+		Snapshots: []backupSnapshot{
+			{ServiceID: "tenant", SnapshotID: "tenant_1", File: "snapshots/tenant_1.tgz", Kept: true},
+		},
+	}
+	writeTestBackup(t, dir, manifest)
+	if actual, err := readBackupManifest(filepath.Join(dir, manifest.Name)); err != nil {
+		t.Fatalf("Failed to read manifest: %s", err)
+	} else if !reflect.DeepEqual(*actual, manifest) {
+		t.Fatalf("Expected %+v, got %+v", manifest, *actual)
+	}
+
+	// backups taken before manifests have none
+	src, err := ioutil.TempDir("", "test-backup-src")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(src)
+	if err := ioutil.WriteFile(filepath.Join(src, "images.json"), []byte("[]"), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %s", err)
+	}
+	legacy := filepath.Join(dir, "backup-2014-09-01-000000.tgz")
+	if err := writeDirectoryToTgz(src, legacy); err != nil {
+		t.Fatalf("Failed to write backup: %s", err)
+	}
+	if actual, err := readBackupManifest(legacy); err != nil {
+		t.Fatalf("Failed to read backup: %s", err)
+	} else if actual != nil {
+		t.Fatalf("Expected no manifest, got %+v", *actual)
+	}
+}
+
+func TestBackup_backupChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-backups")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	names := []string{"backup-2014-10-01-000000.tgz", "backup-2014-10-02-000000.tgz", "backup-2014-10-03-000000.tgz"}
+	for i, name := range names {
+		manifest := backupManifest{Name: name, Created: time.Date(2014, 10, i+1, 0, 0, 0, 0, time.UTC)}
+		if i > 0 {
+			manifest.Parent = names[i-1]
+		}
+		writeTestBackup(t, dir, manifest)
+	}
+
+	chain, err := backupChain(filepath.Join(dir, names[2]))
+	if err != nil {
+		t.Fatalf("Failed to find the chain: %s", err)
+	}
+	var actual []string
+	for _, manifest := range chain {
+		actual = append(actual, manifest.Name)
+	}
+	if !reflect.DeepEqual(actual, names) {
+		t.Fatalf("Expected chain %v, got %v", names, actual)
+	}
+
+	if newest, err := newestBackup(dir); err != nil {
+		t.Fatalf("Failed to find the newest backup: %s", err)
+	} else if newest == nil || newest.Name != names[2] {
+		t.Fatalf("Expected newest backup %s, got %+v", names[2], newest)
+	}
+
+	if backups, err := listBackups(dir); err != nil {
+		t.Fatalf("Failed to list backups: %s", err)
+	} else if len(backups) != 3 || backups[1].Name != names[1] || backups[1].Parent != names[0] {
+		t.Fatalf("Unexpected backups %+v", backups)
+	}
+
+	// the chain is broken without its full backup
+	if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+		t.Fatalf("Failed to remove backup: %s", err)
+	}
+	if _, err := backupChain(filepath.Join(dir, names[2])); err == nil {
+		t.Fatalf("Expected an error for a broken chain")
+	}
+}
+
+func TestBackup_snapshotStreams(t *testing.T) {
+	chain := []*backupManifest{
+		{Name: "1", Snapshots: []backupSnapshot{{ServiceID: "tenant", SnapshotID: "tenant_1", Kept: true}}},
+		{Name: "2", Snapshots: []backupSnapshot{{ServiceID: "tenant", SnapshotID: "tenant_2", Kept: true, Parent: "tenant_1"}}},
+		{Name: "3", Snapshots: []backupSnapshot{{ServiceID: "tenant", SnapshotID: "tenant_3", Kept: true, Parent: "tenant_2"}}},
+	}
+
+	streams, backups, err := snapshotStreams(chain, chain[2].Snapshots[0])
+	if err != nil {
+		t.Fatalf("Failed to find the streams: %s", err)
+	}
+	var actual []string
+	for _, stream := range streams {
+		actual = append(actual, stream.SnapshotID)
+	}
+	if expected := []string{"tenant_1", "tenant_2", "tenant_3"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected streams %v, got %v", expected, actual)
+	}
+	if expected := []int{0, 1, 2}; !reflect.DeepEqual(backups, expected) {
+		t.Errorf("Expected backups %v, got %v", expected, backups)
+	}
+
+	// a full stream ends the walk
+	chain[1].Snapshots[0].Parent = ""
+	if streams, _, err := snapshotStreams(chain, chain[2].Snapshots[0]); err != nil {
+		t.Fatalf("Failed to find the streams: %s", err)
+	} else if len(streams) != 2 {
+		t.Errorf("Expected 2 streams, got %+v", streams)
+	}
+
+	// a missing parent is an error
+	chain[1].Snapshots[0].Parent = "tenant_0"
+	if _, _, err := snapshotStreams(chain, chain[2].Snapshots[0]); err == nil {
+		t.Errorf("Expected an error for a missing parent")
+	}
+}
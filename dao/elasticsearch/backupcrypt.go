@@ -0,0 +1,403 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// An encrypted backup is an envelope around the tgz file of the backup:
+//    magic       "SVCDBAK1"
+//    length      of the header, 4 bytes, big endian
+//    header      JSON backupEnvelope, with the manifest of the backup
+//    ciphertext  the tgz file, encrypted with AES-256 in CTR mode
+//    mac         HMAC-SHA256 of all of the above
+// The manifest is in the clear, so that chains of encrypted backups can be
+// found without the key. The keys of a backup are random, and encrypted with
+// an RSA public key, or derived from a passphrase.
+
+package elasticsearch
+
+import (
+	"code.google.com/p/go.crypto/pbkdf2"
+	"github.com/control-center/serviced/dao"
+
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+const (
+	backupEnvelopeMagic = "SVCDBAK1"
+	backupCipher        = "aes-256-ctr-hmac-sha256"
+	backupKDFIterations = 65536
+	backupKeyCheck      = "serviced backup key"
+
+	// the header has a few keys; a longer one is not read into memory
+	maxBackupEnvelopeSize = 64 * 1024
+
+	// the header is not trusted until the key checks out, so it cannot ask
+	// for much more work than backups are written with
+	maxBackupKDFIterations = 16 * backupKDFIterations
+)
+
+// errBackupModified is returned when the mac of an encrypted backup is wrong
+var errBackupModified = errors.New("the backup was modified after it was written")
+
+// backupEnvelope is the header of an encrypted backup
+type backupEnvelope struct {
+	Manifest   *backupManifest
+	Cipher     string
+	Salt       []byte // salt of the passphrase, if encrypted with one
+	Iterations int    // iterations of PBKDF2 deriving the keys from the passphrase
+	WrappedKey []byte // the keys, encrypted with the public key, if encrypted with one
+	IV         []byte
+	KeyCheck   []byte // HMAC of backupKeyCheck, to tell a wrong key from a modified backup
+}
+
+// backupKeys are the keys of an encrypted backup
+type backupKeys struct {
+	cipher []byte // AES-256
+	mac    []byte // HMAC-SHA256
+}
+
+func splitBackupKeys(b []byte) *backupKeys {
+	return &backupKeys{cipher: b[:32], mac: b[32:]}
+}
+
+func (k *backupKeys) keyCheck() []byte {
+	mac := hmac.New(sha256.New, k.mac)
+	mac.Write([]byte(backupKeyCheck))
+	return mac.Sum(nil)
+}
+
+func backupKeyIsEmpty(key dao.BackupKey) bool {
+	return key.Passphrase == "" && key.PublicKey == "" && key.PrivateKey == ""
+}
+
+// newBackupEnvelope creates the header and the keys of a backup encrypted
+// with a passphrase or a public key
+func newBackupEnvelope(key dao.BackupKey) (*backupEnvelope, *backupKeys, error) {
+	if key.Passphrase != "" && key.PublicKey != "" {
+		return nil, nil, errors.New("a backup is encrypted with either a passphrase or a public key")
+	} else if key.Passphrase == "" && key.PublicKey == "" {
+		return nil, nil, errors.New("a passphrase or a public key is needed to encrypt a backup")
+	}
+
+	envelope := &backupEnvelope{Cipher: backupCipher, IV: make([]byte, aes.BlockSize)}
+	if _, err := io.ReadFull(rand.Reader, envelope.IV); err != nil {
+		return nil, nil, err
+	}
+
+	var keys *backupKeys
+	if key.Passphrase != "" {
+		envelope.Salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, envelope.Salt); err != nil {
+			return nil, nil, err
+		}
+		envelope.Iterations = backupKDFIterations
+		keys = splitBackupKeys(pbkdf2.Key([]byte(key.Passphrase), envelope.Salt, envelope.Iterations, 64, sha256.New))
+	} else {
+		pub, err := parseBackupPublicKey(key.PublicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		b := make([]byte, 64)
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return nil, nil, err
+		}
+		if envelope.WrappedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, b, nil); err != nil {
+			return nil, nil, fmt.Errorf("could not encrypt with the public key: %s", err)
+		}
+		keys = splitBackupKeys(b)
+	}
+	envelope.KeyCheck = keys.keyCheck()
+	return envelope, keys, nil
+}
+
+// keys returns the keys of an encrypted backup, from its passphrase or the
+// private key
+func (e *backupEnvelope) keys(key dao.BackupKey) (*backupKeys, error) {
+	if e.Cipher != backupCipher {
+		return nil, fmt.Errorf("unknown backup cipher %q", e.Cipher)
+	}
+
+	var keys *backupKeys
+	if e.WrappedKey != nil {
+		if key.PrivateKey == "" {
+			return nil, errors.New("the backup is encrypted with a public key; the private key is needed")
+		}
+		priv, err := parseBackupPrivateKey(key.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		b, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, e.WrappedKey, nil)
+		if err != nil || len(b) != 64 {
+			return nil, errors.New("the backup was not encrypted with the public key of this private key")
+		}
+		keys = splitBackupKeys(b)
+	} else {
+		if key.Passphrase == "" {
+			return nil, errors.New("the backup is encrypted with a passphrase; the passphrase is needed")
+		}
+		if e.Iterations < 1 || e.Iterations > maxBackupKDFIterations {
+			return nil, fmt.Errorf("the backup asks for %d iterations of its key derivation, not between 1 and %d", e.Iterations, maxBackupKDFIterations)
+		}
+		keys = splitBackupKeys(pbkdf2.Key([]byte(key.Passphrase), e.Salt, e.Iterations, 64, sha256.New))
+	}
+	if !hmac.Equal(keys.keyCheck(), e.KeyCheck) {
+		return nil, errors.New("the passphrase of the backup is wrong")
+	}
+	return keys, nil
+}
+
+func parseBackupPublicKey(text string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, errors.New("the public key is not PEM encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the public key: %s", err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("the public key is not an RSA key")
+	}
+	return rsaPub, nil
+}
+
+func parseBackupPrivateKey(text string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, errors.New("the private key is not PEM encoded")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the private key: %s", err)
+		}
+		return priv, nil
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the private key: %s", err)
+	}
+	rsaPriv, ok := priv.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key is not an RSA key")
+	}
+	return rsaPriv, nil
+}
+
+// encryptBackup writes the tgz file src to dst, encrypted
+func encryptBackup(src, dst string, envelope *backupEnvelope, keys *backupKeys) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := out.Close(); e != nil && err == nil {
+			err = e
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+
+	header, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(keys.cipher)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, keys.mac)
+	w := bufio.NewWriter(io.MultiWriter(out, mac))
+
+	if _, err := w.WriteString(backupEnvelopeMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(header))); err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(cipher.StreamWriter{S: cipher.NewCTR(block, envelope.IV), W: w}, in); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err = out.Write(mac.Sum(nil))
+	return err
+}
+
+// readBackupEnvelope reads the header of an encrypted backup. It is nil if the
+// backup is not encrypted.
+func readBackupEnvelope(file *os.File) (*backupEnvelope, int64, error) {
+	magic := make([]byte, len(backupEnvelopeMagic))
+	if _, err := io.ReadFull(file, magic); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	} else if string(magic) != backupEnvelopeMagic {
+		return nil, 0, nil
+	}
+
+	var length uint32
+	if err := binary.Read(file, binary.BigEndian, &length); err != nil {
+		return nil, 0, fmt.Errorf("could not read the header of the backup: %s", err)
+	}
+	if length > maxBackupEnvelopeSize {
+		return nil, 0, fmt.Errorf("the header of the backup is %d bytes, more than the %d it can be", length, maxBackupEnvelopeSize)
+	}
+	header := make([]byte, length)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, 0, fmt.Errorf("could not read the header of the backup: %s", err)
+	}
+	var envelope backupEnvelope
+	if err := json.Unmarshal(header, &envelope); err != nil {
+		return nil, 0, fmt.Errorf("could not read the header of the backup: %s", err)
+	}
+	return &envelope, int64(len(magic)) + 4 + int64(length), nil
+}
+
+// backupReader reads the tgz file of a backup, decrypting it if it is
+// encrypted. An encrypted backup that was modified fails to read to its end.
+type backupReader struct {
+	io.Reader
+	file      *os.File
+	Encrypted bool
+}
+
+func (r *backupReader) Close() error {
+	return r.file.Close()
+}
+
+// openBackup opens the tgz file of a backup, with the key to decrypt it if it
+// is encrypted
+func openBackup(filename string, key dao.BackupKey) (*backupReader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	envelope, offset, err := readBackupEnvelope(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not read backup %s: %s", filename, err)
+	} else if envelope == nil {
+		if _, err := file.Seek(0, os.SEEK_SET); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &backupReader{Reader: file, file: file}, nil
+	}
+
+	r, err := newDecryptReader(file, envelope, offset, key)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not decrypt backup %s: %s", filename, err)
+	}
+	return &backupReader{Reader: r, file: file, Encrypted: true}, nil
+}
+
+// decryptReader decrypts the ciphertext of an encrypted backup, and checks
+// its mac at the end
+type decryptReader struct {
+	r      io.Reader
+	stream cipher.Stream
+	mac    hash.Hash
+	sum    []byte
+}
+
+func newDecryptReader(file *os.File, envelope *backupEnvelope, offset int64, key dao.BackupKey) (*decryptReader, error) {
+	keys, err := envelope.keys(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size() - offset - sha256.Size
+	if size < 0 {
+		return nil, errBackupModified
+	}
+	sum := make([]byte, sha256.Size)
+	if _, err := file.ReadAt(sum, offset+size); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(keys.cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	// the mac covers the header too
+	mac := hmac.New(sha256.New, keys.mac)
+	if _, err := file.Seek(0, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(mac, file, offset); err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:      io.LimitReader(file, size),
+		stream: cipher.NewCTR(block, envelope.IV),
+		mac:    mac,
+		sum:    sum,
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if n > 0 {
+		d.mac.Write(p[:n])
+		d.stream.XORKeyStream(p[:n], p[:n])
+	}
+	if err == io.EOF && !hmac.Equal(d.mac.Sum(nil), d.sum) {
+		return n, errBackupModified
+	}
+	return n, err
+}
+
+// decryptBackup writes the tgz file of an encrypted backup to dst
+func decryptBackup(src, dst string, key dao.BackupKey) (err error) {
+	in, err := openBackup(src, key)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := out.Close(); e != nil && err == nil {
+			err = e
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("could not decrypt backup %s: %s", src, err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeChecksummedBackup writes a backup with checksums of its files to dir
+func writeChecksummedBackup(t *testing.T, dir string) string {
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "images"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	manifestDir := filepath.Join(dir, "manifest")
+	if err := os.MkdirAll(manifestDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(src)
+	defer os.RemoveAll(manifestDir)
+
+	files := map[string]string{"templates.json": "{}", "services.json": "[]", "images/0.tar": "layers"}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write data file: %s", err)
+		}
+	}
+	sums, err := checksumFiles(src)
+	if err != nil {
+		t.Fatalf("Failed to checksum files: %s", err)
+	} else if len(sums) != len(files) || sums["images/0.tar"] == "" {
+		t.Fatalf("Unexpected checksums: %v", sums)
+	}
+
+	manifest := backupManifest{Name: "backup.tgz", Files: sums}
+	if err := writeJSONToFile(manifest, filepath.Join(manifestDir, backupManifestName)); err != nil {
+		t.Fatalf("Failed to write manifest: %s", err)
+	}
+	filename := filepath.Join(dir, manifest.Name)
+	if err := writeBackupToTgz(manifestDir, src, filename); err != nil {
+		t.Fatalf("Failed to write backup: %s", err)
+	}
+	return filename
+}
+
+func TestBackup_verifyBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-backup-verify")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := writeChecksummedBackup(t, dir)
+
+	v, err := verifyBackup(filename, dao.BackupKey{})
+	if err != nil {
+		t.Fatalf("Unexpected error verifying backup: %s", err)
+	} else if v.Encrypted || !v.Checksums || v.Files != 3 || len(v.Errors) != 0 {
+		t.Errorf("Unexpected verification: %+v", v)
+	}
+
+	// expanding checks the checksums too
+	dest := filepath.Join(dir, "restore")
+	if err := expandBackup(dest, filename, dao.BackupKey{}); err != nil {
+		t.Fatalf("Unexpected error expanding backup: %s", err)
+	}
+	if problems, err := checkFiles(dest, map[string]string{"templates.json": "0", "services.json": "0", "missing.json": "0"}); err != nil {
+		t.Fatalf("Unexpected error checking files: %s", err)
+	} else if expected := []string{
+		"unexpected file images/0.tar",
+		"checksum mismatch on services.json",
+		"checksum mismatch on templates.json",
+		"missing file missing.json",
+	}; !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Expected %v, got %v", expected, problems)
+	}
+
+	// a backup that was cut short fails to verify
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %s", err)
+	}
+	if err := ioutil.WriteFile(filename, data[:len(data)-10], 0644); err != nil {
+		t.Fatalf("Failed to write backup: %s", err)
+	}
+	if v, err := verifyBackup(filename, dao.BackupKey{}); err != nil {
+		t.Fatalf("Unexpected error verifying backup: %s", err)
+	} else if len(v.Errors) == 0 {
+		t.Errorf("Expected errors verifying a truncated backup")
+	}
+}
+
+func TestBackup_readBackupEnvelope(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-backup-envelope")
+	if err != nil {
+		t.Fatalf("Failed to create temporary file: %s", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// a header too long to be read into memory is refused
+	file.WriteString(backupEnvelopeMagic)
+	binary.Write(file, binary.BigEndian, uint32(1<<31))
+	if _, err := file.Seek(0, os.SEEK_SET); err != nil {
+		t.Fatalf("Failed to seek: %s", err)
+	}
+	if _, _, err := readBackupEnvelope(file); err == nil {
+		t.Errorf("Expected an error reading a header of %d bytes", 1<<31)
+	}
+}
+
+func TestBackup_encryptBackupWithPassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-backup-encrypt")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := writeChecksummedBackup(t, dir)
+	manifest, err := readBackupManifest(filename)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %s", err)
+	}
+
+	key := dao.BackupKey{Passphrase: "secret"}
+	envelope, keys, err := newBackupEnvelope(key)
+	if err != nil {
+		t.Fatalf("Unexpected error creating envelope: %s", err)
+	}
+	envelope.Manifest = manifest
+	encrypted := filename + ".enc"
+	if err := encryptBackup(filename, encrypted, envelope, keys); err != nil {
+		t.Fatalf("Unexpected error encrypting backup: %s", err)
+	}
+
+	// the manifest is readable without the key
+	if m, err := readBackupManifest(encrypted); err != nil {
+		t.Fatalf("Unexpected error reading manifest: %s", err)
+	} else if !reflect.DeepEqual(m, manifest) {
+		t.Errorf("Expected manifest %+v, got %+v", manifest, m)
+	}
+
+	if v, err := verifyBackup(encrypted, key); err != nil {
+		t.Fatalf("Unexpected error verifying backup: %s", err)
+	} else if !v.Encrypted || !v.Checksums || v.Files != 3 || len(v.Errors) != 0 {
+		t.Errorf("Unexpected verification: %+v", v)
+	}
+	if _, err := verifyBackup(encrypted, dao.BackupKey{}); err == nil {
+		t.Errorf("Expected an error verifying without the passphrase")
+	}
+	if _, err := verifyBackup(encrypted, dao.BackupKey{Passphrase: "wrong"}); err == nil {
+		t.Errorf("Expected an error verifying with the wrong passphrase")
+	}
+	if err := expandBackup(filepath.Join(dir, "restore"), encrypted, dao.BackupKey{}); err == nil {
+		t.Errorf("Expected an error expanding without the passphrase")
+	}
+	if err := expandBackup(filepath.Join(dir, "restore"), encrypted, key); err != nil {
+		t.Errorf("Unexpected error expanding backup: %s", err)
+	}
+
+	// a modified backup fails to verify
+	data, err := ioutil.ReadFile(encrypted)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %s", err)
+	}
+	data[len(data)-40] ^= 1
+	if err := ioutil.WriteFile(encrypted, data, 0644); err != nil {
+		t.Fatalf("Failed to write backup: %s", err)
+	}
+	if v, err := verifyBackup(encrypted, key); err != nil {
+		t.Fatalf("Unexpected error verifying backup: %s", err)
+	} else if len(v.Errors) == 0 {
+		t.Errorf("Expected errors verifying a modified backup")
+	}
+}
+
+func TestBackup_backupEnvelopeIterations(t *testing.T) {
+	key := dao.BackupKey{Passphrase: "secret"}
+	envelope, _, err := newBackupEnvelope(key)
+	if err != nil {
+		t.Fatalf("Unexpected error creating envelope: %s", err)
+	}
+	if _, err := envelope.keys(key); err != nil {
+		t.Fatalf("Unexpected error deriving keys: %s", err)
+	}
+
+	// the header cannot ask for unbounded work before the key is checked
+	for _, iterations := range []int{0, -1, maxBackupKDFIterations + 1, 1 << 30} {
+		envelope.Iterations = iterations
+		if _, err := envelope.keys(key); err == nil {
+			t.Errorf("Expected an error deriving keys with %d iterations", iterations)
+		}
+	}
+}
+
+func TestBackup_encryptBackupWithPublicKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-backup-encrypt")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := writeChecksummedBackup(t, dir)
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %s", err)
+	}
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}))
+
+	if _, _, err := newBackupEnvelope(dao.BackupKey{Passphrase: "secret", PublicKey: publicKey}); err == nil {
+		t.Errorf("Expected an error encrypting with both a passphrase and a public key")
+	}
+	envelope, keys, err := newBackupEnvelope(dao.BackupKey{PublicKey: publicKey})
+	if err != nil {
+		t.Fatalf("Unexpected error creating envelope: %s", err)
+	}
+	encrypted := filename + ".enc"
+	if err := encryptBackup(filename, encrypted, envelope, keys); err != nil {
+		t.Fatalf("Unexpected error encrypting backup: %s", err)
+	}
+
+	if _, err := verifyBackup(encrypted, dao.BackupKey{Passphrase: "secret"}); err == nil {
+		t.Errorf("Expected an error verifying without the private key")
+	}
+	decrypted := filepath.Join(dir, "decrypted.tgz")
+	if err := decryptBackup(encrypted, decrypted, dao.BackupKey{PrivateKey: privateKey}); err != nil {
+		t.Fatalf("Unexpected error decrypting backup: %s", err)
+	}
+	expected, _ := ioutil.ReadFile(filename)
+	if actual, _ := ioutil.ReadFile(decrypted); !bytes.Equal(actual, expected) {
+		t.Errorf("Decrypted backup does not match the original")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/role"
+
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// how long a progress or status call waits for a change
+var progressTimeout = 10 * time.Second
+
+// errCanceled is returned by a backup or restore that was canceled
+var errCanceled = errors.New("canceled")
+
+// progressTracker tracks the progress of a backup or restore. Only one of
+// each runs at a time.
+type progressTracker struct {
+	sync.Mutex
+	progress   dao.BackupProgress
+	cancel     chan struct{} // closed to cancel the operation in flight
+	cancelable bool          // false once the operation cannot be undone
+	changed    chan struct{} // closed and replaced on every change
+	statusSeq  int           // the last change reported by status
+}
+
+func newProgressTracker(operation string) *progressTracker {
+	return &progressTracker{
+		progress: dao.BackupProgress{Operation: operation},
+		changed:  make(chan struct{}),
+	}
+}
+
+// start tracks a new operation, unless one is in flight
+func (t *progressTracker) start(file string) error {
+	t.Lock()
+	defer t.Unlock()
+
+	operation := t.progress.Operation
+	if t.progress.Running {
+		return fmt.Errorf("Another %s is currently in progress", operation)
+	}
+	t.progress = dao.BackupProgress{
+		Operation: operation,
+		Sequence:  t.progress.Sequence,
+		File:      file,
+		Message:   "Starting " + operation,
+		Started:   time.Now().UTC(),
+		Running:   true,
+	}
+	t.cancel = make(chan struct{})
+	t.cancelable = true
+	t.notify()
+	return nil
+}
+
+// notify publishes a change. The caller holds the lock.
+func (t *progressTracker) notify() {
+	p := &t.progress
+	p.Sequence++
+	p.ETA = time.Time{}
+	if p.Running && p.StepsDone > 0 && p.Steps > p.StepsDone {
+		elapsed := time.Since(p.Started)
+		p.ETA = p.Started.Add(elapsed * time.Duration(p.Steps) / time.Duration(p.StepsDone))
+	}
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// update changes the progress of the operation in flight
+func (t *progressTracker) update(f func(p *dao.BackupProgress)) {
+	t.Lock()
+	defer t.Unlock()
+	f(&t.progress)
+	t.notify()
+}
+
+// step starts a step of a phase of the operation in flight
+func (t *progressTracker) step(phase, message string) {
+	t.update(func(p *dao.BackupProgress) {
+		p.Phase = phase
+		p.Message = message
+		p.Image = ""
+	})
+}
+
+// finish records the outcome of the operation in flight
+func (t *progressTracker) finish(err error) {
+	t.update(func(p *dao.BackupProgress) {
+		p.Running = false
+		p.Image = ""
+		if err == errCanceled {
+			p.Canceled = true
+			p.Message = "Canceled " + p.Operation
+		} else if err != nil {
+			p.Err = err.Error()
+		} else {
+			p.Message = "Finished " + p.Operation
+			p.StepsDone = p.Steps
+		}
+	})
+}
+
+// checkpoint returns errCanceled if the operation in flight was canceled
+func (t *progressTracker) checkpoint() error {
+	t.Lock()
+	defer t.Unlock()
+	select {
+	case <-t.cancel:
+		return errCanceled
+	default:
+		return nil
+	}
+}
+
+// commit marks the point after which the operation in flight can no longer be
+// canceled, unless it was canceled already
+func (t *progressTracker) commit() error {
+	t.Lock()
+	defer t.Unlock()
+	select {
+	case <-t.cancel:
+		return errCanceled
+	default:
+		t.cancelable = false
+		return nil
+	}
+}
+
+// requestCancel asks the operation in flight to stop at its next checkpoint
+func (t *progressTracker) requestCancel() error {
+	t.Lock()
+	defer t.Unlock()
+	if !t.progress.Running {
+		return fmt.Errorf("no %s in progress", t.progress.Operation)
+	} else if !t.cancelable {
+		return fmt.Errorf("the %s can no longer be canceled", t.progress.Operation)
+	}
+	select {
+	case <-t.cancel:
+	default:
+		close(t.cancel)
+		t.progress.Message = "Canceling " + t.progress.Operation
+		t.notify()
+	}
+	return nil
+}
+
+// wait returns the progress once it changed since the sequence number, or
+// once the timeout passes
+func (t *progressTracker) wait(since int, timeout time.Duration) dao.BackupProgress {
+	t.Lock()
+	if t.progress.Sequence > since {
+		defer t.Unlock()
+		return t.progress
+	}
+	changed := t.changed
+	t.Unlock()
+
+	select {
+	case <-changed:
+	case <-time.After(timeout):
+	}
+	t.Lock()
+	defer t.Unlock()
+	return t.progress
+}
+
+// status reports the changes of the progress one at a time, as BackupStatus
+// and RestoreStatus always have: the message of the next change, "timeout" if
+// there is none, an empty message once the operation is done, or the error
+// the operation failed with
+func (t *progressTracker) status(timeout time.Duration) (string, error) {
+	t.Lock()
+	since := t.statusSeq
+	t.Unlock()
+
+	p := t.wait(since, timeout)
+	if p.Sequence == since {
+		return "timeout", nil
+	}
+
+	t.Lock()
+	t.statusSeq = p.Sequence
+	t.Unlock()
+	switch {
+	case p.Running:
+		return p.Message, nil
+	case p.Err != "":
+		return p.Err, errors.New(p.Err)
+	case p.Canceled:
+		return p.Message, errCanceled
+	}
+	return "", nil
+}
+
+// resetStatus makes status report only the changes from now on, such as the
+// ones of an operation about to start
+func (t *progressTracker) resetStatus() {
+	t.Lock()
+	defer t.Unlock()
+	t.statusSeq = t.progress.Sequence
+}
+
+// pathSize returns the size of a file, or of the files below a directory
+func pathSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// BackupProgress gets the progress of the backup in flight, or of the last
+// one, once it has changed since the sequence number or a timeout passes
+func (this *ControlPlaneDao) BackupProgress(since int, progress *dao.BackupProgress) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	*progress = this.backupProgress.wait(since, progressTimeout)
+	return nil
+}
+
+// RestoreProgress gets the progress of the restore in flight, or of the last
+// one, once it has changed since the sequence number or a timeout passes
+func (this *ControlPlaneDao) RestoreProgress(since int, progress *dao.BackupProgress) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	*progress = this.restoreProgress.wait(since, progressTimeout)
+	return nil
+}
+
+// CancelBackup cancels the backup in flight. The backup stops once the step
+// in flight, such as saving an image, is done; its partial files are removed.
+func (this *ControlPlaneDao) CancelBackup(notUsed string, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	return this.backupProgress.requestCancel()
+}
+
+// CancelRestore cancels the restore in flight, unless it has started restoring
+// templates and services. The restore stops once the step in flight, such as
+// loading an image, is done; its expanded files are removed, while the images
+// it loaded are kept.
+func (this *ControlPlaneDao) CancelRestore(notUsed string, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	return this.restoreProgress.requestCancel()
+}
@@ -0,0 +1,107 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"github.com/control-center/serviced/dao"
+
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackup_progressTracker(t *testing.T) {
+	tracker := newProgressTracker("backup")
+	if err := tracker.requestCancel(); err == nil {
+		t.Errorf("Expected an error canceling without a backup in progress")
+	}
+
+	if err := tracker.start("backup.tgz"); err != nil {
+		t.Fatalf("Unexpected error starting: %s", err)
+	}
+	if err := tracker.start("other.tgz"); err == nil {
+		t.Errorf("Expected an error starting a second backup")
+	}
+
+	p := tracker.wait(0, time.Second)
+	if !p.Running || p.File != "backup.tgz" || p.Sequence != 1 {
+		t.Errorf("Unexpected progress after start: %+v", p)
+	}
+
+	// wait returns once the progress changes
+	go tracker.update(func(p *dao.BackupProgress) {
+		p.Steps = 4
+		p.StepsDone = 1
+	})
+	p = tracker.wait(1, time.Second)
+	if p.Sequence != 2 || p.StepsDone != 1 || p.ETA.IsZero() {
+		t.Errorf("Unexpected progress after update: %+v", p)
+	}
+	if q := tracker.wait(p.Sequence, 10*time.Millisecond); q.Sequence != p.Sequence {
+		t.Errorf("Expected no change, got %+v", q)
+	}
+
+	if err := tracker.checkpoint(); err != nil {
+		t.Errorf("Unexpected error at checkpoint: %s", err)
+	}
+	if err := tracker.requestCancel(); err != nil {
+		t.Errorf("Unexpected error canceling: %s", err)
+	}
+	if err := tracker.requestCancel(); err != nil {
+		t.Errorf("Unexpected error canceling twice: %s", err)
+	}
+	if err := tracker.checkpoint(); err != errCanceled {
+		t.Errorf("Expected %s at checkpoint, got %v", errCanceled, err)
+	}
+	tracker.finish(errCanceled)
+	p = tracker.wait(0, time.Second)
+	if p.Running || !p.Canceled || p.Err != "" {
+		t.Errorf("Unexpected progress after cancel: %+v", p)
+	}
+
+	// a committed operation can no longer be canceled
+	if err := tracker.start("backup.tgz"); err != nil {
+		t.Fatalf("Unexpected error restarting: %s", err)
+	}
+	if err := tracker.commit(); err != nil {
+		t.Errorf("Unexpected error committing: %s", err)
+	}
+	if err := tracker.requestCancel(); err == nil {
+		t.Errorf("Expected an error canceling after commit")
+	}
+	tracker.finish(errors.New("failed"))
+	p = tracker.wait(0, time.Second)
+	if p.Running || p.Canceled || p.Err != "failed" {
+		t.Errorf("Unexpected progress after failure: %+v", p)
+	}
+}
+
+func TestBackup_progressTrackerStatus(t *testing.T) {
+	tracker := newProgressTracker("restore")
+	if status, err := tracker.status(10 * time.Millisecond); status != "timeout" || err != nil {
+		t.Errorf("Expected timeout, got %q, %v", status, err)
+	}
+
+	tracker.start("backup.tgz")
+	tracker.step("images", "Restoring Docker image: abc")
+	if status, err := tracker.status(time.Second); status != "Restoring Docker image: abc" || err != nil {
+		t.Errorf("Unexpected status %q, %v", status, err)
+	}
+	if status, err := tracker.status(10 * time.Millisecond); status != "timeout" || err != nil {
+		t.Errorf("Expected timeout, got %q, %v", status, err)
+	}
+
+	tracker.finish(nil)
+	if status, err := tracker.status(time.Second); status != "" || err != nil {
+		t.Errorf("Expected an empty status when done, got %q, %v", status, err)
+	}
+
+	tracker.resetStatus()
+	tracker.start("backup.tgz")
+	tracker.finish(errors.New("failed"))
+	if status, err := tracker.status(time.Second); status != "failed" || err == nil {
+		t.Errorf("Expected the error, got %q, %v", status, err)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/role"
+
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checksumFile returns the SHA-256 of a file, hex encoded
+func checksumFile(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumFiles returns the SHA-256 of each file below a directory, by its
+// path relative to the directory
+func checksumFiles(dir string) (map[string]string, error) {
+	sums := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if sums[filepath.ToSlash(rel)], err = checksumFile(p); err != nil {
+			return err
+		}
+		return nil
+	})
+	return sums, err
+}
+
+// checkFiles compares the files below a directory with their checksums, and
+// returns what does not match
+func checkFiles(dir string, sums map[string]string) ([]string, error) {
+	var problems []string
+	seen := make(map[string]bool)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == backupManifestName {
+			return nil
+		}
+		seen[name] = true
+		if expected, ok := sums[name]; !ok {
+			problems = append(problems, fmt.Sprintf("unexpected file %s", name))
+		} else if sum, err := checksumFile(p); err != nil {
+			return err
+		} else if sum != expected {
+			problems = append(problems, fmt.Sprintf("checksum mismatch on %s", name))
+		}
+		return nil
+	})
+	return append(problems, missingFiles(sums, seen)...), err
+}
+
+// missingFiles returns the files with checksums that were not seen
+func missingFiles(sums map[string]string, seen map[string]bool) []string {
+	var missing []string
+	for name := range sums {
+		if !seen[name] {
+			missing = append(missing, fmt.Sprintf("missing file %s", name))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// isEncryptedBackup is true if a backup is encrypted
+func isEncryptedBackup(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	envelope, _, err := readBackupEnvelope(file)
+	return envelope != nil, err
+}
+
+// expandBackup expands a backup to dest, decrypting it first if it is
+// encrypted, and checks the checksums of its files
+func expandBackup(dest, filename string, key dao.BackupKey) error {
+	encrypted, err := isEncryptedBackup(filename)
+	if err != nil {
+		return err
+	}
+
+	tgzPath := filename
+	if encrypted {
+		if backupKeyIsEmpty(key) {
+			return fmt.Errorf("backup %s is encrypted; its passphrase or private key is needed", filepath.Base(filename))
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), os.ModeDir|0755); err != nil {
+			return err
+		}
+		tgzPath = dest + ".tgz"
+		if err := decryptBackup(filename, tgzPath, key); err != nil {
+			return err
+		}
+		defer os.Remove(tgzPath)
+	}
+	if err := writeDirectoryFromTgz(dest, tgzPath); err != nil {
+		return err
+	}
+
+	var manifest backupManifest
+	if _, err := os.Stat(filepath.Join(dest, backupManifestName)); os.IsNotExist(err) {
+		return nil // backups taken before there were manifests have no checksums
+	} else if err := readJSONFromFile(&manifest, filepath.Join(dest, backupManifestName)); err != nil {
+		return err
+	} else if manifest.Files == nil {
+		return nil
+	}
+	problems, err := checkFiles(dest, manifest.Files)
+	if err != nil {
+		return err
+	} else if len(problems) > 0 {
+		glog.Errorf("Backup %s is corrupt: %s", filename, strings.Join(problems, "; "))
+		return fmt.Errorf("backup %s is corrupt: %s", filepath.Base(filename), problems[0])
+	}
+	return nil
+}
+
+// verifyBackup reads a backup to its end, checking the checksums of its files
+// and, if it is encrypted, its mac
+func verifyBackup(filename string, key dao.BackupKey) (*dao.BackupVerification, error) {
+	r, err := openBackup(filename, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	verification := &dao.BackupVerification{FilePath: filename, Encrypted: r.Encrypted}
+	problem := func(format string, args ...interface{}) *dao.BackupVerification {
+		verification.Errors = append(verification.Errors, fmt.Sprintf(format, args...))
+		return verification
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return problem("could not read the backup: %s", err), nil
+	}
+	defer gz.Close()
+
+	var manifest *backupManifest
+	seen := make(map[string]bool)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return problem("could not read the backup: %s", err), nil
+		}
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		} else if name == backupManifestName && manifest == nil {
+			manifest = &backupManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return problem("could not read the manifest: %s", err), nil
+			}
+			continue
+		}
+
+		// the files of backups without checksums are read all the same, to
+		// check their compression
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return problem("could not read %s: %s", name, err), nil
+		} else if manifest == nil || manifest.Files == nil {
+			continue
+		}
+		verification.Files++
+		seen[name] = true
+		if expected, ok := manifest.Files[name]; !ok {
+			problem("unexpected file %s", name)
+		} else if hex.EncodeToString(h.Sum(nil)) != expected {
+			problem("checksum mismatch on %s", name)
+		}
+	}
+
+	// what is left of the compressed and of the encrypted backup is checked too
+	if _, err := io.Copy(ioutil.Discard, gz); err != nil {
+		return problem("could not read the backup: %s", err), nil
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return problem("could not read the backup: %s", err), nil
+	}
+
+	if manifest != nil && manifest.Files != nil {
+		verification.Checksums = true
+		verification.Errors = append(verification.Errors, missingFiles(manifest.Files, seen)...)
+	}
+	return verification, nil
+}
+
+// VerifyBackup checks that a backup can be read to its end, and that its
+// files match their checksums, without restoring it. An encrypted backup is
+// checked with its passphrase or private key.
+func (cp *ControlPlaneDao) VerifyBackup(request dao.RestoreRequest, verification *dao.BackupVerification) error {
+	if err := cp.authorize(role.Request{Action: role.Read, Resource: role.ResourceBackup}); err != nil {
+		return err
+	}
+	v, err := verifyBackup(request.FilePath, request.Key)
+	if err != nil {
+		glog.Errorf("Could not verify backup %s: %v", request.FilePath, err)
+		return err
+	}
+	*verification = *v
+	return nil
+}
@@ -12,13 +12,14 @@ package elasticsearch
 import (
 	"fmt"
 	"strconv"
-	"sync"
+	"strings"
 
 	"github.com/zenoss/elastigo/api"
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/dfs"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/facade"
 	"github.com/control-center/serviced/zzk"
@@ -33,15 +34,53 @@ const (
 var _ dao.ControlPlane = &ControlPlaneDao{}
 
 type ControlPlaneDao struct {
-	hostName       string
-	port           int
-	varpath        string
-	vfs            string
-	dfs            *dfs.DistributedFileSystem
-	facade         *facade.Facade
-	dockerRegistry string
-	backupLock     sync.RWMutex
-	restoreLock    sync.RWMutex
+	hostName        string
+	port            int
+	varpath         string
+	vfs             string
+	dfs             *dfs.DistributedFileSystem
+	facade          *facade.Facade
+	dockerRegistry  string
+	backupProgress  *progressTracker
+	restoreProgress *progressTracker
+	ctx             datastore.Context // the caller's context, if known
+}
+
+// WithCaller returns a copy of the dao that attributes the changes made
+// through it to the caller
+func WithCaller(cp *ControlPlaneDao, caller datastore.Caller) *ControlPlaneDao {
+	c := *cp
+	c.ctx = datastore.WithCaller(datastore.Get(), caller)
+	return &c
+}
+
+// context returns the datastore context of the requests made through the dao.
+// Requests made through a dao without a caller are made by the master itself.
+func (this *ControlPlaneDao) context() datastore.Context {
+	if this.ctx != nil {
+		return this.ctx
+	}
+	return datastore.WithCaller(datastore.Get(), datastore.Caller{Source: datastore.SourceRPC, Admin: true})
+}
+
+// authorize checks that the caller of the dao is allowed to make the request
+func (this *ControlPlaneDao) authorize(request role.Request) error {
+	return this.facade.Authorize(this.context(), request)
+}
+
+// trusted returns a copy of the dao for the work of a request that has been
+// authorized, so that the requests it makes on the way are not checked again.
+// The changes are still attributed to the caller.
+func (this *ControlPlaneDao) trusted() *ControlPlaneDao {
+	caller := datastore.CallerOf(this.context())
+	caller.Admin = true
+	return WithCaller(this, caller)
+}
+
+// snapshotTenant returns the tenant that a snapshot was taken of, whose id
+// prefixes the id of the snapshot
+func snapshotTenant(snapshotID string) string {
+	return strings.SplitN(snapshotID, "_", 2)[0]
 }
 
 func serviceGetter(ctx datastore.Context, f *facade.Facade) service.GetService {
@@ -65,7 +104,10 @@ func childFinder(ctx datastore.Context, f *facade.Facade) service.FindChildServi
 }
 
 func (this *ControlPlaneDao) Action(request dao.AttachRequest, unused *int) error {
-	ctx := datastore.Get()
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: request.Running.ServiceID}); err != nil {
+		return err
+	}
+	ctx := this.context()
 	svc, err := this.facade.GetService(ctx, request.Running.ServiceID)
 	if err != nil {
 		return err
@@ -101,10 +143,6 @@ func (this *ControlPlaneDao) Action(request dao.AttachRequest, unused *int) erro
 	return err
 }
 
-func (this *ControlPlaneDao) RestartService(serviceID string, unused *int) error {
-	return dao.ControlPlaneError{Msg: "unimplemented"}
-}
-
 // Create a elastic search control plane data access object
 func NewControlPlaneDao(hostName string, port int, facade *facade.Facade) (*ControlPlaneDao, error) {
 	glog.V(0).Infof("Opening ElasticSearch ControlPlane Dao: hostName=%s, port=%d", hostName, port)
@@ -112,8 +150,10 @@ func NewControlPlaneDao(hostName string, port int, facade *facade.Facade) (*Cont
 	api.Port = strconv.Itoa(port)
 
 	dao := &ControlPlaneDao{
-		hostName: hostName,
-		port:     port,
+		hostName:        hostName,
+		port:            port,
+		backupProgress:  newProgressTracker("backup"),
+		restoreProgress: newProgressTracker("restore"),
 	}
 	if dfs, err := dfs.NewDistributedFileSystem(dao, facade); err != nil {
 		return nil, err
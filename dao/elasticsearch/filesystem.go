@@ -6,13 +6,15 @@ package elasticsearch
 
 import (
 	"github.com/zenoss/glog"
-	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/node"
 	"github.com/control-center/serviced/volume"
 	"github.com/control-center/serviced/zzk"
 	zkSnapshot "github.com/control-center/serviced/zzk/snapshot"
 
 	"errors"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"path"
@@ -20,12 +22,22 @@ import (
 )
 
 func (this *ControlPlaneDao) DeleteSnapshot(snapshotId string, unused *int) error {
-	return this.dfs.DeleteSnapshot(snapshotId)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceSnapshot, ServiceID: snapshotTenant(snapshotId)}); err != nil {
+		return err
+	}
+	if err := this.dfs.DeleteSnapshot(snapshotId); err != nil {
+		return err
+	}
+	this.facade.Audit(this.context(), "remove", "snapshot", snapshotId, nil, nil)
+	return nil
 }
 
 func (this *ControlPlaneDao) DeleteSnapshots(serviceId string, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceSnapshot, ServiceID: serviceId}); err != nil {
+		return err
+	}
 	var tenantId string
-	if err := this.GetTenantId(serviceId, &tenantId); err != nil {
+	if err := this.trusted().GetTenantId(serviceId, &tenantId); err != nil {
 		glog.V(2).Infof("ControlPlaneDao.DeleteSnapshots err=%s", err)
 		return err
 	}
@@ -35,18 +47,32 @@ func (this *ControlPlaneDao) DeleteSnapshots(serviceId string, unused *int) erro
 		return nil
 	}
 
-	return this.dfs.DeleteSnapshots(tenantId)
+	if err := this.dfs.DeleteSnapshots(tenantId); err != nil {
+		return err
+	}
+	this.facade.Audit(this.context(), "remove-snapshots", "service", tenantId, nil, nil)
+	return nil
 }
 
 func (this *ControlPlaneDao) Rollback(snapshotId string, unused *int) error {
-	return this.dfs.Rollback(snapshotId)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceSnapshot, ServiceID: snapshotTenant(snapshotId)}); err != nil {
+		return err
+	}
+	if err := this.dfs.Rollback(snapshotId); err != nil {
+		return err
+	}
+	this.facade.Audit(this.context(), "rollback", "snapshot", snapshotId, nil, nil)
+	return nil
 }
 
 // Takes a snapshot of the DFS via the host
 func (this *ControlPlaneDao) TakeSnapshot(serviceID string, label *string) error {
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceSnapshot, ServiceID: serviceID}); err != nil {
+		return err
+	}
 	var tenantID string
 	var err error
-	if err = this.GetTenantId(serviceID, &tenantID); err != nil {
+	if err = this.trusted().GetTenantId(serviceID, &tenantID); err != nil {
 		glog.V(2).Infof("ControlPlaneDao.DeleteSnapshots err=%s", err)
 		return err
 	}
@@ -56,7 +82,10 @@ func (this *ControlPlaneDao) TakeSnapshot(serviceID string, label *string) error
 
 // Snapshot is called via RPC by the CLI to take a snapshot for a serviceId
 func (this *ControlPlaneDao) Snapshot(serviceID string, label *string) error {
-	myService, err := this.facade.GetService(datastore.Get(), serviceID)
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceSnapshot, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	myService, err := this.facade.GetService(this.context(), serviceID)
 	if err != nil {
 		glog.Errorf("Unable to get service %v: %v", serviceID, err)
 		return err
@@ -83,18 +112,22 @@ func (this *ControlPlaneDao) Snapshot(serviceID string, label *string) error {
 	if snapshot.Err != "" {
 		return errors.New(snapshot.Err)
 	}
+	this.facade.Audit(this.context(), "add", "snapshot", snapshot.Label, nil, struct{ ServiceID string }{serviceID})
 	return nil
 }
 
 func (this *ControlPlaneDao) Snapshots(serviceId string, labels *[]string) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceSnapshot, ServiceID: serviceId}); err != nil {
+		return err
+	}
 
 	var tenantId string
-	if err := this.GetTenantId(serviceId, &tenantId); err != nil {
+	if err := this.trusted().GetTenantId(serviceId, &tenantId); err != nil {
 		glog.V(2).Infof("ControlPlaneDao.Snapshots service=%+v err=%s", serviceId, err)
 		return err
 	}
 	var service service.Service
-	err := this.GetService(tenantId, &service)
+	err := this.trusted().GetService(tenantId, &service)
 	if err != nil {
 		glog.V(2).Infof("ControlPlaneDao.Snapshots service=%+v err=%s", serviceId, err)
 		return err
@@ -114,14 +147,17 @@ func (this *ControlPlaneDao) Snapshots(serviceId string, labels *[]string) error
 	return nil
 }
 func (this *ControlPlaneDao) GetVolume(serviceId string, theVolume *volume.Volume) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceId}); err != nil {
+		return err
+	}
 	var tenantId string
-	if err := this.GetTenantId(serviceId, &tenantId); err != nil {
+	if err := this.trusted().GetTenantId(serviceId, &tenantId); err != nil {
 		glog.V(2).Infof("ControlPlaneDao.GetVolume service=%+v err=%s", serviceId, err)
 		return err
 	}
 	glog.V(3).Infof("ControlPlaneDao.GetVolume service=%+v tenantId=%s", serviceId, tenantId)
 	var service service.Service
-	if err := this.GetService(tenantId, &service); err != nil {
+	if err := this.trusted().GetService(tenantId, &service); err != nil {
 		glog.V(2).Infof("ControlPlaneDao.GetVolume service=%+v err=%s", serviceId, err)
 		return err
 	}
@@ -144,6 +180,9 @@ func (this *ControlPlaneDao) GetVolume(serviceId string, theVolume *volume.Volum
 
 // Commits a container to an image and saves it on the DFS
 func (this *ControlPlaneDao) Commit(containerId string, label *string) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService}); err != nil {
+		return err
+	}
 	if id, err := this.dfs.Commit(containerId); err != nil {
 		glog.V(2).Infof("ControlPlaneDao.GetVolume containerId=%s err=%s", containerId, err)
 		return err
@@ -163,6 +202,45 @@ func getSubvolume(vfs, poolId, tenantId string) (*volume.Volume, error) {
 	return volume.Mount(vfs, tenantId, baseDir)
 }
 
+// addSnapshot adds a snapshot with the data of a tgz file to a volume. The
+// snapshot is taken by the driver of the volume after the data replaces the
+// data of the volume; the volume is rolled back to the data it had if that
+// fails.
+func addSnapshot(vol *volume.Volume, label, filename string) (err error) {
+	saved := node.GetLabel(vol.Name())
+	if e := vol.Snapshot(saved); e != nil {
+		glog.Errorf("Could not save the data of volume %s: %v", vol.Name(), e)
+		return e
+	}
+	defer func() {
+		if err != nil {
+			if e := vol.Rollback(saved); e != nil {
+				glog.Errorf("Could not roll volume %s back to %s: %v", vol.Name(), saved, e)
+			}
+		}
+		if e := vol.RemoveSnapshot(saved); e != nil {
+			glog.Errorf("Could not remove snapshot %s: %v", saved, e)
+			if err == nil {
+				err = e
+			}
+		}
+	}()
+
+	files, e := ioutil.ReadDir(vol.Path())
+	if e != nil {
+		return e
+	}
+	for _, file := range files {
+		if e := os.RemoveAll(filepath.Join(vol.Path(), file.Name())); e != nil {
+			return e
+		}
+	}
+	if e := writeDirectoryFromTgz(vol.Path(), filename); e != nil {
+		return e
+	}
+	return vol.Snapshot(label)
+}
+
 func varPath() string {
 	if len(os.Getenv("SERVICED_HOME")) > 0 {
 		return path.Join(os.Getenv("SERVICED_HOME"), "var")
@@ -176,6 +254,9 @@ func varPath() string {
 }
 
 func (s *ControlPlaneDao) ReadyDFS(unused bool, unusedint *int) (err error) {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceService}); err != nil {
+		return err
+	}
 	s.dfs.Lock()
 	s.dfs.Unlock()
 	return
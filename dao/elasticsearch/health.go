@@ -5,11 +5,113 @@
 package elasticsearch
 
 import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain"
-	"github.com/control-center/serviced/health"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/zzk"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+
+	"time"
 )
 
+// LogHealthCheck records the result of a health check of a service instance
+// in the coordinator, where every master sees it
 func (this *ControlPlaneDao) LogHealthCheck(result domain.HealthCheckResult, unused *int) error {
-	health.RegisterHealthCheck(result.ServiceID, result.Name, result.Passed, this)
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: result.ServiceID}); err != nil {
+		return err
+	}
+	svc, err := this.facade.GetService(this.context(), result.ServiceID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", result.ServiceID, err)
+		return err
+	}
+	check, ok := svc.HealthChecks[result.Name]
+	if !ok {
+		glog.Warningf("ignoring health status, not found in service: %s %s %s", result.ServiceID, result.Name, result.Passed)
+		return nil
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(svc.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", svc.PoolID, err)
+		return err
+	}
+
+	status := zkhealth.Status{
+		ServiceID:  result.ServiceID,
+		InstanceID: result.InstanceID,
+		Name:       result.Name,
+		Status:     result.Passed,
+		Timestamp:  time.Now().UTC(),
+		Interval:   check.Interval,
+	}
+	previous, err := zkhealth.Register(poolBasedConn, status)
+	if err != nil {
+		glog.Errorf("Unable to register health check %s of service %v instance %d: %v", result.Name, result.ServiceID, result.InstanceID, err)
+		return err
+	}
+
+	failing := previous == zkhealth.StatusFailed || previous == zkhealth.StatusMissed
+	if (result.Passed == zkhealth.StatusFailed && previous != zkhealth.StatusFailed) || (result.Passed == zkhealth.StatusPassed && failing) {
+		this.facade.NotifyHealthCheck(this.context(), svc, result.InstanceID, result.Name, result.Passed)
+	}
+	return nil
+}
+
+// GetServiceHealth gets the status of the health checks of each instance of a
+// service
+func (this *ControlPlaneDao) GetServiceHealth(serviceID string, statuses *[]*zkhealth.Status) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	myService, err := this.facade.GetService(this.context(), serviceID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", serviceID, err)
+		return err
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(myService.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", myService.PoolID, err)
+		return err
+	}
+
+	result, err := zkhealth.Get(poolBasedConn, serviceID)
+	if err != nil {
+		glog.Errorf("Unable to get the health of service %v: %v", serviceID, err)
+		return err
+	}
+	*statuses = result
+	return nil
+}
+
+// GetHealthStatuses gets the status of the health checks of every service
+// instance in every pool
+func (this *ControlPlaneDao) GetHealthStatuses(request dao.EntityRequest, statuses *[]*zkhealth.Status) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService}); err != nil {
+		return err
+	}
+	allPools, err := this.facade.GetResourcePools(this.context())
+	if err != nil {
+		glog.Errorf("Unable to get resource pools: %v", err)
+		return err
+	}
+
+	*statuses = []*zkhealth.Status{}
+	for _, aPool := range allPools {
+		poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(aPool.ID))
+		if err != nil {
+			glog.Errorf("Error in getting a connection based on pool %v: %v", aPool.ID, err)
+			return err
+		}
+
+		result, err := zkhealth.GetAll(poolBasedConn)
+		if err != nil {
+			glog.Errorf("Unable to get the health of the services in pool %v: %v", aPool.ID, err)
+			return err
+		}
+		*statuses = append(*statuses, result...)
+	}
 	return nil
 }
@@ -7,11 +7,15 @@ package elasticsearch
 import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/rpc/agent"
 )
 
 func (this *ControlPlaneDao) GetServiceLogs(serviceID string, logs *string) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
 	glog.V(3).Info("ControlPlaneDao.GetServiceLogs serviceID=", serviceID)
 	var serviceStates []*servicestate.ServiceState
 	if err := this.GetServiceStates(serviceID, &serviceStates); err != nil {
@@ -43,6 +47,9 @@ func (this *ControlPlaneDao) GetServiceLogs(serviceID string, logs *string) erro
 }
 
 func (this *ControlPlaneDao) GetServiceStateLogs(request dao.ServiceStateRequest, logs *string) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: request.ServiceID}); err != nil {
+		return err
+	}
 	var serviceState servicestate.ServiceState
 	if err := this.GetServiceState(request, &serviceState); err != nil {
 		glog.Errorf("ControlPlaneDao.GetServiceStateLogs servicestate=%+v err=%s", serviceState, err)
@@ -7,7 +7,7 @@ package elasticsearch
 import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/dao"
-	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/zzk"
 	zkservice "github.com/control-center/serviced/zzk/service"
@@ -16,7 +16,10 @@ import (
 )
 
 func (this *ControlPlaneDao) GetRunningServices(request dao.EntityRequest, allRunningServices *[]*dao.RunningService) error {
-	allPools, err := this.facade.GetResourcePools(datastore.Get())
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService}); err != nil {
+		return err
+	}
+	allPools, err := this.facade.GetResourcePools(this.context())
 	if err != nil {
 		glog.Error("runningservice.go failed to get resource pool")
 		return err
@@ -45,7 +48,10 @@ func (this *ControlPlaneDao) GetRunningServices(request dao.EntityRequest, allRu
 }
 
 func (this *ControlPlaneDao) GetRunningServicesForHost(hostID string, services *[]*dao.RunningService) error {
-	myHost, err := this.facade.GetHost(datastore.Get(), hostID)
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceHost, HostID: hostID}); err != nil {
+		return err
+	}
+	myHost, err := this.facade.GetHost(this.context(), hostID)
 	if err != nil {
 		glog.Errorf("Unable to get host %v: %v", hostID, err)
 		return err
@@ -69,7 +75,10 @@ func (this *ControlPlaneDao) GetRunningServicesForHost(hostID string, services *
 }
 
 func (this *ControlPlaneDao) GetRunningServicesForService(serviceID string, services *[]*dao.RunningService) error {
-	myService, err := this.facade.GetService(datastore.Get(), serviceID)
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	myService, err := this.facade.GetService(this.context(), serviceID)
 	if err != nil {
 		glog.Errorf("Unable to get service %v: %v", serviceID, err)
 		return err
@@ -91,6 +100,9 @@ func (this *ControlPlaneDao) GetRunningServicesForService(serviceID string, serv
 }
 
 func (this *ControlPlaneDao) GetRunningService(request dao.ServiceStateRequest, running *dao.RunningService) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: request.ServiceID}); err != nil {
+		return err
+	}
 	glog.V(3).Infof("ControlPlaneDao.GetRunningService: request=%v", request)
 
 	var myService service.Service
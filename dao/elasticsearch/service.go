@@ -6,13 +6,16 @@ package elasticsearch
 
 import (
 	"github.com/control-center/serviced/dao"
-	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 )
 
 // AddService add a service. Return error if service already exists
 func (this *ControlPlaneDao) AddService(svc service.Service, serviceId *string) error {
-	if err := this.facade.AddService(datastore.Get(), svc); err != nil {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: svc.ParentServiceID, PoolID: svc.PoolID}); err != nil {
+		return err
+	}
+	if err := this.facade.AddService(this.context(), svc); err != nil {
 		return err
 	}
 	*serviceId = svc.ID
@@ -21,7 +24,10 @@ func (this *ControlPlaneDao) AddService(svc service.Service, serviceId *string)
 
 //
 func (this *ControlPlaneDao) UpdateService(svc service.Service, unused *int) error {
-	if err := this.facade.UpdateService(datastore.Get(), svc); err != nil {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: svc.ID}); err != nil {
+		return err
+	}
+	if err := this.facade.UpdateService(this.context(), svc); err != nil {
 		return err
 	}
 	return nil
@@ -29,7 +35,10 @@ func (this *ControlPlaneDao) UpdateService(svc service.Service, unused *int) err
 
 //
 func (this *ControlPlaneDao) RemoveService(id string, unused *int) error {
-	if err := this.facade.RemoveService(datastore.Get(), id); err != nil {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: id}); err != nil {
+		return err
+	}
+	if err := this.facade.RemoveService(this.context(), id); err != nil {
 		return err
 	}
 	return nil
@@ -37,7 +46,10 @@ func (this *ControlPlaneDao) RemoveService(id string, unused *int) error {
 
 //
 func (this *ControlPlaneDao) GetService(id string, myService *service.Service) error {
-	if svc, err := this.facade.GetService(datastore.Get(), id); err == nil {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: id}); err != nil {
+		return err
+	}
+	if svc, err := this.facade.GetService(this.context(), id); err == nil {
 		*myService = *svc
 		return nil
 	} else {
@@ -47,7 +59,10 @@ func (this *ControlPlaneDao) GetService(id string, myService *service.Service) e
 
 //
 func (this *ControlPlaneDao) GetServices(request dao.EntityRequest, services *[]*service.Service) error {
-	if svcs, err := this.facade.GetServices(datastore.Get(), request); err == nil {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService}); err != nil {
+		return err
+	}
+	if svcs, err := this.facade.GetServices(this.context(), request); err == nil {
 		*services = svcs
 		return nil
 	} else {
@@ -57,7 +72,10 @@ func (this *ControlPlaneDao) GetServices(request dao.EntityRequest, services *[]
 
 //
 func (this *ControlPlaneDao) FindChildService(request dao.FindChildRequest, service *service.Service) error {
-	if svc, err := this.facade.FindChildService(datastore.Get(), request.ServiceID, request.ChildName); err == nil {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: request.ServiceID}); err != nil {
+		return err
+	}
+	if svc, err := this.facade.FindChildService(this.context(), request.ServiceID, request.ChildName); err == nil {
 		*service = *svc
 		return nil
 	} else {
@@ -67,7 +85,10 @@ func (this *ControlPlaneDao) FindChildService(request dao.FindChildRequest, serv
 
 //
 func (this *ControlPlaneDao) GetTaggedServices(request dao.EntityRequest, services *[]*service.Service) error {
-	if svcs, err := this.facade.GetTaggedServices(datastore.Get(), request); err == nil {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService}); err != nil {
+		return err
+	}
+	if svcs, err := this.facade.GetTaggedServices(this.context(), request); err == nil {
 		*services = svcs
 		return nil
 	} else {
@@ -77,7 +98,10 @@ func (this *ControlPlaneDao) GetTaggedServices(request dao.EntityRequest, servic
 
 // The tenant id is the root service uuid. Walk the service tree to root to find the tenant id.
 func (this *ControlPlaneDao) GetTenantId(serviceID string, tenantId *string) error {
-	if tid, err := this.facade.GetTenantID(datastore.Get(), serviceID); err == nil {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	if tid, err := this.facade.GetTenantID(this.context(), serviceID); err == nil {
 		*tenantId = tid
 		return nil
 	} else {
@@ -87,7 +111,10 @@ func (this *ControlPlaneDao) GetTenantId(serviceID string, tenantId *string) err
 
 // Get a service endpoint.
 func (this *ControlPlaneDao) GetServiceEndpoints(serviceID string, response *map[string][]*dao.ApplicationEndpoint) (err error) {
-	if result, err := this.facade.GetServiceEndpoints(datastore.Get(), serviceID); err == nil {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	if result, err := this.facade.GetServiceEndpoints(this.context(), serviceID); err == nil {
 		*response = result
 		return nil
 	} else {
@@ -97,13 +124,22 @@ func (this *ControlPlaneDao) GetServiceEndpoints(serviceID string, response *map
 
 // start the provided service
 func (this *ControlPlaneDao) StartService(serviceID string, unused *string) error {
-	return this.facade.StartService(datastore.Get(), serviceID)
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	return this.facade.StartService(this.context(), serviceID)
 }
 func (this *ControlPlaneDao) StopService(id string, unused *int) error {
-	return this.facade.StopService(datastore.Get(), id)
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: id}); err != nil {
+		return err
+	}
+	return this.facade.StopService(this.context(), id)
 }
 
 // assign an IP address to a service (and all its child services) containing non default AddressResourceConfig
 func (this *ControlPlaneDao) AssignIPs(assignmentRequest dao.AssignmentRequest, _ *struct{}) error {
-	return this.facade.AssignIPs(datastore.Get(), assignmentRequest)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: assignmentRequest.ServiceID}); err != nil {
+		return err
+	}
+	return this.facade.AssignIPs(this.context(), assignmentRequest)
 }
@@ -6,15 +6,28 @@ package elasticsearch
 
 import (
 	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/coordinator/client"
 	"github.com/control-center/serviced/dao"
-	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/zzk"
+	"github.com/control-center/serviced/zzk/autoscale"
+	"github.com/control-center/serviced/zzk/rollout"
 	zkservice "github.com/control-center/serviced/zzk/service"
+
+	"fmt"
+	"sort"
+	"time"
 )
 
+// how long a batch of a rolling restart has to pass its health checks by default
+const defaultRolloutTimeout = 5 * time.Minute
+
 func (this *ControlPlaneDao) GetServiceState(request dao.ServiceStateRequest, serviceState *servicestate.ServiceState) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: request.ServiceID}); err != nil {
+		return err
+	}
 	glog.V(3).Infof("ControlPlaneDao.GetServiceState: request=%v", request)
 
 	var myService service.Service
@@ -33,9 +46,12 @@ func (this *ControlPlaneDao) GetServiceState(request dao.ServiceStateRequest, se
 }
 
 func (this *ControlPlaneDao) GetServiceStates(serviceId string, serviceStates *[]*servicestate.ServiceState) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceId}); err != nil {
+		return err
+	}
 	glog.V(2).Infof("ControlPlaneDao.GetServiceStates: serviceId=%s", serviceId)
 
-	myService, err := this.facade.GetService(datastore.Get(), serviceId)
+	myService, err := this.facade.GetService(this.context(), serviceId)
 	if err != nil {
 		glog.Errorf("Unable to get service %v: %v", serviceId, err)
 		return err
@@ -54,7 +70,7 @@ func (this *ControlPlaneDao) GetServiceStates(serviceId string, serviceStates *[
 func (this *ControlPlaneDao) getNonTerminatedServiceStates(serviceId string, serviceStates *[]*servicestate.ServiceState) error {
 	glog.V(2).Infof("ControlPlaneDao.getNonTerminatedServiceStates: serviceId=%s", serviceId)
 
-	myService, err := this.facade.GetService(datastore.Get(), serviceId)
+	myService, err := this.facade.GetService(this.context(), serviceId)
 	if err != nil {
 		glog.Errorf("Unable to get service %v: %v", serviceId, err)
 		return err
@@ -71,9 +87,12 @@ func (this *ControlPlaneDao) getNonTerminatedServiceStates(serviceId string, ser
 
 // Update the current state of a service instance.
 func (this *ControlPlaneDao) UpdateServiceState(state servicestate.ServiceState, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: state.ServiceID}); err != nil {
+		return err
+	}
 	glog.V(2).Infoln("ControlPlaneDao.UpdateServiceState state=%+v", state)
 
-	myService, err := this.facade.GetService(datastore.Get(), state.ServiceID)
+	myService, err := this.facade.GetService(this.context(), state.ServiceID)
 	if err != nil {
 		glog.Errorf("Unable to get service %v: %v", state.ServiceID, err)
 		return err
@@ -89,7 +108,10 @@ func (this *ControlPlaneDao) UpdateServiceState(state servicestate.ServiceState,
 }
 
 func (this *ControlPlaneDao) StopRunningInstance(request dao.HostServiceRequest, unused *int) error {
-	myHost, err := this.facade.GetHost(datastore.Get(), request.HostID)
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, HostID: request.HostID}); err != nil {
+		return err
+	}
+	myHost, err := this.facade.GetHost(this.context(), request.HostID)
 	if err != nil {
 		glog.Errorf("Unable to get host %v: %v", request.HostID, err)
 		return err
@@ -106,5 +128,215 @@ func (this *ControlPlaneDao) StopRunningInstance(request dao.HostServiceRequest,
 		return err
 	}
 
+	this.facade.Audit(this.context(), "stop", "instance", request.ServiceStateID, nil, request)
+	return nil
+}
+
+// RestartService restarts the running instances of a service. A rolling
+// restart is handed to the leader, which restarts the instances a batch at a
+// time. Requesting a rolling restart of a service whose rollout is paused
+// resumes it.
+func (this *ControlPlaneDao) RestartService(request dao.ServiceRestartRequest, unused *int) (err error) {
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: request.ServiceID}); err != nil {
+		return err
+	}
+	glog.V(2).Infof("ControlPlaneDao.RestartService: request=%+v", request)
+	defer func() {
+		if err == nil {
+			this.facade.Audit(this.context(), "restart", "service", request.ServiceID, nil, request)
+		}
+	}()
+
+	myService, err := this.facade.GetService(this.context(), request.ServiceID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", request.ServiceID, err)
+		return err
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(myService.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", myService.PoolID, err)
+		return err
+	}
+
+	var states []*servicestate.ServiceState
+	if err := zzk.GetServiceStates(poolBasedConn, &states, request.ServiceID); err != nil {
+		return err
+	}
+
+	// the leader stops the instances of a plain restart all at once, draining
+	// them and ordering them with their dependents as the scheduler does
+	if !request.Rolling {
+		if len(states) == 0 {
+			return nil
+		}
+		return rollout.Send(poolBasedConn, &rollout.Rollout{
+			ServiceID: request.ServiceID,
+			Pending:   instanceIDs(states),
+			Immediate: true,
+		})
+	}
+
+	if request.BatchSize <= 0 {
+		request.BatchSize = 1
+	}
+	if request.Timeout <= 0 {
+		request.Timeout = defaultRolloutTimeout
+	}
+
+	var current rollout.Rollout
+	if err := rollout.Get(poolBasedConn, request.ServiceID, &current); err == nil && current.Paused() {
+		glog.Infof("Resuming rolling restart of service %s", request.ServiceID)
+		return rollout.Send(poolBasedConn, current.Resume(request.BatchSize, request.Timeout))
+	}
+
+	return sendRollout(poolBasedConn, request.ServiceID, states, request.BatchSize, request.Timeout)
+}
+
+// UpdateServiceRolling updates a service and then restarts its running
+// instances a batch at a time, so that they pick up the new definition
+// without taking the whole service down. A rolling update cannot change the
+// number of instances or the pool of the service; do that with a plain update.
+func (this *ControlPlaneDao) UpdateServiceRolling(request dao.ServiceUpdateRequest, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: request.Service.ID}); err != nil {
+		return err
+	}
+	glog.V(2).Infof("ControlPlaneDao.UpdateServiceRolling: service=%s", request.Service.ID)
+
+	myService, err := this.facade.GetService(this.context(), request.Service.ID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", request.Service.ID, err)
+		return err
+	}
+	if request.Service.Instances != myService.Instances {
+		return fmt.Errorf("a rolling update cannot change the number of instances of service %s", myService.Name)
+	} else if request.Service.PoolID != myService.PoolID {
+		return fmt.Errorf("a rolling update cannot move service %s to another pool", myService.Name)
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(myService.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", myService.PoolID, err)
+		return err
+	}
+
+	// don't save the update unless its rollout can start
+	var current rollout.Rollout
+	if err := rollout.Get(poolBasedConn, myService.ID, &current); err == nil && !current.Done() && !current.Paused() {
+		return fmt.Errorf("a rolling restart of service %s is already in progress", myService.Name)
+	}
+
+	if err := this.facade.UpdateService(this.context(), request.Service); err != nil {
+		return err
+	}
+
+	var states []*servicestate.ServiceState
+	if err := zzk.GetServiceStates(poolBasedConn, &states, myService.ID); err != nil {
+		return err
+	}
+
+	if request.BatchSize <= 0 {
+		request.BatchSize = 1
+	}
+	if request.Timeout <= 0 {
+		request.Timeout = defaultRolloutTimeout
+	}
+	return sendRollout(poolBasedConn, myService.ID, states, request.BatchSize, request.Timeout)
+}
+
+// sendRollout hands the leader a rollout that restarts every running instance
+// of a service
+func sendRollout(conn client.Connection, serviceID string, states []*servicestate.ServiceState, batchSize int, timeout time.Duration) error {
+	return rollout.Send(conn, &rollout.Rollout{
+		ServiceID: serviceID,
+		BatchSize: batchSize,
+		Timeout:   timeout,
+		Pending:   instanceIDs(states),
+	})
+}
+
+// instanceIDs returns the sorted instance ids of the running instances
+func instanceIDs(states []*servicestate.ServiceState) []int {
+	ids := make([]int, len(states))
+	for i, state := range states {
+		ids[i] = state.InstanceID
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// CancelServiceRollout cancels the rolling restart of a service, whether it
+// is paused or in progress. Instances that have already been restarted are
+// left as they are.
+func (this *ControlPlaneDao) CancelServiceRollout(serviceID string, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Operate, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	myService, err := this.facade.GetService(this.context(), serviceID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", serviceID, err)
+		return err
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(myService.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", myService.PoolID, err)
+		return err
+	}
+
+	if err := rollout.Cancel(poolBasedConn, serviceID); err != nil {
+		return err
+	}
+	this.facade.Audit(this.context(), "cancel-rollout", "service", serviceID, nil, nil)
+	return nil
+}
+
+// GetServiceRollout gets the progress of the rolling restart of a service
+func (this *ControlPlaneDao) GetServiceRollout(serviceID string, r *rollout.Rollout) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	myService, err := this.facade.GetService(this.context(), serviceID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", serviceID, err)
+		return err
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(myService.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", myService.PoolID, err)
+		return err
+	}
+
+	if err := rollout.Get(poolBasedConn, serviceID, r); err != nil {
+		glog.V(2).Infof("ControlPlaneDao.GetServiceRollout service=%s err=%s", serviceID, err)
+		return fmt.Errorf("no rolling restart found for service %s", serviceID)
+	}
+	return nil
+}
+
+// GetScalingEvents gets the decisions of the autoscaler for a service
+func (this *ControlPlaneDao) GetScalingEvents(serviceID string, events *[]autoscale.Event) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	myService, err := this.facade.GetService(this.context(), serviceID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", serviceID, err)
+		return err
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(myService.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", myService.PoolID, err)
+		return err
+	}
+
+	result, err := autoscale.Events(poolBasedConn, serviceID)
+	if err != nil {
+		glog.Errorf("Unable to get scaling events of service %v: %v", serviceID, err)
+		return err
+	}
+	*events = result
 	return nil
 }
@@ -6,38 +6,79 @@ package elasticsearch
 
 import (
 	"github.com/control-center/serviced/dao"
-	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicetemplate"
 )
 
 func (this *ControlPlaneDao) AddServiceTemplate(serviceTemplate servicetemplate.ServiceTemplate, templateID *string) error {
-	id, err := this.facade.AddServiceTemplate(datastore.Get(), serviceTemplate)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceTemplate}); err != nil {
+		return err
+	}
+	id, err := this.facade.AddServiceTemplate(this.context(), serviceTemplate)
 	*templateID = id
 	return err
 }
 
 func (this *ControlPlaneDao) UpdateServiceTemplate(template servicetemplate.ServiceTemplate, unused *int) error {
-	return this.facade.UpdateServiceTemplate(datastore.Get(), template)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceTemplate}); err != nil {
+		return err
+	}
+	return this.facade.UpdateServiceTemplate(this.context(), template)
 }
 
 func (this *ControlPlaneDao) RemoveServiceTemplate(id string, unused *int) error {
-	return this.facade.RemoveServiceTemplate(datastore.Get(), id)
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceTemplate}); err != nil {
+		return err
+	}
+	return this.facade.RemoveServiceTemplate(this.context(), id)
 }
 
 func (this *ControlPlaneDao) GetServiceTemplates(unused int, templates *map[string]*servicetemplate.ServiceTemplate) error {
-	templatemap, err := this.facade.GetServiceTemplates(datastore.Get())
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceTemplate}); err != nil {
+		return err
+	}
+	templatemap, err := this.facade.GetServiceTemplates(this.context())
 	*templates = templatemap
 	return err
 }
 
 func (this *ControlPlaneDao) DeployTemplate(request dao.ServiceTemplateDeploymentRequest, tenantID *string) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, PoolID: request.PoolID}); err != nil {
+		return err
+	}
 	var err error
-	*tenantID, err = this.facade.DeployTemplate(datastore.Get(), request.PoolID, request.TemplateID, request.DeploymentID)
+	*tenantID, err = this.facade.DeployTemplate(this.context(), request.PoolID, request.TemplateID, request.DeploymentID)
+	return err
+}
+
+func (this *ControlPlaneDao) DiffTemplate(request dao.ServiceTemplateApplyRequest, diff *service.TemplateDiff) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceService, ServiceID: request.TenantID}); err != nil {
+		return err
+	}
+	result, err := this.facade.DiffTemplate(this.context(), request.TenantID, request.TemplateID, request.OverwriteContext)
+	if result != nil {
+		*diff = *result
+	}
+	return err
+}
+
+func (this *ControlPlaneDao) ApplyTemplate(request dao.ServiceTemplateApplyRequest, diff *service.TemplateDiff) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: request.TenantID}); err != nil {
+		return err
+	}
+	result, err := this.facade.ApplyTemplate(this.context(), request.TenantID, request.TemplateID, request.OverwriteContext)
+	if result != nil {
+		*diff = *result
+	}
 	return err
 }
 
 func (this *ControlPlaneDao) DeployService(request dao.ServiceDeploymentRequest, serviceID *string) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceService, ServiceID: request.ParentID}); err != nil {
+		return err
+	}
 	var err error
-	*serviceID, err = this.facade.DeployService(datastore.Get(), request.ParentID, request.Service)
+	*serviceID, err = this.facade.DeployService(this.context(), request.ParentID, request.Service)
 	return err
 }
@@ -0,0 +1,290 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/commons/docker"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/service"
+
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotExport describes the snapshot packaged in an export archive. The
+// archive also has:
+//    volume.tgz     the data of the snapshot
+//    services.json  the services of the tenant when the snapshot was taken
+//    images.json    the ids and names of the images tagged by the snapshot
+//    images/N.tar   the images
+type snapshotExport struct {
+	SnapshotID string
+	TenantID   string
+}
+
+// tenantServices returns the services of a tenant; that is, the tenant and
+// the services below it
+func tenantServices(services []*service.Service, tenantID string) []*service.Service {
+	parents := make(map[string]string)
+	for _, svc := range services {
+		parents[svc.ID] = svc.ParentServiceID
+	}
+
+	var result []*service.Service
+	for _, svc := range services {
+		for id := svc.ID; id != ""; id = parents[id] {
+			if id == tenantID {
+				result = append(result, svc)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// writeSnapshotToTgz writes the data of a snapshot to a tgz file. The
+// services.json of the snapshot, which has the services of every tenant, is
+// left out.
+func writeSnapshotToTgz(snapDir, filename string) error {
+	cmd, e := commandAsRoot("tar", "-czf", filename, "-C", snapDir, "--exclude=./services.json", ".")
+	if e != nil {
+		return e
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		glog.Errorf("Unable to writeSnapshotToTgz cmd:%+v  error:%v  output:%s", cmd, err, string(output))
+		return err
+	}
+	return nil
+}
+
+// ExportSnapshot writes a tenant snapshot, with the services and images of
+// the tenant as they were when it was taken, to a tgz file that can be
+// imported into another deployment.
+func (cp *ControlPlaneDao) ExportSnapshot(request dao.SnapshotExportRequest, unused *int) (err error) {
+	if err := cp.authorize(role.Request{Action: role.Operate, Resource: role.ResourceSnapshot, ServiceID: snapshotTenant(request.SnapshotID)}); err != nil {
+		return err
+	}
+	return cp.trusted().exportSnapshot(request)
+}
+
+func (cp *ControlPlaneDao) exportSnapshot(request dao.SnapshotExportRequest) (err error) {
+	parts := strings.SplitN(request.SnapshotID, "_", 2)
+	if len(parts) < 2 {
+		return fmt.Errorf("malformed snapshot id: %s", request.SnapshotID)
+	}
+	tenantID, timestamp := parts[0], parts[1]
+
+	var tenant service.Service
+	if e := cp.GetService(tenantID, &tenant); e != nil {
+		glog.Errorf("Could not get tenant %s of snapshot %s: %v", tenantID, request.SnapshotID, e)
+		return e
+	}
+	snapDir, e := getSnapshotPath(cp.vfs, tenant.PoolID, tenantID, request.SnapshotID)
+	if e != nil {
+		glog.Errorf("Could not get subvolume %s:%s: %v", tenant.PoolID, tenantID, e)
+		return e
+	}
+	if _, e := os.Stat(snapDir); e != nil {
+		glog.Errorf("Could not find snapshot %s at %s: %v", request.SnapshotID, snapDir, e)
+		return fmt.Errorf("snapshot %s not found", request.SnapshotID)
+	}
+
+	if e := os.MkdirAll(varPath(), os.ModeDir|0755); e != nil {
+		glog.Errorf("Could not find nor create %s: %v", varPath(), e)
+		return e
+	}
+	exportDir, e := ioutil.TempDir(varPath(), "export-")
+	if e != nil {
+		glog.Errorf("Could not create a directory for the export of %s: %v", request.SnapshotID, e)
+		return e
+	}
+	defer func() {
+		if e := os.RemoveAll(exportDir); e != nil {
+			glog.Errorf("Could not remove %s: %v", exportDir, e)
+			if err == nil {
+				err = e
+			}
+		}
+	}()
+	exportPath := func(relPath ...string) string {
+		return filepath.Join(append([]string{exportDir}, relPath...)...)
+	}
+	if e := os.MkdirAll(exportPath("images"), os.ModeDir|0755); e != nil {
+		glog.Errorf("Could not find nor create %s: %v", exportPath("images"), e)
+		return e
+	}
+
+	if e := writeJSONToFile(snapshotExport{request.SnapshotID, tenantID}, exportPath("snapshot.json")); e != nil {
+		glog.Errorf("Could not write snapshot.json: %v", e)
+		return e
+	}
+
+	// The snapshot has the services of every tenant; only this one's are exported
+	var services []*service.Service
+	if e := readJSONFromFile(&services, filepath.Join(snapDir, "services.json")); e != nil {
+		glog.Errorf("Could not read the services of snapshot %s: %v", request.SnapshotID, e)
+		return e
+	}
+	if e := writeJSONToFile(tenantServices(services, tenantID), exportPath("services.json")); e != nil {
+		glog.Errorf("Could not write services.json: %v", e)
+		return e
+	}
+
+	// Export the images of the tenant that the snapshot tagged
+	images, e := docker.Images()
+	if e != nil {
+		glog.Errorf("Could not get images from docker: %v", e)
+		return e
+	}
+	var imagesNameTags [][]string
+	for _, image := range images {
+		if image.ID.User != tenantID || image.ID.Tag != timestamp {
+			continue
+		}
+		filename := exportPath("images", fmt.Sprintf("%d.tar", len(imagesNameTags)))
+		if e := exportDockerImageToFile(image.UUID, filename); e != nil {
+			glog.Errorf("Error while exporting docker image %s: %v", image.ID, e)
+			return e
+		}
+		imagesNameTags = append(imagesNameTags, []string{image.UUID, image.ID.String()})
+	}
+	if e := writeJSONToFile(imagesNameTags, exportPath("images.json")); e != nil {
+		glog.Errorf("Could not write images.json: %v", e)
+		return e
+	}
+
+	if e := writeSnapshotToTgz(snapDir, exportPath("volume.tgz")); e != nil {
+		glog.Errorf("Could not write %s to %s: %v", snapDir, exportPath("volume.tgz"), e)
+		return e
+	}
+	if e := writeDirectoryToTgz(exportPath(), request.FilePath); e != nil {
+		glog.Errorf("Could not write %s to %s: %v", exportPath(), request.FilePath, e)
+		return e
+	}
+
+	glog.Infof("Exported snapshot %s to file:%s", request.SnapshotID, request.FilePath)
+	cp.facade.Audit(cp.context(), "export", "snapshot", request.SnapshotID, nil, struct{ FilePath string }{request.FilePath})
+	return nil
+}
+
+// ImportSnapshot adds a tenant snapshot from a tgz file written by
+// ExportSnapshot, and rolls the tenant back to it. The images of the snapshot
+// are loaded and the services of the tenant are added or updated. The
+// snapshot is kept, so that the tenant can be rolled back to it again.
+func (cp *ControlPlaneDao) ImportSnapshot(filePath string, snapshotID *string) (err error) {
+	if err := cp.authorize(role.Request{Action: role.Modify, Resource: role.ResourceSnapshot}); err != nil {
+		return err
+	}
+	return cp.trusted().importSnapshot(filePath, snapshotID)
+}
+
+func (cp *ControlPlaneDao) importSnapshot(filePath string, snapshotID *string) (err error) {
+	if e := os.MkdirAll(varPath(), os.ModeDir|0755); e != nil {
+		glog.Errorf("Could not find nor create %s: %v", varPath(), e)
+		return e
+	}
+	importDir, e := ioutil.TempDir(varPath(), "import-")
+	if e != nil {
+		glog.Errorf("Could not create a directory for the import of %s: %v", filePath, e)
+		return e
+	}
+	defer func() {
+		if e := os.RemoveAll(importDir); e != nil {
+			glog.Errorf("Could not remove %s: %v", importDir, e)
+			if err == nil {
+				err = e
+			}
+		}
+	}()
+	importPath := func(relPath ...string) string {
+		return filepath.Join(append([]string{importDir}, relPath...)...)
+	}
+
+	if e := writeDirectoryFromTgz(importPath(), filePath); e != nil {
+		glog.Errorf("Could not expand %s to %s: %v", filePath, importPath(), e)
+		return e
+	}
+
+	var (
+		export         snapshotExport
+		imagesNameTags [][]string
+	)
+	if e := readJSONFromFile(&export, importPath("snapshot.json")); e != nil {
+		glog.Errorf("Could not read the snapshot from %s: %v", importPath("snapshot.json"), e)
+		return e
+	}
+	if e := readJSONFromFile(&imagesNameTags, importPath("images.json")); e != nil {
+		glog.Errorf("Could not read images from %s: %v", importPath("images.json"), e)
+		return e
+	}
+
+	// An existing snapshot is not replaced
+	var tenant service.Service
+	if e := cp.GetService(export.TenantID, &tenant); e == nil {
+		snapDir, e := getSnapshotPath(cp.vfs, tenant.PoolID, tenant.ID, export.SnapshotID)
+		if e != nil {
+			glog.Errorf("Could not get subvolume %s:%s: %v", tenant.PoolID, tenant.ID, e)
+			return e
+		}
+		if _, e := os.Stat(snapDir); e == nil {
+			return fmt.Errorf("snapshot %s already exists", export.SnapshotID)
+		}
+	}
+
+	// Load the images, tagged as the snapshot tagged them
+	for i, imageNameWithTags := range imagesNameTags {
+		filename := importPath("images", fmt.Sprintf("%d.tar", i))
+		if e := loadDockerImage(imageNameWithTags[0], imageNameWithTags[1:], filename); e != nil {
+			return e
+		}
+	}
+
+	// Add or update the services of the tenant
+	if e := cp.dfs.RollbackServices(importPath()); e != nil {
+		glog.Errorf("Could not rollback services: %s", e)
+		return e
+	}
+	if e := cp.GetService(export.TenantID, &tenant); e != nil {
+		glog.Errorf("Could not find tenant %s for snapshot %s: %s", export.TenantID, export.SnapshotID, e)
+		return e
+	}
+	vol, e := getSubvolume(cp.vfs, tenant.PoolID, tenant.ID)
+	if e != nil {
+		glog.Errorf("Could not get subvolume %s:%s: %v", tenant.PoolID, tenant.ID, e)
+		return e
+	}
+
+	// Add the snapshot, with the services of this tenant alone, so that a
+	// rollback to it leaves other tenants be
+	if e := addSnapshot(vol, export.SnapshotID, importPath("volume.tgz")); e != nil {
+		glog.Errorf("Could not add snapshot %s from %s: %v", export.SnapshotID, importPath("volume.tgz"), e)
+		return e
+	}
+	var services []*service.Service
+	if e := readJSONFromFile(&services, importPath("services.json")); e != nil {
+		glog.Errorf("Could not read the services of snapshot %s: %v", export.SnapshotID, e)
+		return e
+	}
+	if e := writeJSONToFile(services, filepath.Join(vol.SnapshotPath(export.SnapshotID), "services.json")); e != nil {
+		glog.Errorf("Could not write services.json: %v", e)
+		return e
+	}
+
+	var unused int
+	if e := cp.Rollback(export.SnapshotID, &unused); e != nil {
+		glog.Errorf("Could not rollback to snapshot %s: %v", export.SnapshotID, e)
+		return e
+	}
+
+	*snapshotID = export.SnapshotID
+	glog.Infof("Imported snapshot %s from file:%s", export.SnapshotID, filePath)
+	cp.facade.Audit(cp.context(), "import", "snapshot", export.SnapshotID, nil, struct{ FilePath string }{filePath})
+	return nil
+}
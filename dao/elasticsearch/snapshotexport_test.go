@@ -0,0 +1,178 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/volume"
+)
+
+// dirConn is a volume whose snapshots are copies of its directory
+type dirConn struct {
+	name string
+	root string
+}
+
+func (c *dirConn) Name() string                     { return c.name }
+func (c *dirConn) Path() string                     { return filepath.Join(c.root, c.name) }
+func (c *dirConn) SnapshotPath(label string) string { return filepath.Join(c.root, label) }
+func (c *dirConn) Unmount() error                   { return os.RemoveAll(c.root) }
+
+func (c *dirConn) Snapshot(label string) error {
+	return exec.Command("cp", "-a", c.Path(), c.SnapshotPath(label)).Run()
+}
+
+func (c *dirConn) Snapshots() ([]string, error) {
+	files, err := ioutil.ReadDir(c.root)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	for _, file := range files {
+		if file.Name() != c.name {
+			labels = append(labels, file.Name())
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+func (c *dirConn) RemoveSnapshot(label string) error {
+	return os.RemoveAll(c.SnapshotPath(label))
+}
+
+func (c *dirConn) Rollback(label string) error {
+	if err := os.RemoveAll(c.Path()); err != nil {
+		return err
+	}
+	return exec.Command("cp", "-a", c.SnapshotPath(label), c.Path()).Run()
+}
+
+// writeFiles creates the files in a directory, each holding its name
+func writeFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("could not create %s: %s", filepath.Dir(filename), err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(name), 0644); err != nil {
+			t.Fatalf("could not write %s: %s", filename, err)
+		}
+	}
+}
+
+// expectFiles checks which of the files are in a directory
+func expectFiles(t *testing.T, dir string, present []string, absent []string) {
+	for _, name := range present {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s in %s: %s", name, dir, err)
+		} else if string(data) != name {
+			t.Errorf("expected %s to hold %q, got %q", name, name, data)
+		}
+	}
+	for _, name := range absent {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected no %s in %s", name, dir)
+		}
+	}
+}
+
+func TestSnapshotExport_tenantServices(t *testing.T) {
+	services := []*service.Service{
+		{ID: "tenant"},
+		{ID: "child", ParentServiceID: "tenant"},
+		{ID: "grandchild", ParentServiceID: "child"},
+		{ID: "other"},
+		{ID: "otherchild", ParentServiceID: "other"},
+	}
+
+	result := tenantServices(services, "tenant")
+	expected := []string{"tenant", "child", "grandchild"}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d services, got %d", len(expected), len(result))
+	}
+	for i, svc := range result {
+		if svc.ID != expected[i] {
+			t.Errorf("expected service %s, got %s", expected[i], svc.ID)
+		}
+	}
+
+	if result := tenantServices(services, "missing"); len(result) != 0 {
+		t.Errorf("expected no services, got %d", len(result))
+	}
+}
+
+func TestSnapshotExport_writeSnapshotToTgz(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "snapshotexport-")
+	if err != nil {
+		t.Fatalf("could not create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	snapDir := filepath.Join(tmp, "snapshot")
+	writeFiles(t, snapDir, "services.json", "data/a", "data/services.json")
+	filename := filepath.Join(tmp, "volume.tgz")
+	if err := writeSnapshotToTgz(snapDir, filename); err != nil {
+		t.Fatalf("could not write the snapshot: %s", err)
+	}
+
+	// the services of every tenant are left out
+	dest := filepath.Join(tmp, "dest")
+	if err := writeDirectoryFromTgz(dest, filename); err != nil {
+		t.Fatalf("could not read the snapshot: %s", err)
+	}
+	expectFiles(t, dest, []string{"data/a", "data/services.json"}, []string{"services.json"})
+}
+
+func TestSnapshotExport_addSnapshot(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "snapshotexport-")
+	if err != nil {
+		t.Fatalf("could not create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	imported := filepath.Join(tmp, "imported")
+	writeFiles(t, imported, "a", "dir/b")
+	filename := filepath.Join(tmp, "volume.tgz")
+	if err := writeDirectoryToTgz(imported, filename); err != nil {
+		t.Fatalf("could not write %s: %s", filename, err)
+	}
+	vol := &volume.Volume{Conn: &dirConn{name: "tenant", root: filepath.Join(tmp, "volumes")}}
+	writeFiles(t, vol.Path(), "live")
+
+	// the snapshot is taken by the driver, and the saved data is removed
+	label := "tenant_20140101-000000"
+	if err := addSnapshot(vol, label, filename); err != nil {
+		t.Fatalf("could not add snapshot: %s", err)
+	}
+	expectFiles(t, vol.SnapshotPath(label), []string{"a", "dir/b"}, []string{"live"})
+	if labels, err := vol.Snapshots(); err != nil {
+		t.Fatalf("could not list snapshots: %s", err)
+	} else if len(labels) != 1 || labels[0] != label {
+		t.Errorf("expected snapshot %s alone, got %v", label, labels)
+	}
+
+	// the data of the volume is restored when the snapshot cannot be added
+	if err := vol.Rollback(label); err != nil {
+		t.Fatalf("could not roll back: %s", err)
+	}
+	writeFiles(t, vol.Path(), "live")
+	if err := addSnapshot(vol, "tenant_20140102-000000", filepath.Join(tmp, "missing.tgz")); err == nil {
+		t.Errorf("expected an error adding a snapshot from a missing file")
+	}
+	expectFiles(t, vol.Path(), []string{"a", "dir/b", "live"}, nil)
+	if labels, err := vol.Snapshots(); err != nil {
+		t.Fatalf("could not list snapshots: %s", err)
+	} else if len(labels) != 1 || labels[0] != label {
+		t.Errorf("expected snapshot %s alone, got %v", label, labels)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package elasticsearch
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/commons/cron"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/zzk"
+	"github.com/control-center/serviced/zzk/snapshotschedule"
+
+	"fmt"
+	"time"
+)
+
+// SetSnapshotPolicy sets the snapshot schedule and retention rules of a
+// tenant. The leader of the pool of the tenant takes and prunes the snapshots.
+func (this *ControlPlaneDao) SetSnapshotPolicy(request dao.SnapshotPolicyRequest, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceSnapshot, ServiceID: request.TenantID}); err != nil {
+		return err
+	}
+	tenant, err := this.facade.GetService(this.context(), request.TenantID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", request.TenantID, err)
+		return err
+	} else if tenant.ParentServiceID != "" {
+		return fmt.Errorf("service %s is not a tenant", request.TenantID)
+	}
+
+	tenant.SnapshotPolicy = request.Policy
+	return this.trusted().UpdateService(*tenant, unused)
+}
+
+// GetSnapshotSchedule gets the snapshot policy of a tenant and the runs of
+// its schedule
+func (this *ControlPlaneDao) GetSnapshotSchedule(serviceID string, schedule *dao.SnapshotSchedule) error {
+	if err := this.authorize(role.Request{Action: role.Read, Resource: role.ResourceSnapshot, ServiceID: serviceID}); err != nil {
+		return err
+	}
+	var tenantID string
+	if err := this.trusted().GetTenantId(serviceID, &tenantID); err != nil {
+		glog.Errorf("Unable to get tenant of service %v: %v", serviceID, err)
+		return err
+	}
+	tenant, err := this.facade.GetService(this.context(), tenantID)
+	if err != nil {
+		glog.Errorf("Unable to get service %v: %v", tenantID, err)
+		return err
+	}
+
+	poolBasedConn, err := zzk.GetBasePathConnection(zzk.GeneratePoolPath(tenant.PoolID))
+	if err != nil {
+		glog.Errorf("Error in getting a connection based on pool %v: %v", tenant.PoolID, err)
+		return err
+	}
+
+	status, err := snapshotschedule.Get(poolBasedConn, tenantID)
+	if err != nil {
+		glog.Errorf("Unable to get snapshot schedule of tenant %v: %v", tenantID, err)
+		return err
+	}
+
+	*schedule = dao.SnapshotSchedule{TenantID: tenantID, Policy: tenant.SnapshotPolicy, Status: status}
+	if tenant.SnapshotPolicy.Enabled() {
+		if s, err := cron.Parse(tenant.SnapshotPolicy.Schedule); err == nil {
+			if status != nil {
+				schedule.Next = status.Next(s)
+			} else {
+				schedule.Next = s.Next(time.Now().UTC())
+			}
+		}
+	}
+	return nil
+}
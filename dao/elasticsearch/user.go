@@ -12,6 +12,7 @@ package elasticsearch
 import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	userdomain "github.com/control-center/serviced/domain/user"
 	"github.com/control-center/serviced/utils"
 
@@ -36,6 +37,9 @@ func hashPassword(password string) string {
 
 //addUser places a new user record into elastic searchp
 func (this *ControlPlaneDao) AddUser(newUser userdomain.User, userName *string) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
 	glog.V(2).Infof("ControlPlane.NewUser: %+v", newUser)
 	name := strings.TrimSpace(*userName)
 	newUser.Password = hashPassword(newUser.Password)
@@ -46,12 +50,15 @@ func (this *ControlPlaneDao) AddUser(newUser userdomain.User, userName *string)
 		return err
 	}
 	store := userdomain.NewStore()
-	return store.Put(datastore.Get(), userdomain.Key(name), &newUser)
+	return store.Put(this.context(), userdomain.Key(name), &newUser)
 }
 
 //UpdateUser updates the user entry in elastic search. NOTE: It is assumed the
 //pasword is NOT hashed when updating the user record
 func (this *ControlPlaneDao) UpdateUser(user userdomain.User, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
 	glog.V(2).Infof("ControlPlaneDao.UpdateUser: %+v", user)
 
 	id := strings.TrimSpace(user.Name)
@@ -63,29 +70,36 @@ func (this *ControlPlaneDao) UpdateUser(user userdomain.User, unused *int) error
 	user.Password = hashPassword(user.Password)
 
 	store := userdomain.NewStore()
-	return store.Put(datastore.Get(), userdomain.Key(user.Name), &user)
+	return store.Put(this.context(), userdomain.Key(user.Name), &user)
 }
 
 func (this *ControlPlaneDao) GetUser(userName string, user *userdomain.User) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
 	glog.V(2).Infof("ControlPlaneDao.GetUser: userName=%s", userName)
 	store := userdomain.NewStore()
-	err := store.Get(datastore.Get(), userdomain.Key(userName), user)
+	err := store.Get(this.context(), userdomain.Key(userName), user)
 	glog.V(2).Infof("ControlPlaneDao.GetUser: userName=%s, user=%+v, err=%s", userName, user, err)
 	return err
 }
 
 // RemoveUser removes the user specified by the userName string
 func (this *ControlPlaneDao) RemoveUser(userName string, unused *int) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
 	glog.V(2).Infof("ControlPlaneDao.RemoveUser: %s", userName)
 	store := userdomain.NewStore()
-	return store.Delete(datastore.Get(), userdomain.Key(userName))
+	return store.Delete(this.context(), userdomain.Key(userName))
 }
 
 //ValidateCredentials takes a user name and password and validates them against a stored user
 func (this *ControlPlaneDao) ValidateCredentials(user userdomain.User, result *bool) error {
 	glog.V(2).Infof("ControlPlaneDao.ValidateCredentials: userName=%s", user.Name)
 	storedUser := userdomain.User{}
-	err := this.GetUser(user.Name, &storedUser)
+	// anyone may check credentials, but only the master may look at the user
+	err := this.trusted().GetUser(user.Name, &storedUser)
 	if err != nil {
 		*result = false
 		return err
@@ -107,6 +121,9 @@ func (this *ControlPlaneDao) ValidateCredentials(user userdomain.User, result *b
 
 //GetSystemUser returns the system user's credentials. The "unused int" is required by the RPC interface.
 func (this *ControlPlaneDao) GetSystemUser(unused int, user *userdomain.User) error {
+	if err := this.authorize(role.Request{Action: role.Modify, Resource: role.ResourceCredentials}); err != nil {
+		return err
+	}
 	systemUser := userdomain.User{
 		Name:     SYSTEM_USER_NAME,
 		Password: INSTANCE_PASSWORD,
@@ -12,6 +12,9 @@ import (
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/domain/user"
 	"github.com/control-center/serviced/volume"
+	"github.com/control-center/serviced/zzk/autoscale"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 )
 
 // A generic ControlPlane error
@@ -66,6 +69,9 @@ type ControlPlane interface {
 	// Update an existing service
 	UpdateService(service service.Service, unused *int) error
 
+	// Update a service and restart its instances a batch at a time
+	UpdateServiceRolling(request ServiceUpdateRequest, unused *int) error
+
 	// Remove a service definition
 	RemoveService(serviceId string, unused *int) error
 
@@ -96,8 +102,23 @@ type ControlPlane interface {
 	// Schedule the given service to start
 	StartService(serviceId string, unused *string) error
 
-	// Schedule the given service to restart
-	RestartService(serviceId string, unused *int) error
+	// Restart the instances of the given service, all at once or a batch at a time
+	RestartService(request ServiceRestartRequest, unused *int) error
+
+	// Get the progress of the rolling restart of a service
+	GetServiceRollout(serviceId string, rollout *rollout.Rollout) error
+
+	// Cancel the paused or in progress rolling restart of a service
+	CancelServiceRollout(serviceId string, unused *int) error
+
+	// Get the decisions of the autoscaler for a service, oldest first
+	GetScalingEvents(serviceId string, events *[]autoscale.Event) error
+
+	// Get the status of the health checks of each instance of a service
+	GetServiceHealth(serviceId string, statuses *[]*zkhealth.Status) error
+
+	// Get the status of the health checks of every service instance
+	GetHealthStatuses(request EntityRequest, statuses *[]*zkhealth.Status) error
 
 	// Schedule the given service to stop
 	StopService(serviceId string, unused *int) error
@@ -135,6 +156,12 @@ type ControlPlane interface {
 	// Deploy an application template in to production
 	DeployTemplate(request ServiceTemplateDeploymentRequest, tenantId *string) error
 
+	// Compare a deployed tenant with a new version of its template
+	DiffTemplate(request ServiceTemplateApplyRequest, diff *service.TemplateDiff) error
+
+	// Update a deployed tenant in place to a new version of its template
+	ApplyTemplate(request ServiceTemplateApplyRequest, diff *service.TemplateDiff) error
+
 	// Add a new service Template
 	AddServiceTemplate(serviceTemplate servicetemplate.ServiceTemplate, templateId *string) error
 
@@ -171,6 +198,18 @@ type ControlPlane interface {
 	// Delete snapshots for a given service
 	DeleteSnapshots(serviceId string, unused *int) error
 
+	// Write a tgz file containing a tenant snapshot with its services and images
+	ExportSnapshot(request SnapshotExportRequest, unused *int) error
+
+	// Add a tenant snapshot from a tgz file (inverse of ExportSnapshot)
+	ImportSnapshot(filePath string, snapshotID *string) error
+
+	// Set the snapshot schedule and retention rules of a tenant
+	SetSnapshotPolicy(request SnapshotPolicyRequest, unused *int) error
+
+	// Get the snapshot policy of a tenant and the runs of its schedule
+	GetSnapshotSchedule(serviceId string, schedule *SnapshotSchedule) error
+
 	// Get the DFS volume
 	GetVolume(serviceId string, theVolume *volume.Volume) error
 
@@ -186,9 +225,38 @@ type ControlPlane interface {
 	// Write a tgz file containing all templates and services
 	Backup(backupDirectory string, backupFilePath *string) error
 
+	// Write a tgz file with the changes from the newest backup in the directory
+	IncrementalBackup(backupDirectory string, backupFilePath *string) error
+
+	// List the backups in a directory
+	ListBackups(backupDirectory string, backups *[]BackupInfo) error
+
 	// Restore templates and services from a tgz file (inverse of Backup)
 	Restore(backupFilePath string, unused *int) error
 
+	// Write an encrypted tgz file containing all templates and services
+	EncryptedBackup(request BackupRequest, backupFilePath *string) error
+
+	// Restore templates and services from an encrypted tgz file
+	EncryptedRestore(request RestoreRequest, unused *int) error
+
+	// Check the checksums of the files of a backup, without restoring it
+	VerifyBackup(request RestoreRequest, verification *BackupVerification) error
+
+	// Get the progress of the backup in flight, or of the last one, once it
+	// has changed since the given sequence number or 10 seconds have passed
+	BackupProgress(since int, progress *BackupProgress) error
+
+	// Get the progress of the restore in flight, or of the last one, once it
+	// has changed since the given sequence number or 10 seconds have passed
+	RestoreProgress(since int, progress *BackupProgress) error
+
+	// Cancel the backup in flight and remove its partial files
+	CancelBackup(notUsed string, unused *int) error
+
+	// Cancel the restore in flight, unless it has started restoring services
+	CancelRestore(notUsed string, unused *int) error
+
 	// Register a health check result
 	LogHealthCheck(result domain.HealthCheckResult, unused *int) error
 }
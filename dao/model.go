@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/utils"
+	"github.com/control-center/serviced/zzk/snapshotschedule"
 )
 
 type User struct {
@@ -51,6 +53,93 @@ type ServiceTemplateDeploymentRequest struct {
 	DeploymentID string // Unique id of the instance of this template
 }
 
+// A request to compare or update a deployed tenant with a service template
+type ServiceTemplateApplyRequest struct {
+	TenantID         string // Id of the tenant that was deployed
+	TemplateID       string // Id of the new version of its template
+	OverwriteContext bool   // Replace the contexts of services with the template's
+}
+
+// A request to export a snapshot to an archive
+type SnapshotExportRequest struct {
+	SnapshotID string // Id of the snapshot to export
+	FilePath   string // Path of the archive to write
+}
+
+// A request to set the snapshot policy of a tenant
+type SnapshotPolicyRequest struct {
+	TenantID string                           // Id of the tenant
+	Policy   servicedefinition.SnapshotPolicy // Policy without a schedule to disable scheduled snapshots
+}
+
+// The snapshot policy of a tenant and the runs of its schedule
+type SnapshotSchedule struct {
+	TenantID string
+	Policy   servicedefinition.SnapshotPolicy
+	Next     time.Time                // When the schedule is next due, if the policy is enabled
+	Status   *snapshotschedule.Status // Nil until the schedule is first evaluated
+}
+
+// A backup in a backup directory
+type BackupInfo struct {
+	Name    string    // File name of the backup
+	Parent  string    // File name of the backup it is incremental to, if any
+	Created time.Time // When the backup was taken
+	Size    int64     // Size of the backup file in bytes
+}
+
+// The key of an encrypted backup. A backup is encrypted with a passphrase or
+// an RSA public key, and decrypted with the passphrase or the private key.
+// The passphrase and the private key are sent to the master in the clear
+// unless the connection uses TLS, so the CLI only sends them to a master on
+// the same host or over TLS.
+type BackupKey struct {
+	Passphrase string
+	PublicKey  string // PEM encoded, to encrypt a backup
+	PrivateKey string // PEM encoded, to decrypt a backup
+}
+
+// A request to write an encrypted backup
+type BackupRequest struct {
+	Dirpath     string    // Directory to write the backup to
+	Incremental bool      // Only write what changed since the newest backup in the directory
+	Key         BackupKey // Passphrase or public key to encrypt the backup with
+}
+
+// A request to restore or verify a backup
+type RestoreRequest struct {
+	FilePath string    // Path of the backup
+	Key      BackupKey // Passphrase or private key to decrypt the backup, and the backups it is incremental to, with
+}
+
+// The outcome of verifying a backup
+type BackupVerification struct {
+	FilePath  string   // Path of the backup
+	Encrypted bool     // Whether the backup is encrypted
+	Checksums bool     // Whether the backup has the checksums of its files; older backups do not
+	Files     int      // Files whose checksums were checked
+	Errors    []string // What is wrong with the backup, empty if nothing is
+}
+
+// The progress of a backup or a restore
+type BackupProgress struct {
+	Operation  string    // "backup" or "restore"
+	Sequence   int       // Increases with every change
+	File       string    // Backup file being written or read
+	Phase      string    // Part of the operation in flight, e.g. "images" or "snapshots"
+	Message    string    // What is being done
+	Image      string    // Docker image being saved or loaded, if any
+	StepsDone  int       // Images, snapshots and files handled so far
+	Steps      int       // Images, snapshots and files to handle, as far as is known
+	BytesDone  int64     // Bytes written to the backup, or read from the backups
+	BytesTotal int64     // Bytes expected in all, zero if unknown
+	Started    time.Time // When the operation started
+	ETA        time.Time // When the operation is estimated to finish, zero until there is an estimate
+	Running    bool      // Whether the operation is in flight
+	Canceled   bool      // Whether the operation was canceled
+	Err        string    // Why the operation failed
+}
+
 // A request to deploy a service from a service definition
 //  Pool and deployment ids are derived from the parent
 type ServiceDeploymentRequest struct {
@@ -58,6 +147,21 @@ type ServiceDeploymentRequest struct {
 	Service  servicedefinition.ServiceDefinition
 }
 
+// A request to restart the instances of a service
+type ServiceRestartRequest struct {
+	ServiceID string
+	Rolling   bool          // Restart the instances a batch at a time
+	BatchSize int           // Number of instances to restart at a time
+	Timeout   time.Duration // How long a batch has to pass its health checks
+}
+
+// A request to update a service and then restart its instances a batch at a time
+type ServiceUpdateRequest struct {
+	Service   service.Service
+	BatchSize int           // Number of instances to restart at a time
+	Timeout   time.Duration // How long a batch has to pass its health checks
+}
+
 // This is created by selecting from service_state and joining to service
 type RunningService struct {
 	ID                string
@@ -0,0 +1,41 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package datastore
+
+// Sources of requests made against the datastore
+const (
+	SourceCLI       = "CLI"
+	SourceREST      = "REST"
+	SourceRPC       = "RPC"
+	SourceScheduler = "scheduler"
+)
+
+// Caller identifies who made a request and how it reached the control plane
+type Caller struct {
+	User   string
+	Source string
+	Token  string // ID of the API token the user authenticated with, if any
+	Host   string // ID of the host whose certificate the caller presented, if any
+	Admin  bool   // Set for processes on the master, which may do anything
+}
+
+// WithCaller returns a copy of the context that is attributed to the caller
+func WithCaller(ctx Context, caller Caller) Context {
+	return &callerContext{ctx, caller}
+}
+
+// CallerOf returns the caller that the context is attributed to. Contexts
+// without a caller are assumed to come from an anonymous RPC client.
+func CallerOf(ctx Context) Caller {
+	if c, ok := ctx.(*callerContext); ok {
+		return c.caller
+	}
+	return Caller{Source: SourceRPC}
+}
+
+type callerContext struct {
+	Context
+	caller Caller
+}
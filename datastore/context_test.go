@@ -42,3 +42,20 @@ func TestContext(t *testing.T) {
 		t.Error("Expected connection, got nil")
 	}
 }
+
+func TestCaller(t *testing.T) {
+	ctx := newCtx(&testDriver{})
+
+	if caller := CallerOf(ctx); caller.User != "" || caller.Source != SourceRPC {
+		t.Errorf("Expected anonymous RPC caller, got %+v", caller)
+	}
+
+	expected := Caller{User: "bob", Source: SourceCLI}
+	ctx = WithCaller(ctx, expected)
+	if caller := CallerOf(ctx); caller != expected {
+		t.Errorf("Expected %+v, got %+v", expected, caller)
+	}
+	if conn, _ := ctx.Connection(); conn == nil {
+		t.Error("Expected connection, got nil")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry records a change made to the control plane
+type Entry struct {
+	ID         string
+	Timestamp  time.Time
+	User       string // who made the change, if known
+	Source     string // how the change reached the control plane (CLI, REST, RPC, scheduler)
+	Action     string // what was done, such as add, update, remove, start or stop
+	EntityType string // the kind of entity that was changed, such as service or host
+	EntityID   string
+	Diff       string // the fields of the entity that changed
+}
+
+// Diff describes the top level fields that differ between two versions of an
+// entity, one field per line, as "Field: old -> new". Either version may be
+// nil when an entity is added or removed.
+func Diff(before, after interface{}) string {
+	b, a := fields(before), fields(after)
+
+	names := make(map[string]struct{})
+	for name := range b {
+		names[name] = struct{}{}
+	}
+	for name := range a {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, name := range sorted {
+		prev, next := b[name], a[name]
+		if reflect.DeepEqual(prev, next) || (isEmpty(prev) && isEmpty(next)) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s", name, format(prev), format(next)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fields decodes the JSON representation of an entity into its top level
+// fields
+func fields(entity interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	if entity == nil || reflect.ValueOf(entity).Kind() == reflect.Ptr && reflect.ValueOf(entity).IsNil() {
+		return result
+	}
+	if data, err := json.Marshal(entity); err == nil {
+		json.Unmarshal(data, &result)
+	}
+	return result
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func format(value interface{}) string {
+	if value == nil {
+		return "<nil>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,54 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+type entity struct {
+	Name      string
+	Instances int
+	Tags      []string
+	Launch    string
+}
+
+func TestDiff(t *testing.T) {
+	before := &entity{Name: "web", Instances: 1}
+	after := &entity{Name: "web", Instances: 3, Tags: []string{"a"}}
+
+	for i, test := range []struct {
+		before, after interface{}
+		expected      string
+	}{
+		{before, after, "Instances: 1 -> 3\nTags: <nil> -> [\"a\"]"},
+		{before, before, ""},
+		{nil, before, "Instances: <nil> -> 1\nName: <nil> -> \"web\""},
+		{before, nil, "Instances: 1 -> <nil>\nName: \"web\" -> <nil>"},
+		{(*entity)(nil), before, "Instances: <nil> -> 1\nName: <nil> -> \"web\""},
+	} {
+		if actual := Diff(test.before, test.after); actual != test.expected {
+			t.Errorf("Test %d: expected %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestValidEntity(t *testing.T) {
+	entry := Entry{ID: "id", Timestamp: time.Now(), Action: "add", EntityType: "service"}
+	if err := entry.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	entry.Timestamp = time.Time{}
+	if err := entry.ValidEntity(); err == nil {
+		t.Error("Expected error for an entry without a timestamp")
+	}
+
+	entry = Entry{Timestamp: time.Now()}
+	if err := entry.ValidEntity(); err == nil {
+		t.Error("Expected error for an empty entry")
+	}
+}
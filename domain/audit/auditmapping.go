@@ -0,0 +1,37 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore/elastic"
+)
+
+var (
+	mappingString = `
+{
+    "auditentry": {
+      "properties":{
+        "ID" :         {"type": "string", "index":"not_analyzed"},
+        "Timestamp" :  {"type": "date", "format" : "dateOptionalTime"},
+        "User":        {"type": "string", "index":"not_analyzed"},
+        "Source":      {"type": "string", "index":"not_analyzed"},
+        "Action":      {"type": "string", "index":"not_analyzed"},
+        "EntityType":  {"type": "string", "index":"not_analyzed"},
+        "EntityID":    {"type": "string", "index":"not_analyzed"},
+        "Diff":        {"type": "string", "index":"no"}
+      }
+    }
+}
+`
+	//MAPPING is the elastic mapping for an audit entry
+	MAPPING, mappingError = elastic.NewMapping(mappingString)
+)
+
+func init() {
+	if mappingError != nil {
+		glog.Fatalf("error creating audit entry mapping: %v", mappingError)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"github.com/zenoss/elastigo/search"
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLimit is the number of entries returned by a search without a limit
+const DefaultLimit = 100
+
+// Filter narrows down a search of the audit log. Empty fields match every
+// entry.
+type Filter struct {
+	EntityType string
+	EntityID   string
+	User       string
+	Since      time.Time
+	Limit      int
+}
+
+//NewStore creates an audit entry store
+func NewStore() *Store {
+	return &Store{}
+}
+
+//Store type for interacting with audit entry persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+//Search returns the entries that match the filter, newest first
+func (s *Store) Search(ctx datastore.Context, filter Filter) ([]*Entry, error) {
+	glog.V(3).Infof("Audit Store.Search %+v", filter)
+
+	terms := []string{"_exists_:ID"}
+	match := func(field, value string) {
+		if value != "" {
+			terms = append(terms, fmt.Sprintf("%s:%q", field, value))
+		}
+	}
+	match("EntityType", filter.EntityType)
+	match("EntityID", filter.EntityID)
+	match("User", filter.User)
+	if !filter.Since.IsZero() {
+		terms = append(terms, fmt.Sprintf("Timestamp:[%s TO *]", filter.Since.UTC().Format(time.RFC3339)))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Search(strings.Join(terms, " AND "))
+	search := search.Search("controlplane").Type(kind).Size(strconv.Itoa(limit)).Sort(search.Sort("Timestamp").Desc()).Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	return convert(results)
+}
+
+//Key creates a Key suitable for getting, putting and deleting audit entries
+func Key(id string) datastore.Key {
+	return datastore.NewKey(kind, id)
+}
+
+func convert(results datastore.Results) ([]*Entry, error) {
+	entries := make([]*Entry, results.Len())
+	for idx := range entries {
+		var entry Entry
+		if err := results.Get(idx, &entry); err != nil {
+			return nil, err
+		}
+		entries[idx] = &entry
+	}
+	return entries, nil
+}
+
+var kind = "auditentry"
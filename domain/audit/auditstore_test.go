@@ -0,0 +1,70 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/datastore/elastic"
+	. "gopkg.in/check.v1"
+
+	"testing"
+	"time"
+)
+
+// This plumbs gocheck into testing
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{
+	ElasticTest: elastic.ElasticTest{
+		Index:    "controlplane",
+		Mappings: []elastic.Mapping{MAPPING},
+	}})
+
+type S struct {
+	elastic.ElasticTest
+	ctx datastore.Context
+	as  *Store
+}
+
+func (s *S) SetUpTest(c *C) {
+	s.ElasticTest.SetUpTest(c)
+	datastore.Register(s.Driver())
+	s.ctx = datastore.Get()
+	s.as = NewStore()
+}
+
+func (s *S) Test_AuditSearch(t *C) {
+	now := time.Now().UTC()
+	entries := []Entry{
+		{ID: "entry1", Timestamp: now.Add(-2 * time.Hour), User: "alice", Source: "CLI", Action: "add", EntityType: "service", EntityID: "svc-1"},
+		{ID: "entry2", Timestamp: now.Add(-time.Hour), User: "bob", Source: "REST", Action: "update", EntityType: "service", EntityID: "svc-1"},
+		{ID: "entry3", Timestamp: now, User: "alice", Source: "CLI", Action: "remove", EntityType: "host", EntityID: "host-1"},
+	}
+	for i := range entries {
+		defer s.as.Delete(s.ctx, Key(entries[i].ID))
+		if err := s.as.Put(s.ctx, Key(entries[i].ID), &entries[i]); err != nil {
+			t.Fatalf("Unexpected failure creating entry %+v: %v", entries[i], err)
+		}
+	}
+
+	match := func(filter Filter, ids ...string) {
+		result, err := s.as.Search(s.ctx, filter)
+		t.Assert(err, IsNil)
+		var actual []string
+		for _, entry := range result {
+			actual = append(actual, entry.ID)
+		}
+		t.Assert(actual, DeepEquals, ids, Commentf("filter %+v", filter))
+	}
+
+	match(Filter{}, "entry3", "entry2", "entry1")
+	match(Filter{Limit: 1}, "entry3")
+	match(Filter{EntityType: "service"}, "entry2", "entry1")
+	match(Filter{EntityType: "service", EntityID: "svc-1", User: "bob"}, "entry2")
+	match(Filter{User: "alice"}, "entry3", "entry1")
+	match(Filter{Since: now.Add(-90 * time.Minute)}, "entry3", "entry2")
+}
@@ -0,0 +1,28 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/validation"
+)
+
+//ValidEntity validates Entry fields
+func (e *Entry) ValidEntity() error {
+	glog.V(4).Info("Validating audit Entry")
+
+	violations := validation.NewValidationError()
+	violations.Add(validation.NotEmpty("Entry.ID", e.ID))
+	violations.Add(validation.NotEmpty("Entry.Action", e.Action))
+	violations.Add(validation.NotEmpty("Entry.EntityType", e.EntityType))
+	if e.Timestamp.IsZero() {
+		violations.AddViolation("Entry.Timestamp must be set")
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
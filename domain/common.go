@@ -7,6 +7,11 @@ package domain
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -34,23 +39,52 @@ func (minmax *MinMax) Validate() error {
 	return nil
 }
 
+// Types of health checks
+const (
+	HealthCheckExec = "exec" // runs Script; the default
+	HealthCheckHTTP = "http" // requests URL and checks the status and the body of the response
+	HealthCheckTCP  = "tcp"  // connects to Address
+)
+
+// DefaultHealthCheckTimeout is how long a health check without a timeout or an
+// interval may take
+const DefaultHealthCheckTimeout = 30 * time.Second
+
 // HealthCheck is a health check object
 type HealthCheck struct {
-	Script   string        // A script to execute to verify the health of a service.
-	Interval time.Duration // The interval at which to execute the script.
+	Type           string        // The type of the check: exec, http or tcp. exec if empty.
+	Script         string        // A script to execute to verify the health of a service.
+	URL            string        // The URL an http check requests.
+	ExpectedStatus int           // The status code an http check expects; 200 if 0.
+	ExpectedBody   string        // A regular expression the body of the response must match, if set.
+	Address        string        // The host:port a tcp check connects to.
+	Interval       time.Duration // The interval at which to execute the check.
+	Timeout        time.Duration // How long the check may take; the interval if 0.
 }
 
 type jsonHealthCheck struct {
-	Script   string
-	Interval float64 // the serialzed version will be in seconds
+	Type           string  `json:",omitempty"`
+	Script         string  `json:",omitempty"`
+	URL            string  `json:",omitempty"`
+	ExpectedStatus int     `json:",omitempty"`
+	ExpectedBody   string  `json:",omitempty"`
+	Address        string  `json:",omitempty"`
+	Interval       float64 // the serialzed version will be in seconds
+	Timeout        float64 `json:",omitempty"` // in seconds too
 }
 
 func (hc HealthCheck) MarshalJSON() ([]byte, error) {
 	// in json, the interval is represented in seconds
 	interval := float64(hc.Interval) / 1000000000.0
 	return json.Marshal(jsonHealthCheck{
-		Script:   hc.Script,
-		Interval: interval,
+		Type:           hc.Type,
+		Script:         hc.Script,
+		URL:            hc.URL,
+		ExpectedStatus: hc.ExpectedStatus,
+		ExpectedBody:   hc.ExpectedBody,
+		Address:        hc.Address,
+		Interval:       interval,
+		Timeout:        float64(hc.Timeout) / 1000000000.0,
 	})
 }
 
@@ -59,17 +93,94 @@ func (hc *HealthCheck) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &tempHc); err != nil {
 		return err
 	}
+	hc.Type = tempHc.Type
 	hc.Script = tempHc.Script
+	hc.URL = tempHc.URL
+	hc.ExpectedStatus = tempHc.ExpectedStatus
+	hc.ExpectedBody = tempHc.ExpectedBody
+	hc.Address = tempHc.Address
 	// interval in js is in seconds, convert to nanoseconds, then duration
 	hc.Interval = time.Duration(tempHc.Interval * 1000000000.0)
+	hc.Timeout = time.Duration(tempHc.Timeout * 1000000000.0)
+	return nil
+}
+
+// Kind returns the type of the health check, which is exec if it has none
+func (hc HealthCheck) Kind() string {
+	if hc.Type == "" {
+		return HealthCheckExec
+	}
+	return hc.Type
+}
+
+// CheckTimeout returns how long a run of the health check may take before it
+// fails: its timeout, or else its interval
+func (hc HealthCheck) CheckTimeout() time.Duration {
+	if hc.Timeout > 0 {
+		return hc.Timeout
+	} else if hc.Interval > 0 {
+		return hc.Interval
+	}
+	return DefaultHealthCheckTimeout
+}
+
+// ExpectedStatusCode returns the status code an http health check expects
+func (hc HealthCheck) ExpectedStatusCode() int {
+	if hc.ExpectedStatus == 0 {
+		return http.StatusOK
+	}
+	return hc.ExpectedStatus
+}
+
+// ValidEntity checks that the health check has what its type needs. The URL
+// and the address are only checked when they are not templates.
+func (hc HealthCheck) ValidEntity() error {
+	if hc.Interval < 0 || hc.Timeout < 0 {
+		return fmt.Errorf("health check cannot have a negative interval or timeout")
+	} else if hc.Interval > 0 && hc.Timeout > hc.Interval {
+		return fmt.Errorf("health check timeout cannot be longer than its interval")
+	}
+	switch hc.Kind() {
+	case HealthCheckExec:
+		if hc.Script == "" {
+			return fmt.Errorf("exec health check requires a script")
+		}
+	case HealthCheckHTTP:
+		if hc.URL == "" {
+			return fmt.Errorf("http health check requires a URL")
+		} else if !strings.Contains(hc.URL, "{{") {
+			if u, err := url.Parse(hc.URL); err != nil {
+				return fmt.Errorf("http health check has an invalid URL: %s", err)
+			} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("http health check requires an http or https URL with a host: %s", hc.URL)
+			}
+		}
+		if hc.ExpectedStatus != 0 && (hc.ExpectedStatus < 100 || hc.ExpectedStatus > 599) {
+			return fmt.Errorf("http health check has an invalid expected status: %d", hc.ExpectedStatus)
+		}
+		if _, err := regexp.Compile(hc.ExpectedBody); err != nil {
+			return fmt.Errorf("http health check has an invalid expected body: %s", err)
+		}
+	case HealthCheckTCP:
+		if hc.Address == "" {
+			return fmt.Errorf("tcp health check requires an address")
+		} else if !strings.Contains(hc.Address, "{{") {
+			if _, _, err := net.SplitHostPort(hc.Address); err != nil {
+				return fmt.Errorf("tcp health check has an invalid address: %s", err)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid health check type %q", hc.Type)
+	}
 	return nil
 }
 
 type HealthCheckResult struct {
-	ServiceID string
-	Name      string
-	Timestamp string
-	Passed    string
+	ServiceID  string
+	InstanceID int
+	Name       string
+	Timestamp  string
+	Passed     string
 }
 
 type Prereq struct {
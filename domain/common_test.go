@@ -35,3 +35,57 @@ func TestHealthCheck(t *testing.T) {
 	}
 
 }
+
+func TestHealthCheckTypes(t *testing.T) {
+	hc := HealthCheck{Type: HealthCheckHTTP, URL: "http://localhost:8080/ping", ExpectedBody: "^ok", Interval: 10 * time.Second, Timeout: 2 * time.Second}
+	data, err := json.Marshal(hc)
+	if err != nil {
+		t.Fatalf("could not marshal test health check: %s", err)
+	}
+	expected := `{"Type":"http","URL":"http://localhost:8080/ping","ExpectedBody":"^ok","Interval":10,"Timeout":2}`
+	if string(data) != expected {
+		t.Errorf("%s does not equal to %s", data, expected)
+	}
+	var actual HealthCheck
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("Could not unmarshal test health check: %s", err)
+	} else if actual != hc {
+		t.Errorf("test hc values is not equal: %v vs %v", actual, hc)
+	}
+
+	if testHc.Kind() != HealthCheckExec || testHc.CheckTimeout() != testHc.Interval {
+		t.Errorf("unexpected defaults of %v: %s, %s", testHc, testHc.Kind(), testHc.CheckTimeout())
+	}
+	if hc.CheckTimeout() != hc.Timeout || hc.ExpectedStatusCode() != 200 {
+		t.Errorf("unexpected defaults of %v: %s, %d", hc, hc.CheckTimeout(), hc.ExpectedStatusCode())
+	}
+
+	valid := []HealthCheck{
+		testHc,
+		hc,
+		{Type: HealthCheckHTTP, URL: "http://localhost:{{(index .Endpoints \"web\").Port}}/ping", ExpectedStatus: 204},
+		{Type: HealthCheckTCP, Address: "localhost:5432"},
+	}
+	for _, hc := range valid {
+		if err := hc.ValidEntity(); err != nil {
+			t.Errorf("unexpected error validating %+v: %s", hc, err)
+		}
+	}
+	invalid := []HealthCheck{
+		{Interval: time.Second},
+		{Type: "ping", Script: "foo"},
+		{Script: "foo", Timeout: -time.Second},
+		{Script: "foo", Interval: time.Second, Timeout: 2 * time.Second},
+		{Type: HealthCheckHTTP},
+		{Type: HealthCheckHTTP, URL: "localhost:8080/ping"},
+		{Type: HealthCheckHTTP, URL: "http://localhost/", ExpectedStatus: 1000},
+		{Type: HealthCheckHTTP, URL: "http://localhost/", ExpectedBody: "(ok"},
+		{Type: HealthCheckTCP},
+		{Type: HealthCheckTCP, Address: "localhost"},
+	}
+	for _, hc := range invalid {
+		if err := hc.ValidEntity(); err == nil {
+			t.Errorf("expected an error validating %+v", hc)
+		}
+	}
+}
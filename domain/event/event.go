@@ -0,0 +1,50 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"time"
+)
+
+// Types of entities that thresholds are evaluated for
+const (
+	EntityHost    = "host"
+	EntityPool    = "pool"
+	EntityService = "service"
+)
+
+// Event records that a threshold of the monitoring profile of an entity was
+// breached. The event stays active while the threshold is breached, and is
+// cleared once the threshold is no longer breached.
+type Event struct {
+	ID          string
+	EntityType  string // host, pool or service
+	EntityID    string
+	PoolID      string
+	ThresholdID string
+	Threshold   string                 // name of the threshold
+	Metric      string                 // data point that breached the threshold
+	Value       float64                // value of the data point that breached the threshold
+	Summary     string                 // how the threshold was breached
+	Tags        map[string]interface{} // event tags of the threshold
+	Count       int                    // number of evaluations that breached the threshold
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Cleared     time.Time // when the threshold stopped being breached
+	Active      bool
+}
+
+// Severity returns the severity in the event tags, or 0 if there is none
+func (e *Event) Severity() int {
+	switch s := e.Tags["Severity"].(type) {
+	case int:
+		return s
+	case int64:
+		return int(s)
+	case float64:
+		return int(s)
+	}
+	return 0
+}
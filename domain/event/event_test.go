@@ -0,0 +1,54 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidEntity(t *testing.T) {
+	now := time.Now()
+	e := Event{ID: "id", EntityType: EntityHost, EntityID: "host-1", ThresholdID: "swap.empty", FirstSeen: now, LastSeen: now, Active: true}
+	if err := e.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	e.Active = false
+	if err := e.ValidEntity(); err == nil {
+		t.Error("Expected error for an inactive event that was not cleared")
+	}
+	e.Cleared = now
+	if err := e.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	e.EntityType = "user"
+	if err := e.ValidEntity(); err == nil {
+		t.Error("Expected error for an event of an unknown type of entity")
+	}
+
+	e = Event{FirstSeen: now, LastSeen: now, Active: true}
+	if err := e.ValidEntity(); err == nil {
+		t.Error("Expected error for an empty event")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	for i, test := range []struct {
+		tags     map[string]interface{}
+		expected int
+	}{
+		{nil, 0},
+		{map[string]interface{}{"Severity": 3}, 3},
+		{map[string]interface{}{"Severity": float64(4)}, 4},
+		{map[string]interface{}{"Severity": "high"}, 0},
+	} {
+		e := Event{Tags: test.tags}
+		if actual := e.Severity(); actual != test.expected {
+			t.Errorf("Test %d: expected %d, got %d", i, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore/elastic"
+)
+
+var (
+	mappingString = `
+{
+    "event": {
+      "properties":{
+        "ID" :          {"type": "string", "index":"not_analyzed"},
+        "EntityType":   {"type": "string", "index":"not_analyzed"},
+        "EntityID":     {"type": "string", "index":"not_analyzed"},
+        "PoolID":       {"type": "string", "index":"not_analyzed"},
+        "ThresholdID":  {"type": "string", "index":"not_analyzed"},
+        "Threshold":    {"type": "string", "index":"not_analyzed"},
+        "Metric":       {"type": "string", "index":"not_analyzed"},
+        "Value":        {"type": "double"},
+        "Summary":      {"type": "string", "index":"no"},
+        "Tags":         {"type": "object", "enabled": false},
+        "Count":        {"type": "long"},
+        "FirstSeen" :   {"type": "date", "format" : "dateOptionalTime"},
+        "LastSeen" :    {"type": "date", "format" : "dateOptionalTime"},
+        "Cleared" :     {"type": "date", "format" : "dateOptionalTime"},
+        "Active":       {"type": "boolean"}
+      }
+    }
+}
+`
+	//MAPPING is the elastic mapping for a threshold event
+	MAPPING, mappingError = elastic.NewMapping(mappingString)
+)
+
+func init() {
+	if mappingError != nil {
+		glog.Fatalf("error creating event mapping: %v", mappingError)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"github.com/zenoss/elastigo/search"
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLimit is the number of events returned by a search without a limit
+const DefaultLimit = 100
+
+// Filter narrows down a search of the events. Empty fields match every event.
+type Filter struct {
+	EntityType string
+	EntityID   string
+	PoolID     string
+	Active     bool      // only match the events that are not cleared
+	Since      time.Time // only match the events seen since
+	Limit      int
+}
+
+//NewStore creates an event store
+func NewStore() *Store {
+	return &Store{}
+}
+
+//Store type for interacting with event persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+//Search returns the events that match the filter, most recently seen first
+func (s *Store) Search(ctx datastore.Context, filter Filter) ([]*Event, error) {
+	glog.V(3).Infof("Event Store.Search %+v", filter)
+
+	terms := []string{"_exists_:ID"}
+	match := func(field, value string) {
+		if value != "" {
+			terms = append(terms, fmt.Sprintf("%s:%q", field, value))
+		}
+	}
+	match("EntityType", filter.EntityType)
+	match("EntityID", filter.EntityID)
+	match("PoolID", filter.PoolID)
+	if filter.Active {
+		terms = append(terms, "Active:true")
+	}
+	if !filter.Since.IsZero() {
+		terms = append(terms, fmt.Sprintf("LastSeen:[%s TO *]", filter.Since.UTC().Format(time.RFC3339)))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Search(strings.Join(terms, " AND "))
+	search := search.Search("controlplane").Type(kind).Size(strconv.Itoa(limit)).Sort(search.Sort("LastSeen").Desc()).Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	return convert(results)
+}
+
+//GetActive returns the active event of a threshold of an entity, or nil if
+//the threshold is not breached
+func (s *Store) GetActive(ctx datastore.Context, entityType, entityID, thresholdID string) (*Event, error) {
+	glog.V(3).Infof("Event Store.GetActive %s %s %s", entityType, entityID, thresholdID)
+	queryString := fmt.Sprintf("_exists_:ID AND Active:true AND EntityType:%q AND EntityID:%q AND ThresholdID:%q", entityType, entityID, thresholdID)
+
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Search(queryString)
+	search := search.Search("controlplane").Type(kind).Size("1").Sort(search.Sort("LastSeen").Desc()).Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	events, err := convert(results)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	return events[0], nil
+}
+
+//Key creates a Key suitable for getting, putting and deleting events
+func Key(id string) datastore.Key {
+	return datastore.NewKey(kind, id)
+}
+
+func convert(results datastore.Results) ([]*Event, error) {
+	events := make([]*Event, results.Len())
+	for idx := range events {
+		var e Event
+		if err := results.Get(idx, &e); err != nil {
+			return nil, err
+		}
+		events[idx] = &e
+	}
+	return events, nil
+}
+
+var kind = "event"
@@ -0,0 +1,79 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/datastore/elastic"
+	. "gopkg.in/check.v1"
+
+	"testing"
+	"time"
+)
+
+// This plumbs gocheck into testing
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{
+	ElasticTest: elastic.ElasticTest{
+		Index:    "controlplane",
+		Mappings: []elastic.Mapping{MAPPING},
+	}})
+
+type S struct {
+	elastic.ElasticTest
+	ctx datastore.Context
+	es  *Store
+}
+
+func (s *S) SetUpTest(c *C) {
+	s.ElasticTest.SetUpTest(c)
+	datastore.Register(s.Driver())
+	s.ctx = datastore.Get()
+	s.es = NewStore()
+}
+
+func (s *S) Test_EventSearch(t *C) {
+	now := time.Now().UTC()
+	events := []Event{
+		{ID: "event1", EntityType: EntityHost, EntityID: "host-1", PoolID: "default", ThresholdID: "swap.empty", FirstSeen: now.Add(-3 * time.Hour), LastSeen: now.Add(-2 * time.Hour), Cleared: now.Add(-2 * time.Hour)},
+		{ID: "event2", EntityType: EntityService, EntityID: "svc-1", PoolID: "default", ThresholdID: "cpu.high", FirstSeen: now.Add(-time.Hour), LastSeen: now.Add(-time.Hour), Active: true, Tags: map[string]interface{}{"Severity": 3}},
+		{ID: "event3", EntityType: EntityHost, EntityID: "host-1", PoolID: "other", ThresholdID: "swap.empty", FirstSeen: now, LastSeen: now, Active: true},
+	}
+	for i := range events {
+		defer s.es.Delete(s.ctx, Key(events[i].ID))
+		if err := s.es.Put(s.ctx, Key(events[i].ID), &events[i]); err != nil {
+			t.Fatalf("Unexpected failure creating event %+v: %v", events[i], err)
+		}
+	}
+
+	match := func(filter Filter, ids ...string) {
+		result, err := s.es.Search(s.ctx, filter)
+		t.Assert(err, IsNil)
+		var actual []string
+		for _, e := range result {
+			actual = append(actual, e.ID)
+		}
+		t.Assert(actual, DeepEquals, ids, Commentf("filter %+v", filter))
+	}
+
+	match(Filter{}, "event3", "event2", "event1")
+	match(Filter{Limit: 1}, "event3")
+	match(Filter{Active: true}, "event3", "event2")
+	match(Filter{EntityType: EntityHost, EntityID: "host-1"}, "event3", "event1")
+	match(Filter{PoolID: "default"}, "event2", "event1")
+	match(Filter{Since: now.Add(-90 * time.Minute)}, "event3", "event2")
+
+	active, err := s.es.GetActive(s.ctx, EntityHost, "host-1", "swap.empty")
+	t.Assert(err, IsNil)
+	t.Assert(active, NotNil)
+	t.Assert(active.ID, Equals, "event3")
+
+	active, err = s.es.GetActive(s.ctx, EntityService, "svc-1", "swap.empty")
+	t.Assert(err, IsNil)
+	t.Assert(active, IsNil)
+}
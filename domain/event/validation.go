@@ -0,0 +1,36 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package event
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/validation"
+)
+
+//ValidEntity validates Event fields
+func (e *Event) ValidEntity() error {
+	glog.V(4).Info("Validating Event")
+
+	violations := validation.NewValidationError()
+	violations.Add(validation.NotEmpty("Event.ID", e.ID))
+	violations.Add(validation.NotEmpty("Event.EntityID", e.EntityID))
+	violations.Add(validation.NotEmpty("Event.ThresholdID", e.ThresholdID))
+	switch e.EntityType {
+	case EntityHost, EntityPool, EntityService:
+	default:
+		violations.AddViolation("Event.EntityType must be host, pool or service")
+	}
+	if e.FirstSeen.IsZero() || e.LastSeen.Before(e.FirstSeen) {
+		violations.AddViolation("Event.LastSeen must not be before Event.FirstSeen")
+	}
+	if e.Active != e.Cleared.IsZero() {
+		violations.AddViolation("Event.Cleared must be set once the event is no longer active")
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
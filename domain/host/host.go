@@ -26,7 +26,8 @@ type Host struct {
 	PrivateNetwork    string // The private network where containers run, eg 172.16.42.0/24
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
-	IPs               []HostIPResource // The static IP resources available on the host
+	IPs               []HostIPResource  // The static IP resources available on the host
+	Labels            map[string]string // Key/value labels used to select hosts when scheduling
 	KernelVersion     string
 	KernelRelease     string
 	ServiceD struct {
@@ -72,6 +73,9 @@ func (a *Host) Equals(b *Host) bool {
 	if !reflect.DeepEqual(a.IPs, b.IPs) {
 		return false
 	}
+	if !labelsEqual(a.Labels, b.Labels) {
+		return false
+	}
 	if a.CreatedAt.Unix() != b.CreatedAt.Unix() {
 		return false
 	}
@@ -88,6 +92,25 @@ func (a *Host) Equals(b *Host) bool {
 	return true
 }
 
+// labelsEqual is true if both hosts have the same labels with the same values;
+// no labels and an empty map are the same
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	for key, value := range b {
+		if other, ok := a[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
 //HostIPResource contains information about a specific IP available as a resource
 type HostIPResource struct {
 	HostID        string
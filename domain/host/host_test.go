@@ -197,3 +197,26 @@ func Test_getOSKernelData(t *testing.T) {
 
 	glog.Infof("Kernel Version:  %v Kernel Release: %v", kernelVersion, kernelRelease)
 }
+
+func Test_EqualsLabels(t *testing.T) {
+	a := &Host{ID: "host", Labels: map[string]string{"zone": "east"}}
+	b := &Host{ID: "host", Labels: map[string]string{"zone": "east"}}
+	if !a.Equals(b) || !b.Equals(a) {
+		t.Errorf("Expected hosts with the same labels to be equal")
+	}
+
+	b.Labels["rack"] = "1"
+	if a.Equals(b) || b.Equals(a) {
+		t.Errorf("Expected hosts to differ when only one has a label")
+	}
+
+	b.Labels = map[string]string{"zone": "west"}
+	if a.Equals(b) || b.Equals(a) {
+		t.Errorf("Expected hosts to differ when a label has another value")
+	}
+
+	a.Labels, b.Labels = nil, map[string]string{}
+	if !a.Equals(b) || !b.Equals(a) {
+		t.Errorf("Expected no labels to equal an empty map of labels")
+	}
+}
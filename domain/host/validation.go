@@ -23,6 +23,11 @@ func (h *Host) ValidEntity() error {
 	violations.Add(validation.StringsEqual(h.ID, trimmedID, "leading and trailing spaces not allowed for host id"))
 	violations.Add(validation.NotEmpty("Host.PoolID", h.PoolID))
 	violations.Add(validation.IsIP(h.IPAddr))
+	for key := range h.Labels {
+		if strings.TrimSpace(key) == "" {
+			violations.Add(errors.New("host label names cannot be empty"))
+		}
+	}
 
 	//TODO: what should we be validating here? It doesn't seem to work for
 	glog.V(4).Infof("Validating IPAddr %v for host %s", h.IPAddr, h.ID)
@@ -0,0 +1,73 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package notification configures where the notifications about the health
+// checks and threshold events of a pool or tenant are sent.
+package notification
+
+import (
+	"time"
+)
+
+// Types of sinks
+const (
+	TypeWebhook = "webhook"
+	TypeSMTP    = "smtp"
+	TypeSyslog  = "syslog"
+)
+
+// Sink sends the notifications about the entities of a pool, or about the
+// services of a tenant, to a webhook, by email or to syslog
+type Sink struct {
+	ID       string
+	Name     string
+	Type     string   // webhook, smtp or syslog
+	PoolID   string   // notifications about the hosts and services of this pool
+	TenantID string   // or about the services of this tenant
+	Webhook  *Webhook `json:",omitempty"`
+	SMTP     *SMTP    `json:",omitempty"`
+	Syslog   *Syslog  `json:",omitempty"`
+	Created  time.Time
+}
+
+// Webhook sends a request to a URL for each notification
+type Webhook struct {
+	URL      string
+	Method   string            // defaults to POST
+	Headers  map[string]string // headers added to each request
+	Template string            // text/template of the payload; the notification as JSON if empty
+}
+
+// SMTP emails each notification
+type SMTP struct {
+	Server   string // host:port of the mail server
+	From     string
+	To       []string
+	Username string // authenticates with PLAIN auth if set
+	Password string
+}
+
+// Syslog writes each notification to syslog
+type Syslog struct {
+	Network string // udp or tcp; the local syslog if empty
+	Address string // host:port of a remote syslog
+	Tag     string // defaults to serviced
+}
+
+// Matches is true if the sink sends the notifications about the pool or the
+// tenant
+func (s *Sink) Matches(poolID, tenantID string) bool {
+	return (s.PoolID != "" && s.PoolID == poolID) || (s.TenantID != "" && s.TenantID == tenantID)
+}
+
+// WithoutSecrets returns a copy of the sink without its password
+func (s *Sink) WithoutSecrets() *Sink {
+	c := *s
+	if s.SMTP != nil && s.SMTP.Password != "" {
+		smtp := *s.SMTP
+		smtp.Password = ""
+		c.SMTP = &smtp
+	}
+	return &c
+}
@@ -0,0 +1,63 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notification
+
+import (
+	"testing"
+)
+
+func TestValidEntity(t *testing.T) {
+	for i, test := range []struct {
+		sink  Sink
+		valid bool
+	}{
+		{Sink{ID: "1", Name: "ops", Type: TypeWebhook, PoolID: "default", Webhook: &Webhook{URL: "https://hooks.example.com/serviced"}}, true},
+		{Sink{ID: "1", Name: "ops", Type: TypeWebhook, PoolID: "default", Webhook: &Webhook{URL: "https://hooks.example.com", Template: `{"text": "{{.Summary}}"}`}}, true},
+		{Sink{ID: "1", Name: "ops", Type: TypeWebhook, PoolID: "default", Webhook: &Webhook{URL: "https://hooks.example.com", Template: `{{.Summary`}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeWebhook, PoolID: "default", Webhook: &Webhook{URL: "ftp://example.com"}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeWebhook, PoolID: "default"}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "cc@example.com", To: []string{"ops@example.com"}}}, true},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail", From: "cc@example.com", To: []string{"ops@example.com"}}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "cc@example.com"}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "cc@example.com\r\nBcc: all@example.com", To: []string{"ops@example.com"}}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "cc@example.com", To: []string{"ops@example.com\nBcc: all@example.com"}}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "cc@example.com", To: []string{"ops@example.com", "not an address"}}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "Control Center <cc@example.com>", To: []string{"ops@example.com"}}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSyslog, PoolID: "default", Syslog: &Syslog{}}, true},
+		{Sink{ID: "1", Name: "ops", Type: TypeSyslog, PoolID: "default", Syslog: &Syslog{Network: "udp", Address: "loghost:514"}}, true},
+		{Sink{ID: "1", Name: "ops", Type: TypeSyslog, PoolID: "default", Syslog: &Syslog{Address: "loghost:514"}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSyslog, PoolID: "default", TenantID: "tenant", Syslog: &Syslog{}}, false},
+		{Sink{ID: "1", Name: "ops", Type: TypeSyslog, Syslog: &Syslog{}}, false},
+		{Sink{ID: "1", Name: "ops", Type: "pager", PoolID: "default"}, false},
+		{Sink{Type: TypeSyslog, PoolID: "default", Syslog: &Syslog{}}, false},
+	} {
+		if err := test.sink.ValidEntity(); test.valid && err != nil {
+			t.Errorf("Test %d: unexpected error: %v", i, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("Test %d: expected an error", i)
+		}
+	}
+}
+
+func TestSink_Matches(t *testing.T) {
+	pool := Sink{PoolID: "default"}
+	tenant := Sink{TenantID: "tenant"}
+	if !pool.Matches("default", "") || !pool.Matches("default", "tenant") || pool.Matches("other", "") {
+		t.Errorf("Unexpected matches of a pool sink")
+	}
+	if !tenant.Matches("default", "tenant") || tenant.Matches("default", "") || tenant.Matches("", "") {
+		t.Errorf("Unexpected matches of a tenant sink")
+	}
+}
+
+func TestSink_WithoutSecrets(t *testing.T) {
+	sink := Sink{ID: "1", SMTP: &SMTP{Username: "cc", Password: "secret"}}
+	if s := sink.WithoutSecrets(); s.SMTP.Password != "" || s.SMTP.Username != "cc" {
+		t.Errorf("Unexpected sink without secrets %+v", s.SMTP)
+	}
+	if sink.SMTP.Password != "secret" {
+		t.Errorf("Expected the sink to keep its password")
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notification
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore/elastic"
+)
+
+var (
+	mappingString = `
+{
+    "notificationsink": {
+      "properties":{
+        "ID" :         {"type": "string", "index":"not_analyzed"},
+        "Name":        {"type": "string", "index":"not_analyzed"},
+        "Type":        {"type": "string", "index":"not_analyzed"},
+        "PoolID":      {"type": "string", "index":"not_analyzed"},
+        "TenantID":    {"type": "string", "index":"not_analyzed"},
+        "Webhook":     {"type": "object", "enabled": false},
+        "SMTP":        {"type": "object", "enabled": false},
+        "Syslog":      {"type": "object", "enabled": false},
+        "Created" :    {"type": "date", "format" : "dateOptionalTime"}
+      }
+    }
+}
+`
+	//MAPPING is the elastic mapping for a notification sink
+	MAPPING, mappingError = elastic.NewMapping(mappingString)
+)
+
+func init() {
+	if mappingError != nil {
+		glog.Fatalf("error creating notification sink mapping: %v", mappingError)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notification
+
+import (
+	"github.com/zenoss/elastigo/search"
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+)
+
+//NewStore creates a notification sink store
+func NewStore() *Store {
+	return &Store{}
+}
+
+//Store type for interacting with notification sink persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+//GetSinks returns every notification sink
+func (s *Store) GetSinks(ctx datastore.Context) ([]*Sink, error) {
+	glog.V(3).Infof("Notification Store.GetSinks")
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Search("_exists_:ID")
+	search := search.Search("controlplane").Type(kind).Size("10000").Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	return convert(results)
+}
+
+//Key creates a Key suitable for getting, putting and deleting notification
+//sinks
+func Key(id string) datastore.Key {
+	return datastore.NewKey(kind, id)
+}
+
+func convert(results datastore.Results) ([]*Sink, error) {
+	sinks := make([]*Sink, results.Len())
+	for idx := range sinks {
+		var s Sink
+		if err := results.Get(idx, &s); err != nil {
+			return nil, err
+		}
+		sinks[idx] = &s
+	}
+	return sinks, nil
+}
+
+var kind = "notificationsink"
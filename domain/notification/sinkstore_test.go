@@ -0,0 +1,61 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notification
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/datastore/elastic"
+	. "gopkg.in/check.v1"
+
+	"testing"
+)
+
+// This plumbs gocheck into testing
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{
+	ElasticTest: elastic.ElasticTest{
+		Index:    "controlplane",
+		Mappings: []elastic.Mapping{MAPPING},
+	}})
+
+type S struct {
+	elastic.ElasticTest
+	ctx datastore.Context
+	ns  *Store
+}
+
+func (s *S) SetUpTest(c *C) {
+	s.ElasticTest.SetUpTest(c)
+	datastore.Register(s.Driver())
+	s.ctx = datastore.Get()
+	s.ns = NewStore()
+}
+
+func (s *S) Test_GetSinks(t *C) {
+	sinks := []Sink{
+		{ID: "sink1", Name: "ops", Type: TypeWebhook, PoolID: "default", Webhook: &Webhook{URL: "http://hooks.example.com", Headers: map[string]string{"X-Token": "abc"}}},
+		{ID: "sink2", Name: "mail", Type: TypeSMTP, TenantID: "tenant", SMTP: &SMTP{Server: "mail:25", From: "cc@example.com", To: []string{"ops@example.com"}}},
+	}
+	for i := range sinks {
+		defer s.ns.Delete(s.ctx, Key(sinks[i].ID))
+		if err := s.ns.Put(s.ctx, Key(sinks[i].ID), &sinks[i]); err != nil {
+			t.Fatalf("Unexpected failure creating sink %+v: %v", sinks[i], err)
+		}
+	}
+
+	result, err := s.ns.GetSinks(s.ctx)
+	t.Assert(err, IsNil)
+	t.Assert(len(result), Equals, 2)
+	for _, sink := range result {
+		if sink.ID == "sink1" {
+			t.Assert(sink.Webhook.Headers["X-Token"], Equals, "abc")
+		} else {
+			t.Assert(sink.SMTP.To, DeepEquals, []string{"ops@example.com"})
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notification
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/validation"
+
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+//ValidEntity validates Sink fields
+func (s *Sink) ValidEntity() error {
+	glog.V(4).Info("Validating notification Sink")
+
+	violations := validation.NewValidationError()
+	violations.Add(validation.NotEmpty("Sink.ID", s.ID))
+	violations.Add(validation.NotEmpty("Sink.Name", s.Name))
+	if (s.PoolID == "") == (s.TenantID == "") {
+		violations.AddViolation("Sink must send the notifications of either a pool or a tenant")
+	}
+
+	switch s.Type {
+	case TypeWebhook:
+		if s.Webhook == nil {
+			violations.AddViolation("Sink.Webhook must be set for a webhook sink")
+		} else {
+			violations.Add(s.Webhook.validate())
+		}
+	case TypeSMTP:
+		if s.SMTP == nil {
+			violations.AddViolation("Sink.SMTP must be set for an smtp sink")
+		} else {
+			violations.Add(s.SMTP.validate())
+		}
+	case TypeSyslog:
+		if s.Syslog == nil {
+			violations.AddViolation("Sink.Syslog must be set for a syslog sink")
+		} else {
+			violations.Add(s.Syslog.validate())
+		}
+	default:
+		violations.AddViolation(fmt.Sprintf("Sink.Type %q must be webhook, smtp or syslog", s.Type))
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
+
+func (w *Webhook) validate() error {
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL %q must be an http or https URL", w.URL)
+	}
+	if w.Template != "" {
+		if _, err := template.New("payload").Parse(w.Template); err != nil {
+			return fmt.Errorf("webhook template is not valid: %s", err)
+		}
+	}
+	return nil
+}
+
+func (m *SMTP) validate() error {
+	if _, _, err := net.SplitHostPort(m.Server); err != nil {
+		return fmt.Errorf("smtp server %q must be a host:port", m.Server)
+	} else if m.From == "" {
+		return fmt.Errorf("smtp sender must be set")
+	} else if !validAddress(m.From) {
+		return fmt.Errorf("smtp sender %q must be an email address", m.From)
+	} else if len(m.To) == 0 {
+		return fmt.Errorf("smtp sink needs at least one recipient")
+	}
+	for _, to := range m.To {
+		if !validAddress(to) {
+			return fmt.Errorf("smtp recipient %q must be an email address", to)
+		}
+	}
+	return nil
+}
+
+// validAddress is true for a bare email address, which can be used both in
+// the headers and in the envelope of an email
+func validAddress(address string) bool {
+	if strings.ContainsAny(address, "\r\n") {
+		return false
+	}
+	parsed, err := mail.ParseAddress(address)
+	return err == nil && parsed.Address == address
+}
+
+func (l *Syslog) validate() error {
+	switch l.Network {
+	case "":
+		if l.Address != "" {
+			return fmt.Errorf("syslog address %q needs a network (udp or tcp)", l.Address)
+		}
+	case "udp", "tcp":
+		if _, _, err := net.SplitHostPort(l.Address); err != nil {
+			return fmt.Errorf("syslog address %q must be a host:port", l.Address)
+		}
+	default:
+		return fmt.Errorf("syslog network %q must be udp or tcp", l.Network)
+	}
+	return nil
+}
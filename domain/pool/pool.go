@@ -31,6 +31,10 @@ type ResourcePool struct {
 	CoreCapacity      int         // Number of cores available as a sum of all cores on all hosts in the pool
 	MemoryCapacity    uint64      // Amount (bytes) of RAM available as a sum of all memory on all hosts in the pool
 	MemoryCommitment  uint64      // Amount (bytes) of RAM committed to services
+	MemoryWeight      float64     // Weight of uncommitted RAM when ranking hosts for scheduling
+	CoreWeight        float64     // Weight of uncommitted cores when ranking hosts for scheduling
+	MemoryOvercommit  float64     // Max ratio of RAM committed on a host to the host's RAM, 0 = unlimited
+	CoreOvercommit    float64     // Max ratio of cores committed on a host to the host's cores, 0 = unlimited
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 	MonitoringProfile domain.MonitorProfile
@@ -86,6 +90,18 @@ func (a *ResourcePool) Equals(b *ResourcePool) bool {
 	if a.MemoryCommitment != b.MemoryCommitment {
 		return false
 	}
+	if a.MemoryWeight != b.MemoryWeight {
+		return false
+	}
+	if a.CoreWeight != b.CoreWeight {
+		return false
+	}
+	if a.MemoryOvercommit != b.MemoryOvercommit {
+		return false
+	}
+	if a.CoreOvercommit != b.CoreOvercommit {
+		return false
+	}
 	if a.CreatedAt.Unix() != b.CreatedAt.Unix() {
 		return false
 	}
@@ -99,6 +115,15 @@ func (a *ResourcePool) Equals(b *ResourcePool) bool {
 	return true
 }
 
+// SchedulingWeights returns the weights of uncommitted RAM and cores used to
+// rank the hosts in the pool. If neither is set, both are weighted equally.
+func (a *ResourcePool) SchedulingWeights() (memory, cores float64) {
+	if a.MemoryWeight == 0 && a.CoreWeight == 0 {
+		return 1, 1
+	}
+	return a.MemoryWeight, a.CoreWeight
+}
+
 // New creates new ResourcePool
 func New(id string) *ResourcePool {
 	pool := &ResourcePool{}
@@ -23,7 +23,11 @@ var (
         "CreatedAt" :   {"type": "date", "format" : "dateOptionalTime"},
         "UpdatedAt" :   {"type": "date", "format" : "dateOptionalTime"},
         "CoreCapacity": {"type": "long", "format": "not_analyzed"},
-        "MemoryCapacity": {"type": "long", "format": "not_analyzed"}
+        "MemoryCapacity": {"type": "long", "format": "not_analyzed"},
+        "MemoryWeight":     {"type": "double", "index":"not_analyzed"},
+        "CoreWeight":       {"type": "double", "index":"not_analyzed"},
+        "MemoryOvercommit": {"type": "double", "index":"not_analyzed"},
+        "CoreOvercommit":   {"type": "double", "index":"not_analyzed"}
       }
     }
 }
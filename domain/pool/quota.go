@@ -0,0 +1,52 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package pool
+
+import (
+	"fmt"
+	"strings"
+)
+
+const quotaErrorPrefix = "resource pool quota exceeded"
+
+// ErrQuotaExceeded is returned when an operation would commit more RAM or
+// cores to a pool than the pool's MemoryLimit or CoreLimit allows.
+type ErrQuotaExceeded struct {
+	PoolID    string
+	Resource  string // "memory" or "cores"
+	Limit     uint64
+	Committed uint64 // already committed to other running services
+	Requested uint64
+}
+
+func (e ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("%s: pool %s %s limit is %d, %d committed, %d requested (%d over)",
+		quotaErrorPrefix, e.PoolID, e.Resource, e.Limit, e.Committed, e.Requested, e.Committed+e.Requested-e.Limit)
+}
+
+// IsErrQuotaExceeded checks if the error is an ErrQuotaExceeded. Errors that
+// have been passed over rpc lose their type, so the message is checked too.
+func IsErrQuotaExceeded(err error) bool {
+	switch err.(type) {
+	case nil:
+		return false
+	case ErrQuotaExceeded:
+		return true
+	}
+	return strings.HasPrefix(err.Error(), quotaErrorPrefix)
+}
+
+// CheckQuota returns an ErrQuotaExceeded if the requested RAM and cores,
+// on top of what is already committed, would exceed the pool's limits. A
+// limit of 0 is unlimited.
+func (a *ResourcePool) CheckQuota(memCommitted, memRequested, coresCommitted, coresRequested uint64) error {
+	if a.MemoryLimit > 0 && memCommitted+memRequested > a.MemoryLimit {
+		return ErrQuotaExceeded{a.ID, "memory", a.MemoryLimit, memCommitted, memRequested}
+	}
+	if a.CoreLimit > 0 && coresCommitted+coresRequested > uint64(a.CoreLimit) {
+		return ErrQuotaExceeded{a.ID, "cores", uint64(a.CoreLimit), coresCommitted, coresRequested}
+	}
+	return nil
+}
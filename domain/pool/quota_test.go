@@ -0,0 +1,56 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package pool
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckQuota(t *testing.T) {
+	p := New("quota")
+
+	// no limits
+	if err := p.CheckQuota(1<<40, 1<<40, 1000, 1000); err != nil {
+		t.Errorf("unexpected error with no limits: %s", err)
+	}
+
+	p.MemoryLimit = 1024
+	p.CoreLimit = 4
+	if err := p.CheckQuota(512, 512, 2, 2); err != nil {
+		t.Errorf("unexpected error at the limit: %s", err)
+	}
+
+	err := p.CheckQuota(512, 513, 0, 0)
+	if e, ok := err.(ErrQuotaExceeded); !ok {
+		t.Fatalf("expected ErrQuotaExceeded; got %v", err)
+	} else if e.Resource != "memory" || e.Limit != 1024 || e.Committed != 512 || e.Requested != 513 {
+		t.Errorf("unexpected error %+v", e)
+	}
+
+	err = p.CheckQuota(0, 0, 3, 2)
+	if e, ok := err.(ErrQuotaExceeded); !ok {
+		t.Fatalf("expected ErrQuotaExceeded; got %v", err)
+	} else if e.Resource != "cores" || e.Limit != 4 || e.Committed != 3 || e.Requested != 2 {
+		t.Errorf("unexpected error %+v", e)
+	}
+}
+
+func TestIsErrQuotaExceeded(t *testing.T) {
+	err := ErrQuotaExceeded{"default", "memory", 1024, 1024, 1}
+	if !IsErrQuotaExceeded(err) {
+		t.Errorf("expected %v to be a quota error", err)
+	}
+	// the message is all that is left after a round trip over rpc
+	if !IsErrQuotaExceeded(errors.New(err.Error())) {
+		t.Errorf("expected message %q to be a quota error", err.Error())
+	}
+	if IsErrQuotaExceeded(errors.New("some other error")) {
+		t.Errorf("unexpected quota error")
+	}
+	if IsErrQuotaExceeded(nil) {
+		t.Errorf("nil is not a quota error")
+	}
+}
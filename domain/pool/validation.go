@@ -8,6 +8,7 @@ import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/validation"
 
+	"errors"
 	"strings"
 )
 
@@ -19,6 +20,12 @@ func (p *ResourcePool) ValidEntity() error {
 	violations := validation.NewValidationError()
 	violations.Add(validation.NotEmpty("Pool.ID", p.ID))
 	violations.Add(validation.StringsEqual(p.ID, trimmedID, "leading and trailing spaces not allowed for pool id"))
+	if p.MemoryWeight < 0 || p.CoreWeight < 0 {
+		violations.Add(errors.New("scheduling weights cannot be negative"))
+	}
+	if p.MemoryOvercommit < 0 || p.CoreOvercommit < 0 {
+		violations.Add(errors.New("overcommit ratios cannot be negative"))
+	}
 
 	if len(violations.Errors) > 0 {
 		return violations
@@ -0,0 +1,182 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package role grants users roles that limit what they may do to the control
+// plane.
+package role
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles
+const (
+	Viewer   = "viewer"   // may look at everything
+	Operator = "operator" // may also start, stop, restart and snapshot services and take backups
+	Admin    = "admin"    // may also add, change and remove anything, including roles
+)
+
+// Actions
+const (
+	Read    = "read"
+	Operate = "operate"
+	Modify  = "modify"
+)
+
+// Types of resources
+const (
+	ResourceService  = "service"
+	ResourceHost     = "host"
+	ResourcePool     = "pool"
+	ResourceTemplate = "template"
+	ResourceSnapshot = "snapshot"
+	ResourceBackup   = "backup"
+	ResourceUser     = "user"
+	ResourceAudit    = "audit"
+	ResourceEvent    = "event"
+
+	// the credentials that containers authenticate with, which only
+	// administrators may read
+	ResourceCredentials = "credentials"
+)
+
+// Roles and resources that are recognized
+var (
+	Roles     = []string{Viewer, Operator, Admin}
+	Resources = []string{ResourceService, ResourceHost, ResourcePool, ResourceTemplate, ResourceSnapshot, ResourceBackup, ResourceUser, ResourceAudit, ResourceEvent, ResourceCredentials}
+)
+
+// DefaultUser names the roles of the users that have not been granted roles
+// of their own
+const DefaultUser = "*"
+
+// actions lists what each role is allowed to do
+var actions = map[string][]string{
+	Viewer:   {Read},
+	Operator: {Read, Operate},
+	Admin:    {Read, Operate, Modify},
+}
+
+const unauthorizedPrefix = "permission denied"
+
+// ErrUnauthorized is returned when a user is not allowed to make a request
+type ErrUnauthorized struct {
+	User    string
+	Request Request
+}
+
+func (e ErrUnauthorized) Error() string {
+	return fmt.Sprintf("%s: user %q may not %s %s", unauthorizedPrefix, e.User, e.Request.Action, e.Request)
+}
+
+// IsErrUnauthorized checks if the error is an ErrUnauthorized. Errors that
+// have been passed over rpc lose their type, so the message is checked too.
+func IsErrUnauthorized(err error) bool {
+	switch err.(type) {
+	case nil:
+		return false
+	case ErrUnauthorized:
+		return true
+	}
+	return strings.HasPrefix(err.Error(), unauthorizedPrefix)
+}
+
+// Request describes what a user is trying to do. Requests about a service or
+// host are scoped to the pool and tenant that the service or host belongs to.
+type Request struct {
+	Action    string
+	Resource  string
+	PoolID    string
+	TenantID  string
+	ServiceID string
+	HostID    string
+}
+
+// String describes the resource the request is about
+func (r Request) String() string {
+	s := r.Resource + "s"
+	if r.PoolID != "" {
+		s += " in pool " + r.PoolID
+	}
+	if r.TenantID != "" {
+		s += " of tenant " + r.TenantID
+	}
+	return s
+}
+
+// Grant gives a role on a type of resource, optionally limited to a pool or
+// a tenant. Empty fields match everything.
+type Grant struct {
+	Role     string
+	Resource string
+	PoolID   string
+	TenantID string
+}
+
+// Allows reports whether the grant permits the request. Grants limited to a
+// pool or tenant only permit reading resources that are not in a particular
+// pool or tenant, such as lists of them.
+func (g Grant) Allows(r Request) bool {
+	if g.Resource != "" && g.Resource != r.Resource {
+		return false
+	}
+	if !scopeMatches(g.PoolID, r.PoolID, r.Action) || !scopeMatches(g.TenantID, r.TenantID, r.Action) {
+		return false
+	}
+	for _, action := range actions[g.Role] {
+		if action == r.Action {
+			return true
+		}
+	}
+	return false
+}
+
+func scopeMatches(granted, requested, action string) bool {
+	switch {
+	case granted == "" || granted == requested:
+		return true
+	case requested == "":
+		return action == Read
+	}
+	return false
+}
+
+// UserRoles holds the roles granted to a user
+type UserRoles struct {
+	User   string
+	Grants []Grant
+}
+
+// Allows reports whether any of the user's grants permits the request
+func (u *UserRoles) Allows(r Request) bool {
+	for _, g := range u.Grants {
+		if g.Allows(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add adds the grant, unless the user already has it
+func (u *UserRoles) Add(grant Grant) bool {
+	for _, g := range u.Grants {
+		if g == grant {
+			return false
+		}
+	}
+	u.Grants = append(u.Grants, grant)
+	return true
+}
+
+// Remove removes the grant, if the user has it
+func (u *UserRoles) Remove(grant Grant) bool {
+	for i, g := range u.Grants {
+		if g == grant {
+			u.Grants = append(u.Grants[:i], u.Grants[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
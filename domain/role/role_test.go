@@ -0,0 +1,96 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package role
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGrantAllows(t *testing.T) {
+	for i, test := range []struct {
+		grant    Grant
+		request  Request
+		expected bool
+	}{
+		{Grant{Role: Viewer}, Request{Action: Read, Resource: ResourceService}, true},
+		{Grant{Role: Viewer}, Request{Action: Operate, Resource: ResourceService}, false},
+		{Grant{Role: Operator}, Request{Action: Operate, Resource: ResourceService}, true},
+		{Grant{Role: Operator}, Request{Action: Modify, Resource: ResourceService}, false},
+		{Grant{Role: Admin}, Request{Action: Modify, Resource: ResourceUser}, true},
+		{Grant{Role: "unknown"}, Request{Action: Read, Resource: ResourceService}, false},
+
+		// resource types
+		{Grant{Role: Admin, Resource: ResourceHost}, Request{Action: Modify, Resource: ResourceHost}, true},
+		{Grant{Role: Admin, Resource: ResourceHost}, Request{Action: Read, Resource: ResourceService}, false},
+
+		// pools and tenants
+		{Grant{Role: Operator, PoolID: "a"}, Request{Action: Operate, Resource: ResourceService, PoolID: "a"}, true},
+		{Grant{Role: Operator, PoolID: "a"}, Request{Action: Operate, Resource: ResourceService, PoolID: "b"}, false},
+		{Grant{Role: Operator, PoolID: "a"}, Request{Action: Read, Resource: ResourceService, PoolID: "b"}, false},
+		{Grant{Role: Operator, PoolID: "a"}, Request{Action: Read, Resource: ResourceService}, true},
+		{Grant{Role: Operator, PoolID: "a"}, Request{Action: Operate, Resource: ResourceService}, false},
+		{Grant{Role: Admin, TenantID: "t"}, Request{Action: Modify, Resource: ResourceService, PoolID: "a", TenantID: "t"}, true},
+		{Grant{Role: Admin, TenantID: "t"}, Request{Action: Modify, Resource: ResourceService, PoolID: "a", TenantID: "u"}, false},
+	} {
+		if actual := test.grant.Allows(test.request); actual != test.expected {
+			t.Errorf("Test %d: expected %+v allows %+v to be %v", i, test.grant, test.request, test.expected)
+		}
+	}
+}
+
+func TestUserRoles(t *testing.T) {
+	roles := UserRoles{User: "bob"}
+	request := Request{Action: Operate, Resource: ResourceService, PoolID: "a"}
+	if roles.Allows(request) {
+		t.Errorf("Expected a user without grants to be denied")
+	}
+
+	viewer, operator := Grant{Role: Viewer}, Grant{Role: Operator, PoolID: "a"}
+	if !roles.Add(viewer) || !roles.Add(operator) || roles.Add(viewer) {
+		t.Errorf("Expected each grant to be added once, got %+v", roles.Grants)
+	}
+	if !roles.Allows(request) {
+		t.Errorf("Expected %+v to allow %+v", roles, request)
+	}
+
+	if !roles.Remove(operator) || roles.Remove(operator) {
+		t.Errorf("Expected the grant to be removed once, got %+v", roles.Grants)
+	}
+	if roles.Allows(request) {
+		t.Errorf("Expected %+v to deny %+v", roles, request)
+	}
+}
+
+func TestValidEntity(t *testing.T) {
+	roles := UserRoles{User: "bob", Grants: []Grant{{Role: Admin, Resource: ResourcePool}}}
+	if err := roles.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	for _, invalid := range []UserRoles{
+		{User: "", Grants: []Grant{{Role: Admin}}},
+		{User: " bob", Grants: []Grant{{Role: Admin}}},
+		{User: "bob", Grants: []Grant{{Role: "root"}}},
+		{User: "bob", Grants: []Grant{{Role: Admin, Resource: "vhost"}}},
+	} {
+		if err := invalid.ValidEntity(); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestIsErrUnauthorized(t *testing.T) {
+	err := ErrUnauthorized{"bob", Request{Action: Modify, Resource: ResourcePool, PoolID: "default"}}
+	if expected := `permission denied: user "bob" may not modify pools in pool default`; err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+	if !IsErrUnauthorized(err) || !IsErrUnauthorized(errors.New(err.Error())) {
+		t.Errorf("Expected %s to be unauthorized", err)
+	}
+	if IsErrUnauthorized(nil) || IsErrUnauthorized(errors.New("pool not found")) {
+		t.Errorf("Expected other errors not to be unauthorized")
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package role
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore/elastic"
+)
+
+var (
+	mappingString = `
+{
+    "userroles": {
+      "properties":{
+        "User":         {"type": "string", "index":"not_analyzed"},
+        "Grants" :{
+          "properties":{
+            "Role" :     {"type": "string", "index":"not_analyzed"},
+            "Resource" : {"type": "string", "index":"not_analyzed"},
+            "PoolID" :   {"type": "string", "index":"not_analyzed"},
+            "TenantID" : {"type": "string", "index":"not_analyzed"}
+          }
+        }
+      }
+    }
+}
+`
+	//MAPPING is the elastic mapping for the roles of a user
+	MAPPING, mappingError = elastic.NewMapping(mappingString)
+)
+
+func init() {
+	if mappingError != nil {
+		glog.Fatalf("error creating user roles mapping: %v", mappingError)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package role
+
+import (
+	"github.com/zenoss/elastigo/search"
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+
+	"strings"
+)
+
+//NewStore creates a user roles store
+func NewStore() *Store {
+	return &Store{}
+}
+
+//Store type for interacting with user roles persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+//GetUserRoles returns the roles of every user that has been granted any
+func (s *Store) GetUserRoles(ctx datastore.Context) ([]*UserRoles, error) {
+	glog.V(3).Infof("Role Store.GetUserRoles")
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Search("_exists_:User")
+	search := search.Search("controlplane").Type(kind).Size("10000").Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	return convert(results)
+}
+
+//Key creates a Key suitable for getting, putting and deleting the roles of a user
+func Key(user string) datastore.Key {
+	return datastore.NewKey(kind, strings.TrimSpace(user))
+}
+
+func convert(results datastore.Results) ([]*UserRoles, error) {
+	roles := make([]*UserRoles, results.Len())
+	for idx := range roles {
+		var r UserRoles
+		if err := results.Get(idx, &r); err != nil {
+			return nil, err
+		}
+		roles[idx] = &r
+	}
+	return roles, nil
+}
+
+var kind = "userroles"
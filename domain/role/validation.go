@@ -0,0 +1,44 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package role
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/validation"
+
+	"strings"
+)
+
+//ValidEntity validates Grant fields
+func (g *Grant) ValidEntity() error {
+	violations := validation.NewValidationError()
+	violations.Add(validation.StringIn(g.Role, Roles...))
+	if g.Resource != "" {
+		violations.Add(validation.StringIn(g.Resource, Resources...))
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
+
+//ValidEntity validates UserRoles fields
+func (u *UserRoles) ValidEntity() error {
+	glog.V(4).Info("Validating UserRoles")
+
+	trimmed := strings.TrimSpace(u.User)
+	violations := validation.NewValidationError()
+	violations.Add(validation.NotEmpty("UserRoles.User", u.User))
+	violations.Add(validation.StringsEqual(u.User, trimmed, "leading and trailing spaces not allowed for user name"))
+	for i := range u.Grants {
+		violations.Add(u.Grants[i].ValidEntity())
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+// DependsOnService reports whether the service has to wait for the other
+// service to be running and healthy before it starts, either because it names
+// the other service in StartAfter or because the other service exports an
+// application in DependsOn.
+func (s *Service) DependsOnService(other *Service) bool {
+	if s.ID == other.ID {
+		return false
+	}
+	for _, name := range s.StartAfter {
+		if name == other.Name {
+			return true
+		}
+	}
+	for _, application := range s.DependsOn {
+		for _, ep := range other.GetServiceExports() {
+			if ep.Application == application {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Dependencies returns the services that the service has to wait for before
+// it starts
+func Dependencies(svc *Service, services []*Service) []*Service {
+	var result []*Service
+	for _, other := range services {
+		if svc.DependsOnService(other) {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// Dependents returns the services that have to stop before the service stops
+func Dependents(svc *Service, services []*Service) []*Service {
+	var result []*Service
+	for _, other := range services {
+		if other.DependsOnService(svc) {
+			result = append(result, other)
+		}
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func TestDependencies(t *testing.T) {
+	mysql := &Service{
+		ID:   "mysql-id",
+		Name: "mysql",
+		Endpoints: []ServiceEndpoint{
+			BuildServiceEndpoint(servicedefinition.EndpointDefinition{Purpose: "export", Application: "mysql"}),
+		},
+	}
+	redis := &Service{ID: "redis-id", Name: "redis"}
+	zope := &Service{
+		ID:         "zope-id",
+		Name:       "zope",
+		StartAfter: []string{"redis"},
+		DependsOn:  []string{"mysql"},
+		Endpoints: []ServiceEndpoint{
+			BuildServiceEndpoint(servicedefinition.EndpointDefinition{Purpose: "import", Application: "mysql"}),
+		},
+	}
+	services := []*Service{mysql, redis, zope}
+
+	if deps := Dependencies(zope, services); len(deps) != 2 || deps[0] != mysql || deps[1] != redis {
+		t.Errorf("unexpected dependencies of zope: %v", deps)
+	}
+	if deps := Dependencies(mysql, services); len(deps) != 0 {
+		t.Errorf("unexpected dependencies of mysql: %v", deps)
+	}
+	if deps := Dependents(mysql, services); len(deps) != 1 || deps[0] != zope {
+		t.Errorf("unexpected dependents of mysql: %v", deps)
+	}
+	if deps := Dependents(zope, services); len(deps) != 0 {
+		t.Errorf("unexpected dependents of zope: %v", deps)
+	}
+}
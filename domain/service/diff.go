@@ -0,0 +1,274 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+// Kinds of differences between a deployed tenant and a template
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// What happens to a service context that differs from the template's
+const (
+	ContextKept        = "kept"        // The context users may have edited is kept
+	ContextOverwritten = "overwritten" // The context is replaced with the template's
+)
+
+// ItemDiff describes an endpoint or config file that differs
+type ItemDiff struct {
+	Name      string
+	Change    string
+	Preserved bool // The config file was edited by a user, whose edits are kept
+}
+
+// ServiceDiff describes how a deployed service differs from its definition
+type ServiceDiff struct {
+	Path        string // Names of the service and its parents, such as /Zenoss.core/zproxy
+	ServiceID   string // Empty for services that would be added
+	Change      string
+	Image       string   // "old -> new" when the image changed
+	Context     string   // Kept or overwritten when the context differs from the template's
+	Fields      []string // Other settings that changed
+	Endpoints   []ItemDiff
+	ConfigFiles []ItemDiff
+}
+
+// Empty is true when applying the definition would not change the service
+func (d *ServiceDiff) Empty() bool {
+	return d.Change == DiffChanged && d.Image == "" && d.Context != ContextOverwritten && len(d.Fields) == 0 && len(d.Endpoints) == 0 && len(d.ConfigFiles) == 0
+}
+
+// TemplateDiff describes how a deployed tenant differs from a template
+type TemplateDiff struct {
+	TenantID   string
+	TemplateID string
+	Services   []ServiceDiff
+}
+
+// Empty is true when the tenant matches the template
+func (d *TemplateDiff) Empty() bool {
+	return len(d.Services) == 0
+}
+
+// DiffDefinition compares a deployed service with a new version of its
+// definition. The names of config files that have been edited by users are
+// marked as preserved. A context that differs from the definition's is kept
+// unless overwriteContext is set. Images, instance counts and monitoring
+// profiles are not compared here.
+func DiffDefinition(svc *Service, sd *servicedefinition.ServiceDefinition, edited map[string]bool, overwriteContext bool) ServiceDiff {
+	diff := ServiceDiff{ServiceID: svc.ID, Change: DiffChanged}
+
+	if !same(decode(svc.Context), decode(encode(sd.Context))) {
+		if overwriteContext {
+			diff.Context = ContextOverwritten
+		} else {
+			diff.Context = ContextKept
+		}
+	}
+
+	field := func(name string, deployed, defined interface{}) {
+		if !same(deployed, defined) {
+			diff.Fields = append(diff.Fields, name)
+		}
+	}
+	field("Startup", svc.Startup, sd.Command)
+	field("Description", svc.Description, sd.Description)
+	field("Tags", svc.Tags, sd.Tags)
+	field("InstanceLimits", svc.InstanceLimits, sd.Instances)
+	field("ChangeOptions", svc.ChangeOptions, sd.ChangeOptions)
+	field("Launch", svc.Launch, sd.Launch)
+	field("HostPolicy", svc.HostPolicy, sd.HostPolicy)
+	field("HostSelector", svc.HostSelector, sd.HostSelector)
+	field("ScalingPolicy", svc.ScalingPolicy, sd.ScalingPolicy)
+	field("Hostname", svc.Hostname, sd.Hostname)
+	field("Privileged", svc.Privileged, sd.Privileged)
+	field("Tasks", svc.Tasks, sd.Tasks)
+	field("StartAfter", svc.StartAfter, sd.StartAfter)
+	field("DependsOn", svc.DependsOn, sd.DependsOn)
+	field("Volumes", svc.Volumes, sd.Volumes)
+	field("LogConfigs", svc.LogConfigs, sd.LogConfigs)
+	field("Snapshot", svc.Snapshot, sd.Snapshot)
+	field("RAMCommitment", svc.RAMCommitment, sd.RAMCommitment)
+	field("CPUCommitment", svc.CPUCommitment, sd.CPUCommitment)
+	field("Runs", svc.Runs, sd.Runs)
+	field("Actions", svc.Actions, sd.Actions)
+	field("HealthChecks", svc.HealthChecks, sd.HealthChecks)
+	field("FailurePolicy", svc.FailurePolicy, sd.FailurePolicy)
+	field("Prereqs", svc.Prereqs, sd.Prereqs)
+
+	// endpoints are compared without the application names that were
+	// evaluated, the vhosts users added and the addresses they assigned
+	deployed := make(map[string]servicedefinition.EndpointDefinition)
+	for _, ep := range svc.Endpoints {
+		deployed[ep.Name] = endpointDefinition(ep.EndpointDefinition)
+	}
+	defined := make(map[string]servicedefinition.EndpointDefinition)
+	for _, ep := range sd.Endpoints {
+		defined[ep.Name] = endpointDefinition(ep)
+	}
+	for _, name := range sortedKeys(deployed, defined) {
+		old, inOld := deployed[name]
+		next, inNext := defined[name]
+		switch {
+		case !inNext:
+			diff.Endpoints = append(diff.Endpoints, ItemDiff{Name: name, Change: DiffRemoved})
+		case !inOld:
+			diff.Endpoints = append(diff.Endpoints, ItemDiff{Name: name, Change: DiffAdded})
+		case !same(old, next):
+			diff.Endpoints = append(diff.Endpoints, ItemDiff{Name: name, Change: DiffChanged})
+		}
+	}
+
+	// config files are compared with the versions they were deployed from
+	for _, name := range sortedKeys(svc.OriginalConfigs, sd.ConfigFiles) {
+		old, inOld := svc.OriginalConfigs[name]
+		next, inNext := sd.ConfigFiles[name]
+		switch {
+		case !inNext:
+			diff.ConfigFiles = append(diff.ConfigFiles, ItemDiff{Name: name, Change: DiffRemoved})
+		case !inOld:
+			diff.ConfigFiles = append(diff.ConfigFiles, ItemDiff{Name: name, Change: DiffAdded})
+		case !same(old, next):
+			diff.ConfigFiles = append(diff.ConfigFiles, ItemDiff{Name: name, Change: DiffChanged, Preserved: edited[name]})
+		}
+	}
+	return diff
+}
+
+// ApplyDefinition updates a deployed service to match a new version of its
+// definition. The service keeps its identity, pool, desired state and number
+// of instances (within the new limits), and its endpoints keep the vhosts and
+// addresses that were assigned to them. The definition's config files become
+// the originals; edits users made to them are stored separately and still
+// apply. The context is only replaced if overwriteContext is set, since users
+// may have edited it. The image is left for the caller to retag.
+func ApplyDefinition(svc *Service, sd *servicedefinition.ServiceDefinition, overwriteContext bool) error {
+	if overwriteContext {
+		ctx, err := json.Marshal(sd.Context)
+		if err != nil {
+			return err
+		}
+		svc.Context = string(ctx)
+	}
+
+	svc.Name = sd.Name
+	svc.Startup = sd.Command
+	svc.Description = sd.Description
+	svc.Tags = sd.Tags
+	svc.InstanceLimits = sd.Instances
+	if sd.Instances.Min > 0 && svc.Instances < sd.Instances.Min {
+		svc.Instances = sd.Instances.Min
+	} else if sd.Instances.Max > 0 && svc.Instances > sd.Instances.Max {
+		svc.Instances = sd.Instances.Max
+	}
+	svc.ChangeOptions = sd.ChangeOptions
+	svc.Launch = sd.Launch
+	svc.HostPolicy = sd.HostPolicy
+	svc.HostSelector = sd.HostSelector
+	svc.ScalingPolicy = sd.ScalingPolicy
+	svc.Hostname = sd.Hostname
+	svc.Privileged = sd.Privileged
+	svc.OriginalConfigs = sd.ConfigFiles
+	svc.ConfigFiles = sd.ConfigFiles
+	svc.Tasks = sd.Tasks
+	svc.StartAfter = sd.StartAfter
+	svc.DependsOn = sd.DependsOn
+	svc.Volumes = sd.Volumes
+	svc.LogConfigs = sd.LogConfigs
+	svc.Snapshot = sd.Snapshot
+	svc.RAMCommitment = sd.RAMCommitment
+	svc.CPUCommitment = sd.CPUCommitment
+	svc.Runs = sd.Runs
+	svc.Actions = sd.Actions
+	svc.HealthChecks = sd.HealthChecks
+	svc.FailurePolicy = sd.FailurePolicy
+	svc.Prereqs = sd.Prereqs
+
+	deployed := make(map[string]ServiceEndpoint)
+	for _, ep := range svc.Endpoints {
+		deployed[ep.Name] = ep
+	}
+	svc.Endpoints = make([]ServiceEndpoint, 0, len(sd.Endpoints))
+	for _, epd := range sd.Endpoints {
+		ep := BuildServiceEndpoint(epd)
+		if old, ok := deployed[epd.Name]; ok {
+			ep.VHosts = old.VHosts
+			ep.AddressAssignment = old.AddressAssignment
+		}
+		svc.Endpoints = append(svc.Endpoints, ep)
+	}
+	return nil
+}
+
+// endpointDefinition returns the parts of an endpoint that come from its
+// definition
+func endpointDefinition(ep servicedefinition.EndpointDefinition) servicedefinition.EndpointDefinition {
+	if ep.ApplicationTemplate != "" {
+		ep.Application = ep.ApplicationTemplate
+	}
+	ep.ApplicationTemplate = ""
+	ep.VHosts = nil
+	return ep
+}
+
+// same compares deployed and defined settings, treating empty slices and maps
+// as equal to missing ones
+func same(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if empty(va) && empty(vb) {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func encode(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// decode normalizes the JSON of a service context so contexts with the same
+// values compare equal
+func decode(data string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return data
+	}
+	return v
+}
+
+// sortedKeys returns the keys of both maps in order
+func sortedKeys(maps ...interface{}) []string {
+	set := make(map[string]struct{})
+	for _, m := range maps {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			set[key.String()] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
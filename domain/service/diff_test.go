@@ -0,0 +1,150 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/servicedefinition"
+)
+
+func diffTestDefinition() servicedefinition.ServiceDefinition {
+	return servicedefinition.ServiceDefinition{
+		Name:      "zope",
+		Command:   "runzope",
+		ImageID:   "zenoss/core",
+		Instances: domain.MinMax{Min: 1, Max: 4},
+		Context:   map[string]interface{}{"workers": 2},
+		ConfigFiles: map[string]servicedefinition.ConfigFile{
+			"/etc/zope.conf": {Filename: "/etc/zope.conf", Content: "workers 2"},
+			"/etc/log.conf":  {Filename: "/etc/log.conf", Content: "level info"},
+		},
+		Endpoints: []servicedefinition.EndpointDefinition{
+			{Name: "zope", Purpose: "export", Protocol: "tcp", PortNumber: 9080, Application: "zope"},
+			{Name: "mysql", Purpose: "import", Protocol: "tcp", PortNumber: 3306, Application: "mysql"},
+		},
+	}
+}
+
+func TestDiffDefinition_unchanged(t *testing.T) {
+	sd := diffTestDefinition()
+	svc, err := BuildService(sd, "parent-id", "default", SVCStop, "deployment")
+	if err != nil {
+		t.Fatalf("Could not build service: %s", err)
+	}
+	// users add vhosts and assign addresses after services are deployed
+	svc.Endpoints[0].VHosts = []string{"zope"}
+	svc.Endpoints[0].AddressAssignment = addressassignment.AddressAssignment{IPAddr: "10.0.0.1"}
+
+	if diff := DiffDefinition(svc, &sd, nil, false); !diff.Empty() {
+		t.Errorf("Expected no differences, got %+v", diff)
+	}
+}
+
+func TestDiffDefinition_changed(t *testing.T) {
+	sd := diffTestDefinition()
+	svc, err := BuildService(sd, "parent-id", "default", SVCStop, "deployment")
+	if err != nil {
+		t.Fatalf("Could not build service: %s", err)
+	}
+
+	sd.Command = "runzope --debug"
+	sd.Context = map[string]interface{}{"workers": 4}
+	sd.Endpoints[0].PortNumber = 8080
+	sd.Endpoints = append(sd.Endpoints[:1], servicedefinition.EndpointDefinition{Name: "redis", Purpose: "import", Application: "redis"})
+	sd.ConfigFiles = map[string]servicedefinition.ConfigFile{
+		"/etc/zope.conf":  {Filename: "/etc/zope.conf", Content: "workers 4"},
+		"/etc/redis.conf": {Filename: "/etc/redis.conf", Content: "host redis"},
+	}
+
+	diff := DiffDefinition(svc, &sd, map[string]bool{"/etc/zope.conf": true}, false)
+	expected := ServiceDiff{
+		ServiceID: svc.ID,
+		Change:    DiffChanged,
+		Context:   ContextKept,
+		Fields:    []string{"Startup"},
+		Endpoints: []ItemDiff{
+			{Name: "mysql", Change: DiffRemoved},
+			{Name: "redis", Change: DiffAdded},
+			{Name: "zope", Change: DiffChanged},
+		},
+		ConfigFiles: []ItemDiff{
+			{Name: "/etc/log.conf", Change: DiffRemoved},
+			{Name: "/etc/redis.conf", Change: DiffAdded},
+			{Name: "/etc/zope.conf", Change: DiffChanged, Preserved: true},
+		},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("\ngot:\n%+v\nwant:\n%+v", diff, expected)
+	}
+}
+
+func TestApplyDefinition(t *testing.T) {
+	sd := diffTestDefinition()
+	svc, err := BuildService(sd, "parent-id", "default", SVCRun, "deployment")
+	if err != nil {
+		t.Fatalf("Could not build service: %s", err)
+	}
+	svc.Instances = 6
+	svc.Endpoints[0].VHosts = []string{"zope"}
+	svc.Endpoints[0].AddressAssignment = addressassignment.AddressAssignment{IPAddr: "10.0.0.1"}
+	id := svc.ID
+
+	sd.Command = "runzope --debug"
+	sd.Instances = domain.MinMax{Min: 1, Max: 2}
+	sd.Endpoints[0].PortNumber = 8080
+	if err := ApplyDefinition(svc, &sd, false); err != nil {
+		t.Fatalf("Could not apply definition: %s", err)
+	}
+
+	if svc.ID != id || svc.ParentServiceID != "parent-id" || svc.PoolID != "default" || svc.DesiredState != SVCRun {
+		t.Errorf("Expected the service to keep its identity, got %+v", svc)
+	}
+	if svc.Instances != 2 {
+		t.Errorf("Expected instances to be limited to 2, got %d", svc.Instances)
+	}
+	if svc.Endpoints[0].PortNumber != 8080 || svc.Endpoints[0].AddressAssignment.IPAddr != "10.0.0.1" || len(svc.Endpoints[0].VHosts) != 1 {
+		t.Errorf("Expected the endpoint to change and keep its vhosts and address, got %+v", svc.Endpoints[0])
+	}
+	if diff := DiffDefinition(svc, &sd, nil, false); !diff.Empty() {
+		t.Errorf("Expected no differences after applying, got %+v", diff)
+	}
+}
+
+func TestApplyDefinition_context(t *testing.T) {
+	sd := diffTestDefinition()
+	svc, err := BuildService(sd, "parent-id", "default", SVCRun, "deployment")
+	if err != nil {
+		t.Fatalf("Could not build service: %s", err)
+	}
+	// a user edited the context
+	svc.Context = `{"workers": 8}`
+	sd.Context = map[string]interface{}{"workers": 4}
+
+	// the edited context is kept, which does not change the service
+	if diff := DiffDefinition(svc, &sd, nil, false); diff.Context != ContextKept || !diff.Empty() {
+		t.Errorf("Expected the context to be kept, got %+v", diff)
+	}
+	if err := ApplyDefinition(svc, &sd, false); err != nil {
+		t.Fatalf("Could not apply definition: %s", err)
+	}
+	if svc.Context != `{"workers": 8}` {
+		t.Errorf("Expected the edited context to be kept, got %s", svc.Context)
+	}
+
+	// overwriting it is a change that is shown
+	if diff := DiffDefinition(svc, &sd, nil, true); diff.Context != ContextOverwritten || diff.Empty() {
+		t.Errorf("Expected the context to be overwritten, got %+v", diff)
+	}
+	if err := ApplyDefinition(svc, &sd, true); err != nil {
+		t.Fatalf("Could not apply definition: %s", err)
+	}
+	if diff := DiffDefinition(svc, &sd, nil, true); diff.Context != "" {
+		t.Errorf("Expected the template's context, got %s", svc.Context)
+	}
+}
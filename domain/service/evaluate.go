@@ -230,18 +230,24 @@ func (service *Service) EvaluatePrereqsTemplate(gs GetService, fc FindChildServi
 	return
 }
 
-// EvaluateHealthCheckTemplate parses and evals the Script field for each HealthCheck.
+// EvaluateHealthCheckTemplate parses and evals the Script, URL and Address
+// fields for each HealthCheck.
 func (service *Service) EvaluateHealthCheckTemplate(gs GetService, fc FindChildService, instanceID int) (err error) {
-	glog.V(3).Infof("Evaluating HealthCheck scripts for %s:%d", service.ID, instanceID)
+	glog.V(3).Infof("Evaluating HealthCheck templates for %s:%d", service.ID, instanceID)
 	for key, healthcheck := range service.HealthChecks {
-		err, result := service.evaluateTemplate(gs, fc, instanceID, healthcheck.Script)
-		if err != nil {
-			return err
-		}
-		if result != "" {
-			healthcheck.Script = result
-			service.HealthChecks[key] = healthcheck
+		for _, field := range []*string{&healthcheck.Script, &healthcheck.URL, &healthcheck.Address} {
+			if *field == "" {
+				continue
+			}
+			err, result := service.evaluateTemplate(gs, fc, instanceID, *field)
+			if err != nil {
+				return err
+			}
+			if result != "" {
+				*field = result
+			}
 		}
+		service.HealthChecks[key] = healthcheck
 	}
 	return
 }
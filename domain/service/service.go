@@ -41,12 +41,17 @@ type Service struct {
 	PoolID            string
 	DesiredState      int
 	HostPolicy        servicedefinition.HostPolicy
+	HostSelector      servicedefinition.HostSelector
+	ScalingPolicy     servicedefinition.ScalingPolicy
+	SnapshotPolicy    servicedefinition.SnapshotPolicy
 	Hostname          string
 	Privileged        bool
 	Launch            string
 	Endpoints         []ServiceEndpoint
 	Tasks             []servicedefinition.Task
 	ParentServiceID   string
+	StartAfter        []string // Names of services that must be running and healthy before this service starts
+	DependsOn         []string // Applications of imported endpoints whose services must be running and healthy before this service starts
 	Volumes           []servicedefinition.Volume
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
@@ -58,8 +63,9 @@ type Service struct {
 	RAMCommitment     uint64
 	CPUCommitment     uint64
 	Actions           map[string]string
-	HealthChecks      map[string]domain.HealthCheck // A health check for the service.
-	Prereqs           []domain.Prereq               // Optional list of scripts that must be successfully run before kicking off the service command.
+	HealthChecks      map[string]domain.HealthCheck   // A health check for the service.
+	FailurePolicy     servicedefinition.FailurePolicy // When instances failing their health checks are restarted
+	Prereqs           []domain.Prereq                 // Optional list of scripts that must be successfully run before kicking off the service command.
 	MonitoringProfile domain.MonitorProfile
 }
 
@@ -124,12 +130,16 @@ func BuildService(sd servicedefinition.ServiceDefinition, parentServiceID string
 	svc.DesiredState = desiredState
 	svc.Launch = sd.Launch
 	svc.HostPolicy = sd.HostPolicy
+	svc.HostSelector = sd.HostSelector
+	svc.ScalingPolicy = sd.ScalingPolicy
 	svc.Hostname = sd.Hostname
 	svc.Privileged = sd.Privileged
 	svc.OriginalConfigs = sd.ConfigFiles
 	svc.ConfigFiles = sd.ConfigFiles
 	svc.Tasks = sd.Tasks
 	svc.ParentServiceID = parentServiceID
+	svc.StartAfter = sd.StartAfter
+	svc.DependsOn = sd.DependsOn
 	svc.CreatedAt = now
 	svc.UpdatedAt = now
 	svc.Volumes = sd.Volumes
@@ -137,9 +147,11 @@ func BuildService(sd servicedefinition.ServiceDefinition, parentServiceID string
 	svc.LogConfigs = sd.LogConfigs
 	svc.Snapshot = sd.Snapshot
 	svc.RAMCommitment = sd.RAMCommitment
+	svc.CPUCommitment = sd.CPUCommitment
 	svc.Runs = sd.Runs
 	svc.Actions = sd.Actions
 	svc.HealthChecks = sd.HealthChecks
+	svc.FailurePolicy = sd.FailurePolicy
 	svc.Prereqs = sd.Prereqs
 
 	svc.Endpoints = make([]ServiceEndpoint, 0)
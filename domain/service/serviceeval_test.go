@@ -452,6 +452,14 @@ func (s *S) TestIllegalTemplates(t *C) {
 				},
 			},
 		},
+		Service{
+			HealthChecks: map[string]domain.HealthCheck{
+				"check": domain.HealthCheck{
+					Type: domain.HealthCheckHTTP,
+					URL:  "{{illegal_healthcheck_url}}",
+				},
+			},
+		},
 	}
 
 	for _, svc := range illegal_services {
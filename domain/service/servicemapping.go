@@ -36,6 +36,8 @@ var (
 		"Hostname":        {"type": "string", "index":"not_analyzed"},
 		"Privileged":      {"type": "string", "index":"not_analyzed"},
 		"ParentServiceID": {"type": "string", "index":"not_analyzed"},
+		"StartAfter":      {"type": "string", "index":"not_analyzed"},
+		"DependsOn":       {"type": "string", "index":"not_analyzed"},
 		"Volume":          {
 		  "properties":    {
 			"ResourcePath" : {"type": "string", "index":"not_analyzed"},
@@ -66,7 +68,8 @@ var (
 			"Purpose" :             {"type": "string", "index":"not_analyzed"},
 			"PortNumber" :          {"type": "long",   "index":"not_analyzed"},
 			"VirtualAddress" :      {"type": "string", "index":"not_analyzed"},
-			"VHost" :               {"type": "string", "index":"not_analyzed"}
+			"VHost" :               {"type": "string", "index":"not_analyzed"},
+			"LoadBalancer" :        {"type": "string", "index":"not_analyzed"}
 		  }
 		},
 		"Tasks": {
@@ -83,6 +83,30 @@ func (s *Store) GetChildServices(ctx datastore.Context, parentID string) ([]*Ser
 	return query(ctx, queryString)
 }
 
+//GetTenantServices returns the tenant service with the given id along with
+//all of its descendants, querying one level of the service tree at a time
+func (s *Store) GetTenantServices(ctx datastore.Context, tenantID string) ([]*Service, error) {
+	tenant, err := s.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*Service{tenant}
+	parentIDs := []string{tenant.ID}
+	for len(parentIDs) > 0 {
+		children, err := query(ctx, fmt.Sprintf("ParentServiceID:(%s)", strings.Join(parentIDs, " OR ")))
+		if err != nil {
+			return nil, err
+		}
+		parentIDs = parentIDs[:0]
+		for _, child := range children {
+			result = append(result, child)
+			parentIDs = append(parentIDs, child.ID)
+		}
+	}
+	return result, nil
+}
+
 func query(ctx datastore.Context, query string) ([]*Service, error) {
 	q := datastore.NewQuery(ctx)
 	elasticQuery := search.Query().Search(query)
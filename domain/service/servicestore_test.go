@@ -111,3 +111,24 @@ func (s *S) Test_GetServices(t *C) {
 	t.Assert(len(svcs), Equals, 2)
 
 }
+
+func (s *S) Test_GetTenantServices(t *C) {
+	tenant := &Service{ID: "tenant_id", PoolID: "testPool", Name: "tenant", Launch: "auto"}
+	child := &Service{ID: "child_id", ParentServiceID: "tenant_id", PoolID: "testPool", Name: "child", Launch: "auto"}
+	grandchild := &Service{ID: "grandchild_id", ParentServiceID: "child_id", PoolID: "testPool", Name: "grandchild", Launch: "auto"}
+	other := &Service{ID: "other_id", PoolID: "testPool", Name: "other", Launch: "auto"}
+	for _, svc := range []*Service{tenant, child, grandchild, other} {
+		t.Assert(s.store.Put(s.ctx, svc), IsNil)
+	}
+
+	svcs, err := s.store.GetTenantServices(s.ctx, "tenant_id")
+	t.Assert(err, IsNil)
+	ids := make(map[string]bool)
+	for _, svc := range svcs {
+		ids[svc.ID] = true
+	}
+	t.Assert(ids, DeepEquals, map[string]bool{"tenant_id": true, "child_id": true, "grandchild_id": true})
+
+	_, err = s.store.GetTenantServices(s.ctx, "missing_id")
+	t.Assert(err, NotNil)
+}
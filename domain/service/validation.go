@@ -7,6 +7,8 @@ package service
 import (
 	"github.com/control-center/serviced/commons"
 	"github.com/control-center/serviced/validation"
+
+	"fmt"
 )
 
 //ValidEntity validate that Service has all required fields
@@ -19,6 +21,23 @@ func (s *Service) ValidEntity() error {
 
 	vErr.Add(validation.StringIn(s.Launch, commons.AUTO, commons.MANUAL))
 	vErr.Add(validation.IntIn(s.DesiredState, SVCRun, SVCStop, SVCRestart))
+	vErr.Add(s.ScalingPolicy.ValidEntity())
+	if s.ScalingPolicy.Enabled() && s.InstanceLimits.Max == 0 {
+		vErr.AddViolation("scaling policy requires a maximum number of instances")
+	}
+	for name, hc := range s.HealthChecks {
+		if err := hc.ValidEntity(); err != nil {
+			vErr.AddViolation(fmt.Sprintf("health check %s: %s", name, err))
+		}
+	}
+	vErr.Add(s.FailurePolicy.ValidEntity())
+	if s.FailurePolicy.Enabled() && len(s.HealthChecks) == 0 {
+		vErr.AddViolation("failure policy requires health checks")
+	}
+	vErr.Add(s.SnapshotPolicy.ValidEntity())
+	if s.SnapshotPolicy.Enabled() && s.ParentServiceID != "" {
+		vErr.AddViolation("snapshot policy is only allowed on a tenant")
+	}
 
 	if vErr.HasError() {
 		return vErr
@@ -0,0 +1,120 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package servicedefinition
+
+import (
+	"fmt"
+	"strings"
+)
+
+// imports reports whether the service definition imports an endpoint of the
+// application
+func (sd *ServiceDefinition) imports(application string) bool {
+	for _, ep := range sd.Endpoints {
+		if (ep.Purpose == "import" || ep.Purpose == "import_all") && ep.Application == application {
+			return true
+		}
+	}
+	return false
+}
+
+// exports reports whether the service definition exports an endpoint of the
+// application
+func (sd *ServiceDefinition) exports(application string) bool {
+	for _, ep := range sd.Endpoints {
+		if ep.Purpose == "export" && ep.Application == application {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidDependencies checks the StartAfter and DependsOn settings across the
+// service definition hierarchies. A service may only depend on applications
+// that it imports, and the dependencies may not form a cycle. Services that
+// are named but not defined in the hierarchies are assumed to be deployed
+// elsewhere in the tenant.
+func ValidDependencies(sds []ServiceDefinition) error {
+	var defs []ServiceDefinition
+	collect := func(sd *ServiceDefinition) error {
+		defs = append(defs, *sd)
+		return nil
+	}
+	for i := range sds {
+		Walk(&sds[i], collect)
+	}
+
+	// edges[i] lists the definitions that definition i has to wait for
+	edges := make([][]int, len(defs))
+	for i, sd := range defs {
+		for _, application := range sd.DependsOn {
+			if !sd.imports(application) {
+				return fmt.Errorf("service definition %v: depends on application %s which it does not import", sd.Name, application)
+			}
+		}
+		for j, dep := range defs {
+			if dependsOn(&sd, &dep) {
+				edges[i] = append(edges[i], j)
+			}
+		}
+	}
+
+	// depth first search for a path back to a definition being visited
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(defs))
+	var path []int
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			var names []string
+			for k := len(path) - 1; k >= 0; k-- {
+				names = append([]string{defs[path[k]].Name}, names...)
+				if path[k] == i {
+					break
+				}
+			}
+			return fmt.Errorf("service definition %v: dependency cycle %s -> %s", defs[i].Name, strings.Join(names, " -> "), defs[i].Name)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		path = append(path, i)
+		for _, j := range edges[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range defs {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependsOn reports whether sd names dep in StartAfter or depends on an
+// application that dep exports
+func dependsOn(sd, dep *ServiceDefinition) bool {
+	for _, name := range sd.StartAfter {
+		if name == dep.Name {
+			return true
+		}
+	}
+	for _, application := range sd.DependsOn {
+		if dep.exports(application) {
+			return true
+		}
+	}
+	return false
+}
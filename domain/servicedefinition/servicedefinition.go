@@ -7,7 +7,10 @@ package servicedefinition
 import (
 	"github.com/control-center/serviced/domain"
 
+	"encoding/json"
 	"errors"
+	"math"
+	"sort"
 	"strings"
 	"time"
 )
@@ -23,12 +26,16 @@ type ServiceDefinition struct {
 	ChangeOptions     []string               // Control options for what happens when a running service is changed
 	Launch            string                 // Must be "AUTO", the default, or "MANUAL"
 	HostPolicy        HostPolicy             // Policy for starting up instances
+	HostSelector      HostSelector           // Host label and service affinity constraints for starting up instances
+	ScalingPolicy     ScalingPolicy          // Metric based adjustment of the number of instances
 	Hostname          string                 // Optional hostname which should be set on run
 	Privileged        bool                   // Whether to run the container with extended privileges
 	ConfigFiles       map[string]ConfigFile  // Config file templates
 	Context           map[string]interface{} // Context information for the service
 	Endpoints         []EndpointDefinition   // Comms endpoints used by the service
 	Services          []ServiceDefinition    // Supporting subservices
+	StartAfter        []string               // Names of services that must be running and healthy before this service starts
+	DependsOn         []string               // Applications of imported endpoints whose services must be running and healthy before this service starts
 	Tasks             []Task                 // Scheduled tasks for celery to find
 	LogFilters        map[string]string      // map of log filter name to log filter definitions
 	Volumes           []Volume               // list of volumes to bind into containers
@@ -39,6 +46,7 @@ type ServiceDefinition struct {
 	Runs              map[string]string             // Map of commands that can be executed with 'serviced run ...'
 	Actions           map[string]string             // Map of commands that can be executed with 'serviced action ...'
 	HealthChecks      map[string]domain.HealthCheck // HealthChecks for a service.
+	FailurePolicy     FailurePolicy                 // Restarts the instances that keep failing their health checks
 	Prereqs           []domain.Prereq               // Optional list of scripts that must be successfully run before kicking off the service command.
 	MonitoringProfile domain.MonitorProfile         // An optional list of queryable metrics, graphs, and thresholds
 }
@@ -60,10 +68,18 @@ type EndpointDefinition struct {
 	Application         string
 	ApplicationTemplate string
 	AddressConfig       AddressResourceConfig
+	LoadBalancer        string   // How an import spreads connections over the instances of the endpoint; round robin if not set
 	VHosts              []string // VHost is used to request named vhost for this endpoint. Should be the name of a
 	// subdomain, i.e "myapplication"  not "myapplication.host.com"
 }
 
+// Load balancing strategies of imported endpoints
+const (
+	LoadBalancerRoundRobin       = "roundrobin" // each connection goes to the next instance
+	LoadBalancerLeastConnections = "leastconn"  // each connection goes to the instance with the fewest active connections
+	LoadBalancerTwoChoices       = "p2c"        // each connection goes to the less busy of two random instances
+)
+
 // Task A scheduled task
 type Task struct {
 	Name          string
@@ -126,6 +142,248 @@ const (
 	RequireSeparate = "REQUIRE_SEPARATE"
 )
 
+// HostSelector narrows down the hosts on which instances of a service may run.
+// Required labels and anti-affinities rule hosts out; preferred labels and
+// affinities rank the remaining hosts ahead of the others. A label with an
+// empty value matches any host that carries the label.
+type HostSelector struct {
+	RequiredLabels  map[string]string // Labels a host must have to run an instance
+	PreferredLabels map[string]string // Labels that make a host more desirable
+	Affinity        []string          // Names of services whose hosts are preferred
+	AntiAffinity    []string          // Names of services whose hosts must be avoided
+}
+
+// ScalingPolicy adjusts the number of instances of a service, within its
+// instance limits, so that the average of a metric across the instances stays
+// near the target. A policy without a metric is disabled.
+type ScalingPolicy struct {
+	Metric   string        // Metric reported by each instance, e.g. "cpu.user"
+	Target   float64       // Desired average value of the metric per instance
+	Cooldown time.Duration // Minimum time between scaling decisions
+}
+
+type jsonScalingPolicy struct {
+	Metric   string
+	Target   float64
+	Cooldown float64 // the serialized version will be in seconds
+}
+
+func (p ScalingPolicy) MarshalJSON() ([]byte, error) {
+	// in json, the cooldown is represented in seconds
+	return json.Marshal(jsonScalingPolicy{
+		Metric:   p.Metric,
+		Target:   p.Target,
+		Cooldown: p.Cooldown.Seconds(),
+	})
+}
+
+func (p *ScalingPolicy) UnmarshalJSON(data []byte) error {
+	var temp jsonScalingPolicy
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	p.Metric = temp.Metric
+	p.Target = temp.Target
+	p.Cooldown = time.Duration(temp.Cooldown * float64(time.Second))
+	return nil
+}
+
+// Enabled is true when the policy names a metric
+func (p ScalingPolicy) Enabled() bool {
+	return p.Metric != ""
+}
+
+// how far the average may stray from the target of a scaling policy before
+// the number of instances changes
+const scalingTolerance = 0.1
+
+// DesiredInstances returns the number of instances that would bring the
+// average value of the metric per instance back to the target, within the
+// instance limits. At least one instance is kept so that the metric continues
+// to be reported.
+func (p ScalingPolicy) DesiredInstances(limits domain.MinMax, current int, value float64) int {
+	desired := current
+	if ratio := value / p.Target; math.Abs(ratio-1) > scalingTolerance {
+		desired = int(math.Ceil(float64(current) * ratio))
+	}
+
+	min := limits.Min
+	if min < 1 {
+		min = 1
+	}
+	if desired < min {
+		desired = min
+	}
+	if limits.Max > 0 && desired > limits.Max {
+		desired = limits.Max
+	}
+	return desired
+}
+
+// FailurePolicy restarts an instance of a service once one of its health
+// checks failed a number of times in a row. Each restart within the window
+// doubles the time to wait before the next one, and an instance that was
+// restarted MaxRestarts times within the window is left running. A policy
+// without a number of failures is disabled.
+type FailurePolicy struct {
+	Failures    int           // Consecutive failed results of a health check that restart the instance
+	Backoff     time.Duration // Time to wait after a restart before the next one
+	MaxRestarts int           // Restarts allowed within the window; unlimited if 0
+	Window      time.Duration // Restarts older than this are forgotten; never if 0
+}
+
+type jsonFailurePolicy struct {
+	Failures    int
+	Backoff     float64 // the serialized version will be in seconds
+	MaxRestarts int
+	Window      float64 // the serialized version will be in seconds
+}
+
+func (p FailurePolicy) MarshalJSON() ([]byte, error) {
+	// in json, the backoff and the window are represented in seconds
+	return json.Marshal(jsonFailurePolicy{
+		Failures:    p.Failures,
+		Backoff:     p.Backoff.Seconds(),
+		MaxRestarts: p.MaxRestarts,
+		Window:      p.Window.Seconds(),
+	})
+}
+
+func (p *FailurePolicy) UnmarshalJSON(data []byte) error {
+	var temp jsonFailurePolicy
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	p.Failures = temp.Failures
+	p.Backoff = time.Duration(temp.Backoff * float64(time.Second))
+	p.MaxRestarts = temp.MaxRestarts
+	p.Window = time.Duration(temp.Window * float64(time.Second))
+	return nil
+}
+
+// Enabled is true when the policy has a number of failures
+func (p FailurePolicy) Enabled() bool {
+	return p.Failures > 0
+}
+
+// the longest the backoff of a failure policy grows to
+const maxFailureBackoff = time.Hour
+
+// MaxFailureRestarts is the most restarts a failure policy may allow within
+// its window, which is as many as are remembered of an instance
+const MaxFailureRestarts = 20
+
+// Recent returns the restarts, given by the times they happened, that are
+// within the window as of now
+func (p FailurePolicy) Recent(restarts []time.Time, now time.Time) []time.Time {
+	if p.Window <= 0 {
+		return restarts
+	}
+	var recent []time.Time
+	for _, t := range restarts {
+		if now.Sub(t) < p.Window {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// NextRestart returns when an instance that was restarted at the given times,
+// oldest first, may be restarted again. Returns false if the instance was
+// restarted MaxRestarts times within the window.
+func (p FailurePolicy) NextRestart(restarts []time.Time, now time.Time) (time.Time, bool) {
+	recent := p.Recent(restarts, now)
+	if p.MaxRestarts > 0 && len(recent) >= p.MaxRestarts {
+		return time.Time{}, false
+	} else if len(recent) == 0 {
+		return now, true
+	}
+
+	backoff := p.Backoff
+	for i := 1; i < len(recent) && backoff < maxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxFailureBackoff {
+		backoff = maxFailureBackoff
+	}
+	return recent[len(recent)-1].Add(backoff), true
+}
+
+// SnapshotPolicy takes snapshots of a tenant on a cron schedule and prunes
+// the ones it took once no retention rule keeps them. A policy without a
+// schedule is disabled; a policy without retention rules keeps everything.
+type SnapshotPolicy struct {
+	Schedule   string // Cron expression, e.g. "0 2 * * *" or "@daily"
+	KeepLast   int    // Number of most recent snapshots kept
+	KeepDaily  int    // Number of days, counting today, for which the newest snapshot of the day is kept
+	KeepWeekly int    // Number of weeks, counting this one, for which the newest snapshot of the week is kept
+}
+
+// Enabled is true when the policy has a schedule
+func (p SnapshotPolicy) Enabled() bool {
+	return p.Schedule != ""
+}
+
+// Expired returns the indexes of the snapshots, given by the times they were
+// taken, that no retention rule keeps as of now. Days and weeks are in UTC,
+// and weeks start on monday.
+func (p SnapshotPolicy) Expired(created []time.Time, now time.Time) []int {
+	if p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 {
+		return nil
+	}
+
+	// newest first
+	order := make([]int, len(created))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Sort(byCreated{order, created})
+
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	thisWeek := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	firstDay := today.AddDate(0, 0, 1-p.KeepDaily)
+	firstWeek := thisWeek.AddDate(0, 0, 7*(1-p.KeepWeekly))
+
+	var (
+		expired []int
+		days    = make(map[time.Time]bool)
+		weeks   = make(map[time.Time]bool)
+	)
+	for n, i := range order {
+		t := created[i].UTC()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+
+		keep := n < p.KeepLast
+		if p.KeepDaily > 0 && !day.Before(firstDay) && !days[day] {
+			days[day] = true
+			keep = true
+		}
+		if p.KeepWeekly > 0 && !week.Before(firstWeek) && !weeks[week] {
+			weeks[week] = true
+			keep = true
+		}
+		if !keep {
+			expired = append(expired, i)
+		}
+	}
+	sort.Ints(expired)
+	return expired
+}
+
+// byCreated sorts indexes into the creation times of snapshots, newest first
+type byCreated struct {
+	order   []int
+	created []time.Time
+}
+
+func (s byCreated) Len() int      { return len(s.order) }
+func (s byCreated) Swap(i, j int) { s.order[i], s.order[j] = s.order[j], s.order[i] }
+func (s byCreated) Less(i, j int) bool {
+	return s.created[s.order[i]].After(s.created[s.order[j]])
+}
+
 // UnmarshalText implements the encoding/TextUnmarshaler interface
 func (p *HostPolicy) UnmarshalText(b []byte) error {
 	s := strings.Trim(string(b), `"`)
@@ -7,6 +7,7 @@ package servicedefinition
 import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/commons"
+	"github.com/control-center/serviced/commons/cron"
 	"github.com/control-center/serviced/validation"
 
 	"fmt"
@@ -20,8 +21,10 @@ func (sd *ServiceDefinition) ValidEntity() error {
 
 	context := validationContext{make(map[string]EndpointDefinition)}
 	//TODO: do servicedefinition names need to be unique?
-	err := sd.validate(&context)
-	return err
+	if err := sd.validate(&context); err != nil {
+		return err
+	}
+	return ValidDependencies([]ServiceDefinition{*sd})
 }
 
 //validate ServiceDefinition configuration and any embedded ServiceDefinitions
@@ -37,6 +40,28 @@ func (sd *ServiceDefinition) validate(context *validationContext) error {
 		return fmt.Errorf("service definition %v: invalid launch setting %v", sd.Name, err)
 	}
 
+	if err := sd.HostSelector.ValidEntity(); err != nil {
+		return fmt.Errorf("service definition %v: %v", sd.Name, err)
+	}
+
+	if err := sd.ScalingPolicy.ValidEntity(); err != nil {
+		return fmt.Errorf("service definition %v: %v", sd.Name, err)
+	} else if sd.ScalingPolicy.Enabled() && sd.Instances.Max == 0 {
+		return fmt.Errorf("service definition %v: scaling policy requires a maximum number of instances", sd.Name)
+	}
+
+	for name, hc := range sd.HealthChecks {
+		if err := hc.ValidEntity(); err != nil {
+			return fmt.Errorf("service definition %v: health check %s: %v", sd.Name, name, err)
+		}
+	}
+
+	if err := sd.FailurePolicy.ValidEntity(); err != nil {
+		return fmt.Errorf("service definition %v: %v", sd.Name, err)
+	} else if sd.FailurePolicy.Enabled() && len(sd.HealthChecks) == 0 {
+		return fmt.Errorf("service definition %v: failure policy requires health checks", sd.Name)
+	}
+
 	//validate endpoint config
 	names := make(map[string]struct{})
 	for _, se := range sd.Endpoints {
@@ -102,6 +127,14 @@ func (se EndpointDefinition) ValidEntity() error {
 	if trimName == "" {
 		return fmt.Errorf("service definition %v: endpoint must have a name %v", se.Name, se)
 	}
+	if se.LoadBalancer != "" {
+		if err := validation.StringIn(se.LoadBalancer, LoadBalancerRoundRobin, LoadBalancerLeastConnections, LoadBalancerTwoChoices); err != nil {
+			return fmt.Errorf("endpoint '%s': invalid load balancer %v", se.Name, err)
+		}
+	}
+	if len(se.VHosts) > 0 && strings.ToLower(se.Protocol) == commons.UDP {
+		return fmt.Errorf("endpoint '%s': vhosts are not supported for udp endpoints", se.Name)
+	}
 	if se.Purpose != "import" {
 		if err := validation.ValidPort(int(se.PortNumber)); err != nil {
 			return fmt.Errorf("endpoint '%s': %s", se.Name, err)
@@ -121,6 +154,77 @@ func applicationValidation(application string) error {
 	return err
 }
 
+//ValidEntity used to make sure HostSelector is in a valid state
+func (hs HostSelector) ValidEntity() error {
+	for key := range hs.RequiredLabels {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("required host label must have a name")
+		}
+	}
+	for key := range hs.PreferredLabels {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("preferred host label must have a name")
+		}
+	}
+	for _, name := range hs.Affinity {
+		for _, anti := range hs.AntiAffinity {
+			if name == anti {
+				return fmt.Errorf("service %s cannot be in both affinity and anti-affinity", name)
+			}
+		}
+	}
+	return nil
+}
+
+//ValidEntity used to make sure ScalingPolicy is in a valid state
+func (p ScalingPolicy) ValidEntity() error {
+	if !p.Enabled() {
+		return nil
+	}
+	if p.Target <= 0 {
+		return fmt.Errorf("scaling policy for metric %s must have a positive target", p.Metric)
+	}
+	if p.Cooldown < 0 {
+		return fmt.Errorf("scaling policy for metric %s cannot have a negative cooldown", p.Metric)
+	}
+	return nil
+}
+
+//ValidEntity used to make sure FailurePolicy is in a valid state
+func (p FailurePolicy) ValidEntity() error {
+	if p.Failures < 0 {
+		return fmt.Errorf("failure policy cannot have a negative number of failures")
+	}
+	if !p.Enabled() {
+		return nil
+	}
+	if p.Backoff < 0 || p.Window < 0 {
+		return fmt.Errorf("failure policy cannot have a negative backoff or window")
+	}
+	if p.MaxRestarts < 0 {
+		return fmt.Errorf("failure policy cannot have a negative number of restarts")
+	} else if p.MaxRestarts > MaxFailureRestarts {
+		return fmt.Errorf("failure policy cannot allow more than %d restarts within its window", MaxFailureRestarts)
+	} else if p.MaxRestarts > 0 && p.Window == 0 {
+		return fmt.Errorf("failure policy requires a window to limit the number of restarts")
+	}
+	return nil
+}
+
+//ValidEntity used to make sure SnapshotPolicy is in a valid state
+func (p SnapshotPolicy) ValidEntity() error {
+	if !p.Enabled() {
+		return nil
+	}
+	if _, err := cron.Parse(p.Schedule); err != nil {
+		return fmt.Errorf("snapshot policy: %v", err)
+	}
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 {
+		return fmt.Errorf("snapshot policy cannot keep a negative number of snapshots")
+	}
+	return nil
+}
+
 //ValidEntity used to make sure AddressResourceConfig is in a valid state
 func (arc AddressResourceConfig) ValidEntity() error {
 	//check if protocol set or port not 0
@@ -6,11 +6,15 @@ package servicedefinition_test
 
 import (
 	"github.com/control-center/serviced/commons"
+	"github.com/control-center/serviced/domain"
 	. "github.com/control-center/serviced/domain/servicedefinition"
 	. "github.com/control-center/serviced/domain/servicedefinition/testutils"
 
+	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestServiceDefinitionValidate(t *testing.T) {
@@ -126,3 +130,314 @@ func TestServiceDefinitionEmptyEndpointName(t *testing.T) {
 		t.Errorf("Unexpected Error %v", err)
 	}
 }
+
+func TestServiceDefinitionEndpointLoadBalancer(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].Endpoints[0].LoadBalancer = LoadBalancerLeastConnections
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	sd.Services[0].Endpoints[0].LoadBalancer = "fastest"
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "invalid load balancer") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+}
+
+func TestServiceDefinitionEndpointUDPVHosts(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].Endpoints[0].Protocol = "UDP"
+	sd.Services[0].Endpoints[0].VHosts = []string{"syslog"}
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "vhosts are not supported") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+}
+
+func TestServiceDefinitionHostSelector(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].HostSelector = HostSelector{
+		RequiredLabels: map[string]string{"disk": "ssd"},
+		Affinity:       []string{"zope"},
+		AntiAffinity:   []string{"mysql"},
+	}
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	sd.Services[0].HostSelector.RequiredLabels[" "] = "ssd"
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "required host label must have a name") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+	delete(sd.Services[0].HostSelector.RequiredLabels, " ")
+
+	sd.Services[0].HostSelector.AntiAffinity = []string{"zope"}
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "both affinity and anti-affinity") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+}
+
+func TestServiceDefinitionDependencies(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].DependsOn = []string{"websvc"}
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	sd.Services[0].DependsOn = []string{"www"}
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "depends on application www which it does not import") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	sd.Services[0].DependsOn = []string{"websvc"}
+	sd.Services[1].StartAfter = []string{"s1"}
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "dependency cycle s1 -> s2 -> s1") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	sd.Services[0].DependsOn = nil
+	sd.Services[1].StartAfter = []string{"s2"}
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "dependency cycle s2 -> s2") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	// services outside of the hierarchy may be deployed elsewhere
+	sd.Services[1].StartAfter = []string{"mysql"}
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestServiceDefinitionScalingPolicy(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].ScalingPolicy = ScalingPolicy{Metric: "cpu.user", Target: 50, Cooldown: time.Minute}
+	sd.Services[0].Instances = domain.MinMax{Min: 1, Max: 4}
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	sd.Services[0].ScalingPolicy.Target = 0
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "must have a positive target") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	sd.Services[0].ScalingPolicy.Target = 50
+	sd.Services[0].Instances.Max = 0
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "requires a maximum number of instances") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+}
+
+func TestScalingPolicyDesiredInstances(t *testing.T) {
+	policy := ScalingPolicy{Metric: "cpu.user", Target: 50}
+	limits := domain.MinMax{Min: 0, Max: 6}
+	for _, c := range []struct {
+		current  int
+		value    float64
+		expected int
+	}{
+		{2, 50, 2},  // on target
+		{2, 54, 2},  // within tolerance
+		{2, 100, 4}, // twice the target
+		{2, 60, 3},  // rounds up
+		{4, 400, 6}, // capped at the maximum
+		{4, 10, 1},  // never below one instance
+		{4, 25, 2},  // half the target
+	} {
+		if actual := policy.DesiredInstances(limits, c.current, c.value); actual != c.expected {
+			t.Errorf("%d instances at %v: expected %d; got %d", c.current, c.value, c.expected, actual)
+		}
+	}
+
+	limits.Min = 3
+	if actual := policy.DesiredInstances(limits, 4, 10); actual != 3 {
+		t.Errorf("expected the minimum of 3 instances; got %d", actual)
+	}
+}
+
+func TestScalingPolicyJSON(t *testing.T) {
+	policy := ScalingPolicy{Metric: "cpu.user", Target: 50, Cooldown: 90 * time.Second}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if !strings.Contains(string(data), `"Cooldown":90`) {
+		t.Errorf("Expected the cooldown in seconds; got %s", data)
+	}
+
+	var actual ScalingPolicy
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if actual != policy {
+		t.Errorf("MISMATCH: %+v != %+v", policy, actual)
+	}
+}
+
+func TestServiceDefinitionFailurePolicy(t *testing.T) {
+	sd := CreateValidServiceDefinition()
+	sd.Services[0].FailurePolicy = FailurePolicy{Failures: 3, Backoff: time.Minute, MaxRestarts: 5, Window: time.Hour}
+	sd.Services[0].HealthChecks = map[string]domain.HealthCheck{"ready": {Script: "true", Interval: time.Second}}
+	if err := sd.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	sd.Services[0].FailurePolicy.Window = 0
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "requires a window") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	sd.Services[0].FailurePolicy.Window = time.Hour
+	sd.Services[0].FailurePolicy.MaxRestarts = MaxFailureRestarts + 1
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "more than") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	sd.Services[0].FailurePolicy.MaxRestarts = 5
+	sd.Services[0].HealthChecks = nil
+	if err := sd.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "requires health checks") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+}
+
+func TestFailurePolicyNextRestart(t *testing.T) {
+	policy := FailurePolicy{Failures: 3, Backoff: time.Minute, MaxRestarts: 3, Window: time.Hour}
+	now := time.Now()
+
+	if next, ok := policy.NextRestart(nil, now); !ok || !next.Equal(now) {
+		t.Errorf("Expected a first restart right away; got %s, %t", next, ok)
+	}
+
+	// the backoff doubles with each restart within the window
+	restarts := []time.Time{now.Add(-2 * time.Hour), now.Add(-10 * time.Minute), now.Add(-time.Minute)}
+	if next, ok := policy.NextRestart(restarts, now); !ok || !next.Equal(restarts[2].Add(2*time.Minute)) {
+		t.Errorf("Expected the next restart 2m after the last; got %s, %t", next, ok)
+	}
+
+	restarts = append(restarts, now)
+	if _, ok := policy.NextRestart(restarts, now); ok {
+		t.Errorf("Expected no restart after %d restarts within the window", policy.MaxRestarts)
+	}
+	if recent := policy.Recent(restarts, now); len(recent) != 3 {
+		t.Errorf("Expected 3 recent restarts; got %v", recent)
+	}
+
+	// without a window, restarts are never forgotten and the backoff is capped
+	policy = FailurePolicy{Failures: 1, Backoff: 10 * time.Minute}
+	restarts = make([]time.Time, 30)
+	for i := range restarts {
+		restarts[i] = now.Add(-time.Duration(30-i) * time.Hour)
+	}
+	if next, ok := policy.NextRestart(restarts, now); !ok || !next.Equal(restarts[29].Add(time.Hour)) {
+		t.Errorf("Expected the next restart an hour after the last; got %s, %t", next, ok)
+	}
+}
+
+func TestFailurePolicyJSON(t *testing.T) {
+	policy := FailurePolicy{Failures: 3, Backoff: 30 * time.Second, MaxRestarts: 5, Window: time.Hour}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if !strings.Contains(string(data), `"Backoff":30`) || !strings.Contains(string(data), `"Window":3600`) {
+		t.Errorf("Expected the backoff and the window in seconds; got %s", data)
+	}
+
+	var actual FailurePolicy
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if actual != policy {
+		t.Errorf("MISMATCH: %+v != %+v", policy, actual)
+	}
+}
+
+func TestSnapshotPolicyValidEntity(t *testing.T) {
+	policy := SnapshotPolicy{Schedule: "0 2 * * *", KeepLast: 3, KeepDaily: 7, KeepWeekly: 4}
+	if err := policy.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	policy.Schedule = "0 25 * * *"
+	if err := policy.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "invalid hour") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	policy.Schedule = "@daily"
+	policy.KeepLast = -1
+	if err := policy.ValidEntity(); err == nil {
+		t.Error("Expected error")
+	} else if !strings.Contains(err.Error(), "negative number of snapshots") {
+		t.Errorf("Unexpected Error %v", err)
+	}
+
+	// disabled policies are not checked
+	if err := (SnapshotPolicy{KeepLast: -1}).ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSnapshotPolicyExpired(t *testing.T) {
+	// a thursday
+	now := time.Date(2014, 10, 16, 12, 0, 0, 0, time.UTC)
+	day := func(d, h int) time.Time { return time.Date(2014, 10, d, h, 0, 0, 0, time.UTC) }
+
+	// two a day since the first, oldest first
+	var created []time.Time
+	for d := 1; d <= 16; d++ {
+		created = append(created, day(d, 2), day(d, 14))
+	}
+	created = created[:len(created)-1] // the afternoon of the 16th has yet to come
+
+	kept := func(policy SnapshotPolicy) []time.Time {
+		expired := make(map[int]bool)
+		for _, i := range policy.Expired(created, now) {
+			expired[i] = true
+		}
+		var result []time.Time
+		for i, t := range created {
+			if !expired[i] {
+				result = append(result, t)
+			}
+		}
+		return result
+	}
+
+	for _, c := range []struct {
+		policy   SnapshotPolicy
+		expected []time.Time
+	}{
+		// no rules keep everything
+		{SnapshotPolicy{Schedule: "@daily"}, created},
+		{SnapshotPolicy{KeepLast: 3}, []time.Time{day(15, 2), day(15, 14), day(16, 2)}},
+		{SnapshotPolicy{KeepDaily: 3}, []time.Time{day(14, 14), day(15, 14), day(16, 2)}},
+		// weeks start on monday the 29th of september, the 6th and the 13th
+		{SnapshotPolicy{KeepWeekly: 3}, []time.Time{day(5, 14), day(12, 14), day(16, 2)}},
+		{SnapshotPolicy{KeepLast: 2, KeepDaily: 2, KeepWeekly: 2}, []time.Time{day(12, 14), day(15, 14), day(16, 2)}},
+	} {
+		if actual := kept(c.policy); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%+v: expected %v; got %v", c.policy, c.expected, actual)
+		}
+	}
+}
@@ -43,6 +43,8 @@ type ServiceState struct {
 	Endpoints  []service.ServiceEndpoint
 	HostIP     string
 	InstanceID int
+	Restarts   int         // Times the instance was restarted for failing its health checks
+	RestartLog []time.Time // When the most recent of those restarts happened, oldest first
 }
 
 func (ss *ServiceState) evalPortTemplate(portTemplate string) (int, error) {
@@ -49,6 +49,11 @@ func (st *ServiceTemplate) ValidEntity() error {
 		violations.Add(servicedefinition.Walk(&sd, visit))
 	}
 
+	//each hierarchy checks its own dependencies, but they may also depend on each other
+	if len(st.Services) > 1 {
+		violations.Add(servicedefinition.ValidDependencies(st.Services))
+	}
+
 	if len(violations.Errors) > 0 {
 		return violations
 	}
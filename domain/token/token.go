@@ -0,0 +1,127 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package token issues API tokens that let non-interactive clients call the
+// REST API without logging in. Only a hash of the secret part of a token is
+// stored, so a token cannot be recovered after it has been created.
+package token
+
+import (
+	"github.com/control-center/serviced/domain/role"
+
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed, unknown or
+	// revoked
+	ErrInvalidToken = errors.New("invalid API token")
+	// ErrTokenExpired is returned for tokens that have expired
+	ErrTokenExpired = errors.New("API token has expired")
+)
+
+// separates the ID of a token from its secret
+const separator = "."
+
+// Token is a long-lived credential of a user
+type Token struct {
+	ID          string
+	User        string
+	Description string
+	Hash        string       // SHA-256 of the secret, hex encoded
+	Grants      []role.Grant // Limits the token to these roles; empty for all of the user's roles
+	Created     time.Time
+	Expires     time.Time // Zero if the token never expires
+	LastUsed    time.Time
+}
+
+// New creates a token for the user that expires after the ttl, or never if
+// the ttl is zero. It returns the token to store and the value clients
+// present as a bearer token, which is the only copy of the secret.
+func New(user, description string, grants []role.Grant, ttl time.Duration) (*Token, string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t := &Token{
+		ID:          id,
+		User:        user,
+		Description: description,
+		Hash:        hash(secret),
+		Grants:      grants,
+		Created:     time.Now().UTC(),
+	}
+	if ttl > 0 {
+		t.Expires = t.Created.Add(ttl)
+	}
+	return t, id + separator + secret, nil
+}
+
+// Parse splits the value of a bearer token into the ID of the token and its
+// secret
+func Parse(bearer string) (id, secret string, err error) {
+	parts := strings.SplitN(bearer, separator, 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrInvalidToken
+	}
+	return parts[0], parts[1], nil
+}
+
+// Matches reports whether the secret is the secret of the token
+func (t *Token) Matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(t.Hash)) == 1
+}
+
+// Expired reports whether the token has expired by the given time
+func (t *Token) Expired(now time.Time) bool {
+	return !t.Expires.IsZero() && !now.Before(t.Expires)
+}
+
+// Allows reports whether the scope of the token permits the request. The
+// roles of the token's user have to permit the request as well.
+func (t *Token) Allows(r role.Request) bool {
+	if len(t.Grants) == 0 {
+		return true
+	}
+	for _, g := range t.Grants {
+		if g.Allows(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// IsErrInvalid checks if the error is ErrInvalidToken or ErrTokenExpired.
+// Errors that have been passed over rpc lose their identity, so the message
+// is checked too.
+func IsErrInvalid(err error) bool {
+	if err == nil {
+		return false
+	}
+	return err.Error() == ErrInvalidToken.Error() || err.Error() == ErrTokenExpired.Error()
+}
@@ -0,0 +1,95 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"github.com/control-center/serviced/domain/role"
+
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tok, bearer, err := New("bob", "nightly backups", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := tok.ValidEntity(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if strings.Contains(tok.Hash, strings.SplitN(bearer, ".", 2)[1]) {
+		t.Errorf("Expected the secret not to be stored")
+	}
+
+	id, secret, err := Parse(bearer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if id != tok.ID {
+		t.Errorf("Expected token %s, got %s", tok.ID, id)
+	}
+	if !tok.Matches(secret) {
+		t.Errorf("Expected the secret to match")
+	}
+	if tok.Matches(secret + "0") {
+		t.Errorf("Expected a different secret not to match")
+	}
+
+	if tok.Expired(time.Now()) {
+		t.Errorf("Expected the token not to have expired")
+	}
+	if !tok.Expired(time.Now().Add(time.Hour)) {
+		t.Errorf("Expected the token to have expired")
+	}
+	if tok, _, _ := New("bob", "", nil, 0); tok.Expired(time.Now().AddDate(10, 0, 0)) {
+		t.Errorf("Expected a token without a ttl never to expire")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, bearer := range []string{"", "abc", ".abc", "abc."} {
+		if _, _, err := Parse(bearer); err != ErrInvalidToken {
+			t.Errorf("Expected %q to be invalid, got %v", bearer, err)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	request := role.Request{Action: role.Operate, Resource: role.ResourceService, PoolID: "default"}
+
+	tok := Token{}
+	if !tok.Allows(request) {
+		t.Errorf("Expected a token without grants to allow %+v", request)
+	}
+	tok.Grants = []role.Grant{{Role: role.Viewer}}
+	if tok.Allows(request) {
+		t.Errorf("Expected a read only token to deny %+v", request)
+	}
+	tok.Grants = append(tok.Grants, role.Grant{Role: role.Operator, PoolID: "default"})
+	if !tok.Allows(request) {
+		t.Errorf("Expected %+v to allow %+v", tok.Grants, request)
+	}
+}
+
+func TestValidEntity(t *testing.T) {
+	tok, _, _ := New("bob", "", []role.Grant{{Role: "root"}}, 0)
+	if err := tok.ValidEntity(); err == nil {
+		t.Errorf("Expected an error for an unknown role")
+	}
+	tok.Grants, tok.Hash = nil, "secret"
+	if err := tok.ValidEntity(); err == nil {
+		t.Errorf("Expected an error for an unhashed secret")
+	}
+}
+
+func TestIsErrInvalid(t *testing.T) {
+	if !IsErrInvalid(ErrInvalidToken) || !IsErrInvalid(ErrTokenExpired) || !IsErrInvalid(errors.New(ErrTokenExpired.Error())) {
+		t.Errorf("Expected token errors to be invalid")
+	}
+	if IsErrInvalid(nil) || IsErrInvalid(errors.New("connection refused")) {
+		t.Errorf("Expected other errors not to be invalid")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore/elastic"
+)
+
+var (
+	mappingString = `
+{
+    "apitoken": {
+      "properties":{
+        "ID" :          {"type": "string", "index":"not_analyzed"},
+        "User":         {"type": "string", "index":"not_analyzed"},
+        "Description":  {"type": "string", "index":"not_analyzed"},
+        "Hash":         {"type": "string", "index":"not_analyzed"},
+        "Grants" :{
+          "properties":{
+            "Role" :     {"type": "string", "index":"not_analyzed"},
+            "Resource" : {"type": "string", "index":"not_analyzed"},
+            "PoolID" :   {"type": "string", "index":"not_analyzed"},
+            "TenantID" : {"type": "string", "index":"not_analyzed"}
+          }
+        },
+        "Created" :     {"type": "date", "format" : "dateOptionalTime"},
+        "Expires" :     {"type": "date", "format" : "dateOptionalTime"},
+        "LastUsed" :    {"type": "date", "format" : "dateOptionalTime"}
+      }
+    }
+}
+`
+	//MAPPING is the elastic mapping for an API token
+	MAPPING, mappingError = elastic.NewMapping(mappingString)
+)
+
+func init() {
+	if mappingError != nil {
+		glog.Fatalf("error creating API token mapping: %v", mappingError)
+	}
+}
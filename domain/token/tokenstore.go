@@ -0,0 +1,60 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"github.com/zenoss/elastigo/search"
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+
+	"fmt"
+)
+
+//NewStore creates an API token store
+func NewStore() *Store {
+	return &Store{}
+}
+
+//Store type for interacting with API token persistent storage
+type Store struct {
+	datastore.DataStore
+}
+
+//GetTokens returns the tokens of the user, or of every user if user is empty
+func (s *Store) GetTokens(ctx datastore.Context, user string) ([]*Token, error) {
+	glog.V(3).Infof("Token Store.GetTokens %s", user)
+	queryString := "_exists_:Hash"
+	if user != "" {
+		queryString = fmt.Sprintf("%s AND User:%q", queryString, user)
+	}
+
+	q := datastore.NewQuery(ctx)
+	query := search.Query().Search(queryString)
+	search := search.Search("controlplane").Type(kind).Size("10000").Query(query)
+	results, err := q.Execute(search)
+	if err != nil {
+		return nil, err
+	}
+	return convert(results)
+}
+
+//Key creates a Key suitable for getting, putting and deleting API tokens
+func Key(id string) datastore.Key {
+	return datastore.NewKey(kind, id)
+}
+
+func convert(results datastore.Results) ([]*Token, error) {
+	tokens := make([]*Token, results.Len())
+	for idx := range tokens {
+		var t Token
+		if err := results.Get(idx, &t); err != nil {
+			return nil, err
+		}
+		tokens[idx] = &t
+	}
+	return tokens, nil
+}
+
+var kind = "apitoken"
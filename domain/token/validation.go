@@ -0,0 +1,36 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/validation"
+
+	"strings"
+)
+
+//ValidEntity validates Token fields
+func (t *Token) ValidEntity() error {
+	glog.V(4).Info("Validating Token")
+
+	violations := validation.NewValidationError()
+	violations.Add(validation.NotEmpty("Token.ID", t.ID))
+	violations.Add(validation.NotEmpty("Token.User", t.User))
+	violations.Add(validation.StringsEqual(t.User, strings.TrimSpace(t.User), "leading and trailing spaces not allowed for user name"))
+	if len(t.Hash) != 64 {
+		violations.AddViolation("Token.Hash must be a hex encoded SHA-256")
+	}
+	for i := range t.Grants {
+		violations.Add(t.Grants[i].ValidEntity())
+	}
+	if !t.Expires.IsZero() && !t.Expires.After(t.Created) {
+		violations.AddViolation("Token.Expires must be after Token.Created")
+	}
+
+	if len(violations.Errors) > 0 {
+		return violations
+	}
+	return nil
+}
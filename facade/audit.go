@@ -0,0 +1,48 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/audit"
+	"github.com/control-center/serviced/utils"
+)
+
+// Audit appends an entry to the audit log for a change made to an entity on
+// behalf of the caller of the context. The change has already been made, so
+// failures to record it are logged rather than returned.
+func (f *Facade) Audit(ctx datastore.Context, action, entityType, entityID string, before, after interface{}) {
+	caller := datastore.CallerOf(ctx)
+	entry := audit.Entry{
+		Timestamp:  time.Now().UTC(),
+		User:       caller.User,
+		Source:     caller.Source,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       audit.Diff(before, after),
+	}
+
+	var err error
+	if entry.ID, err = utils.NewUUID36(); err != nil {
+		glog.Errorf("Unable to audit %s of %s %s: %s", action, entityType, entityID, err)
+		return
+	}
+	if err := f.auditStore.Put(ctx, audit.Key(entry.ID), &entry); err != nil {
+		glog.Errorf("Unable to audit %s of %s %s: %s", action, entityType, entityID, err)
+		return
+	}
+	glog.V(2).Infof("Facade.Audit: %+v", entry)
+}
+
+// GetAuditEntries returns the audit log entries that match the filter, newest
+// first
+func (f *Facade) GetAuditEntries(ctx datastore.Context, filter audit.Filter) ([]*audit.Entry, error) {
+	glog.V(3).Infof("Facade.GetAuditEntries: %+v", filter)
+	return f.auditStore.Search(ctx, filter)
+}
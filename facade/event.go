@@ -0,0 +1,80 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/notify"
+	"github.com/control-center/serviced/utils"
+)
+
+// RaiseEvent records that a threshold of an entity is breached. The active
+// event of the threshold is updated if there is one, otherwise a new event is
+// created, and the notification sinks are told about it.
+func (f *Facade) RaiseEvent(ctx datastore.Context, e event.Event) (*event.Event, error) {
+	glog.V(2).Infof("Facade.RaiseEvent: %s %s %s", e.EntityType, e.EntityID, e.ThresholdID)
+	now := time.Now().UTC()
+
+	active, err := f.eventStore.GetActive(ctx, e.EntityType, e.EntityID, e.ThresholdID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		e.ID = active.ID
+		e.FirstSeen = active.FirstSeen
+		e.Count = active.Count + 1
+	} else {
+		if e.ID, err = utils.NewUUID36(); err != nil {
+			return nil, err
+		}
+		e.FirstSeen = now
+		e.Count = 1
+		glog.Infof("Threshold %s of %s %s breached: %s", e.ThresholdID, e.EntityType, e.EntityID, e.Summary)
+	}
+	e.LastSeen = now
+	e.Cleared = time.Time{}
+	e.Active = true
+
+	if err := e.ValidEntity(); err != nil {
+		return nil, err
+	}
+	if err := f.eventStore.Put(ctx, event.Key(e.ID), &e); err != nil {
+		return nil, err
+	}
+	if active == nil {
+		f.notifyEvent(ctx, &e, notify.StateRaised)
+	}
+	return &e, nil
+}
+
+// ClearEvent clears the active event of a threshold of an entity, if there is
+// one. Returns the cleared event, or nil if the threshold was not breached.
+func (f *Facade) ClearEvent(ctx datastore.Context, entityType, entityID, thresholdID string) (*event.Event, error) {
+	glog.V(3).Infof("Facade.ClearEvent: %s %s %s", entityType, entityID, thresholdID)
+	e, err := f.eventStore.GetActive(ctx, entityType, entityID, thresholdID)
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	e.Active = false
+	e.Cleared = time.Now().UTC()
+	if err := f.eventStore.Put(ctx, event.Key(e.ID), e); err != nil {
+		return nil, err
+	}
+	glog.Infof("Threshold %s of %s %s cleared", thresholdID, entityType, entityID)
+	f.notifyEvent(ctx, e, notify.StateCleared)
+	return e, nil
+}
+
+// GetEvents returns the events that match the filter, most recently seen
+// first
+func (f *Facade) GetEvents(ctx datastore.Context, filter event.Filter) ([]*event.Event, error) {
+	glog.V(3).Infof("Facade.GetEvents: %+v", filter)
+	return f.eventStore.Search(ctx, filter)
+}
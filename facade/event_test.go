@@ -0,0 +1,47 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/domain/event"
+	. "gopkg.in/check.v1"
+)
+
+func (ft *FacadeTest) Test_RaiseClearEvent(t *C) {
+	breach := event.Event{EntityType: event.EntityHost, EntityID: "host-1", PoolID: "default", ThresholdID: "swap.empty", Metric: "swap.free", Value: 0}
+	raised, err := ft.Facade.RaiseEvent(ft.CTX, breach)
+	if err != nil {
+		t.Fatalf("Failure raising event: %s", err)
+	}
+	defer ft.Facade.eventStore.Delete(ft.CTX, event.Key(raised.ID))
+	if !raised.Active || raised.Count != 1 || raised.FirstSeen.IsZero() {
+		t.Errorf("Unexpected event %+v", raised)
+	}
+
+	again, err := ft.Facade.RaiseEvent(ft.CTX, breach)
+	if err != nil {
+		t.Fatalf("Failure raising event: %s", err)
+	}
+	if again.ID != raised.ID || again.Count != 2 || !again.FirstSeen.Equal(raised.FirstSeen) {
+		t.Errorf("Expected the event to be updated, got %+v", again)
+	}
+
+	cleared, err := ft.Facade.ClearEvent(ft.CTX, event.EntityHost, "host-1", "swap.empty")
+	if err != nil {
+		t.Fatalf("Failure clearing event: %s", err)
+	} else if cleared == nil || cleared.Active || cleared.Cleared.IsZero() {
+		t.Errorf("Unexpected cleared event %+v", cleared)
+	}
+
+	if cleared, err := ft.Facade.ClearEvent(ft.CTX, event.EntityHost, "host-1", "swap.empty"); err != nil || cleared != nil {
+		t.Errorf("Expected nothing to clear, got %+v, %v", cleared, err)
+	}
+	events, err := ft.Facade.GetEvents(ft.CTX, event.Filter{EntityID: "host-1"})
+	if err != nil {
+		t.Fatalf("Failure getting events: %s", err)
+	} else if len(events) != 1 || events[0].Active {
+		t.Errorf("Unexpected events %+v", events)
+	}
+}
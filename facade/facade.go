@@ -5,28 +5,46 @@
 package facade
 
 import (
+	"github.com/control-center/serviced/domain/audit"
+	"github.com/control-center/serviced/domain/event"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/notification"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicetemplate"
+	"github.com/control-center/serviced/domain/token"
+	"github.com/control-center/serviced/notify"
 )
 
 // New creates an initialized Facade instance
 func New(dockerRegistry string) *Facade {
 	return &Facade{
-		hostStore:      host.NewStore(),
-		poolStore:      pool.NewStore(),
-		serviceStore:   service.NewStore(),
-		templateStore:  servicetemplate.NewStore(),
-		dockerRegistry: dockerRegistry,
+		auditStore:        audit.NewStore(),
+		eventStore:        event.NewStore(),
+		hostStore:         host.NewStore(),
+		notificationStore: notification.NewStore(),
+		poolStore:         pool.NewStore(),
+		roleStore:         role.NewStore(),
+		serviceStore:      service.NewStore(),
+		templateStore:     servicetemplate.NewStore(),
+		tokenStore:        token.NewStore(),
+		notifier:          notify.NewDispatcher(),
+		dockerRegistry:    dockerRegistry,
 	}
 }
 
 // Facade is an entrypoint to available controlplane methods
 type Facade struct {
-	hostStore      *host.HostStore
-	poolStore      *pool.Store
-	templateStore  *servicetemplate.Store
-	serviceStore   *service.Store
-	dockerRegistry string
+	auditStore        *audit.Store
+	eventStore        *event.Store
+	hostStore         *host.HostStore
+	notificationStore *notification.Store
+	poolStore         *pool.Store
+	roleStore         *role.Store
+	templateStore     *servicetemplate.Store
+	serviceStore      *service.Store
+	tokenStore        *token.Store
+	notifier          *notify.Dispatcher
+	dockerRegistry    string
 }
@@ -57,6 +57,7 @@ func (f *Facade) AddHost(ctx datastore.Context, entity *host.Host) error {
 	if err = zkAPI(f).RegisterHost(entity); err != nil {
 		return err
 	}
+	f.Audit(ctx, "add", "host", entity.ID, nil, entity)
 
 	defer f.afterEvent(afterHostAdd, ec, entity, err)
 	return err
@@ -67,13 +68,21 @@ func (f *Facade) AddHost(ctx datastore.Context, entity *host.Host) error {
 func (f *Facade) UpdateHost(ctx datastore.Context, entity *host.Host) error {
 	glog.V(2).Infof("Facade.UpdateHost: %+v", entity)
 	//TODO: make sure pool exists
+	previous, err := f.GetHost(ctx, entity.ID)
+	if err != nil {
+		return err
+	}
+
 	ec := newEventCtx()
-	err := f.beforeEvent(beforeHostAdd, ec, entity)
+	err = f.beforeEvent(beforeHostAdd, ec, entity)
 	if err == nil {
 		now := time.Now()
 		entity.UpdatedAt = now
 		err = f.hostStore.Put(ctx, host.HostKey(entity.ID), entity)
 	}
+	if err == nil {
+		f.Audit(ctx, "update", "host", entity.ID, previous, entity)
+	}
 	defer f.afterEvent(afterHostAdd, ec, entity, err)
 	return err
 }
@@ -97,7 +106,9 @@ func (f *Facade) RemoveHost(ctx datastore.Context, hostID string) (err error) {
 		return err
 	}
 
-	err = f.hostStore.Delete(ctx, host.HostKey(hostID))
+	if err = f.hostStore.Delete(ctx, host.HostKey(hostID)); err == nil {
+		f.Audit(ctx, "remove", "host", hostID, _host, nil)
+	}
 	return err
 }
 
@@ -0,0 +1,197 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/notification"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/notify"
+	"github.com/control-center/serviced/utils"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+)
+
+// AddNotificationSink configures a sink for the notifications of a pool or a
+// tenant, and returns its id
+func (f *Facade) AddNotificationSink(ctx datastore.Context, sink notification.Sink) (string, error) {
+	glog.V(2).Infof("Facade.AddNotificationSink: %s %s", sink.Name, sink.Type)
+	var err error
+	if sink.ID, err = utils.NewUUID36(); err != nil {
+		return "", err
+	}
+	sink.Created = time.Now().UTC()
+	if err := sink.ValidEntity(); err != nil {
+		return "", err
+	}
+	if err := f.notificationStore.Put(ctx, notification.Key(sink.ID), &sink); err != nil {
+		return "", err
+	}
+	f.Audit(ctx, "add", "sink", sink.ID, nil, sink.WithoutSecrets())
+	return sink.ID, nil
+}
+
+// getNotificationSink gets a sink with its secrets. Returns nil if the sink
+// does not exist.
+func (f *Facade) getNotificationSink(ctx datastore.Context, id string) (*notification.Sink, error) {
+	var sink notification.Sink
+	if err := f.notificationStore.Get(ctx, notification.Key(id), &sink); datastore.IsErrNoSuchEntity(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &sink, nil
+}
+
+// GetNotificationSink gets a notification sink by id, without its secrets.
+// Returns nil if the sink does not exist.
+func (f *Facade) GetNotificationSink(ctx datastore.Context, id string) (*notification.Sink, error) {
+	glog.V(3).Infof("Facade.GetNotificationSink: %s", id)
+	sink, err := f.getNotificationSink(ctx, id)
+	if err != nil || sink == nil {
+		return nil, err
+	}
+	return sink.WithoutSecrets(), nil
+}
+
+// GetNotificationSinks returns every notification sink, without their
+// secrets
+func (f *Facade) GetNotificationSinks(ctx datastore.Context) ([]*notification.Sink, error) {
+	glog.V(3).Infof("Facade.GetNotificationSinks")
+	sinks, err := f.notificationStore.GetSinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sinks {
+		sinks[i] = sinks[i].WithoutSecrets()
+	}
+	return sinks, nil
+}
+
+// RemoveNotificationSink removes a notification sink
+func (f *Facade) RemoveNotificationSink(ctx datastore.Context, id string) error {
+	glog.V(2).Infof("Facade.RemoveNotificationSink: %s", id)
+	sink, err := f.GetNotificationSink(ctx, id)
+	if err != nil {
+		return err
+	} else if sink == nil {
+		return fmt.Errorf("notification sink %s not found", id)
+	}
+	if err := f.notificationStore.Delete(ctx, notification.Key(id)); err != nil {
+		return err
+	}
+	f.Audit(ctx, "remove", "sink", id, sink, nil)
+	return nil
+}
+
+// TestNotificationSink sends a test notification to a sink right away, and
+// returns why it could not be sent
+func (f *Facade) TestNotificationSink(ctx datastore.Context, id string) error {
+	glog.V(2).Infof("Facade.TestNotificationSink: %s", id)
+	sink, err := f.getNotificationSink(ctx, id)
+	if err != nil {
+		return err
+	} else if sink == nil {
+		return fmt.Errorf("notification sink %s not found", id)
+	}
+
+	caller := datastore.CallerOf(ctx)
+	n := notify.Notification{
+		Key:       "test/" + sink.ID,
+		Kind:      notify.KindTest,
+		State:     notify.StateRaised,
+		PoolID:    sink.PoolID,
+		TenantID:  sink.TenantID,
+		Summary:   fmt.Sprintf("Test notification of sink %s, sent by %s", sink.Name, caller.User),
+		Timestamp: time.Now().UTC(),
+	}
+	if sink.PoolID != "" {
+		n.EntityType, n.EntityID = event.EntityPool, sink.PoolID
+	} else {
+		n.EntityType, n.EntityID = event.EntityService, sink.TenantID
+	}
+	return notify.Send(sink, n)
+}
+
+// Notify sends a notification to the sinks of its pool and tenant, unless it
+// is a duplicate or its condition is flapping
+func (f *Facade) Notify(ctx datastore.Context, n notify.Notification) {
+	sinks, err := f.notificationStore.GetSinks(ctx)
+	if err != nil {
+		glog.Warningf("Could not load notification sinks to notify %s %s: %s", n.Key, n.State, err)
+		return
+	}
+	var matches []*notification.Sink
+	for _, sink := range sinks {
+		if sink.Matches(n.PoolID, n.TenantID) {
+			matches = append(matches, sink)
+		}
+	}
+	f.notifier.Dispatch(n, matches)
+}
+
+// notifyEvent notifies the sinks that a threshold event was raised or
+// cleared
+func (f *Facade) notifyEvent(ctx datastore.Context, e *event.Event, state string) {
+	n := notify.Notification{
+		Key:        "threshold/" + e.EntityType + "/" + e.EntityID + "/" + e.ThresholdID,
+		Kind:       notify.KindThreshold,
+		State:      state,
+		Severity:   e.Severity(),
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		PoolID:     e.PoolID,
+		Summary:    e.Summary,
+		Timestamp:  e.LastSeen,
+		Tags:       e.Tags,
+	}
+	if state == notify.StateCleared {
+		n.Timestamp = e.Cleared
+		n.Summary = fmt.Sprintf("Threshold %s is no longer breached", e.ThresholdID)
+	}
+	if e.EntityType == event.EntityService {
+		if tenantID, err := f.GetTenantID(ctx, e.EntityID); err == nil {
+			n.TenantID = tenantID
+		}
+	}
+	f.Notify(ctx, n)
+}
+
+// NotifyHealthCheck tells the notification sinks of the service's pool and
+// tenant that a health check of an instance started failing or was missed, or
+// that it passes again
+func (f *Facade) NotifyHealthCheck(ctx datastore.Context, svc *service.Service, instanceID int, name, status string) {
+	tenantID, err := f.GetTenantID(ctx, svc.ID)
+	if err != nil {
+		glog.Warningf("Could not look up the tenant of service %s to notify health check %s: %s", svc.ID, name, err)
+		return
+	}
+
+	n := notify.Notification{
+		Key:        fmt.Sprintf("health/%s/%d/%s", svc.ID, instanceID, name),
+		Kind:       notify.KindHealth,
+		State:      notify.StateRaised,
+		Severity:   4,
+		EntityType: event.EntityService,
+		EntityID:   svc.ID,
+		PoolID:     svc.PoolID,
+		TenantID:   tenantID,
+		Timestamp:  time.Now().UTC(),
+	}
+	switch status {
+	case zkhealth.StatusFailed:
+		n.Summary = fmt.Sprintf("Health check %s of %s instance %d is failing", name, svc.Name, instanceID)
+	case zkhealth.StatusMissed:
+		n.Summary = fmt.Sprintf("Health check %s of %s instance %d has not reported in twice its interval", name, svc.Name, instanceID)
+	default:
+		n.State, n.Severity = notify.StateCleared, 0
+		n.Summary = fmt.Sprintf("Health check %s of %s instance %d is passing again", name, svc.Name, instanceID)
+	}
+	f.Notify(ctx, n)
+}
@@ -0,0 +1,50 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/domain/notification"
+	. "gopkg.in/check.v1"
+)
+
+func (ft *FacadeTest) Test_NotificationSinks(t *C) {
+	sink := notification.Sink{
+		Name:   "ops",
+		Type:   notification.TypeSMTP,
+		PoolID: "default",
+		SMTP:   &notification.SMTP{Server: "mail.example.com:25", From: "serviced@example.com", To: []string{"ops@example.com"}, Password: "secret"},
+	}
+	id, err := ft.Facade.AddNotificationSink(ft.CTX, sink)
+	if err != nil {
+		t.Fatalf("Failure adding sink: %s", err)
+	}
+	defer ft.Facade.notificationStore.Delete(ft.CTX, notification.Key(id))
+
+	if _, err := ft.Facade.AddNotificationSink(ft.CTX, notification.Sink{Name: "bad", Type: notification.TypeSMTP, PoolID: "default"}); err == nil {
+		t.Errorf("Expected an error adding a sink without its configuration")
+	}
+
+	sinks, err := ft.Facade.GetNotificationSinks(ft.CTX)
+	if err != nil {
+		t.Fatalf("Failure getting sinks: %s", err)
+	} else if len(sinks) != 1 || sinks[0].ID != id || sinks[0].SMTP.Password != "" {
+		t.Errorf("Unexpected sinks %+v", sinks)
+	}
+
+	// the secrets are kept for sending
+	if full, err := ft.Facade.getNotificationSink(ft.CTX, id); err != nil || full == nil || full.SMTP.Password != "secret" {
+		t.Errorf("Expected the sink with its password, got %+v, %v", full, err)
+	}
+
+	if err := ft.Facade.RemoveNotificationSink(ft.CTX, id); err != nil {
+		t.Fatalf("Failure removing sink: %s", err)
+	}
+	if err := ft.Facade.RemoveNotificationSink(ft.CTX, id); err == nil {
+		t.Errorf("Expected an error removing a missing sink")
+	}
+	if err := ft.Facade.TestNotificationSink(ft.CTX, id); err == nil {
+		t.Errorf("Expected an error testing a missing sink")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/validation"
 
 	"errors"
@@ -51,6 +52,9 @@ func (f *Facade) AddResourcePool(ctx datastore.Context, entity *pool.ResourcePoo
 		entity.UpdatedAt = now
 		err = f.poolStore.Put(ctx, pool.Key(entity.ID), entity)
 	}
+	if err == nil {
+		f.Audit(ctx, "add", "pool", entity.ID, nil, entity)
+	}
 	f.afterEvent(afterPoolAdd, ec, entity, err)
 	return err
 }
@@ -138,6 +142,10 @@ func (f *Facade) UpdateResourcePool(ctx datastore.Context, entity *pool.Resource
 	if err := f.validateVirtualIPs(ctx, entity); err != nil {
 		return err
 	}
+	var previous pool.ResourcePool
+	if err := f.poolStore.Get(ctx, pool.Key(entity.ID), &previous); err != nil && !datastore.IsErrNoSuchEntity(err) {
+		return err
+	}
 	ec := newEventCtx()
 	err := f.beforeEvent(beforePoolUpdate, ec, entity)
 	if err == nil {
@@ -145,6 +153,9 @@ func (f *Facade) UpdateResourcePool(ctx datastore.Context, entity *pool.Resource
 		entity.UpdatedAt = now
 		err = f.poolStore.Put(ctx, pool.Key(entity.ID), entity)
 	}
+	if err == nil {
+		f.Audit(ctx, "update", "pool", entity.ID, &previous, entity)
+	}
 	f.afterEvent(afterPoolUpdate, ec, entity, err)
 	return err
 }
@@ -159,7 +170,15 @@ func (f *Facade) RemoveResourcePool(ctx datastore.Context, id string) error {
 		return errors.New("cannot delete resource pool with hosts")
 	}
 
-	return f.delete(ctx, f.poolStore, pool.Key(id), beforePoolDelete, afterPoolDelete)
+	var previous pool.ResourcePool
+	if err := f.poolStore.Get(ctx, pool.Key(id), &previous); err != nil && !datastore.IsErrNoSuchEntity(err) {
+		return err
+	}
+	if err := f.delete(ctx, f.poolStore, pool.Key(id), beforePoolDelete, afterPoolDelete); err != nil {
+		return err
+	}
+	f.Audit(ctx, "remove", "pool", id, &previous, nil)
+	return nil
 }
 
 //GetResourcePools Returns a list of all ResourcePools
@@ -248,6 +267,55 @@ func (f *Facade) calcPoolCommitment(ctx datastore.Context, pool *pool.ResourcePo
 	return err
 }
 
+// poolCommitment sums the RAM and cores committed to the running services in
+// the pool, skipping the services in exclude
+func (f *Facade) poolCommitment(ctx datastore.Context, poolID string, exclude ...string) (uint64, uint64, error) {
+	services, err := f.serviceStore.GetServicesByPool(ctx, poolID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	skip := make(map[string]struct{})
+	for _, id := range exclude {
+		skip[id] = struct{}{}
+	}
+
+	var memCommitment, coreCommitment uint64
+	for _, svc := range services {
+		if _, ok := skip[svc.ID]; ok || svc.DesiredState != service.SVCRun {
+			continue
+		}
+		mem, cores := serviceCommitment(svc)
+		memCommitment += mem
+		coreCommitment += cores
+	}
+	return memCommitment, coreCommitment, nil
+}
+
+// checkPoolQuota returns a pool.ErrQuotaExceeded if the pool cannot take on
+// the requested RAM and cores on top of its running services
+func (f *Facade) checkPoolQuota(ctx datastore.Context, poolID string, mem, cores uint64, exclude ...string) error {
+	p, err := f.GetResourcePool(ctx, poolID)
+	if err != nil {
+		return err
+	} else if p == nil {
+		return fmt.Errorf("poolid %s not found", poolID)
+	} else if p.MemoryLimit == 0 && p.CoreLimit == 0 {
+		return nil
+	}
+
+	memCommitted, coresCommitted, err := f.poolCommitment(ctx, poolID, exclude...)
+	if err != nil {
+		return err
+	}
+
+	if err := p.CheckQuota(memCommitted, mem, coresCommitted, cores); err != nil {
+		glog.Warningf("Rejecting request for %d bytes of RAM and %d cores: %s", mem, cores, err)
+		return err
+	}
+	return nil
+}
+
 // GetPoolIPs gets all IPs available to a Pool
 func (f *Facade) GetPoolIPs(ctx datastore.Context, poolID string) (*PoolIPs, error) {
 	glog.V(0).Infof("Facade.GetPoolIPs: %+v", poolID)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/control-center/serviced/domain/host"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/service"
 	. "gopkg.in/check.v1"
 )
 
@@ -453,3 +454,69 @@ func (ft *FacadeTest) Test_PoolCommitment(t *C) {
 		t.Fatalf("Unexpected error calculating pool commitment: %v", err)
 	}
 }
+
+func (ft *FacadeTest) Test_PoolQuota(t *C) {
+	poolid := "Test_PoolQuota"
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolid)
+
+	rp := pool.New(poolid)
+	rp.MemoryLimit = 3072
+	rp.CoreLimit = 4
+	if err := ft.Facade.AddResourcePool(ft.CTX, rp); err != nil {
+		t.Fatalf("Could not add pool for test: %v", err)
+	}
+
+	running := service.Service{
+		ID:            "Test_PoolQuota_running",
+		Name:          "running",
+		PoolID:        poolid,
+		Launch:        "auto",
+		DesiredState:  service.SVCRun,
+		Instances:     2,
+		RAMCommitment: 1024,
+		CPUCommitment: 1,
+	}
+	stopped := running
+	stopped.ID = "Test_PoolQuota_stopped"
+	stopped.Name = "stopped"
+	stopped.DesiredState = service.SVCStop
+	for _, svc := range []service.Service{running, stopped} {
+		if err := ft.Facade.AddService(ft.CTX, svc); err != nil {
+			t.Fatalf("Could not add service %s: %v", svc.ID, err)
+		}
+		defer ft.Facade.RemoveService(ft.CTX, svc.ID)
+	}
+
+	// only the running service counts against the quota
+	if mem, cores, err := ft.Facade.poolCommitment(ft.CTX, poolid); err != nil {
+		t.Fatalf("Unexpected error calculating pool commitment: %v", err)
+	} else if mem != 2048 || cores != 2 {
+		t.Errorf("Expected 2048 bytes and 2 cores committed; got %d bytes and %d cores", mem, cores)
+	}
+
+	// starting the stopped service would commit 4096 bytes of RAM
+	if err := ft.Facade.StartService(ft.CTX, stopped.ID); !pool.IsErrQuotaExceeded(err) {
+		t.Errorf("Expected quota error starting %s; got %v", stopped.ID, err)
+	}
+
+	// scaling down the running service is always allowed
+	running.Instances = 1
+	if err := ft.Facade.UpdateService(ft.CTX, running); err != nil {
+		t.Errorf("Unexpected error scaling down %s: %v", running.ID, err)
+	}
+
+	// a single instance of the stopped service now fits
+	stopped.Instances = 1
+	if err := ft.Facade.UpdateService(ft.CTX, stopped); err != nil {
+		t.Fatalf("Unexpected error updating %s: %v", stopped.ID, err)
+	}
+	if err := ft.Facade.StartService(ft.CTX, stopped.ID); err != nil {
+		t.Errorf("Unexpected error starting %s: %v", stopped.ID, err)
+	}
+
+	// but scaling the running service back up does not
+	running.Instances = 3
+	if err := ft.Facade.UpdateService(ft.CTX, running); !pool.IsErrQuotaExceeded(err) {
+		t.Errorf("Expected quota error scaling up %s; got %v", running.ID, err)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"fmt"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
+)
+
+// users that are always administrators. The names of users are only trusted
+// once the web server or an API token has verified them.
+var adminUsers = map[string]bool{
+	"system_user": true,
+}
+
+// Authorize checks that the caller of the context is allowed to make the
+// request. Requests about a service or host are scoped to the pool and tenant
+// of the service or host. Users without roles of their own get the roles of
+// role.DefaultUser, and callers that authenticated with an API token are also
+// limited to the scope of the token. Hosts may do what their agents need to
+// run the services of their pool, and callers that are neither a user nor a
+// host are refused unless they are internal to the master.
+func (f *Facade) Authorize(ctx datastore.Context, request role.Request) error {
+	caller := datastore.CallerOf(ctx)
+	glog.V(3).Infof("Facade.Authorize: %+v %+v", caller, request)
+
+	if caller.Admin {
+		return nil
+	} else if caller.Host != "" {
+		return f.authorizeHost(ctx, caller.Host, request)
+	} else if caller.User == "" {
+		return role.ErrUnauthorized{User: caller.User, Request: request}
+	}
+
+	if err := f.scopeRequest(ctx, &request); err != nil {
+		return err
+	}
+	if caller.Token != "" {
+		if err := f.authorizeToken(ctx, caller, request); err != nil {
+			return err
+		}
+	}
+	if adminUsers[caller.User] {
+		return nil
+	}
+
+	roles, err := f.GetUserRole(ctx, caller.User)
+	if err != nil {
+		return err
+	} else if roles == nil {
+		if roles, err = f.GetUserRole(ctx, role.DefaultUser); err != nil {
+			return err
+		} else if roles == nil {
+			roles = &role.UserRoles{User: caller.User}
+		}
+	}
+
+	if !roles.Allows(request) {
+		return role.ErrUnauthorized{User: caller.User, Request: request}
+	}
+	return nil
+}
+
+// authorizeHost lets the agent of a host look at the services, pools, hosts
+// and templates, operate the services of its pool, update its own host, and
+// read the credentials that its containers authenticate with
+func (f *Facade) authorizeHost(ctx datastore.Context, hostID string, request role.Request) error {
+	h, err := f.GetHost(ctx, hostID)
+	if err != nil {
+		return err
+	} else if h == nil {
+		return role.ErrUnauthorized{User: "host " + hostID, Request: request}
+	}
+
+	if request.Resource == role.ResourceHost && request.HostID == hostID {
+		return nil
+	}
+	if err := f.scopeRequest(ctx, &request); err != nil {
+		return err
+	}
+	roles := role.UserRoles{
+		User: "host " + hostID,
+		Grants: []role.Grant{
+			{Role: role.Viewer, Resource: role.ResourceService},
+			{Role: role.Operator, Resource: role.ResourceService, PoolID: h.PoolID},
+			{Role: role.Viewer, Resource: role.ResourcePool},
+			{Role: role.Viewer, Resource: role.ResourceHost},
+			{Role: role.Viewer, Resource: role.ResourceTemplate},
+			{Role: role.Admin, Resource: role.ResourceCredentials},
+		},
+	}
+	if !roles.Allows(request) {
+		return role.ErrUnauthorized{User: roles.User, Request: request}
+	}
+	return nil
+}
+
+// CreateDefaultRoles makes root an administrator when nobody has been granted
+// any roles, so that a new deployment can be administered from the web UI
+func (f *Facade) CreateDefaultRoles(ctx datastore.Context) error {
+	all, err := f.roleStore.GetUserRoles(ctx)
+	if err != nil {
+		return err
+	} else if len(all) > 0 {
+		return nil
+	}
+	glog.Infof("No roles have been granted; making root an administrator")
+	return f.GrantRole(ctx, "root", role.Grant{Role: role.Admin})
+}
+
+// scopeRequest fills in the pool and tenant of the service or host that the
+// request is about. Services and hosts that do not exist are left for the
+// request itself to report.
+func (f *Facade) scopeRequest(ctx datastore.Context, request *role.Request) error {
+	if request.ServiceID != "" {
+		svc, err := f.GetService(ctx, request.ServiceID)
+		if datastore.IsErrNoSuchEntity(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		request.PoolID = svc.PoolID
+		if request.TenantID, err = f.GetTenantID(ctx, svc.ID); err != nil {
+			return err
+		}
+	} else if request.HostID != "" {
+		h, err := f.GetHost(ctx, request.HostID)
+		if err != nil || h == nil {
+			return err
+		}
+		request.PoolID = h.PoolID
+	}
+	return nil
+}
+
+// GetUserRoles returns the roles of every user that has been granted any
+func (f *Facade) GetUserRoles(ctx datastore.Context) ([]*role.UserRoles, error) {
+	glog.V(3).Infof("Facade.GetUserRoles")
+	return f.roleStore.GetUserRoles(ctx)
+}
+
+// GetUserRole returns the roles of a user. Returns nil if the user has not
+// been granted any roles.
+func (f *Facade) GetUserRole(ctx datastore.Context, user string) (*role.UserRoles, error) {
+	glog.V(3).Infof("Facade.GetUserRole: %s", user)
+	var roles role.UserRoles
+	if err := f.roleStore.Get(ctx, role.Key(user), &roles); datastore.IsErrNoSuchEntity(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &roles, nil
+}
+
+// GrantRole grants a role to a user
+func (f *Facade) GrantRole(ctx datastore.Context, user string, grant role.Grant) error {
+	glog.V(2).Infof("Facade.GrantRole: %s %+v", user, grant)
+	if err := grant.ValidEntity(); err != nil {
+		return err
+	}
+
+	current, err := f.GetUserRole(ctx, user)
+	if err != nil {
+		return err
+	}
+	next := role.UserRoles{User: user}
+	if current != nil {
+		next.Grants = append(next.Grants, current.Grants...)
+	}
+	if !next.Add(grant) {
+		return nil
+	}
+	if err := next.ValidEntity(); err != nil {
+		return err
+	}
+	if err := f.roleStore.Put(ctx, role.Key(user), &next); err != nil {
+		return err
+	}
+	f.Audit(ctx, "grant", role.ResourceUser, user, current, &next)
+	return nil
+}
+
+// RevokeRole revokes a role from a user. The user's roles are removed when no
+// grants remain.
+func (f *Facade) RevokeRole(ctx datastore.Context, user string, grant role.Grant) error {
+	glog.V(2).Infof("Facade.RevokeRole: %s %+v", user, grant)
+	current, err := f.GetUserRole(ctx, user)
+	if err != nil {
+		return err
+	} else if current == nil {
+		return fmt.Errorf("user %s has not been granted any roles", user)
+	}
+
+	next := role.UserRoles{User: user, Grants: append([]role.Grant{}, current.Grants...)}
+	if !next.Remove(grant) {
+		return fmt.Errorf("user %s has not been granted role %s", user, grant.Role)
+	}
+	if len(next.Grants) == 0 {
+		err = f.roleStore.Delete(ctx, role.Key(user))
+	} else {
+		err = f.roleStore.Put(ctx, role.Key(user), &next)
+	}
+	if err != nil {
+		return err
+	}
+	f.Audit(ctx, "revoke", role.ResourceUser, user, current, &next)
+	return nil
+}
+
+// RemoveUserRoles removes all of the roles of a user
+func (f *Facade) RemoveUserRoles(ctx datastore.Context, user string) error {
+	glog.V(2).Infof("Facade.RemoveUserRoles: %s", user)
+	current, err := f.GetUserRole(ctx, user)
+	if err != nil {
+		return err
+	} else if current == nil {
+		return fmt.Errorf("user %s has not been granted any roles", user)
+	}
+	if err := f.roleStore.Delete(ctx, role.Key(user)); err != nil {
+		return err
+	}
+	f.Audit(ctx, "remove", role.ResourceUser, user, current, nil)
+	return nil
+}
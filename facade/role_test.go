@@ -0,0 +1,168 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/role"
+	. "gopkg.in/check.v1"
+)
+
+func (ft *FacadeTest) Test_Authorize(t *C) {
+	bob := datastore.WithCaller(ft.CTX, datastore.Caller{User: "bob", Source: datastore.SourceREST})
+	anonymous := datastore.WithCaller(ft.CTX, datastore.Caller{Source: datastore.SourceREST})
+	admin := datastore.WithCaller(ft.CTX, datastore.Caller{Source: datastore.SourceCLI, Admin: true})
+	root := datastore.WithCaller(ft.CTX, datastore.Caller{User: "root", Source: datastore.SourceRPC})
+	modify := role.Request{Action: role.Modify, Resource: role.ResourcePool, PoolID: "default"}
+	read := role.Request{Action: role.Read, Resource: role.ResourcePool, PoolID: "default"}
+
+	// only administrators are authorized until roles are granted
+	if err := ft.Facade.Authorize(bob, read); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected bob to be unauthorized without roles, got %v", err)
+	}
+	if err := ft.Facade.Authorize(root, modify); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected root to be unauthorized without roles, got %v", err)
+	}
+	if err := ft.Facade.Authorize(anonymous, read); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected an anonymous REST caller to be unauthorized, got %v", err)
+	}
+	if err := ft.Facade.Authorize(ft.CTX, read); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected an anonymous RPC caller to be unauthorized, got %v", err)
+	}
+	if err := ft.Facade.Authorize(admin, modify); err != nil {
+		t.Errorf("Expected administrators to be authorized: %s", err)
+	}
+
+	defer ft.Facade.RemoveUserRoles(ft.CTX, "bob")
+	if err := ft.Facade.GrantRole(ft.CTX, "bob", role.Grant{Role: role.Viewer}); err != nil {
+		t.Fatalf("Failure granting role: %s", err)
+	}
+	if err := ft.Facade.Authorize(bob, read); err != nil {
+		t.Errorf("Expected viewer to be authorized to read: %s", err)
+	}
+	if err := ft.Facade.Authorize(bob, modify); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected viewer to be unauthorized to modify, got %v", err)
+	}
+
+	// users without roles get the default roles, and nothing without them
+	alice := datastore.WithCaller(ft.CTX, datastore.Caller{User: "alice", Source: datastore.SourceREST})
+	if err := ft.Facade.Authorize(alice, read); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected alice to be unauthorized, got %v", err)
+	}
+	defer ft.Facade.RemoveUserRoles(ft.CTX, role.DefaultUser)
+	if err := ft.Facade.GrantRole(ft.CTX, role.DefaultUser, role.Grant{Role: role.Viewer}); err != nil {
+		t.Fatalf("Failure granting default role: %s", err)
+	}
+	if err := ft.Facade.Authorize(alice, read); err != nil {
+		t.Errorf("Expected alice to get the default roles: %s", err)
+	}
+}
+
+func (ft *FacadeTest) Test_AuthorizeHost(t *C) {
+	rp := pool.New("host-pool")
+	if err := ft.Facade.AddResourcePool(ft.CTX, rp); err != nil {
+		t.Fatalf("Could not add pool for test: %v", err)
+	}
+	defer ft.Facade.RemoveResourcePool(ft.CTX, rp.ID)
+	h, err := host.Build("", rp.ID, []string{}...)
+	if err != nil {
+		t.Fatalf("Unexpected error building host: %v", err)
+	}
+	h.ID = "authorizehostid"
+	if err := ft.Facade.AddHost(ft.CTX, h); err != nil {
+		t.Fatalf("Unexpected error adding host: %v", err)
+	}
+	defer ft.Facade.RemoveHost(ft.CTX, h.ID)
+
+	agent := datastore.WithCaller(ft.CTX, datastore.Caller{Source: datastore.SourceRPC, Host: h.ID})
+	for _, request := range []role.Request{
+		{Action: role.Modify, Resource: role.ResourceHost, HostID: h.ID},
+		{Action: role.Read, Resource: role.ResourceHost},
+		{Action: role.Read, Resource: role.ResourceService},
+		{Action: role.Operate, Resource: role.ResourceService, PoolID: rp.ID},
+		{Action: role.Modify, Resource: role.ResourceCredentials},
+	} {
+		if err := ft.Facade.Authorize(agent, request); err != nil {
+			t.Errorf("Expected the host to be authorized to %s %s: %s", request.Action, request, err)
+		}
+	}
+	for _, request := range []role.Request{
+		{Action: role.Modify, Resource: role.ResourceHost, HostID: "otherhostid"},
+		{Action: role.Operate, Resource: role.ResourceService, PoolID: "default"},
+		{Action: role.Modify, Resource: role.ResourceService, PoolID: rp.ID},
+		{Action: role.Read, Resource: role.ResourceUser},
+		{Action: role.Operate, Resource: role.ResourceBackup},
+	} {
+		if err := ft.Facade.Authorize(agent, request); !role.IsErrUnauthorized(err) {
+			t.Errorf("Expected the host to be unauthorized to %s %s, got %v", request.Action, request, err)
+		}
+	}
+
+	removed := datastore.WithCaller(ft.CTX, datastore.Caller{Source: datastore.SourceRPC, Host: "removedhostid"})
+	if err := ft.Facade.Authorize(removed, role.Request{Action: role.Read, Resource: role.ResourceHost}); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected a host that does not exist to be unauthorized, got %v", err)
+	}
+}
+
+func (ft *FacadeTest) Test_CreateDefaultRoles(t *C) {
+	root := datastore.WithCaller(ft.CTX, datastore.Caller{User: "root", Source: datastore.SourceREST})
+	modify := role.Request{Action: role.Modify, Resource: role.ResourcePool}
+
+	defer ft.Facade.RemoveUserRoles(ft.CTX, "root")
+	if err := ft.Facade.CreateDefaultRoles(ft.CTX); err != nil {
+		t.Fatalf("Failure creating default roles: %s", err)
+	}
+	if err := ft.Facade.Authorize(root, modify); err != nil {
+		t.Errorf("Expected root to be an administrator: %s", err)
+	}
+
+	// roles that have been granted are left alone
+	if err := ft.Facade.RevokeRole(ft.CTX, "root", role.Grant{Role: role.Admin}); err != nil {
+		t.Fatalf("Failure revoking role: %s", err)
+	}
+	defer ft.Facade.RemoveUserRoles(ft.CTX, "carol")
+	if err := ft.Facade.GrantRole(ft.CTX, "carol", role.Grant{Role: role.Viewer}); err != nil {
+		t.Fatalf("Failure granting role: %s", err)
+	}
+	if err := ft.Facade.CreateDefaultRoles(ft.CTX); err != nil {
+		t.Fatalf("Failure creating default roles: %s", err)
+	}
+	if err := ft.Facade.Authorize(root, modify); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected root to be unauthorized once roles are granted, got %v", err)
+	}
+}
+
+func (ft *FacadeTest) Test_GrantRevokeRole(t *C) {
+	defer ft.Facade.RemoveUserRoles(ft.CTX, "carol")
+	operator := role.Grant{Role: role.Operator, PoolID: "default"}
+
+	if err := ft.Facade.GrantRole(ft.CTX, "carol", role.Grant{Role: "superuser"}); err == nil {
+		t.Errorf("Expected failure granting an unknown role")
+	}
+	if err := ft.Facade.GrantRole(ft.CTX, "carol", operator); err != nil {
+		t.Fatalf("Failure granting role: %s", err)
+	}
+
+	roles, err := ft.Facade.GetUserRole(ft.CTX, "carol")
+	if err != nil {
+		t.Fatalf("Failure getting roles: %s", err)
+	} else if roles == nil || len(roles.Grants) != 1 || roles.Grants[0] != operator {
+		t.Errorf("Expected %+v, got %+v", operator, roles)
+	}
+
+	if err := ft.Facade.RevokeRole(ft.CTX, "carol", operator); err != nil {
+		t.Fatalf("Failure revoking role: %s", err)
+	}
+	if roles, err := ft.Facade.GetUserRole(ft.CTX, "carol"); err != nil {
+		t.Fatalf("Failure getting roles: %s", err)
+	} else if roles != nil {
+		t.Errorf("Expected the roles to be removed, got %+v", roles)
+	}
+	if err := ft.Facade.RevokeRole(ft.CTX, "carol", operator); err == nil {
+		t.Errorf("Expected failure revoking a role that was not granted")
+	}
+}
@@ -49,6 +49,7 @@ func (f *Facade) AddService(ctx datastore.Context, svc service.Service) error {
 		return err
 	}
 	glog.V(2).Infof("Facade.AddService: id %+v", svc.ID)
+	f.Audit(ctx, "add", "service", svc.ID, nil, &svc)
 
 	return zkAPI(f).updateService(&svc)
 }
@@ -61,9 +62,19 @@ func (f *Facade) UpdateService(ctx datastore.Context, svc service.Service) error
 		if err := f.validateServicesForStarting(ctx, &svc); err != nil {
 			return err
 		}
-
+		if err := f.validateServiceQuota(ctx, &svc); err != nil {
+			return err
+		}
 	}
-	return f.updateService(ctx, &svc)
+	previous, err := f.serviceStore.Get(ctx, svc.ID)
+	if err != nil {
+		return err
+	}
+	if err := f.updateService(ctx, &svc); err != nil {
+		return err
+	}
+	f.Audit(ctx, "update", "service", svc.ID, previous, &svc)
+	return nil
 }
 
 //
@@ -86,8 +97,10 @@ func (f *Facade) RemoveService(ctx datastore.Context, id string) error {
 		err := store.Delete(ctx, svc.ID)
 		if err != nil {
 			glog.Errorf("Error removing service %s	 %s ", svc.ID, err)
+			return err
 		}
-		return err
+		f.Audit(ctx, "remove", "service", svc.ID, svc, nil)
+		return nil
 	})
 	if err != nil {
 		return err
@@ -125,6 +138,18 @@ func (f *Facade) GetServices(ctx datastore.Context, request dao.EntityRequest) (
 	return results, nil
 }
 
+// GetServicesByPool returns the services of a pool as they are stored,
+// without filling out their config files and address assignments
+func (f *Facade) GetServicesByPool(ctx datastore.Context, poolID string) ([]*service.Service, error) {
+	glog.V(3).Infof("Facade.GetServicesByPool: %s", poolID)
+	results, err := f.serviceStore.GetServicesByPool(ctx, poolID)
+	if err != nil {
+		glog.Error("Facade.GetServicesByPool: err=", err)
+		return nil, err
+	}
+	return results, nil
+}
+
 //
 func (f *Facade) GetTaggedServices(ctx datastore.Context, request dao.EntityRequest) ([]*service.Service, error) {
 	glog.V(3).Infof("Facade.GetTaggedServices")
@@ -158,6 +183,46 @@ func (f *Facade) GetTenantID(ctx datastore.Context, serviceID string) (string, e
 	return getTenantID(serviceID, gs)
 }
 
+// GetServiceDependencies returns the services of the same tenant that the
+// service has to wait for before it starts
+func (f *Facade) GetServiceDependencies(ctx datastore.Context, serviceID string) ([]*service.Service, error) {
+	svc, services, err := f.getTenantServices(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	return service.Dependencies(svc, services), nil
+}
+
+// GetServiceDependents returns the services of the same tenant that have to
+// stop before the service stops
+func (f *Facade) GetServiceDependents(ctx datastore.Context, serviceID string) ([]*service.Service, error) {
+	svc, services, err := f.getTenantServices(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	return service.Dependents(svc, services), nil
+}
+
+// getTenantServices returns the service along with every service that shares
+// its tenant
+func (f *Facade) getTenantServices(ctx datastore.Context, serviceID string) (*service.Service, []*service.Service, error) {
+	tenantID, err := f.GetTenantID(ctx, serviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	services, err := f.serviceStore.GetTenantServices(ctx, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, svc := range services {
+		if svc.ID == serviceID {
+			return svc, services, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("service %s not found", serviceID)
+}
+
 // Get a service endpoint.
 func (f *Facade) GetServiceEndpoints(ctx datastore.Context, serviceId string) (map[string][]*dao.ApplicationEndpoint, error) {
 	glog.V(2).Infof("Facade.GetServiceEndpoints serviceId=%s", serviceId)
@@ -283,15 +348,20 @@ func (f *Facade) StartService(ctx datastore.Context, serviceId string) error {
 	if err != nil {
 		return err
 	}
+	if err := f.validateStartQuota(ctx, serviceId); err != nil {
+		return err
+	}
 
 	visitor := func(svc *service.Service) error {
 		//start f service
+		previous := svc.DesiredState
 		svc.DesiredState = service.SVCRun
 		err = f.updateService(ctx, svc)
 		glog.V(4).Infof("Facade.StartService update service %v, %v: %v", svc.Name, svc.ID, err)
 		if err != nil {
 			return err
 		}
+		f.auditDesiredState(ctx, "start", svc, previous)
 		return nil
 	}
 
@@ -306,10 +376,12 @@ func (f *Facade) StopService(ctx datastore.Context, id string) error {
 		if svc.Launch == commons.MANUAL {
 			return nil
 		}
+		previous := svc.DesiredState
 		svc.DesiredState = service.SVCStop
 		if err := f.updateService(ctx, svc); err != nil {
 			return err
 		}
+		f.auditDesiredState(ctx, "stop", svc, previous)
 		return nil
 	}
 
@@ -453,9 +525,18 @@ func (f *Facade) AssignIPs(ctx datastore.Context, assignmentRequest dao.Assignme
 	}
 
 	glog.Infof("All services requiring an explicit IP address (at f moment) from service: %v and down ... have been assigned: %s", assignmentRequest.ServiceID, assignmentRequest.IPAddress)
+	f.Audit(ctx, "assign-ips", "service", assignmentRequest.ServiceID, nil, assignmentRequest)
 	return nil
 }
 
+// auditDesiredState records a change to the desired state of a service
+func (f *Facade) auditDesiredState(ctx datastore.Context, action string, svc *service.Service, previous int) {
+	if previous == svc.DesiredState {
+		return
+	}
+	f.Audit(ctx, action, "service", svc.ID, struct{ DesiredState int }{previous}, struct{ DesiredState int }{svc.DesiredState})
+}
+
 //getService is an internal method that returns a Service without filling in all related service data like address assignments
 //and modified config files
 func (f *Facade) getService(ctx datastore.Context, id string) (service.Service, error) {
@@ -611,6 +692,67 @@ func (f *Facade) validateService(ctx datastore.Context, serviceId string) error
 	return f.walkServices(ctx, serviceId, visitor)
 }
 
+// serviceCommitment returns the RAM and cores committed to all instances of
+// the service
+func serviceCommitment(svc *service.Service) (uint64, uint64) {
+	if svc.Instances <= 0 {
+		return 0, 0
+	}
+	n := uint64(svc.Instances)
+	return svc.RAMCommitment * n, svc.CPUCommitment * n
+}
+
+// validateServiceQuota checks that running the service will not exceed its
+// pool's quota. Updates that don't raise the service's commitment are always
+// allowed, so that a pool whose limits were lowered can still be managed.
+func (f *Facade) validateServiceQuota(ctx datastore.Context, svc *service.Service) error {
+	mem, cores := serviceCommitment(svc)
+
+	oldSvc, err := f.serviceStore.Get(ctx, svc.ID)
+	if err != nil && !datastore.IsErrNoSuchEntity(err) {
+		return err
+	} else if err == nil && oldSvc.DesiredState == service.SVCRun && oldSvc.PoolID == svc.PoolID {
+		if oldMem, oldCores := serviceCommitment(oldSvc); mem <= oldMem && cores <= oldCores {
+			return nil
+		}
+	}
+
+	return f.checkPoolQuota(ctx, svc.PoolID, mem, cores, svc.ID)
+}
+
+// validateStartQuota checks that starting the stopped services in the tree
+// will not exceed the quotas of their pools
+func (f *Facade) validateStartQuota(ctx datastore.Context, serviceID string) error {
+	type commitment struct{ mem, cores uint64 }
+	requested := make(map[string]*commitment)
+
+	visitor := func(svc *service.Service) error {
+		if svc.DesiredState == service.SVCRun {
+			return nil
+		}
+		c, ok := requested[svc.PoolID]
+		if !ok {
+			c = &commitment{}
+			requested[svc.PoolID] = c
+		}
+		mem, cores := serviceCommitment(svc)
+		c.mem += mem
+		c.cores += cores
+		return nil
+	}
+
+	if err := f.walkServices(ctx, serviceID, visitor); err != nil {
+		return err
+	}
+
+	for poolID, c := range requested {
+		if err := f.checkPoolQuota(ctx, poolID, c.mem, c.cores); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (f *Facade) fillOutService(ctx datastore.Context, svc *service.Service) error {
 	if err := f.fillServiceAddr(ctx, svc); err != nil {
 		return err
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strings"
 
 	dutils "github.com/dotcloud/docker/utils"
@@ -21,6 +22,7 @@ import (
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/isvcs"
@@ -49,6 +51,7 @@ func (f *Facade) AddServiceTemplate(ctx datastore.Context, serviceTemplate servi
 	if err = f.templateStore.Put(ctx, serviceTemplate); err != nil {
 		return "", err
 	}
+	f.Audit(ctx, "add", "template", hash, nil, &serviceTemplate)
 
 	// this takes a while so don't block the main thread
 	go LogstashContainerReloader(ctx, f)
@@ -57,16 +60,19 @@ func (f *Facade) AddServiceTemplate(ctx datastore.Context, serviceTemplate servi
 
 //UpdateServiceTemplate updates a service template
 func (f *Facade) UpdateServiceTemplate(ctx datastore.Context, template servicetemplate.ServiceTemplate) error {
+	previous, _ := f.templateStore.Get(ctx, template.ID)
 	if err := f.templateStore.Put(ctx, template); err != nil {
 		return err
 	}
+	f.Audit(ctx, "update", "template", template.ID, previous, &template)
 	go LogstashContainerReloader(ctx, f) // don't block the main thread
 	return nil
 }
 
 //RemoveServiceTemplate removes the service template from the system
 func (f *Facade) RemoveServiceTemplate(ctx datastore.Context, id string) error {
-	if _, err := f.templateStore.Get(ctx, id); err != nil {
+	previous, err := f.templateStore.Get(ctx, id)
+	if err != nil {
 		return fmt.Errorf("Unable to find template: %s", id)
 	}
 
@@ -74,6 +80,7 @@ func (f *Facade) RemoveServiceTemplate(ctx datastore.Context, id string) error {
 	if err := f.templateStore.Delete(ctx, id); err != nil {
 		return err
 	}
+	f.Audit(ctx, "remove", "template", id, previous, nil)
 
 	go LogstashContainerReloader(ctx, f)
 	return nil
@@ -151,6 +158,11 @@ func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID
 		return "", fmt.Errorf("poolid %s not found", poolID)
 	}
 
+	mem, cores := definitionCommitment(template.Services...)
+	if err := f.checkPoolQuota(ctx, poolID, mem, cores); err != nil {
+		return "", err
+	}
+
 	if err := pullTemplateImages(template); err != nil {
 		glog.Errorf("Unable to pull one or more images")
 		return "", err
@@ -159,6 +171,10 @@ func (f *Facade) DeployTemplate(ctx datastore.Context, poolID string, templateID
 	volumes := make(map[string]string)
 	var tenantID string
 	err = f.deployServiceDefinitions(ctx, template.Services, poolID, "", volumes, deploymentID, &tenantID)
+	if err == nil {
+		deployment := struct{ PoolID, DeploymentID, TenantID string }{poolID, deploymentID, tenantID}
+		f.Audit(ctx, "deploy", "template", templateID, nil, deployment)
+	}
 	return tenantID, err
 }
 
@@ -173,6 +189,11 @@ func (f *Facade) DeployService(ctx datastore.Context, parentID string, sd servic
 		return "", fmt.Errorf("getting tenant id: %s", err)
 	}
 
+	mem, cores := definitionCommitment(sd)
+	if err := f.checkPoolQuota(ctx, parent.PoolID, mem, cores); err != nil {
+		return "", err
+	}
+
 	volumes := make(map[string]string)
 	serviceDefinitions := []servicedefinition.ServiceDefinition{sd}
 
@@ -181,6 +202,398 @@ func (f *Facade) DeployService(ctx datastore.Context, parentID string, sd servic
 	return tenantId, err
 }
 
+// templateChange is a difference between a deployed tenant and a template,
+// along with what is needed to apply it
+type templateChange struct {
+	diff     service.ServiceDiff
+	svc      *service.Service                     // the deployed service, unless it was added
+	sd       *servicedefinition.ServiceDefinition // the new definition, unless the service was removed
+	parentID string                               // the parent of an added service
+	image    string                               // the tenant's name for a changed image
+	applied  *service.Service                     // the changed service updated to its definition
+	removed  []string                             // the paths of a removed service and its children
+}
+
+// DiffTemplate compares a deployed tenant with a version of a service
+// template. The contexts of changed services are listed as overwritten only if
+// overwriteContext is set.
+func (f *Facade) DiffTemplate(ctx datastore.Context, tenantID string, templateID string, overwriteContext bool) (*service.TemplateDiff, error) {
+	_, changes, err := f.diffTemplate(ctx, tenantID, templateID, overwriteContext)
+	if err != nil {
+		return nil, err
+	}
+	return templateDiff(tenantID, templateID, changes), nil
+}
+
+// ApplyTemplate updates a deployed tenant in place to match a version of a
+// service template. Changed services keep their ids, instances and state, and
+// config files that users edited keep their edits; their contexts are only
+// replaced if overwriteContext is set. Services that are not in the template
+// are removed and new ones are deployed stopped. Every change is checked
+// before any is made, and the changes already made are undone if one fails.
+func (f *Facade) ApplyTemplate(ctx datastore.Context, tenantID string, templateID string, overwriteContext bool) (*service.TemplateDiff, error) {
+	template, err := f.templateStore.Get(ctx, templateID)
+	if err != nil {
+		glog.Errorf("unable to load template: %s", templateID)
+		return nil, err
+	}
+	if err := pullTemplateImages(template); err != nil {
+		glog.Errorf("Unable to pull one or more images")
+		return nil, err
+	}
+
+	tenant, changes, err := f.diffTemplate(ctx, tenantID, templateID, overwriteContext)
+	if err != nil {
+		return nil, err
+	}
+	diff := templateDiff(tenantID, templateID, changes)
+	if diff.Empty() {
+		return diff, nil
+	}
+
+	// build the new version of every changed service and check the quota
+	// with the changed services at their new commitments
+	var mem, cores uint64
+	var exclude []string
+	for i := range changes {
+		c := &changes[i]
+		switch c.diff.Change {
+		case service.DiffAdded:
+			if err := c.sd.ValidEntity(); err != nil {
+				glog.Errorf("Could not apply template %s to %s: %s", templateID, c.diff.Path, err)
+				return nil, err
+			}
+			m, n := definitionCommitment(*c.sd)
+			mem, cores = mem+m, cores+n
+		case service.DiffChanged:
+			if c.applied, err = f.prepareDefinition(ctx, *c, overwriteContext); err != nil {
+				glog.Errorf("Could not apply template %s to %s: %s", templateID, c.diff.Path, err)
+				return nil, err
+			}
+			if c.svc.DesiredState != service.SVCRun {
+				continue
+			}
+			m, n := serviceCommitment(c.applied)
+			mem, cores = mem+m, cores+n
+			exclude = append(exclude, c.svc.ID)
+		case service.DiffRemoved:
+			if c.removed, err = f.servicePaths(ctx, c.svc.ID, c.diff.Path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := f.checkPoolQuota(ctx, tenant.PoolID, mem, cores, exclude...); err != nil {
+		return nil, err
+	}
+
+	// make the changes, removing services last as they cannot be undone
+	var undo []func()
+	var backups []string
+	apply := func(c templateChange) error {
+		switch c.diff.Change {
+		case service.DiffChanged:
+			if c.image != "" {
+				backup, err := moveTenantImage(c.sd.ImageID, c.image)
+				if err != nil {
+					return err
+				}
+				backups = append(backups, backup)
+				undo = append(undo, func() { restoreTenantImage(c.image, backup) })
+			}
+			undo = append(undo, func() { f.restoreService(ctx, c.svc) })
+			return f.updateDefinition(ctx, c.svc, c.applied)
+		case service.DiffAdded:
+			undo = append(undo, func() { f.removeChildService(ctx, c.parentID, c.sd.Name) })
+			volumes := make(map[string]string)
+			return f.deployServiceDefinitions(ctx, []servicedefinition.ServiceDefinition{*c.sd}, tenant.PoolID, c.parentID, volumes, tenant.DeploymentID, &tenantID)
+		}
+		return nil
+	}
+	for _, c := range changes {
+		if err := apply(c); err != nil {
+			glog.Errorf("Could not apply template %s to %s, undoing the changes made: %s", templateID, c.diff.Path, err)
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+			return nil, err
+		}
+	}
+	for _, backup := range backups {
+		dropImageTag(backup)
+	}
+	for _, c := range changes {
+		if c.diff.Change != service.DiffRemoved {
+			continue
+		}
+		if err := f.RemoveService(ctx, c.svc.ID); err != nil {
+			glog.Errorf("Could not apply template %s to %s: %s", templateID, c.diff.Path, err)
+			return nil, err
+		}
+	}
+
+	f.removeOrphanedConfigs(ctx, tenantID, changes)
+	f.Audit(ctx, "apply", "template", templateID, nil, diff)
+	return diff, nil
+}
+
+// prepareDefinition returns a copy of a deployed service updated to its new
+// definition, without saving it
+func (f *Facade) prepareDefinition(ctx datastore.Context, c templateChange, overwriteContext bool) (*service.Service, error) {
+	svc := *c.svc
+	if err := service.ApplyDefinition(&svc, c.sd, overwriteContext); err != nil {
+		return nil, err
+	}
+
+	getSvc := func(svcID string) (service.Service, error) {
+		svc, err := f.GetService(ctx, svcID)
+		return *svc, err
+	}
+	findChild := func(svcID, childName string) (service.Service, error) {
+		svc, err := f.FindChildService(ctx, svcID, childName)
+		return *svc, err
+	}
+	if err := svc.EvaluateEndpointTemplates(getSvc, findChild); err != nil {
+		return nil, err
+	}
+
+	if c.diff.Image != "" {
+		if c.image == "" {
+			svc.ImageID = ""
+		} else {
+			if _, err := docker.FindImage(c.sd.ImageID, false); err != nil {
+				return nil, fmt.Errorf("could not look up image %s of %s: %s", c.sd.ImageID, c.diff.Path, err)
+			}
+			svc.ImageID = c.image
+		}
+	}
+
+	if err := svc.ValidEntity(); err != nil {
+		return nil, err
+	}
+	f.fillServiceAddr(ctx, &svc)
+	return &svc, nil
+}
+
+// updateDefinition saves a service that was updated to its new definition
+func (f *Facade) updateDefinition(ctx datastore.Context, previous, svc *service.Service) error {
+	if err := f.serviceStore.Put(ctx, svc); err != nil {
+		return err
+	}
+	f.Audit(ctx, "update", "service", svc.ID, previous, svc)
+	return zkAPI(f).updateService(svc)
+}
+
+// restoreService puts back a service as it was before a template was applied
+func (f *Facade) restoreService(ctx datastore.Context, svc *service.Service) {
+	if err := f.serviceStore.Put(ctx, svc); err != nil {
+		glog.Errorf("Could not restore service %s: %s", svc.ID, err)
+		return
+	}
+	if err := zkAPI(f).updateService(svc); err != nil {
+		glog.Errorf("Could not restore service %s: %s", svc.ID, err)
+	}
+}
+
+// removeChildService removes a service that was deployed while a template was
+// applied, if it got that far
+func (f *Facade) removeChildService(ctx datastore.Context, parentID, name string) {
+	children, err := f.serviceStore.GetChildServices(ctx, parentID)
+	if err != nil {
+		glog.Errorf("Could not look up the children of %s: %s", parentID, err)
+		return
+	}
+	for _, child := range children {
+		if child.Name != name {
+			continue
+		}
+		if err := f.RemoveService(ctx, child.ID); err != nil {
+			glog.Errorf("Could not remove service %s: %s", child.ID, err)
+		}
+	}
+}
+
+// servicePaths returns the paths of a service and of all of its children
+func (f *Facade) servicePaths(ctx datastore.Context, serviceID, path string) ([]string, error) {
+	paths := []string{path}
+	children, err := f.serviceStore.GetChildServices(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		childPaths, err := f.servicePaths(ctx, child.ID, path+"/"+child.Name)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, childPaths...)
+	}
+	return paths, nil
+}
+
+// removeOrphanedConfigs deletes the edits of config files that are no longer
+// in a service's definition, and of services that were removed
+func (f *Facade) removeOrphanedConfigs(ctx datastore.Context, tenantID string, changes []templateChange) {
+	store := serviceconfigfile.NewStore()
+	remove := func(path string, keep map[string]servicedefinition.ConfigFile) {
+		confs, err := store.GetConfigFiles(ctx, tenantID, path)
+		if err != nil {
+			glog.Errorf("Could not look up the config files of %s: %s", path, err)
+			return
+		}
+		for _, conf := range confs {
+			if _, ok := keep[conf.ConfFile.Filename]; ok {
+				continue
+			}
+			if err := store.Delete(ctx, serviceconfigfile.Key(conf.ID)); err != nil {
+				glog.Errorf("Could not remove config file %s of %s: %s", conf.ConfFile.Filename, path, err)
+			}
+		}
+	}
+	for _, c := range changes {
+		switch c.diff.Change {
+		case service.DiffChanged:
+			remove(c.diff.Path, c.sd.ConfigFiles)
+		case service.DiffRemoved:
+			for _, path := range c.removed {
+				remove(path, nil)
+			}
+		}
+	}
+}
+
+// diffTemplate compares a deployed tenant with a template. The template must
+// deploy a single service with the same name as the tenant.
+func (f *Facade) diffTemplate(ctx datastore.Context, tenantID string, templateID string, overwriteContext bool) (*service.Service, []templateChange, error) {
+	template, err := f.templateStore.Get(ctx, templateID)
+	if err != nil {
+		glog.Errorf("unable to load template: %s", templateID)
+		return nil, nil, err
+	}
+	tenant, err := f.serviceStore.Get(ctx, tenantID)
+	if err != nil {
+		glog.Errorf("unable to load tenant: %s", tenantID)
+		return nil, nil, err
+	}
+	if tenant.ParentServiceID != "" {
+		return nil, nil, fmt.Errorf("service %s is not a tenant", tenantID)
+	}
+	if len(template.Services) != 1 {
+		return nil, nil, fmt.Errorf("template %s must define a single top-level service to be applied to a tenant", templateID)
+	}
+	if sd := template.Services[0]; sd.Name != tenant.Name {
+		return nil, nil, fmt.Errorf("template %s deploys %s, not %s", templateID, sd.Name, tenant.Name)
+	}
+
+	var changes []templateChange
+	if err := f.diffService(ctx, tenantID, tenant, &template.Services[0], "", overwriteContext, &changes); err != nil {
+		return nil, nil, err
+	}
+	return tenant, changes, nil
+}
+
+// diffService compares a deployed service and its children with their
+// definitions. Children are matched by name; the children of added and
+// removed services are not listed separately.
+func (f *Facade) diffService(ctx datastore.Context, tenantID string, svc *service.Service, sd *servicedefinition.ServiceDefinition, parentPath string, overwriteContext bool, changes *[]templateChange) error {
+	path := parentPath + "/" + sd.Name
+
+	edited := make(map[string]bool)
+	confs, err := serviceconfigfile.NewStore().GetConfigFiles(ctx, tenantID, path)
+	if err != nil {
+		return err
+	}
+	for _, conf := range confs {
+		edited[conf.ConfFile.Filename] = true
+	}
+
+	change := templateChange{diff: service.DiffDefinition(svc, sd, edited, overwriteContext), svc: svc, sd: sd}
+	change.diff.Path = path
+	image, changed, err := f.diffImage(svc, sd, tenantID)
+	if err != nil {
+		return err
+	}
+	if changed {
+		change.image = image
+		change.diff.Image = fmt.Sprintf("%s -> %s", svc.ImageID, sd.ImageID)
+	}
+	if !change.diff.Empty() {
+		*changes = append(*changes, change)
+	}
+
+	children, err := f.serviceStore.GetChildServices(ctx, svc.ID)
+	if err != nil {
+		return err
+	}
+	deployed := make(map[string]*service.Service)
+	for _, child := range children {
+		deployed[child.Name] = child
+	}
+	for i := range sd.Services {
+		childSD := &sd.Services[i]
+		if child, ok := deployed[childSD.Name]; ok {
+			delete(deployed, childSD.Name)
+			if err := f.diffService(ctx, tenantID, child, childSD, path, overwriteContext, changes); err != nil {
+				return err
+			}
+			continue
+		}
+		*changes = append(*changes, templateChange{
+			diff:     service.ServiceDiff{Path: path + "/" + childSD.Name, Change: service.DiffAdded, Image: childSD.ImageID},
+			sd:       childSD,
+			parentID: svc.ID,
+		})
+	}
+
+	names := make([]string, 0, len(deployed))
+	for name := range deployed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := deployed[name]
+		*changes = append(*changes, templateChange{
+			diff: service.ServiceDiff{Path: path + "/" + name, ServiceID: child.ID, Change: service.DiffRemoved, Image: child.ImageID},
+			svc:  child,
+		})
+	}
+	return nil
+}
+
+// diffImage returns the tenant's name for the image of a service definition,
+// and whether the service would run a different image. An image that has not
+// been pulled yet counts as different.
+func (f *Facade) diffImage(svc *service.Service, sd *servicedefinition.ServiceDefinition, tenantID string) (string, bool, error) {
+	if sd.ImageID == "" {
+		return "", svc.ImageID != "", nil
+	}
+	name, err := renameImageID(f.dockerRegistry, sd.ImageID, tenantID)
+	if err != nil {
+		glog.Errorf("malformed imageId: %s", sd.ImageID)
+		return "", false, err
+	}
+	if name != svc.ImageID {
+		return name, true, nil
+	}
+
+	current, err := docker.FindImage(name, false)
+	if err != nil {
+		return name, true, nil
+	}
+	image, err := docker.FindImage(sd.ImageID, false)
+	if err != nil {
+		return name, true, nil
+	}
+	return name, current.UUID != image.UUID, nil
+}
+
+// templateDiff collects the differences of a tenant and a template
+func templateDiff(tenantID, templateID string, changes []templateChange) *service.TemplateDiff {
+	diff := &service.TemplateDiff{TenantID: tenantID, TemplateID: templateID, Services: []service.ServiceDiff{}}
+	for _, c := range changes {
+		diff.Services = append(diff.Services, c.diff)
+	}
+	return diff
+}
+
 func (f *Facade) deployServiceDefinition(ctx datastore.Context, sd servicedefinition.ServiceDefinition, pool string, parentServiceID string, volumes map[string]string, deploymentId string, tenantId *string) error {
 	// Always deploy in stopped state, starting is a separate step
 	ds := service.SVCStop
@@ -225,22 +638,8 @@ func (f *Facade) deployServiceDefinition(ctx datastore.Context, sd servicedefini
 			return err
 		}
 
-		_, err = docker.FindImage(name, false)
-		if err != nil {
-			if err != docker.ErrNoSuchImage && !strings.HasPrefix(err.Error(), "No such id:") {
-				glog.Error(err)
-				return err
-			}
-			image, err := docker.FindImage(svc.ImageID, false)
-			if err != nil {
-				msg := fmt.Errorf("could not look up image %s: %s", svc.ImageID, err)
-				glog.Error(err.Error())
-				return msg
-			}
-			if _, err := image.Tag(name); err != nil {
-				glog.Errorf("could not tag image: %s (%v)", image.ID, err)
-				return err
-			}
+		if err := tagTenantImage(svc.ImageID, name, false); err != nil {
+			return err
 		}
 		svc.ImageID = name
 	}
@@ -277,6 +676,23 @@ func (f *Facade) deployServiceDefinitions(ctx datastore.Context, sds []servicede
 	return nil
 }
 
+// definitionCommitment returns the RAM and cores committed to the minimum
+// number of instances of the service definitions and their children
+func definitionCommitment(sds ...servicedefinition.ServiceDefinition) (uint64, uint64) {
+	var memCommitment, coreCommitment uint64
+	for _, sd := range sds {
+		if sd.Instances.Min > 0 {
+			n := uint64(sd.Instances.Min)
+			memCommitment += sd.RAMCommitment * n
+			coreCommitment += sd.CPUCommitment * n
+		}
+		mem, cores := definitionCommitment(sd.Services...)
+		memCommitment += mem
+		coreCommitment += cores
+	}
+	return memCommitment, coreCommitment
+}
+
 func getSubServiceImageIDs(ids map[string]struct{}, svc servicedefinition.ServiceDefinition) {
 	found := struct{}{}
 
@@ -288,6 +704,99 @@ func getSubServiceImageIDs(ids map[string]struct{}, svc servicedefinition.Servic
 	}
 }
 
+// tagTenantImage tags an image with the name a tenant uses for it. An existing
+// tag is only moved to the image if replace is set.
+func tagTenantImage(imageID, name string, replace bool) error {
+	current, err := docker.FindImage(name, false)
+	if err == nil {
+		if !replace {
+			return nil
+		}
+	} else if err != docker.ErrNoSuchImage && !strings.HasPrefix(err.Error(), "No such id:") {
+		glog.Error(err)
+		return err
+	} else {
+		current = nil
+	}
+
+	image, err := docker.FindImage(imageID, false)
+	if err != nil {
+		msg := fmt.Errorf("could not look up image %s: %s", imageID, err)
+		glog.Error(msg.Error())
+		return msg
+	}
+	if current != nil {
+		if current.UUID == image.UUID {
+			return nil
+		}
+		if err := current.Delete(); err != nil {
+			glog.Errorf("could not remove tag %s: %s", name, err)
+			return err
+		}
+	}
+
+	if _, err := image.Tag(name); err != nil {
+		glog.Errorf("could not tag image: %s (%v)", image.ID, err)
+		return err
+	}
+	return nil
+}
+
+// moveTenantImage moves the tag a tenant uses for an image to a new image. The
+// image it tagged before keeps a backup tag, which is returned, so that the
+// move can be undone with restoreTenantImage until the backup is dropped.
+func moveTenantImage(imageID, name string) (string, error) {
+	current, err := docker.FindImage(name, false)
+	if err != nil {
+		if err != docker.ErrNoSuchImage && !strings.HasPrefix(err.Error(), "No such id:") {
+			glog.Error(err)
+			return "", err
+		}
+		return "", tagTenantImage(imageID, name, true)
+	}
+
+	backup := name + ":" + templateBackupTag
+	if _, err := current.Tag(backup); err != nil {
+		glog.Errorf("could not tag image: %s (%v)", current.ID, err)
+		return "", err
+	}
+	if err := tagTenantImage(imageID, name, true); err != nil {
+		dropImageTag(backup)
+		return "", err
+	}
+	return backup, nil
+}
+
+// templateBackupTag tags the images a tenant used before a template was applied
+const templateBackupTag = "pre-apply"
+
+// restoreTenantImage moves the tag a tenant uses for an image back to the
+// image it tagged before moveTenantImage
+func restoreTenantImage(name, backup string) {
+	if backup == "" {
+		dropImageTag(name)
+		return
+	}
+	if err := tagTenantImage(backup, name, true); err != nil {
+		glog.Errorf("could not restore image %s: %s", name, err)
+	}
+	dropImageTag(backup)
+}
+
+// dropImageTag removes a tag from an image
+func dropImageTag(name string) {
+	if name == "" {
+		return
+	}
+	image, err := docker.FindImage(name, false)
+	if err != nil {
+		return
+	}
+	if err := image.Delete(); err != nil {
+		glog.Errorf("could not remove tag %s: %s", name, err)
+	}
+}
+
 func renameImageID(dockerRegistry, imageId, tenantId string) (string, error) {
 
 	repo, _ := dutils.ParseRepositoryTag(imageId)
@@ -5,10 +5,14 @@
 package facade
 
 import (
+	"reflect"
+
 	"github.com/control-center/serviced/commons"
 	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/pool"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/domain/servicetemplate"
 	. "gopkg.in/check.v1"
 )
 
@@ -70,3 +74,144 @@ func (ft *FacadeTest) TestRenameImageID(t *C) {
 		t.FailNow()
 	}
 }
+
+func (ft *FacadeTest) Test_DiffApplyTemplate(t *C) {
+	poolid := "Test_DiffApplyTemplate"
+	if err := ft.Facade.AddResourcePool(ft.CTX, pool.New(poolid)); err != nil {
+		t.Fatalf("Could not add pool for test: %v", err)
+	}
+	defer ft.Facade.RemoveResourcePool(ft.CTX, poolid)
+
+	conf := servicedefinition.ConfigFile{Filename: "/etc/a.conf", Content: "v1"}
+	v1 := servicetemplate.ServiceTemplate{
+		Name: "Test_DiffApplyTemplate",
+		Services: []servicedefinition.ServiceDefinition{{
+			Name:   "root",
+			Launch: "auto",
+			Services: []servicedefinition.ServiceDefinition{
+				{Name: "a", Launch: "auto", Command: "a", ConfigFiles: map[string]servicedefinition.ConfigFile{conf.Filename: conf}},
+				{Name: "b", Launch: "auto", Command: "b"},
+			},
+		}},
+	}
+	v1ID, err := ft.Facade.AddServiceTemplate(ft.CTX, v1)
+	if err != nil {
+		t.Fatalf("Could not add template: %v", err)
+	}
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, v1ID)
+	tenantID, err := ft.Facade.DeployTemplate(ft.CTX, poolid, v1ID, "Test_DiffApplyTemplate")
+	if err != nil {
+		t.Fatalf("Could not deploy template: %v", err)
+	}
+	defer ft.Facade.RemoveService(ft.CTX, tenantID)
+
+	// the deployed tenant matches its template
+	if diff, err := ft.Facade.DiffTemplate(ft.CTX, tenantID, v1ID, false); err != nil {
+		t.Fatalf("Could not diff template: %v", err)
+	} else if !diff.Empty() {
+		t.Errorf("Expected no differences, got %+v", diff)
+	}
+
+	// a user edits the config file and the context of a
+	a, err := ft.Facade.FindChildService(ft.CTX, tenantID, "a")
+	if err != nil {
+		t.Fatalf("Could not find service a: %v", err)
+	}
+	edit := conf
+	edit.Content = "edited"
+	a.ConfigFiles = map[string]servicedefinition.ConfigFile{conf.Filename: edit}
+	a.Context = `{"edited":true}`
+	if err := ft.Facade.UpdateService(ft.CTX, *a); err != nil {
+		t.Fatalf("Could not edit config file: %v", err)
+	}
+
+	v2 := v1
+	conf.Content = "v2"
+	v2.Services = []servicedefinition.ServiceDefinition{{
+		Name:   "root",
+		Launch: "auto",
+		Services: []servicedefinition.ServiceDefinition{
+			{Name: "a", Launch: "auto", Command: "a --v2", ConfigFiles: map[string]servicedefinition.ConfigFile{conf.Filename: conf}},
+			{Name: "c", Launch: "auto", Command: "c"},
+		},
+	}}
+	v2ID, err := ft.Facade.AddServiceTemplate(ft.CTX, v2)
+	if err != nil {
+		t.Fatalf("Could not add template: %v", err)
+	}
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, v2ID)
+
+	b, err := ft.Facade.FindChildService(ft.CTX, tenantID, "b")
+	if err != nil {
+		t.Fatalf("Could not find service b: %v", err)
+	}
+	expected := []service.ServiceDiff{
+		{
+			Path:        "/root/a",
+			ServiceID:   a.ID,
+			Change:      service.DiffChanged,
+			Context:     service.ContextKept,
+			Fields:      []string{"Startup"},
+			ConfigFiles: []service.ItemDiff{{Name: conf.Filename, Change: service.DiffChanged, Preserved: true}},
+		},
+		{Path: "/root/c", Change: service.DiffAdded},
+		{Path: "/root/b", ServiceID: b.ID, Change: service.DiffRemoved},
+	}
+	diff, err := ft.Facade.DiffTemplate(ft.CTX, tenantID, v2ID, false)
+	if err != nil {
+		t.Fatalf("Could not diff template: %v", err)
+	} else if !reflect.DeepEqual(diff.Services, expected) {
+		t.Errorf("\ngot:\n%+v\nwant:\n%+v", diff.Services, expected)
+	}
+
+	if _, err := ft.Facade.ApplyTemplate(ft.CTX, tenantID, v2ID, false); err != nil {
+		t.Fatalf("Could not apply template: %v", err)
+	}
+	if diff, err := ft.Facade.DiffTemplate(ft.CTX, tenantID, v2ID, false); err != nil {
+		t.Fatalf("Could not diff template: %v", err)
+	} else if !diff.Empty() {
+		t.Errorf("Expected no differences after applying, got %+v", diff)
+	}
+
+	// a keeps its id and the user's edits
+	applied, err := ft.Facade.GetService(ft.CTX, a.ID)
+	if err != nil {
+		t.Fatalf("Could not get service a: %v", err)
+	}
+	if applied.Startup != "a --v2" || applied.ConfigFiles[conf.Filename].Content != "edited" || applied.Context != a.Context {
+		t.Errorf("Unexpected service after applying template: %+v", applied)
+	}
+	if _, err := ft.Facade.GetService(ft.CTX, b.ID); !datastore.IsErrNoSuchEntity(err) {
+		t.Errorf("Expected service b to be removed, got %v", err)
+	}
+	if _, err := ft.Facade.FindChildService(ft.CTX, tenantID, "c"); err != nil {
+		t.Errorf("Expected service c to be added: %v", err)
+	}
+
+	// the context is only replaced when asked to
+	if diff, err := ft.Facade.DiffTemplate(ft.CTX, tenantID, v2ID, true); err != nil {
+		t.Fatalf("Could not diff template: %v", err)
+	} else if len(diff.Services) != 1 || diff.Services[0].Context != service.ContextOverwritten {
+		t.Errorf("Expected the context of a to be overwritten, got %+v", diff)
+	}
+	if _, err := ft.Facade.ApplyTemplate(ft.CTX, tenantID, v2ID, true); err != nil {
+		t.Fatalf("Could not apply template: %v", err)
+	}
+	if applied, err := ft.Facade.GetService(ft.CTX, a.ID); err != nil {
+		t.Fatalf("Could not get service a: %v", err)
+	} else if applied.Context == a.Context {
+		t.Errorf("Expected the context of a to be overwritten, got %s", applied.Context)
+	}
+
+	// templates that deploy a different application cannot be applied
+	v3 := v1
+	v3.Services = []servicedefinition.ServiceDefinition{{Name: "other", Launch: "auto"}}
+	v3ID, err := ft.Facade.AddServiceTemplate(ft.CTX, v3)
+	if err != nil {
+		t.Fatalf("Could not add template: %v", err)
+	}
+	defer ft.Facade.RemoveServiceTemplate(ft.CTX, v3ID)
+	if _, err := ft.Facade.ApplyTemplate(ft.CTX, tenantID, v3ID, false); err == nil {
+		t.Errorf("Expected failure applying a template for another application")
+	}
+}
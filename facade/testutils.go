@@ -8,12 +8,17 @@ import (
 	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/datastore/elastic"
 	"github.com/control-center/serviced/domain/addressassignment"
+	"github.com/control-center/serviced/domain/audit"
+	"github.com/control-center/serviced/domain/event"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/notification"
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/serviceconfigfile"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/domain/servicetemplate"
+	"github.com/control-center/serviced/domain/token"
 	"github.com/control-center/serviced/domain/user"
 	gocheck "gopkg.in/check.v1"
 )
@@ -39,6 +44,11 @@ func (ft *FacadeTest) SetUpSuite(c *gocheck.C) {
 	ft.Mappings = append(ft.Mappings, addressassignment.MAPPING)
 	ft.Mappings = append(ft.Mappings, serviceconfigfile.MAPPING)
 	ft.Mappings = append(ft.Mappings, user.MAPPING)
+	ft.Mappings = append(ft.Mappings, audit.MAPPING)
+	ft.Mappings = append(ft.Mappings, event.MAPPING)
+	ft.Mappings = append(ft.Mappings, notification.MAPPING)
+	ft.Mappings = append(ft.Mappings, role.MAPPING)
+	ft.Mappings = append(ft.Mappings, token.MAPPING)
 
 	ft.ElasticTest.SetUpSuite(c)
 	datastore.Register(ft.Driver())
@@ -0,0 +1,145 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/token"
+)
+
+// how often the last used time of a token is saved
+var tokenUsedInterval = time.Minute
+
+// CreateToken creates an API token for the user, or for the caller if user
+// is empty, and returns the bearer value that is the only copy of its secret.
+// Tokens cannot be used to create more tokens.
+func (f *Facade) CreateToken(ctx datastore.Context, user, description string, grants []role.Grant, ttl time.Duration) (string, error) {
+	caller := datastore.CallerOf(ctx)
+	glog.V(2).Infof("Facade.CreateToken: %s %+v", user, grants)
+	if caller.Token != "" {
+		return "", errors.New("API tokens cannot be created with an API token")
+	}
+	if user == "" {
+		user = caller.User
+	}
+
+	t, bearer, err := token.New(user, description, grants, ttl)
+	if err != nil {
+		return "", err
+	}
+	if err := t.ValidEntity(); err != nil {
+		return "", err
+	}
+	if err := f.tokenStore.Put(ctx, token.Key(t.ID), t); err != nil {
+		return "", err
+	}
+	f.Audit(ctx, "add", "token", t.ID, nil, withoutHash(t))
+	return bearer, nil
+}
+
+// GetToken gets an API token by id. Returns nil if the token does not exist.
+func (f *Facade) GetToken(ctx datastore.Context, id string) (*token.Token, error) {
+	glog.V(3).Infof("Facade.GetToken: %s", id)
+	var t token.Token
+	if err := f.tokenStore.Get(ctx, token.Key(id), &t); datastore.IsErrNoSuchEntity(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return withoutHash(&t), nil
+}
+
+// GetTokens returns the API tokens of the user, or of every user if user is
+// empty
+func (f *Facade) GetTokens(ctx datastore.Context, user string) ([]*token.Token, error) {
+	glog.V(3).Infof("Facade.GetTokens: %s", user)
+	tokens, err := f.tokenStore.GetTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tokens {
+		tokens[i] = withoutHash(tokens[i])
+	}
+	return tokens, nil
+}
+
+// RevokeToken removes an API token so it can no longer be used
+func (f *Facade) RevokeToken(ctx datastore.Context, id string) error {
+	glog.V(2).Infof("Facade.RevokeToken: %s", id)
+	t, err := f.GetToken(ctx, id)
+	if err != nil {
+		return err
+	} else if t == nil {
+		return fmt.Errorf("API token %s not found", id)
+	}
+	if err := f.tokenStore.Delete(ctx, token.Key(id)); err != nil {
+		return err
+	}
+	f.Audit(ctx, "remove", "token", id, t, nil)
+	return nil
+}
+
+// AuthenticateToken checks the bearer value of an API token and returns the
+// token if it is valid and has not expired. The time the token was last used
+// is saved at most once every tokenUsedInterval.
+func (f *Facade) AuthenticateToken(ctx datastore.Context, bearer string) (*token.Token, error) {
+	id, secret, err := token.Parse(bearer)
+	if err != nil {
+		return nil, err
+	}
+
+	var t token.Token
+	if err := f.tokenStore.Get(ctx, token.Key(id), &t); datastore.IsErrNoSuchEntity(err) {
+		return nil, token.ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if !t.Matches(secret) {
+		return nil, token.ErrInvalidToken
+	} else if t.Expired(now) {
+		return nil, token.ErrTokenExpired
+	}
+
+	if now.Sub(t.LastUsed) >= tokenUsedInterval {
+		t.LastUsed = now
+		if err := f.tokenStore.Put(ctx, token.Key(t.ID), &t); err != nil {
+			glog.Warningf("Unable to update the last use of API token %s: %s", t.ID, err)
+		}
+	}
+	return withoutHash(&t), nil
+}
+
+// authorizeToken checks that the API token the caller authenticated with is
+// still valid and that its scope permits the request
+func (f *Facade) authorizeToken(ctx datastore.Context, caller datastore.Caller, request role.Request) error {
+	var t token.Token
+	if err := f.tokenStore.Get(ctx, token.Key(caller.Token), &t); datastore.IsErrNoSuchEntity(err) {
+		return token.ErrInvalidToken
+	} else if err != nil {
+		return err
+	}
+	if t.User != caller.User {
+		return token.ErrInvalidToken
+	} else if t.Expired(time.Now()) {
+		return token.ErrTokenExpired
+	} else if !t.Allows(request) {
+		return role.ErrUnauthorized{User: caller.User, Request: request}
+	}
+	return nil
+}
+
+// withoutHash returns a copy of the token without the hash of its secret
+func withoutHash(t *token.Token) *token.Token {
+	result := *t
+	result.Hash = ""
+	return &result
+}
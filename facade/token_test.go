@@ -0,0 +1,71 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package facade
+
+import (
+	"time"
+
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/token"
+	. "gopkg.in/check.v1"
+)
+
+func (ft *FacadeTest) Test_CreateAuthenticateToken(t *C) {
+	bob := datastore.WithCaller(ft.CTX, datastore.Caller{User: "bob", Source: datastore.SourceCLI})
+	bearer, err := ft.Facade.CreateToken(bob, "", "automation", []role.Grant{{Role: role.Viewer}}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failure creating token: %s", err)
+	}
+
+	tok, err := ft.Facade.AuthenticateToken(ft.CTX, bearer)
+	if err != nil {
+		t.Fatalf("Failure authenticating token: %s", err)
+	}
+	defer ft.Facade.RevokeToken(ft.CTX, tok.ID)
+	if tok.User != "bob" || tok.Description != "automation" || tok.Hash != "" {
+		t.Errorf("Unexpected token %+v", tok)
+	}
+	if tok.LastUsed.IsZero() {
+		t.Errorf("Expected the last use of the token to be recorded")
+	}
+
+	if _, err := ft.Facade.AuthenticateToken(ft.CTX, tok.ID+".wrong"); err != token.ErrInvalidToken {
+		t.Errorf("Expected %s, got %v", token.ErrInvalidToken, err)
+	}
+
+	tokens, err := ft.Facade.GetTokens(ft.CTX, "bob")
+	if err != nil {
+		t.Fatalf("Failure getting tokens: %s", err)
+	} else if len(tokens) != 1 || tokens[0].ID != tok.ID {
+		t.Errorf("Expected token %s, got %+v", tok.ID, tokens)
+	}
+
+	// tokens are limited to their scope and cannot create more tokens
+	defer ft.Facade.RemoveUserRoles(ft.CTX, "bob")
+	if err := ft.Facade.GrantRole(ft.CTX, "bob", role.Grant{Role: role.Admin}); err != nil {
+		t.Fatalf("Failure granting role: %s", err)
+	}
+	withToken := datastore.WithCaller(ft.CTX, datastore.Caller{User: "bob", Source: datastore.SourceREST, Token: tok.ID})
+	if err := ft.Facade.Authorize(withToken, role.Request{Action: role.Read, Resource: role.ResourcePool}); err != nil {
+		t.Errorf("Expected the token to allow reading: %s", err)
+	}
+	if err := ft.Facade.Authorize(withToken, role.Request{Action: role.Modify, Resource: role.ResourcePool}); !role.IsErrUnauthorized(err) {
+		t.Errorf("Expected the token to deny modifying, got %v", err)
+	}
+	if _, err := ft.Facade.CreateToken(withToken, "", "", nil, 0); err == nil {
+		t.Errorf("Expected failure creating a token with a token")
+	}
+
+	if err := ft.Facade.RevokeToken(ft.CTX, tok.ID); err != nil {
+		t.Fatalf("Failure revoking token: %s", err)
+	}
+	if _, err := ft.Facade.AuthenticateToken(ft.CTX, bearer); err != token.ErrInvalidToken {
+		t.Errorf("Expected a revoked token to be invalid, got %v", err)
+	}
+	if err := ft.Facade.Authorize(withToken, role.Request{Action: role.Read, Resource: role.ResourcePool}); !token.IsErrInvalid(err) {
+		t.Errorf("Expected a revoked token to be denied, got %v", err)
+	}
+}
@@ -7,10 +7,9 @@ package health
 import (
 	"github.com/zenoss/glog"
 	"github.com/zenoss/go-json-rest"
-	"github.com/control-center/serviced/dao"
-	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/node"
-	"sync"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"net/http"
 	"time"
 )
 
@@ -25,47 +24,51 @@ type messagePacket struct {
 	Statuses  map[string]map[string]*healthStatus
 }
 
-var healthStatuses = make(map[string]map[string]*healthStatus)
-var exitChannel = make(chan bool)
-var lock = &sync.Mutex{}
-
-// RestGetHealthStatus writes a JSON response with the health status of all services that have health checks.
-func RestGetHealthStatus(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
-	packet := messagePacket{time.Now().UTC().Unix(), healthStatuses}
-	w.WriteJson(&packet)
+// precedence of the statuses when the instances of a service disagree
+var precedence = map[string]int{
+	zkhealth.StatusFailed: 3,
+	zkhealth.StatusMissed: 2,
+	zkhealth.StatusPassed: 1,
 }
 
-// RegisterHealthCheck updates the healthStatus and healthTime structures with a health check result.
-func RegisterHealthCheck(serviceID string, name string, passed string, d dao.ControlPlane) {
-	lock.Lock()
-	defer lock.Unlock()
-
-	serviceStatus, ok := healthStatuses[serviceID]
-	if !ok {
-		// healthStatuses[serviceID]
-		serviceStatus = make(map[string]*healthStatus)
-		healthStatuses[serviceID] = serviceStatus
-	}
-	thisStatus, ok := serviceStatus[name]
-	if !ok {
-		var service service.Service
-		err := d.GetService(serviceID, &service)
-		if err != nil {
-			glog.Errorf("Unable to acquire services.")
-			return
+// summarize combines the statuses of the instances of each service into one
+// status per service and health check: the worst of the instances, as of the
+// most recent result
+func summarize(statuses []*zkhealth.Status, now time.Time) map[string]map[string]*healthStatus {
+	summary := make(map[string]map[string]*healthStatus)
+	for _, s := range statuses {
+		serviceStatus, ok := summary[s.ServiceID]
+		if !ok {
+			serviceStatus = make(map[string]*healthStatus)
+			summary[s.ServiceID] = serviceStatus
 		}
-		for iname, icheck := range service.HealthChecks {
-			_, ok = serviceStatus[iname]
-			if !ok {
-				serviceStatus[name] = &healthStatus{"unknown", 0, icheck.Interval.Seconds()}
+		status := s.Current(now)
+		timestamp := s.Timestamp.Unix()
+		if thisStatus, ok := serviceStatus[s.Name]; !ok {
+			serviceStatus[s.Name] = &healthStatus{status, timestamp, s.Interval.Seconds()}
+		} else {
+			if precedence[status] > precedence[thisStatus.Status] {
+				thisStatus.Status = status
+			}
+			if timestamp > thisStatus.Timestamp {
+				thisStatus.Timestamp = timestamp
 			}
 		}
 	}
-	thisStatus, ok = serviceStatus[name]
-	if !ok {
-		glog.Warningf("ignoring health status, not found in service: %s %s %s", serviceID, name, passed)
+	return summary
+}
+
+// RestGetHealthStatus writes a JSON response with the health status of all services that have health checks.
+func RestGetHealthStatus(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	var empty interface{}
+	var statuses []*zkhealth.Status
+	if err := client.GetHealthStatuses(&empty, &statuses); err != nil {
+		glog.Errorf("Could not get health statuses: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteJson(map[string]string{"Detail": err.Error()})
 		return
 	}
-	thisStatus.Status = passed
-	thisStatus.Timestamp = time.Now().UTC().Unix()
+	now := time.Now()
+	packet := messagePacket{now.UTC().Unix(), summarize(statuses, now)}
+	w.WriteJson(&packet)
 }
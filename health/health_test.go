@@ -0,0 +1,31 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	zkhealth "github.com/control-center/serviced/zzk/health"
+
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Now()
+	statuses := []*zkhealth.Status{
+		{ServiceID: "s1", InstanceID: 0, Name: "ready", Status: zkhealth.StatusPassed, Timestamp: now.Add(-time.Second), Interval: 5 * time.Second},
+		{ServiceID: "s1", InstanceID: 1, Name: "ready", Status: zkhealth.StatusPassed, Timestamp: now.Add(-time.Minute), Interval: 5 * time.Second},
+		{ServiceID: "s1", InstanceID: 2, Name: "ready", Status: zkhealth.StatusPassed, Timestamp: now.Add(-2 * time.Second), Interval: 5 * time.Second},
+		{ServiceID: "s2", InstanceID: 0, Name: "answering", Status: zkhealth.StatusFailed, Timestamp: now.Add(-3 * time.Second), Interval: 5 * time.Second},
+		{ServiceID: "s2", InstanceID: 1, Name: "answering", Status: zkhealth.StatusPassed, Timestamp: now.Add(-time.Second), Interval: 5 * time.Second},
+	}
+
+	summary := summarize(statuses, now)
+	if s := summary["s1"]["ready"]; s == nil || s.Status != zkhealth.StatusMissed || s.Timestamp != now.Add(-time.Second).Unix() || s.Interval != 5 {
+		t.Errorf("Expected s1 to have missed a check; got %+v", s)
+	}
+	if s := summary["s2"]["answering"]; s == nil || s.Status != zkhealth.StatusFailed {
+		t.Errorf("Expected s2 to be failing; got %+v", s)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package metrics queries the performance data that the control plane and
+// its containers report to the metrics service.
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultAddress is where the metric query service listens on the master
+const DefaultAddress = "http://127.0.0.1:8888"
+
+// Query is a request for the values of one or more metrics over a time span
+type Query struct {
+	Start      string        `json:"start"`                // e.g. "5m-ago"
+	End        string        `json:"end,omitempty"`        // defaults to now
+	Downsample string        `json:"downsample,omitempty"` // e.g. "1m-avg"
+	Metrics    []MetricQuery `json:"metrics"`
+}
+
+// MetricQuery selects a metric, and combines the series that match its tags
+// using the aggregator
+type MetricQuery struct {
+	Metric     string              `json:"metric"`
+	Aggregator string              `json:"aggregator,omitempty"` // e.g. "avg", "sum", "max"
+	Rate       bool                `json:"rate,omitempty"`
+	Tags       map[string][]string `json:"tags,omitempty"`
+}
+
+// Datapoint is a single value of a metric
+type Datapoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type byTimestamp []Datapoint
+
+func (d byTimestamp) Len() int           { return len(d) }
+func (d byTimestamp) Less(i, j int) bool { return d[i].Timestamp < d[j].Timestamp }
+func (d byTimestamp) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// Result is the series returned for a metric
+type Result struct {
+	Metric     string      `json:"metric"`
+	Datapoints []Datapoint `json:"datapoints"`
+}
+
+// Average returns the mean of the datapoints, and false if there are none
+func (r Result) Average() (float64, bool) {
+	if len(r.Datapoints) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, dp := range r.Datapoints {
+		sum += dp.Value
+	}
+	return sum / float64(len(r.Datapoints)), true
+}
+
+type queryResponse struct {
+	Results []Result `json:"results"`
+}
+
+// Client queries the metric service
+type Client struct {
+	address string
+	client  *http.Client
+}
+
+// NewClient creates a client for the metric service at the address
+func NewClient(address string) *Client {
+	return &Client{
+		address: strings.TrimRight(address, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Query returns the series that match the query
+func (c *Client) Query(query Query) ([]Result, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Post(c.address+"/api/performance/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metric query failed: %s", resp.Status)
+	}
+
+	var response queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("could not decode metric query response: %s", err)
+	}
+	return response.Results, nil
+}
+
+// ServiceAverage returns the average of a metric across the instances of a
+// service since the start, and false if no values were reported
+func (c *Client) ServiceAverage(serviceID, metric, start string) (float64, bool, error) {
+	results, err := c.Query(Query{
+		Start: start,
+		Metrics: []MetricQuery{{
+			Metric:     metric,
+			Aggregator: "avg",
+			Tags:       map[string][]string{"controlplane_service_id": []string{serviceID}},
+		}},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	for _, result := range results {
+		if value, ok := result.Average(); ok {
+			return value, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// Series returns the values of a metric since the start, oldest first, with
+// the series that match the tags averaged together. The values are
+// downsampled if downsample is set, e.g. "1m-avg".
+func (c *Client) Series(metric string, tags map[string][]string, start, downsample string) ([]Datapoint, error) {
+	results, err := c.Query(Query{
+		Start:      start,
+		Downsample: downsample,
+		Metrics: []MetricQuery{{
+			Metric:     metric,
+			Aggregator: "avg",
+			Tags:       tags,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if len(result.Datapoints) > 0 {
+			sort.Sort(byTimestamp(result.Datapoints))
+			return result.Datapoints, nil
+		}
+	}
+	return []Datapoint{}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClient_ServiceAverage(t *testing.T) {
+	var received Query
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/performance/query" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("could not decode query: %s", err)
+		}
+		w.Write([]byte(`{"clientId":"x","results":[{"metric":"cpu.user","datapoints":[{"timestamp":1,"value":10},{"timestamp":2,"value":30}]}]}`))
+	}))
+	defer server.Close()
+
+	value, ok, err := NewClient(server.URL+"/").ServiceAverage("service-id", "cpu.user", "5m-ago")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !ok || value != 20 {
+		t.Errorf("expected average 20; got %v (%v)", value, ok)
+	}
+
+	expected := Query{
+		Start: "5m-ago",
+		Metrics: []MetricQuery{{
+			Metric:     "cpu.user",
+			Aggregator: "avg",
+			Tags:       map[string][]string{"controlplane_service_id": []string{"service-id"}},
+		}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("MISMATCH: query %+v != %+v", expected, received)
+	}
+}
+
+func TestClient_QueryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, _, err := NewClient(server.URL).ServiceAverage("service-id", "cpu.user", "5m-ago"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestResult_Average(t *testing.T) {
+	if _, ok := (Result{}).Average(); ok {
+		t.Errorf("expected no average without datapoints")
+	}
+}
+
+func TestClient_Series(t *testing.T) {
+	var received Query
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("could not decode query: %s", err)
+		}
+		w.Write([]byte(`{"results":[{"metric":"swap.free","datapoints":[{"timestamp":2,"value":0},{"timestamp":1,"value":5}]}]}`))
+	}))
+	defer server.Close()
+
+	tags := map[string][]string{"controlplane_host_id": []string{"host-1", "host-2"}}
+	values, err := NewClient(server.URL).Series("swap.free", tags, "600s-ago", "60s-avg")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if expected := []Datapoint{{1, 5}, {2, 0}}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected values oldest first %v; got %v", expected, values)
+	}
+	if received.Start != "600s-ago" || received.Downsample != "60s-avg" || !reflect.DeepEqual(received.Metrics[0].Tags, tags) {
+		t.Errorf("unexpected query %+v", received)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+// Types of thresholds
+const (
+	ThresholdMinMax      = "MinMax"
+	ThresholdDuration    = "Duration"
+	ThresholdValueChange = "ValueChange"
+	ThresholdHoltWinters = "HoltWinters"
+)
+
+var (
+	// how far back the last values of a metric are looked for
+	lastValueWindow = 5 * time.Minute
+
+	// the interval the values of a metric are downsampled to for forecasting
+	holtWintersStep = time.Minute
+
+	// how many deviations a value may stray from its forecast
+	holtWintersDelta = 2.0
+)
+
+// Breach describes how the values of a metric breached a threshold
+type Breach struct {
+	Metric  string
+	Value   float64
+	Summary string
+}
+
+// Evaluator applies the semantics of a type of threshold to the values of a
+// metric
+type Evaluator interface {
+	// Window returns how far back the values are needed, and how they are
+	// downsampled, if at all
+	Window() (start, downsample string)
+
+	// Evaluate returns the breach of the threshold by the values, oldest
+	// first, or nil if the threshold is not breached. ok is false if there
+	// are not enough values to tell.
+	Evaluate(metric string, values []Datapoint) (breach *Breach, ok bool)
+}
+
+// NewEvaluator creates the evaluator of a threshold. The threshold data may
+// be the threshold type itself or its JSON representation as a map.
+func NewEvaluator(config domain.ThresholdConfig) (Evaluator, error) {
+	switch config.Type {
+	case ThresholdMinMax:
+		var t domain.MinMaxThreshold
+		if err := decodeThreshold(config, &t); err != nil {
+			return nil, err
+		}
+		return &minMaxEvaluator{t}, nil
+	case ThresholdDuration:
+		var t domain.DurationThreshold
+		if err := decodeThreshold(config, &t); err != nil {
+			return nil, err
+		} else if t.TimePeriod < time.Second {
+			return nil, fmt.Errorf("threshold %s needs a time period of at least a second", config.ID)
+		} else if t.Percentage < 0 || t.Percentage > 100 {
+			return nil, fmt.Errorf("threshold %s needs a percentage from 0 to 100", config.ID)
+		}
+		return &durationEvaluator{t}, nil
+	case ThresholdValueChange:
+		return &valueChangeEvaluator{}, nil
+	case ThresholdHoltWinters:
+		var t domain.HoltWintersThreshold
+		if err := decodeThreshold(config, &t); err != nil {
+			return nil, err
+		} else if t.Alpha < 0 || t.Alpha > 1 || t.Beta < 0 || t.Beta > 1 {
+			return nil, fmt.Errorf("threshold %s needs an alpha and a beta from 0 to 1", config.ID)
+		} else if t.Season < 1 || t.Rows <= t.Season {
+			return nil, fmt.Errorf("threshold %s needs more rows than the length of its season", config.ID)
+		}
+		return &holtWintersEvaluator{t}, nil
+	}
+	return nil, fmt.Errorf("threshold %s has unknown type %q", config.ID, config.Type)
+}
+
+func decodeThreshold(config domain.ThresholdConfig, threshold interface{}) error {
+	data, err := json.Marshal(config.Threshold)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, threshold); err != nil {
+		return fmt.Errorf("threshold %s is not a valid %s threshold: %s", config.ID, config.Type, err)
+	}
+	return nil
+}
+
+// ago returns the metric query start of a duration before now
+func ago(d time.Duration) string {
+	return fmt.Sprintf("%ds-ago", int64(d/time.Second))
+}
+
+// outside is true if the value is below the min or above the max
+func outside(min, max *int64, value float64) bool {
+	return (min != nil && value < float64(*min)) || (max != nil && value > float64(*max))
+}
+
+// describeRange describes the values outside the min and max
+func describeRange(min, max *int64) string {
+	switch {
+	case min != nil && max != nil:
+		return fmt.Sprintf("outside %d to %d", *min, *max)
+	case min != nil:
+		return fmt.Sprintf("below %d", *min)
+	case max != nil:
+		return fmt.Sprintf("above %d", *max)
+	}
+	return "outside no range"
+}
+
+// minMaxEvaluator breaches when the last value is outside the min and max
+type minMaxEvaluator struct {
+	domain.MinMaxThreshold
+}
+
+func (e *minMaxEvaluator) Window() (string, string) {
+	return ago(lastValueWindow), ""
+}
+
+func (e *minMaxEvaluator) Evaluate(metric string, values []Datapoint) (*Breach, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	value := values[len(values)-1].Value
+	if !outside(e.Min, e.Max, value) {
+		return nil, true
+	}
+	return &Breach{
+		Metric:  metric,
+		Value:   value,
+		Summary: fmt.Sprintf("%s is %g, %s", metric, value, describeRange(e.Min, e.Max)),
+	}, true
+}
+
+// durationEvaluator breaches when at least the percentage of the values
+// within the time period are outside the min and max. A percentage of 0
+// breaches on any value outside them.
+type durationEvaluator struct {
+	domain.DurationThreshold
+}
+
+func (e *durationEvaluator) Window() (string, string) {
+	return ago(e.TimePeriod), ""
+}
+
+func (e *durationEvaluator) Evaluate(metric string, values []Datapoint) (*Breach, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	var violations int
+	var last float64
+	for _, dp := range values {
+		if outside(e.Min, e.Max, dp.Value) {
+			violations++
+			last = dp.Value
+		}
+	}
+	if violations == 0 || violations*100 < e.Percentage*len(values) {
+		return nil, true
+	}
+	return &Breach{
+		Metric: metric,
+		Value:  last,
+		Summary: fmt.Sprintf("%s was %s in %d of %d values (%d%%) over the last %s",
+			metric, describeRange(e.Min, e.Max), violations, len(values), violations*100/len(values), e.TimePeriod),
+	}, true
+}
+
+// valueChangeEvaluator breaches when the last value differs from the one
+// before it
+type valueChangeEvaluator struct{}
+
+func (e *valueChangeEvaluator) Window() (string, string) {
+	return ago(lastValueWindow), ""
+}
+
+func (e *valueChangeEvaluator) Evaluate(metric string, values []Datapoint) (*Breach, bool) {
+	if len(values) < 2 {
+		return nil, false
+	}
+	previous, value := values[len(values)-2].Value, values[len(values)-1].Value
+	if value == previous {
+		return nil, true
+	}
+	return &Breach{
+		Metric:  metric,
+		Value:   value,
+		Summary: fmt.Sprintf("%s changed from %g to %g", metric, previous, value),
+	}, true
+}
+
+// holtWintersEvaluator forecasts the last value from the values before it
+// with additive Holt-Winters smoothing, and breaches when the value strays
+// from its forecast by more than holtWintersDelta smoothed deviations. As in
+// rrdtool, the seasonal coefficients and deviations are smoothed with alpha.
+type holtWintersEvaluator struct {
+	domain.HoltWintersThreshold
+}
+
+func (e *holtWintersEvaluator) Window() (string, string) {
+	return ago(time.Duration(e.Rows) * holtWintersStep), fmt.Sprintf("%ds-avg", int64(holtWintersStep/time.Second))
+}
+
+func (e *holtWintersEvaluator) Evaluate(metric string, values []Datapoint) (*Breach, bool) {
+	m, n := int(e.Season), len(values)
+	if n <= m {
+		return nil, false
+	}
+
+	// the first season initializes the level, and the seasonal coefficients
+	// and deviations
+	var level, trend float64
+	for i := 0; i < m; i++ {
+		level += values[i].Value
+	}
+	level /= float64(m)
+	seasonal, deviation := make([]float64, m), make([]float64, m)
+	for i := 0; i < m; i++ {
+		seasonal[i] = values[i].Value - level
+		deviation[i] = math.Abs(seasonal[i])
+	}
+
+	gamma := e.Alpha
+	for t := m; t < n-1; t++ {
+		s, x := t%m, values[t].Value
+		forecast := level + trend + seasonal[s]
+		deviation[s] = gamma*math.Abs(x-forecast) + (1-gamma)*deviation[s]
+		previous := level
+		level = e.Alpha*(x-seasonal[s]) + (1-e.Alpha)*(level+trend)
+		trend = e.Beta*(level-previous) + (1-e.Beta)*trend
+		seasonal[s] = gamma*(x-level) + (1-gamma)*seasonal[s]
+	}
+
+	s, value := (n-1)%m, values[n-1].Value
+	forecast, bound := level+trend+seasonal[s], holtWintersDelta*deviation[s]
+	if diff := math.Abs(value - forecast); diff <= bound || diff < 1e-9 {
+		return nil, true
+	}
+	return &Breach{
+		Metric:  metric,
+		Value:   value,
+		Summary: fmt.Sprintf("%s is %g, outside its forecast of %.2f ± %.2f", metric, value, forecast, bound),
+	}, true
+}
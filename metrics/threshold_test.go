@@ -0,0 +1,139 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/domain"
+)
+
+func int64p(v int64) *int64 { return &v }
+
+func datapoints(values ...float64) []Datapoint {
+	dps := make([]Datapoint, len(values))
+	for i, v := range values {
+		dps[i] = Datapoint{Timestamp: int64(i), Value: v}
+	}
+	return dps
+}
+
+func newTestEvaluator(t *testing.T, config domain.ThresholdConfig) Evaluator {
+	e, err := NewEvaluator(config)
+	if err != nil {
+		t.Fatalf("unexpected error creating evaluator: %s", err)
+	}
+	return e
+}
+
+func TestEvaluator_MinMax(t *testing.T) {
+	e := newTestEvaluator(t, domain.ThresholdConfig{ID: "swap.empty", Type: ThresholdMinMax, Threshold: domain.MinMaxThreshold{Min: int64p(0), Max: int64p(100)}})
+	if start, downsample := e.Window(); start != "300s-ago" || downsample != "" {
+		t.Errorf("unexpected window %s %s", start, downsample)
+	}
+
+	if _, ok := e.Evaluate("swap.free", nil); ok {
+		t.Errorf("expected no decision without values")
+	}
+	if breach, ok := e.Evaluate("swap.free", datapoints(-1, 50)); !ok || breach != nil {
+		t.Errorf("expected only the last value to count; got %+v", breach)
+	}
+	if breach, ok := e.Evaluate("swap.free", datapoints(50, 101)); !ok || breach == nil || breach.Value != 101 {
+		t.Errorf("expected a breach of the max; got %+v", breach)
+	} else if breach.Summary != "swap.free is 101, outside 0 to 100" {
+		t.Errorf("unexpected summary %q", breach.Summary)
+	}
+}
+
+func TestEvaluator_Duration(t *testing.T) {
+	config := domain.ThresholdConfig{ID: "cpu.high", Type: ThresholdDuration, Threshold: domain.DurationThreshold{Max: int64p(90), TimePeriod: 10 * time.Minute, Percentage: 50}}
+	e := newTestEvaluator(t, config)
+	if start, _ := e.Window(); start != "600s-ago" {
+		t.Errorf("unexpected window start %s", start)
+	}
+
+	if breach, ok := e.Evaluate("cpu.user", datapoints(95, 10, 10, 10)); !ok || breach != nil {
+		t.Errorf("expected 25%% of violations not to breach; got %+v", breach)
+	}
+	if breach, ok := e.Evaluate("cpu.user", datapoints(95, 10, 99, 10)); !ok || breach == nil || breach.Value != 99 {
+		t.Errorf("expected 50%% of violations to breach; got %+v", breach)
+	} else if breach.Summary != "cpu.user was above 90 in 2 of 4 values (50%) over the last 10m0s" {
+		t.Errorf("unexpected summary %q", breach.Summary)
+	}
+
+	// any violation breaches a threshold of 0 percent, none breaches without one
+	config.Threshold = domain.DurationThreshold{Max: int64p(90), TimePeriod: 10 * time.Minute}
+	e = newTestEvaluator(t, config)
+	if breach, _ := e.Evaluate("cpu.user", datapoints(10, 10, 91, 10)); breach == nil {
+		t.Errorf("expected a single violation to breach")
+	}
+	if breach, _ := e.Evaluate("cpu.user", datapoints(10, 10)); breach != nil {
+		t.Errorf("expected no breach without violations; got %+v", breach)
+	}
+
+	config.Threshold = domain.DurationThreshold{Max: int64p(90), TimePeriod: 10 * time.Minute, Percentage: 101}
+	if _, err := NewEvaluator(config); err == nil {
+		t.Errorf("expected an error for a percentage over 100")
+	}
+}
+
+func TestEvaluator_ValueChange(t *testing.T) {
+	e := newTestEvaluator(t, domain.ThresholdConfig{ID: "changed", Type: ThresholdValueChange})
+	if _, ok := e.Evaluate("state", datapoints(1)); ok {
+		t.Errorf("expected no decision from a single value")
+	}
+	if breach, ok := e.Evaluate("state", datapoints(2, 1, 1)); !ok || breach != nil {
+		t.Errorf("expected no breach; got %+v", breach)
+	}
+	if breach, _ := e.Evaluate("state", datapoints(1, 1, 3)); breach == nil || breach.Summary != "state changed from 1 to 3" {
+		t.Errorf("expected a breach; got %+v", breach)
+	}
+}
+
+func TestEvaluator_HoltWinters(t *testing.T) {
+	e := newTestEvaluator(t, domain.ThresholdConfig{ID: "forecast", Type: ThresholdHoltWinters, Threshold: domain.HoltWintersThreshold{Alpha: 0.5, Beta: 0.1, Rows: 12, Season: 4}})
+	if start, downsample := e.Window(); start != "720s-ago" || downsample != "60s-avg" {
+		t.Errorf("unexpected window %s %s", start, downsample)
+	}
+
+	season := []float64{10, 20, 30, 20, 11, 19, 31, 21, 10, 20, 30}
+	if _, ok := e.Evaluate("load", datapoints(season[:4]...)); ok {
+		t.Errorf("expected no decision from a single season")
+	}
+	if breach, ok := e.Evaluate("load", datapoints(append(season, 20)...)); !ok || breach != nil {
+		t.Errorf("expected a seasonal value not to breach; got %+v", breach)
+	}
+	if breach, ok := e.Evaluate("load", datapoints(append(season, 80)...)); !ok || breach == nil || breach.Value != 80 {
+		t.Errorf("expected an unseasonal value to breach; got %+v", breach)
+	}
+}
+
+func TestNewEvaluator(t *testing.T) {
+	// thresholds read from the datastore are maps
+	var config domain.ThresholdConfig
+	data := `{"ID":"cpu.high","Type":"Duration","Threshold":{"Max":90,"TimePeriod":300,"Percentage":80}}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("could not decode threshold: %s", err)
+	}
+	e, err := NewEvaluator(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if d, ok := e.(*durationEvaluator); !ok || d.TimePeriod != 5*time.Minute || *d.Max != 90 || d.Percentage != 80 {
+		t.Errorf("unexpected evaluator %+v", e)
+	}
+
+	for _, config := range []domain.ThresholdConfig{
+		{ID: "unknown", Type: "Unknown"},
+		{ID: "minmax", Type: ThresholdMinMax, Threshold: "0"},
+		{ID: "duration", Type: ThresholdDuration, Threshold: domain.DurationThreshold{}},
+		{ID: "holtwinters", Type: ThresholdHoltWinters, Threshold: domain.HoltWintersThreshold{Alpha: 0.5, Rows: 4, Season: 4}},
+	} {
+		if _, err := NewEvaluator(config); err == nil {
+			t.Errorf("expected an error for threshold %s", config.ID)
+		}
+	}
+}
@@ -74,6 +74,7 @@ type HostAgent struct {
 	vfs                  string               // driver for container volumes
 	currentServices      map[string]*exec.Cmd // the current running services
 	mux                  *proxy.TCPMux
+	certs                *HostCertificates // the certificates issued to the host
 	proxyRegistry        proxy.ProxyRegistry
 	zkClient             *coordclient.Client
 	dockerRegistry       string        // the docker registry to use
@@ -109,6 +110,7 @@ type AgentOptions struct {
 	VFS                  string
 	Zookeepers           []string
 	Mux                  *proxy.TCPMux
+	Certificates         *HostCertificates // The certificates issued to the host
 	DockerRegistry       string
 	MaxContainerAge      time.Duration // Maximum container age for a stopped container before being removed
 	VirtualAddressSubnet string
@@ -127,6 +129,7 @@ func NewHostAgent(options AgentOptions) (*HostAgent, error) {
 	agent.mount = options.Mount
 	agent.vfs = options.VFS
 	agent.mux = options.Mux
+	agent.certs = options.Certificates
 	agent.maxContainerAge = options.MaxContainerAge
 	agent.virtualAddressSubnet = options.VirtualAddressSubnet
 
@@ -564,7 +567,7 @@ func configureContainer(a *HostAgent, client *ControlClient,
 					}
 				}
 				var p string
-				switch endpoint.Protocol {
+				switch strings.ToLower(endpoint.Protocol) {
 				case commons.UDP:
 					p = fmt.Sprintf("%d/%s", port, "udp")
 				default:
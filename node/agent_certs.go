@@ -0,0 +1,219 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// This file implements the certificates of the host agent.
+package node
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/proxy"
+	"github.com/control-center/serviced/rpc/master"
+	"github.com/control-center/serviced/rpc/rpcutils"
+)
+
+// hostCertsFile is the file in the certs directory of the var path that the
+// certificates of the host are kept in
+const hostCertsFile = "host.json"
+
+// ErrNoJoinToken is returned when a host that has not been issued a
+// certificate has no join token to prove who it is with
+var ErrNoJoinToken = errors.New("no certificate has been issued to this host; start it with a join token created by 'serviced cert join-token HOSTID'")
+
+// CertificateOptions configure the certificates of a host
+type CertificateOptions struct {
+	Master    string
+	HostID    string
+	VarPath   string
+	JoinToken string             // Proves who the host is until it has been issued a certificate
+	MuxTLS    *proxy.TLSListener // The listener of the mux, if it uses TLS
+	MuxCert   *tls.Certificate   // A certificate the mux presents to clients that are not peers, if configured
+}
+
+// HostCertificates are the certificates the master issues to a host. They
+// identify the host to the master and to the muxes of its peers.
+type HostCertificates struct {
+	master    string
+	hostID    string
+	filename  string
+	joinToken string
+	muxTLS    *proxy.TLSListener
+	muxCert   *tls.Certificate
+	lock      sync.Mutex
+	bundle    *proxy.CertificateBundle
+}
+
+// NewHostCertificates uses the certificates that were issued to the host
+// before, if any
+func NewHostCertificates(options CertificateOptions) (*HostCertificates, error) {
+	c := &HostCertificates{
+		master:    options.Master,
+		hostID:    options.HostID,
+		filename:  path.Join(options.VarPath, "certs", hostCertsFile),
+		joinToken: options.JoinToken,
+		muxTLS:    options.MuxTLS,
+		muxCert:   options.MuxCert,
+	}
+
+	data, err := ioutil.ReadFile(c.filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var bundle proxy.CertificateBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, err
+	}
+	if err := c.use(&bundle); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the certificates of the host, or nil if none have been issued
+func (c *HostCertificates) Get() *proxy.CertificateBundle {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.bundle
+}
+
+// Refresh has the master issue the certificates of the host if it has none,
+// if they have been rotated, or if they are about to expire. A host proves
+// who it is with its current certificate, or with its join token if it has
+// none that is still valid.
+func (c *HostCertificates) Refresh() error {
+	current := c.Get()
+	joinToken := ""
+	if current == nil || expired(current) {
+		if c.joinToken == "" {
+			return ErrNoJoinToken
+		}
+		joinToken = c.joinToken
+
+		// the master refuses connections with a certificate that has expired
+		creds := rpcutils.DefaultCredentials()
+		creds.TLS = nil
+		rpcutils.SetDefaultCredentials(creds)
+	}
+
+	rpcMaster, err := master.NewClient(c.master)
+	if err != nil {
+		return err
+	}
+	defer rpcMaster.Close()
+
+	if joinToken == "" {
+		generation, err := rpcMaster.GetCertificateGeneration()
+		if err != nil {
+			return err
+		}
+		// certificates issued before there were proxy certificates are
+		// reissued so containers can dial muxes without the host's key
+		if current.Generation == generation && !current.NeedsRenewal() && current.ProxyBundle() != nil {
+			return nil
+		}
+	}
+
+	bundle, err := rpcMaster.IssueCertificate(c.hostID, joinToken)
+	if err != nil {
+		return err
+	}
+	if err := c.save(bundle); err != nil {
+		return err
+	}
+	if err := c.use(bundle); err != nil {
+		return err
+	}
+	glog.Infof("Using certificates of generation %d", bundle.Generation)
+	return nil
+}
+
+// RefreshLoop keeps the certificates of the host up to date until shutdown
+func (c *HostCertificates) RefreshLoop(interval time.Duration, shutdown <-chan interface{}) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-shutdown:
+			return
+		}
+		if err := c.Refresh(); err != nil {
+			glog.Errorf("Could not refresh certificates: %s", err)
+		}
+	}
+}
+
+// use has the mux and the connections to the master use the certificates
+func (c *HostCertificates) use(bundle *proxy.CertificateBundle) error {
+	clientConfig, err := bundle.ClientConfig()
+	if err != nil {
+		return err
+	}
+	if c.muxTLS != nil {
+		config, err := bundle.ServerConfig()
+		if err != nil {
+			return err
+		}
+		if c.muxCert != nil {
+			// peers ask for the issued certificate by name; other clients
+			// get the configured one
+			config.Certificates = append([]tls.Certificate{*c.muxCert}, config.Certificates...)
+			config.BuildNameToCertificate()
+		}
+		c.muxTLS.SetConfig(config)
+	}
+
+	creds := rpcutils.DefaultCredentials()
+	creds.TLS = clientConfig
+	rpcutils.SetDefaultCredentials(creds)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.bundle = bundle
+	return nil
+}
+
+// save keeps the certificates for when the agent restarts
+func (c *HostCertificates) save(bundle *proxy.CertificateBundle) error {
+	if err := os.MkdirAll(path.Dir(c.filename), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	// write a new file and move it into place so a crash never leaves
+	// partial certificates behind
+	if err := ioutil.WriteFile(c.filename+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(c.filename+".tmp", c.filename)
+}
+
+// expired is true if the certificate of the bundle can no longer be used
+func expired(bundle *proxy.CertificateBundle) bool {
+	expires, err := bundle.Expires()
+	return err != nil || time.Now().After(expires)
+}
+
+// GetProxyCertificates returns the certificates that the proxies of the
+// containers on this host dial muxes with. They are not the certificates of
+// the host, whose key never leaves the agent.
+func (a *HostAgent) GetProxyCertificates(_ string, bundle *proxy.CertificateBundle) error {
+	certs := a.certs.Get().ProxyBundle()
+	if certs == nil {
+		return proxy.ErrNoCertificates
+	}
+	*bundle = *certs
+	glog.V(4).Infof("ControlPlaneAgent.GetProxyCertificates(): generation %d", bundle.Generation)
+	return nil
+}
@@ -14,13 +14,18 @@ import (
 
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/addressassignment"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicestate"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/domain/user"
+	"github.com/control-center/serviced/rpc/rpcutils"
 	"github.com/control-center/serviced/volume"
+	"github.com/control-center/serviced/zzk/autoscale"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 )
 
 // A serviced client.
@@ -37,7 +42,17 @@ func NewControlClient(addr string) (s *ControlClient, err error) {
 	s = new(ControlClient)
 	s.addr = addr
 	glog.V(4).Infof("Connecting to %s", addr)
-	rpcClient, err := rpc.DialHTTP("tcp", s.addr)
+	rpcClient, err := rpcutils.DialHTTP(s.addr, datastore.Caller{})
+	s.rpcClient = rpcClient
+	return s, err
+}
+
+// Create a new ControlClient whose requests are made on behalf of the caller.
+func NewControlClientForCaller(addr string, caller datastore.Caller) (s *ControlClient, err error) {
+	s = new(ControlClient)
+	s.addr = addr
+	glog.V(4).Infof("Connecting to %s as %+v", addr, caller)
+	rpcClient, err := rpcutils.DialHTTP(s.addr, caller)
 	s.rpcClient = rpcClient
 	return s, err
 }
@@ -83,6 +98,10 @@ func (s *ControlClient) UpdateService(service service.Service, unused *int) (err
 	return s.rpcClient.Call("ControlPlane.UpdateService", service, unused)
 }
 
+func (s *ControlClient) UpdateServiceRolling(request dao.ServiceUpdateRequest, unused *int) (err error) {
+	return s.rpcClient.Call("ControlPlane.UpdateServiceRolling", request, unused)
+}
+
 func (s *ControlClient) RemoveService(serviceId string, unused *int) (err error) {
 	return s.rpcClient.Call("ControlPlane.RemoveService", serviceId, unused)
 }
@@ -135,8 +154,28 @@ func (s *ControlClient) StartService(serviceId string, hostId *string) (err erro
 	return s.rpcClient.Call("ControlPlane.StartService", serviceId, hostId)
 }
 
-func (s *ControlClient) RestartService(serviceId string, unused *int) (err error) {
-	return s.rpcClient.Call("ControlPlane.RestartService", serviceId, unused)
+func (s *ControlClient) RestartService(request dao.ServiceRestartRequest, unused *int) (err error) {
+	return s.rpcClient.Call("ControlPlane.RestartService", request, unused)
+}
+
+func (s *ControlClient) GetServiceRollout(serviceId string, rollout *rollout.Rollout) (err error) {
+	return s.rpcClient.Call("ControlPlane.GetServiceRollout", serviceId, rollout)
+}
+
+func (s *ControlClient) CancelServiceRollout(serviceId string, unused *int) (err error) {
+	return s.rpcClient.Call("ControlPlane.CancelServiceRollout", serviceId, unused)
+}
+
+func (s *ControlClient) GetScalingEvents(serviceId string, events *[]autoscale.Event) (err error) {
+	return s.rpcClient.Call("ControlPlane.GetScalingEvents", serviceId, events)
+}
+
+func (s *ControlClient) GetServiceHealth(serviceId string, statuses *[]*zkhealth.Status) (err error) {
+	return s.rpcClient.Call("ControlPlane.GetServiceHealth", serviceId, statuses)
+}
+
+func (s *ControlClient) GetHealthStatuses(request dao.EntityRequest, statuses *[]*zkhealth.Status) (err error) {
+	return s.rpcClient.Call("ControlPlane.GetHealthStatuses", request, statuses)
 }
 
 func (s *ControlClient) StopService(serviceId string, unused *int) (err error) {
@@ -151,6 +190,14 @@ func (s *ControlClient) DeployTemplate(request dao.ServiceTemplateDeploymentRequ
 	return s.rpcClient.Call("ControlPlane.DeployTemplate", request, tenantId)
 }
 
+func (s *ControlClient) DiffTemplate(request dao.ServiceTemplateApplyRequest, diff *service.TemplateDiff) error {
+	return s.rpcClient.Call("ControlPlane.DiffTemplate", request, diff)
+}
+
+func (s *ControlClient) ApplyTemplate(request dao.ServiceTemplateApplyRequest, diff *service.TemplateDiff) error {
+	return s.rpcClient.Call("ControlPlane.ApplyTemplate", request, diff)
+}
+
 func (s *ControlClient) GetServiceTemplates(unused int, serviceTemplates *map[string]*servicetemplate.ServiceTemplate) error {
 	return s.rpcClient.Call("ControlPlane.GetServiceTemplates", unused, serviceTemplates)
 }
@@ -199,6 +246,22 @@ func (s *ControlClient) DeleteSnapshots(serviceId string, unused *int) error {
 	return s.rpcClient.Call("ControlPlane.DeleteSnapshots", serviceId, unused)
 }
 
+func (s *ControlClient) ExportSnapshot(request dao.SnapshotExportRequest, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.ExportSnapshot", request, unused)
+}
+
+func (s *ControlClient) ImportSnapshot(filePath string, snapshotID *string) error {
+	return s.rpcClient.Call("ControlPlane.ImportSnapshot", filePath, snapshotID)
+}
+
+func (s *ControlClient) SetSnapshotPolicy(request dao.SnapshotPolicyRequest, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.SetSnapshotPolicy", request, unused)
+}
+
+func (s *ControlClient) GetSnapshotSchedule(serviceId string, schedule *dao.SnapshotSchedule) error {
+	return s.rpcClient.Call("ControlPlane.GetSnapshotSchedule", serviceId, schedule)
+}
+
 func (s *ControlClient) GetVolume(serviceId string, volume *volume.Volume) error {
 	// WARNING: it would not make sense to call this from the CLI
 	// since volume is a pointer
@@ -221,6 +284,14 @@ func (s *ControlClient) Backup(backupDirectory string, backupFilePath *string) e
 	return s.rpcClient.Call("ControlPlane.Backup", backupDirectory, backupFilePath)
 }
 
+func (s *ControlClient) IncrementalBackup(backupDirectory string, backupFilePath *string) error {
+	return s.rpcClient.Call("ControlPlane.IncrementalBackup", backupDirectory, backupFilePath)
+}
+
+func (s *ControlClient) ListBackups(backupDirectory string, backups *[]dao.BackupInfo) error {
+	return s.rpcClient.Call("ControlPlane.ListBackups", backupDirectory, backups)
+}
+
 func (s *ControlClient) AsyncBackup(backupDirectory string, backupFilePath *string) error {
 	return s.rpcClient.Call("ControlPlane.AsyncBackup", backupDirectory, backupFilePath)
 }
@@ -248,3 +319,31 @@ func (s *ControlClient) AsyncRestore(backupFilePath string, unused *int) error {
 func (s *ControlClient) RestoreStatus(notUsed string, restoreStatus *string) error {
 	return s.rpcClient.Call("ControlPlane.RestoreStatus", notUsed, restoreStatus)
 }
+
+func (s *ControlClient) EncryptedBackup(request dao.BackupRequest, backupFilePath *string) error {
+	return s.rpcClient.Call("ControlPlane.EncryptedBackup", request, backupFilePath)
+}
+
+func (s *ControlClient) EncryptedRestore(request dao.RestoreRequest, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.EncryptedRestore", request, unused)
+}
+
+func (s *ControlClient) VerifyBackup(request dao.RestoreRequest, verification *dao.BackupVerification) error {
+	return s.rpcClient.Call("ControlPlane.VerifyBackup", request, verification)
+}
+
+func (s *ControlClient) BackupProgress(since int, progress *dao.BackupProgress) error {
+	return s.rpcClient.Call("ControlPlane.BackupProgress", since, progress)
+}
+
+func (s *ControlClient) RestoreProgress(since int, progress *dao.BackupProgress) error {
+	return s.rpcClient.Call("ControlPlane.RestoreProgress", since, progress)
+}
+
+func (s *ControlClient) CancelBackup(notUsed string, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.CancelBackup", notUsed, unused)
+}
+
+func (s *ControlClient) CancelRestore(notUsed string, unused *int) error {
+	return s.rpcClient.Call("ControlPlane.CancelRestore", notUsed, unused)
+}
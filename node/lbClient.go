@@ -9,6 +9,7 @@ import (
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/proxy"
 
 	"net/rpc"
 )
@@ -96,6 +97,12 @@ func (a *LBClient) GetHostID(hostID *string) error {
 	return a.rpcClient.Call("ControlPlaneAgent.GetHostID", "na", hostID)
 }
 
+// GetProxyCertificates returns the certificates the agent dials muxes with
+func (a *LBClient) GetProxyCertificates(bundle *proxy.CertificateBundle) error {
+	glog.V(4).Infof("ControlPlaneAgent.GetProxyCertificates()")
+	return a.rpcClient.Call("ControlPlaneAgent.GetProxyCertificates", "na", bundle)
+}
+
 // GetZkInfo returns the agent's zookeeper connection string
 func (a *LBClient) GetZkInfo(zkInfo *ZkInfo) error {
 	glog.V(4).Infof("ControlPlaneAgent.GetZkInfo()")
@@ -0,0 +1,135 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/domain/notification"
+)
+
+var (
+	// how long a notification of a condition in the same state is not sent
+	// again
+	dedupWindow = 30 * time.Minute
+
+	// a condition that changes state flapCount times within the flapWindow is
+	// flapping, and is not notified again until it has not changed for the
+	// flapWindow
+	flapWindow = 10 * time.Minute
+	flapCount  = 4
+)
+
+// condition tracks the changes to the state of a condition, and what was last
+// sent about it
+type condition struct {
+	state     string
+	changes   []time.Time
+	flapping  bool
+	sentState string
+	sent      time.Time
+}
+
+// Dispatcher sends notifications to sinks, dropping the notifications of a
+// condition in a state that was already sent, and of conditions that are
+// flapping
+type Dispatcher struct {
+	sync.Mutex
+	conditions map[string]*condition
+	pruned     time.Time
+}
+
+// NewDispatcher creates a dispatcher
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{conditions: make(map[string]*condition)}
+}
+
+// filter returns the notification to send for the notification of a
+// condition, if any. Once a condition starts flapping, a single flapping
+// notification is sent in its place.
+func (d *Dispatcher) filter(n Notification, now time.Time) (Notification, bool) {
+	d.Lock()
+	defer d.Unlock()
+	d.prune(now)
+
+	c, ok := d.conditions[n.Key]
+	if !ok {
+		c = &condition{state: n.State}
+		d.conditions[n.Key] = c
+	} else if c.state != n.State {
+		c.state = n.State
+		c.changes = append(c.changes, now)
+	}
+	for len(c.changes) > 0 && now.Sub(c.changes[0]) > flapWindow {
+		c.changes = c.changes[1:]
+	}
+
+	if len(c.changes) >= flapCount {
+		if c.flapping {
+			return n, false
+		}
+		c.flapping = true
+		n.State = StateFlapping
+		n.Summary = "Changing state too often to notify each change: " + n.Summary
+	} else {
+		c.flapping = false
+		if n.State == c.sentState && now.Sub(c.sent) < dedupWindow {
+			return n, false
+		}
+	}
+	c.sentState, c.sent = n.State, now
+	return n, true
+}
+
+// prune forgets the conditions that were cleared and have not changed for
+// long enough to be notified again anyway
+func (d *Dispatcher) prune(now time.Time) {
+	if now.Sub(d.pruned) < flapWindow {
+		return
+	}
+	d.pruned = now
+	for key, c := range d.conditions {
+		if c.state == StateCleared && now.Sub(c.sent) > dedupWindow && (len(c.changes) == 0 || now.Sub(c.changes[len(c.changes)-1]) > flapWindow) {
+			delete(d.conditions, key)
+		}
+	}
+}
+
+// Dispatch sends the notification to the sinks in the background, unless it
+// is a duplicate or its condition is flapping. Returns false if the
+// notification was dropped.
+func (d *Dispatcher) Dispatch(n Notification, sinks []*notification.Sink) bool {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+	n, ok := d.filter(n, n.Timestamp)
+	if !ok {
+		glog.V(2).Infof("Not notifying %s %s again", n.Key, n.State)
+		return false
+	}
+	if len(sinks) == 0 {
+		return true
+	}
+
+	go func() {
+		for _, sink := range sinks {
+			if err := Send(sink, n); err != nil {
+				glog.Warningf("Could not notify sink %s (%s) of %s %s: %s", sink.Name, sink.ID, n.Key, n.State, err)
+			}
+		}
+	}()
+	return true
+}
+
+// Send sends the notification to a sink right away
+func Send(sink *notification.Sink, n Notification) error {
+	sender, err := NewSender(sink)
+	if err != nil {
+		return err
+	}
+	return sender.Send(n)
+}
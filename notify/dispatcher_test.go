@@ -0,0 +1,64 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDispatcher_dedup(t *testing.T) {
+	d := NewDispatcher()
+	now := time.Now()
+	n := Notification{Key: "health/svc/0/ready", State: StateRaised}
+
+	if _, ok := d.filter(n, now); !ok {
+		t.Errorf("expected the first notification to be sent")
+	}
+	if _, ok := d.filter(n, now.Add(time.Minute)); ok {
+		t.Errorf("expected a duplicate to be dropped")
+	}
+	if _, ok := d.filter(n, now.Add(dedupWindow+time.Minute)); !ok {
+		t.Errorf("expected a reminder once the dedup window passed")
+	}
+
+	n.State = StateCleared
+	if _, ok := d.filter(n, now.Add(dedupWindow+2*time.Minute)); !ok {
+		t.Errorf("expected a change of state to be sent")
+	}
+	other := Notification{Key: "health/svc/1/ready", State: StateRaised}
+	if _, ok := d.filter(other, now.Add(dedupWindow+2*time.Minute)); !ok {
+		t.Errorf("expected other conditions to be sent")
+	}
+}
+
+func TestDispatcher_flapping(t *testing.T) {
+	d := NewDispatcher()
+	now := time.Now()
+	states := []string{StateRaised, StateCleared}
+
+	var sent []string
+	for i := 0; i < 10; i++ {
+		n := Notification{Key: "threshold/host/h/cpu", State: states[i%2]}
+		if n, ok := d.filter(n, now.Add(time.Duration(i)*time.Second)); ok {
+			sent = append(sent, n.State)
+		}
+	}
+	expected := []string{StateRaised, StateCleared, StateRaised, StateCleared, StateFlapping}
+	if len(sent) != len(expected) {
+		t.Fatalf("expected %v to be sent; got %v", expected, sent)
+	}
+	for i := range expected {
+		if sent[i] != expected[i] {
+			t.Fatalf("expected %v to be sent; got %v", expected, sent)
+		}
+	}
+
+	// once the condition settles, its state is sent again
+	n := Notification{Key: "threshold/host/h/cpu", State: StateRaised}
+	if n, ok := d.filter(n, now.Add(flapWindow+time.Minute)); !ok || n.State != StateRaised {
+		t.Errorf("expected the settled state to be sent; got %v %+v", ok, n)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package notify sends notifications about failing health checks and
+// breached thresholds to the webhooks, mail servers and syslogs configured
+// for a pool or tenant.
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// Kinds of notifications
+const (
+	KindHealth    = "health"
+	KindThreshold = "threshold"
+	KindTest      = "test"
+)
+
+// States of the condition a notification is about
+const (
+	StateRaised   = "raised"
+	StateCleared  = "cleared"
+	StateFlapping = "flapping" // raised and cleared too often to notify each time
+)
+
+// Notification describes a change to a condition of a host, pool or service
+type Notification struct {
+	Key        string // identifies the condition
+	Kind       string // health, threshold or test
+	State      string // raised, cleared or flapping
+	Severity   int    // as in the event tags of thresholds, 5 being critical
+	EntityType string
+	EntityID   string
+	PoolID     string
+	TenantID   string
+	Summary    string
+	Timestamp  time.Time
+	Tags       map[string]interface{} `json:",omitempty"`
+}
+
+// Subject describes the notification on one line
+func (n *Notification) Subject() string {
+	return fmt.Sprintf("[serviced] %s %s %s: %s", n.EntityType, n.EntityID, n.State, n.Summary)
+}
+
+// Sender sends notifications to a sink
+type Sender interface {
+	Send(n Notification) error
+}
+
+// NewSender creates the sender of a sink
+func NewSender(sink *notification.Sink) (Sender, error) {
+	switch {
+	case sink.Type == notification.TypeWebhook && sink.Webhook != nil:
+		return newWebhookSender(sink.Webhook)
+	case sink.Type == notification.TypeSMTP && sink.SMTP != nil:
+		return &smtpSender{sink.SMTP}, nil
+	case sink.Type == notification.TypeSyslog && sink.Syslog != nil:
+		return &syslogSender{sink.Syslog}, nil
+	}
+	return nil, fmt.Errorf("sink %s has no %s configuration", sink.ID, sink.Type)
+}
@@ -0,0 +1,188 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/domain/notification"
+)
+
+var testNotification = Notification{
+	Key:        "threshold/host/host-1/swap.empty",
+	Kind:       KindThreshold,
+	State:      StateRaised,
+	Severity:   4,
+	EntityType: "host",
+	EntityID:   "host-1",
+	PoolID:     "default",
+	Summary:    "swap.free is -1, below 0",
+	Timestamp:  time.Date(2014, 8, 1, 12, 0, 0, 0, time.UTC),
+	Tags:       map[string]interface{}{"EventClass": "/Perf/Memory"},
+}
+
+func TestWebhook(t *testing.T) {
+	var method, contentType, token string
+	var body []byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType, token = r.Method, r.Header.Get("Content-Type"), r.Header.Get("X-Token")
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	// the notification is sent as JSON by default
+	sink := &notification.Sink{ID: "1", Type: notification.TypeWebhook, Webhook: &notification.Webhook{URL: server.URL, Headers: map[string]string{"X-Token": "abc"}}}
+	if err := Send(sink, testNotification); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var received Notification
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatalf("could not decode payload %s: %s", body, err)
+	}
+	if method != "POST" || contentType != "application/json" || token != "abc" || received.Key != testNotification.Key {
+		t.Errorf("unexpected request %s %s %s %+v", method, contentType, token, received)
+	}
+
+	// or as the payload rendered by its template
+	sink.Webhook = &notification.Webhook{URL: server.URL, Method: "PUT", Template: `{"text": "{{.EntityID}} {{.State}}: {{.Summary}}"}`}
+	if err := Send(sink, testNotification); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := `{"text": "host-1 raised: swap.free is -1, below 0"}`; method != "PUT" || string(body) != expected {
+		t.Errorf("expected %s %s; got %s %s", "PUT", expected, method, body)
+	}
+
+	status = http.StatusInternalServerError
+	if err := Send(sink, testNotification); err == nil {
+		t.Errorf("expected an error when the webhook fails")
+	}
+}
+
+// serveSMTP accepts a single message, and returns what it received
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Errorf("could not accept connection: %s", err)
+		close(received)
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var transcript []string
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			break
+		}
+		transcript = append(transcript, line)
+		switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "DATA":
+			text.PrintfLine("354 go ahead")
+			data, _ := text.ReadDotLines()
+			transcript = append(transcript, data...)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			received <- strings.Join(transcript, "\n")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+	received <- strings.Join(transcript, "\n")
+}
+
+func TestSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go serveSMTP(t, listener, received)
+
+	sink := &notification.Sink{ID: "1", Type: notification.TypeSMTP, SMTP: &notification.SMTP{Server: listener.Addr().String(), From: "cc@example.com", To: []string{"ops@example.com", "oncall@example.com"}}}
+	if err := Send(sink, testNotification); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	transcript := <-received
+	for _, expected := range []string{
+		"MAIL FROM:<cc@example.com>",
+		"RCPT TO:<oncall@example.com>",
+		"To: ops@example.com, oncall@example.com",
+		"Subject: [serviced] host host-1 raised: swap.free is -1, below 0",
+		"Severity: 4",
+		"EventClass: /Perf/Memory",
+	} {
+		if !strings.Contains(transcript, expected) {
+			t.Errorf("expected %q in the mail transcript:\n%s", expected, transcript)
+		}
+	}
+
+	// the subject cannot add headers
+	n := testNotification
+	n.Summary = "swap\r\nBcc: evil@example.com\rX-Injected: 1"
+	message := string((&smtpSender{sink.SMTP}).message(n))
+	if !strings.Contains(message, "Subject: [serviced] host host-1 raised: swap  Bcc: evil@example.com X-Injected: 1\r\n") {
+		t.Errorf("expected the subject on one line:\n%s", message)
+	}
+
+	// neither can the addresses of sinks saved before they were validated
+	config := *sink.SMTP
+	config.From = "cc@example.com\r\nBcc: evil@example.com"
+	config.To = []string{"ops@example.com\nX-Injected: 1"}
+	message = string((&smtpSender{&config}).message(testNotification))
+	if !strings.Contains(message, "From: cc@example.com  Bcc: evil@example.com\r\nTo: ops@example.com X-Injected: 1\r\n") {
+		t.Errorf("expected the addresses on one line each:\n%s", message)
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	defer conn.Close()
+
+	sink := &notification.Sink{ID: "1", Type: notification.TypeSyslog, Syslog: &notification.Syslog{Network: "udp", Address: conn.LocalAddr().String()}}
+	if err := Send(sink, testNotification); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	buffer := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Fatalf("could not read syslog message: %s", err)
+	}
+	// daemon facility (3) at error priority (3)
+	message := string(buffer[:n])
+	if !strings.HasPrefix(message, "<27>") || !strings.Contains(message, "serviced") || !strings.Contains(message, testNotification.Subject()) {
+		t.Errorf("unexpected syslog message %q", message)
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	if _, err := NewSender(&notification.Sink{ID: "1", Type: notification.TypeSMTP}); err == nil {
+		t.Errorf("expected an error for a sink without its configuration")
+	}
+	if _, err := NewSender(&notification.Sink{ID: "1", Type: notification.TypeWebhook, Webhook: &notification.Webhook{URL: "http://localhost", Template: "{{"}}); err == nil {
+		t.Errorf("expected an error for a webhook with an invalid template")
+	}
+}
+
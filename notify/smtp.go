@@ -0,0 +1,73 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// smtpSender emails each notification
+type smtpSender struct {
+	config *notification.SMTP
+}
+
+// headerReplacer keeps a value on its header line, so that it cannot add
+// headers of its own
+var headerReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+// message formats the notification as an email
+func (s *smtpSender) message(n Notification) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "From: %s\r\n", headerReplacer.Replace(s.config.From))
+	fmt.Fprintf(&buffer, "To: %s\r\n", headerReplacer.Replace(strings.Join(s.config.To, ", ")))
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", headerReplacer.Replace(n.Subject()))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	fmt.Fprintf(&buffer, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&buffer, "%s\r\n\r\n", n.Summary)
+	fields := [][2]string{
+		{"State", n.State},
+		{"Kind", n.Kind},
+		{"Severity", fmt.Sprint(n.Severity)},
+		{"Entity", n.EntityType + " " + n.EntityID},
+		{"Pool", n.PoolID},
+		{"Tenant", n.TenantID},
+		{"Time", n.Timestamp.Format(time.RFC3339)},
+	}
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Fprintf(&buffer, "%s: %s\r\n", field[0], field[1])
+		}
+	}
+	var tags []string
+	for name := range n.Tags {
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	for _, name := range tags {
+		fmt.Fprintf(&buffer, "%s: %v\r\n", name, n.Tags[name])
+	}
+	return buffer.Bytes()
+}
+
+func (s *smtpSender) Send(n Notification) error {
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		host, _, err := net.SplitHostPort(s.config.Server)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, host)
+	}
+	return smtp.SendMail(s.config.Server, auth, s.config.From, s.config.To, s.message(n))
+}
@@ -0,0 +1,47 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"log/syslog"
+
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// syslogSender writes each notification to syslog, at a priority that
+// follows its severity. Notifications without a severity are warnings.
+type syslogSender struct {
+	config *notification.Syslog
+}
+
+func (s *syslogSender) Send(n Notification) error {
+	tag := s.config.Tag
+	if tag == "" {
+		tag = "serviced"
+	}
+	writer, err := syslog.Dial(s.config.Network, s.config.Address, syslog.LOG_DAEMON|syslog.LOG_NOTICE, tag)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	message := n.Subject()
+	if n.State == StateCleared {
+		return writer.Notice(message)
+	}
+	switch {
+	case n.Severity >= 5:
+		return writer.Crit(message)
+	case n.Severity == 4:
+		return writer.Err(message)
+	case n.Severity == 3:
+		return writer.Warning(message)
+	case n.Severity == 2:
+		return writer.Info(message)
+	case n.Severity == 1:
+		return writer.Debug(message)
+	}
+	return writer.Warning(message)
+}
@@ -0,0 +1,81 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// how long a webhook has to respond
+var webhookTimeout = 10 * time.Second
+
+// webhookSender sends each notification in a request to a URL, as JSON or
+// as the payload rendered by the template of the webhook
+type webhookSender struct {
+	config   *notification.Webhook
+	template *template.Template
+	client   *http.Client
+}
+
+func newWebhookSender(config *notification.Webhook) (*webhookSender, error) {
+	sender := &webhookSender{config: config, client: &http.Client{Timeout: webhookTimeout}}
+	if config.Template != "" {
+		t, err := template.New("payload").Parse(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		sender.template = t
+	}
+	return sender, nil
+}
+
+func (s *webhookSender) payload(n Notification) ([]byte, error) {
+	if s.template == nil {
+		return json.Marshal(n)
+	}
+	var buffer bytes.Buffer
+	if err := s.template.Execute(&buffer, n); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *webhookSender) Send(n Notification) error {
+	body, err := s.payload(n)
+	if err != nil {
+		return fmt.Errorf("could not render webhook payload: %s", err)
+	}
+	method := s.config.Method
+	if method == "" {
+		method = "POST"
+	}
+	request, err := http.NewRequest(method, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if s.template == nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range s.config.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded %s", s.config.URL, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"github.com/zenoss/glog"
+
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CAActivationDelay is how long a new certificate authority is trusted before
+// certificates are issued from it. Hosts pick up the new authority in the
+// meantime, so certificates from either authority are accepted everywhere
+// while the rotation is rolled out.
+var CAActivationDelay = 5 * time.Minute
+
+// JoinTokenTTL is how long a join token can be used to issue the first
+// certificate of a host
+var JoinTokenTTL = 24 * time.Hour
+
+// ErrInvalidJoinToken is returned when a join token is unknown, was already
+// used, or has expired
+var ErrInvalidJoinToken = errors.New("invalid join token")
+
+const authorityFile = "authority.json"
+
+// authorityState is what a certificate authority keeps on disk
+type authorityState struct {
+	CertPEM         []byte
+	KeyPEM          []byte
+	PreviousCertPEM []byte // The authority before the last rotation, which is still trusted
+	NextCertPEM     []byte // A new authority that is trusted but not yet issuing
+	NextKeyPEM      []byte
+	NextActivation  time.Time // When the new authority starts issuing
+	Generation      int
+	JoinTokens      map[string]joinToken // The unused join tokens by host
+}
+
+// joinToken lets a host prove that it may be issued a certificate before it
+// has one. Only its hash is kept.
+type joinToken struct {
+	Hash    []byte
+	Expires time.Time
+}
+
+// CertificateAuthority issues the mux certificates of the hosts of a
+// deployment. Its state is kept in a directory of the master.
+type CertificateAuthority struct {
+	lock  sync.Mutex
+	dir   string
+	state authorityState
+	own   *CertificateBundle // the master's own certificate
+}
+
+// LoadCertificateAuthority loads the certificate authority kept in dir,
+// creating it the first time the master starts
+func LoadCertificateAuthority(dir string) (*CertificateAuthority, error) {
+	ca := &CertificateAuthority{dir: dir}
+	data, err := ioutil.ReadFile(filepath.Join(dir, authorityFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &ca.state); err != nil {
+			return nil, fmt.Errorf("could not read certificate authority: %s", err)
+		}
+		return ca, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	glog.Infof("Creating certificate authority in %s", dir)
+	if ca.state.CertPEM, ca.state.KeyPEM, err = NewCA("serviced"); err != nil {
+		return nil, err
+	}
+	ca.state.Generation = 1
+	if err := ca.save(); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// Generation returns the number of times certificates have been rotated.
+// Hosts reissue their certificates when it changes.
+func (ca *CertificateAuthority) Generation() (int, error) {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	if err := ca.activate(); err != nil {
+		return 0, err
+	}
+	return ca.state.Generation, nil
+}
+
+// Issue creates a certificate for a host with the given addresses, along with
+// the certificate that the proxies of its containers dial muxes with
+func (ca *CertificateAuthority) Issue(name string, ips []string) (*CertificateBundle, error) {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	if err := ca.activate(); err != nil {
+		return nil, err
+	}
+	bundle, err := ca.issue(name, ips)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.ProxyCertPEM, bundle.ProxyKeyPEM, err = IssueProxyCertificate(ca.state.CertPEM, ca.state.KeyPEM, name); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// Own returns the certificate the master uses to connect to muxes
+func (ca *CertificateAuthority) Own() (*CertificateBundle, error) {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	if err := ca.activate(); err != nil {
+		return nil, err
+	}
+	if ca.own == nil || ca.own.Generation != ca.state.Generation || ca.own.NeedsRenewal() {
+		own, err := ca.issue("master", nil)
+		if err != nil {
+			return nil, err
+		}
+		ca.own = own
+	}
+	return ca.own, nil
+}
+
+// CreateJoinToken creates a token that the host can be issued a certificate
+// with once, replacing any earlier token of the host
+func (ca *CertificateAuthority) CreateJoinToken(hostID string) (string, error) {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(buf)
+	hash := sha256.Sum256([]byte(secret))
+
+	state := ca.state
+	now := time.Now()
+	state.JoinTokens = make(map[string]joinToken)
+	for id, token := range ca.state.JoinTokens {
+		if now.Before(token.Expires) {
+			state.JoinTokens[id] = token
+		}
+	}
+	state.JoinTokens[hostID] = joinToken{Hash: hash[:], Expires: now.Add(JoinTokenTTL)}
+	if err := ca.update(state); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// UseJoinToken checks the join token of the host, which cannot be used again
+func (ca *CertificateAuthority) UseJoinToken(hostID, secret string) error {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+
+	token, ok := ca.state.JoinTokens[hostID]
+	hash := sha256.Sum256([]byte(secret))
+	if !ok || subtle.ConstantTimeCompare(token.Hash, hash[:]) != 1 || time.Now().After(token.Expires) {
+		return ErrInvalidJoinToken
+	}
+
+	state := ca.state
+	state.JoinTokens = make(map[string]joinToken)
+	for id, token := range ca.state.JoinTokens {
+		if id != hostID {
+			state.JoinTokens[id] = token
+		}
+	}
+	return ca.update(state)
+}
+
+// Rotate makes hosts reissue their certificates. If newCA is set, a new
+// authority is created; it is trusted right away and starts issuing after the
+// CAActivationDelay. The previous authority stays trusted until the next time
+// the authority is replaced.
+func (ca *CertificateAuthority) Rotate(newCA bool) (int, error) {
+	ca.lock.Lock()
+	defer ca.lock.Unlock()
+	if err := ca.activate(); err != nil {
+		return 0, err
+	}
+
+	state := ca.state
+	if newCA {
+		if len(state.NextCertPEM) > 0 {
+			return 0, fmt.Errorf("a new certificate authority is already being rolled out until %s", state.NextActivation.Format(time.RFC3339))
+		}
+		var err error
+		if state.NextCertPEM, state.NextKeyPEM, err = NewCA("serviced"); err != nil {
+			return 0, err
+		}
+		state.NextActivation = time.Now().Add(CAActivationDelay)
+	}
+	state.Generation++
+	return state.Generation, ca.update(state)
+}
+
+// activate starts issuing from a new authority once it has been trusted long
+// enough
+func (ca *CertificateAuthority) activate() error {
+	state := ca.state
+	if len(state.NextCertPEM) == 0 || time.Now().Before(state.NextActivation) {
+		return nil
+	}
+	glog.Infof("Issuing certificates from the new certificate authority")
+	state.PreviousCertPEM = state.CertPEM
+	state.CertPEM, state.KeyPEM = state.NextCertPEM, state.NextKeyPEM
+	state.NextCertPEM, state.NextKeyPEM = nil, nil
+	state.NextActivation = time.Time{}
+	state.Generation++
+	return ca.update(state)
+}
+
+func (ca *CertificateAuthority) issue(name string, ips []string) (*CertificateBundle, error) {
+	certPEM, keyPEM, err := IssueCertificate(ca.state.CertPEM, ca.state.KeyPEM, name, ips)
+	if err != nil {
+		return nil, err
+	}
+	trusted := [][]byte{ca.state.CertPEM, ca.state.PreviousCertPEM, ca.state.NextCertPEM}
+	return &CertificateBundle{
+		CertPEM:    certPEM,
+		KeyPEM:     keyPEM,
+		CAPEM:      bytes.Join(trusted, nil),
+		Generation: ca.state.Generation,
+	}, nil
+}
+
+// update saves the state before using it
+func (ca *CertificateAuthority) update(state authorityState) error {
+	previous := ca.state
+	ca.state = state
+	if err := ca.save(); err != nil {
+		ca.state = previous
+		return err
+	}
+	return nil
+}
+
+func (ca *CertificateAuthority) save() error {
+	if err := os.MkdirAll(ca.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ca.state)
+	if err != nil {
+		return err
+	}
+	// write a new file and move it into place so a crash never leaves a
+	// partial authority behind
+	path := filepath.Join(ca.dir, authorityFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
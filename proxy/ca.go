@@ -0,0 +1,238 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"github.com/control-center/serviced/rpc/rpcutils"
+
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"time"
+)
+
+// MuxServerName is the name every mux certificate is issued for. Peers are
+// dialed by IP, so clients verify this name instead of the address.
+const MuxServerName = "serviced-mux"
+
+const (
+	caTTL   = 10 * 365 * 24 * time.Hour
+	certTTL = 365 * 24 * time.Hour
+	renewal = 30 * 24 * time.Hour // how long before they expire certificates are reissued
+	keyBits = 2048
+)
+
+// ErrNoCertificates is returned when TLS is used without certificates
+var ErrNoCertificates = errors.New("no TLS certificates have been issued")
+
+// CertificateBundle is a certificate and key issued by the certificate
+// authority of a deployment, along with the authorities that peers are
+// trusted from.
+type CertificateBundle struct {
+	CertPEM      []byte
+	KeyPEM       []byte
+	CAPEM        []byte // The certificates of one or more trusted authorities
+	Generation   int    // Incremented each time certificates are rotated
+	ProxyCertPEM []byte // A certificate that only dials muxes, for the proxies of containers
+	ProxyKeyPEM  []byte
+}
+
+// ProxyBundle returns the certificate that the proxies of containers dial
+// muxes with, or nil if none was issued with the bundle
+func (b *CertificateBundle) ProxyBundle() *CertificateBundle {
+	if b == nil || len(b.ProxyCertPEM) == 0 {
+		return nil
+	}
+	return &CertificateBundle{
+		CertPEM:    b.ProxyCertPEM,
+		KeyPEM:     b.ProxyKeyPEM,
+		CAPEM:      b.CAPEM,
+		Generation: b.Generation,
+	}
+}
+
+// ServerConfig returns the TLS configuration of a mux that requires its
+// clients to present a trusted certificate
+func (b *CertificateBundle) ServerConfig() (*tls.Config, error) {
+	cert, pool, err := b.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}, nil
+}
+
+// ClientConfig returns the TLS configuration of a client that presents its
+// certificate to a mux and verifies the certificate of the mux
+func (b *CertificateBundle) ClientConfig() (*tls.Config, error) {
+	cert, pool, err := b.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   MuxServerName,
+	}, nil
+}
+
+// Expires returns when the certificate of the bundle expires
+func (b *CertificateBundle) Expires() (time.Time, error) {
+	cert, err := parseCertificate(b.CertPEM)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// NeedsRenewal is true if the certificate of the bundle is about to expire or
+// cannot be read
+func (b *CertificateBundle) NeedsRenewal() bool {
+	expires, err := b.Expires()
+	return err != nil || time.Now().Add(renewal).After(expires)
+}
+
+func (b *CertificateBundle) parse() (tls.Certificate, *x509.CertPool, error) {
+	if b == nil || len(b.CertPEM) == 0 {
+		return tls.Certificate{}, nil, ErrNoCertificates
+	}
+	cert, err := tls.X509KeyPair(b.CertPEM, b.KeyPEM)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b.CAPEM) {
+		return tls.Certificate{}, nil, errors.New("no trusted certificate authorities")
+	}
+	return cert, pool, nil
+}
+
+// NewCA creates the self-signed certificate and key of a certificate
+// authority
+func NewCA(name string) (certPEM, keyPEM []byte, err error) {
+	template, err := newTemplate(name, caTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	return createCertificate(template, nil, nil)
+}
+
+// IssueCertificate creates a certificate and key for a host, signed by a
+// certificate authority. The certificate may be used by both sides of a mux
+// connection.
+func IssueCertificate(caCertPEM, caKeyPEM []byte, name string, ips []string) (certPEM, keyPEM []byte, err error) {
+	caCert, err := parseCertificate(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := parseKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(name, certTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	template.DNSNames = []string{MuxServerName}
+	for _, ip := range ips {
+		if addr := net.ParseIP(ip); addr != nil {
+			template.IPAddresses = append(template.IPAddresses, addr)
+		}
+	}
+	if template.NotAfter.After(caCert.NotAfter) {
+		template.NotAfter = caCert.NotAfter
+	}
+	return createCertificate(template, caCert, caKey)
+}
+
+// IssueProxyCertificate creates a certificate and key that the proxies of the
+// containers on a host dial muxes with, signed by a certificate authority.
+// The certificate can only be used by clients and does not identify the host.
+func IssueProxyCertificate(caCertPEM, caKeyPEM []byte, name string) (certPEM, keyPEM []byte, err error) {
+	caCert, err := parseCertificate(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := parseKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(name+"/"+rpcutils.ProxyUnit, certTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.Subject.OrganizationalUnit = []string{rpcutils.ProxyUnit}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	if template.NotAfter.After(caCert.NotAfter) {
+		template.NotAfter = caCert.NotAfter
+	}
+	return createCertificate(template, caCert, caKey)
+}
+
+func newTemplate(name string, ttl time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"serviced"}, CommonName: name},
+		NotBefore:    now.Add(-time.Hour), // allow for clock skew between hosts
+		NotAfter:     now.Add(ttl),
+	}, nil
+}
+
+// createCertificate signs a new key with the parent, or with itself if there
+// is no parent
+func createCertificate(template, parent *x509.Certificate, parentKey *rsa.PrivateKey) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, errors.New("invalid private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
@@ -0,0 +1,283 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"github.com/control-center/serviced/rpc/rpcutils"
+
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// handshake connects a client with the client bundle to a TLSListener with
+// the server bundle
+func handshake(t *testing.T, server, client *CertificateBundle) error {
+	serverConfig, err := server.ServerConfig()
+	if err != nil {
+		t.Fatalf("could not configure server: %s", err)
+	}
+	clientConfig, err := client.ClientConfig()
+	if err != nil {
+		t.Fatalf("could not configure client: %s", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	tlsListener := NewTLSListener(listener, serverConfig)
+	defer tlsListener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := tlsListener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err == nil {
+		conn.Close()
+	}
+	if sErr := <-serverErr; err == nil {
+		err = sErr
+	}
+	return err
+}
+
+func TestCertificateAuthority(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviced-ca-")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := LoadCertificateAuthority(dir)
+	if err != nil {
+		t.Fatalf("could not create certificate authority: %s", err)
+	}
+	hostA, err := ca.Issue("host-a", []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	hostB, err := ca.Issue("host-b", []string{"127.0.0.2"})
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	if err := handshake(t, hostA, hostB); err != nil {
+		t.Errorf("expected hosts of the same authority to trust each other: %s", err)
+	}
+
+	// the authority is loaded from disk when the master restarts
+	reloaded, err := LoadCertificateAuthority(dir)
+	if err != nil {
+		t.Fatalf("could not load certificate authority: %s", err)
+	}
+	hostC, err := reloaded.Issue("host-c", nil)
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	if err := handshake(t, hostA, hostC); err != nil {
+		t.Errorf("expected the reloaded authority to be trusted: %s", err)
+	}
+
+	// certificates from another deployment are not trusted
+	otherDir, err := ioutil.TempDir("", "serviced-ca-")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(otherDir)
+	other, err := LoadCertificateAuthority(otherDir)
+	if err != nil {
+		t.Fatalf("could not create certificate authority: %s", err)
+	}
+	stranger, err := other.Issue("stranger", nil)
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	if err := handshake(t, hostA, stranger); err == nil {
+		t.Errorf("expected a client of another deployment to be rejected")
+	}
+	if err := handshake(t, stranger, hostA); err == nil {
+		t.Errorf("expected a server of another deployment to be rejected")
+	}
+}
+
+func TestCertificateAuthority_Proxy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviced-ca-")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := LoadCertificateAuthority(dir)
+	if err != nil {
+		t.Fatalf("could not create certificate authority: %s", err)
+	}
+	hostA, err := ca.Issue("host-a", []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	hostB, err := ca.Issue("host-b", []string{"127.0.0.2"})
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+
+	// the proxies of containers dial muxes without the key of their host
+	proxyA := hostA.ProxyBundle()
+	if proxyA == nil {
+		t.Fatalf("expected a proxy certificate to be issued with the host's")
+	} else if bytes.Equal(proxyA.KeyPEM, hostA.KeyPEM) {
+		t.Errorf("expected the proxy certificate to have its own key")
+	}
+	if err := handshake(t, hostB, proxyA); err != nil {
+		t.Errorf("expected a mux to trust the proxies of its peers: %s", err)
+	}
+	if err := handshake(t, proxyA, hostB); err == nil {
+		t.Errorf("expected a proxy certificate not to serve a mux")
+	}
+
+	// only the proxy certificate is marked as one
+	if cert, err := parseCertificate(proxyA.CertPEM); err != nil {
+		t.Fatalf("could not parse certificate: %s", err)
+	} else if !rpcutils.IsProxyCertificate(cert) || cert.Subject.CommonName == "host-a" {
+		t.Errorf("expected a proxy certificate that does not name the host, got %+v", cert.Subject)
+	}
+	if cert, err := parseCertificate(hostA.CertPEM); err != nil {
+		t.Fatalf("could not parse certificate: %s", err)
+	} else if rpcutils.IsProxyCertificate(cert) {
+		t.Errorf("expected the host certificate not to be a proxy certificate")
+	}
+
+	// the master's own certificate has no proxy certificate
+	if own, err := ca.Own(); err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	} else if own.ProxyBundle() != nil {
+		t.Errorf("expected no proxy certificate for the master")
+	}
+}
+
+func TestCertificateAuthority_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviced-ca-")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := LoadCertificateAuthority(dir)
+	if err != nil {
+		t.Fatalf("could not create certificate authority: %s", err)
+	}
+	old, err := ca.Issue("host-a", nil)
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+
+	// reissuing certificates only changes the generation
+	if generation, err := ca.Rotate(false); err != nil {
+		t.Fatalf("could not rotate certificates: %s", err)
+	} else if generation != old.Generation+1 {
+		t.Errorf("expected generation %d, got %d", old.Generation+1, generation)
+	}
+
+	// a new authority is trusted before it issues, so hosts that reissue
+	// during the rollout still trust hosts that have not
+	if _, err := ca.Rotate(true); err != nil {
+		t.Fatalf("could not rotate certificate authority: %s", err)
+	}
+	if _, err := ca.Rotate(true); err == nil {
+		t.Errorf("expected failure replacing the authority during a rollout")
+	}
+	refreshed, err := ca.Issue("host-b", nil)
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	if err := handshake(t, old, refreshed); err != nil {
+		t.Errorf("expected hosts to trust each other during the rollout: %s", err)
+	}
+
+	// once activated, hosts with certificates of either authority trust each
+	// other as long as they have reissued since the rollout started
+	ca.state.NextActivation = time.Now()
+	rotated, err := ca.Issue("host-c", nil)
+	if err != nil {
+		t.Fatalf("could not issue certificate: %s", err)
+	}
+	if rotated.Generation != old.Generation+3 {
+		t.Errorf("expected generation %d, got %d", old.Generation+3, rotated.Generation)
+	}
+	if err := handshake(t, refreshed, rotated); err != nil {
+		t.Errorf("expected the old authority to still be trusted: %s", err)
+	}
+	if err := handshake(t, old, rotated); err == nil {
+		t.Errorf("expected a host that never reissued to reject the new authority")
+	}
+}
+
+func TestCertificateAuthority_JoinToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviced-ca-")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := LoadCertificateAuthority(dir)
+	if err != nil {
+		t.Fatalf("could not create certificate authority: %s", err)
+	}
+	if err := ca.UseJoinToken("host-a", ""); err != ErrInvalidJoinToken {
+		t.Errorf("expected %s without a token, got %v", ErrInvalidJoinToken, err)
+	}
+
+	first, err := ca.CreateJoinToken("host-a")
+	if err != nil {
+		t.Fatalf("could not create join token: %s", err)
+	}
+	second, err := ca.CreateJoinToken("host-a")
+	if err != nil {
+		t.Fatalf("could not create join token: %s", err)
+	}
+	other, err := ca.CreateJoinToken("host-b")
+	if err != nil {
+		t.Fatalf("could not create join token: %s", err)
+	}
+
+	// only the latest token of the host is valid, and only for that host
+	if err := ca.UseJoinToken("host-a", first); err != ErrInvalidJoinToken {
+		t.Errorf("expected a replaced token to be rejected, got %v", err)
+	}
+	if err := ca.UseJoinToken("host-a", other); err != ErrInvalidJoinToken {
+		t.Errorf("expected the token of another host to be rejected, got %v", err)
+	}
+
+	// tokens survive a restart of the master and can be used once
+	reloaded, err := LoadCertificateAuthority(dir)
+	if err != nil {
+		t.Fatalf("could not load certificate authority: %s", err)
+	}
+	if err := reloaded.UseJoinToken("host-a", second); err != nil {
+		t.Errorf("could not use join token: %s", err)
+	}
+	if err := reloaded.UseJoinToken("host-a", second); err != ErrInvalidJoinToken {
+		t.Errorf("expected a used token to be rejected, got %v", err)
+	}
+
+	// expired tokens are rejected
+	token := reloaded.state.JoinTokens["host-b"]
+	token.Expires = time.Now().Add(-time.Second)
+	reloaded.state.JoinTokens["host-b"] = token
+	if err := reloaded.UseJoinToken("host-b", other); err != ErrInvalidJoinToken {
+		t.Errorf("expected an expired token to be rejected, got %v", err)
+	}
+}
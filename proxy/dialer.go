@@ -0,0 +1,46 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"github.com/zenoss/glog"
+
+	"crypto/tls"
+	"net"
+	"sync"
+)
+
+// MuxDialer dials muxes over TLS with certificates that can be replaced while
+// it is in use
+type MuxDialer struct {
+	lock   sync.Mutex
+	config *tls.Config
+}
+
+// SetCertificates replaces the certificates of new connections
+func (d *MuxDialer) SetCertificates(bundle *CertificateBundle) error {
+	config, err := bundle.ClientConfig()
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.config = config
+	return nil
+}
+
+// Dial connects to the mux at addr. Muxes cannot be dialed until certificates
+// have been set.
+func (d *MuxDialer) Dial(addr string) (net.Conn, error) {
+	d.lock.Lock()
+	config := d.config
+	d.lock.Unlock()
+
+	if config == nil {
+		glog.Warningf("Not dialing mux %s: %s", addr, ErrNoCertificates)
+		return nil, ErrNoCertificates
+	}
+	return tls.Dial("tcp4", addr, config)
+}
@@ -101,9 +101,9 @@ func (mux *TCPMux) loop() {
 
 // muxConnection takes an inbound connection reads a line from it and
 // then attempts to set up a connection to the service specified by the
-// line. The service is specified in the form "IP:PORT\n". If the connection
-// to the service is sucessful, all traffic continues to be proxied between
-// two connections.
+// line. The service is specified in the form "IP:PORT\n", or "IP:PORT/udp\n"
+// for a datagram session. If the connection to the service is sucessful, all
+// traffic continues to be proxied between two connections.
 func (mux *TCPMux) muxConnection(conn net.Conn) {
 	// make sure that we don't block indefinitely
 	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
@@ -118,6 +118,11 @@ func (mux *TCPMux) muxConnection(conn net.Conn) {
 	conn.SetReadDeadline(time.Time{})
 	line = strings.TrimSpace(line)
 
+	if strings.HasSuffix(line, UDPSuffix) {
+		mux.muxDatagrams(conn, reader, line)
+		return
+	}
+
 	svc, err := net.Dial("tcp4", line)
 	if err != nil {
 		glog.Errorf("got %s => %s, could not dial to '%s' : %s", conn.LocalAddr(), conn.RemoteAddr(), line, err)
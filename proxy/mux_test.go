@@ -128,3 +128,50 @@ func TestTCPMux(t *testing.T) {
 	conn.Close()
 
 }
+
+func TestTCPMux_UDP(t *testing.T) {
+	// a udp echo server
+	target, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	defer target.Close()
+	go func() {
+		buffer := make([]byte, MaxDatagramSize)
+		for {
+			n, addr, err := target.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			target.WriteTo(buffer[:n], addr)
+		}
+	}()
+
+	muxEndpoint, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("could not create tcpmux endpoint: %s", err)
+	}
+	mux, err := NewTCPMux(muxEndpoint)
+	if err != nil {
+		t.Fatalf("did not expect failure creating TCPMux: %s", err)
+	}
+
+	conn := mux.testConnect(t)
+	defer conn.Close()
+	header := fmt.Sprintf("%s%s\n", target.LocalAddr(), UDPSuffix)
+	conn.Write([]byte(header))
+	for _, testMsg := range []string{"hello", "world"} {
+		if err := WriteDatagram(conn, []byte(testMsg)); err != nil {
+			t.Fatalf("could not write datagram: %s", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		buffer := make([]byte, MaxDatagramSize)
+		n, err := ReadDatagram(conn, buffer)
+		if err != nil {
+			t.Fatalf("could not read datagram: %s", err)
+		}
+		if returnedValue := string(buffer[:n]); returnedValue != testMsg {
+			t.Fatalf("got back %+v expected %+v", returnedValue, testMsg)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"github.com/zenoss/glog"
+
+	"crypto/tls"
+	"net"
+	"sync"
+)
+
+// TLSListener accepts TLS connections with a configuration that can be
+// replaced while it is listening, so certificates are rotated without
+// dropping established connections. Connections that arrive before it has
+// a configuration are closed.
+type TLSListener struct {
+	net.Listener
+	lock   sync.Mutex
+	config *tls.Config
+}
+
+// NewTLSListener wraps a listener; config may be nil until certificates have
+// been issued
+func NewTLSListener(listener net.Listener, config *tls.Config) *TLSListener {
+	return &TLSListener{Listener: listener, config: config}
+}
+
+// SetConfig replaces the configuration of new connections
+func (l *TLSListener) SetConfig(config *tls.Config) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.config = config
+}
+
+func (l *TLSListener) getConfig() *tls.Config {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.config
+}
+
+// Accept waits for the next connection that can be secured
+func (l *TLSListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if config := l.getConfig(); config != nil {
+			return tls.Server(conn, config), nil
+		}
+		glog.Warningf("Closing connection from %s: %s", conn.RemoteAddr(), ErrNoCertificates)
+		conn.Close()
+	}
+}
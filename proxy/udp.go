@@ -0,0 +1,136 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"github.com/zenoss/glog"
+
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// UDPSessionTimeout is how long a datagram session is kept open without
+// traffic in either direction
+var UDPSessionTimeout = time.Minute
+
+// MaxDatagramSize is the size of the largest datagram that is relayed
+const MaxDatagramSize = 65507
+
+// UDPSuffix marks the mux line of a datagram session, such as
+// "10.0.0.1:514/udp". The datagrams of the session are framed by
+// WriteDatagram.
+const UDPSuffix = "/udp"
+
+// ErrDatagramTooLarge is returned when a datagram cannot be framed
+var ErrDatagramTooLarge = errors.New("datagram too large")
+
+// WriteDatagram writes a datagram to a stream, prefixed by its length
+func WriteDatagram(w io.Writer, datagram []byte) error {
+	if len(datagram) > MaxDatagramSize {
+		return ErrDatagramTooLarge
+	}
+	frame := make([]byte, 2+len(datagram))
+	frame[0], frame[1] = byte(len(datagram)>>8), byte(len(datagram))
+	copy(frame[2:], datagram)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadDatagram reads a datagram written by WriteDatagram into buf, which
+// should hold MaxDatagramSize bytes
+func ReadDatagram(r io.Reader, buf []byte) (int, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	size := int(header[0])<<8 | int(header[1])
+	if size > len(buf) {
+		return 0, ErrDatagramTooLarge
+	}
+	return io.ReadFull(r, buf[:size])
+}
+
+// SessionActivity tracks when a datagram session last had traffic
+type SessionActivity struct {
+	last int64
+}
+
+// Touch records traffic
+func (a *SessionActivity) Touch() {
+	atomic.StoreInt64(&a.last, time.Now().UnixNano())
+}
+
+// Idle is true if there has been no traffic for the UDPSessionTimeout
+func (a *SessionActivity) Idle() bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&a.last))) >= UDPSessionTimeout
+}
+
+// muxDatagrams relays the datagrams framed on a mux connection to a UDP
+// service, and the replies of the service back, until either side closes or
+// the session is idle
+func (mux *TCPMux) muxDatagrams(conn net.Conn, reader io.Reader, address string) {
+	address = strings.TrimSuffix(address, UDPSuffix)
+	svc, err := net.Dial("udp4", address)
+	if err != nil {
+		glog.Errorf("got %s => %s, could not dial to '%s' : %s", conn.LocalAddr(), conn.RemoteAddr(), address, err)
+		conn.Close()
+		return
+	}
+
+	var activity SessionActivity
+	activity.Touch()
+	done := make(chan struct{}, 2)
+	go func() {
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, err := ReadDatagram(reader, buf)
+			if err != nil {
+				break
+			}
+			activity.Touch()
+			if _, err := svc.Write(buf[:n]); err != nil {
+				// the service may not be listening yet
+				glog.V(2).Infof("could not send datagram to %s: %s", address, err)
+			}
+		}
+		done <- struct{}{}
+	}()
+	go func() {
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, err := svc.Read(buf)
+			if err != nil {
+				// the service may not be listening yet
+				if strings.Contains(err.Error(), "connection refused") {
+					continue
+				}
+				break
+			}
+			activity.Touch()
+			if err := WriteDatagram(conn, buf[:n]); err != nil {
+				break
+			}
+		}
+		done <- struct{}{}
+	}()
+
+	defer conn.Close()
+	defer svc.Close()
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(UDPSessionTimeout / 4):
+			if activity.Idle() {
+				glog.V(2).Infof("closing idle datagram session %s => %s", conn.RemoteAddr(), address)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/audit"
+)
+
+// GetAuditEntries returns the audit log entries that match the filter, newest
+// first
+func (c *Client) GetAuditEntries(filter audit.Filter) ([]*audit.Entry, error) {
+	response := make([]*audit.Entry, 0)
+	if err := c.call("GetAuditEntries", filter, &response); err != nil {
+		return []*audit.Entry{}, err
+	}
+	return response, nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/audit"
+	"github.com/control-center/serviced/domain/role"
+)
+
+// GetAuditEntries returns the audit log entries that match the filter
+func (s *Server) GetAuditEntries(filter audit.Filter, reply *[]*audit.Entry) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceAudit}); err != nil {
+		return err
+	}
+	entries, err := s.f.GetAuditEntries(s.context(), filter)
+	if err != nil {
+		return err
+	}
+	*reply = entries
+	return nil
+}
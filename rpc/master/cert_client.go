@@ -0,0 +1,51 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/proxy"
+)
+
+// IssueCertificate issues the certificate that identifies a host to the
+// master and secures its mux. Hosts without a certificate prove who they are
+// with their join token.
+func (c *Client) IssueCertificate(hostID, joinToken string) (*proxy.CertificateBundle, error) {
+	response := proxy.CertificateBundle{}
+	request := CertificateRequest{HostID: hostID, JoinToken: joinToken}
+	if err := c.call("IssueCertificate", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateJoinToken creates the token that a host is issued its first
+// certificate with
+func (c *Client) CreateJoinToken(hostID string) (string, error) {
+	var response string
+	if err := c.call("CreateJoinToken", hostID, &response); err != nil {
+		return "", err
+	}
+	return response, nil
+}
+
+// GetCertificateGeneration returns the number of times certificates have been
+// rotated
+func (c *Client) GetCertificateGeneration() (int, error) {
+	var response int
+	if err := c.call("GetCertificateGeneration", empty, &response); err != nil {
+		return 0, err
+	}
+	return response, nil
+}
+
+// RotateCertificates makes every host reissue its mux certificate, replacing
+// the certificate authority if newCA is set, and returns the new generation
+func (c *Client) RotateCertificates(newCA bool) (int, error) {
+	var response int
+	if err := c.call("RotateCertificates", newCA, &response); err != nil {
+		return 0, err
+	}
+	return response, nil
+}
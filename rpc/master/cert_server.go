@@ -0,0 +1,128 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/proxy"
+
+	"errors"
+	"fmt"
+)
+
+// ErrNoCertificateAuthority is returned when the master has no certificate
+// authority
+var ErrNoCertificateAuthority = errors.New("no certificate authority")
+
+// CertificateRequest is a request for the certificate of a host
+type CertificateRequest struct {
+	HostID    string
+	JoinToken string // Proves that the caller is the host, if it has no certificate yet
+}
+
+// authorizeHost checks that the caller is the host, either by the certificate
+// the host was issued before or by its join token, or that the caller may
+// modify the host
+func (s *Server) authorizeHost(request CertificateRequest) error {
+	if s.caller != nil && s.caller.Host != "" {
+		if s.caller.Host != request.HostID {
+			return fmt.Errorf("host %s may not be issued the certificate of host %s", s.caller.Host, request.HostID)
+		}
+		return nil
+	}
+	if request.JoinToken != "" {
+		return s.ca.UseJoinToken(request.HostID, request.JoinToken)
+	}
+	return s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceHost, HostID: request.HostID})
+}
+
+// IssueCertificate issues the certificate that identifies a host to the
+// master and secures its mux. The caller is authorized before the host is
+// looked up, so callers that may not be issued the certificate cannot tell
+// which hosts exist.
+func (s *Server) IssueCertificate(request CertificateRequest, reply *proxy.CertificateBundle) error {
+	if s.ca == nil {
+		return ErrNoCertificateAuthority
+	}
+	if err := s.authorizeHost(request); err != nil {
+		return err
+	}
+	h, err := s.f.GetHost(s.context(), request.HostID)
+	if err != nil {
+		return err
+	} else if h == nil {
+		return fmt.Errorf("host %s not found", request.HostID)
+	}
+
+	ips := []string{h.IPAddr}
+	for _, ip := range h.IPs {
+		ips = append(ips, ip.IPAddress)
+	}
+	bundle, err := s.ca.Issue(request.HostID, ips)
+	if err != nil {
+		return err
+	}
+	*reply = *bundle
+	return nil
+}
+
+// CreateJoinToken creates the token that a host is issued its first
+// certificate with. The token can be used once.
+func (s *Server) CreateJoinToken(hostID string, reply *string) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceHost, HostID: hostID}); err != nil {
+		return err
+	}
+	if s.ca == nil {
+		return ErrNoCertificateAuthority
+	}
+	h, err := s.f.GetHost(s.context(), hostID)
+	if err != nil {
+		return err
+	} else if h == nil {
+		return fmt.Errorf("host %s not found", hostID)
+	}
+	token, err := s.ca.CreateJoinToken(hostID)
+	if err != nil {
+		return err
+	}
+	s.f.Audit(s.context(), "join-token", "host", hostID, nil, nil)
+	*reply = token
+	return nil
+}
+
+// GetCertificateGeneration returns the number of times certificates have been
+// rotated
+func (s *Server) GetCertificateGeneration(empty struct{}, reply *int) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceHost}); err != nil {
+		return err
+	}
+	if s.ca == nil {
+		return ErrNoCertificateAuthority
+	}
+	generation, err := s.ca.Generation()
+	if err != nil {
+		return err
+	}
+	*reply = generation
+	return nil
+}
+
+// RotateCertificates makes every host reissue its mux certificate, replacing
+// the certificate authority if newCA is set
+func (s *Server) RotateCertificates(newCA bool, reply *int) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceHost}); err != nil {
+		return err
+	}
+	if s.ca == nil {
+		return ErrNoCertificateAuthority
+	}
+	generation, err := s.ca.Rotate(newCA)
+	if err != nil {
+		return err
+	}
+	s.f.Audit(s.context(), "rotate", "certificate", fmt.Sprintf("%d", generation), nil, map[string]interface{}{"NewCA": newCA})
+	*reply = generation
+	return nil
+}
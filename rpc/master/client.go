@@ -6,6 +6,8 @@ package master
 
 import (
 	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/rpc/rpcutils"
 
 	"net/rpc"
 )
@@ -25,7 +27,18 @@ func NewClient(addr string) (*Client, error) {
 	s := new(Client)
 	s.addr = addr
 	glog.V(4).Infof("Connecting to %s", addr)
-	rpcClient, err := rpc.DialHTTP("tcp", s.addr)
+	rpcClient, err := rpcutils.DialHTTP(s.addr, datastore.Caller{})
+	s.rpcClient = rpcClient
+	return s, err
+}
+
+// NewClientForCaller creates a new rpc client whose requests are made on
+// behalf of the caller
+func NewClientForCaller(addr string, caller datastore.Caller) (*Client, error) {
+	s := new(Client)
+	s.addr = addr
+	glog.V(4).Infof("Connecting to %s as %+v", addr, caller)
+	rpcClient, err := rpcutils.DialHTTP(s.addr, caller)
 	s.rpcClient = rpcClient
 	return s, err
 }
@@ -0,0 +1,19 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/event"
+)
+
+// GetEvents returns the threshold events that match the filter, most recently
+// seen first
+func (c *Client) GetEvents(filter event.Filter) ([]*event.Event, error) {
+	response := make([]*event.Event, 0)
+	if err := c.call("GetEvents", filter, &response); err != nil {
+		return []*event.Event{}, err
+	}
+	return response, nil
+}
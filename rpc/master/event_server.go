@@ -0,0 +1,23 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/role"
+)
+
+// GetEvents returns the threshold events that match the filter
+func (s *Server) GetEvents(filter event.Filter, reply *[]*event.Event) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceEvent, PoolID: filter.PoolID}); err != nil {
+		return err
+	}
+	events, err := s.f.GetEvents(s.context(), filter)
+	if err != nil {
+		return err
+	}
+	*reply = events
+	return nil
+}
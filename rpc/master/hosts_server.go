@@ -6,12 +6,16 @@ package master
 
 import (
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/role"
 
 	"errors"
 )
 
 // GetHost gets the host
 func (s *Server) GetHost(hostID string, reply *host.Host) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceHost, HostID: hostID}); err != nil {
+		return err
+	}
 	response, err := s.f.GetHost(s.context(), hostID)
 	if err != nil {
 		return err
@@ -25,6 +29,9 @@ func (s *Server) GetHost(hostID string, reply *host.Host) error {
 
 // GetHosts returns all Hosts
 func (s *Server) GetHosts(empty struct{}, hostReply *[]*host.Host) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceHost}); err != nil {
+		return err
+	}
 	hosts, err := s.f.GetHosts(s.context())
 	if err != nil {
 		return err
@@ -35,21 +42,33 @@ func (s *Server) GetHosts(empty struct{}, hostReply *[]*host.Host) error {
 
 // AddHost adds the host
 func (s *Server) AddHost(host host.Host, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceHost, PoolID: host.PoolID}); err != nil {
+		return err
+	}
 	return s.f.AddHost(s.context(), &host)
 }
 
 // UpdateHost updates the host
 func (s *Server) UpdateHost(host host.Host, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceHost, HostID: host.ID}); err != nil {
+		return err
+	}
 	return s.f.UpdateHost(s.context(), &host)
 }
 
 // RemoveHost removes the host
 func (s *Server) RemoveHost(hostID string, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceHost, HostID: hostID}); err != nil {
+		return err
+	}
 	return s.f.RemoveHost(s.context(), hostID)
 }
 
 // FindHostsInPool  Returns all Hosts in a pool
 func (s *Server) FindHostsInPool(poolID string, hostReply *[]*host.Host) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceHost, PoolID: poolID}); err != nil {
+		return err
+	}
 	hosts, err := s.f.FindHostsInPool(s.context(), poolID)
 	if err != nil {
 		return err
@@ -0,0 +1,38 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/notification"
+)
+
+// AddNotificationSink configures a notification sink and returns its id
+func (c *Client) AddNotificationSink(sink notification.Sink) (string, error) {
+	var id string
+	if err := c.call("AddNotificationSink", sink, &id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetNotificationSinks returns the notification sinks, without their secrets
+func (c *Client) GetNotificationSinks() ([]*notification.Sink, error) {
+	response := make([]*notification.Sink, 0)
+	if err := c.call("GetNotificationSinks", empty, &response); err != nil {
+		return []*notification.Sink{}, err
+	}
+	return response, nil
+}
+
+// RemoveNotificationSink removes a notification sink
+func (c *Client) RemoveNotificationSink(id string) error {
+	return c.call("RemoveNotificationSink", id, nil)
+}
+
+// TestNotificationSink sends a test notification to a sink, and returns why
+// it could not be sent
+func (c *Client) TestNotificationSink(id string) error {
+	return c.call("TestNotificationSink", id, nil)
+}
@@ -0,0 +1,74 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"errors"
+
+	"github.com/control-center/serviced/domain/notification"
+	"github.com/control-center/serviced/domain/role"
+)
+
+// authorizeSink requires the permission on the pool or the tenant a
+// notification sink is configured for
+func (s *Server) authorizeSink(sink *notification.Sink, action string) error {
+	if sink.TenantID != "" {
+		return s.authorize(role.Request{Action: action, Resource: role.ResourceService, TenantID: sink.TenantID})
+	}
+	return s.authorize(role.Request{Action: action, Resource: role.ResourcePool, PoolID: sink.PoolID})
+}
+
+// getSink gets a notification sink and requires the permission on it
+func (s *Server) getSink(id, action string) error {
+	sink, err := s.f.GetNotificationSink(s.context(), id)
+	if err != nil {
+		return err
+	} else if sink == nil {
+		return errors.New("notification sink not found")
+	}
+	return s.authorizeSink(sink, action)
+}
+
+// AddNotificationSink configures a notification sink and replies with its id
+func (s *Server) AddNotificationSink(sink notification.Sink, reply *string) error {
+	if err := s.authorizeSink(&sink, role.Modify); err != nil {
+		return err
+	}
+	id, err := s.f.AddNotificationSink(s.context(), sink)
+	if err != nil {
+		return err
+	}
+	*reply = id
+	return nil
+}
+
+// GetNotificationSinks returns the notification sinks, without their secrets
+func (s *Server) GetNotificationSinks(empty struct{}, reply *[]*notification.Sink) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceEvent}); err != nil {
+		return err
+	}
+	sinks, err := s.f.GetNotificationSinks(s.context())
+	if err != nil {
+		return err
+	}
+	*reply = sinks
+	return nil
+}
+
+// RemoveNotificationSink removes a notification sink
+func (s *Server) RemoveNotificationSink(id string, _ *struct{}) error {
+	if err := s.getSink(id, role.Modify); err != nil {
+		return err
+	}
+	return s.f.RemoveNotificationSink(s.context(), id)
+}
+
+// TestNotificationSink sends a test notification to a sink
+func (s *Server) TestNotificationSink(id string, _ *struct{}) error {
+	if err := s.getSink(id, role.Operate); err != nil {
+		return err
+	}
+	return s.f.TestNotificationSink(s.context(), id)
+}
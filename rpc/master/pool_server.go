@@ -8,11 +8,15 @@ import (
 	"errors"
 
 	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/facade"
 )
 
 // GetResourcePools returns all ResourcePools
 func (s *Server) GetResourcePools(empty struct{}, poolsReply *[]*pool.ResourcePool) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourcePool}); err != nil {
+		return err
+	}
 	pools, err := s.f.GetResourcePools(s.context())
 	if err != nil {
 		return err
@@ -24,16 +28,25 @@ func (s *Server) GetResourcePools(empty struct{}, poolsReply *[]*pool.ResourcePo
 
 // AddResourcePool adds the pool
 func (s *Server) AddResourcePool(pool pool.ResourcePool, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourcePool, PoolID: pool.ID}); err != nil {
+		return err
+	}
 	return s.f.AddResourcePool(s.context(), &pool)
 }
 
 // UpdateResourcePool updates the pool
 func (s *Server) UpdateResourcePool(pool pool.ResourcePool, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourcePool, PoolID: pool.ID}); err != nil {
+		return err
+	}
 	return s.f.UpdateResourcePool(s.context(), &pool)
 }
 
 // GetResourcePool gets the pool
 func (s *Server) GetResourcePool(poolID string, reply *pool.ResourcePool) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourcePool, PoolID: poolID}); err != nil {
+		return err
+	}
 	response, err := s.f.GetResourcePool(s.context(), poolID)
 	if err != nil {
 		return err
@@ -47,11 +60,17 @@ func (s *Server) GetResourcePool(poolID string, reply *pool.ResourcePool) error
 
 // RemoveResourcePool removes the pool
 func (s *Server) RemoveResourcePool(poolID string, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourcePool, PoolID: poolID}); err != nil {
+		return err
+	}
 	return s.f.RemoveResourcePool(s.context(), poolID)
 }
 
 // GetPoolIPs gets all ips available to a pool
 func (s *Server) GetPoolIPs(poolID string, reply *facade.PoolIPs) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourcePool, PoolID: poolID}); err != nil {
+		return err
+	}
 	response, err := s.f.GetPoolIPs(s.context(), poolID)
 	if err != nil {
 		return err
@@ -65,10 +84,16 @@ func (s *Server) GetPoolIPs(poolID string, reply *facade.PoolIPs) error {
 
 // AddVirtualIP adds a specific virtual IP to a pool
 func (s *Server) AddVirtualIP(requestVirtualIP pool.VirtualIP, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourcePool, PoolID: requestVirtualIP.PoolID}); err != nil {
+		return err
+	}
 	return s.f.AddVirtualIP(s.context(), requestVirtualIP)
 }
 
 // RemoveVirtualIP removes a specific virtual IP from a pool
 func (s *Server) RemoveVirtualIP(requestVirtualIP pool.VirtualIP, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourcePool, PoolID: requestVirtualIP.PoolID}); err != nil {
+		return err
+	}
 	return s.f.RemoveVirtualIP(s.context(), requestVirtualIP)
 }
@@ -0,0 +1,48 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/role"
+)
+
+// GetUserRoles returns the roles of every user that has been granted any
+func (c *Client) GetUserRoles() ([]*role.UserRoles, error) {
+	response := make([]*role.UserRoles, 0)
+	if err := c.call("GetUserRoles", empty, &response); err != nil {
+		return []*role.UserRoles{}, err
+	}
+	return response, nil
+}
+
+// GetUserRole gets the roles of a user
+func (c *Client) GetUserRole(user string) (*role.UserRoles, error) {
+	var response role.UserRoles
+	if err := c.call("GetUserRole", user, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GrantRole grants a role to a user
+func (c *Client) GrantRole(user string, grant role.Grant) error {
+	return c.call("GrantRole", GrantRequest{user, grant}, nil)
+}
+
+// RevokeRole revokes a role from a user
+func (c *Client) RevokeRole(user string, grant role.Grant) error {
+	return c.call("RevokeRole", GrantRequest{user, grant}, nil)
+}
+
+// RemoveUserRoles removes all of the roles of a user
+func (c *Client) RemoveUserRoles(user string) error {
+	return c.call("RemoveUserRoles", user, nil)
+}
+
+// Authorize checks that the caller of the client is allowed to make the
+// request
+func (c *Client) Authorize(request role.Request) error {
+	return c.call("Authorize", request, nil)
+}
@@ -0,0 +1,76 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"errors"
+
+	"github.com/control-center/serviced/domain/role"
+)
+
+// GrantRequest is a request to grant or revoke a role of a user
+type GrantRequest struct {
+	User  string
+	Grant role.Grant
+}
+
+// GetUserRoles returns the roles of every user that has been granted any
+func (s *Server) GetUserRoles(empty struct{}, reply *[]*role.UserRoles) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
+	roles, err := s.f.GetUserRoles(s.context())
+	if err != nil {
+		return err
+	}
+	*reply = roles
+	return nil
+}
+
+// GetUserRole gets the roles of a user
+func (s *Server) GetUserRole(user string, reply *role.UserRoles) error {
+	if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
+	response, err := s.f.GetUserRole(s.context(), user)
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return errors.New("user has not been granted any roles")
+	}
+	*reply = *response
+	return nil
+}
+
+// GrantRole grants a role to a user
+func (s *Server) GrantRole(request GrantRequest, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
+	return s.f.GrantRole(s.context(), request.User, request.Grant)
+}
+
+// RevokeRole revokes a role from a user
+func (s *Server) RevokeRole(request GrantRequest, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
+	return s.f.RevokeRole(s.context(), request.User, request.Grant)
+}
+
+// RemoveUserRoles removes all of the roles of a user
+func (s *Server) RemoveUserRoles(user string, _ *struct{}) error {
+	if err := s.authorize(role.Request{Action: role.Modify, Resource: role.ResourceUser}); err != nil {
+		return err
+	}
+	return s.f.RemoveUserRoles(s.context(), user)
+}
+
+// Authorize checks that the caller of the connection is allowed to make the
+// request
+func (s *Server) Authorize(request role.Request, _ *struct{}) error {
+	return s.authorize(request)
+}
@@ -6,21 +6,39 @@ package master
 
 import (
 	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/facade"
+	"github.com/control-center/serviced/proxy"
 )
 
 // NewServer creates a new serviced master rpc server
-func NewServer(f *facade.Facade) *Server {
-	return &Server{f}
+func NewServer(f *facade.Facade, ca *proxy.CertificateAuthority) *Server {
+	return &Server{f, ca, nil}
+}
+
+// NewServerForCaller creates a new serviced master rpc server whose changes
+// are attributed to the caller
+func NewServerForCaller(f *facade.Facade, ca *proxy.CertificateAuthority, caller datastore.Caller) *Server {
+	return &Server{f, ca, &caller}
 }
 
 // Server is the RPC type for the master(s)
 type Server struct {
-	f *facade.Facade
+	f      *facade.Facade
+	ca     *proxy.CertificateAuthority
+	caller *datastore.Caller
 }
 
+// context attributes the requests to the caller of the connection, if known
 func (s *Server) context() datastore.Context {
-	//here in case we ever need to create a per request context
+	if s.caller != nil {
+		return datastore.WithCaller(datastore.Get(), *s.caller)
+	}
 	return datastore.Get()
+}
 
+// authorize checks that the caller of the connection is allowed to make the
+// request
+func (s *Server) authorize(request role.Request) error {
+	return s.f.Authorize(s.context(), request)
 }
@@ -0,0 +1,44 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"github.com/control-center/serviced/domain/token"
+)
+
+// CreateToken creates an API token and returns its bearer value, which is the
+// only copy of its secret
+func (c *Client) CreateToken(request TokenRequest) (string, error) {
+	var bearer string
+	if err := c.call("CreateToken", request, &bearer); err != nil {
+		return "", err
+	}
+	return bearer, nil
+}
+
+// GetTokens returns the API tokens of a user, or of every user if the user is
+// empty
+func (c *Client) GetTokens(user string) ([]*token.Token, error) {
+	response := make([]*token.Token, 0)
+	if err := c.call("GetTokens", user, &response); err != nil {
+		return []*token.Token{}, err
+	}
+	return response, nil
+}
+
+// RevokeToken revokes an API token
+func (c *Client) RevokeToken(id string) error {
+	return c.call("RevokeToken", id, nil)
+}
+
+// AuthenticateToken checks the bearer value of an API token and returns the
+// token if it is valid
+func (c *Client) AuthenticateToken(bearer string) (*token.Token, error) {
+	var response token.Token
+	if err := c.call("AuthenticateToken", bearer, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package master
+
+import (
+	"errors"
+	"time"
+
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/token"
+)
+
+// TokenRequest is a request to create an API token
+type TokenRequest struct {
+	User        string // Defaults to the caller
+	Description string
+	Grants      []role.Grant
+	TTL         time.Duration
+}
+
+// authorizeUser lets users look after their own tokens, and requires the
+// permission for anyone else's
+func (s *Server) authorizeUser(user, action string) error {
+	if s.caller != nil && s.caller.User != "" && (user == "" || user == s.caller.User) {
+		return nil
+	}
+	return s.authorize(role.Request{Action: action, Resource: role.ResourceUser})
+}
+
+// CreateToken creates an API token and replies with its bearer value
+func (s *Server) CreateToken(request TokenRequest, reply *string) error {
+	if err := s.authorizeUser(request.User, role.Modify); err != nil {
+		return err
+	}
+	bearer, err := s.f.CreateToken(s.context(), request.User, request.Description, request.Grants, request.TTL)
+	if err != nil {
+		return err
+	}
+	*reply = bearer
+	return nil
+}
+
+// GetTokens returns the API tokens of a user, or of every user if the user is
+// empty
+func (s *Server) GetTokens(user string, reply *[]*token.Token) error {
+	if s.caller == nil || s.caller.User == "" || user != s.caller.User {
+		if err := s.authorize(role.Request{Action: role.Read, Resource: role.ResourceUser}); err != nil {
+			return err
+		}
+	}
+	tokens, err := s.f.GetTokens(s.context(), user)
+	if err != nil {
+		return err
+	}
+	*reply = tokens
+	return nil
+}
+
+// RevokeToken revokes an API token
+func (s *Server) RevokeToken(id string, _ *struct{}) error {
+	t, err := s.f.GetToken(s.context(), id)
+	if err != nil {
+		return err
+	} else if t == nil {
+		return errors.New("API token not found")
+	}
+	if err := s.authorizeUser(t.User, role.Modify); err != nil {
+		return err
+	}
+	return s.f.RevokeToken(s.context(), id)
+}
+
+// AuthenticateToken checks the bearer value of an API token and replies with
+// the token if it is valid
+func (s *Server) AuthenticateToken(bearer string, reply *token.Token) error {
+	t, err := s.f.AuthenticateToken(s.context(), bearer)
+	if err != nil {
+		return err
+	}
+	*reply = *t
+	return nil
+}
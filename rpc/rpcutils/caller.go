@@ -0,0 +1,242 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package rpcutils authenticates the callers of RPC requests made over HTTP,
+// so the changes a connection makes can be attributed to, and limited to what
+// is allowed for, the user or host that made them.
+package rpcutils
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/datastore"
+
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sync"
+)
+
+const (
+	userHeader   = "X-Serviced-User"
+	sourceHeader = "X-Serviced-Source"
+	tokenHeader  = "X-Serviced-Token"
+	keyHeader    = "X-Serviced-Key"
+	bearerHeader = "X-Serviced-Bearer"
+
+	// the status net/rpc responds with when a connection is established
+	connected = "200 Connected to Go RPC"
+)
+
+// ProxyUnit is the organizational unit of the certificates that the proxies
+// of containers dial muxes with. They never identify a host.
+const ProxyUnit = "proxy"
+
+// IsProxyCertificate is true if the certificate was issued for the proxies of
+// containers rather than for a host
+func IsProxyCertificate(cert *x509.Certificate) bool {
+	for _, unit := range cert.Subject.OrganizationalUnit {
+		if unit == ProxyUnit {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidCredentials is returned when a connection carries credentials
+// that cannot be verified
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Credentials are what the connections of a process authenticate with
+type Credentials struct {
+	Key    string      // The key of the master, which only processes on the master can read
+	Bearer string      // An API token, as ID.SECRET
+	TLS    *tls.Config // The certificate of the host, issued by the master
+}
+
+var (
+	credentials     Credentials
+	credentialsLock sync.Mutex
+)
+
+// SetDefaultCredentials sets the credentials that DialHTTP authenticates with
+func SetDefaultCredentials(creds Credentials) {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	credentials = creds
+}
+
+// DefaultCredentials returns the credentials that DialHTTP authenticates with
+func DefaultCredentials() Credentials {
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	return credentials
+}
+
+// DialHTTP connects to an HTTP RPC server at the address with the default
+// credentials. Callers that authenticate with the key of the master may act
+// on behalf of the caller they name, and are administrators unless they act
+// for a user of the web server; everyone else is identified by their
+// credentials alone.
+func DialHTTP(address string, caller datastore.Caller) (*rpc.Client, error) {
+	creds := DefaultCredentials()
+
+	var conn net.Conn
+	var err error
+	if creds.TLS != nil {
+		conn, err = tls.Dial("tcp", address, creds.TLS)
+	} else {
+		conn, err = net.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	header.Set(userHeader, caller.User)
+	header.Set(sourceHeader, caller.Source)
+	if caller.Token != "" {
+		header.Set(tokenHeader, caller.Token)
+	}
+	if creds.Key != "" {
+		header.Set(keyHeader, creds.Key)
+	}
+	if creds.Bearer != "" {
+		header.Set(bearerHeader, creds.Bearer)
+	}
+	var request bytes.Buffer
+	request.WriteString("CONNECT " + rpc.DefaultRPCPath + " HTTP/1.0\r\n")
+	header.Write(&request)
+	request.WriteString("\r\n")
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Require successful HTTP response before switching to RPC protocol
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: "CONNECT"})
+	if err == nil && resp.Status == connected {
+		return rpc.NewClient(conn), nil
+	}
+	if err == nil {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	conn.Close()
+	return nil, &net.OpError{Op: "dial-http", Net: "tcp " + address, Err: err}
+}
+
+// Authenticator verifies the credentials of RPC connections
+type Authenticator struct {
+	Key    string                                           // The key of the master
+	Bearer func(bearer string) (user, id string, err error) // Authenticates an API token
+	Host   func(hostID string) error                        // Checks that the host of a verified certificate exists
+}
+
+// Authenticate returns the caller of the connection. ok is false if the
+// connection carries no credentials.
+func (a *Authenticator) Authenticate(req *http.Request) (caller datastore.Caller, ok bool, err error) {
+	claimed := datastore.Caller{
+		User:   req.Header.Get(userHeader),
+		Source: req.Header.Get(sourceHeader),
+		Token:  req.Header.Get(tokenHeader),
+	}
+	if claimed.Source == "" {
+		claimed.Source = datastore.SourceRPC
+	}
+
+	if key := req.Header.Get(keyHeader); key != "" {
+		if a.Key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(a.Key)) != 1 {
+			return caller, false, ErrInvalidCredentials
+		}
+		// the web server acts on behalf of the users it has verified, who
+		// are limited to their roles
+		claimed.Admin = claimed.Source != datastore.SourceREST
+		return claimed, true, nil
+	}
+
+	if bearer := req.Header.Get(bearerHeader); bearer != "" {
+		if a.Bearer == nil {
+			return caller, false, ErrInvalidCredentials
+		}
+		user, id, err := a.Bearer(bearer)
+		if err != nil {
+			glog.V(1).Infof("Unable to authenticate API token: %s", err)
+			return caller, false, ErrInvalidCredentials
+		}
+		return datastore.Caller{User: user, Source: claimed.Source, Token: id}, true, nil
+	}
+
+	// the certificate was verified during the handshake; the certificates
+	// handed to the proxies of containers do not identify their host
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		cert := req.TLS.PeerCertificates[0]
+		hostID := cert.Subject.CommonName
+		if IsProxyCertificate(cert) {
+			glog.V(1).Infof("Refusing proxy certificate %s as a host", hostID)
+			return caller, false, ErrInvalidCredentials
+		} else if a.Host == nil {
+			return caller, false, ErrInvalidCredentials
+		} else if err := a.Host(hostID); err != nil {
+			glog.V(1).Infof("Unable to authenticate host %s: %s", hostID, err)
+			return caller, false, ErrInvalidCredentials
+		}
+		return datastore.Caller{Source: datastore.SourceRPC, Host: hostID}, true, nil
+	}
+
+	return caller, false, nil
+}
+
+// ServerFactory creates an RPC server whose services attribute their changes
+// to the caller
+type ServerFactory func(caller datastore.Caller) (*rpc.Server, error)
+
+// Handler serves the RPC connections of authenticated callers with a server
+// created for each connection. Connections without credentials are passed on
+// to the next handler, and those with credentials that are not valid are
+// refused.
+type Handler struct {
+	next    http.Handler
+	auth    *Authenticator
+	factory ServerFactory
+}
+
+// NewHandler creates a handler for authenticated RPC connections
+func NewHandler(next http.Handler, auth *Authenticator, factory ServerFactory) *Handler {
+	return &Handler{
+		next:    next,
+		auth:    auth,
+		factory: factory,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != rpc.DefaultRPCPath {
+		h.next.ServeHTTP(w, req)
+		return
+	}
+
+	caller, ok, err := h.auth.Authenticate(req)
+	if err != nil {
+		glog.Warningf("Refusing rpc connection from %s: %s", req.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if !ok {
+		h.next.ServeHTTP(w, req)
+		return
+	}
+
+	server, err := h.factory(caller)
+	if err != nil {
+		glog.Errorf("Could not create rpc server for %+v: %s", caller, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	server.ServeHTTP(w, req)
+}
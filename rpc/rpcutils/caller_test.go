@@ -0,0 +1,125 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package rpcutils
+
+import (
+	"github.com/control-center/serviced/datastore"
+
+	"errors"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"testing"
+)
+
+type whoAmI struct {
+	caller datastore.Caller
+}
+
+func (w *whoAmI) Caller(unused int, caller *datastore.Caller) error {
+	*caller = w.caller
+	return nil
+}
+
+// whoIs connects with the caller and asks the server who the caller of the
+// connection is
+func whoIs(t *testing.T, address string, caller datastore.Caller) datastore.Caller {
+	client, err := DialHTTP(address, caller)
+	if err != nil {
+		t.Fatalf("Could not connect: %s", err)
+	}
+	return ask(t, client)
+}
+
+func ask(t *testing.T, client *rpc.Client) datastore.Caller {
+	defer client.Close()
+	var caller datastore.Caller
+	if err := client.Call("WhoAmI.Caller", 0, &caller); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return caller
+}
+
+// newWhoAmIHandler creates a handler that serves anonymous connections with
+// the anonymous caller, counting the servers it creates for the others
+func newWhoAmIHandler(auth *Authenticator, created *int) *Handler {
+	anonymous := rpc.NewServer()
+	anonymous.RegisterName("WhoAmI", &whoAmI{datastore.Caller{Source: "anonymous"}})
+	return NewHandler(anonymous, auth, func(caller datastore.Caller) (*rpc.Server, error) {
+		*created++
+		server := rpc.NewServer()
+		err := server.RegisterName("WhoAmI", &whoAmI{caller})
+		return server, err
+	})
+}
+
+func TestHandler(t *testing.T) {
+	defer SetDefaultCredentials(Credentials{})
+
+	auth := &Authenticator{
+		Key: "secret",
+		Bearer: func(bearer string) (string, string, error) {
+			if bearer == "token-1.good" {
+				return "carol", "token-1", nil
+			}
+			return "", "", errors.New("unknown token")
+		},
+	}
+	created := 0
+	server := httptest.NewServer(newWhoAmIHandler(auth, &created))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	// no credentials
+	client, err := rpc.DialHTTP("tcp", address)
+	if err != nil {
+		t.Fatalf("Could not connect: %s", err)
+	}
+	if caller := ask(t, client); caller.Source != "anonymous" {
+		t.Errorf("Expected the anonymous server, got %+v", caller)
+	}
+	bob := datastore.Caller{User: "bob", Source: datastore.SourceCLI}
+	if caller := whoIs(t, address, bob); caller.Source != "anonymous" {
+		t.Errorf("Expected a claim without credentials to be anonymous, got %+v", caller)
+	}
+
+	// the key of the master
+	SetDefaultCredentials(Credentials{Key: "secret"})
+	bobAdmin := bob
+	bobAdmin.Admin = true
+	for i := 0; i < 2; i++ {
+		if caller := whoIs(t, address, bob); caller != bobAdmin {
+			t.Errorf("Expected %+v, got %+v", bobAdmin, caller)
+		}
+	}
+	if created != 2 {
+		t.Errorf("Expected a server for each connection, got %d", created)
+	}
+	alice := datastore.Caller{User: "alice"}
+	if caller := whoIs(t, address, alice); caller.User != "alice" || caller.Source != datastore.SourceRPC || !caller.Admin {
+		t.Errorf("Expected alice over RPC as an administrator, got %+v", caller)
+	}
+	dave := datastore.Caller{User: "dave", Source: datastore.SourceREST}
+	if caller := whoIs(t, address, dave); caller != dave {
+		t.Errorf("Expected the user of the web server to be limited to their roles, got %+v", caller)
+	}
+
+	SetDefaultCredentials(Credentials{Key: "guess"})
+	if _, err := DialHTTP(address, bob); err == nil {
+		t.Errorf("Expected a connection with the wrong key to be refused")
+	}
+
+	// an API token, which names the caller
+	SetDefaultCredentials(Credentials{Bearer: "token-1.good"})
+	carol := datastore.Caller{User: "carol", Source: datastore.SourceCLI, Token: "token-1"}
+	if caller := whoIs(t, address, bob); caller != carol {
+		t.Errorf("Expected %+v, got %+v", carol, caller)
+	}
+
+	SetDefaultCredentials(Credentials{Bearer: "token-1.bad"})
+	if _, err := DialHTTP(address, bob); err == nil {
+		t.Errorf("Expected a connection with an invalid token to be refused")
+	}
+}
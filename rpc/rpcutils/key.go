@@ -0,0 +1,57 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package rpcutils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeyFile is the file in the var path of the master that holds the key that
+// processes on the master authenticate with. Only root can read it.
+const KeyFile = "rpc.key"
+
+// ReadKey reads the key of the master from a file
+func ReadKey(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", errors.New("empty key")
+	}
+	return key, nil
+}
+
+// CreateKey reads the key of the master from a file, creating the file with
+// a new random key the first time
+func CreateKey(filename string) (string, error) {
+	if key, err := ReadKey(filename); err == nil {
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", err
+	}
+	// write a new file and move it into place so a crash never leaves a
+	// partial key behind
+	if err := ioutil.WriteFile(filename+".tmp", []byte(key+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return key, os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,147 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package rpcutils
+
+import (
+	"github.com/zenoss/glog"
+
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// the first byte of a TLS handshake record
+const tlsHandshake = 0x16
+
+// how long a client has to send its first byte before it is dropped
+var sniffTimeout = 10 * time.Second
+
+// ErrListenerClosed is returned by Accept once the listener is closed
+var ErrListenerClosed = errors.New("listener closed")
+
+// Listener accepts both plain and TLS connections on the same port, so that
+// hosts can present their certificates while other clients keep connecting
+// without TLS. TLS connections are refused until a configuration is set.
+type Listener struct {
+	listener net.Listener
+	lock     sync.Mutex
+	config   *tls.Config
+	accepted chan accepted
+	closed   chan struct{}
+	once     sync.Once
+}
+
+type accepted struct {
+	conn net.Conn
+	err  error
+}
+
+// NewListener starts accepting connections of the listener
+func NewListener(listener net.Listener) *Listener {
+	l := &Listener{
+		listener: listener,
+		accepted: make(chan accepted),
+		closed:   make(chan struct{}),
+	}
+	go l.accept()
+	return l
+}
+
+// SetConfig sets the TLS configuration of new connections
+func (l *Listener) SetConfig(config *tls.Config) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.config = config
+}
+
+func (l *Listener) getConfig() *tls.Config {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.config
+}
+
+// Accept implements net.Listener
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case a := <-l.accepted:
+		return a.conn, a.err
+	case <-l.closed:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close implements net.Listener
+func (l *Listener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return l.listener.Close()
+}
+
+// Addr implements net.Listener
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *Listener) accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			select {
+			case l.accepted <- accepted{err: err}:
+			case <-l.closed:
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		// a slow client must not hold up the others
+		go l.sniff(conn)
+	}
+}
+
+// sniff looks at the first byte of a connection to tell whether the client
+// is starting a TLS handshake
+func (l *Listener) sniff(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	first, err := reader.Peek(1)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	var c net.Conn = &bufferedConn{conn, reader}
+	if first[0] == tlsHandshake {
+		config := l.getConfig()
+		if config == nil {
+			glog.V(1).Infof("Refusing TLS connection from %s: no certificates", conn.RemoteAddr())
+			conn.Close()
+			return
+		}
+		c = tls.Server(c, config)
+	}
+
+	select {
+	case l.accepted <- accepted{conn: c}:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// bufferedConn reads the bytes that were peeked at before the rest of the
+// connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,131 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package rpcutils
+
+import (
+	"github.com/control-center/serviced/datastore"
+
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newCertificate creates a certificate signed by the parent, or a
+// self-signed authority if there is no parent
+func newCertificate(t *testing.T, name string, parent *tls.Certificate, units ...string) tls.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Could not generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name, OrganizationalUnit: units},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"serviced-test"},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		if signer, err = x509.ParseCertificate(parent.Certificate[0]); err != nil {
+			t.Fatalf("Could not parse certificate: %s", err)
+		}
+		signerKey = parent.PrivateKey.(*rsa.PrivateKey)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Could not create certificate: %s", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestListener(t *testing.T) {
+	defer SetDefaultCredentials(Credentials{})
+
+	ca := newCertificate(t, "ca", nil)
+	caCert, _ := x509.ParseCertificate(ca.Certificate[0])
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	master := newCertificate(t, "master", &ca)
+	host := newCertificate(t, "host-1", &ca)
+	removed := newCertificate(t, "host-2", &ca)
+	proxied := newCertificate(t, "host-1", &ca, ProxyUnit)
+	stranger := newCertificate(t, "host-3", nil)
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %s", err)
+	}
+	listener := NewListener(inner)
+	defer listener.Close()
+	auth := &Authenticator{
+		Host: func(hostID string) error {
+			if hostID != "host-1" {
+				return errors.New("host not found")
+			}
+			return nil
+		},
+	}
+	created := 0
+	go http.Serve(listener, newWhoAmIHandler(auth, &created))
+	address := inner.Addr().String()
+
+	clientConfig := func(cert tls.Certificate) *tls.Config {
+		return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool, ServerName: "serviced-test"}
+	}
+
+	// TLS is refused until there are certificates
+	SetDefaultCredentials(Credentials{TLS: clientConfig(host)})
+	if _, err := DialHTTP(address, datastore.Caller{}); err == nil {
+		t.Errorf("Expected TLS connection to be refused without certificates")
+	}
+
+	listener.SetConfig(&tls.Config{
+		Certificates: []tls.Certificate{master},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+	})
+
+	// the host is identified by its certificate, whatever it claims
+	if caller := whoIs(t, address, datastore.Caller{User: "root"}); caller.Host != "host-1" || caller.User != "" {
+		t.Errorf("Expected host-1, got %+v", caller)
+	}
+
+	// hosts that were removed are refused
+	SetDefaultCredentials(Credentials{TLS: clientConfig(removed)})
+	if _, err := DialHTTP(address, datastore.Caller{}); err == nil {
+		t.Errorf("Expected the connection of a removed host to be refused")
+	}
+
+	// the certificates of the proxies of containers do not identify the host
+	SetDefaultCredentials(Credentials{TLS: clientConfig(proxied)})
+	if _, err := DialHTTP(address, datastore.Caller{}); err == nil {
+		t.Errorf("Expected the connection of a proxy certificate to be refused")
+	}
+
+	// certificates of other authorities do not identify the host
+	SetDefaultCredentials(Credentials{TLS: clientConfig(stranger)})
+	if caller := whoIs(t, address, datastore.Caller{}); caller.Host != "" || caller.Source != "anonymous" {
+		t.Errorf("Expected the anonymous server, got %+v", caller)
+	}
+
+	// plain connections share the port
+	SetDefaultCredentials(Credentials{})
+	if caller := whoIs(t, address, datastore.Caller{}); caller.Source != "anonymous" {
+		t.Errorf("Expected the anonymous server, got %+v", caller)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/metrics"
+	"github.com/control-center/serviced/zzk/autoscale"
+)
+
+var (
+	// how often the scaling policies are evaluated
+	autoscaleInterval = time.Minute
+
+	// how far back the metrics of a service are averaged
+	autoscaleWindow = "5m-ago"
+)
+
+// autoscale periodically evaluates the scaling policies of the running
+// services in the leader's pool
+func (l *leader) autoscale(shutdown <-chan interface{}) {
+	client := metrics.NewClient(metrics.DefaultAddress)
+	for {
+		select {
+		case <-time.After(autoscaleInterval):
+		case <-shutdown:
+			return
+		}
+
+		var request dao.EntityRequest
+		services, err := l.facade.GetServices(l.context, request)
+		if err != nil {
+			glog.Warningf("Autoscaler unable to load services: %v", err)
+			continue
+		}
+
+		for _, svc := range services {
+			if svc.PoolID != l.poolID || svc.DesiredState != service.SVCRun || !svc.ScalingPolicy.Enabled() || svc.Instances < 1 {
+				continue
+			}
+			l.scaleService(client, svc)
+		}
+	}
+}
+
+// scaleService applies the scaling policy of a service, and records the
+// decision if the number of instances changes
+func (l *leader) scaleService(client *metrics.Client, svc *service.Service) {
+	policy := svc.ScalingPolicy
+
+	if last, err := autoscale.LastScaled(l.conn, svc.ID); err != nil {
+		glog.Warningf("Autoscaler unable to load scaling history of service %s: %v", svc.Name, err)
+		return
+	} else if time.Since(last) < policy.Cooldown {
+		glog.V(2).Infof("Autoscaler skipping service %s until its cooldown expires", svc.Name)
+		return
+	}
+
+	value, ok, err := client.ServiceAverage(svc.ID, policy.Metric, autoscaleWindow)
+	if err != nil {
+		glog.Warningf("Autoscaler unable to query metric %s of service %s: %v", policy.Metric, svc.Name, err)
+		return
+	} else if !ok {
+		glog.V(2).Infof("Autoscaler found no values of metric %s for service %s", policy.Metric, svc.Name)
+		return
+	}
+
+	desired := policy.DesiredInstances(svc.InstanceLimits, svc.Instances, value)
+	if desired == svc.Instances {
+		return
+	}
+
+	event := autoscale.Event{
+		ServiceID: svc.ID,
+		Timestamp: time.Now(),
+		Metric:    policy.Metric,
+		Value:     value,
+		Target:    policy.Target,
+		From:      svc.Instances,
+		To:        desired,
+	}
+	glog.Infof("Autoscaler scaling service %s from %d to %d instances (%s averaged %.2f, target %.2f)",
+		svc.Name, svc.Instances, desired, policy.Metric, value, policy.Target)
+
+	svc.Instances = desired
+	ctx := datastore.WithCaller(l.context, datastore.Caller{User: "autoscaler", Source: datastore.SourceScheduler})
+	if err := l.facade.UpdateService(ctx, *svc); err != nil {
+		glog.Warningf("Autoscaler unable to scale service %s: %v", svc.Name, err)
+		event.Err = err.Error()
+	}
+	if err := autoscale.Record(l.conn, event); err != nil {
+		glog.Warningf("Autoscaler unable to record scaling of service %s: %v", svc.Name, err)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	coordclient "github.com/control-center/serviced/coordinator/client"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/zzk"
+)
+
+// how often to check on the dependencies of a service that is waiting to start
+// or stop
+var dependencyPollInterval = 5 * time.Second
+
+// healthCheckNames returns the names of the service's health checks
+func healthCheckNames(svc *service.Service) []string {
+	names := make([]string, 0, len(svc.HealthChecks))
+	for name := range svc.HealthChecks {
+		names = append(names, name)
+	}
+	return names
+}
+
+// poolConnection connects to the pool of a service that is not in the pool
+// of the leader
+var poolConnection = func(poolID string) (coordclient.Connection, error) {
+	return zzk.GetBasePathConnection(zzk.GeneratePoolPath(poolID))
+}
+
+// serviceConn returns the connection to the pool that the instances of a
+// service are scheduled in, which may not be the pool of the leader
+func (l *leader) serviceConn(svc *service.Service) (coordclient.Connection, error) {
+	if svc.PoolID == l.poolID {
+		return l.conn, nil
+	}
+	return poolConnection(svc.PoolID)
+}
+
+// dependenciesReady reports whether each dependency of the service that is
+// supposed to be running has all of its instances started and passing their
+// health checks. Services start in dependency order because each one waits
+// for the services before it.
+func (l *leader) dependenciesReady(svc *service.Service) bool {
+	deps, err := l.facade.GetServiceDependencies(l.context, svc.ID)
+	if err != nil {
+		glog.Warningf("Unable to look up dependencies of service %s: %v", svc.Name, err)
+		return false
+	}
+	return l.servicesReady(svc, deps)
+}
+
+// servicesReady reports whether each of the dependencies of the service that
+// is supposed to be running is ready, looking up their instances in their own
+// pools
+func (l *leader) servicesReady(svc *service.Service, deps []*service.Service) bool {
+	for _, dep := range deps {
+		if dep.DesiredState != service.SVCRun {
+			glog.V(1).Infof("Service %s is not waiting for dependency %s, which is not set to run", svc.Name, dep.Name)
+			continue
+		}
+
+		conn, err := l.serviceConn(dep)
+		if err != nil {
+			glog.Warningf("Unable to connect to pool %s of service %s: %v", dep.PoolID, dep.Name, err)
+			return false
+		}
+		var states []*servicestate.ServiceState
+		if err := zzk.GetServiceStates(conn, &states, dep.ID); err != nil {
+			glog.Warningf("Unable to look up instances of service %s: %v", dep.Name, err)
+			return false
+		}
+		if len(states) < dep.Instances {
+			glog.V(1).Infof("Service %s is waiting for dependency %s to start", svc.Name, dep.Name)
+			return false
+		}
+
+		checks := healthCheckNames(dep)
+		for _, state := range states {
+			if state.Started.IsZero() || !l.instanceHealthy(conn, dep.ID, state.InstanceID, checks, state.Scheduled) {
+				glog.V(1).Infof("Service %s is waiting for instance %d of dependency %s to pass its health checks", svc.Name, state.InstanceID, dep.Name)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// dependentsStopped reports whether every service that depends on the service
+// and is also stopping has stopped all of its instances, so that services
+// stop in the reverse of the order they started.
+func (l *leader) dependentsStopped(svc *service.Service) bool {
+	dependents, err := l.facade.GetServiceDependents(l.context, svc.ID)
+	if err != nil {
+		glog.Warningf("Unable to look up dependents of service %s: %v", svc.Name, err)
+		return true
+	}
+	return l.servicesStopped(svc, dependents)
+}
+
+// servicesStopped reports whether each of the dependents of the service that
+// is stopping has stopped, looking up their instances in their own pools
+func (l *leader) servicesStopped(svc *service.Service, dependents []*service.Service) bool {
+	for _, dependent := range dependents {
+		if dependent.DesiredState != service.SVCStop {
+			continue
+		}
+
+		conn, err := l.serviceConn(dependent)
+		if err != nil {
+			glog.Warningf("Unable to connect to pool %s of service %s: %v", dependent.PoolID, dependent.Name, err)
+			return true
+		}
+		var states []*servicestate.ServiceState
+		if err := zzk.GetServiceStates(conn, &states, dependent.ID); err != nil {
+			glog.Warningf("Unable to look up instances of service %s: %v", dependent.Name, err)
+			return true
+		} else if len(states) > 0 {
+			glog.V(1).Infof("Service %s is waiting for dependent %s to stop", svc.Name, dependent.Name)
+			return false
+		}
+	}
+	return true
+}
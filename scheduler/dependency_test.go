@@ -0,0 +1,75 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	coordclient "github.com/control-center/serviced/coordinator/client"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicestate"
+	zkservice "github.com/control-center/serviced/zzk/service"
+)
+
+// addInstance schedules an instance of a service in the pool of the connection
+func addInstance(t *testing.T, conn coordclient.Connection, serviceID, stateID string) {
+	if err := conn.CreateDir("/services/" + serviceID); err != nil {
+		t.Fatalf("Could not create service %s: %s", serviceID, err)
+	}
+	state := &servicestate.ServiceState{ID: stateID, ServiceID: serviceID, Scheduled: time.Now(), Started: time.Now()}
+	if err := conn.Create("/services/"+serviceID+"/"+stateID, &zkservice.ServiceStateNode{ServiceState: state}); err != nil {
+		t.Fatalf("Could not add instance %s: %s", stateID, err)
+	}
+}
+
+func TestDependencies_otherPool(t *testing.T) {
+	connA := coordclient.NewTestConnection()
+	defer connA.Close()
+	connB := coordclient.NewTestConnection()
+	defer connB.Close()
+
+	defer func(f func(string) (coordclient.Connection, error)) { poolConnection = f }(poolConnection)
+	poolConnection = func(poolID string) (coordclient.Connection, error) {
+		if poolID == "pool-b" {
+			return connB, nil
+		}
+		return nil, errors.New("no such pool")
+	}
+
+	l := &leader{conn: connA, poolID: "pool-a"}
+	svc := &service.Service{ID: "web", Name: "web", PoolID: "pool-a", DesiredState: service.SVCRun}
+	dep := &service.Service{ID: "db", Name: "db", PoolID: "pool-b", DesiredState: service.SVCRun, Instances: 1}
+
+	// the dependency has not started in its own pool
+	connB.CreateDir("/services/db")
+	if l.servicesReady(svc, []*service.Service{dep}) {
+		t.Errorf("Expected the service to wait for its dependency")
+	}
+
+	// an instance in the pool of the leader is not the dependency's
+	addInstance(t, connA, "db", "state-a")
+	if l.servicesReady(svc, []*service.Service{dep}) {
+		t.Errorf("Expected the dependency to be looked up in its own pool")
+	}
+
+	addInstance(t, connB, "db", "state-b")
+	if !l.servicesReady(svc, []*service.Service{dep}) {
+		t.Errorf("Expected the dependency in another pool to be ready")
+	}
+
+	// the dependent in the other pool has to stop first
+	dep.DesiredState = service.SVCStop
+	if l.servicesStopped(svc, []*service.Service{dep}) {
+		t.Errorf("Expected the service to wait for its dependent in another pool to stop")
+	}
+	if err := connB.Delete("/services/db/state-b"); err != nil {
+		t.Fatalf("Could not remove instance: %s", err)
+	}
+	if !l.servicesStopped(svc, []*service.Service{dep}) {
+		t.Errorf("Expected the dependent in another pool to be stopped")
+	}
+}
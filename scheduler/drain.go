@@ -0,0 +1,139 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/zzk"
+	"github.com/control-center/serviced/zzk/registry"
+	zkservice "github.com/control-center/serviced/zzk/service"
+)
+
+// drainer tracks the instances that are draining before they are stopped
+type drainer struct {
+	sync.Mutex
+	timeout  time.Duration       // how long in-flight connections are given
+	draining map[string]struct{} // the ids of the draining service states
+	shutdown <-chan interface{}  // stops draining instances right away when the leader shuts down
+	wg       *sync.WaitGroup     // the routines of the leader, which the draining instances are added to
+}
+
+func newDrainer(timeout time.Duration, shutdown <-chan interface{}, wg *sync.WaitGroup) *drainer {
+	return &drainer{timeout: timeout, draining: make(map[string]struct{}), shutdown: shutdown, wg: wg}
+}
+
+// start tracks a service state as draining; it is false if it already was
+func (d *drainer) start(stateID string) bool {
+	d.Lock()
+	defer d.Unlock()
+	if _, ok := d.draining[stateID]; ok {
+		return false
+	}
+	d.draining[stateID] = struct{}{}
+	return true
+}
+
+// done stops tracking a service state
+func (d *drainer) done(stateID string) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.draining, stateID)
+}
+
+// stopServiceInstance stops an instance once the endpoints it exports have
+// been draining for the drain timeout, so that proxies and vhosts send no new
+// connections to it while in-flight connections finish. Instances that are
+// already draining are left alone.
+func (l *leader) stopServiceInstance(state *servicestate.ServiceState) {
+	if l.drainer.timeout <= 0 || state.DockerID == "" || !hasExports(state) {
+		l.stopNow(state)
+		return
+	}
+	if !l.drainer.start(state.ID) {
+		glog.V(2).Infof("Instance %s:%s is already draining", state.HostID, state.ID)
+		return
+	}
+	if err := l.drainServiceInstance(state); err != nil {
+		glog.Warningf("Could not drain %s:%s, stopping it now: %s", state.HostID, state.ID, err)
+		l.stopNow(state)
+		l.drainer.done(state.ID)
+		return
+	}
+
+	glog.V(1).Infof("Draining %s:%s for %s", state.HostID, state.ID, l.drainer.timeout)
+	l.drainer.wg.Add(1)
+	go func() {
+		defer l.drainer.wg.Done()
+		defer l.drainer.done(state.ID)
+		select {
+		case <-time.After(l.drainer.timeout):
+		case <-l.drainer.shutdown:
+			glog.V(1).Infof("Leader is shutting down, stopping %s:%s before it has drained", state.HostID, state.ID)
+		}
+		l.stopNow(state)
+	}()
+}
+
+// stopNow stops an instance without draining it
+func (l *leader) stopNow(state *servicestate.ServiceState) {
+	glog.V(2).Infof("Killing host service state %s:%s", state.HostID, state.ID)
+	if err := zkservice.StopServiceInstance(l.conn, state.HostID, state.ID); err != nil {
+		glog.Warningf("%s:%s wouldn't die", state.HostID, state.ID)
+	}
+}
+
+// drainServiceInstance marks the endpoints and vhosts exported by an
+// instance as draining in the registry
+func (l *leader) drainServiceInstance(state *servicestate.ServiceState) error {
+	tenantID, err := l.facade.GetTenantID(l.context, state.ServiceID)
+	if err != nil {
+		return err
+	}
+	// the endpoint and vhost registries are not pool aware
+	rootConn, err := zzk.GetBasePathConnection("/")
+	if err != nil {
+		return err
+	}
+	endpoints, err := registry.CreateEndpointRegistry(rootConn)
+	if err != nil {
+		return err
+	}
+	vhosts, err := registry.VHostRegistry(rootConn)
+	if err != nil {
+		return err
+	}
+
+	for _, ep := range state.Endpoints {
+		if ep.Purpose != "export" {
+			continue
+		}
+		if err := endpoints.SetDraining(rootConn, tenantID, ep.Application, state.HostID, state.DockerID); err != nil {
+			return err
+		}
+		// named as the container registers it
+		endpointName := fmt.Sprintf("%s_%v", ep.Name, state.InstanceID)
+		for _, vhost := range ep.VHosts {
+			if err := vhosts.SetDraining(rootConn, vhost, state.ServiceID, endpointName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasExports is true if an instance exports any endpoints
+func hasExports(state *servicestate.ServiceState) bool {
+	for _, ep := range state.Endpoints {
+		if ep.Purpose == "export" {
+			return true
+		}
+	}
+	return false
+}
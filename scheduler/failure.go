@@ -0,0 +1,120 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
+	"github.com/control-center/serviced/domain/servicestate"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+)
+
+// number of restarts kept on the state of an instance; enough for the most
+// restarts a failure policy may allow within its window
+const maxRestartLog = servicedefinition.MaxFailureRestarts
+
+// how long a restart is carried over to the replacement of an instance
+var restartCarryOver = time.Hour
+
+// restart is a restart of an instance for failing its health checks, waiting
+// for the instance to be replaced
+type restart struct {
+	stateID   string
+	restarts  int
+	log       []time.Time
+	timestamp time.Time
+}
+
+// restartTracker carries the restarts of the instances that the leader
+// restarted for failing their health checks over to the states of their
+// replacements
+type restartTracker struct {
+	sync.Mutex
+	pending map[string]restart // by service id and instance id
+}
+
+func instanceKey(serviceID string, instanceID int) string {
+	return fmt.Sprintf("%s/%d", serviceID, instanceID)
+}
+
+func newRestartTracker() *restartTracker {
+	return &restartTracker{pending: make(map[string]restart)}
+}
+
+// restarting is true if the instance was restarted and not replaced yet
+func (t *restartTracker) restarting(state *servicestate.ServiceState) bool {
+	t.Lock()
+	defer t.Unlock()
+	r, ok := t.pending[instanceKey(state.ServiceID, state.InstanceID)]
+	return ok && r.stateID == state.ID
+}
+
+// add records the restart of an instance
+func (t *restartTracker) add(state *servicestate.ServiceState, log []time.Time, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	for key, r := range t.pending {
+		if now.Sub(r.timestamp) > restartCarryOver {
+			delete(t.pending, key)
+		}
+	}
+	if len(log) > maxRestartLog {
+		log = log[len(log)-maxRestartLog:]
+	}
+	t.pending[instanceKey(state.ServiceID, state.InstanceID)] = restart{state.ID, state.Restarts + 1, log, now}
+}
+
+// forget drops the restart of an instance that could not be stopped
+func (t *restartTracker) forget(state *servicestate.ServiceState) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.pending, instanceKey(state.ServiceID, state.InstanceID))
+}
+
+// take sets the restarts of the instance that a new state replaces. Returns
+// true if the instance was restarted.
+func (t *restartTracker) take(state *servicestate.ServiceState) bool {
+	t.Lock()
+	defer t.Unlock()
+	key := instanceKey(state.ServiceID, state.InstanceID)
+	r, ok := t.pending[key]
+	if ok {
+		state.Restarts, state.RestartLog = r.restarts, r.log
+		delete(t.pending, key)
+	}
+	return ok
+}
+
+// restartFailing restarts an instance that failed a health check as many
+// times in a row as its service's failure policy allows, unless it is backing
+// off from its previous restart or was restarted too often within the window
+func (l *leader) restartFailing(svc *service.Service, state *servicestate.ServiceState, s *zkhealth.Status, now time.Time) {
+	policy := svc.FailurePolicy
+	if !policy.Enabled() || s.Failures < policy.Failures || l.restarts.restarting(state) {
+		return
+	}
+	next, ok := policy.NextRestart(state.RestartLog, now)
+	if !ok {
+		glog.V(1).Infof("Not restarting instance %d of service %s, which was restarted %d times within %s", state.InstanceID, svc.Name, policy.MaxRestarts, policy.Window)
+		return
+	} else if now.Before(next) {
+		glog.V(1).Infof("Not restarting instance %d of service %s before %s", state.InstanceID, svc.Name, next)
+		return
+	}
+
+	glog.Warningf("Restarting instance %d of service %s, which failed health check %s %d times in a row", state.InstanceID, svc.Name, s.Name, s.Failures)
+	l.restarts.add(state, append(policy.Recent(state.RestartLog, now), now), now)
+	var unused int
+	if err := l.dao.StopRunningInstance(dao.HostServiceRequest{HostID: state.HostID, ServiceStateID: state.ID}, &unused); err != nil {
+		glog.Warningf("Unable to restart instance %d of service %s: %v", state.InstanceID, svc.Name, err)
+		l.restarts.forget(state)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/domain/servicestate"
+)
+
+func TestRestartTracker(t *testing.T) {
+	tracker := newRestartTracker()
+	now := time.Now()
+	state := &servicestate.ServiceState{ID: "state-1", ServiceID: "service-1", InstanceID: 2, Restarts: 4}
+
+	tracker.add(state, []time.Time{now}, now)
+	if !tracker.restarting(state) {
+		t.Errorf("Expected instance to be restarting")
+	}
+
+	// the replacement takes over the restarts
+	replacement := &servicestate.ServiceState{ID: "state-2", ServiceID: "service-1", InstanceID: 2}
+	if tracker.restarting(replacement) {
+		t.Errorf("Expected replacement not to be restarting")
+	}
+	if !tracker.take(replacement) {
+		t.Errorf("Expected the replacement of a restarted instance")
+	}
+	if replacement.Restarts != 5 || len(replacement.RestartLog) != 1 || !replacement.RestartLog[0].Equal(now) {
+		t.Errorf("Unexpected restarts of the replacement: %d %v", replacement.Restarts, replacement.RestartLog)
+	}
+	if tracker.restarting(state) {
+		t.Errorf("Expected instance not to be restarting once replaced")
+	}
+
+	// restarts that are not taken are dropped after a while
+	tracker.add(state, []time.Time{now}, now)
+	other := &servicestate.ServiceState{ID: "state-3", ServiceID: "service-2", InstanceID: 0}
+	tracker.add(other, nil, now.Add(restartCarryOver+time.Second))
+	if tracker.restarting(state) || !tracker.restarting(other) {
+		t.Errorf("Expected only the newest restart to be pending: %v", tracker.pending)
+	}
+
+	// the log is capped
+	log := make([]time.Time, maxRestartLog+5)
+	tracker.add(state, log, now)
+	tracker.take(state)
+	if len(state.RestartLog) != maxRestartLog {
+		t.Errorf("Expected %d restarts in the log, got %d", maxRestartLog, len(state.RestartLog))
+	}
+}
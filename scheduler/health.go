@@ -0,0 +1,107 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	coordclient "github.com/control-center/serviced/coordinator/client"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/zzk"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+)
+
+// how often the health checks of the pool are looked over for missed results
+var healthInterval = 15 * time.Second
+
+// instanceHealthy reports whether each of the named health checks has passed
+// for the service instance since the given time, as recorded in the pool of
+// the connection
+func (l *leader) instanceHealthy(conn coordclient.Connection, serviceID string, instanceID int, checks []string, since time.Time) bool {
+	healthy, err := zkhealth.InstanceHealthy(conn, serviceID, instanceID, checks, since)
+	if err != nil {
+		glog.Warningf("Unable to look up the health of instance %d of service %s: %v", instanceID, serviceID, err)
+		return false
+	}
+	return healthy
+}
+
+// watchHealth periodically marks the health checks of the running instances
+// in the pool that have not reported within twice their interval as missed,
+// restarts the instances that keep failing them as their service's failure
+// policy says, and forgets the health of the instances that are no longer
+// running
+func (l *leader) watchHealth(shutdown <-chan interface{}) {
+	for {
+		select {
+		case <-time.After(healthInterval):
+		case <-shutdown:
+			return
+		}
+
+		statuses, err := zkhealth.GetAll(l.conn)
+		if err != nil {
+			glog.Warningf("Unable to look up the health checks of pool %s: %v", l.poolID, err)
+			continue
+		}
+		now := time.Now()
+		running := make(map[string][]*servicestate.ServiceState)
+		for _, s := range statuses {
+			stale := s.Stale(now)
+			if !stale && s.Failures == 0 {
+				continue
+			}
+			states, ok := running[s.ServiceID]
+			if !ok {
+				if exists, err := l.conn.Exists(zzk.ServicePath(s.ServiceID)); err == nil && !exists {
+					// the service was removed, so none of its instances run
+				} else if err := zzk.GetServiceStates(l.conn, &states, s.ServiceID); err != nil {
+					glog.Warningf("Unable to look up instances of service %s: %v", s.ServiceID, err)
+					continue
+				}
+				running[s.ServiceID] = states
+			}
+			state := findInstance(states, s.InstanceID)
+			switch {
+			case state == nil && stale:
+				glog.V(2).Infof("Forgetting the health of instance %d of service %s, which is not running", s.InstanceID, s.ServiceID)
+				if err := zkhealth.RemoveInstance(l.conn, s.ServiceID, s.InstanceID); err != nil {
+					glog.Warningf("Unable to remove the health of instance %d of service %s: %v", s.InstanceID, s.ServiceID, err)
+				}
+			case state == nil:
+				// waiting for the instance to be replaced
+			case stale:
+				l.markMissed(s, now)
+			default:
+				svc, err := l.facade.GetService(l.context, s.ServiceID)
+				if err != nil {
+					glog.Warningf("Unable to look up service %s: %v", s.ServiceID, err)
+					continue
+				}
+				l.restartFailing(svc, state, s, now)
+			}
+		}
+	}
+}
+
+// markMissed marks a stale health check of a running instance as missed, and
+// notifies the sinks the first time
+func (l *leader) markMissed(s *zkhealth.Status, now time.Time) {
+	missed, err := zkhealth.MarkMissed(l.conn, s, now)
+	if err != nil {
+		glog.Warningf("Unable to mark health check %s of instance %d of service %s as missed: %v", s.Name, s.InstanceID, s.ServiceID, err)
+		return
+	} else if !missed {
+		return
+	}
+	glog.Warningf("Health check %s of instance %d of service %s has not reported since %s", s.Name, s.InstanceID, s.ServiceID, s.Timestamp)
+	svc, err := l.facade.GetService(l.context, s.ServiceID)
+	if err != nil {
+		glog.Warningf("Unable to look up service %s: %v", s.ServiceID, err)
+		return
+	}
+	l.facade.NotifyHealthCheck(l.context, svc, s.InstanceID, s.Name, zkhealth.StatusMissed)
+}
@@ -12,19 +12,24 @@ import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/pool"
 	"github.com/control-center/serviced/domain/service"
 )
 
 // HostInfo provides methods for getting host information from the dao or
 // otherwise. It's a separate interface for the sake of testing.
 type HostInfo interface {
-	AvailableRAM(*host.Host, chan *hostitem, <-chan bool)
-	PrioritizeByMemory([]*host.Host) ([]*host.Host, error)
+	AvailableResources(*host.Host, chan *hostitem, <-chan bool)
+	PrioritizeByResources([]*host.Host) ([]*host.Host, error)
 	ServicesOnHost(*host.Host) []*dao.RunningService
 }
 
+// DAOHostInfo computes host resources for scheduling a service according to
+// the scheduling configuration of the service's pool.
 type DAOHostInfo struct {
-	dao dao.ControlPlane
+	dao  dao.ControlPlane
+	svc  *service.Service   // the service being scheduled
+	pool *pool.ResourcePool // the pool of the service, may be nil
 }
 
 func (hi *DAOHostInfo) ServicesOnHost(h *host.Host) []*dao.RunningService {
@@ -35,17 +40,18 @@ func (hi *DAOHostInfo) ServicesOnHost(h *host.Host) []*dao.RunningService {
 	return rss
 }
 
-// AvailableRAM computes the amount of RAM available on a given host by
-// subtracting the sum of the RAM commitments of each of its running services
-// from its total memory.
-func (hi *DAOHostInfo) AvailableRAM(host *host.Host, result chan *hostitem, done <-chan bool) {
+// AvailableResources computes the amount of RAM and cores available on a
+// given host by subtracting the sum of the commitments of each of its running
+// services from its totals. Hosts that would be overcommitted beyond the
+// pool's ratios by running the service are not sent.
+func (hi *DAOHostInfo) AvailableResources(host *host.Host, result chan *hostitem, done <-chan bool) {
 	rss := []*dao.RunningService{}
 	if err := hi.dao.GetRunningServicesForHost(host.ID, &rss); err != nil {
 		glog.Errorf("cannot retrieve running services for host: %s (%v)", host.ID, err)
 		return // this host won't be scheduled
 	}
 
-	var cr uint64
+	var cr, cc uint64
 
 	for i := range rss {
 		s := service.Service{}
@@ -55,12 +61,44 @@ func (hi *DAOHostInfo) AvailableRAM(host *host.Host, result chan *hostitem, done
 		}
 
 		cr += s.RAMCommitment
+		cc += s.CPUCommitment
 	}
 
-	result <- &hostitem{host, host.Memory - cr, -1}
+	if hi.overcommitted(host, cr, cc) {
+		glog.V(2).Infof("Host %s does not have the resources to run service %s", host.ID, hi.svc.Name)
+		return // this host won't be scheduled
+	}
+
+	select {
+	case result <- &hostitem{host: host, ram: int64(host.Memory) - int64(cr), cores: int64(host.Cores) - int64(cc), index: -1}:
+	case <-done:
+	}
 }
 
-func (hi *DAOHostInfo) PrioritizeByMemory(hosts []*host.Host) ([]*host.Host, error) {
+// overcommitted reports whether adding the service's commitments to the
+// host's committed RAM and cores would exceed the pool's overcommit ratios.
+func (hi *DAOHostInfo) overcommitted(host *host.Host, ram, cores uint64) bool {
+	if hi.pool == nil {
+		return false
+	}
+	if ratio := hi.pool.MemoryOvercommit; ratio > 0 {
+		if float64(ram+hi.svc.RAMCommitment) > float64(host.Memory)*ratio {
+			return true
+		}
+	}
+	if ratio := hi.pool.CoreOvercommit; ratio > 0 {
+		if float64(cores+hi.svc.CPUCommitment) > float64(host.Cores)*ratio {
+			return true
+		}
+	}
+	return false
+}
+
+// PrioritizeByResources orders the hosts by their weighted share of
+// uncommitted RAM and cores, with the most available host first. Each
+// resource is scaled against the largest amount available on any host, so
+// that neither dominates just because of its units.
+func (hi *DAOHostInfo) PrioritizeByResources(hosts []*host.Host) ([]*host.Host, error) {
 	var wg sync.WaitGroup
 
 	result := make([]*host.Host, 0)
@@ -69,11 +107,11 @@ func (hi *DAOHostInfo) PrioritizeByMemory(hosts []*host.Host) ([]*host.Host, err
 
 	hic := make(chan *hostitem)
 
-	// fan-out available RAM computation for each host
+	// fan-out available resource computation for each host
 	for _, h := range hosts {
 		wg.Add(1)
 		go func(host *host.Host) {
-			hi.AvailableRAM(host, hic, done)
+			hi.AvailableResources(host, hic, done)
 			wg.Done()
 		}(h)
 	}
@@ -84,20 +122,47 @@ func (hi *DAOHostInfo) PrioritizeByMemory(hosts []*host.Host) ([]*host.Host, err
 		close(hic)
 	}()
 
-	pq := &PriorityQueue{}
-	heap.Init(pq)
-
-	// fan-in all the available RAM computations
-	for hi := range hic {
-		heap.Push(pq, hi)
+	// fan-in all the available resource computations
+	var (
+		items           []*hostitem
+		maxRAM, maxCore int64
+	)
+	for item := range hic {
+		items = append(items, item)
+		if item.ram > maxRAM {
+			maxRAM = item.ram
+		}
+		if item.cores > maxCore {
+			maxCore = item.cores
+		}
 	}
 
-	if pq.Len() < 1 {
+	if len(items) < 1 {
 		return nil, errors.New("Unable to find a host to schedule")
 	}
 
+	memoryWeight, coreWeight := 1.0, 1.0
+	if hi.pool != nil {
+		memoryWeight, coreWeight = hi.pool.SchedulingWeights()
+	}
+
+	pq := &PriorityQueue{}
+	heap.Init(pq)
+	for _, item := range items {
+		item.priority = memoryWeight*share(item.ram, maxRAM) + coreWeight*share(item.cores, maxCore)
+		heap.Push(pq, item)
+	}
+
 	for pq.Len() > 0 {
 		result = append(result, heap.Pop(pq).(*hostitem).host)
 	}
 	return result, nil
 }
+
+// share returns the amount as a fraction of the maximum.
+func share(amount, max int64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return float64(amount) / float64(max)
+}
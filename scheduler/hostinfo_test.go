@@ -0,0 +1,119 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/pool"
+	"github.com/control-center/serviced/domain/service"
+)
+
+const gigabyte = 1024 * 1024 * 1024
+
+// Stub out the dao with running services and their commitments
+type TestControlPlane struct {
+	dao.ControlPlane
+	running  map[string][]*dao.RunningService
+	services map[string]*service.Service
+}
+
+func (t *TestControlPlane) GetRunningServicesForHost(hostID string, rss *[]*dao.RunningService) error {
+	*rss = t.running[hostID]
+	return nil
+}
+
+func (t *TestControlPlane) GetService(serviceID string, svc *service.Service) error {
+	*svc = *t.services[serviceID]
+	return nil
+}
+
+func (t *TestControlPlane) run(svc *service.Service, h *host.Host) {
+	t.services[svc.ID] = svc
+	t.running[h.ID] = append(t.running[h.ID], &dao.RunningService{ServiceID: svc.ID, Name: svc.Name})
+}
+
+func newTestResources() (*TestControlPlane, *host.Host, *host.Host) {
+	cp := &TestControlPlane{
+		running:  make(map[string][]*dao.RunningService),
+		services: make(map[string]*service.Service),
+	}
+	// bigmem has more RAM, manycore has more cores
+	bigmem := &host.Host{ID: "bigmem", Memory: 16 * gigabyte, Cores: 2}
+	manycore := &host.Host{ID: "manycore", Memory: 8 * gigabyte, Cores: 8}
+	return cp, bigmem, manycore
+}
+
+func TestPrioritizeByResources_weights(t *testing.T) {
+	cp, bigmem, manycore := newTestResources()
+	hosts := []*host.Host{bigmem, manycore}
+	svc := &service.Service{ID: "svc", RAMCommitment: gigabyte, CPUCommitment: 1}
+
+	hinfo := &DAOHostInfo{cp, svc, &pool.ResourcePool{MemoryWeight: 1}}
+	if prioritized, err := hinfo.PrioritizeByResources(hosts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if prioritized[0] != bigmem {
+		t.Fatalf("Expected bigmem host but got %s", prioritized[0].ID)
+	}
+
+	hinfo.pool = &pool.ResourcePool{CoreWeight: 1}
+	if prioritized, err := hinfo.PrioritizeByResources(hosts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if prioritized[0] != manycore {
+		t.Fatalf("Expected manycore host but got %s", prioritized[0].ID)
+	}
+
+	// Equal weights by default; manycore has 1/2 the RAM but 4x the cores
+	hinfo.pool = &pool.ResourcePool{}
+	if prioritized, err := hinfo.PrioritizeByResources(hosts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if prioritized[0] != manycore {
+		t.Fatalf("Expected manycore host but got %s", prioritized[0].ID)
+	}
+}
+
+func TestPrioritizeByResources_commitments(t *testing.T) {
+	cp, bigmem, manycore := newTestResources()
+	hosts := []*host.Host{bigmem, manycore}
+	svc := &service.Service{ID: "svc", RAMCommitment: gigabyte, CPUCommitment: 1}
+	hinfo := &DAOHostInfo{cp, svc, &pool.ResourcePool{}}
+
+	// Commit most of manycore's cores
+	cp.run(&service.Service{ID: "cpuhog", CPUCommitment: 7}, manycore)
+	if prioritized, err := hinfo.PrioritizeByResources(hosts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if prioritized[0] != bigmem {
+		t.Fatalf("Expected bigmem host but got %s", prioritized[0].ID)
+	}
+}
+
+func TestPrioritizeByResources_overcommit(t *testing.T) {
+	cp, bigmem, manycore := newTestResources()
+	hosts := []*host.Host{bigmem, manycore}
+	svc := &service.Service{ID: "svc", RAMCommitment: 4 * gigabyte, CPUCommitment: 2}
+	hinfo := &DAOHostInfo{cp, svc, &pool.ResourcePool{MemoryOvercommit: 1, CoreOvercommit: 1}}
+
+	cp.run(&service.Service{ID: "memhog", RAMCommitment: 6 * gigabyte}, manycore)
+	if prioritized, err := hinfo.PrioritizeByResources(hosts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if len(prioritized) != 1 || prioritized[0] != bigmem {
+		t.Fatalf("Expected only bigmem host but got %v", prioritized)
+	}
+
+	cp.run(&service.Service{ID: "cpuhog", CPUCommitment: 1}, bigmem)
+	if _, err := hinfo.PrioritizeByResources(hosts); err == nil {
+		t.Fatalf("Should have received an error but didn't")
+	}
+
+	// Allow cores to be overcommitted 2:1
+	hinfo.pool.CoreOvercommit = 2
+	if prioritized, err := hinfo.PrioritizeByResources(hosts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	} else if len(prioritized) != 1 || prioritized[0] != bigmem {
+		t.Fatalf("Expected only bigmem host but got %v", prioritized)
+	}
+}
@@ -6,10 +6,12 @@ package scheduler
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/dao"
 	"github.com/control-center/serviced/domain/host"
+	"github.com/control-center/serviced/domain/pool"
 	"github.com/control-center/serviced/domain/service"
 	"github.com/control-center/serviced/domain/servicedefinition"
 )
@@ -22,9 +24,11 @@ type ServiceHostPolicy struct {
 	hinfo HostInfo
 }
 
-// ServiceHostPolicy returns a new ServiceHostPolicy.
-func NewServiceHostPolicy(s *service.Service, cp dao.ControlPlane) *ServiceHostPolicy {
-	return &ServiceHostPolicy{s, &DAOHostInfo{cp}}
+// ServiceHostPolicy returns a new ServiceHostPolicy. The pool, if not nil,
+// configures how host resources are weighed and how far they may be
+// overcommitted.
+func NewServiceHostPolicy(s *service.Service, cp dao.ControlPlane, p *pool.ResourcePool) *ServiceHostPolicy {
+	return &ServiceHostPolicy{s, &DAOHostInfo{cp, s, p}}
 }
 
 func (sp *ServiceHostPolicy) SelectHost(hosts []*host.Host) (*host.Host, error) {
@@ -56,14 +60,102 @@ hosts:
 	return nil
 }
 
-// leastCommittedHost chooses the host with the least RAM committed to running
-// containers.
+// prioritize orders the hosts by available resources, removes the hosts ruled out by
+// the service's required labels and anti-affinities, and then moves the hosts
+// matching the most preferred labels and affinities to the front.
+func (sp *ServiceHostPolicy) prioritize(hosts []*host.Host) ([]*host.Host, error) {
+	prioritized, err := sp.hinfo.PrioritizeByResources(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := sp.svc.HostSelector
+	ranked := &rankedHosts{}
+	for _, h := range prioritized {
+		if matchLabels(h.Labels, selector.RequiredLabels) < len(selector.RequiredLabels) {
+			glog.V(2).Infof("Host %s does not have the labels required by service %s", h.ID, sp.svc.Name)
+			continue
+		}
+
+		var names []string
+		if len(selector.Affinity) > 0 || len(selector.AntiAffinity) > 0 {
+			names = sp.serviceNamesOnHost(h)
+		}
+		if matchNames(names, selector.AntiAffinity) > 0 {
+			glog.V(2).Infof("Host %s is running a service that conflicts with service %s", h.ID, sp.svc.Name)
+			continue
+		}
+
+		score := matchLabels(h.Labels, selector.PreferredLabels) + matchNames(names, selector.Affinity)
+		ranked.hosts = append(ranked.hosts, h)
+		ranked.scores = append(ranked.scores, score)
+	}
+
+	if len(ranked.hosts) == 0 {
+		return nil, errors.New("Unable to find a host that satisfies the host selector")
+	}
+
+	// stable sort so that hosts with equal scores remain ordered by resources
+	sort.Stable(ranked)
+	return ranked.hosts, nil
+}
+
+// serviceNamesOnHost returns the names of the services running on the host.
+func (sp *ServiceHostPolicy) serviceNamesOnHost(h *host.Host) []string {
+	var names []string
+	for _, rs := range sp.hinfo.ServicesOnHost(h) {
+		names = append(names, rs.Name)
+	}
+	return names
+}
+
+// matchLabels returns the number of selector labels found on the host. A
+// selector label with an empty value matches any value.
+func matchLabels(labels, selector map[string]string) int {
+	count := 0
+	for key, value := range selector {
+		if v, ok := labels[key]; ok && (value == "" || value == v) {
+			count++
+		}
+	}
+	return count
+}
+
+// matchNames returns the number of selector names found in names.
+func matchNames(names, selector []string) int {
+	count := 0
+	for _, s := range selector {
+		for _, name := range names {
+			if name == s {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// rankedHosts implements sort.Interface, ordering hosts by descending score.
+type rankedHosts struct {
+	hosts  []*host.Host
+	scores []int
+}
+
+func (r *rankedHosts) Len() int           { return len(r.hosts) }
+func (r *rankedHosts) Less(i, j int) bool { return r.scores[i] > r.scores[j] }
+func (r *rankedHosts) Swap(i, j int) {
+	r.hosts[i], r.hosts[j] = r.hosts[j], r.hosts[i]
+	r.scores[i], r.scores[j] = r.scores[j], r.scores[i]
+}
+
+// leastCommittedHost chooses the host with the least RAM and cores committed
+// to running containers.
 func (sp *ServiceHostPolicy) leastCommittedHost(hosts []*host.Host) (*host.Host, error) {
 	var (
 		prioritized []*host.Host
 		err         error
 	)
-	if prioritized, err = sp.hinfo.PrioritizeByMemory(hosts); err != nil {
+	if prioritized, err = sp.prioritize(hosts); err != nil {
 		return nil, err
 	}
 	return prioritized[0], nil
@@ -77,7 +169,7 @@ func (sp *ServiceHostPolicy) preferSeparateHosts(hosts []*host.Host) (*host.Host
 		prioritized []*host.Host
 		err         error
 	)
-	if prioritized, err = sp.hinfo.PrioritizeByMemory(hosts); err != nil {
+	if prioritized, err = sp.prioritize(hosts); err != nil {
 		return nil, err
 	}
 	// First pass: find one that isn't running an instance of the service
@@ -99,7 +191,7 @@ func (sp *ServiceHostPolicy) requireSeparateHosts(hosts []*host.Host) (*host.Hos
 		prioritized []*host.Host
 		err         error
 	)
-	if prioritized, err = sp.hinfo.PrioritizeByMemory(hosts); err != nil {
+	if prioritized, err = sp.prioritize(hosts); err != nil {
 		return nil, err
 	}
 	// First pass: find one that isn't running an instance of the service
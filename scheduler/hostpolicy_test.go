@@ -17,7 +17,7 @@ var (
 	least, middlest, most *host.Host
 	unprioritized         []*host.Host
 	prioritized           []*host.Host
-	hoststates            map[*host.Host][]string
+	hoststates            map[*host.Host][]*service.Service
 	testinfo              *TestHostInfo
 )
 
@@ -27,21 +27,21 @@ func BeforeEach() {
 	most = &host.Host{ID: "most"}
 	prioritized = []*host.Host{most, middlest, least}
 	unprioritized = []*host.Host{least, middlest, most}
-	hoststates = map[*host.Host][]string{least: []string{}, most: []string{}, middlest: []string{}}
+	hoststates = map[*host.Host][]*service.Service{least: {}, most: {}, middlest: {}}
 	testinfo = &TestHostInfo{prioritized, hoststates}
 }
 
 // First we stub out the HostInfo to return static data
 type TestHostInfo struct {
 	prioritized []*host.Host
-	services    map[*host.Host][]string
+	services    map[*host.Host][]*service.Service
 }
 
 // Just satisfy the interface; we're prioritizing explicitly in the test
-func (t *TestHostInfo) AvailableRAM(h *host.Host, c chan *hostitem, d <-chan bool) {}
+func (t *TestHostInfo) AvailableResources(h *host.Host, c chan *hostitem, d <-chan bool) {}
 
 // Return the list of hosts prioritized with no modification (ignore what's passed in)
-func (t *TestHostInfo) PrioritizeByMemory(hosts []*host.Host) ([]*host.Host, error) {
+func (t *TestHostInfo) PrioritizeByResources(hosts []*host.Host) ([]*host.Host, error) {
 	return t.prioritized, nil
 }
 
@@ -49,13 +49,13 @@ func (t *TestHostInfo) PrioritizeByMemory(hosts []*host.Host) ([]*host.Host, err
 func (t *TestHostInfo) ServicesOnHost(h *host.Host) []*dao.RunningService {
 	result := []*dao.RunningService{}
 	for _, s := range t.services[h] {
-		result = append(result, &dao.RunningService{ServiceID: s})
+		result = append(result, &dao.RunningService{ServiceID: s.ID, Name: s.Name})
 	}
 	return result
 }
 
 func (t *TestHostInfo) addServiceToHost(svc *service.Service, h *host.Host) {
-	t.services[h] = append(t.services[h], svc.ID)
+	t.services[h] = append(t.services[h], svc)
 }
 
 func TestLeastCommitted(t *testing.T) {
@@ -111,3 +111,121 @@ func TestRequireSeparate(t *testing.T) {
 		t.Fatalf("Should have received an error but didn't")
 	}
 }
+
+func TestRequiredLabels(t *testing.T) {
+	BeforeEach()
+	least.Labels = map[string]string{"disk": "ssd", "zone": "a"}
+	middlest.Labels = map[string]string{"disk": "ssd"}
+	svc := service.Service{
+		HostPolicy:   servicedefinition.LeastCommitted,
+		HostSelector: servicedefinition.HostSelector{RequiredLabels: map[string]string{"disk": "ssd"}},
+	}
+	policy := ServiceHostPolicy{&svc, testinfo}
+
+	if h, _ := policy.SelectHost(unprioritized); h != middlest {
+		t.Fatalf("Expected middlest host but got %s", h.ID)
+	}
+
+	// Empty values match any host with the label
+	svc.HostSelector.RequiredLabels = map[string]string{"zone": ""}
+	if h, _ := policy.SelectHost(unprioritized); h != least {
+		t.Fatalf("Expected least host but got %s", h.ID)
+	}
+
+	// No host has the label
+	svc.HostSelector.RequiredLabels = map[string]string{"disk": "spinning"}
+	if _, err := policy.SelectHost(unprioritized); err == nil {
+		t.Fatalf("Should have received an error but didn't")
+	}
+}
+
+func TestPreferredLabels(t *testing.T) {
+	BeforeEach()
+	least.Labels = map[string]string{"disk": "ssd", "zone": "a"}
+	middlest.Labels = map[string]string{"disk": "ssd"}
+	svc := service.Service{
+		HostPolicy:   servicedefinition.LeastCommitted,
+		HostSelector: servicedefinition.HostSelector{PreferredLabels: map[string]string{"disk": "ssd", "zone": "a"}},
+	}
+	policy := ServiceHostPolicy{&svc, testinfo}
+
+	if h, _ := policy.SelectHost(unprioritized); h != least {
+		t.Fatalf("Expected least host but got %s", h.ID)
+	}
+
+	// Ties are broken by available memory
+	svc.HostSelector.PreferredLabels = map[string]string{"disk": "ssd"}
+	if h, _ := policy.SelectHost(unprioritized); h != middlest {
+		t.Fatalf("Expected middlest host but got %s", h.ID)
+	}
+
+	// Fall back to memory when no host has the label
+	svc.HostSelector.PreferredLabels = map[string]string{"disk": "spinning"}
+	if h, _ := policy.SelectHost(unprioritized); h != most {
+		t.Fatalf("Expected most host but got %s", h.ID)
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	BeforeEach()
+	zope := service.Service{ID: "zope", Name: "zope"}
+	svc := service.Service{
+		HostPolicy:   servicedefinition.LeastCommitted,
+		HostSelector: servicedefinition.HostSelector{Affinity: []string{"zope"}},
+	}
+	policy := ServiceHostPolicy{&svc, testinfo}
+
+	// No host is running zope
+	if h, _ := policy.SelectHost(unprioritized); h != most {
+		t.Fatalf("Expected most host but got %s", h.ID)
+	}
+
+	testinfo.addServiceToHost(&zope, least)
+	if h, _ := policy.SelectHost(unprioritized); h != least {
+		t.Fatalf("Expected least host but got %s", h.ID)
+	}
+}
+
+func TestAntiAffinity(t *testing.T) {
+	BeforeEach()
+	mysql := service.Service{ID: "mysql", Name: "mysql"}
+	svc := service.Service{
+		HostPolicy:   servicedefinition.LeastCommitted,
+		HostSelector: servicedefinition.HostSelector{AntiAffinity: []string{"mysql"}},
+	}
+	policy := ServiceHostPolicy{&svc, testinfo}
+
+	testinfo.addServiceToHost(&mysql, most)
+	if h, _ := policy.SelectHost(unprioritized); h != middlest {
+		t.Fatalf("Expected middlest host but got %s", h.ID)
+	}
+
+	testinfo.addServiceToHost(&mysql, middlest)
+	testinfo.addServiceToHost(&mysql, least)
+	if _, err := policy.SelectHost(unprioritized); err == nil {
+		t.Fatalf("Should have received an error but didn't")
+	}
+}
+
+func TestSelectorWithRequireSeparate(t *testing.T) {
+	BeforeEach()
+	least.Labels = map[string]string{"disk": "ssd"}
+	middlest.Labels = map[string]string{"disk": "ssd"}
+	svc := service.Service{
+		ID:           "svc",
+		HostPolicy:   servicedefinition.RequireSeparate,
+		HostSelector: servicedefinition.HostSelector{RequiredLabels: map[string]string{"disk": "ssd"}},
+	}
+	policy := ServiceHostPolicy{&svc, testinfo}
+
+	testinfo.addServiceToHost(&svc, middlest)
+	if h, _ := policy.SelectHost(unprioritized); h != least {
+		t.Fatalf("Expected least host but got %s", h.ID)
+	}
+
+	// The only other host doesn't have the required label
+	testinfo.addServiceToHost(&svc, least)
+	if _, err := policy.SelectHost(unprioritized); err == nil {
+		t.Fatalf("Should have received an error but didn't")
+	}
+}
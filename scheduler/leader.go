@@ -22,6 +22,8 @@ import (
 	"github.com/control-center/serviced/facade"
 	"github.com/control-center/serviced/utils"
 	"github.com/control-center/serviced/zzk"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 	zkservice "github.com/control-center/serviced/zzk/service"
 	"github.com/control-center/serviced/zzk/snapshot"
 	"github.com/control-center/serviced/zzk/virtualips"
@@ -35,13 +37,21 @@ type leader struct {
 	context      datastore.Context
 	poolID       string
 	hostRegistry *zkservice.HostRegistryListener
+	drainer      *drainer
+	restarts     *restartTracker
 }
 
 // Lead is executed by the "leader" of the control plane cluster to handle its management responsibilities of:
 //    services
 //    snapshots
+//    rolling restarts
+//    autoscaling
+//    scheduled snapshots
+//    threshold events
+//    missed health checks
+//    restarts of instances that fail their health checks
 //    virtual IPs
-func Lead(facade *facade.Facade, dao dao.ControlPlane, conn coordclient.Connection, zkEvent <-chan coordclient.Event, poolID string, shutdown <-chan interface{}) {
+func Lead(facade *facade.Facade, dao dao.ControlPlane, conn coordclient.Connection, zkEvent <-chan coordclient.Event, poolID string, drainTimeout time.Duration, shutdown <-chan interface{}) {
 	glog.V(0).Info("Entering Lead()!")
 	defer glog.V(0).Info("Exiting Lead()!")
 	shutdownmode := false
@@ -52,8 +62,9 @@ func Lead(facade *facade.Facade, dao dao.ControlPlane, conn coordclient.Connecti
 		return
 	}
 
-	leader := leader{facade: facade, dao: dao, conn: conn, context: datastore.Get(), poolID: poolID, hostRegistry: hostRegistry}
+	ctx := datastore.WithCaller(datastore.Get(), datastore.Caller{Source: datastore.SourceScheduler})
 	var wg sync.WaitGroup
+	leader := leader{facade: facade, dao: dao, conn: conn, context: ctx, poolID: poolID, hostRegistry: hostRegistry, drainer: newDrainer(drainTimeout, shutdown, &wg), restarts: newRestartTracker()}
 	for {
 		done := make(chan interface{})
 		if shutdownmode {
@@ -84,6 +95,55 @@ func Lead(facade *facade.Facade, dao dao.ControlPlane, conn coordclient.Connecti
 			wg.Done()
 		}()
 
+		// creates a listener for rolling restarts, which also resumes any
+		// rollout left unfinished by a previous leader
+		rolloutListener := rollout.NewRolloutListener(conn, &leader)
+		wg.Add(1)
+		go func() {
+			glog.Info("rolloutListener starting")
+			rolloutListener.Listen(done)
+			glog.Info("rolloutListener stopped")
+			wg.Done()
+		}()
+
+		// evaluates the scaling policies of the services in the pool
+		wg.Add(1)
+		go func() {
+			glog.Info("autoscaler starting")
+			leader.autoscale(done)
+			glog.Info("autoscaler stopped")
+			wg.Done()
+		}()
+
+		// takes and prunes the scheduled snapshots of the tenants in the pool
+		wg.Add(1)
+		go func() {
+			glog.Info("snapshot scheduler starting")
+			leader.snapshotSchedule(done)
+			glog.Info("snapshot scheduler stopped")
+			wg.Done()
+		}()
+
+		// raises and clears the events of the thresholds of the pool, its
+		// hosts and its services
+		wg.Add(1)
+		go func() {
+			glog.Info("threshold evaluator starting")
+			leader.evaluateThresholds(done)
+			glog.Info("threshold evaluator stopped")
+			wg.Done()
+		}()
+
+		// marks the health checks that stopped reporting as missed, and
+		// restarts the instances that keep failing them
+		wg.Add(1)
+		go func() {
+			glog.Info("health watcher starting")
+			leader.watchHealth(done)
+			glog.Info("health watcher stopped")
+			wg.Done()
+		}()
+
 		// starts a listener for the host registry
 		wg.Add(1)
 		go func() {
@@ -233,12 +293,20 @@ func (l *leader) watchService(shutdown <-chan int, done chan<- string, serviceID
 			return
 		}
 
-		// Is the service supposed to be running at all?
+		// Is the service supposed to be running at all? Services wait on their
+		// dependencies to start and their dependents to stop.
+		var retry <-chan time.Time
 		switch {
 		case svc.DesiredState == service.SVCStop:
-			shutdownServiceInstances(l.conn, serviceStates, len(serviceStates))
+			if len(serviceStates) > 0 && !l.dependentsStopped(&svc) {
+				retry = time.After(dependencyPollInterval)
+			} else {
+				l.shutdownServiceInstances(serviceStates, len(serviceStates))
+			}
 		case svc.DesiredState == service.SVCRun:
-			if err := l.updateServiceInstances(&svc, serviceStates); err != nil {
+			if len(serviceStates) == 0 && svc.Instances > 0 && !l.dependenciesReady(&svc) {
+				retry = time.After(dependencyPollInterval)
+			} else if err := l.updateServiceInstances(&svc, serviceStates); err != nil {
 				glog.Errorf("%v", err)
 			}
 		default:
@@ -249,7 +317,7 @@ func (l *leader) watchService(shutdown <-chan int, done chan<- string, serviceID
 		case evt := <-zkEvent:
 			if evt.Type == coordclient.EventNodeDeleted {
 				glog.V(0).Info("Shutting down due to node delete ", serviceID)
-				shutdownServiceInstances(l.conn, serviceStates, len(serviceStates))
+				l.shutdownServiceInstances(serviceStates, len(serviceStates))
 				return
 			}
 			glog.V(1).Infof("Service %s received event: %v", svc.Name, evt)
@@ -259,6 +327,10 @@ func (l *leader) watchService(shutdown <-chan int, done chan<- string, serviceID
 			glog.V(1).Infof("Service %s received child event: %v", svc.Name, evt)
 			continue
 
+		case <-retry:
+			glog.V(2).Infof("Service %s checking its dependencies again", svc.Name)
+			continue
+
 		case <-shutdown:
 			glog.V(1).Info("Leader stopping watch on ", svc.Name)
 			return
@@ -288,7 +360,7 @@ func (l *leader) updateServiceInstances(service *service.Service, serviceStates
 
 	if instancesToKill > 0 {
 		glog.V(2).Infof("updateServiceInstances wants to kill %d instances", instancesToKill)
-		shutdownServiceInstances(l.conn, serviceStates, instancesToKill)
+		l.shutdownServiceInstances(serviceStates, instancesToKill)
 	} else if instancesToStart > 0 {
 		//Note: This must not be a separate 'if' statement. Since killing instances is an asynchronous operation,
 		//	 	multple zk updates come through this method. This causes a race condition and runaway instance
@@ -303,6 +375,7 @@ func (l *leader) updateServiceInstances(service *service.Service, serviceStates
 		if len(hosts) == 0 {
 			glog.Warningf("Pool %s has no hosts", service.PoolID)
 		} else {
+			l.loadHostLabels(hosts)
 			err = l.startServiceInstances(service, hosts, instancesToStart)
 			if err != nil {
 				glog.Errorf("Leader unable to start %d instances of service %s: %v", instancesToStart, service.ID, err)
@@ -313,6 +386,26 @@ func (l *leader) updateServiceInstances(service *service.Service, serviceStates
 	return nil
 }
 
+// loadHostLabels refreshes the labels of the registered hosts from the
+// datastore, since labels may have been changed after a host registered. The
+// hosts of the pool are loaded with a single query for each scheduling pass.
+func (l *leader) loadHostLabels(hosts []*host.Host) {
+	stored, err := l.facade.FindHostsInPool(l.context, l.poolID)
+	if err != nil {
+		glog.Warningf("Unable to load labels for hosts in pool %s: %v", l.poolID, err)
+		return
+	}
+	labels := make(map[string]map[string]string)
+	for _, h := range stored {
+		labels[h.ID] = h.Labels
+	}
+	for _, h := range hosts {
+		if hostLabels, ok := labels[h.ID]; ok {
+			h.Labels = hostLabels
+		}
+	}
+}
+
 // TODO: move me into zzk
 // getFreeInstanceIDs looks up running instances of this service and returns n
 // unused instance ids.
@@ -355,7 +448,13 @@ func (l *leader) startServiceInstances(svc *service.Service, hosts []*host.Host,
 		return err
 	}
 
-	hostPolicy := NewServiceHostPolicy(svc, l.dao)
+	myPool, err := l.facade.GetResourcePool(l.context, svc.PoolID)
+	if err != nil {
+		glog.Errorf("Unable to load resource pool %s: %v", svc.PoolID, err)
+		return err
+	}
+
+	hostPolicy := NewServiceHostPolicy(svc, l.dao, myPool)
 
 	// Start up an instance per id
 	for _, i := range freeids {
@@ -373,6 +472,13 @@ func (l *leader) startServiceInstances(svc *service.Service, hosts []*host.Host,
 
 		serviceState.HostIP = servicehost.IPAddr
 		serviceState.InstanceID = i
+		if l.restarts.take(serviceState) {
+			// the instance that was restarted has stopped; its failures are
+			// not held against its replacement
+			if err := zkhealth.ResetFailures(l.conn, svc.ID, i); err != nil {
+				glog.Warningf("Unable to reset the health check failures of instance %d of service %s: %v", i, svc.Name, err)
+			}
+		}
 		err = zzk.AddServiceState(l.conn, serviceState)
 		if err != nil {
 			glog.Errorf("Leader unable to add service state: %v", err)
@@ -384,24 +490,21 @@ func (l *leader) startServiceInstances(svc *service.Service, hosts []*host.Host,
 }
 
 // TODO: move me into zzk?
-func shutdownServiceInstances(conn coordclient.Connection, serviceStates []*servicestate.ServiceState, numToKill int) {
+func (l *leader) shutdownServiceInstances(serviceStates []*servicestate.ServiceState, numToKill int) {
 	glog.V(2).Infof("Stopping %d instances from %d total", numToKill, len(serviceStates))
 	maxId := len(serviceStates) - numToKill - 1
 	for i := 0; i < len(serviceStates); i++ {
 		// Kill all instances with an ID > maxId - leaving instances with IDs [0 - Instances-1] running
 		if serviceStates[i].InstanceID > maxId {
-			glog.V(2).Infof("Killing host service state %s:%s\n", serviceStates[i].HostID, serviceStates[i].ID)
 			serviceStates[i].Terminated = time.Date(2, time.January, 1, 0, 0, 0, 0, time.UTC)
-			if err := zkservice.StopServiceInstance(conn, serviceStates[i].HostID, serviceStates[i].ID); err != nil {
-				glog.Warningf("%s:%s wouldn't die", serviceStates[i].HostID, serviceStates[i].ID)
-			}
+			l.stopServiceInstance(serviceStates[i])
 		}
 	}
 }
 
 // selectPoolHostForService chooses a host from the pool for the specified service. If the service
 // has an address assignment the host will already be selected. If not the host with the least amount
-// of memory committed to running containers will be chosen.
+// of memory and cores committed to running containers will be chosen.
 func (l *leader) selectPoolHostForService(s *service.Service, hosts []*host.Host, policy *ServiceHostPolicy) (*host.Host, error) {
 	var assignmentType string
 	var ipAddr string
@@ -14,11 +14,13 @@ import (
 // PriorityQueue implements the heap.Interface and holds hostitems
 type PriorityQueue []*hostitem
 
-// hostitem is what is stored in the least commited RAM scheduler's priority queue
+// hostitem is what is stored in the least commited resources scheduler's priority queue
 type hostitem struct {
 	host     *host.Host
-	priority uint64 // the host's available RAM
-	index    int    // the index of the hostitem in the heap
+	ram      int64   // the host's uncommitted RAM
+	cores    int64   // the host's uncommitted cores
+	priority float64 // the host's weighted share of uncommitted resources
+	index    int     // the index of the hostitem in the heap
 }
 
 // Len is the number of elements in the collection.
@@ -0,0 +1,101 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/zzk"
+)
+
+// how often to check on the instances being restarted by a rollout
+var rolloutPollInterval = 5 * time.Second
+
+// RestartInstances implements rollout.RolloutHandler. Instances that were
+// scheduled before the batch started are stopped, and the leader's service
+// watch schedules their replacements. A replacement is ready once it has
+// started and passed each of the service's health checks.
+func (l *leader) RestartInstances(serviceID string, instanceIDs []int, since time.Time, timeout time.Duration, cancel <-chan interface{}) error {
+	var svc service.Service
+	if err := l.dao.GetService(serviceID, &svc); err != nil {
+		return err
+	}
+	checks := healthCheckNames(&svc)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	stopped := make(map[string]struct{})
+	for {
+		var states []*servicestate.ServiceState
+		if err := zzk.GetServiceStates(l.conn, &states, serviceID); err != nil {
+			return err
+		}
+
+		ready := 0
+		for _, id := range instanceIDs {
+			state := findInstance(states, id)
+			switch {
+			case state == nil:
+				// waiting for the replacement to be scheduled
+			case state.Scheduled.Before(since):
+				if _, ok := stopped[state.ID]; !ok {
+					glog.V(2).Infof("Stopping instance %d of service %s for rollout", id, serviceID)
+					l.stopServiceInstance(state)
+					stopped[state.ID] = struct{}{}
+				}
+			case state.Started.IsZero():
+				// waiting for the replacement to start
+			case l.instanceHealthy(l.conn, serviceID, id, checks, state.Scheduled):
+				ready++
+			}
+		}
+
+		if ready == len(instanceIDs) {
+			return nil
+		}
+
+		select {
+		case <-time.After(rolloutPollInterval):
+		case <-expired:
+			return fmt.Errorf("instances %v of service %s did not pass their health checks within %s", instanceIDs, svc.Name, timeout)
+		case <-cancel:
+			return nil
+		}
+	}
+}
+
+// StopInstances implements rollout.RolloutHandler. The instances are drained
+// and stopped as any instance the leader stops, and the leader's service watch
+// schedules their replacements.
+func (l *leader) StopInstances(serviceID string, instanceIDs []int) error {
+	var states []*servicestate.ServiceState
+	if err := zzk.GetServiceStates(l.conn, &states, serviceID); err != nil {
+		return err
+	}
+	for _, id := range instanceIDs {
+		if state := findInstance(states, id); state != nil {
+			glog.V(2).Infof("Stopping instance %d of service %s for restart", id, serviceID)
+			l.stopServiceInstance(state)
+		}
+	}
+	return nil
+}
+
+// findInstance returns the state of the instance with the given id
+func findInstance(states []*servicestate.ServiceState, instanceID int) *servicestate.ServiceState {
+	for _, state := range states {
+		if state.InstanceID == instanceID {
+			return state
+		}
+	}
+	return nil
+}
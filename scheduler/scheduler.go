@@ -18,7 +18,7 @@ import (
 	"time"
 )
 
-type leaderFunc func(*facade.Facade, dao.ControlPlane, coordclient.Connection, <-chan coordclient.Event, string, <-chan interface{})
+type leaderFunc func(*facade.Facade, dao.ControlPlane, coordclient.Connection, <-chan coordclient.Event, string, time.Duration, <-chan interface{})
 
 type scheduler struct {
 	cpDao        dao.ControlPlane // ControlPlane interface
@@ -29,9 +29,10 @@ type scheduler struct {
 	started      bool             // is the loop running
 	zkleaderFunc leaderFunc       // multiple implementations of leader function possible
 	facade       *facade.Facade
+	drainTimeout time.Duration // how long stopping instances are drained
 }
 
-func NewScheduler(cluster_path string, instance_id string, cpDao dao.ControlPlane, facade *facade.Facade) (s *scheduler, shutdown <-chan error) {
+func NewScheduler(cluster_path string, instance_id string, cpDao dao.ControlPlane, facade *facade.Facade, drainTimeout time.Duration) (s *scheduler, shutdown <-chan error) {
 	s = &scheduler{
 		cpDao:        cpDao,
 		cluster_path: cluster_path,
@@ -40,6 +41,7 @@ func NewScheduler(cluster_path string, instance_id string, cpDao dao.ControlPlan
 		shutdown:     make(chan error, 1),
 		zkleaderFunc: Lead, // random scheduler implementation
 		facade:       facade,
+		drainTimeout: drainTimeout,
 	}
 	return s, s.shutdown
 }
@@ -130,7 +132,7 @@ func (s *scheduler) loop() {
 		glog.Infof(" Creating a leader for pool: %v --- %+v", aPool.ID, poolBasedConn)
 		wg.Add(1)
 		go func(conn coordclient.Connection, zkevents <-chan coordclient.Event, poolID string) {
-			s.zkleaderFunc(s.facade, s.cpDao, conn, zkevents, poolID, stop)
+			s.zkleaderFunc(s.facade, s.cpDao, conn, zkevents, poolID, s.drainTimeout, stop)
 			glog.Infof("Leader done for pool: %v --- %+v", poolID, conn)
 			wg.Done()
 		}(poolBasedConn, events, aPool.ID)
@@ -0,0 +1,146 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/commons/cron"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/zzk/snapshotschedule"
+)
+
+// how often the snapshot schedules are evaluated
+var snapshotScheduleInterval = time.Minute
+
+// snapshotSchedule periodically takes the snapshots that the snapshot
+// policies of the tenants in the leader's pool have scheduled, and prunes the
+// ones their retention rules no longer keep
+func (l *leader) snapshotSchedule(shutdown <-chan interface{}) {
+	for {
+		select {
+		case <-time.After(snapshotScheduleInterval):
+		case <-shutdown:
+			return
+		}
+
+		services, err := l.facade.GetServicesByPool(l.context, l.poolID)
+		if err != nil {
+			glog.Warningf("Snapshot scheduler unable to load services: %v", err)
+			continue
+		}
+
+		for _, svc := range services {
+			if svc.ParentServiceID != "" || !svc.SnapshotPolicy.Enabled() {
+				continue
+			}
+			l.runSnapshotPolicy(svc, time.Now().UTC())
+		}
+	}
+}
+
+// runSnapshotPolicy takes a snapshot of a tenant if its schedule is due, and
+// records the run
+func (l *leader) runSnapshotPolicy(tenant *service.Service, now time.Time) {
+	policy := tenant.SnapshotPolicy
+	schedule, err := cron.Parse(policy.Schedule)
+	if err != nil {
+		glog.Warningf("Snapshot scheduler unable to parse schedule of tenant %s: %v", tenant.Name, err)
+		return
+	}
+
+	status, err := snapshotschedule.Get(l.conn, tenant.ID)
+	if err != nil {
+		glog.Warningf("Snapshot scheduler unable to load status of tenant %s: %v", tenant.Name, err)
+		return
+	} else if status == nil {
+		// the schedule starts from the first time it is seen
+		status = &snapshotschedule.Status{TenantID: tenant.ID, Started: now}
+		if err := snapshotschedule.Set(l.conn, status); err != nil {
+			glog.Warningf("Snapshot scheduler unable to record status of tenant %s: %v", tenant.Name, err)
+		}
+		return
+	}
+
+	if next := status.Next(schedule); next.IsZero() || now.Before(next) {
+		return
+	}
+
+	status.LastRun = now
+	status.LastSnapshot = ""
+	status.Err = ""
+	status.Pruned = nil
+
+	glog.Infof("Snapshot scheduler taking a snapshot of tenant %s", tenant.Name)
+	if label, err := l.TakeSnapshot(tenant.ID); err != nil {
+		glog.Warningf("Snapshot scheduler unable to snapshot tenant %s: %v", tenant.Name, err)
+		status.Err = err.Error()
+	} else {
+		status.LastSnapshot = label
+		status.Snapshots = append(status.Snapshots, snapshotschedule.Snapshot{ID: label, Created: now})
+		l.facade.Audit(l.context, "add", "snapshot", label, nil, struct{ ServiceID string }{tenant.ID})
+	}
+
+	l.pruneSnapshots(tenant, status, now)
+	if err := snapshotschedule.Set(l.conn, status); err != nil {
+		glog.Warningf("Snapshot scheduler unable to record status of tenant %s: %v", tenant.Name, err)
+	}
+}
+
+// pruneSnapshots removes the scheduled snapshots of a tenant that the
+// retention rules of its policy no longer keep. Snapshots that were removed
+// by other means are forgotten.
+func (l *leader) pruneSnapshots(tenant *service.Service, status *snapshotschedule.Status, now time.Time) {
+	var labels []string
+	if err := l.dao.Snapshots(tenant.ID, &labels); err != nil {
+		glog.Warningf("Snapshot scheduler unable to list snapshots of tenant %s: %v", tenant.Name, err)
+		if status.Err == "" {
+			status.Err = err.Error()
+		}
+		return
+	}
+	exists := make(map[string]bool)
+	for _, label := range labels {
+		exists[label] = true
+	}
+
+	var (
+		snapshots []snapshotschedule.Snapshot
+		created   []time.Time
+	)
+	for _, snapshot := range status.Snapshots {
+		if exists[snapshot.ID] {
+			snapshots = append(snapshots, snapshot)
+			created = append(created, snapshot.Created)
+		}
+	}
+
+	expired := make(map[int]bool)
+	for _, i := range tenant.SnapshotPolicy.Expired(created, now) {
+		expired[i] = true
+	}
+
+	status.Snapshots = nil
+	for i, snapshot := range snapshots {
+		if !expired[i] {
+			status.Snapshots = append(status.Snapshots, snapshot)
+			continue
+		}
+
+		var unused int
+		if err := l.dao.DeleteSnapshot(snapshot.ID, &unused); err != nil {
+			glog.Warningf("Snapshot scheduler unable to remove snapshot %s of tenant %s: %v", snapshot.ID, tenant.Name, err)
+			if status.Err == "" {
+				status.Err = err.Error()
+			}
+			// try again on the next run
+			status.Snapshots = append(status.Snapshots, snapshot)
+			continue
+		}
+		glog.Infof("Snapshot scheduler removed snapshot %s of tenant %s", snapshot.ID, tenant.Name)
+		status.Pruned = append(status.Pruned, snapshot.ID)
+	}
+}
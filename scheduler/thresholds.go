@@ -0,0 +1,215 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package scheduler
+
+import (
+	"path"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/domain"
+	"github.com/control-center/serviced/domain/event"
+	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/metrics"
+)
+
+// how often the thresholds of the monitoring profiles are evaluated
+var thresholdInterval = time.Minute
+
+// How a threshold is applied, by ThresholdConfig.AppliedTo
+const (
+	appliedToEverything      = 0
+	appliedToServices        = 1
+	appliedToRunningServices = 2
+)
+
+// monitoredEntity is a host, pool or service whose thresholds are evaluated
+type monitoredEntity struct {
+	entityType string
+	entityID   string
+	tags       map[string][]string // tags that select the metrics of the entity
+	profile    domain.MonitorProfile
+	running    bool
+}
+
+// applies is true if the threshold is evaluated for the entity
+func (m *monitoredEntity) applies(config domain.ThresholdConfig) bool {
+	switch config.AppliedTo {
+	case appliedToEverything:
+		return true
+	case appliedToServices:
+		return m.entityType == event.EntityService
+	case appliedToRunningServices:
+		return m.entityType == event.EntityService && m.running
+	}
+	return false
+}
+
+// metrics returns the metrics the threshold is evaluated on: its data points,
+// or every metric of its metric source if it names none
+func (m *monitoredEntity) metrics(config domain.ThresholdConfig) []string {
+	if len(config.DataPoints) > 0 {
+		return config.DataPoints
+	}
+	var names []string
+	for _, metricConfig := range m.profile.MetricConfigs {
+		if metricConfig.ID == config.MetricSource {
+			for _, metric := range metricConfig.Metrics {
+				names = append(names, metric.ID)
+			}
+		}
+	}
+	return names
+}
+
+func eventKey(entityType, entityID, thresholdID string) string {
+	return path.Join(entityType, entityID, thresholdID)
+}
+
+// evaluateThresholds periodically evaluates the thresholds of the leader's
+// pool, of its hosts and of its services, raising an event while a threshold
+// is breached and clearing it once it is not
+func (l *leader) evaluateThresholds(shutdown <-chan interface{}) {
+	client := metrics.NewClient(metrics.DefaultAddress)
+	for {
+		select {
+		case <-time.After(thresholdInterval):
+		case <-shutdown:
+			return
+		}
+
+		entities, err := l.monitoredEntities()
+		if err != nil {
+			glog.Warningf("Threshold evaluator unable to load the entities of pool %s: %v", l.poolID, err)
+			continue
+		}
+
+		evaluated := make(map[string]bool)
+		for _, entity := range entities {
+			for _, config := range entity.profile.ThresholdConfigs {
+				if !entity.applies(config) {
+					continue
+				}
+				evaluated[eventKey(entity.entityType, entity.entityID, config.ID)] = true
+				l.evaluateThreshold(client, entity, config)
+			}
+		}
+		l.clearUnevaluatedEvents(evaluated)
+	}
+}
+
+// monitoredEntities returns the leader's pool, its hosts and its services
+func (l *leader) monitoredEntities() ([]*monitoredEntity, error) {
+	var entities []*monitoredEntity
+
+	hosts, err := l.facade.FindHostsInPool(l.context, l.poolID)
+	if err != nil {
+		return nil, err
+	}
+	hostIDs := make([]string, len(hosts))
+	for i, h := range hosts {
+		hostIDs[i] = h.ID
+		entities = append(entities, &monitoredEntity{
+			entityType: event.EntityHost,
+			entityID:   h.ID,
+			tags:       map[string][]string{"controlplane_host_id": []string{h.ID}},
+			profile:    h.MonitoringProfile,
+		})
+	}
+
+	if p, err := l.facade.GetResourcePool(l.context, l.poolID); err != nil {
+		return nil, err
+	} else if p != nil && len(hostIDs) > 0 {
+		entities = append(entities, &monitoredEntity{
+			entityType: event.EntityPool,
+			entityID:   p.ID,
+			tags:       map[string][]string{"controlplane_host_id": hostIDs},
+			profile:    p.MonitoringProfile,
+		})
+	}
+
+	services, err := l.facade.GetServicesByPool(l.context, l.poolID)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		entities = append(entities, &monitoredEntity{
+			entityType: event.EntityService,
+			entityID:   svc.ID,
+			tags:       map[string][]string{"controlplane_service_id": []string{svc.ID}},
+			profile:    svc.MonitoringProfile,
+			running:    svc.DesiredState == service.SVCRun && svc.Instances > 0,
+		})
+	}
+	return entities, nil
+}
+
+// evaluateThreshold queries the metrics of a threshold of an entity, and
+// raises its event if any of them breaches it. The event is cleared once none
+// do; when there are no values to tell, the event is left as it is.
+func (l *leader) evaluateThreshold(client *metrics.Client, entity *monitoredEntity, config domain.ThresholdConfig) {
+	evaluator, err := metrics.NewEvaluator(config)
+	if err != nil {
+		glog.Warningf("Threshold evaluator skipping threshold %s of %s %s: %v", config.ID, entity.entityType, entity.entityID, err)
+		return
+	}
+	start, downsample := evaluator.Window()
+
+	var breach *metrics.Breach
+	var decided bool
+	for _, metric := range entity.metrics(config) {
+		values, err := client.Series(metric, entity.tags, start, downsample)
+		if err != nil {
+			glog.Warningf("Threshold evaluator unable to query metric %s of %s %s: %v", metric, entity.entityType, entity.entityID, err)
+			return
+		}
+		b, ok := evaluator.Evaluate(metric, values)
+		decided = decided || ok
+		if b != nil {
+			breach = b
+			break
+		}
+	}
+
+	if breach != nil {
+		e := event.Event{
+			EntityType:  entity.entityType,
+			EntityID:    entity.entityID,
+			PoolID:      l.poolID,
+			ThresholdID: config.ID,
+			Threshold:   config.Name,
+			Metric:      breach.Metric,
+			Value:       breach.Value,
+			Summary:     breach.Summary,
+			Tags:        config.EventTags,
+		}
+		if _, err := l.facade.RaiseEvent(l.context, e); err != nil {
+			glog.Warningf("Threshold evaluator unable to raise event for threshold %s of %s %s: %v", config.ID, entity.entityType, entity.entityID, err)
+		}
+	} else if decided {
+		if _, err := l.facade.ClearEvent(l.context, entity.entityType, entity.entityID, config.ID); err != nil {
+			glog.Warningf("Threshold evaluator unable to clear event for threshold %s of %s %s: %v", config.ID, entity.entityType, entity.entityID, err)
+		}
+	}
+}
+
+// clearUnevaluatedEvents clears the active events of the pool whose
+// thresholds were not evaluated, because the threshold or its entity was
+// removed, or it no longer applies
+func (l *leader) clearUnevaluatedEvents(evaluated map[string]bool) {
+	active, err := l.facade.GetEvents(l.context, event.Filter{PoolID: l.poolID, Active: true, Limit: 10000})
+	if err != nil {
+		glog.Warningf("Threshold evaluator unable to load the events of pool %s: %v", l.poolID, err)
+		return
+	}
+	for _, e := range active {
+		if evaluated[eventKey(e.EntityType, e.EntityID, e.ThresholdID)] {
+			continue
+		}
+		if _, err := l.facade.ClearEvent(l.context, e.EntityType, e.EntityID, e.ThresholdID); err != nil {
+			glog.Warningf("Threshold evaluator unable to clear event %s: %v", e.ID, err)
+		}
+	}
+}
@@ -211,6 +211,35 @@ func (c *BtrfsConn) Rollback(label string) error {
 	return err
 }
 
+// Send writes a snapshot to a file as a btrfs send stream. The stream has the
+// changes from the parent snapshot, or the whole snapshot if there is none.
+func (c *BtrfsConn) Send(label, parent, filename string) error {
+	c.Lock()
+	defer c.Unlock()
+	args := []string{"send", "-f", filename}
+	if parent != "" {
+		args = append(args, "-p", c.SnapshotPath(parent))
+	}
+	args = append(args, c.SnapshotPath(label))
+	if output, err := runcmd(c.sudoer, args...); err != nil {
+		glog.Errorf("Could not send snapshot %s: %s", label, string(output))
+		return fmt.Errorf("could not send snapshot %s (%v)", label, err)
+	}
+	return nil
+}
+
+// Receive adds the snapshot in a btrfs send stream to the volume. The parent
+// snapshot of the stream, if any, must already be there.
+func (c *BtrfsConn) Receive(filename string) error {
+	c.Lock()
+	defer c.Unlock()
+	if output, err := runcmd(c.sudoer, "receive", "-f", filename, c.root); err != nil {
+		glog.Errorf("Could not receive %s: %s", filename, string(output))
+		return fmt.Errorf("could not receive %s (%v)", filename, err)
+	}
+	return nil
+}
+
 // snapshotExists queries the snapshot existence for the given label
 func (c *BtrfsConn) snapshotExists(label string) (exists bool, err error) {
 	if snapshots, err := c.Snapshots(); err != nil {
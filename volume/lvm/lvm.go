@@ -0,0 +1,463 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Package lvm is a volume driver that keeps each volume on a thin volume of an
+// LVM thin pool. Snapshots are thin snapshots, so taking and rolling back to
+// one is instant, however large the volume. Each snapshot is mounted read only
+// at its SnapshotPath. Volumes and snapshots are named after their logical
+// volumes, so their names are unique across the volume group.
+package lvm
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/volume"
+
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// DriverName is the name of this lvm volume driver implementation
+	DriverName = "lvm"
+
+	// ThinPoolEnv names the thin pool of the registered driver, as
+	// VOLUMEGROUP/THINPOOL
+	ThinPoolEnv = "SERVICED_LVM_THINPOOL"
+
+	// VolumeSizeEnv sets the virtual size of the volumes of the registered
+	// driver
+	VolumeSizeEnv = "SERVICED_LVM_VOLUME_SIZE"
+
+	defaultThinPool   = "serviced/thinpool"
+	defaultVolumeSize = "100G"
+	fsType            = "ext4"
+)
+
+// validName matches the names lvm allows for logical volumes
+var validName = regexp.MustCompile(`^[a-zA-Z0-9+_.][a-zA-Z0-9+_.-]*$`)
+
+// LVMDriver is a driver for volumes on an lvm thin pool
+type LVMDriver struct {
+	sudoer      bool
+	volumeGroup string
+	thinPool    string
+	size        string
+	sync.Mutex
+}
+
+// LVMConn is a connection to a volume on an lvm thin pool
+type LVMConn struct {
+	driver *LVMDriver
+	name   string
+	root   string
+	sync.Mutex
+}
+
+func init() {
+	lvmdriver, err := New()
+	if err != nil {
+		glog.Errorf("Can't create lvm driver: %s", err)
+		return
+	}
+
+	volume.Register(DriverName, lvmdriver)
+}
+
+// New creates a new LVMDriver on the thin pool set by the environment
+func New() (*LVMDriver, error) {
+	thinPool, size := os.Getenv(ThinPoolEnv), os.Getenv(VolumeSizeEnv)
+	if thinPool == "" {
+		thinPool = defaultThinPool
+	}
+	if size == "" {
+		size = defaultVolumeSize
+	}
+	parts := strings.Split(thinPool, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s must be VOLUMEGROUP/THINPOOL: %s", ThinPoolEnv, thinPool)
+	}
+	return NewThinPool(parts[0], parts[1], size)
+}
+
+// NewThinPool creates a new LVMDriver that creates volumes of the given
+// virtual size on a thin pool of a volume group
+func NewThinPool(volumeGroup, thinPool, size string) (*LVMDriver, error) {
+	user, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LVMDriver{volumeGroup: volumeGroup, thinPool: thinPool, size: size}
+	if user.Uid != "0" {
+		err := exec.Command("sudo", "-n", "lvs", "--version").Run()
+		result.sudoer = err == nil
+	}
+
+	return result, nil
+}
+
+// Mount creates a thin volume, if it does not exist yet, and mounts it and
+// its snapshots under the given root dir
+func (d *LVMDriver) Mount(volumeName, rootDir string) (volume.Conn, error) {
+	d.Lock()
+	defer d.Unlock()
+	if !validName.MatchString(volumeName) {
+		return nil, fmt.Errorf("invalid lvm volume name: %s", volumeName)
+	}
+
+	c := &LVMConn{driver: d, name: volumeName, root: rootDir}
+	if exists, err := d.exists(volumeName); err != nil {
+		return nil, err
+	} else if !exists {
+		if _, err := d.runcmd("lvcreate", "-V", d.size, "-T", d.thinPool, "-n", volumeName, d.volumeGroup); err != nil {
+			glog.Errorf("Could not create thin volume %s", volumeName)
+			return nil, fmt.Errorf("could not create thin volume: %s (%v)", volumeName, err)
+		}
+		if _, err := d.runcmd("mkfs."+fsType, "-q", "-E", "nodiscard", d.device(volumeName)); err != nil {
+			glog.Errorf("Could not make a filesystem on thin volume %s", volumeName)
+			return nil, fmt.Errorf("could not make a filesystem: %s (%v)", volumeName, err)
+		}
+	}
+
+	if err := d.mount(d.device(volumeName), c.Path()); err != nil {
+		return nil, err
+	}
+
+	// the snapshots of the volume are no longer mounted after a reboot
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range snapshots {
+		if err := d.mount(d.device(label), c.SnapshotPath(label), "-o", "ro,noload"); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// List returns the thin volumes on the thin pool
+func (d *LVMDriver) List(rootDir string) (result []string) {
+	volumes, err := d.volumes()
+	if err != nil {
+		glog.Errorf("Could not list thin volumes of %s/%s: %s", d.volumeGroup, d.thinPool, err)
+		return
+	}
+	for name := range volumes {
+		result = append(result, name)
+	}
+	return
+}
+
+// Name provides the name of the volume
+func (c *LVMConn) Name() string {
+	return c.name
+}
+
+// Path provides the full path to the volume
+func (c *LVMConn) Path() string {
+	return path.Join(c.root, c.name)
+}
+
+// SnapshotPath provides the full path to a snapshot of the volume
+func (c *LVMConn) SnapshotPath(label string) string {
+	return path.Join(c.root, label)
+}
+
+// Snapshot takes a thin snapshot of the volume and mounts it read only
+func (c *LVMConn) Snapshot(label string) error {
+	c.Lock()
+	defer c.Unlock()
+	if !validName.MatchString(label) {
+		return fmt.Errorf("invalid lvm snapshot label: %s", label)
+	}
+	if exists, err := c.driver.exists(label); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("snapshot %s already exists", label)
+	}
+
+	// freeze the filesystem so that the snapshot is consistent
+	if _, err := c.driver.runcmd("fsfreeze", "-f", c.Path()); err != nil {
+		return fmt.Errorf("could not freeze %s: %v", c.Path(), err)
+	}
+	_, err := c.driver.runcmd("lvcreate", "-s", "-kn", "-n", label, c.driver.volumeGroup+"/"+c.name)
+	if _, err := c.driver.runcmd("fsfreeze", "-u", c.Path()); err != nil {
+		glog.Errorf("Could not unfreeze %s: %s", c.Path(), err)
+	}
+	if err != nil {
+		return fmt.Errorf("could not snapshot %s: %v", c.name, err)
+	}
+	return c.driver.mount(c.driver.device(label), c.SnapshotPath(label), "-o", "ro,noload")
+}
+
+// Snapshots returns the current snapshots on the volume
+func (c *LVMConn) Snapshots() ([]string, error) {
+	volumes, err := c.driver.volumes()
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, 0)
+	prefixedName := c.name + "_"
+	for name := range volumes {
+		if strings.HasPrefix(name, prefixedName) {
+			labels = append(labels, name)
+		}
+	}
+	return labels, nil
+}
+
+// RemoveSnapshot unmounts and removes the snapshot with the given label
+func (c *LVMConn) RemoveSnapshot(label string) error {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.snapshotExists(label); err != nil {
+		return err
+	}
+	return c.driver.remove(label, c.SnapshotPath(label))
+}
+
+// Unmount removes the volume and its snapshots
+func (c *LVMConn) Unmount() error {
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		if err := c.RemoveSnapshot(snapshot); err != nil {
+			return err
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return c.driver.remove(c.name, c.Path())
+}
+
+// Rollback replaces the volume with a writable thin snapshot of the snapshot
+// with the given label. The snapshot is taken under a temporary name and
+// renamed into place, and the volume is only removed once it has been
+// replaced; if any step fails the volume is put back as it was.
+func (c *LVMConn) Rollback(label string) error {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.snapshotExists(label); err != nil {
+		return err
+	}
+
+	d := c.driver
+	rollback, previous := c.name+"-rollback", c.name+"-previous"
+	if exists, err := d.exists(previous); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("could not roll back %s: %s is left over from an earlier rollback", c.name, previous)
+	}
+	if exists, err := d.exists(rollback); err != nil {
+		return err
+	} else if exists {
+		if _, err := d.runcmd("lvremove", "-f", d.volumeGroup+"/"+rollback); err != nil {
+			return fmt.Errorf("could not remove thin volume %s: %v", rollback, err)
+		}
+	}
+
+	if _, err := d.runcmd("lvcreate", "-s", "-kn", "-n", rollback, d.volumeGroup+"/"+label); err != nil {
+		return fmt.Errorf("could not roll back %s to %s: %v", c.name, label, err)
+	}
+	discard := func() {
+		if _, err := d.runcmd("lvremove", "-f", d.volumeGroup+"/"+rollback); err != nil {
+			glog.Errorf("Could not remove thin volume %s: %s", rollback, err)
+		}
+	}
+	remount := func() {
+		if err := d.mount(d.device(c.name), c.Path()); err != nil {
+			glog.Errorf("Could not remount %s: %s", c.name, err)
+		}
+	}
+
+	if err := d.unmount(c.Path()); err != nil {
+		discard()
+		return err
+	}
+	if err := d.rename(c.name, previous); err != nil {
+		remount()
+		discard()
+		return err
+	}
+	if err := d.rename(rollback, c.name); err != nil {
+		if err := d.rename(previous, c.name); err != nil {
+			glog.Errorf("Could not put back %s: %s", c.name, err)
+			return err
+		}
+		remount()
+		discard()
+		return err
+	}
+	if err := d.mount(d.device(c.name), c.Path()); err != nil {
+		if err := d.rename(c.name, rollback); err != nil {
+			glog.Errorf("Could not put back %s: %s", c.name, err)
+			return err
+		}
+		if err := d.rename(previous, c.name); err != nil {
+			glog.Errorf("Could not put back %s: %s", c.name, err)
+			return err
+		}
+		remount()
+		discard()
+		return err
+	}
+
+	if _, err := d.runcmd("lvremove", "-f", d.volumeGroup+"/"+previous); err != nil {
+		glog.Warningf("Could not remove %s after rolling back %s: %s", previous, c.name, err)
+	}
+	return nil
+}
+
+// snapshotExists returns an error if there is no snapshot with the label
+func (c *LVMConn) snapshotExists(label string) error {
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		return fmt.Errorf("could not get current snapshot list: %v", err)
+	}
+	for _, snapLabel := range snapshots {
+		if label == snapLabel {
+			return nil
+		}
+	}
+	return fmt.Errorf("snapshot %s does not exist", label)
+}
+
+// device is the path to the device of a logical volume
+func (d *LVMDriver) device(name string) string {
+	return path.Join("/dev", d.volumeGroup, name)
+}
+
+// volumes returns the names of the thin volumes on the thin pool
+func (d *LVMDriver) volumes() (map[string]bool, error) {
+	output, err := d.runcmd("lvs", "--noheadings", "--separator", ",", "-o", "lv_name,pool_lv", d.volumeGroup)
+	if err != nil {
+		return nil, fmt.Errorf("could not list logical volumes of %s: %v", d.volumeGroup, err)
+	}
+	return parseVolumes(string(output), d.thinPool), nil
+}
+
+// exists is true if the thin pool has a thin volume of the name
+func (d *LVMDriver) exists(name string) (bool, error) {
+	volumes, err := d.volumes()
+	if err != nil {
+		return false, err
+	}
+	return volumes[name], nil
+}
+
+// mount mounts a device at a dir, if nothing is mounted there yet
+func (d *LVMDriver) mount(device, dir string, options ...string) error {
+	if mounted, err := isMounted(dir); err != nil {
+		return err
+	} else if mounted {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	if _, err := d.runcmd("lvchange", "-ay", "-K", device); err != nil {
+		return fmt.Errorf("could not activate %s: %v", device, err)
+	}
+	args := append(options, device, dir)
+	if _, err := d.runcmd("mount", args...); err != nil {
+		return fmt.Errorf("could not mount %s at %s: %v", device, dir, err)
+	}
+	return nil
+}
+
+// unmount unmounts a dir, if anything is mounted there
+func (d *LVMDriver) unmount(dir string) error {
+	if mounted, err := isMounted(dir); err != nil {
+		return err
+	} else if mounted {
+		if _, err := d.runcmd("umount", dir); err != nil {
+			return fmt.Errorf("could not unmount %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// rename renames a logical volume
+func (d *LVMDriver) rename(from, to string) error {
+	if _, err := d.runcmd("lvrename", d.volumeGroup, from, to); err != nil {
+		return fmt.Errorf("could not rename thin volume %s to %s: %v", from, to, err)
+	}
+	return nil
+}
+
+// remove unmounts a thin volume from a dir and removes both
+func (d *LVMDriver) remove(name, dir string) error {
+	if err := d.unmount(dir); err != nil {
+		return err
+	}
+	if _, err := d.runcmd("lvremove", "-f", d.volumeGroup+"/"+name); err != nil {
+		return fmt.Errorf("could not remove thin volume %s: %v", name, err)
+	}
+	if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// runcmd runs an lvm or filesystem command
+func (d *LVMDriver) runcmd(name string, args ...string) ([]byte, error) {
+	cmd := append([]string{name}, args...)
+	if d.sudoer {
+		cmd = append([]string{"sudo", "-n"}, cmd...)
+	}
+	glog.V(4).Infof("Executing: %v", cmd)
+	output, err := runCommand(cmd[0], cmd[1:]...)
+	if err != nil {
+		glog.V(2).Infof("%v: %s", cmd, string(output))
+	}
+	return output, err
+}
+
+// runCommand runs a command and returns its combined output
+var runCommand = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// parseVolumes parses the lv_name,pool_lv output of lvs for the thin volumes
+// of a thin pool
+func parseVolumes(output, thinPool string) map[string]bool {
+	volumes := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) == 2 && parts[1] == thinPool {
+			volumes[parts[0]] = true
+		}
+	}
+	return volumes
+}
+
+// isMounted is true if a filesystem is mounted at the dir
+func isMounted(dir string) (bool, error) {
+	file, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	dir = path.Clean(dir)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 1 && fields[1] == dir {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
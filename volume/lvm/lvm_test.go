@@ -0,0 +1,210 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package lvm
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testVolumeGroup = "serviced_unittest"
+
+func TestParseVolumes(t *testing.T) {
+	output := `  thinpool           ,
+  unittest           ,thinpool
+  unittest_foo       ,thinpool
+  other              ,otherpool
+  root               ,
+`
+	volumes := parseVolumes(strings.Replace(output, " ", "", -1), "thinpool")
+	expected := map[string]bool{"unittest": true, "unittest_foo": true}
+	if !reflect.DeepEqual(volumes, expected) {
+		t.Errorf("expected %v, got %v", expected, volumes)
+	}
+}
+
+// fakeCommands replaces the commands the driver runs, failing the ones that
+// start with any of the given prefixes, and records the commands that ran
+func fakeCommands(volumes string, fail ...string) (*[]string, func()) {
+	var ran []string
+	original := runCommand
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		cmd := strings.Join(append([]string{name}, args...), " ")
+		ran = append(ran, cmd)
+		for _, prefix := range fail {
+			if strings.HasPrefix(cmd, prefix) {
+				return nil, errors.New("failed")
+			}
+		}
+		if name == "lvs" {
+			return []byte(volumes), nil
+		}
+		return nil, nil
+	}
+	return &ran, func() { runCommand = original }
+}
+
+func TestLVMVolume_RollbackFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviced-lvm-")
+	if err != nil {
+		t.Fatalf("Could not create test dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := &LVMDriver{volumeGroup: "vg", thinPool: "thinpool", size: "1G"}
+	c := &LVMConn{driver: d, name: "unittest", root: dir}
+	volumes := "unittest,thinpool\nunittest_foo,thinpool\n"
+
+	// the volume is put back if the snapshot cannot be renamed into place
+	ran, restore := fakeCommands(volumes, "lvrename vg unittest-rollback unittest")
+	defer restore()
+	if err := c.Rollback("unittest_foo"); err == nil {
+		t.Fatalf("Expected the rollback to fail")
+	}
+	expected := []string{
+		"lvcreate -s -kn -n unittest-rollback vg/unittest_foo",
+		"lvrename vg unittest unittest-previous",
+		"lvrename vg unittest-rollback unittest",
+		"lvrename vg unittest-previous unittest",
+		"lvchange -ay -K /dev/vg/unittest",
+		"mount /dev/vg/unittest " + c.Path(),
+		"lvremove -f vg/unittest-rollback",
+	}
+	if actual := (*ran)[len(*ran)-len(expected):]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected commands\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(*ran, "\n"))
+	}
+
+	// the volume is left alone if the snapshot cannot be taken
+	ran, restore = fakeCommands(volumes, "lvcreate")
+	defer restore()
+	if err := c.Rollback("unittest_foo"); err == nil {
+		t.Fatalf("Expected the rollback to fail")
+	}
+	for _, cmd := range *ran {
+		if strings.HasPrefix(cmd, "lvrename") || strings.HasPrefix(cmd, "lvremove") {
+			t.Errorf("Unexpected command after the snapshot failed: %s", cmd)
+		}
+	}
+}
+
+// loopbackThinPool creates a volume group on a loopback device with a thin
+// pool, and returns a func that removes them
+func loopbackThinPool(t *testing.T, dir string) func() {
+	run := func(name string, args ...string) string {
+		output, err := exec.Command(name, args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %v: %s (%s)", name, args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	backing := path.Join(dir, "thinpool.img")
+	run("truncate", "-s", "1G", backing)
+	device := run("losetup", "-f", "--show", backing)
+	run("vgcreate", testVolumeGroup, device)
+	run("lvcreate", "-L", "800M", "-T", testVolumeGroup+"/thinpool")
+	return func() {
+		exec.Command("vgremove", "-f", testVolumeGroup).Run()
+		exec.Command("pvremove", "-f", device).Run()
+		exec.Command("losetup", "-d", device).Run()
+	}
+}
+
+func TestLVMVolume(t *testing.T) {
+	if user, err := user.Current(); err != nil {
+		panic(err)
+	} else if user.Uid != "0" {
+		t.Skip("Skipping lvm tests because we are not running as root")
+	}
+	for _, tool := range []string{"lvcreate", "losetup", "mkfs." + fsType, "fsfreeze"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("Skipping lvm tests because %s was not found in the path", tool)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "serviced-lvm-")
+	if err != nil {
+		t.Fatalf("Could not create test dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer loopbackThinPool(t, dir)()
+
+	lvmd, err := NewThinPool(testVolumeGroup, "thinpool", "200M")
+	if err != nil {
+		t.Fatalf("Unable to create lvm driver: %v", err)
+	}
+
+	root := path.Join(dir, "volumes")
+	c, err := lvmd.Mount("unittest", root)
+	if err != nil {
+		t.Fatalf("Could not create volume object: %s", err)
+	}
+	defer c.Unmount()
+
+	testFile := path.Join(c.Path(), "test.txt")
+	testData := []byte("testData\n")
+	testData2 := []byte("testData2\n")
+
+	if err := ioutil.WriteFile(testFile, testData, 0664); err != nil {
+		t.Fatalf("Could not write out test file: %s", err)
+	}
+
+	label := "unittest_foo"
+	if err := c.Snapshot(label); err != nil {
+		t.Fatalf("Could not snapshot: %s", err)
+	}
+
+	if err := ioutil.WriteFile(testFile, testData2, 0664); err != nil {
+		t.Fatalf("Could not write out test file 2: %s", err)
+	}
+
+	// the snapshot is readable at its path
+	if output, err := ioutil.ReadFile(path.Join(c.SnapshotPath(label), "test.txt")); err != nil {
+		t.Fatalf("Could not read the snapshot of the test file: %s", err)
+	} else if !reflect.DeepEqual(output, testData) {
+		t.Fatalf("snapshot has %q, expected %q", output, testData)
+	}
+
+	snapshots, err := c.Snapshots()
+	if err != nil {
+		t.Fatalf("Could not list snapshots: %s", err)
+	}
+	if len(snapshots) != 1 || snapshots[0] != label {
+		t.Fatalf("Found %v, expected %s", snapshots, label)
+	}
+
+	if list := lvmd.List(root); len(list) != 2 {
+		t.Errorf("Found volumes %v, expected the volume and its snapshot", list)
+	}
+
+	if err := c.Rollback(label); err != nil {
+		t.Fatalf("Could not roll back: %s", err)
+	}
+
+	if output, err := ioutil.ReadFile(testFile); err != nil {
+		t.Fatalf("Could not read back test file: %s", err)
+	} else if !reflect.DeepEqual(output, testData) {
+		t.Fatalf("read %q after rollback, expected %q", output, testData)
+	}
+
+	// a remount finds the volume and its snapshot as they were
+	if _, err := lvmd.Mount("unittest", root); err != nil {
+		t.Fatalf("Could not remount volume: %s", err)
+	}
+
+	if err := c.RemoveSnapshot(label); err != nil {
+		t.Fatalf("Could not remove %s: %s", label, err)
+	}
+	if snapshots, _ := c.Snapshots(); len(snapshots) != 0 {
+		t.Errorf("Found %v after removing %s", snapshots, label)
+	}
+}
@@ -30,6 +30,14 @@ type Conn interface {
 	Unmount() error
 }
 
+// Sender is implemented by the connections of drivers that can write a
+// snapshot as a stream of its changes from a parent snapshot, and add a
+// snapshot from such a stream
+type Sender interface {
+	Send(label, parent, filename string) error
+	Receive(filename string) error
+}
+
 type Volume struct {
 	Conn
 }
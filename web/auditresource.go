@@ -0,0 +1,58 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package web
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+	"github.com/control-center/serviced/domain/audit"
+
+	"strconv"
+	"time"
+)
+
+// restGetAuditEntries retrieves the audit log, newest first. The entries can
+// be filtered with the type, id, user, since (a duration such as 24h) and
+// limit query parameters. Response is []audit.Entry
+func restGetAuditEntries(w *rest.ResponseWriter, r *rest.Request, ctx *requestContext) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		EntityType: query.Get("type"),
+		EntityID:   query.Get("id"),
+		User:       query.Get("user"),
+	}
+	if since := query.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			glog.V(1).Infof("Invalid audit log duration %q", since)
+			restBadRequest(w)
+			return
+		}
+		filter.Since = time.Now().Add(-d)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			glog.V(1).Infof("Invalid audit log limit %q", limit)
+			restBadRequest(w)
+			return
+		}
+		filter.Limit = n
+	}
+
+	client, err := ctx.getMasterClient()
+	if err != nil {
+		restServerError(w)
+		return
+	}
+
+	entries, err := client.GetAuditEntries(filter)
+	if err != nil {
+		glog.Error("Could not get audit entries: ", err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&entries)
+}
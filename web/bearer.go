@@ -0,0 +1,85 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package web
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/token"
+
+	"strings"
+	"sync"
+	"time"
+)
+
+// how long an authenticated API token is trusted before it is checked again,
+// so revoked tokens stop working soon after
+var bearerTTL = time.Minute
+
+type bearerT struct {
+	token   *token.Token
+	checked time.Time
+}
+
+var (
+	bearers     = make(map[string]*bearerT)
+	bearersLock sync.Mutex
+)
+
+// bearerToken returns the API token in the Authorization header of the
+// request, if any
+func bearerToken(r *rest.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// findBearer returns the token of a bearer value that was authenticated
+// recently
+func findBearer(bearer string) *token.Token {
+	bearersLock.Lock()
+	defer bearersLock.Unlock()
+	if b, ok := bearers[bearer]; ok && time.Since(b.checked) < bearerTTL {
+		return b.token
+	}
+	return nil
+}
+
+// authenticate checks that the request carries a valid API token or belongs
+// to a logged in session
+func (sc *ServiceConfig) authenticate(r *rest.Request) bool {
+	bearer := bearerToken(r)
+	if bearer == "" {
+		return loginOK(r)
+	} else if findBearer(bearer) != nil {
+		return true
+	}
+
+	client, err := sc.getMasterClient(datastore.Caller{Source: datastore.SourceREST})
+	if err != nil {
+		return false
+	}
+	defer client.Close()
+	t, err := client.AuthenticateToken(bearer)
+	if err != nil {
+		glog.V(1).Infof("Unable to authenticate API token: %s", err)
+		return false
+	}
+
+	bearersLock.Lock()
+	defer bearersLock.Unlock()
+	now := time.Now()
+	for key, b := range bearers {
+		if now.Sub(b.checked) >= bearerTTL {
+			delete(bearers, key)
+		}
+	}
+	bearers[bearer] = &bearerT{t, now}
+	glog.V(2).Infof("API token %s of user %s used", t.ID, t.User)
+	return true
+}
@@ -16,6 +16,9 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/zenoss/glog"
 	"github.com/zenoss/go-json-rest"
+	"github.com/control-center/serviced/datastore"
+	"github.com/control-center/serviced/domain/role"
+	"github.com/control-center/serviced/domain/token"
 	"github.com/control-center/serviced/node"
 	"github.com/control-center/serviced/proxy"
 	"github.com/control-center/serviced/rpc/master"
@@ -29,6 +32,7 @@ type ServiceConfig struct {
 	hostaliases []string
 	muxTLS      bool
 	muxPort     int
+	muxDialer   *proxy.MuxDialer
 }
 
 var defaultHostAlias string
@@ -42,6 +46,7 @@ func NewServiceConfig(bindPort string, agentPort string, stats bool, hostaliases
 		hostaliases: []string{},
 		muxTLS:      muxTLS,
 		muxPort:     muxPort,
+		muxDialer:   &proxy.MuxDialer{},
 	}
 	if len(cfg.agentPort) == 0 {
 		cfg.agentPort = "127.0.0.1:4979"
@@ -49,6 +54,12 @@ func NewServiceConfig(bindPort string, agentPort string, stats bool, hostaliases
 	return &cfg
 }
 
+// MuxDialer returns the dialer that virtual hosts connect to muxes with when
+// TLS is enabled
+func (sc *ServiceConfig) MuxDialer() *proxy.MuxDialer {
+	return sc.muxDialer
+}
+
 // Serve handles control plane web UI requests and virtual host requests for zenoss web based services.
 // The UI server actually listens on port 7878, the uihandler defined here just reverse proxies to it.
 // Virtual host routing to zenoss web based services is done by the vhosthandler function.
@@ -156,7 +167,7 @@ func routeToInternalServiceProxy(path string, target string, routes []rest.Route
 
 func (sc *ServiceConfig) unAuthorizedClient(realfunc handlerClientFunc) handlerFunc {
 	return func(w *rest.ResponseWriter, r *rest.Request) {
-		client, err := sc.getClient()
+		client, err := sc.getClient(restCaller(r))
 		if err != nil {
 			glog.Errorf("Unable to acquire client: %v", err)
 			restServerError(w)
@@ -169,11 +180,11 @@ func (sc *ServiceConfig) unAuthorizedClient(realfunc handlerClientFunc) handlerF
 
 func (sc *ServiceConfig) authorizedClient(realfunc handlerClientFunc) handlerFunc {
 	return func(w *rest.ResponseWriter, r *rest.Request) {
-		if !loginOK(r) {
+		if !sc.authenticate(r) {
 			restUnauthorized(w)
 			return
 		}
-		client, err := sc.getClient()
+		client, err := sc.getClient(restCaller(r))
 		if err != nil {
 			glog.Errorf("Unable to acquire client: %v", err)
 			restServerError(w)
@@ -195,18 +206,30 @@ func (sc *ServiceConfig) isCollectingStats() handlerFunc {
 	}
 }
 
-func (sc *ServiceConfig) getClient() (c *node.ControlClient, err error) {
+// restCaller identifies the user making a REST request, and the API token
+// they authenticated with if any
+func restCaller(r *rest.Request) datastore.Caller {
+	if bearer := bearerToken(r); bearer != "" {
+		if t := findBearer(bearer); t != nil {
+			return datastore.Caller{User: t.User, Source: datastore.SourceREST, Token: t.ID}
+		}
+		return datastore.Caller{Source: datastore.SourceREST}
+	}
+	return datastore.Caller{User: sessionUser(r), Source: datastore.SourceREST}
+}
+
+func (sc *ServiceConfig) getClient(caller datastore.Caller) (c *node.ControlClient, err error) {
 	// setup the client
-	c, err = node.NewControlClient(sc.agentPort)
+	c, err = node.NewControlClientForCaller(sc.agentPort, caller)
 	if err != nil {
 		glog.Fatalf("Could not create a control plane client: %v", err)
 	}
 	return c, err
 }
 
-func (sc *ServiceConfig) getMasterClient() (*master.Client, error) {
+func (sc *ServiceConfig) getMasterClient(caller datastore.Caller) (*master.Client, error) {
 	glog.Info("start getMasterClient ... sc.agentPort: %+v", sc.agentPort)
-	c, err := master.NewClient(sc.agentPort)
+	c, err := master.NewClientForCaller(sc.agentPort, caller)
 	if err != nil {
 		glog.Errorf("Could not create a control plane client to %v: %v", sc.agentPort, err)
 		return nil, err
@@ -220,7 +243,7 @@ func (sc *ServiceConfig) newRequestHandler(check checkFunc, realfunc ctxhandlerF
 		if !check(w, r) {
 			return
 		}
-		reqCtx := newRequestContext(sc)
+		reqCtx := newRequestContext(sc, restCaller(r))
 		defer reqCtx.end()
 		realfunc(w, r, reqCtx)
 	}
@@ -228,7 +251,7 @@ func (sc *ServiceConfig) newRequestHandler(check checkFunc, realfunc ctxhandlerF
 
 func (sc *ServiceConfig) checkAuth(realfunc ctxhandlerFunc) handlerFunc {
 	check := func(w *rest.ResponseWriter, r *rest.Request) bool {
-		if !loginOK(r) {
+		if !sc.authenticate(r) {
 			restUnauthorized(w)
 			return false
 		}
@@ -237,6 +260,50 @@ func (sc *ServiceConfig) checkAuth(realfunc ctxhandlerFunc) handlerFunc {
 	return sc.newRequestHandler(check, realfunc)
 }
 
+// allow checks that the logged in user's roles permit the action on the type
+// of resource before calling the handler. Requests are scoped to the pool,
+// service or host named in the path, if any.
+func (sc *ServiceConfig) allow(action, resource string, handler handlerFunc) handlerFunc {
+	return func(w *rest.ResponseWriter, r *rest.Request) {
+		if !sc.authenticate(r) {
+			restUnauthorized(w)
+			return
+		}
+
+		request := role.Request{Action: action, Resource: resource}
+		for param, value := range map[string]*string{
+			"poolId":    &request.PoolID,
+			"serviceId": &request.ServiceID,
+			"hostId":    &request.HostID,
+		} {
+			if v, err := url.QueryUnescape(r.PathParam(param)); err == nil {
+				*value = v
+			}
+		}
+
+		client, err := sc.getMasterClient(restCaller(r))
+		if err != nil {
+			restServerError(w)
+			return
+		}
+		err = client.Authorize(request)
+		client.Close()
+		if token.IsErrInvalid(err) {
+			restUnauthorized(w)
+			return
+		} else if role.IsErrUnauthorized(err) {
+			glog.Warningf("Denied %s %s: %s", r.Method, r.URL.Path, err)
+			restForbidden(w, err)
+			return
+		} else if err != nil {
+			glog.Errorf("Unable to authorize %s %s: %s", r.Method, r.URL.Path, err)
+			restServerError(w)
+			return
+		}
+		handler(w, r)
+	}
+}
+
 func (sc *ServiceConfig) noAuth(realfunc ctxhandlerFunc) handlerFunc {
 	check := func(w *rest.ResponseWriter, r *rest.Request) bool {
 		return true
@@ -246,16 +313,17 @@ func (sc *ServiceConfig) noAuth(realfunc ctxhandlerFunc) handlerFunc {
 
 type requestContext struct {
 	sc     *ServiceConfig
+	caller datastore.Caller
 	master *master.Client
 }
 
-func newRequestContext(sc *ServiceConfig) *requestContext {
-	return &requestContext{sc: sc}
+func newRequestContext(sc *ServiceConfig, caller datastore.Caller) *requestContext {
+	return &requestContext{sc: sc, caller: caller}
 }
 
 func (ctx *requestContext) getMasterClient() (*master.Client, error) {
 	if ctx.master == nil {
-		c, err := ctx.sc.getMasterClient()
+		c, err := ctx.sc.getMasterClient(ctx.caller)
 		if err != nil {
 			glog.Errorf("Could not create a control plane client: %v", err)
 			return nil, err
@@ -0,0 +1,68 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package web
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/zenoss/go-json-rest"
+	"github.com/control-center/serviced/domain/event"
+
+	"strconv"
+	"time"
+)
+
+// restGetEvents retrieves the threshold events, most recently seen first. The
+// events can be filtered with the type, id, pool, active (true or false),
+// since (a duration such as 24h) and limit query parameters. Response is
+// []event.Event
+func restGetEvents(w *rest.ResponseWriter, r *rest.Request, ctx *requestContext) {
+	query := r.URL.Query()
+	filter := event.Filter{
+		EntityType: query.Get("type"),
+		EntityID:   query.Get("id"),
+		PoolID:     query.Get("pool"),
+	}
+	if active := query.Get("active"); active != "" {
+		b, err := strconv.ParseBool(active)
+		if err != nil {
+			glog.V(1).Infof("Invalid event active flag %q", active)
+			restBadRequest(w)
+			return
+		}
+		filter.Active = b
+	}
+	if since := query.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			glog.V(1).Infof("Invalid event duration %q", since)
+			restBadRequest(w)
+			return
+		}
+		filter.Since = time.Now().Add(-d)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			glog.V(1).Infof("Invalid event limit %q", limit)
+			restBadRequest(w)
+			return
+		}
+		filter.Limit = n
+	}
+
+	client, err := ctx.getMasterClient()
+	if err != nil {
+		restServerError(w)
+		return
+	}
+
+	events, err := client.GetEvents(filter)
+	if err != nil {
+		glog.Error("Could not get events: ", err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&events)
+}
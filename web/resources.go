@@ -5,11 +5,13 @@
 package web
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,11 +19,16 @@ import (
 	"github.com/zenoss/go-json-rest"
 
 	"github.com/control-center/serviced/dao"
+	"github.com/control-center/serviced/domain/pool"
 	"github.com/control-center/serviced/domain/service"
+	"github.com/control-center/serviced/domain/servicedefinition"
 	"github.com/control-center/serviced/domain/servicetemplate"
 	"github.com/control-center/serviced/isvcs"
 	"github.com/control-center/serviced/node"
 	"github.com/control-center/serviced/servicedversion"
+	"github.com/control-center/serviced/zzk/autoscale"
+	zkhealth "github.com/control-center/serviced/zzk/health"
+	"github.com/control-center/serviced/zzk/rollout"
 )
 
 var empty interface{}
@@ -48,7 +55,11 @@ func restDeployAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node
 	err = client.DeployTemplate(payload, &tenantID)
 	if err != nil {
 		glog.Error("Could not deploy template: ", err)
-		restServerError(w)
+		if pool.IsErrQuotaExceeded(err) {
+			restQuotaExceeded(w, err)
+		} else {
+			restServerError(w)
+		}
 		return
 	}
 	glog.V(0).Info("Deployed template ", payload)
@@ -65,6 +76,57 @@ func restDeployAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node
 	w.WriteJson(&simpleResponse{tenantID, servicesLinks()})
 }
 
+// templateApplyRequest reads the template and tenant of a request to compare
+// or apply a template. Service contexts are only overwritten if the
+// overwriteContext query parameter is set.
+func templateApplyRequest(r *rest.Request) (dao.ServiceTemplateApplyRequest, error) {
+	templateID, err := url.QueryUnescape(r.PathParam("templateId"))
+	if err != nil {
+		return dao.ServiceTemplateApplyRequest{}, err
+	}
+	tenantID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		return dao.ServiceTemplateApplyRequest{}, err
+	}
+	overwriteContext, _ := strconv.ParseBool(r.URL.Query().Get("overwriteContext"))
+	return dao.ServiceTemplateApplyRequest{TenantID: tenantID, TemplateID: templateID, OverwriteContext: overwriteContext}, nil
+}
+
+func restDiffAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	request, err := templateApplyRequest(r)
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var diff service.TemplateDiff
+	if err := client.DiffTemplate(request, &diff); err != nil {
+		glog.Errorf("Could not compare template %s with %s: %s", request.TemplateID, request.TenantID, err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&diff)
+}
+
+func restApplyAppTemplate(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	request, err := templateApplyRequest(r)
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var diff service.TemplateDiff
+	if err := client.ApplyTemplate(request, &diff); err != nil {
+		glog.Errorf("Could not apply template %s to %s: %s", request.TemplateID, request.TenantID, err)
+		if pool.IsErrQuotaExceeded(err) {
+			restQuotaExceeded(w, err)
+		} else {
+			restServerError(w)
+		}
+		return
+	}
+	glog.V(0).Infof("Applied template %s to %s", request.TemplateID, request.TenantID)
+	w.WriteJson(&diff)
+}
+
 func filterByNameRegex(nmregex string, services []*service.Service) ([]*service.Service, error) {
 	r, err := regexp.Compile(nmregex)
 	if err != nil {
@@ -386,7 +448,11 @@ func restDeployService(w *rest.ResponseWriter, r *rest.Request, client *node.Con
 	err = client.DeployService(payload, &serviceID)
 	if err != nil {
 		glog.Errorf("Unable to deploy service: %v", err)
-		restServerError(w)
+		if pool.IsErrQuotaExceeded(err) {
+			restQuotaExceeded(w, err)
+		} else {
+			restServerError(w)
+		}
 		return
 	}
 
@@ -409,10 +475,35 @@ func restUpdateService(w *rest.ResponseWriter, r *rest.Request, client *node.Con
 		restBadRequest(w)
 		return
 	}
-	err = client.UpdateService(payload, &unused)
+	// ?rolling=true[&batch=N][&timeout=SECONDS] restarts the instances a batch
+	// at a time once the service is updated
+	if rolling, _ := strconv.ParseBool(r.URL.Query().Get("rolling")); rolling {
+		request := dao.ServiceUpdateRequest{Service: payload}
+		if batch := r.URL.Query().Get("batch"); batch != "" {
+			if request.BatchSize, err = strconv.Atoi(batch); err != nil {
+				restBadRequest(w)
+				return
+			}
+		}
+		if timeout := r.URL.Query().Get("timeout"); timeout != "" {
+			seconds, err := strconv.ParseFloat(timeout, 64)
+			if err != nil {
+				restBadRequest(w)
+				return
+			}
+			request.Timeout = time.Duration(seconds * float64(time.Second))
+		}
+		err = client.UpdateServiceRolling(request, &unused)
+	} else {
+		err = client.UpdateService(payload, &unused)
+	}
 	if err != nil {
 		glog.Errorf("Unable to update service %s: %v", serviceID, err)
-		restServerError(w)
+		if pool.IsErrQuotaExceeded(err) {
+			restQuotaExceeded(w, err)
+		} else {
+			restServerError(w)
+		}
 		return
 	}
 	glog.V(1).Info("Updated service ", serviceID)
@@ -462,7 +553,12 @@ func restStartService(w *rest.ResponseWriter, r *rest.Request, client *node.Cont
 	err = client.StartService(serviceID, &i)
 	if err != nil {
 		glog.Errorf("Unexpected error starting service: %v", err)
-		restServerError(w)
+		if pool.IsErrQuotaExceeded(err) {
+			restQuotaExceeded(w, err)
+		} else {
+			restServerError(w)
+		}
+		return
 	}
 	w.WriteJson(&simpleResponse{"Started service", serviceLinks(serviceID)})
 }
@@ -483,6 +579,148 @@ func restStopService(w *rest.ResponseWriter, r *rest.Request, client *node.Contr
 	w.WriteJson(&simpleResponse{"Stopped service", serviceLinks(serviceID)})
 }
 
+// restartPayload is the optional request body of restRestartService
+type restartPayload struct {
+	Rolling   bool
+	BatchSize int
+	Timeout   float64 // the serialized version is in seconds
+}
+
+// restRestartService restarts the instances of the service with the given id,
+// all at once or a batch at a time
+func restRestartService(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var payload restartPayload
+	if r.ContentLength > 0 {
+		if err := r.DecodeJsonPayload(&payload); err != nil {
+			glog.V(1).Info("Could not decode restart payload: ", err)
+			restBadRequest(w)
+			return
+		}
+	}
+	request := dao.ServiceRestartRequest{
+		ServiceID: serviceID,
+		Rolling:   payload.Rolling,
+		BatchSize: payload.BatchSize,
+		Timeout:   time.Duration(payload.Timeout * float64(time.Second)),
+	}
+	var unused int
+	if err := client.RestartService(request, &unused); err != nil {
+		glog.Errorf("Unexpected error restarting service: %v", err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&simpleResponse{"Restarted service", serviceLinks(serviceID)})
+}
+
+// restGetServiceRollout gets the progress of the rolling restart of a service
+func restGetServiceRollout(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var progress rollout.Rollout
+	if err := client.GetServiceRollout(serviceID, &progress); err != nil {
+		glog.Errorf("Could not get rollout for service %s: %v", serviceID, err)
+		writeJSON(w, &simpleResponse{err.Error(), serviceLinks(serviceID)}, http.StatusNotFound)
+		return
+	}
+	w.WriteJson(&progress)
+}
+
+// restCancelServiceRollout cancels the paused or in progress rolling restart
+// of a service
+func restCancelServiceRollout(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var unused int
+	if err := client.CancelServiceRollout(serviceID, &unused); err != nil {
+		glog.Errorf("Could not cancel rollout for service %s: %v", serviceID, err)
+		writeJSON(w, &simpleResponse{err.Error(), serviceLinks(serviceID)}, http.StatusNotFound)
+		return
+	}
+	w.WriteJson(&simpleResponse{"Canceled rolling restart", serviceLinks(serviceID)})
+}
+
+func restGetScalingEvents(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var events []autoscale.Event
+	if err := client.GetScalingEvents(serviceID, &events); err != nil {
+		glog.Errorf("Could not get scaling events for service %s: %v", serviceID, err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&events)
+}
+
+func restGetServiceHealth(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var statuses []*zkhealth.Status
+	if err := client.GetServiceHealth(serviceID, &statuses); err != nil {
+		glog.Errorf("Could not get health of service %s: %v", serviceID, err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&statuses)
+}
+
+func restGetSnapshotSchedule(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var schedule dao.SnapshotSchedule
+	if err := client.GetSnapshotSchedule(serviceID, &schedule); err != nil {
+		glog.Errorf("Could not get snapshot schedule for service %s: %v", serviceID, err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&schedule)
+}
+
+func restSetSnapshotPolicy(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
+	if err != nil {
+		restBadRequest(w)
+		return
+	}
+	var policy servicedefinition.SnapshotPolicy
+	if err := r.DecodeJsonPayload(&policy); err != nil {
+		glog.V(1).Info("Could not decode snapshot policy payload: ", err)
+		restBadRequest(w)
+		return
+	} else if err := policy.ValidEntity(); err != nil {
+		glog.V(1).Info("Invalid snapshot policy: ", err)
+		restBadRequest(w)
+		return
+	}
+	var unused int
+	request := dao.SnapshotPolicyRequest{TenantID: serviceID, Policy: policy}
+	if err := client.SetSnapshotPolicy(request, &unused); err != nil {
+		glog.Errorf("Unable to set snapshot policy of service %s: %v", serviceID, err)
+		restServerError(w)
+		return
+	}
+	w.WriteJson(&simpleResponse{"Set snapshot policy", serviceLinks(serviceID)})
+}
+
 func restSnapshotService(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
 	serviceID, err := url.QueryUnescape(r.PathParam("serviceId"))
 	if err != nil {
@@ -635,3 +873,75 @@ func RestRestoreStatus(w *rest.ResponseWriter, r *rest.Request, client *node.Con
 	}
 	w.WriteJson(&simpleResponse{restoreStatus, servicesLinks()})
 }
+
+// progressFunc gets the progress of a backup or restore once it changed since
+// a sequence number
+type progressFunc func(since int, progress *dao.BackupProgress) error
+
+// streamProgress streams the progress of the backup or restore in flight as
+// newline delimited JSON, one object per change, until it is done. A client
+// that only wants the current progress passes ?follow=false.
+func streamProgress(w *rest.ResponseWriter, r *rest.Request, get progressFunc) {
+	var progress dao.BackupProgress
+	if err := get(-1, &progress); err != nil {
+		glog.Errorf("Unexpected error getting the %s progress: %v", progress.Operation, err)
+		restServerError(w)
+		return
+	}
+	if r.URL.Query().Get("follow") == "false" {
+		w.WriteJson(&progress)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.ResponseWriter.(http.Flusher)
+	encoder := json.NewEncoder(w.ResponseWriter)
+	for {
+		if err := encoder.Encode(&progress); err != nil {
+			glog.V(2).Infof("Stopped streaming the %s progress: %v", progress.Operation, err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if !progress.Running {
+			return
+		}
+
+		// progress that did not change before the timeout is sent again,
+		// which finds the clients that went away
+		if err := get(progress.Sequence, &progress); err != nil {
+			glog.Errorf("Unexpected error getting the %s progress: %v", progress.Operation, err)
+			return
+		}
+	}
+}
+
+func RestBackupProgress(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	streamProgress(w, r, client.BackupProgress)
+}
+
+func RestRestoreProgress(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	streamProgress(w, r, client.RestoreProgress)
+}
+
+func RestBackupCancel(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	var unused int
+	if err := client.CancelBackup("", &unused); err != nil {
+		glog.Errorf("Could not cancel backup: %v", err)
+		writeJSON(w, &simpleResponse{err.Error(), homeLink()}, http.StatusConflict)
+		return
+	}
+	w.WriteJson(&simpleResponse{"Canceling backup", servicesLinks()})
+}
+
+func RestRestoreCancel(w *rest.ResponseWriter, r *rest.Request, client *node.ControlClient) {
+	var unused int
+	if err := client.CancelRestore("", &unused); err != nil {
+		glog.Errorf("Could not cancel restore: %v", err)
+		writeJSON(w, &simpleResponse{err.Error(), homeLink()}, http.StatusConflict)
+		return
+	}
+	w.WriteJson(&simpleResponse{"Canceling restore", servicesLinks()})
+}
@@ -6,6 +6,7 @@ package web
 
 import (
 	"github.com/zenoss/go-json-rest"
+	"github.com/control-center/serviced/domain/role"
 	"github.com/control-center/serviced/health"
 )
 
@@ -17,72 +18,89 @@ func (sc *ServiceConfig) getRoutes() []rest.Route {
 		rest.Route{"GET", "/test", testPage},
 		rest.Route{"GET", "/stats", sc.isCollectingStats()},
 		rest.Route{"GET", "/version", sc.authorizedClient(restGetServicedVersion)},
-		rest.Route{"GET", "/backup/create", sc.authorizedClient(RestBackupCreate)},
-		rest.Route{"GET", "/backup/restore", sc.authorizedClient(RestBackupRestore)},
-		rest.Route{"GET", "/backup/list", sc.checkAuth(RestBackupFileList)},
-		rest.Route{"GET", "/backup/status", sc.authorizedClient(RestBackupStatus)},
-		rest.Route{"GET", "/backup/restore/status", sc.authorizedClient(RestRestoreStatus)},
+		rest.Route{"GET", "/backup/create", sc.allow(role.Operate, role.ResourceBackup, sc.authorizedClient(RestBackupCreate))},
+		rest.Route{"GET", "/backup/restore", sc.allow(role.Modify, role.ResourceBackup, sc.authorizedClient(RestBackupRestore))},
+		rest.Route{"GET", "/backup/list", sc.allow(role.Read, role.ResourceBackup, sc.checkAuth(RestBackupFileList))},
+		rest.Route{"GET", "/backup/status", sc.allow(role.Read, role.ResourceBackup, sc.authorizedClient(RestBackupStatus))},
+		rest.Route{"GET", "/backup/restore/status", sc.allow(role.Read, role.ResourceBackup, sc.authorizedClient(RestRestoreStatus))},
+		rest.Route{"GET", "/backup/progress", sc.allow(role.Read, role.ResourceBackup, sc.authorizedClient(RestBackupProgress))},
+		rest.Route{"GET", "/backup/restore/progress", sc.allow(role.Read, role.ResourceBackup, sc.authorizedClient(RestRestoreProgress))},
+		rest.Route{"PUT", "/backup/cancel", sc.allow(role.Operate, role.ResourceBackup, sc.authorizedClient(RestBackupCancel))},
+		rest.Route{"PUT", "/backup/restore/cancel", sc.allow(role.Modify, role.ResourceBackup, sc.authorizedClient(RestRestoreCancel))},
+		// Audit log
+		rest.Route{"GET", "/audit", sc.allow(role.Read, role.ResourceAudit, sc.checkAuth(restGetAuditEntries))},
+		// Threshold events
+		rest.Route{"GET", "/events", sc.allow(role.Read, role.ResourceEvent, sc.checkAuth(restGetEvents))},
 		// Hosts
-		rest.Route{"GET", "/hosts", sc.checkAuth(restGetHosts)},
-		rest.Route{"GET", "/hosts/defaultHostAlias", sc.checkAuth(restGetDefaultHostAlias)},
-		rest.Route{"GET", "/hosts/:hostId", sc.checkAuth(restGetHost)},
-		rest.Route{"POST", "/hosts/add", sc.checkAuth(restAddHost)},
-		rest.Route{"DELETE", "/hosts/:hostId", sc.checkAuth(restRemoveHost)},
-		rest.Route{"PUT", "/hosts/:hostId", sc.checkAuth(restUpdateHost)},
-		rest.Route{"GET", "/hosts/:hostId/running", sc.authorizedClient(restGetRunningForHost)},
-		rest.Route{"DELETE", "/hosts/:hostId/:serviceStateId", sc.authorizedClient(restKillRunning)},
+		rest.Route{"GET", "/hosts", sc.allow(role.Read, role.ResourceHost, sc.checkAuth(restGetHosts))},
+		rest.Route{"GET", "/hosts/defaultHostAlias", sc.allow(role.Read, role.ResourceHost, sc.checkAuth(restGetDefaultHostAlias))},
+		rest.Route{"GET", "/hosts/:hostId", sc.allow(role.Read, role.ResourceHost, sc.checkAuth(restGetHost))},
+		rest.Route{"POST", "/hosts/add", sc.allow(role.Modify, role.ResourceHost, sc.checkAuth(restAddHost))},
+		rest.Route{"DELETE", "/hosts/:hostId", sc.allow(role.Modify, role.ResourceHost, sc.checkAuth(restRemoveHost))},
+		rest.Route{"PUT", "/hosts/:hostId", sc.allow(role.Modify, role.ResourceHost, sc.checkAuth(restUpdateHost))},
+		rest.Route{"GET", "/hosts/:hostId/running", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetRunningForHost))},
+		rest.Route{"DELETE", "/hosts/:hostId/:serviceStateId", sc.allow(role.Operate, role.ResourceService, sc.authorizedClient(restKillRunning))},
 
 		// Pools
-		rest.Route{"GET", "/pools/:poolId", sc.checkAuth(restGetPool)},
-		rest.Route{"DELETE", "/pools/:poolId", sc.checkAuth(restRemovePool)},
-		rest.Route{"PUT", "/pools/:poolId", sc.checkAuth(restUpdatePool)},
-		rest.Route{"POST", "/pools/add", sc.checkAuth(restAddPool)},
-		rest.Route{"GET", "/pools", sc.checkAuth(restGetPools)},
-		rest.Route{"GET", "/pools/:poolId/hosts", sc.checkAuth(restGetHostsForResourcePool)},
+		rest.Route{"GET", "/pools/:poolId", sc.allow(role.Read, role.ResourcePool, sc.checkAuth(restGetPool))},
+		rest.Route{"DELETE", "/pools/:poolId", sc.allow(role.Modify, role.ResourcePool, sc.checkAuth(restRemovePool))},
+		rest.Route{"PUT", "/pools/:poolId", sc.allow(role.Modify, role.ResourcePool, sc.checkAuth(restUpdatePool))},
+		rest.Route{"POST", "/pools/add", sc.allow(role.Modify, role.ResourcePool, sc.checkAuth(restAddPool))},
+		rest.Route{"GET", "/pools", sc.allow(role.Read, role.ResourcePool, sc.checkAuth(restGetPools))},
+		rest.Route{"GET", "/pools/:poolId/hosts", sc.allow(role.Read, role.ResourceHost, sc.checkAuth(restGetHostsForResourcePool))},
 
 		// Pools (VirtualIP)
-		rest.Route{"PUT", "/pools/:poolId/virtualip", sc.checkAuth(restAddPoolVirtualIP)},
-		rest.Route{"DELETE", "/pools/:poolId/virtualip/*ip", sc.checkAuth(restRemovePoolVirtualIP)},
+		rest.Route{"PUT", "/pools/:poolId/virtualip", sc.allow(role.Modify, role.ResourcePool, sc.checkAuth(restAddPoolVirtualIP))},
+		rest.Route{"DELETE", "/pools/:poolId/virtualip/*ip", sc.allow(role.Modify, role.ResourcePool, sc.checkAuth(restRemovePoolVirtualIP))},
 
 		// Pools (IPs)
-		rest.Route{"GET", "/pools/:poolId/ips", sc.checkAuth(restGetPoolIps)},
+		rest.Route{"GET", "/pools/:poolId/ips", sc.allow(role.Read, role.ResourcePool, sc.checkAuth(restGetPoolIps))},
 
 		// Services (Apps)
-		rest.Route{"GET", "/services", sc.authorizedClient(restGetAllServices)},
-		rest.Route{"GET", "/servicehealth", sc.authorizedClient(health.RestGetHealthStatus)},
-		rest.Route{"GET", "/services/:serviceId", sc.authorizedClient(restGetService)},
-		rest.Route{"GET", "/services/:serviceId/running", sc.authorizedClient(restGetRunningForService)},
-		rest.Route{"GET", "/services/:serviceId/running/:serviceStateId", sc.authorizedClient(restGetRunningService)},
-		rest.Route{"GET", "/services/:serviceId/:serviceStateId/logs", sc.authorizedClient(restGetServiceStateLogs)},
-		rest.Route{"POST", "/services/add", sc.authorizedClient(restAddService)},
-		rest.Route{"POST", "/services/deploy", sc.authorizedClient(restDeployService)},
-		rest.Route{"DELETE", "/services/:serviceId", sc.authorizedClient(restRemoveService)},
-		rest.Route{"GET", "/services/:serviceId/logs", sc.authorizedClient(restGetServiceLogs)},
-		rest.Route{"PUT", "/services/:serviceId", sc.authorizedClient(restUpdateService)},
-		rest.Route{"GET", "/services/:serviceId/snapshot", sc.authorizedClient(restSnapshotService)},
-		rest.Route{"PUT", "/services/:serviceId/startService", sc.authorizedClient(restStartService)},
-		rest.Route{"PUT", "/services/:serviceId/stopService", sc.authorizedClient(restStopService)},
+		rest.Route{"GET", "/services", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetAllServices))},
+		rest.Route{"GET", "/servicehealth", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(health.RestGetHealthStatus))},
+		rest.Route{"GET", "/services/:serviceId", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetService))},
+		rest.Route{"GET", "/services/:serviceId/running", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetRunningForService))},
+		rest.Route{"GET", "/services/:serviceId/running/:serviceStateId", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetRunningService))},
+		rest.Route{"GET", "/services/:serviceId/:serviceStateId/logs", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetServiceStateLogs))},
+		rest.Route{"POST", "/services/add", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restAddService))},
+		rest.Route{"POST", "/services/deploy", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restDeployService))},
+		rest.Route{"DELETE", "/services/:serviceId", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restRemoveService))},
+		rest.Route{"GET", "/services/:serviceId/logs", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetServiceLogs))},
+		rest.Route{"PUT", "/services/:serviceId", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restUpdateService))},
+		rest.Route{"GET", "/services/:serviceId/snapshot", sc.allow(role.Operate, role.ResourceSnapshot, sc.authorizedClient(restSnapshotService))},
+		rest.Route{"GET", "/services/:serviceId/snapshotschedule", sc.allow(role.Read, role.ResourceSnapshot, sc.authorizedClient(restGetSnapshotSchedule))},
+		rest.Route{"PUT", "/services/:serviceId/snapshotschedule", sc.allow(role.Modify, role.ResourceSnapshot, sc.authorizedClient(restSetSnapshotPolicy))},
+		rest.Route{"PUT", "/services/:serviceId/startService", sc.allow(role.Operate, role.ResourceService, sc.authorizedClient(restStartService))},
+		rest.Route{"PUT", "/services/:serviceId/stopService", sc.allow(role.Operate, role.ResourceService, sc.authorizedClient(restStopService))},
+		rest.Route{"PUT", "/services/:serviceId/restartService", sc.allow(role.Operate, role.ResourceService, sc.authorizedClient(restRestartService))},
+		rest.Route{"GET", "/services/:serviceId/rollout", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetServiceRollout))},
+		rest.Route{"DELETE", "/services/:serviceId/rollout", sc.allow(role.Operate, role.ResourceService, sc.authorizedClient(restCancelServiceRollout))},
+		rest.Route{"GET", "/services/:serviceId/scaling", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetScalingEvents))},
+		rest.Route{"GET", "/services/:serviceId/health", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetServiceHealth))},
 
 		// Services (Virtual Host)
-		rest.Route{"GET", "/services/vhosts", sc.authorizedClient(restGetVirtualHosts)},
-		rest.Route{"PUT", "/services/:serviceId/endpoint/:application/vhosts/*name", sc.authorizedClient(restAddVirtualHost)},
-		rest.Route{"DELETE", "/services/:serviceId/endpoint/:application/vhosts/*name", sc.authorizedClient(restRemoveVirtualHost)},
+		rest.Route{"GET", "/services/vhosts", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetVirtualHosts))},
+		rest.Route{"PUT", "/services/:serviceId/endpoint/:application/vhosts/*name", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restAddVirtualHost))},
+		rest.Route{"DELETE", "/services/:serviceId/endpoint/:application/vhosts/*name", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restRemoveVirtualHost))},
 
 		// Services (IP)
-		rest.Route{"PUT", "/services/:serviceId/ip", sc.authorizedClient(restServiceAutomaticAssignIP)},
-		rest.Route{"PUT", "/services/:serviceId/ip/*ip", sc.authorizedClient(restServiceManualAssignIP)},
+		rest.Route{"PUT", "/services/:serviceId/ip", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restServiceAutomaticAssignIP))},
+		rest.Route{"PUT", "/services/:serviceId/ip/*ip", sc.allow(role.Modify, role.ResourceService, sc.authorizedClient(restServiceManualAssignIP))},
 
 		// Service templates (App templates)
-		rest.Route{"GET", "/templates", sc.authorizedClient(restGetAppTemplates)},
-		rest.Route{"POST", "/templates/deploy", sc.authorizedClient(restDeployAppTemplate)},
+		rest.Route{"GET", "/templates", sc.allow(role.Read, role.ResourceTemplate, sc.authorizedClient(restGetAppTemplates))},
+		rest.Route{"POST", "/templates/deploy", sc.allow(role.Modify, role.ResourceTemplate, sc.authorizedClient(restDeployAppTemplate))},
+		rest.Route{"GET", "/templates/:templateId/diff/:serviceId", sc.allow(role.Read, role.ResourceTemplate, sc.authorizedClient(restDiffAppTemplate))},
+		rest.Route{"PUT", "/templates/:templateId/apply/:serviceId", sc.allow(role.Modify, role.ResourceTemplate, sc.authorizedClient(restApplyAppTemplate))},
 
 		// Login
 		rest.Route{"POST", "/login", sc.unAuthorizedClient(restLogin)},
 		rest.Route{"DELETE", "/login", restLogout},
 
 		// "Misc" stuff
-		rest.Route{"GET", "/top/services", sc.authorizedClient(restGetTopServices)},
-		rest.Route{"GET", "/running", sc.authorizedClient(restGetAllRunning)},
+		rest.Route{"GET", "/top/services", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetTopServices))},
+		rest.Route{"GET", "/running", sc.allow(role.Read, role.ResourceService, sc.authorizedClient(restGetAllRunning))},
 
 		// Generic static data
 		rest.Route{"GET", "/favicon.ico", favIcon},
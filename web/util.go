@@ -82,6 +82,22 @@ func restBadRequest(w *rest.ResponseWriter) {
 	return
 }
 
+/*
+ * The request would commit more resources to a pool than its quota allows.
+ */
+func restQuotaExceeded(w *rest.ResponseWriter, err error) {
+	writeJSON(w, &simpleResponse{err.Error(), poolsLinks()}, http.StatusConflict)
+	return
+}
+
+/*
+ * The user is logged in, but their roles do not permit the request.
+ */
+func restForbidden(w *rest.ResponseWriter, err error) {
+	writeJSON(w, &simpleResponse{err.Error(), homeLink()}, http.StatusForbidden)
+	return
+}
+
 /*
  * Write 200 success
  */
@@ -9,10 +9,10 @@ import (
 	"github.com/zenoss/glog"
 	"github.com/control-center/serviced/coordinator/client"
 	"github.com/control-center/serviced/domain/servicestate"
+	"github.com/control-center/serviced/proxy"
 	"github.com/control-center/serviced/zzk"
 	"github.com/control-center/serviced/zzk/registry"
 
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -199,6 +199,8 @@ func (sc *ServiceConfig) processVhost(vhostID string) registry.ProcessChildrenFu
 			return
 		}
 
+		// draining endpoints take no new requests
+		childIDs = registry.ActiveItems(conn, parentPath, childIDs)
 		vhostEndpoints := newVhostInfo()
 		for _, child := range childIDs {
 			vhEndpoint, err := vr.GetItem(conn, parentPath+"/"+child)
@@ -249,7 +251,7 @@ func (sc *ServiceConfig) vhosthandler(w http.ResponseWriter, r *http.Request) {
 	if sc.muxTLS && (sc.muxPort > 0) { // Only do TLS if connecting to a TCPMux
 		remoteAddr = fmt.Sprintf("%s:%d", vhEP.hostIP, sc.muxPort)
 	}
-	rp := getReverseProxy(remoteAddr, sc.muxPort, vhEP.privateIP, vhEP.epPort, sc.muxTLS && (sc.muxPort > 0))
+	rp := getReverseProxy(remoteAddr, sc.muxPort, vhEP.privateIP, vhEP.epPort, sc.muxTLS && (sc.muxPort > 0), sc.muxDialer)
 	glog.V(1).Infof("vhost proxy remoteAddr:%s sc.muxPort:%s vhEP.privateIP:%s vhEP.epPort:%s", remoteAddr, sc.muxPort, vhEP.privateIP, vhEP.epPort)
 	glog.V(1).Infof("Time to set up %s vhost proxy for %v: %v", subdomain, r.URL, time.Since(start))
 	rp.ServeHTTP(w, r)
@@ -263,7 +265,7 @@ func init() {
 	reverseProxies = make(map[string]*httputil.ReverseProxy)
 }
 
-func getReverseProxy(remoteAddr string, muxPort int, privateIP string, privatePort uint16, useTLS bool) *httputil.ReverseProxy {
+func getReverseProxy(remoteAddr string, muxPort int, privateIP string, privatePort uint16, useTLS bool, muxDialer *proxy.MuxDialer) *httputil.ReverseProxy {
 
 	reverseProxiesLock.Lock()
 	defer reverseProxiesLock.Unlock()
@@ -281,9 +283,8 @@ func getReverseProxy(remoteAddr string, muxPort int, privateIP string, privatePo
 	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
 	transport.Dial = func(network, addr string) (remote net.Conn, err error) {
 		if useTLS { // Only do TLS if connecting to a TCPMux
-			config := tls.Config{InsecureSkipVerify: true}
 			glog.V(1).Infof("vhost about to dial %s", remoteAddr)
-			remote, err = tls.Dial("tcp4", remoteAddr, &config)
+			remote, err = muxDialer.Dial(remoteAddr)
 		} else {
 			glog.V(1).Info("vhost about to dial %s", remoteAddr)
 			remote, err = net.Dial("tcp4", remoteAddr)
@@ -0,0 +1,112 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"path"
+	"time"
+
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+const (
+	zkAutoscale = "/autoscale"
+
+	// number of scaling events kept per service
+	maxEvents = 50
+)
+
+func autoscalePath(nodes ...string) string {
+	p := []string{zkAutoscale}
+	p = append(p, nodes...)
+	return path.Join(p...)
+}
+
+// Event records a scaling decision made by the autoscaler
+type Event struct {
+	ServiceID string
+	Timestamp time.Time
+	Metric    string
+	Value     float64 // Average value of the metric per instance
+	Target    float64
+	From      int    // Number of instances before scaling
+	To        int    // Number of instances requested
+	Err       string // Why the service could not be scaled
+}
+
+// history is the zk node that holds the most recent scaling events of a
+// service
+type history struct {
+	Events  []Event
+	version interface{}
+}
+
+// Version implements client.Node
+func (h *history) Version() interface{} { return h.version }
+
+// SetVersion implements client.Node
+func (h *history) SetVersion(version interface{}) { h.version = version }
+
+// Record appends the event to the scaling history of its service, dropping
+// the oldest events beyond the most recent maxEvents
+func Record(conn client.Connection, event Event) error {
+	node := autoscalePath(event.ServiceID)
+
+	var h history
+	if err := conn.Get(node, &h); err == client.ErrNoNode {
+		// the parent has to exist, or the node is created without its data
+		if exists, err := conn.Exists(autoscalePath()); err != nil && err != client.ErrNoNode {
+			return err
+		} else if !exists {
+			if err := conn.CreateDir(autoscalePath()); err != nil {
+				return err
+			}
+		}
+		h.Events = []Event{event}
+		return conn.Create(node, &h)
+	} else if err != nil {
+		return err
+	}
+
+	h.Events = append(h.Events, event)
+	if len(h.Events) > maxEvents {
+		h.Events = h.Events[len(h.Events)-maxEvents:]
+	}
+	return conn.Set(node, &h)
+}
+
+// Events returns the scaling history of a service, oldest first
+func Events(conn client.Connection, serviceID string) ([]Event, error) {
+	var h history
+	if err := conn.Get(autoscalePath(serviceID), &h); err == client.ErrNoNode {
+		return []Event{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return h.Events, nil
+}
+
+// LastScaled returns when the service was last scaled, or the zero time if it
+// never has been
+func LastScaled(conn client.Connection, serviceID string) (time.Time, error) {
+	events, err := Events(conn, serviceID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Err == "" {
+			return events[i].Timestamp, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// Remove deletes the scaling history of a service
+func Remove(conn client.Connection, serviceID string) error {
+	if err := conn.Delete(autoscalePath(serviceID)); err != nil && err != client.ErrNoNode {
+		return err
+	}
+	return nil
+}
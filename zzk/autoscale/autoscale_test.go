@@ -0,0 +1,50 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package autoscale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+func TestRecord(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	if last, err := LastScaled(conn, "service-id"); err != nil {
+		t.Fatalf("Could not get last scaled time: %s", err)
+	} else if !last.IsZero() {
+		t.Errorf("Expected service to never have been scaled; got %s", last)
+	}
+
+	start := time.Now()
+	for i := 0; i < maxEvents+5; i++ {
+		event := Event{ServiceID: "service-id", Timestamp: start.Add(time.Duration(i) * time.Second), From: i, To: i + 1}
+		if err := Record(conn, event); err != nil {
+			t.Fatalf("Could not record event %d: %s", i, err)
+		}
+	}
+
+	events, err := Events(conn, "service-id")
+	if err != nil {
+		t.Fatalf("Could not get events: %s", err)
+	} else if len(events) != maxEvents {
+		t.Fatalf("Expected %d events; got %d", maxEvents, len(events))
+	} else if events[0].From != 5 || events[maxEvents-1].From != maxEvents+4 {
+		t.Errorf("Expected the oldest events to be dropped; got %d..%d", events[0].From, events[maxEvents-1].From)
+	}
+
+	// failed decisions do not count toward the cooldown
+	if err := Record(conn, Event{ServiceID: "service-id", Timestamp: time.Now().Add(time.Hour), Err: "quota exceeded"}); err != nil {
+		t.Fatalf("Could not record event: %s", err)
+	}
+	if last, err := LastScaled(conn, "service-id"); err != nil {
+		t.Fatalf("Could not get last scaled time: %s", err)
+	} else if !last.Equal(events[maxEvents-1].Timestamp) {
+		t.Errorf("MISMATCH: last scaled %s != %s", events[maxEvents-1].Timestamp, last)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+const (
+	zkHealth = "/health"
+
+	// number of status changes kept per health check
+	maxHistory = 20
+)
+
+// Health check statuses
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed"
+	StatusMissed = "missed" // no result within twice the interval of the check
+)
+
+func healthPath(nodes ...string) string {
+	p := []string{zkHealth}
+	p = append(p, nodes...)
+	return path.Join(p...)
+}
+
+func checkPath(serviceID string, instanceID int, name string) string {
+	return healthPath(serviceID, strconv.Itoa(instanceID), name)
+}
+
+// Change records when a health check changed status
+type Change struct {
+	Status    string
+	Timestamp time.Time
+}
+
+// Status is the latest result of a health check of a service instance
+type Status struct {
+	ServiceID  string
+	InstanceID int
+	Name       string
+	Status     string
+	Timestamp  time.Time     // of the latest result
+	Interval   time.Duration // between results
+	Failures   int           // consecutive failed results
+	History    []Change      // the most recent status changes, oldest first
+	version    interface{}
+}
+
+// Version implements client.Node
+func (s *Status) Version() interface{} { return s.version }
+
+// SetVersion implements client.Node
+func (s *Status) SetVersion(version interface{}) { s.version = version }
+
+// Stale is true if no result arrived within twice the interval of the check
+func (s *Status) Stale(now time.Time) bool {
+	return s.Interval > 0 && now.Sub(s.Timestamp) > 2*s.Interval
+}
+
+// Current returns the status of the check, or missed if it is stale
+func (s *Status) Current(now time.Time) string {
+	if s.Stale(now) {
+		return StatusMissed
+	}
+	return s.Status
+}
+
+// Since returns when the check last changed status
+func (s *Status) Since() time.Time {
+	if len(s.History) == 0 {
+		return time.Time{}
+	}
+	return s.History[len(s.History)-1].Timestamp
+}
+
+// change sets the status, recording the change in the history
+func (s *Status) change(status string, at time.Time) {
+	if s.Status == status && len(s.History) > 0 {
+		return
+	}
+	s.Status = status
+	s.History = append(s.History, Change{status, at})
+	if len(s.History) > maxHistory {
+		s.History = s.History[len(s.History)-maxHistory:]
+	}
+}
+
+// Register records the result of a health check of a service instance, and
+// returns the status it had before, or "" if there was no result yet
+func Register(conn client.Connection, result Status) (string, error) {
+	node := checkPath(result.ServiceID, result.InstanceID, result.Name)
+
+	var s Status
+	if err := conn.Get(node, &s); err == client.ErrNoNode {
+		// the parent has to exist, or the node is created without its data
+		if err := conn.CreateDir(path.Dir(node)); err != nil {
+			return "", err
+		}
+		s = Status{ServiceID: result.ServiceID, InstanceID: result.InstanceID, Name: result.Name}
+		s.record(result)
+		return "", conn.Create(node, &s)
+	} else if err != nil {
+		return "", err
+	}
+
+	previous := s.Status
+	s.record(result)
+	return previous, conn.Set(node, &s)
+}
+
+// record updates the status with a result, counting the consecutive failures
+func (s *Status) record(result Status) {
+	s.change(result.Status, result.Timestamp)
+	s.Timestamp, s.Interval = result.Timestamp, result.Interval
+	if result.Status == StatusFailed {
+		s.Failures++
+	} else {
+		s.Failures = 0
+	}
+}
+
+// ResetFailures restarts the count of consecutive failures of each health
+// check of a service instance, such as when the instance is replaced
+func ResetFailures(conn client.Connection, serviceID string, instanceID int) error {
+	names, err := conn.Children(healthPath(serviceID, strconv.Itoa(instanceID)))
+	if err == client.ErrNoNode {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var s Status
+		if err := conn.Get(checkPath(serviceID, instanceID, name), &s); err == client.ErrNoNode {
+			continue
+		} else if err != nil {
+			return err
+		} else if s.Failures == 0 {
+			continue
+		}
+		s.Failures = 0
+		if err := conn.Set(checkPath(serviceID, instanceID, name), &s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkMissed sets the status of a health check that is stale to missed, and
+// returns true if it was not already
+func MarkMissed(conn client.Connection, s *Status, now time.Time) (bool, error) {
+	if !s.Stale(now) || s.Status == StatusMissed {
+		return false, nil
+	}
+	s.change(StatusMissed, now)
+	if err := conn.Set(checkPath(s.ServiceID, s.InstanceID, s.Name), s); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// byInstance sorts statuses by service, instance and check name
+type byInstance []*Status
+
+func (b byInstance) Len() int      { return len(b) }
+func (b byInstance) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byInstance) Less(i, j int) bool {
+	if b[i].ServiceID != b[j].ServiceID {
+		return b[i].ServiceID < b[j].ServiceID
+	} else if b[i].InstanceID != b[j].InstanceID {
+		return b[i].InstanceID < b[j].InstanceID
+	}
+	return b[i].Name < b[j].Name
+}
+
+// Get returns the status of the health checks of each instance of a service,
+// by instance and check name
+func Get(conn client.Connection, serviceID string) ([]*Status, error) {
+	instances, err := conn.Children(healthPath(serviceID))
+	if err == client.ErrNoNode {
+		return []*Status{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	statuses := []*Status{}
+	for _, instance := range instances {
+		names, err := conn.Children(healthPath(serviceID, instance))
+		if err == client.ErrNoNode {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			var s Status
+			if err := conn.Get(healthPath(serviceID, instance, name), &s); err == client.ErrNoNode {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, &s)
+		}
+	}
+	sort.Sort(byInstance(statuses))
+	return statuses, nil
+}
+
+// GetAll returns the status of the health checks of every service instance
+func GetAll(conn client.Connection) ([]*Status, error) {
+	serviceIDs, err := conn.Children(healthPath())
+	if err == client.ErrNoNode {
+		return []*Status{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	statuses := []*Status{}
+	for _, serviceID := range serviceIDs {
+		s, err := Get(conn, serviceID)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s...)
+	}
+	sort.Sort(byInstance(statuses))
+	return statuses, nil
+}
+
+// InstanceHealthy reports whether each of the named health checks has passed
+// for the service instance since the given time, and is not stale
+func InstanceHealthy(conn client.Connection, serviceID string, instanceID int, names []string, since time.Time) (bool, error) {
+	now := time.Now()
+	for _, name := range names {
+		var s Status
+		if err := conn.Get(checkPath(serviceID, instanceID, name), &s); err == client.ErrNoNode {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if s.Current(now) != StatusPassed || s.Timestamp.Before(since) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RemoveInstance deletes the health check statuses of a service instance
+func RemoveInstance(conn client.Connection, serviceID string, instanceID int) error {
+	if err := conn.Delete(healthPath(serviceID, strconv.Itoa(instanceID))); err != nil && err != client.ErrNoNode {
+		return err
+	}
+	return nil
+}
+
+// Remove deletes the health check statuses of every instance of a service
+func Remove(conn client.Connection, serviceID string) error {
+	if err := conn.Delete(healthPath(serviceID)); err != nil && err != client.ErrNoNode {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+func TestRegister(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	start := time.Now().Add(-time.Hour)
+	result := Status{ServiceID: "service-id", InstanceID: 0, Name: "ready", Status: StatusPassed, Timestamp: start, Interval: time.Second}
+	if previous, err := Register(conn, result); err != nil {
+		t.Fatalf("Could not register result: %s", err)
+	} else if previous != "" {
+		t.Errorf("Expected no previous status; got %q", previous)
+	}
+
+	// only the changes are kept in the history
+	for i := 1; i <= maxHistory+5; i++ {
+		result.Timestamp = start.Add(time.Duration(i) * time.Second)
+		result.Status = StatusPassed
+		if i%2 == 0 {
+			result.Status = StatusFailed
+		}
+		for j := 0; j < 2; j++ {
+			if _, err := Register(conn, result); err != nil {
+				t.Fatalf("Could not register result %d: %s", i, err)
+			}
+		}
+	}
+	result.Timestamp = time.Now()
+	if previous, err := Register(conn, result); err != nil {
+		t.Fatalf("Could not register result: %s", err)
+	} else if previous != StatusPassed {
+		t.Errorf("Expected the previous status to be %s; got %q", StatusPassed, previous)
+	}
+
+	statuses, err := Get(conn, "service-id")
+	if err != nil {
+		t.Fatalf("Could not get statuses: %s", err)
+	} else if len(statuses) != 1 {
+		t.Fatalf("Expected 1 status; got %d", len(statuses))
+	}
+	s := statuses[0]
+	if s.Status != StatusPassed || !s.Timestamp.Equal(result.Timestamp) || len(s.History) != maxHistory {
+		t.Errorf("Unexpected status %+v", s)
+	} else if last := s.History[maxHistory-1]; !last.Timestamp.Equal(start.Add(time.Duration(maxHistory+5) * time.Second)) {
+		t.Errorf("Expected the last change at %s; got %s", start.Add(time.Duration(maxHistory+5)*time.Second), last.Timestamp)
+	}
+}
+
+func TestMarkMissed(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	now := time.Now()
+	for i, name := range []string{"ready", "answering"} {
+		result := Status{ServiceID: "service-id", InstanceID: i, Name: name, Status: StatusPassed, Timestamp: now.Add(-time.Duration(i*10) * time.Second), Interval: 3 * time.Second}
+		if _, err := Register(conn, result); err != nil {
+			t.Fatalf("Could not register result: %s", err)
+		}
+	}
+
+	statuses, err := GetAll(conn)
+	if err != nil {
+		t.Fatalf("Could not get statuses: %s", err)
+	} else if len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses; got %d", len(statuses))
+	}
+	if statuses[0].Current(now) != StatusPassed || statuses[1].Current(now) != StatusMissed {
+		t.Errorf("Expected instance 1 to have missed its check; got %+v", statuses)
+	}
+	for i, s := range statuses {
+		if missed, err := MarkMissed(conn, s, now); err != nil {
+			t.Fatalf("Could not mark status as missed: %s", err)
+		} else if missed != (i == 1) {
+			t.Errorf("Unexpected missed %t for instance %d", missed, i)
+		}
+	}
+	if missed, err := MarkMissed(conn, statuses[1], now); err != nil || missed {
+		t.Errorf("Expected the check to be marked missed once; got %t, %v", missed, err)
+	}
+
+	if ok, err := InstanceHealthy(conn, "service-id", 0, []string{"ready"}, now.Add(-time.Minute)); err != nil || !ok {
+		t.Errorf("Expected instance 0 to be healthy; got %t, %v", ok, err)
+	}
+	if ok, err := InstanceHealthy(conn, "service-id", 1, []string{"answering"}, now.Add(-time.Minute)); err != nil || ok {
+		t.Errorf("Expected instance 1 not to be healthy; got %t, %v", ok, err)
+	}
+	if ok, err := InstanceHealthy(conn, "service-id", 0, []string{"ready", "answering"}, now.Add(-time.Minute)); err != nil || ok {
+		t.Errorf("Expected instance 0 not to be healthy without all its checks; got %t, %v", ok, err)
+	}
+
+	if err := RemoveInstance(conn, "service-id", 1); err != nil {
+		t.Fatalf("Could not remove instance: %s", err)
+	}
+	if statuses, err := Get(conn, "service-id"); err != nil || len(statuses) != 1 || statuses[0].InstanceID != 0 {
+		t.Errorf("Expected only instance 0 to be left; got %+v, %v", statuses, err)
+	}
+	if err := Remove(conn, "service-id"); err != nil {
+		t.Fatalf("Could not remove service: %s", err)
+	}
+	if statuses, err := GetAll(conn); err != nil || len(statuses) != 0 {
+		t.Errorf("Expected no statuses; got %+v, %v", statuses, err)
+	}
+}
+
+func TestResetFailures(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	now := time.Now()
+	result := Status{ServiceID: "service-id", InstanceID: 0, Name: "ready", Status: StatusFailed, Timestamp: now, Interval: time.Second}
+	for i := 0; i < 3; i++ {
+		if _, err := Register(conn, result); err != nil {
+			t.Fatalf("Could not register result: %s", err)
+		}
+	}
+	if statuses, err := Get(conn, "service-id"); err != nil || len(statuses) != 1 || statuses[0].Failures != 3 {
+		t.Fatalf("Expected 3 consecutive failures; got %+v, %v", statuses, err)
+	}
+
+	if err := ResetFailures(conn, "service-id", 0); err != nil {
+		t.Fatalf("Could not reset failures: %s", err)
+	}
+	if _, err := Register(conn, result); err != nil {
+		t.Fatalf("Could not register result: %s", err)
+	}
+	if statuses, err := Get(conn, "service-id"); err != nil || len(statuses) != 1 || statuses[0].Failures != 1 {
+		t.Errorf("Expected 1 failure after the reset; got %+v, %v", statuses, err)
+	}
+
+	result.Status = StatusPassed
+	if _, err := Register(conn, result); err != nil {
+		t.Fatalf("Could not register result: %s", err)
+	}
+	if statuses, err := Get(conn, "service-id"); err != nil || len(statuses) != 1 || statuses[0].Failures != 0 {
+		t.Errorf("Expected a passed result to end the failures; got %+v, %v", statuses, err)
+	}
+	if err := ResetFailures(conn, "service-id", 5); err != nil {
+		t.Errorf("Unexpected error resetting the failures of a missing instance: %s", err)
+	}
+}
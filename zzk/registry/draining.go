@@ -0,0 +1,119 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+// Items of a key are drained before the container behind them is stopped. A
+// draining item is marked by a sibling node, so that the watchers of the key
+// are notified, and the watchers leave it out of new connections:
+//    /endpoints
+//      /<endpoint key>
+//         |--<hostID_containerID:zope_Inst1>
+//         |--<hostID_containerID:zope_Inst1>.draining
+//         |--<hostID_containerID:zope_Inst2>
+
+package registry
+
+import (
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/coordinator/client"
+
+	"path"
+	"strings"
+)
+
+const drainingSuffix = ".draining"
+
+// drainingNode marks an item of a key as draining. Marks are ephemeral, so
+// that they go away with the session of the leader that drains the item.
+type drainingNode struct {
+	Item    string // the child of the key that is draining
+	version interface{}
+}
+
+// Version is an implementation of client.Node
+func (n *drainingNode) Version() interface{} { return n.version }
+
+// SetVersion is an implementation of client.Node
+func (n *drainingNode) SetVersion(version interface{}) { n.version = version }
+
+// isDrainingMark is true if the child of a key marks another child draining
+func isDrainingMark(childID string) bool {
+	return strings.Contains(childID, drainingSuffix)
+}
+
+// getDraining returns the items of a key that are marked draining, by the
+// child that marks them
+func getDraining(conn client.Connection, parentPath string, childIDs []string) map[string]string {
+	draining := make(map[string]string)
+	for _, childID := range childIDs {
+		if !isDrainingMark(childID) {
+			continue
+		}
+		var mark drainingNode
+		if err := conn.Get(path.Join(parentPath, childID), &mark); err != nil {
+			glog.Warningf("Could not get draining mark %s/%s: %s", parentPath, childID, err)
+			continue
+		}
+		draining[mark.Item] = childID
+	}
+	return draining
+}
+
+// ActiveItems returns the children of a key that may take new connections;
+// that is, the items that are not marked draining.
+func ActiveItems(conn client.Connection, parentPath string, childIDs []string) []string {
+	draining := getDraining(conn, parentPath, childIDs)
+	active := make([]string, 0, len(childIDs))
+	for _, childID := range childIDs {
+		if _, ok := draining[childID]; ok || isDrainingMark(childID) {
+			glog.V(1).Infof("Skipping draining item %s/%s", parentPath, childID)
+			continue
+		}
+		active = append(active, childID)
+	}
+	return active
+}
+
+// setDraining marks the items of a key that match as draining. Marks of
+// items that have gone away are removed.
+func (r *registryType) setDraining(conn client.Connection, key string, match func(childID string) (bool, error)) error {
+	parentPath := r.getPath(key)
+	childIDs, err := conn.Children(parentPath)
+	if err == client.ErrNoNode {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	draining := getDraining(conn, parentPath, childIDs)
+	items := make(map[string]bool)
+	for _, childID := range childIDs {
+		if !isDrainingMark(childID) {
+			items[childID] = true
+		}
+	}
+	for item, mark := range draining {
+		if !items[item] {
+			if err := removeNode(conn, path.Join(parentPath, mark)); err != nil {
+				glog.Warningf("Could not remove draining mark %s/%s: %s", parentPath, mark, err)
+			}
+		}
+	}
+
+	for item := range items {
+		if _, ok := draining[item]; ok {
+			continue
+		}
+		if ok, err := match(item); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		glog.Infof("Marking %s/%s draining", parentPath, item)
+		if _, err := conn.CreateEphemeral(r.getPath(key, item+drainingSuffix), &drainingNode{Item: item}); err != nil {
+			glog.Errorf("Could not mark %s/%s draining: %s", parentPath, item, err)
+			return err
+		}
+	}
+	return nil
+}
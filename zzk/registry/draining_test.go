@@ -0,0 +1,50 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package registry
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+// nodeConnection serves the nodes of a key
+type nodeConnection struct {
+	client.Connection
+	nodes map[string]interface{}
+}
+
+func (conn *nodeConnection) Get(path string, node client.Node) error {
+	data, ok := conn.nodes[path]
+	if !ok {
+		return client.ErrNoNode
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, node)
+}
+
+func TestActiveItems(t *testing.T) {
+	conn := &nodeConnection{nodes: map[string]interface{}{
+		"/endpoints/tenant_zope/_c_1234-host_c2.draining0000000003": drainingNode{Item: "_c_5678-host_c20000000002"},
+		// the mark of an item that is gone
+		"/endpoints/tenant_zope/_c_1234-host_c0.draining0000000000": drainingNode{Item: "_c_5678-host_c00000000000"},
+	}}
+	childIDs := []string{
+		"_c_5678-host_c10000000001",
+		"_c_5678-host_c20000000002",
+		"_c_1234-host_c2.draining0000000003",
+		"_c_1234-host_c0.draining0000000000",
+	}
+
+	active := ActiveItems(conn, "/endpoints/tenant_zope", childIDs)
+	if expected := []string{"_c_5678-host_c10000000001"}; !reflect.DeepEqual(active, expected) {
+		t.Errorf("expected %v, got %v", expected, active)
+	}
+}
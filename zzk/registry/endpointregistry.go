@@ -154,6 +154,19 @@ func (ar *EndpointRegistry) RemoveItem(conn client.Connection, tenantID, endpoin
 	return ar.removeItem(conn, TenantEndpointKey(tenantID, endpointID), hostContainerKey(hostID, containerID))
 }
 
+// SetDraining marks the endpoints a container exports under an endpoint key
+// as draining
+func (ar *EndpointRegistry) SetDraining(conn client.Connection, tenantID, endpointID, hostID, containerID string) error {
+	key := TenantEndpointKey(tenantID, endpointID)
+	return ar.setDraining(conn, key, func(childID string) (bool, error) {
+		node, err := ar.GetItem(conn, ar.getPath(key, childID))
+		if err != nil {
+			return false, err
+		}
+		return node.HostID == hostID && node.ContainerID == containerID, nil
+	})
+}
+
 // WatchTenantEndpoint watches a tenant endpoint directory
 func (ar *EndpointRegistry) WatchTenantEndpoint(conn client.Connection, tenantEndpointKey string,
 	processChildren ProcessChildrenFunc, errorHandler WatchError) error {
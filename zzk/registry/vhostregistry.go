@@ -94,6 +94,17 @@ func (vr *VhostRegistry) GetItem(conn client.Connection, path string) (*VhostEnd
 	return &vep, nil
 }
 
+//SetDraining marks the VhostEndpoint of a service endpoint under the vhost key as draining
+func (vr *VhostRegistry) SetDraining(conn client.Connection, key, serviceID, endpointName string) error {
+	return vr.setDraining(conn, key, func(childID string) (bool, error) {
+		vep, err := vr.GetItem(conn, vr.getPath(key, childID))
+		if err != nil {
+			return false, err
+		}
+		return vep.ServiceID == serviceID && vep.EndpointName == endpointName, nil
+	})
+}
+
 //WatchVhostEndpoint watch a specific VhostEnpoint
 func (vr *VhostRegistry) WatchVhostEndpoint(conn client.Connection, path string, cancel <-chan bool, processVhostEdnpoint func(conn client.Connection,
 	node *VhostEndpoint), errorHandler WatchError) error {
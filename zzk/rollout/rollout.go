@@ -0,0 +1,299 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package rollout
+
+import (
+	"errors"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/zenoss/glog"
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+const (
+	zkRollout = "/rollouts"
+)
+
+var (
+	// ErrRolloutReplaced is returned when a rollout was removed or replaced by
+	// another request while it was being processed
+	ErrRolloutReplaced = errors.New("rollout was replaced")
+)
+
+func rolloutPath(nodes ...string) string {
+	p := []string{zkRollout}
+	p = append(p, nodes...)
+	return path.Join(p...)
+}
+
+// Rollout is a request to restart the instances of a service a batch at a
+// time. Its progress is kept up to date so a new leader can resume it.
+type Rollout struct {
+	ServiceID    string
+	BatchSize    int           // Number of instances to restart at a time
+	Timeout      time.Duration // How long a batch has to pass its health checks
+	Pending      []int         // Instances that have yet to be restarted
+	Batch        []int         // Instances that are being restarted
+	Restarted    []int         // Instances that have been restarted
+	Created      time.Time
+	BatchStarted time.Time
+	Finished     time.Time
+	Err          string // Why the rollout was paused
+	Immediate    bool   // Stop every instance at once without waiting on health checks
+	version      interface{}
+}
+
+// Version implements client.Node
+func (r *Rollout) Version() interface{} { return r.version }
+
+// SetVersion implements client.Node
+func (r *Rollout) SetVersion(version interface{}) { r.version = version }
+
+// Done is true when every instance has been restarted
+func (r *Rollout) Done() bool { return !r.Finished.IsZero() }
+
+// Paused is true when a batch failed to restart
+func (r *Rollout) Paused() bool { return r.Err != "" }
+
+// Resume returns a new rollout for the instances that have yet to be
+// restarted by a paused rollout
+func (r *Rollout) Resume(batchSize int, timeout time.Duration) *Rollout {
+	return &Rollout{
+		ServiceID: r.ServiceID,
+		BatchSize: batchSize,
+		Timeout:   timeout,
+		Pending:   append(append([]int{}, r.Batch...), r.Pending...),
+		Restarted: r.Restarted,
+	}
+}
+
+// RolloutHandler is the handler interface for running a rollout listener
+type RolloutHandler interface {
+	// RestartInstances restarts the instances of a service that were started
+	// before the given time and waits for their replacements to pass their
+	// health checks.
+	RestartInstances(serviceID string, instanceIDs []int, since time.Time, timeout time.Duration, cancel <-chan interface{}) error
+
+	// StopInstances stops the instances of a service the way the scheduler
+	// stops them, and leaves their replacements to be scheduled.
+	StopInstances(serviceID string, instanceIDs []int) error
+}
+
+// RolloutListener is the zk listener for rollouts
+type RolloutListener struct {
+	conn    client.Connection
+	handler RolloutHandler
+}
+
+// NewRolloutListener instantiates a new listener for rollouts
+func NewRolloutListener(conn client.Connection, handler RolloutHandler) *RolloutListener {
+	return &RolloutListener{conn, handler}
+}
+
+// Listen is the listener call for rollouts
+func (l *RolloutListener) Listen(shutdown <-chan interface{}) {
+	// Make the path if it doesn't exist
+	if exists, err := l.conn.Exists(rolloutPath()); err != nil && err != client.ErrNoNode {
+		glog.Errorf("Error checking path %s: %s", rolloutPath(), err)
+		return
+	} else if !exists {
+		if err := l.conn.CreateDir(rolloutPath()); err != nil {
+			glog.Errorf("Could not create path %s: %s", rolloutPath(), err)
+			return
+		}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	processing := make(map[string]struct{})
+	done := make(chan string)
+
+	// Wait for rollout events
+	for {
+		nodes, event, err := l.conn.ChildrenW(rolloutPath())
+		if err != nil {
+			glog.Errorf("Could not watch rollouts: %s", err)
+			return
+		}
+
+		for _, serviceID := range nodes {
+			if _, ok := processing[serviceID]; ok {
+				continue
+			}
+
+			var rollout Rollout
+			if err := l.conn.Get(rolloutPath(serviceID), &rollout); err != nil {
+				glog.V(1).Infof("Could not get rollout %s: %s", serviceID, err)
+				continue
+			}
+
+			// Rollout is finished or waiting to be resumed
+			if rollout.Done() || rollout.Paused() {
+				continue
+			}
+
+			processing[serviceID] = struct{}{}
+			wg.Add(1)
+			go func(rollout *Rollout) {
+				defer wg.Done()
+				l.run(shutdown, rollout)
+				select {
+				case done <- rollout.ServiceID:
+				case <-shutdown:
+				}
+			}(&rollout)
+		}
+
+		// Wait for an event that something changed
+		select {
+		case e := <-event:
+			glog.V(2).Info("Received rollout event: ", e)
+		case serviceID := <-done:
+			delete(processing, serviceID)
+		case <-shutdown:
+			return
+		}
+	}
+}
+
+// run restarts the instances of the rollout a batch at a time, saving its
+// progress after each step
+func (l *RolloutListener) run(shutdown <-chan interface{}, rollout *Rollout) {
+	if rollout.Immediate {
+		l.stop(rollout)
+		return
+	}
+
+	glog.V(1).Infof("Rolling restart of service %s: %d instances remaining", rollout.ServiceID, len(rollout.Pending)+len(rollout.Batch))
+	for {
+		if len(rollout.Batch) == 0 {
+			if len(rollout.Pending) == 0 {
+				rollout.Finished = time.Now()
+				if err := l.update(rollout); err != nil {
+					glog.Warningf("Could not finish rollout %s: %s", rollout.ServiceID, err)
+				} else {
+					glog.Infof("Finished rolling restart of service %s", rollout.ServiceID)
+				}
+				return
+			}
+
+			// Start the next batch
+			size := rollout.BatchSize
+			if size <= 0 {
+				size = 1
+			} else if size > len(rollout.Pending) {
+				size = len(rollout.Pending)
+			}
+			rollout.Batch, rollout.Pending = rollout.Pending[:size], rollout.Pending[size:]
+			rollout.BatchStarted = time.Now()
+			if err := l.update(rollout); err != nil {
+				glog.Warningf("Could not update rollout %s: %s", rollout.ServiceID, err)
+				return
+			}
+		}
+
+		glog.V(1).Infof("Restarting instances %v of service %s", rollout.Batch, rollout.ServiceID)
+		err := l.handler.RestartInstances(rollout.ServiceID, rollout.Batch, rollout.BatchStarted, rollout.Timeout, shutdown)
+
+		// The new leader will pick up where we left off
+		select {
+		case <-shutdown:
+			return
+		default:
+		}
+
+		if err != nil {
+			glog.Warningf("Pausing rolling restart of service %s: %s", rollout.ServiceID, err)
+			rollout.Err = err.Error()
+			if err := l.update(rollout); err != nil {
+				glog.Warningf("Could not pause rollout %s: %s", rollout.ServiceID, err)
+			}
+			return
+		}
+
+		rollout.Restarted = append(rollout.Restarted, rollout.Batch...)
+		rollout.Batch = nil
+		if err := l.update(rollout); err != nil {
+			glog.Warningf("Could not update rollout %s: %s", rollout.ServiceID, err)
+			return
+		}
+	}
+}
+
+// stop stops every instance of an immediate rollout at once
+func (l *RolloutListener) stop(rollout *Rollout) {
+	glog.V(1).Infof("Restarting instances %v of service %s", rollout.Pending, rollout.ServiceID)
+	if err := l.handler.StopInstances(rollout.ServiceID, rollout.Pending); err != nil {
+		glog.Warningf("Could not restart service %s: %s", rollout.ServiceID, err)
+		rollout.Err = err.Error()
+		if err := l.update(rollout); err != nil {
+			glog.Warningf("Could not pause rollout %s: %s", rollout.ServiceID, err)
+		}
+		return
+	}
+
+	rollout.Restarted = append(rollout.Restarted, rollout.Pending...)
+	rollout.Pending = nil
+	rollout.Finished = time.Now()
+	if err := l.update(rollout); err != nil {
+		glog.Warningf("Could not finish rollout %s: %s", rollout.ServiceID, err)
+	} else {
+		glog.Infof("Finished restart of service %s", rollout.ServiceID)
+	}
+}
+
+// update saves the rollout as long as it hasn't been replaced
+func (l *RolloutListener) update(rollout *Rollout) error {
+	var current Rollout
+	if err := l.conn.Get(rolloutPath(rollout.ServiceID), &current); err == client.ErrNoNode {
+		return ErrRolloutReplaced
+	} else if err != nil {
+		return err
+	} else if !current.Created.Equal(rollout.Created) || current.Done() || current.Paused() {
+		return ErrRolloutReplaced
+	}
+	rollout.SetVersion(current.Version())
+	return l.conn.Set(rolloutPath(rollout.ServiceID), rollout)
+}
+
+// Send sends a new rollout request, replacing any rollout of the service that
+// is finished or paused
+func Send(conn client.Connection, rollout *Rollout) error {
+	node := rolloutPath(rollout.ServiceID)
+
+	var current Rollout
+	if err := conn.Get(node, &current); err == nil {
+		if !current.Done() && !current.Paused() {
+			return errors.New("a rolling restart of the service is already in progress")
+		} else if err := conn.Delete(node); err != nil {
+			return err
+		}
+	} else if err != client.ErrNoNode {
+		return err
+	}
+
+	rollout.Created = time.Now()
+	return conn.Create(node, rollout)
+}
+
+// Get looks up the rollout of a service
+func Get(conn client.Connection, serviceID string, rollout *Rollout) error {
+	return conn.Get(rolloutPath(serviceID), rollout)
+}
+
+// Cancel removes the rollout of a service, whether it is paused or in
+// progress. An instance that is being restarted when the rollout is canceled
+// finishes restarting, but no further batches are started.
+func Cancel(conn client.Connection, serviceID string) error {
+	node := rolloutPath(serviceID)
+	if exists, err := conn.Exists(node); err != nil && err != client.ErrNoNode {
+		return err
+	} else if !exists {
+		return errors.New("no rolling restart of the service to cancel")
+	}
+	return conn.Delete(node)
+}
@@ -0,0 +1,225 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package rollout
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+type TestRolloutHandler struct {
+	sync.Mutex
+	Batches [][]int
+	Stopped []int
+	Fail    map[int]bool
+}
+
+func (handler *TestRolloutHandler) RestartInstances(serviceID string, instanceIDs []int, since time.Time, timeout time.Duration, cancel <-chan interface{}) error {
+	handler.Lock()
+	defer handler.Unlock()
+
+	handler.Batches = append(handler.Batches, instanceIDs)
+	for _, id := range instanceIDs {
+		if handler.Fail[id] {
+			return fmt.Errorf("instance %d failed its health checks", id)
+		}
+	}
+	return nil
+}
+
+func (handler *TestRolloutHandler) StopInstances(serviceID string, instanceIDs []int) error {
+	handler.Lock()
+	defer handler.Unlock()
+
+	handler.Stopped = append(handler.Stopped, instanceIDs...)
+	return nil
+}
+
+// wait polls the rollout until it is finished or paused
+func wait(t *testing.T, conn client.Connection, serviceID string) *Rollout {
+	timeout := time.After(5 * time.Second)
+	for {
+		var rollout Rollout
+		if err := Get(conn, serviceID, &rollout); err != nil {
+			t.Fatalf("Could not get rollout %s: %s", serviceID, err)
+		} else if rollout.Done() || rollout.Paused() {
+			return &rollout
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("Timed out waiting for rollout %s", serviceID)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestRolloutListener_Listen(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	handler := &TestRolloutHandler{Fail: make(map[int]bool)}
+	shutdown := make(chan interface{})
+	defer close(shutdown)
+	listener := NewRolloutListener(conn, handler)
+	go listener.Listen(shutdown)
+
+	// send the rollout
+	if err := Send(conn, &Rollout{ServiceID: "service-id", BatchSize: 2, Pending: []int{0, 1, 2}}); err != nil {
+		t.Fatalf("Could not send rollout: %s", err)
+	}
+
+	rollout := wait(t, conn, "service-id")
+	if rollout.Paused() {
+		t.Fatalf("Rollout paused: %s", rollout.Err)
+	}
+
+	// verify the batches
+	expected := [][]int{{0, 1}, {2}}
+	if !reflect.DeepEqual(handler.Batches, expected) {
+		t.Errorf("MISMATCH: batches do not match %v != %v", expected, handler.Batches)
+	}
+	if !reflect.DeepEqual(rollout.Restarted, []int{0, 1, 2}) {
+		t.Errorf("MISMATCH: restarted instances do not match [0 1 2] != %v", rollout.Restarted)
+	}
+}
+
+func TestRolloutListener_Immediate(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	handler := &TestRolloutHandler{Fail: make(map[int]bool)}
+	shutdown := make(chan interface{})
+	defer close(shutdown)
+	listener := NewRolloutListener(conn, handler)
+	go listener.Listen(shutdown)
+
+	// a plain restart stops every instance through the handler at once
+	if err := Send(conn, &Rollout{ServiceID: "service-id", Pending: []int{0, 1, 2}, Immediate: true}); err != nil {
+		t.Fatalf("Could not send rollout: %s", err)
+	}
+
+	rollout := wait(t, conn, "service-id")
+	if rollout.Paused() {
+		t.Fatalf("Rollout paused: %s", rollout.Err)
+	}
+
+	if !reflect.DeepEqual(handler.Stopped, []int{0, 1, 2}) {
+		t.Errorf("MISMATCH: stopped instances do not match [0 1 2] != %v", handler.Stopped)
+	}
+	if len(handler.Batches) > 0 {
+		t.Errorf("Expected no batches waiting on health checks, got %v", handler.Batches)
+	}
+	if !reflect.DeepEqual(rollout.Restarted, []int{0, 1, 2}) {
+		t.Errorf("MISMATCH: restarted instances do not match [0 1 2] != %v", rollout.Restarted)
+	}
+}
+
+func TestRolloutListener_Resume(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	handler := &TestRolloutHandler{Fail: map[int]bool{1: true}}
+	shutdown := make(chan interface{})
+	defer close(shutdown)
+	listener := NewRolloutListener(conn, handler)
+	go listener.Listen(shutdown)
+
+	// the second batch should fail
+	if err := Send(conn, &Rollout{ServiceID: "service-id", BatchSize: 1, Pending: []int{0, 1, 2}}); err != nil {
+		t.Fatalf("Could not send rollout: %s", err)
+	}
+
+	rollout := wait(t, conn, "service-id")
+	if !rollout.Paused() {
+		t.Fatalf("Expected rollout to be paused")
+	} else if !reflect.DeepEqual(rollout.Batch, []int{1}) || !reflect.DeepEqual(rollout.Pending, []int{2}) {
+		t.Errorf("MISMATCH: unexpected progress batch=%v pending=%v", rollout.Batch, rollout.Pending)
+	}
+
+	// resume the rollout after fixing the problem
+	handler.Lock()
+	handler.Fail = nil
+	handler.Unlock()
+	if err := Send(conn, rollout.Resume(2, time.Minute)); err != nil {
+		t.Fatalf("Could not resume rollout: %s", err)
+	}
+
+	rollout = wait(t, conn, "service-id")
+	if rollout.Paused() {
+		t.Fatalf("Rollout paused: %s", rollout.Err)
+	}
+
+	expected := [][]int{{0}, {1}, {1, 2}}
+	if !reflect.DeepEqual(handler.Batches, expected) {
+		t.Errorf("MISMATCH: batches do not match %v != %v", expected, handler.Batches)
+	}
+	if !reflect.DeepEqual(rollout.Restarted, []int{0, 1, 2}) {
+		t.Errorf("MISMATCH: restarted instances do not match [0 1 2] != %v", rollout.Restarted)
+	}
+}
+
+func TestSend_InProgress(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	if err := Send(conn, &Rollout{ServiceID: "service-id", Pending: []int{0}}); err != nil {
+		t.Fatalf("Could not send rollout: %s", err)
+	}
+	if err := Send(conn, &Rollout{ServiceID: "service-id", Pending: []int{0}}); err == nil {
+		t.Errorf("Expected error sending a rollout while one is in progress")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	if err := Cancel(conn, "service-id"); err == nil {
+		t.Errorf("Expected error canceling a rollout that does not exist")
+	}
+
+	// a paused rollout can be canceled instead of resumed
+	if err := Send(conn, &Rollout{ServiceID: "service-id", Pending: []int{0}, Err: "failed"}); err != nil {
+		t.Fatalf("Could not send rollout: %s", err)
+	}
+	if err := Cancel(conn, "service-id"); err != nil {
+		t.Fatalf("Could not cancel rollout: %s", err)
+	}
+	var rollout Rollout
+	if err := Get(conn, "service-id", &rollout); err != client.ErrNoNode {
+		t.Errorf("Expected canceled rollout to be removed, got %v", err)
+	}
+
+	// a new rollout can start once the old one is canceled
+	if err := Send(conn, &Rollout{ServiceID: "service-id", Pending: []int{0}}); err != nil {
+		t.Errorf("Could not send rollout after canceling: %s", err)
+	}
+}
+
+func TestRolloutListener_Cancel(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	listener := NewRolloutListener(conn, &TestRolloutHandler{})
+	rollout := &Rollout{ServiceID: "service-id", Pending: []int{0, 1}}
+	if err := Send(conn, rollout); err != nil {
+		t.Fatalf("Could not send rollout: %s", err)
+	}
+	if err := Cancel(conn, "service-id"); err != nil {
+		t.Fatalf("Could not cancel rollout: %s", err)
+	}
+
+	// progress of a canceled rollout is not saved
+	rollout.Batch = []int{0}
+	if err := listener.update(rollout); err != ErrRolloutReplaced {
+		t.Errorf("Expected %s, got %v", ErrRolloutReplaced, err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package snapshotschedule
+
+import (
+	"path"
+	"time"
+
+	"github.com/control-center/serviced/commons/cron"
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+const zkSnapshotSchedule = "/snapshotschedule"
+
+func schedulePath(nodes ...string) string {
+	p := []string{zkSnapshotSchedule}
+	p = append(p, nodes...)
+	return path.Join(p...)
+}
+
+// Snapshot is a snapshot taken by the schedule of a tenant
+type Snapshot struct {
+	ID      string
+	Created time.Time
+}
+
+// Status records the runs of the snapshot schedule of a tenant
+type Status struct {
+	TenantID     string
+	Started      time.Time  // When the leader first evaluated the schedule
+	LastRun      time.Time  // When the schedule last took a snapshot, or tried to
+	LastSnapshot string     // Snapshot taken by the last run
+	Err          string     // Why the last run failed
+	Snapshots    []Snapshot // Snapshots taken by the schedule that are kept, oldest first
+	Pruned       []string   // Snapshots removed by the last run
+	version      interface{}
+}
+
+// Version implements client.Node
+func (s *Status) Version() interface{} { return s.version }
+
+// SetVersion implements client.Node
+func (s *Status) SetVersion(version interface{}) { s.version = version }
+
+// Next returns when the schedule is next due. Runs missed while there was no
+// leader are caught up with a single run.
+func (s *Status) Next(schedule *cron.Schedule) time.Time {
+	last := s.Started
+	if s.LastRun.After(last) {
+		last = s.LastRun
+	}
+	return schedule.Next(last)
+}
+
+// Scheduled is true when the schedule took the snapshot
+func (s *Status) Scheduled(snapshotID string) bool {
+	for _, snapshot := range s.Snapshots {
+		if snapshot.ID == snapshotID {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the status of the snapshot schedule of a tenant, or nil if the
+// schedule was never evaluated
+func Get(conn client.Connection, tenantID string) (*Status, error) {
+	var status Status
+	if err := conn.Get(schedulePath(tenantID), &status); err == client.ErrNoNode {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Set creates or updates the status of the snapshot schedule of a tenant
+func Set(conn client.Connection, status *Status) error {
+	node := schedulePath(status.TenantID)
+
+	var current Status
+	if err := conn.Get(node, &current); err == client.ErrNoNode {
+		// the parent has to exist, or the node is created without its data
+		if exists, err := conn.Exists(schedulePath()); err != nil && err != client.ErrNoNode {
+			return err
+		} else if !exists {
+			if err := conn.CreateDir(schedulePath()); err != nil {
+				return err
+			}
+		}
+		return conn.Create(node, status)
+	} else if err != nil {
+		return err
+	}
+
+	status.SetVersion(current.Version())
+	return conn.Set(node, status)
+}
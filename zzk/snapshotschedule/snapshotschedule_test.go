@@ -0,0 +1,60 @@
+// Copyright 2014, The Serviced Authors. All rights reserved.
+// Use of this source code is governed by the Apache 2.0
+// license that can be found in the LICENSE file.
+
+package snapshotschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/control-center/serviced/commons/cron"
+	"github.com/control-center/serviced/coordinator/client"
+)
+
+func TestSet(t *testing.T) {
+	conn := client.NewTestConnection()
+	defer conn.Close()
+
+	if status, err := Get(conn, "tenant-id"); err != nil {
+		t.Fatalf("Could not get status: %s", err)
+	} else if status != nil {
+		t.Errorf("Expected no status; got %+v", status)
+	}
+
+	start := time.Date(2014, 10, 1, 10, 30, 0, 0, time.UTC)
+	if err := Set(conn, &Status{TenantID: "tenant-id", Started: start}); err != nil {
+		t.Fatalf("Could not set status: %s", err)
+	}
+	snapshot := Snapshot{ID: "tenant-id_20141002-000000", Created: start.Add(time.Hour)}
+	if err := Set(conn, &Status{TenantID: "tenant-id", Started: start, LastRun: snapshot.Created, Snapshots: []Snapshot{snapshot}}); err != nil {
+		t.Fatalf("Could not update status: %s", err)
+	}
+
+	status, err := Get(conn, "tenant-id")
+	if err != nil {
+		t.Fatalf("Could not get status: %s", err)
+	} else if status == nil || !status.LastRun.Equal(snapshot.Created) {
+		t.Fatalf("Expected last run at %s; got %+v", snapshot.Created, status)
+	} else if !status.Scheduled(snapshot.ID) || status.Scheduled("tenant-id_20141001-000000") {
+		t.Errorf("Expected only %s to be scheduled; got %+v", snapshot.ID, status.Snapshots)
+	}
+}
+
+func TestStatus_Next(t *testing.T) {
+	schedule, err := cron.Parse("@daily")
+	if err != nil {
+		t.Fatalf("Could not parse schedule: %s", err)
+	}
+
+	start := time.Date(2014, 10, 1, 10, 30, 0, 0, time.UTC)
+	status := Status{Started: start}
+	if next, expected := status.Next(schedule), time.Date(2014, 10, 2, 0, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected the first run at %s; got %s", expected, next)
+	}
+
+	status.LastRun = time.Date(2014, 10, 5, 0, 0, 0, 0, time.UTC)
+	if next, expected := status.Next(schedule), time.Date(2014, 10, 6, 0, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected the next run at %s; got %s", expected, next)
+	}
+}